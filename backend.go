@@ -1,7 +1,9 @@
 package flux
 
 import (
+	"crypto/tls"
 	"net/http"
+	"time"
 )
 
 // BackendTransport 表示某种特定协议的后端服务，例如Dubbo, gRPC, Http等协议的后端服务。
@@ -13,5 +15,77 @@ type BackendTransport interface {
 	Invoke(BackendService, Context) (interface{}, *ServeError)
 }
 
+// ResultIterator 逐个返回结果元素的迭代器。后端服务的结果集较大时（例如数据库查询），解码函数可返回迭代器作为响应体，
+// 网关按流式JSON数组逐个编码输出元素，不在内存中构建完整的响应
+type ResultIterator interface {
+	// Next 返回下一个元素；没有更多元素时返回 io.EOF
+	Next() (interface{}, error)
+	// Close 释放迭代器的资源
+	Close() error
+}
+
 // BackendTransportDecodeFunc 解析Backend返回的数据
 type BackendTransportDecodeFunc func(ctx Context, response interface{}) (statusCode int, headers http.Header, body interface{}, err error)
+
+const (
+	// BackendService.RemoteHost 引用命名上游集群的前缀，例如 upstream://order-cluster
+	UpstreamHostPrefix = "upstream://"
+	// 静态上游集群配置的命名空间
+	KeyConfigRootUpstreams = "Upstreams"
+)
+
+// Upstream 命名的上游集群：包含多个上游地址，每次调用由负载均衡策略选择其中一个地址
+type Upstream struct {
+	Name      string            `json:"name"`
+	Balancer  string            `json:"balancer"` // 负载均衡策略名称，默认为 round-robin
+	HashKey   string            `json:"hashKey"`  // 一致性哈希策略的哈希Key查找表达式，例如 header:X-User-Id
+	Addresses []UpstreamAddress `json:"addresses"`
+	// 健康检查：连续失败的地址被标记为不健康，负载均衡时跳过
+	HealthCheck UpstreamHealthCheck `json:"healthCheck"`
+	// 使用SPIFFE身份进行mTLS时，允许的上游SPIFFE ID；为空时使用全局的验证策略
+	SpiffeIDs []string `json:"spiffeIds"`
+}
+
+// UpstreamHealthCheck 上游地址的健康检查配置；未指定主动检查类型时，只根据调用结果进行被动检查
+type UpstreamHealthCheck struct {
+	Type               string        `json:"type"`               // 主动检查类型：http, tcp, dubbo
+	Path               string        `json:"path"`               // http检查的请求路径，默认为 /
+	Interval           time.Duration `json:"interval"`           // 主动检查间隔，默认10s
+	Timeout            time.Duration `json:"timeout"`            // 主动检查超时，默认2s
+	UnhealthyThreshold int           `json:"unhealthyThreshold"` // 标记为不健康的连续失败次数，默认3
+	HealthyThreshold   int           `json:"healthyThreshold"`   // 主动检查恢复为健康的连续成功次数，默认2
+	EjectDuration      time.Duration `json:"ejectDuration"`      // 未开启主动检查时，不健康地址的摘除时长，默认30s
+}
+
+// UpstreamAddress 上游集群中的地址及其权重
+type UpstreamAddress struct {
+	Address string `json:"address"`
+	Weight  int    `json:"weight"`
+}
+
+// UpstreamBalancer 上游负载均衡策略，从上游集群中为本次调用选择一个地址
+type UpstreamBalancer interface {
+	Select(ctx Context) (UpstreamAddress, error)
+}
+
+// UpstreamBalancerFactory 为上游集群创建负载均衡策略实例；上游集群定义变更后重新创建
+type UpstreamBalancerFactory func(upstream Upstream) (UpstreamBalancer, error)
+
+// UpstreamTLSConfigFunc 返回连接上游地址（host:port）使用的TLS客户端配置，例如提供mTLS的客户端证书和对端验证策略；
+// 返回nil时使用默认配置
+type UpstreamTLSConfigFunc func(address string) (*tls.Config, error)
+
+// BackendInvocation 一次后端服务调用的信息；Service 为已解析上游地址的服务副本
+type BackendInvocation struct {
+	RpcProto string
+	Upstream string // 引用的上游集群名称；未引用上游集群时为空
+	Attempt  int    // 故障转移的调用次数，从1开始
+	Service  BackendService
+}
+
+// BackendInvokeFunc 执行一次后端服务调用
+type BackendInvokeFunc func(invocation BackendInvocation, ctx Context) (interface{}, *ServeError)
+
+// BackendInterceptor 后端服务调用拦截器：包装每一次后端服务调用（包括重试、故障转移、对冲和流量镜像的调用），
+// 可用于按协议和上游集群记录调用耗时、错误次数和数据大小等指标，无需修改各协议的Backend实现
+type BackendInterceptor func(next BackendInvokeFunc) BackendInvokeFunc
@@ -0,0 +1,42 @@
+package amqp
+
+import (
+	"errors"
+	"github.com/bytepowered/flux"
+	"github.com/spf13/cast"
+	amqpgo "github.com/streadway/amqp"
+	"net/http"
+)
+
+const (
+	// rpc 模式下，响应消息中指定HTTP状态码的Header
+	ReplyHeaderStatusCode = "status-code"
+)
+
+var (
+	ErrUnknownAmqpBackendResponse = errors.New("BACKEND:UNKNOWN_AMQP_RESPONSE")
+)
+
+// NewAmqpBackendTransportDecodeFunc publish 模式返回 202 和确认信息；rpc 模式返回响应消息Body
+func NewAmqpBackendTransportDecodeFunc() flux.BackendTransportDecodeFunc {
+	return func(ctx flux.Context, value interface{}) (statusCode int, headers http.Header, body interface{}, err error) {
+		switch resp := value.(type) {
+		case *Ack:
+			return http.StatusAccepted, http.Header{}, resp, nil
+		case *amqpgo.Delivery:
+			header := http.Header{}
+			if "" != resp.ContentType {
+				header.Set(flux.HeaderContentType, resp.ContentType)
+			}
+			status := http.StatusOK
+			if v, ok := resp.Headers[ReplyHeaderStatusCode]; ok {
+				if code := cast.ToInt(v); code > 0 {
+					status = code
+				}
+			}
+			return status, header, resp.Body, nil
+		default:
+			return http.StatusInternalServerError, http.Header{}, nil, ErrUnknownAmqpBackendResponse
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package amqp
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+)
+
+func init() {
+	ext.StoreBackendTransport(flux.ProtoAmqp, NewAmqpBackendTransport())
+	ext.StoreBackendTransportDecodeFunc(flux.ProtoAmqp, NewAmqpBackendTransportDecodeFunc())
+}
@@ -0,0 +1,35 @@
+package amqp
+
+import (
+	"fmt"
+	"github.com/spf13/cast"
+	"strings"
+)
+
+// ExpandRoutingKey 展开RoutingKey模板：{name} 占位符替换为同名参数的值。例如：
+// order.{region}.{orderId} -> order.cn.10001
+func ExpandRoutingKey(template string, values map[string]interface{}) (string, error) {
+	if !strings.Contains(template, "{") {
+		return template, nil
+	}
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			sb.WriteString(template)
+			return sb.String(), nil
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unclosed routing key placeholder: %s", template)
+		}
+		name := template[start+1 : start+end]
+		value, ok := values[name]
+		if !ok {
+			return "", fmt.Errorf("routing key argument not found: %s", name)
+		}
+		sb.WriteString(template[:start])
+		sb.WriteString(cast.ToString(value))
+		template = template[start+end+1:]
+	}
+}
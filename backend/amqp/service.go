@@ -0,0 +1,275 @@
+package amqp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/backend"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"github.com/spf13/cast"
+	amqpgo "github.com/streadway/amqp"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+const (
+	configKeyURL     = "url"
+	configKeyTimeout = "timeout"
+)
+
+const (
+	// BackendService扩展属性：RoutingKey模板，支持 {name} 参数占位符；未设置时使用 Method
+	ServiceExtKeyRoutingKey = "amqp-routing-key"
+	// BackendService扩展属性：调用模式，publish（默认）或 rpc
+	ServiceExtKeyMode = "amqp-mode"
+	// BackendService扩展属性：是否持久化消息
+	ServiceExtKeyPersistent = "amqp-persistent"
+)
+
+const (
+	// 发布消息，Broker确认后返回
+	ModePublish = "publish"
+	// 发布请求消息，通过 Direct reply-to 队列等待响应消息
+	ModeRPC = "rpc"
+)
+
+const (
+	// RabbitMQ的 Direct reply-to 伪队列
+	directReplyQueue = "amq.rabbitmq.reply-to"
+)
+
+var (
+	ErrPublishNotConfirmed = errors.New("amqp publish not confirmed by broker")
+)
+
+var (
+	_ flux.BackendTransport = new(BackendTransportService)
+)
+
+// Ack publish 模式下，消息发布成功后返回的确认信息
+type Ack struct {
+	Exchange   string `json:"exchange"`
+	RoutingKey string `json:"routingKey"`
+	RequestId  string `json:"requestId"`
+}
+
+// BackendTransportService 发布消息到RabbitMQ Exchange的BackendService：
+// BackendService.Interface 为Exchange名称，RemoteHost 为AMQP连接地址（未设置时使用全局配置）；
+// 定义了Arguments时，消息为参数值组成的JSON对象，否则为原始请求Body。Attributes作为消息Header传递。
+type BackendTransportService struct {
+	url         string
+	timeout     time.Duration
+	connections map[string]*amqpgo.Connection
+	mu          sync.Mutex
+}
+
+// NewAmqpBackendTransport New amqp backend instance
+func NewAmqpBackendTransport() flux.BackendTransport {
+	return &BackendTransportService{
+		connections: make(map[string]*amqpgo.Connection, 2),
+	}
+}
+
+// Init init backend
+func (b *BackendTransportService) Init(config *flux.Configuration) error {
+	logger.Info("AMQP backend transport initializing")
+	config.SetDefaults(map[string]interface{}{
+		configKeyTimeout: time.Second * 10,
+	})
+	b.url = config.GetString(configKeyURL)
+	b.timeout = config.GetDuration(configKeyTimeout)
+	return nil
+}
+
+// Shutdown shutdown backend
+func (b *BackendTransportService) Shutdown(_ context.Context) error {
+	logger.Info("AMQP backend transport shutdown")
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for url, conn := range b.connections {
+		if err := conn.Close(); nil != err && amqpgo.ErrClosed != err {
+			logger.Warnw("AMQP backend transport close connection", "url", url, "error", err)
+		}
+	}
+	b.connections = make(map[string]*amqpgo.Connection, 2)
+	return nil
+}
+
+// Exchange do exchange with context
+func (b *BackendTransportService) Exchange(ctx flux.Context) *flux.ServeError {
+	return backend.DoExchange(ctx, b)
+}
+
+// Invoke 发布消息；rpc 模式下返回响应消息 amqp.Delivery，否则返回 Ack
+func (b *BackendTransportService) Invoke(service flux.BackendService, ctx flux.Context) (interface{}, *flux.ServeError) {
+	routingKey, message, err := b.Assemble(service, ctx)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusBadRequest,
+			ErrorCode:  flux.ErrorCodeRequestInvalid,
+			Message:    flux.ErrorMessageAmqpAssembleFailed,
+			Internal:   err,
+		}
+	}
+	timeout := b.timeout
+	if to := service.AttrRpcTimeout(); "" != to {
+		if d, err := time.ParseDuration(to); nil == err {
+			timeout = d
+		} else {
+			logger.Warnf("Illegal endpoint rpc-timeout: ", to)
+		}
+	}
+	toctx, cancel := context.WithTimeout(ctx.Context(), timeout)
+	defer cancel()
+	channel, err := b.channelOf(service)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusBadGateway,
+			ErrorCode:  flux.ErrorCodeGatewayBackend,
+			Message:    flux.ErrorMessageAmqpPublishFailed,
+			Internal:   err,
+		}
+	}
+	defer channel.Close()
+	var resp interface{}
+	if ModeRPC == service.ExtString(ServiceExtKeyMode) {
+		resp, err = call(toctx, channel, service.Interface, routingKey, message)
+	} else {
+		err = publish(toctx, channel, service.Interface, routingKey, message)
+		resp = &Ack{Exchange: service.Interface, RoutingKey: routingKey, RequestId: ctx.RequestId()}
+	}
+	if nil != err {
+		serr := &flux.ServeError{
+			StatusCode: flux.StatusBadGateway,
+			ErrorCode:  flux.ErrorCodeGatewayBackend,
+			Message:    flux.ErrorMessageAmqpPublishFailed,
+			Internal:   err,
+		}
+		if context.DeadlineExceeded == err {
+			serr.StatusCode = flux.StatusGatewayTimeout
+		}
+		return nil, serr
+	}
+	return resp, nil
+}
+
+// Assemble 生成RoutingKey和AMQP消息
+func (b *BackendTransportService) Assemble(service flux.BackendService, ctx flux.Context) (string, amqpgo.Publishing, error) {
+	message := amqpgo.Publishing{
+		Headers:   amqpgo.Table{},
+		MessageId: ctx.RequestId(),
+		Timestamp: time.Now(),
+	}
+	if service.ExtBool(ServiceExtKeyPersistent) {
+		message.DeliveryMode = amqpgo.Persistent
+	}
+	values, err := backend.ResolveArgumentValues(service.Arguments, ctx)
+	if nil != err {
+		return "", message, err
+	}
+	template := service.ExtString(ServiceExtKeyRoutingKey)
+	if "" == template {
+		template = service.Method
+	}
+	routingKey, err := ExpandRoutingKey(template, values)
+	if nil != err {
+		return "", message, err
+	}
+	if len(service.Arguments) > 0 {
+		data, err := ext.JSONMarshal(values)
+		if nil != err {
+			return "", message, err
+		}
+		message.ContentType = flux.MIMEApplicationJSON
+		message.Body = data
+	} else {
+		reader, err := ctx.Request().RequestBodyReader()
+		if nil != err {
+			return "", message, err
+		}
+		data, err := ioutil.ReadAll(reader)
+		_ = reader.Close()
+		if nil != err {
+			return "", message, err
+		}
+		message.ContentType = ctx.Request().HeaderValue(flux.HeaderContentType)
+		message.Body = data
+	}
+	for k, v := range ctx.Attributes() {
+		message.Headers[k] = cast.ToString(v)
+	}
+	return routingKey, message, nil
+}
+
+// channelOf 在复用的连接上打开新的Channel；连接断开时重新连接
+func (b *BackendTransportService) channelOf(service flux.BackendService) (*amqpgo.Channel, error) {
+	url := b.url
+	if "" != service.RemoteHost {
+		url = service.RemoteHost
+	}
+	if "" == url {
+		return nil, fmt.Errorf("amqp url is required, service: %s", service.ServiceID())
+	}
+	b.mu.Lock()
+	conn, ok := b.connections[url]
+	if !ok || conn.IsClosed() {
+		var err error
+		conn, err = amqpgo.DialConfig(url, amqpgo.Config{Dial: amqpgo.DefaultDial(b.timeout)})
+		if nil != err {
+			b.mu.Unlock()
+			return nil, err
+		}
+		b.connections[url] = conn
+	}
+	b.mu.Unlock()
+	return conn.Channel()
+}
+
+// publish 以 Publisher Confirms 模式发布消息，等待Broker确认
+func publish(ctx context.Context, channel *amqpgo.Channel, exchange, routingKey string, message amqpgo.Publishing) error {
+	if err := channel.Confirm(false); nil != err {
+		return err
+	}
+	confirms := channel.NotifyPublish(make(chan amqpgo.Confirmation, 1))
+	if err := channel.Publish(exchange, routingKey, false, false, message); nil != err {
+		return err
+	}
+	select {
+	case confirm := <-confirms:
+		if !confirm.Ack {
+			return ErrPublishNotConfirmed
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// call 通过 Direct reply-to 队列执行RPC调用，返回响应消息
+func call(ctx context.Context, channel *amqpgo.Channel, exchange, routingKey string, message amqpgo.Publishing) (*amqpgo.Delivery, error) {
+	replies, err := channel.Consume(directReplyQueue, "", true, false, false, false, nil)
+	if nil != err {
+		return nil, err
+	}
+	message.ReplyTo = directReplyQueue
+	message.CorrelationId = message.MessageId
+	if err := channel.Publish(exchange, routingKey, false, false, message); nil != err {
+		return nil, err
+	}
+	for {
+		select {
+		case reply, ok := <-replies:
+			if !ok {
+				return nil, amqpgo.ErrClosed
+			}
+			if reply.CorrelationId == message.CorrelationId {
+				return &reply, nil
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
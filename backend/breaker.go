@@ -0,0 +1,238 @@
+package backend
+
+import (
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"github.com/spf13/cast"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// BackendService扩展属性：熔断的失败率阈值（百分比），窗口内失败调用的比例达到阈值时熔断；上游5xx和网关5xx错误视为失败
+	ServiceExtKeyBreakerFailureRate = "breaker-failure-rate"
+	// BackendService扩展属性：熔断的慢调用率阈值（百分比），窗口内慢调用的比例达到阈值时熔断
+	ServiceExtKeyBreakerSlowRate = "breaker-slow-rate"
+	// BackendService扩展属性：慢调用的耗时阈值；默认1s
+	ServiceExtKeyBreakerSlowDuration = "breaker-slow-duration"
+	// BackendService扩展属性：统计窗口；默认10s
+	ServiceExtKeyBreakerWindow = "breaker-window"
+	// BackendService扩展属性：计算失败率的最小调用次数；默认20
+	ServiceExtKeyBreakerMinCalls = "breaker-min-calls"
+	// BackendService扩展属性：熔断持续时间，之后进入半开状态放行探测调用；默认5s
+	ServiceExtKeyBreakerOpenDuration = "breaker-open-duration"
+	// BackendService扩展属性：半开状态的探测调用次数，全部成功后恢复；默认3
+	ServiceExtKeyBreakerHalfOpenCalls = "breaker-half-open-calls"
+)
+
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+const (
+	breakerBuckets = 10
+)
+
+var (
+	breakerStateNames = []string{"CLOSED", "OPEN", "HALF_OPEN"}
+	// 熔断器按上游和熔断配置区分；配置变更后使用新的熔断器
+	breakers sync.Map
+)
+
+// breakerOptions 熔断配置
+type breakerOptions struct {
+	failureRate   float64
+	slowRate      float64
+	slowDuration  time.Duration
+	window        time.Duration
+	minCalls      int
+	openDuration  time.Duration
+	halfOpenCalls int
+}
+
+// breakerOptionsOf 返回BackendService声明的熔断配置；未声明失败率和慢调用率阈值时返回false
+func breakerOptionsOf(service flux.BackendService) (breakerOptions, bool) {
+	extOf := func(name string, def interface{}) interface{} {
+		if v, ok := service.Ext(name); ok {
+			return v
+		}
+		return def
+	}
+	opts := breakerOptions{
+		failureRate:   cast.ToFloat64(extOf(ServiceExtKeyBreakerFailureRate, 0)),
+		slowRate:      cast.ToFloat64(extOf(ServiceExtKeyBreakerSlowRate, 0)),
+		slowDuration:  cast.ToDuration(extOf(ServiceExtKeyBreakerSlowDuration, time.Second)),
+		window:        cast.ToDuration(extOf(ServiceExtKeyBreakerWindow, 10*time.Second)),
+		minCalls:      cast.ToInt(extOf(ServiceExtKeyBreakerMinCalls, 20)),
+		openDuration:  cast.ToDuration(extOf(ServiceExtKeyBreakerOpenDuration, 5*time.Second)),
+		halfOpenCalls: cast.ToInt(extOf(ServiceExtKeyBreakerHalfOpenCalls, 3)),
+	}
+	if opts.failureRate <= 0 && opts.slowRate <= 0 {
+		return opts, false
+	}
+	// 窗口按桶均分，不能小于桶数量
+	if opts.window < breakerBuckets {
+		opts.window = 10 * time.Second
+	}
+	if opts.minCalls < 1 {
+		opts.minCalls = 1
+	}
+	if opts.halfOpenCalls < 1 {
+		opts.halfOpenCalls = 1
+	}
+	return opts, true
+}
+
+// breakerOf 返回BackendService所属上游的熔断器；上游为服务的RemoteHost，未声明时为ServiceId
+func breakerOf(service flux.BackendService) (*circuitBreaker, bool) {
+	opts, ok := breakerOptionsOf(service)
+	if !ok {
+		return nil, false
+	}
+	upstream := service.RemoteHost
+	if "" == upstream {
+		upstream = service.ServiceID()
+	}
+	key := fmt.Sprintf("%s#%v", upstream, opts)
+	if v, ok := breakers.Load(key); ok {
+		return v.(*circuitBreaker), true
+	}
+	v, _ := breakers.LoadOrStore(key, newCircuitBreaker(upstream, opts))
+	return v.(*circuitBreaker), true
+}
+
+type breakerBucket struct {
+	start    int64
+	calls    int
+	failures int
+	slows    int
+}
+
+// circuitBreaker 熔断器：关闭状态下按滑动窗口统计失败率和慢调用率，达到阈值时打开；
+// 打开状态持续 openDuration 后进入半开状态，放行有限的探测调用，全部成功后关闭，任一失败或慢调用时重新打开
+type circuitBreaker struct {
+	upstream string
+	opts     breakerOptions
+	mu       sync.Mutex
+	state    int
+	openedAt time.Time
+	probes   int
+	passed   int
+	buckets  [breakerBuckets]breakerBucket
+}
+
+func newCircuitBreaker(upstream string, opts breakerOptions) *circuitBreaker {
+	return &circuitBreaker{upstream: upstream, opts: opts}
+}
+
+// allow 返回是否放行调用
+func (b *circuitBreaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if now.Sub(b.openedAt) < b.opts.openDuration {
+			return false
+		}
+		b.transit(breakerHalfOpen)
+		b.probes, b.passed = 0, 0
+		fallthrough
+	case breakerHalfOpen:
+		if b.probes >= b.opts.halfOpenCalls {
+			return false
+		}
+		b.probes++
+	}
+	return true
+}
+
+// record 记录调用结果
+func (b *circuitBreaker) record(now time.Time, elapsed time.Duration, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	slow := b.opts.slowRate > 0 && elapsed >= b.opts.slowDuration
+	switch b.state {
+	case breakerOpen:
+		// 打开前放行的调用迟到的结果
+		return
+	case breakerHalfOpen:
+		if failed || slow {
+			b.open(now)
+			return
+		}
+		if b.passed++; b.passed >= b.opts.halfOpenCalls {
+			b.buckets = [breakerBuckets]breakerBucket{}
+			b.transit(breakerClosed)
+		}
+		return
+	}
+	size := int64(b.opts.window) / breakerBuckets
+	start := now.UnixNano() / size * size
+	bucket := &b.buckets[(start/size)%breakerBuckets]
+	if bucket.start != start {
+		*bucket = breakerBucket{start: start}
+	}
+	bucket.calls++
+	if failed {
+		bucket.failures++
+	}
+	if slow {
+		bucket.slows++
+	}
+	calls, failures, slows := 0, 0, 0
+	for _, item := range b.buckets {
+		if start-item.start < int64(b.opts.window) {
+			calls, failures, slows = calls+item.calls, failures+item.failures, slows+item.slows
+		}
+	}
+	if calls < b.opts.minCalls {
+		return
+	}
+	if (b.opts.failureRate > 0 && float64(failures*100) >= b.opts.failureRate*float64(calls)) ||
+		(b.opts.slowRate > 0 && float64(slows*100) >= b.opts.slowRate*float64(calls)) {
+		b.open(now)
+	}
+}
+
+func (b *circuitBreaker) open(now time.Time) {
+	b.openedAt = now
+	b.transit(breakerOpen)
+}
+
+func (b *circuitBreaker) transit(state int) {
+	logger.Infow("Backend circuit breaker state changed", "upstream", b.upstream,
+		"from", breakerStateNames[b.state], "to", breakerStateNames[state])
+	b.state = state
+}
+
+// breakerFailed 返回调用结果是否计为失败：5xx错误，或上游Http响应5xx；明确分类为网关内部错误的不计为上游失败
+func breakerFailed(resp interface{}, err *flux.ServeError) bool {
+	if nil != err {
+		return err.StatusCode >= http.StatusInternalServerError && flux.ErrorCategoryGateway != err.Category
+	}
+	if r, ok := resp.(*http.Response); ok {
+		return r.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// invokeWithBreaker 熔断器打开时直接返回503，否则调用并记录结果
+func invokeWithBreaker(breaker *circuitBreaker, invoke func() (interface{}, *flux.ServeError)) (interface{}, *flux.ServeError) {
+	if !breaker.allow(time.Now()) {
+		return nil, &flux.ServeError{
+			StatusCode: http.StatusServiceUnavailable,
+			ErrorCode:  flux.ErrorCodeGatewayCircuited,
+			Message:    flux.ErrorMessageBackendCircuitOpen,
+			Internal:   fmt.Errorf("circuit breaker is open, upstream: %s", breaker.upstream),
+			Category:   flux.ErrorCategoryGateway,
+		}
+	}
+	start := time.Now()
+	resp, err := invoke()
+	breaker.record(time.Now(), time.Since(start), breakerFailed(resp, err))
+	return resp, err
+}
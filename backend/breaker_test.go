@@ -0,0 +1,85 @@
+package backend
+
+import (
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBreakerOptionsOf(t *testing.T) {
+	assert := assert2.New(t)
+	service := flux.BackendService{}
+	_, ok := breakerOptionsOf(service)
+	assert.False(ok)
+	service.Extensions = map[string]interface{}{
+		ServiceExtKeyBreakerFailureRate: 50,
+		ServiceExtKeyBreakerMinCalls:    5,
+	}
+	opts, ok := breakerOptionsOf(service)
+	assert.True(ok)
+	assert.Equal(float64(50), opts.failureRate)
+	assert.Equal(5, opts.minCalls)
+	assert.Equal(10*time.Second, opts.window)
+	assert.Equal(3, opts.halfOpenCalls)
+}
+
+func TestCircuitBreaker_FailureRate(t *testing.T) {
+	assert := assert2.New(t)
+	breaker := newCircuitBreaker("test", breakerOptions{failureRate: 50, window: 10 * time.Second,
+		minCalls: 4, openDuration: time.Second, halfOpenCalls: 2})
+	now := time.Now()
+	failed := []bool{false, true, false, true}
+	for _, f := range failed {
+		assert.True(breaker.allow(now))
+		breaker.record(now, time.Millisecond, f)
+	}
+	// 失败率达到50%，熔断
+	assert.False(breaker.allow(now))
+	// 熔断持续时间后半开，只放行探测调用
+	now = now.Add(time.Second)
+	assert.True(breaker.allow(now))
+	assert.True(breaker.allow(now))
+	assert.False(breaker.allow(now))
+	breaker.record(now, time.Millisecond, false)
+	breaker.record(now, time.Millisecond, false)
+	assert.Equal(breakerClosed, breaker.state)
+	assert.True(breaker.allow(now))
+}
+
+func TestCircuitBreaker_SlowCall(t *testing.T) {
+	assert := assert2.New(t)
+	breaker := newCircuitBreaker("test", breakerOptions{slowRate: 60, slowDuration: 100 * time.Millisecond,
+		window: 10 * time.Second, minCalls: 5, openDuration: time.Second, halfOpenCalls: 1})
+	now := time.Now()
+	for _, elapsed := range []time.Duration{time.Second, time.Second, 0, time.Second, 0} {
+		breaker.record(now, elapsed, false)
+	}
+	assert.Equal(breakerOpen, breaker.state)
+	// 半开探测失败，重新熔断
+	now = now.Add(time.Second)
+	assert.True(breaker.allow(now))
+	breaker.record(now, time.Millisecond, true)
+	assert.Equal(breakerOpen, breaker.state)
+	assert.False(breaker.allow(now))
+}
+
+func TestInvokeWithBreaker(t *testing.T) {
+	assert := assert2.New(t)
+	breaker := newCircuitBreaker("test", breakerOptions{failureRate: 50, window: 10 * time.Second,
+		minCalls: 1, openDuration: time.Minute, halfOpenCalls: 1})
+	calls := 0
+	invoke := func() (interface{}, *flux.ServeError) {
+		calls++
+		return &http.Response{StatusCode: http.StatusBadGateway}, nil
+	}
+	_, err := invokeWithBreaker(breaker, invoke)
+	assert.Nil(err)
+	_, err = invokeWithBreaker(breaker, invoke)
+	assert.Equal(1, calls)
+	assert.Equal(http.StatusServiceUnavailable, err.StatusCode)
+	assert.Equal(flux.ErrorCodeGatewayCircuited, err.ErrorCode)
+	// 熔断错误不重试
+	assert.False(retryPolicy{statusCodes: map[int]bool{503: true}}.retryable(nil, err))
+}
@@ -0,0 +1,254 @@
+package backend
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"github.com/spf13/cast"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// Endpoint扩展属性：响应缓存的有效期，例如 "30s"；大于0时开启缓存。只缓存GET/HEAD请求的2xx响应，
+	// 缓存Key为Endpoint和解析后的参数值。缓存的非流式响应体对象由多个请求共享，Filter不应修改
+	EndpointExtKeyResponseCacheTTL = "response-cache-ttl"
+	// Endpoint扩展属性：每个Endpoint最多缓存的响应数量，超出时淘汰最久未使用的响应；默认1024
+	EndpointExtKeyResponseCacheSize = "response-cache-size"
+	// Endpoint扩展属性：可缓存的响应体最大字节数，超出时不缓存；默认1MB
+	EndpointExtKeyResponseCacheMaxBodySize = "response-cache-max-body-size"
+	// Endpoint扩展属性：缓存过期后仍保留的最长时间，例如 "1h"；大于0时开启 stale-while-error 模式：
+	// 上游调用失败（5xx）时，返回该缓存Key最近一次成功的响应，并添加 Warning 和 Age 响应头。适用于可容忍过期数据的读接口
+	EndpointExtKeyResponseCacheMaxStale = "response-cache-max-stale"
+)
+
+const (
+	// 过期缓存响应的Warning响应头
+	responseCacheStaleWarning = `111 - "Revalidation Failed"`
+)
+
+const (
+	defaultResponseCacheSize        = 1024
+	defaultResponseCacheMaxBodySize = 1 << 20
+)
+
+var (
+	// 响应缓存按Endpoint和缓存配置区分；配置变更后使用新的缓存实例
+	responseCaches sync.Map
+)
+
+// cachedResponse 缓存的解码后响应；stream为true时，body为读取的响应体字节，每次命中时返回新的Reader
+type cachedResponse struct {
+	key      string
+	code     int
+	headers  http.Header
+	body     interface{}
+	stream   bool
+	storedAt time.Time
+	expireAt time.Time
+}
+
+// responseCache 按LRU淘汰、带有效期的响应缓存
+type responseCache struct {
+	ttl         time.Duration
+	size        int
+	maxBodySize int64
+	maxStale    time.Duration
+	mu          sync.Mutex
+	entries     map[string]*list.Element
+	lru         *list.List
+}
+
+// responseCacheOf 返回请求使用的响应缓存和缓存Key；未开启缓存、非GET/HEAD请求，或参数解析失败时，返回false
+func responseCacheOf(ctx flux.Context) (*responseCache, string, bool) {
+	endpoint := ctx.Endpoint()
+	v, ok := endpoint.Ext(EndpointExtKeyResponseCacheTTL)
+	if !ok {
+		return nil, "", false
+	}
+	if _, override := upstreamOverrideOf(ctx); override {
+		return nil, "", false
+	}
+	ttl := cast.ToDuration(v)
+	if ttl <= 0 || (http.MethodGet != ctx.Method() && http.MethodHead != ctx.Method()) {
+		return nil, "", false
+	}
+	size := endpoint.ExtInt(EndpointExtKeyResponseCacheSize)
+	if size <= 0 {
+		size = defaultResponseCacheSize
+	}
+	maxBodySize := int64(endpoint.ExtInt(EndpointExtKeyResponseCacheMaxBodySize))
+	if maxBodySize <= 0 {
+		maxBodySize = defaultResponseCacheMaxBodySize
+	}
+	maxStale := cast.ToDuration(endpoint.ExtString(EndpointExtKeyResponseCacheMaxStale))
+	key, err := responseCacheKeyOf(endpoint.Service, ctx)
+	if nil != err {
+		logger.TraceContext(ctx).Warnw("Backend response cache, resolve key failed", "error", err)
+		return nil, "", false
+	}
+	id := fmt.Sprintf("%s:%s:%s:%s:%d:%d:%s", endpoint.HttpMethod, endpoint.HttpPattern, endpoint.Version, ttl, size, maxBodySize, maxStale)
+	if c, ok := responseCaches.Load(id); ok {
+		return c.(*responseCache), key, true
+	}
+	c, _ := responseCaches.LoadOrStore(id, &responseCache{
+		ttl:         ttl,
+		size:        size,
+		maxBodySize: maxBodySize,
+		maxStale:    maxStale,
+		entries:     make(map[string]*list.Element, size),
+		lru:         list.New(),
+	})
+	return c.(*responseCache), key, true
+}
+
+// responseCacheKeyOf 返回请求方法和解析后参数值的规范化JSON作为缓存Key；Map按Key排序编码
+func responseCacheKeyOf(service flux.BackendService, ctx flux.Context) (string, error) {
+	values, err := ResolveArgumentValues(service.Arguments, ctx)
+	if nil != err {
+		return "", err
+	}
+	data, err := json.Marshal(values)
+	if nil != err {
+		return "", err
+	}
+	return ctx.Method() + ":" + string(data), nil
+}
+
+// Load 返回未过期的缓存响应；流式响应体返回新的Reader
+func (c *responseCache) Load(key string) (int, http.Header, interface{}, bool) {
+	entry, ok := c.load(key, false)
+	if !ok {
+		return 0, nil, nil, false
+	}
+	return entry.code, entry.headers.Clone(), entry.bodyOf(), true
+}
+
+// LoadStale 返回最近一次成功的缓存响应，包括过期时间未超出 max-stale 的响应，以及响应已缓存的时长；用于上游调用失败时的降级响应
+func (c *responseCache) LoadStale(key string) (int, http.Header, interface{}, time.Duration, bool) {
+	entry, ok := c.load(key, true)
+	if !ok {
+		return 0, nil, nil, 0, false
+	}
+	return entry.code, entry.headers.Clone(), entry.bodyOf(), time.Since(entry.storedAt), true
+}
+
+// load 返回缓存响应；超出 max-stale 的响应被删除，过期但未超出 max-stale 的响应只在stale为true时返回
+func (c *responseCache) load(key string, stale bool) (*cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cachedResponse)
+	now := time.Now()
+	if now.After(entry.expireAt.Add(c.maxStale)) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	if !stale && now.After(entry.expireAt) {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return entry, true
+}
+
+func (e *cachedResponse) bodyOf() interface{} {
+	if e.stream {
+		return ioutil.NopCloser(bytes.NewReader(e.body.([]byte)))
+	}
+	return e.body
+}
+
+// Store 缓存2xx响应，返回调用方应继续使用的响应体：流式响应体被读取后替换为新的Reader；
+// 响应体超出大小限制或读取失败时不缓存，已读取的数据与剩余数据拼接返回
+func (c *responseCache) Store(key string, code int, headers http.Header, body interface{}) interface{} {
+	if code < http.StatusOK || code >= http.StatusMultipleChoices {
+		return body
+	}
+	now := time.Now()
+	entry := &cachedResponse{key: key, code: code, headers: headers.Clone(), body: body, storedAt: now, expireAt: now.Add(c.ttl)}
+	if reader, ok := body.(io.Reader); ok {
+		data, err := ioutil.ReadAll(io.LimitReader(reader, c.maxBodySize+1))
+		if nil != err || int64(len(data)) > c.maxBodySize {
+			return struct {
+				io.Reader
+				io.Closer
+			}{Reader: io.MultiReader(bytes.NewReader(data), reader), Closer: closerOf(reader)}
+		}
+		if closer, ok := reader.(io.Closer); ok {
+			_ = closer.Close()
+		}
+		entry.body, entry.stream = data, true
+		body = ioutil.NopCloser(bytes.NewReader(data))
+	} else if size, err := bodySizeOf(body); nil != err || size > c.maxBodySize {
+		return body
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.lru.Remove(elem)
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedResponse).key)
+	}
+	return body
+}
+
+// ServeStaleResponse 使用响应缓存中最近一次成功的响应（包括过期但未超出 max-stale 的响应）作为降级响应，并添加 Warning 和 Age 响应头；
+// 用于熔断、限流等无法调用上游的场景。Endpoint未开启响应缓存、流式转发，或者没有可用的缓存响应时，返回false
+func ServeStaleResponse(ctx flux.Context) bool {
+	if ctx.Endpoint().ExtBool(EndpointExtKeyStreamResponse) {
+		return false
+	}
+	cache, key, ok := responseCacheOf(ctx)
+	return ok && serveStale(ctx, cache, key)
+}
+
+// serveStale 写入缓存的响应；没有可用的缓存响应时返回false
+func serveStale(ctx flux.Context, cache *responseCache, key string) bool {
+	code, headers, body, age, ok := cache.LoadStale(key)
+	if !ok {
+		return false
+	}
+	ctx.Response().SetStatusCode(code)
+	ctx.Response().SetHeaders(FilterResponseHeaders(ctx.Endpoint(), headers))
+	ctx.Response().SetHeader(flux.HeaderWarning, responseCacheStaleWarning)
+	ctx.Response().SetHeader(flux.HeaderAge, strconv.Itoa(int(age.Seconds())))
+	ctx.Response().SetBody(body)
+	return true
+}
+
+// bodySizeOf 返回非流式响应体的字节数：字节数组和字符串按长度计算，其它对象按JSON编码后的长度计算
+func bodySizeOf(body interface{}) (int64, error) {
+	switch b := body.(type) {
+	case nil:
+		return 0, nil
+	case []byte:
+		return int64(len(b)), nil
+	case string:
+		return int64(len(b)), nil
+	default:
+		data, err := json.Marshal(body)
+		return int64(len(data)), err
+	}
+}
+
+func closerOf(reader io.Reader) io.Closer {
+	if closer, ok := reader.(io.Closer); ok {
+		return closer
+	}
+	return ioutil.NopCloser(nil)
+}
@@ -0,0 +1,147 @@
+package backend
+
+import (
+	"bytes"
+	"container/list"
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestResponseCache(ttl time.Duration, size int, maxBodySize int64) *responseCache {
+	return &responseCache{ttl: ttl, size: size, maxBodySize: maxBodySize, entries: make(map[string]*list.Element), lru: list.New()}
+}
+
+func TestResponseCache_LoadStore(t *testing.T) {
+	assert := assert2.New(t)
+	cache := newTestResponseCache(time.Minute, 2, 8)
+	// 流式响应体被读取后缓存，每次命中返回新的Reader
+	body := cache.Store("a", http.StatusOK, http.Header{"X-Id": {"a"}}, ioutil.NopCloser(strings.NewReader("hello")))
+	data, _ := ioutil.ReadAll(body.(io.Reader))
+	assert.Equal("hello", string(data))
+	for i := 0; i < 2; i++ {
+		code, headers, cached, ok := cache.Load("a")
+		assert.True(ok)
+		assert.Equal(http.StatusOK, code)
+		assert.Equal("a", headers.Get("X-Id"))
+		data, _ := ioutil.ReadAll(cached.(io.Reader))
+		assert.Equal("hello", string(data))
+	}
+	// 超出大小限制时不缓存，响应体保持完整
+	body = cache.Store("large", http.StatusOK, nil, bytes.NewReader([]byte("0123456789")))
+	data, _ = ioutil.ReadAll(body.(io.Reader))
+	assert.Equal("0123456789", string(data))
+	_, _, _, ok := cache.Load("large")
+	assert.False(ok)
+	// 非2xx响应不缓存
+	cache.Store("error", http.StatusBadGateway, nil, "error")
+	_, _, _, ok = cache.Load("error")
+	assert.False(ok)
+	// 超出数量时淘汰最久未使用的响应
+	cache.Store("b", http.StatusOK, nil, map[string]interface{}{"id": 1})
+	cache.Load("a")
+	cache.Store("c", http.StatusOK, nil, "c")
+	_, _, _, ok = cache.Load("b")
+	assert.False(ok)
+	_, _, _, ok = cache.Load("a")
+	assert.True(ok)
+	// 过期的响应不返回
+	expired := newTestResponseCache(time.Millisecond, 2, 8)
+	expired.Store("a", http.StatusOK, nil, "a")
+	time.Sleep(5 * time.Millisecond)
+	_, _, _, ok = expired.Load("a")
+	assert.False(ok)
+}
+
+func TestResponseCache_LoadStale(t *testing.T) {
+	assert := assert2.New(t)
+	cache := newTestResponseCache(time.Millisecond, 2, 8)
+	cache.maxStale = time.Minute
+	cache.Store("a", http.StatusOK, http.Header{"X-Id": {"a"}}, ioutil.NopCloser(strings.NewReader("hello")))
+	time.Sleep(5 * time.Millisecond)
+	// 过期的响应不作为新鲜响应返回，但在 max-stale 内作为过期响应返回
+	_, _, _, ok := cache.Load("a")
+	assert.False(ok)
+	code, headers, body, age, ok := cache.LoadStale("a")
+	assert.True(ok)
+	assert.Equal(http.StatusOK, code)
+	assert.Equal("a", headers.Get("X-Id"))
+	assert.True(age >= 5*time.Millisecond)
+	data, _ := ioutil.ReadAll(body.(io.Reader))
+	assert.Equal("hello", string(data))
+	// 超出 max-stale 的响应被删除
+	cache.maxStale = time.Millisecond
+	_, _, _, _, ok = cache.LoadStale("a")
+	assert.False(ok)
+	assert.Equal(0, cache.lru.Len())
+}
+
+type cacheTestResponse struct {
+	status int
+	header http.Header
+	body   interface{}
+}
+
+func (r *cacheTestResponse) SetStatusCode(status int)       { r.status = status }
+func (r *cacheTestResponse) StatusCode() int                { return r.status }
+func (r *cacheTestResponse) HeaderValues() http.Header      { return r.header }
+func (r *cacheTestResponse) AddHeader(name, value string)   { r.header.Add(name, value) }
+func (r *cacheTestResponse) SetHeader(name, value string)   { r.header.Set(name, value) }
+func (r *cacheTestResponse) SetHeaders(headers http.Header) { r.header = headers }
+func (r *cacheTestResponse) SetBody(body interface{})       { r.body = body }
+func (r *cacheTestResponse) Body() interface{}              { return r.body }
+
+type cacheTestContext struct {
+	hedgeTestContext
+	response *cacheTestResponse
+}
+
+func (c *cacheTestContext) Response() flux.ResponseWriter { return c.response }
+
+func TestServeStaleResponse(t *testing.T) {
+	assert := assert2.New(t)
+	endpoint := flux.Endpoint{HttpMethod: http.MethodGet, HttpPattern: "/stale"}
+	endpoint.Extensions = map[string]interface{}{EndpointExtKeyResponseCacheTTL: "1ms", EndpointExtKeyResponseCacheMaxStale: "1m"}
+	newContext := func(method string) *cacheTestContext {
+		return &cacheTestContext{
+			hedgeTestContext: hedgeTestContext{method: method, endpoint: endpoint, values: map[string]interface{}{}},
+			response:         &cacheTestResponse{header: http.Header{}},
+		}
+	}
+	ctx := newContext(http.MethodGet)
+	// 没有缓存的响应时不降级
+	assert.False(ServeStaleResponse(ctx))
+	cache, key, ok := responseCacheOf(ctx)
+	assert.True(ok)
+	cache.Store(key, http.StatusOK, http.Header{"X-Id": {"a"}}, "cached")
+	time.Sleep(5 * time.Millisecond)
+	assert.True(ServeStaleResponse(ctx))
+	assert.Equal(http.StatusOK, ctx.response.status)
+	assert.Equal("cached", ctx.response.body)
+	assert.Equal(responseCacheStaleWarning, ctx.response.header.Get(flux.HeaderWarning))
+	assert.Equal("0", ctx.response.header.Get(flux.HeaderAge))
+	// 非GET/HEAD请求不使用缓存的响应
+	assert.False(ServeStaleResponse(newContext(http.MethodPost)))
+}
+
+func TestResponseCacheOf(t *testing.T) {
+	assert := assert2.New(t)
+	endpoint := flux.Endpoint{HttpMethod: http.MethodGet, HttpPattern: "/cache"}
+	endpoint.Extensions = map[string]interface{}{EndpointExtKeyResponseCacheTTL: "10s"}
+	cache, key, ok := responseCacheOf(&hedgeTestContext{method: http.MethodGet, endpoint: endpoint})
+	assert.True(ok)
+	assert.Equal(10*time.Second, cache.ttl)
+	assert.Equal(defaultResponseCacheSize, cache.size)
+	assert.Equal("GET:{}", key)
+	same, _, _ := responseCacheOf(&hedgeTestContext{method: http.MethodGet, endpoint: endpoint})
+	assert.True(cache == same)
+	_, _, ok = responseCacheOf(&hedgeTestContext{method: http.MethodPost, endpoint: endpoint})
+	assert.False(ok)
+	_, _, ok = responseCacheOf(&hedgeTestContext{method: http.MethodGet})
+	assert.False(ok)
+}
@@ -0,0 +1,130 @@
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+const (
+	// Endpoint扩展属性：合并相同的并发请求。同一Endpoint、相同参数值的GET/HEAD请求在上游调用返回前，
+	// 只发起一次上游调用，其它请求等待并共享调用结果；用于在缓存失效时保护上游服务。
+	// 共享的流式响应体被完整读取后分别返回给每个请求，非流式响应体对象由多个请求共享，Filter不应修改
+	EndpointExtKeyCoalesce = "coalesce"
+)
+
+var (
+	coalesceCalls = &coalesceGroup{calls: make(map[string]*coalesceCall, 16)}
+)
+
+type exchangeResult struct {
+	code    int
+	headers http.Header
+	body    interface{}
+	err     *flux.ServeError
+}
+
+// coalesceCall 正在执行的上游调用；stream为true时，body为读取的响应体字节
+type coalesceCall struct {
+	done    chan struct{}
+	result  exchangeResult
+	stream  bool
+	waiters int
+}
+
+// coalesceGroup 按Key合并并发调用
+type coalesceGroup struct {
+	mu    sync.Mutex
+	calls map[string]*coalesceCall
+}
+
+// coalesceKeyOf 返回请求的合并Key；未开启合并、非GET/HEAD请求、流式转发或流式JSON数组输出，或参数解析失败时，返回false
+func coalesceKeyOf(ctx flux.Context, argsKey string) (string, bool) {
+	endpoint := ctx.Endpoint()
+	if !endpoint.ExtBool(EndpointExtKeyCoalesce) || endpoint.ExtBool(EndpointExtKeyStreamResponse) || endpoint.ExtBool(EndpointExtKeyStreamJSONArray) {
+		return "", false
+	}
+	if http.MethodGet != ctx.Method() && http.MethodHead != ctx.Method() {
+		return "", false
+	}
+	if _, override := upstreamOverrideOf(ctx); override {
+		return "", false
+	}
+	if "" == argsKey {
+		key, err := responseCacheKeyOf(endpoint.Service, ctx)
+		if nil != err {
+			logger.TraceContext(ctx).Warnw("Backend coalesce, resolve key failed", "error", err)
+			return "", false
+		}
+		argsKey = key
+	}
+	return fmt.Sprintf("%s#%s#%s|%s", endpoint.HttpMethod, endpoint.HttpPattern, endpoint.Version, argsKey), true
+}
+
+// Do 执行调用；相同Key的调用正在执行时，等待并返回其结果。等待期间请求被取消时，返回超时错误
+func (g *coalesceGroup) Do(ctx flux.Context, key string, fn func() exchangeResult) exchangeResult {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		call.waiters++
+		g.mu.Unlock()
+		select {
+		case <-call.done:
+			logger.TraceContext(ctx).Debugw("Backend coalesced call shared", "key", key)
+			return call.shared()
+		case <-ctx.Context().Done():
+			return exchangeResult{err: &flux.ServeError{
+				StatusCode: flux.StatusGatewayTimeout,
+				ErrorCode:  flux.ErrorCodeGatewayTimeout,
+				Message:    flux.ErrorMessageBackendInvokeTimeout,
+				Internal:   ctx.Context().Err(),
+			}}
+		}
+	}
+	call := &coalesceCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	result := fn()
+	g.mu.Lock()
+	delete(g.calls, key)
+	waiters := call.waiters
+	g.mu.Unlock()
+	// 有等待的请求时，读取流式响应体用于共享
+	if reader, ok := result.body.(io.Reader); ok && waiters > 0 && nil == result.err {
+		data, err := ioutil.ReadAll(reader)
+		if c, ok := reader.(io.Closer); ok {
+			_ = c.Close()
+		}
+		if nil != err {
+			result = exchangeResult{err: &flux.ServeError{
+				StatusCode: flux.StatusBadGateway,
+				ErrorCode:  flux.ErrorCodeGatewayBackend,
+				Message:    flux.ErrorMessageBackendDecodeResponse,
+				Internal:   err,
+			}}
+		} else {
+			result.body, call.stream = data, true
+		}
+	}
+	call.result = result
+	close(call.done)
+	if waiters > 0 {
+		return call.shared()
+	}
+	return result
+}
+
+// shared 返回调用结果的副本：Header被复制，流式响应体返回新的Reader
+func (c *coalesceCall) shared() exchangeResult {
+	result := c.result
+	result.headers = result.headers.Clone()
+	if c.stream {
+		result.body = ioutil.NopCloser(bytes.NewReader(c.result.body.([]byte)))
+	}
+	return result
+}
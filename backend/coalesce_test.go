@@ -0,0 +1,68 @@
+package backend
+
+import (
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCoalesceGroup_Do(t *testing.T) {
+	assert := assert2.New(t)
+	group := &coalesceGroup{calls: make(map[string]*coalesceCall)}
+	var invoked int32
+	waiting := func() int {
+		group.mu.Lock()
+		defer group.mu.Unlock()
+		if call, ok := group.calls["k"]; ok {
+			return call.waiters
+		}
+		return 0
+	}
+	fn := func() exchangeResult {
+		atomic.AddInt32(&invoked, 1)
+		// 等待其它请求加入后返回
+		for waiting() < 2 {
+			time.Sleep(time.Millisecond)
+		}
+		return exchangeResult{code: http.StatusOK, headers: http.Header{"X-Id": {"1"}}, body: ioutil.NopCloser(strings.NewReader("shared"))}
+	}
+	var wg sync.WaitGroup
+	bodies := make([]string, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result := group.Do(&hedgeTestContext{method: http.MethodGet}, "k", fn)
+			data, _ := ioutil.ReadAll(result.body.(io.Reader))
+			bodies[i] = string(data) + result.headers.Get("X-Id")
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(int32(1), atomic.LoadInt32(&invoked))
+	assert.Equal([]string{"shared1", "shared1", "shared1"}, bodies)
+	// 调用结束后，相同Key的请求重新调用
+	result := group.Do(&hedgeTestContext{method: http.MethodGet}, "k", func() exchangeResult {
+		return exchangeResult{code: http.StatusOK, body: "alone"}
+	})
+	assert.Equal("alone", result.body)
+}
+
+func TestCoalesceKeyOf(t *testing.T) {
+	assert := assert2.New(t)
+	endpoint := flux.Endpoint{HttpMethod: http.MethodGet, HttpPattern: "/coalesce"}
+	endpoint.Extensions = map[string]interface{}{EndpointExtKeyCoalesce: true}
+	key, ok := coalesceKeyOf(&hedgeTestContext{method: http.MethodGet, endpoint: endpoint}, "")
+	assert.True(ok)
+	assert.Equal("GET#/coalesce#|GET:{}", key)
+	_, ok = coalesceKeyOf(&hedgeTestContext{method: http.MethodPost, endpoint: endpoint}, "")
+	assert.False(ok)
+	_, ok = coalesceKeyOf(&hedgeTestContext{method: http.MethodGet}, "")
+	assert.False(ok)
+}
@@ -0,0 +1,22 @@
+package compose
+
+import (
+	"errors"
+	"github.com/bytepowered/flux"
+	"net/http"
+)
+
+var (
+	ErrUnknownComposeBackendResponse = errors.New("BACKEND:UNKNOWN_COMPOSE_RESPONSE")
+)
+
+// NewComposeBackendTransportDecodeFunc 返回合并后的JSON响应
+func NewComposeBackendTransportDecodeFunc() flux.BackendTransportDecodeFunc {
+	return func(ctx flux.Context, value interface{}) (statusCode int, headers http.Header, body interface{}, err error) {
+		resp, ok := value.(*Response)
+		if !ok {
+			return http.StatusInternalServerError, http.Header{}, nil, ErrUnknownComposeBackendResponse
+		}
+		return resp.StatusCode, resp.Header, resp.Body, nil
+	}
+}
@@ -0,0 +1,11 @@
+package compose
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+)
+
+func init() {
+	ext.StoreBackendTransport(flux.ProtoCompose, NewComposeBackendTransport())
+	ext.StoreBackendTransportDecodeFunc(flux.ProtoCompose, NewComposeBackendTransportDecodeFunc())
+}
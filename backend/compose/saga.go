@@ -0,0 +1,92 @@
+package compose
+
+import (
+	"context"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/pkg"
+	"time"
+)
+
+// OutboxEvent 编排部分失败事件：必需的步骤失败时，已完成的步骤可能已产生副作用；
+// 事件记录各步骤的完成和补偿状态，由下游（例如对账任务）处理补偿失败或未定义补偿的步骤
+type OutboxEvent struct {
+	RequestId        string    `json:"requestId"`
+	HttpMethod       string    `json:"httpMethod"`
+	HttpPattern      string    `json:"httpPattern"`
+	ServiceId        string    `json:"serviceId"`
+	FailedStep       string    `json:"failedStep"`
+	Error            string    `json:"error"`
+	Completed        []string  `json:"completed"`
+	Compensated      []string  `json:"compensated"`
+	CompensateFailed []string  `json:"compensateFailed"`
+	Uncompensated    []string  `json:"uncompensated"`
+	CreatedAt        time.Time `json:"createdAt"`
+}
+
+// OutboxPublisher 发布编排部分失败事件，例如写入消息队列或数据库Outbox表
+type OutboxPublisher interface {
+	Publish(event OutboxEvent) error
+}
+
+// OutboxPublisherFunc 函数形式的 OutboxPublisher
+type OutboxPublisherFunc func(event OutboxEvent) error
+
+func (f OutboxPublisherFunc) Publish(event OutboxEvent) error {
+	return f(event)
+}
+
+var (
+	outboxPublisher OutboxPublisher = OutboxPublisherFunc(func(event OutboxEvent) error {
+		logger.Warnw("BACKEND:COMPOSE:OUTBOX", "event", event)
+		return nil
+	})
+)
+
+// SetOutboxPublisher 设置编排部分失败事件的发布者；默认输出到日志。需要在网关启动前调用
+func SetOutboxPublisher(publisher OutboxPublisher) {
+	outboxPublisher = pkg.RequireNotNil(publisher, "OutboxPublisher is nil").(OutboxPublisher)
+}
+
+// compensate 按完成顺序的逆序调用已完成步骤的补偿服务，并发布部分失败事件；没有已完成的步骤时不处理。
+// 补偿调用不受原请求取消的影响，使用独立的超时；沿用原请求绑定的扩展组件注册表。
+func (b *BackendTransportService) compensate(ctx flux.Context, spec *Spec, service flux.BackendService,
+	completed []Step, results map[string]interface{}, failed Step, cause *flux.ServeError) {
+	if 0 == len(completed) {
+		return
+	}
+	endpoint := ctx.Endpoint()
+	event := OutboxEvent{
+		RequestId:   ctx.RequestId(),
+		HttpMethod:  endpoint.HttpMethod,
+		HttpPattern: endpoint.HttpPattern,
+		ServiceId:   service.ServiceID(),
+		FailedStep:  failed.Name,
+		Error:       cause.Error(),
+		Completed:   make([]string, 0, len(completed)),
+		CreatedAt:   time.Now(),
+	}
+	goctx, cancel := context.WithTimeout(ext.WithRegistry(context.Background(), ext.RegistryOf(ctx.Context())), spec.CompensateTimeout)
+	defer cancel()
+	for i := len(completed) - 1; i >= 0; i-- {
+		step := completed[i]
+		event.Completed = append(event.Completed, step.Name)
+		if nil == step.Compensate {
+			event.Uncompensated = append(event.Uncompensated, step.Name)
+			continue
+		}
+		out := b.invokeService(ctx, goctx, step.Name+"-Compensate", step.Compensate.Service, step.Compensate.Values, results)
+		mergeMetrics(out.ctx)
+		if nil != out.err {
+			logger.TraceContext(ctx).Warnw("BACKEND:COMPOSE:COMPENSATE_FAILED",
+				"step", step.Name, "service-id", step.Compensate.Service, "error", out.err)
+			event.CompensateFailed = append(event.CompensateFailed, step.Name)
+		} else {
+			event.Compensated = append(event.Compensated, step.Name)
+		}
+	}
+	if err := outboxPublisher.Publish(event); nil != err {
+		logger.TraceContext(ctx).Errorw("BACKEND:COMPOSE:OUTBOX_PUBLISH", "event", event, "error", err)
+	}
+}
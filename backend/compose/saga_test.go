@@ -0,0 +1,49 @@
+package compose
+
+import (
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBackendTransportService_Compensate(t *testing.T) {
+	assert := assert2.New(t)
+	transport := &composeTestTransport{
+		responses: map[string]interface{}{
+			"user:invoke":  map[string]interface{}{"id": "u-1"},
+			"order:invoke": map[string]interface{}{"id": "o-1"},
+		},
+		failures: map[string]bool{"pay:invoke": true},
+	}
+	spec := map[string]interface{}{
+		"sequential": true,
+		"steps": []interface{}{
+			map[string]interface{}{"name": "user", "service": "user:invoke",
+				"compensate": map[string]interface{}{"service": "user-cancel:invoke", "values": map[string]string{"userId": "user.id"}}},
+			map[string]interface{}{"name": "order", "service": "order:invoke"},
+			map[string]interface{}{"name": "pay", "service": "pay:invoke"},
+		},
+	}
+	events := make(chan OutboxEvent, 1)
+	SetOutboxPublisher(OutboxPublisherFunc(func(event OutboxEvent) error {
+		events <- event
+		return nil
+	}))
+	ctx := newComposeTestContext(spec, transport, "user", "order", "pay", "user-cancel")
+	_, serr := NewComposeBackendTransport().Invoke(flux.BackendService{}, ctx)
+	if assert.NotNil(serr) {
+		assert.Equal(flux.ErrorCodeGatewayBackend, serr.GetErrorCode())
+	}
+	assert.Equal([]string{"user:invoke", "order:invoke", "pay:invoke", "user-cancel:invoke"}, transport.calls)
+	assert.Equal("u-1", transport.values["user-cancel:invoke"])
+	select {
+	case event := <-events:
+		assert.Equal("req-1", event.RequestId)
+		assert.Equal("pay", event.FailedStep)
+		assert.Equal([]string{"order", "user"}, event.Completed)
+		assert.Equal([]string{"user"}, event.Compensated)
+		assert.Equal([]string{"order"}, event.Uncompensated)
+	default:
+		assert.Fail("outbox event not published")
+	}
+}
@@ -0,0 +1,257 @@
+package compose
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/backend"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/support"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	_ flux.BackendTransport = new(BackendTransportService)
+)
+
+// Response 组合编排的响应：Body为合并后的JSON对象
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       interface{}
+}
+
+type stepResult struct {
+	ctx   *backend.OverlayContext
+	value interface{}
+	err   *flux.ServeError
+}
+
+// BackendTransportService 组合编排的BackendService：一个Endpoint按编排定义调用多个后端服务，
+// 按响应映射将各服务的响应合并为一个JSON对象。步骤的响应按服务协议和Content-Type解码后，以JSON结构参与映射。
+type BackendTransportService struct {
+}
+
+// NewComposeBackendTransport New compose backend instance
+func NewComposeBackendTransport() flux.BackendTransport {
+	return &BackendTransportService{}
+}
+
+// Exchange do exchange with context
+func (b *BackendTransportService) Exchange(ctx flux.Context) *flux.ServeError {
+	return backend.DoExchange(ctx, b)
+}
+
+// Invoke 按依赖关系分层调用步骤，同一层的步骤并行调用；必需的步骤失败时取消其它调用并返回错误
+func (b *BackendTransportService) Invoke(service flux.BackendService, ctx flux.Context) (interface{}, *flux.ServeError) {
+	v, ok := extOf(ctx.Endpoint(), service, ExtKeyCompose)
+	if !ok {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageComposeSpecInvalid,
+			Internal:   errors.New("compose spec is required, ext: " + ExtKeyCompose),
+		}
+	}
+	spec, levels, err := specOf(v)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageComposeSpecInvalid,
+			Internal:   err,
+		}
+	}
+	goctx, cancel := context.WithCancel(ctx.Context())
+	defer cancel()
+	results := make(map[string]interface{}, len(spec.Steps))
+	completed := make([]Step, 0, len(spec.Steps))
+	for _, level := range levels {
+		outs := make([]stepResult, len(level))
+		var wg sync.WaitGroup
+		for i, step := range level {
+			wg.Add(1)
+			go func(i int, step Step) {
+				defer wg.Done()
+				outs[i] = b.invokeStep(ctx, goctx, step, results)
+				if nil != outs[i].err && !step.Optional {
+					cancel()
+				}
+			}(i, step)
+		}
+		wg.Wait()
+		var failed *Step
+		var cause *flux.ServeError
+		errs := new(flux.MultiError)
+		for i, out := range outs {
+			step := level[i]
+			mergeMetrics(out.ctx)
+			if nil == out.err {
+				results[step.Name] = out.value
+				completed = append(completed, step)
+				continue
+			}
+			if step.Optional {
+				logger.TraceContext(ctx).Warnw("BACKEND:COMPOSE:OPTIONAL_STEP_FAILED",
+					"step", step.Name, "service-id", step.Service, "error", out.err)
+				results[step.Name] = nil
+			} else {
+				errs.Append(flux.NewFieldError(step.Name, "", out.err))
+				if nil == failed {
+					failed, cause = &level[i], out.err
+				}
+			}
+		}
+		if nil != failed {
+			b.compensate(ctx, spec, service, completed, results, *failed, cause)
+			if len(errs.Errors) > 1 {
+				// 同一层多个必需步骤失败：返回第一个失败步骤的错误，内部错误聚合全部失败步骤
+				aggregated := *cause
+				aggregated.Internal = errs
+				return nil, &aggregated
+			}
+			return nil, cause
+		}
+	}
+	return &Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{flux.HeaderContentType: []string{flux.MIMEApplicationJSONCharsetUTF8}},
+		Body:       merge(spec.Mappings, results),
+	}, nil
+}
+
+// invokeStep 调用步骤的后端服务，返回解码后的JSON响应
+func (b *BackendTransportService) invokeStep(ctx flux.Context, goctx context.Context, step Step, results map[string]interface{}) stepResult {
+	return b.invokeService(ctx, goctx, step.Name, step.Service, step.Values, results)
+}
+
+// invokeService 调用后端服务，返回解码后的JSON响应；已完成步骤的响应字段按 values 定义写入调用的Context Value
+func (b *BackendTransportService) invokeService(ctx flux.Context, goctx context.Context, name, serviceId string,
+	values map[string]string, results map[string]interface{}) stepResult {
+	service, ok := ext.RegistryOf(ctx.Context()).LoadBackendService(serviceId)
+	if !ok {
+		return stepResult{err: &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageComposeServiceNotFound,
+			Internal:   fmt.Errorf("compose step: %s, service-id: %s", name, serviceId),
+		}}
+	}
+	// 步骤使用叠加的Context：替换后端服务，Attribute、Value和Metric写入本地，避免并行的步骤修改原Context
+	sctx := backend.NewOverlayContext(ctx, goctx).WithService(service)
+	for key, path := range values {
+		sctx.SetValue(key, support.JSONPathValue(results, path))
+	}
+	start := time.Now()
+	defer func() {
+		sctx.AddMetric("M-Compose-"+name, time.Since(start))
+	}()
+	resp, serr := backend.DoInvoke(service, sctx)
+	if nil != serr {
+		return stepResult{ctx: sctx, err: serr}
+	}
+	code, _, body, serr := backend.DoDecode(service, sctx, resp)
+	if nil != serr {
+		return stepResult{ctx: sctx, err: serr}
+	}
+	value, err := jsonValueOf(body)
+	if nil == err && code >= http.StatusBadRequest {
+		err = fmt.Errorf("upstream status: %d, body: %v", code, value)
+	}
+	if nil != err {
+		return stepResult{ctx: sctx, err: &flux.ServeError{
+			StatusCode: flux.StatusBadGateway,
+			ErrorCode:  flux.ErrorCodeGatewayBackend,
+			Message:    flux.ErrorMessageComposeStepFailed,
+			Internal:   fmt.Errorf("compose step: %s, %w", name, err),
+		}}
+	}
+	return stepResult{ctx: sctx, value: value}
+}
+
+// mergeMetrics 将调用步骤的Metric合并到原Context
+func mergeMetrics(sctx *backend.OverlayContext) {
+	if nil != sctx {
+		sctx.CommitMetrics()
+	}
+}
+
+// jsonValueOf 将解码后的响应转换为JSON结构；非JSON数据转换为字符串
+func jsonValueOf(body interface{}) (interface{}, error) {
+	var data []byte
+	switch v := body.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}, []interface{}:
+		return v, nil
+	case io.Reader:
+		if c, ok := v.(io.Closer); ok {
+			defer c.Close()
+		}
+		read, err := ioutil.ReadAll(v)
+		if nil != err {
+			return nil, err
+		}
+		data = read
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		marshaled, err := json.Marshal(v)
+		if nil != err {
+			return nil, err
+		}
+		data = marshaled
+	}
+	if 0 == len(bytes.TrimSpace(data)) {
+		return nil, nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); nil != err {
+		return string(data), nil
+	}
+	return value, nil
+}
+
+// merge 按响应映射合并步骤的响应；未定义映射时返回 {步骤名称: 响应}
+func merge(mappings map[string]string, results map[string]interface{}) interface{} {
+	if 0 == len(mappings) {
+		return results
+	}
+	// 按字段路径排序，使上层字段先于下层字段写入
+	keys := make([]string, 0, len(mappings))
+	for key := range mappings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	out := make(map[string]interface{}, len(mappings))
+	for _, key := range keys {
+		setPath(out, key, support.JSONPathValue(results, mappings[key]))
+	}
+	return out
+}
+
+// setPath 按点号分隔的字段路径写入值，自动创建中间对象
+func setPath(out map[string]interface{}, path string, value interface{}) {
+	names := strings.Split(path, ".")
+	for _, name := range names[:len(names)-1] {
+		next, ok := out[name].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{}, 4)
+			out[name] = next
+		}
+		out = next
+	}
+	out[names[len(names)-1]] = value
+}
@@ -0,0 +1,154 @@
+package compose
+
+import (
+	"context"
+	"errors"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testComposeProto = "COMPOSE-TEST"
+)
+
+type fluxContext = flux.Context
+
+type composeTestContext struct {
+	fluxContext
+	goctx    context.Context
+	endpoint flux.Endpoint
+	mu       sync.Mutex
+	values   map[string]interface{}
+	metrics  []string
+}
+
+func (c *composeTestContext) RequestId() string        { return "req-1" }
+func (c *composeTestContext) Endpoint() flux.Endpoint  { return c.endpoint }
+func (c *composeTestContext) Context() context.Context { return c.goctx }
+func (c *composeTestContext) GetContextLogger() (flux.Logger, bool) {
+	return logger.SimpleLogger(), true
+}
+func (c *composeTestContext) GetAttribute(string) (interface{}, bool) {
+	return nil, false
+}
+func (c *composeTestContext) GetValue(name string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.values[name]
+	return v, ok
+}
+func (c *composeTestContext) AddMetric(name string, _ time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.metrics = append(c.metrics, name)
+}
+
+// composeTestTransport 按ServiceId返回响应，记录调用顺序和调用时的Context Value
+type composeTestTransport struct {
+	mu        sync.Mutex
+	responses map[string]interface{}
+	failures  map[string]bool
+	calls     []string
+	values    map[string]interface{}
+}
+
+func (b *composeTestTransport) Exchange(flux.Context) *flux.ServeError {
+	return nil
+}
+
+func (b *composeTestTransport) Invoke(service flux.BackendService, ctx flux.Context) (interface{}, *flux.ServeError) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := service.ServiceID()
+	b.calls = append(b.calls, id)
+	if v, ok := ctx.GetValue("userId"); ok {
+		b.values[id] = v
+	}
+	if b.failures[id] {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusBadGateway,
+			ErrorCode:  flux.ErrorCodeGatewayBackend,
+			Internal:   errors.New("failed: " + id),
+		}
+	}
+	return b.responses[id], nil
+}
+
+func newComposeTestContext(spec map[string]interface{}, transport *composeTestTransport, services ...string) *composeTestContext {
+	registry := ext.NewRegistry()
+	registry.StoreBackendTransport(testComposeProto, transport)
+	registry.StoreBackendTransportDecodeFunc(testComposeProto, func(_ flux.Context, resp interface{}) (int, http.Header, interface{}, error) {
+		return http.StatusOK, nil, resp, nil
+	})
+	for _, id := range services {
+		service := flux.BackendService{Interface: id, Method: "invoke",
+			EmbeddedAttributes: flux.EmbeddedAttributes{
+				Attributes: []flux.Attribute{{Tag: flux.ServiceAttrTagRpcProto, Value: testComposeProto}}}}
+		registry.StoreBackendService(service)
+	}
+	if nil == transport.values {
+		transport.values = make(map[string]interface{}, 4)
+	}
+	return &composeTestContext{
+		goctx: ext.WithRegistry(context.Background(), registry),
+		endpoint: flux.Endpoint{HttpMethod: http.MethodGet, HttpPattern: "/orders",
+			EmbeddedExtensions: flux.EmbeddedExtensions{Extensions: map[string]interface{}{ExtKeyCompose: spec}}},
+		values: make(map[string]interface{}, 4),
+	}
+}
+
+func TestBackendTransportService_Invoke(t *testing.T) {
+	assert := assert2.New(t)
+	transport := &composeTestTransport{responses: map[string]interface{}{
+		"user:invoke":  map[string]interface{}{"id": "u-1", "name": "yongjia"},
+		"order:invoke": `{"items":[1,2]}`,
+	}}
+	spec := map[string]interface{}{
+		"steps": []interface{}{
+			map[string]interface{}{"name": "user", "service": "user:invoke"},
+			map[string]interface{}{"name": "orders", "service": "order:invoke", "depends": []string{"user"},
+				"values": map[string]string{"userId": "user.id"}},
+			map[string]interface{}{"name": "ads", "service": "ads:invoke", "optional": true},
+		},
+		"mappings": map[string]string{"name": "user.name", "orders.items": "orders.items", "ads": "ads"},
+	}
+	ctx := newComposeTestContext(spec, transport, "user", "order")
+	resp, serr := NewComposeBackendTransport().Invoke(flux.BackendService{}, ctx)
+	assert.Nil(serr)
+	assert.Equal(map[string]interface{}{
+		"name":   "yongjia",
+		"orders": map[string]interface{}{"items": []interface{}{float64(1), float64(2)}},
+		"ads":    nil,
+	}, resp.(*Response).Body)
+	// 依赖步骤的响应字段只写入步骤的Context
+	assert.Equal("u-1", transport.values["order:invoke"])
+	assert.Empty(ctx.values)
+	assert.Contains(ctx.metrics, "M-Compose-user")
+	assert.Contains(ctx.metrics, "M-Compose-orders")
+}
+
+func TestSpecOf_Invalid(t *testing.T) {
+	assert := assert2.New(t)
+	cases := []map[string]interface{}{
+		{},
+		{"steps": []interface{}{map[string]interface{}{"name": "a"}}},
+		{"steps": []interface{}{
+			map[string]interface{}{"name": "a", "service": "a:invoke", "depends": []string{"b"}},
+			map[string]interface{}{"name": "b", "service": "b:invoke", "depends": []string{"a"}},
+		}},
+		{"sequential": true, "steps": []interface{}{
+			map[string]interface{}{"name": "a", "service": "a:invoke", "depends": []string{"b"}},
+			map[string]interface{}{"name": "b", "service": "b:invoke"},
+		}},
+	}
+	for _, c := range cases {
+		_, _, err := specOf(c)
+		assert.Error(err)
+	}
+}
@@ -0,0 +1,166 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/spf13/cast"
+	"time"
+)
+
+const (
+	// Endpoint/BackendService扩展属性：组合编排定义，Endpoint的扩展属性优先于BackendService；例如：
+	// {"steps": [{"name": "user", "service": "svc:user:get"},
+	//            {"name": "orders", "service": "svc:order:list", "depends": ["user"], "values": {"userId": "user.id"}}],
+	//  "mappings": {"name": "user.name", "orders": "orders.items"}}
+	ExtKeyCompose = "compose"
+)
+
+const (
+	// 补偿调用的默认超时
+	defaultCompensateTimeout = 5 * time.Second
+)
+
+// Spec 组合编排定义：
+// Steps 为调用步骤，无依赖关系的步骤并行调用，Sequential 为 true 时按声明顺序逐个调用；
+// Mappings 为响应映射，Key为输出JSON的字段路径（点号分隔），Value为 步骤名称.响应字段路径；未定义时输出 {步骤名称: 响应}；
+// CompensateTimeout 为补偿调用的超时，默认5秒
+type Spec struct {
+	Steps             []Step            `json:"steps"`
+	Mappings          map[string]string `json:"mappings"`
+	Sequential        bool              `json:"sequential"`
+	CompensateTimeout time.Duration     `json:"compensateTimeout"`
+}
+
+// Step 调用步骤：
+// Service 为后端服务的ServiceId；Depends 为依赖的步骤名称；
+// Values 将依赖步骤的响应字段（步骤名称.字段路径）写入调用的Context Value，后端服务参数通过 VALUE 域读取；
+// Optional 为 true 时，调用失败不中断编排，响应为 null；
+// Compensate 为补偿调用，必需的步骤失败时，按完成顺序的逆序调用已完成步骤的补偿服务
+type Step struct {
+	Name       string            `json:"name"`
+	Service    string            `json:"service"`
+	Depends    []string          `json:"depends"`
+	Values     map[string]string `json:"values"`
+	Optional   bool              `json:"optional"`
+	Compensate *Compensate       `json:"compensate"`
+}
+
+// Compensate 补偿调用：Service 为补偿服务的ServiceId；Values 定义同 Step.Values，可引用本步骤及其它已完成步骤的响应字段，
+// 例如：{"service": "svc:order:cancel", "values": {"orderId": "order.id"}}
+type Compensate struct {
+	Service string            `json:"service"`
+	Values  map[string]string `json:"values"`
+}
+
+// specOf 解析组合编排定义，返回按依赖关系分层的调用步骤：同一层的步骤并行调用，层之间顺序调用
+func specOf(value interface{}) (*Spec, [][]Step, error) {
+	m := cast.ToStringMap(value)
+	spec := &Spec{
+		Mappings:          cast.ToStringMapString(m["mappings"]),
+		Sequential:        cast.ToBool(m["sequential"]),
+		CompensateTimeout: cast.ToDuration(m["compensateTimeout"]),
+	}
+	if spec.CompensateTimeout <= 0 {
+		spec.CompensateTimeout = defaultCompensateTimeout
+	}
+	for _, item := range cast.ToSlice(m["steps"]) {
+		sm := cast.ToStringMap(item)
+		step := Step{
+			Name:     cast.ToString(sm["name"]),
+			Service:  cast.ToString(sm["service"]),
+			Depends:  cast.ToStringSlice(sm["depends"]),
+			Values:   cast.ToStringMapString(sm["values"]),
+			Optional: cast.ToBool(sm["optional"]),
+		}
+		if v, ok := sm["compensate"]; ok {
+			cm := cast.ToStringMap(v)
+			step.Compensate = &Compensate{
+				Service: cast.ToString(cm["service"]),
+				Values:  cast.ToStringMapString(cm["values"]),
+			}
+		}
+		spec.Steps = append(spec.Steps, step)
+	}
+	if 0 == len(spec.Steps) {
+		return nil, nil, errors.New("compose steps is empty")
+	}
+	if err := validate(spec.Steps, spec.Sequential); nil != err {
+		return nil, nil, err
+	}
+	if spec.Sequential {
+		levels := make([][]Step, len(spec.Steps))
+		for i, step := range spec.Steps {
+			levels[i] = []Step{step}
+		}
+		return spec, levels, nil
+	}
+	levels, err := levelsOf(spec.Steps)
+	return spec, levels, err
+}
+
+// validate 校验步骤定义；顺序调用时，只能依赖在前面声明的步骤
+func validate(steps []Step, sequential bool) error {
+	names := make(map[string]bool, len(steps))
+	for _, step := range steps {
+		if "" == step.Name || "" == step.Service {
+			return fmt.Errorf("compose step requires name and service, step: %s", step.Name)
+		}
+		if names[step.Name] {
+			return fmt.Errorf("compose step name is duplicated, step: %s", step.Name)
+		}
+		if nil != step.Compensate && "" == step.Compensate.Service {
+			return fmt.Errorf("compose step compensate requires service, step: %s", step.Name)
+		}
+		names[step.Name] = true
+	}
+	if sequential {
+		names = make(map[string]bool, len(steps))
+	}
+	for _, step := range steps {
+		for _, dep := range step.Depends {
+			if !names[dep] {
+				return fmt.Errorf("compose step depends on unknown step, step: %s, depends: %s", step.Name, dep)
+			}
+		}
+		names[step.Name] = true
+	}
+	return nil
+}
+
+// levelsOf 按依赖关系分层；存在循环依赖时返回错误
+func levelsOf(steps []Step) ([][]Step, error) {
+	done := make(map[string]bool, len(steps))
+	levels := make([][]Step, 0, len(steps))
+	for len(done) < len(steps) {
+		level := make([]Step, 0, len(steps))
+		for _, step := range steps {
+			if done[step.Name] {
+				continue
+			}
+			ready := true
+			for _, dep := range step.Depends {
+				ready = ready && done[dep]
+			}
+			if ready {
+				level = append(level, step)
+			}
+		}
+		if 0 == len(level) {
+			return nil, errors.New("compose steps has circular depends")
+		}
+		for _, step := range level {
+			done[step.Name] = true
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// extOf 读取扩展属性，Endpoint的扩展属性优先于BackendService
+func extOf(endpoint flux.Endpoint, service flux.BackendService, name string) (interface{}, bool) {
+	if v, ok := endpoint.Ext(name); ok {
+		return v, true
+	}
+	return service.Ext(name)
+}
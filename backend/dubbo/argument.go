@@ -16,21 +16,27 @@ func DefaultArgumentsAssembleFunc(arguments []flux.Argument, ctx flux.Context) (
 	size := len(arguments)
 	types := make([]string, size)
 	values := make([]hessian.Object, size)
-	lookup := ext.LoadArgumentValueLookupFunc()
-	resolver := ext.LoadArgumentValueResolveFunc()
+	registry := ext.RegistryOf(ctx.Context())
+	lookup := registry.LoadArgumentValueLookupFunc()
+	resolver := registry.LoadArgumentValueResolveFunc()
 	for i, argument := range arguments {
-		types[i] = argument.Class
+		types[i] = ToHessianType(argument.Class)
 		if flux.ArgumentTypePrimitive == argument.Type {
-			if value, err := backend.LookupResolveWith(argument, lookup, resolver, ctx); nil != err {
+			value, err := backend.LookupResolveWith(argument, lookup, resolver, ctx)
+			if nil != err {
+				return nil, nil, err
+			}
+			if values[i], err = ToHessianValue(argument.Class, value); nil != err {
 				return nil, nil, err
-			} else {
-				values[i] = value
 			}
 		} else if flux.ArgumentTypeComplex == argument.Type {
-			if value, err := ArgumentsComplex(argument, lookup, resolver, ctx); nil != err {
+			value, err := ArgumentsComplex(argument, lookup, resolver, ctx)
+			if nil != err {
+				return nil, nil, err
+			}
+			// 已注册POJO的Class，按hessian2对象格式编码
+			if values[i], err = ToHessianObject(argument.Class, value); nil != err {
 				return nil, nil, err
-			} else {
-				values[i] = value
 			}
 		} else {
 			logger.TraceContext(ctx).Warnw("Unsupported parameter type", "argument-type", argument.Type)
@@ -41,13 +47,15 @@ func DefaultArgumentsAssembleFunc(arguments []flux.Argument, ctx flux.Context) (
 
 func ArgumentsComplex(argument flux.Argument, lookup flux.ArgumentValueLookupFunc, resolver flux.ArgumentValueResolveFunc, ctx flux.Context) (map[string]interface{}, error) {
 	m := make(map[string]interface{}, 1+len(argument.Fields))
-	m["class"] = argument.Class
+	m[complexClassKey] = argument.Class
 	for _, field := range argument.Fields {
 		if flux.ArgumentTypePrimitive == field.Type {
-			if value, err := backend.LookupResolveWith(field, lookup, resolver, ctx); nil != err {
+			value, err := backend.LookupResolveWith(field, lookup, resolver, ctx)
+			if nil != err {
+				return nil, err
+			}
+			if m[field.Name], err = ToHessianValue(field.Class, value); nil != err {
 				return nil, err
-			} else {
-				m[field.Name] = value
 			}
 		} else if flux.ArgumentTypeComplex == field.Type {
 			if value, err := ArgumentsComplex(field, lookup, resolver, ctx); nil != err {
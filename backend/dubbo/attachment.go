@@ -0,0 +1,92 @@
+package dubbo
+
+import (
+	"context"
+	"github.com/apache/dubbo-go/common/extension"
+	"github.com/apache/dubbo-go/filter"
+	"github.com/apache/dubbo-go/protocol"
+	"github.com/bytepowered/flux"
+	"net/http"
+	"strings"
+)
+
+const (
+	// 请求Header映射为Dubbo调用Attachment的配置：Header-Name: attachment-name；未配置的Header不会传递给后端
+	configKeyRequestAttachments = "request-attachments"
+	// 响应Attachment映射为Http响应Header的配置：attachment-name: Header-Name；未配置的Attachment不会返回给客户端
+	configKeyResponseAttachments = "response-attachments"
+	// 读取响应Attachments的Dubbo Filter名称
+	responseAttachmentsFilterName = "flux-response-attachments"
+)
+
+const (
+	// 请求范围内保存响应Attachment映射Header的Key
+	ContextKeyResponseAttachmentHeaders = "@net.bytepowered.flux.dubbo.attachment-headers"
+)
+
+type responseAttachmentsKey struct{}
+
+func init() {
+	extension.SetFilter(responseAttachmentsFilterName, func() filter.Filter {
+		return new(responseAttachmentsFilter)
+	})
+}
+
+// responseAttachmentsFilter 将响应Attachments保存到调用Context中；GenericService的返回值不包含Attachments
+type responseAttachmentsFilter struct{}
+
+func (f *responseAttachmentsFilter) Invoke(ctx context.Context, invoker protocol.Invoker, invocation protocol.Invocation) protocol.Result {
+	return invoker.Invoke(ctx, invocation)
+}
+
+func (f *responseAttachmentsFilter) OnResponse(ctx context.Context, result protocol.Result, _ protocol.Invoker, _ protocol.Invocation) protocol.Result {
+	if holder, ok := ctx.Value(responseAttachmentsKey{}).(*map[string]string); ok {
+		*holder = result.Attachments()
+	}
+	return result
+}
+
+// newRequestAttachmentsMapping 读取Header与Attachment的映射配置；Header名称不区分大小写
+func newRequestAttachmentsMapping(config *flux.Configuration) map[string]string {
+	mapping := make(map[string]string, 4)
+	for header, name := range config.GetStringMapString(configKeyRequestAttachments) {
+		if "" != name {
+			mapping[http.CanonicalHeaderKey(header)] = name
+		}
+	}
+	return mapping
+}
+
+// mapRequestAttachments 按映射配置将请求Header写入Attachments；网关设置的同名Attribute优先，空值的Header被忽略
+func mapRequestAttachments(request flux.RequestReader, mapping map[string]string, attachments map[string]string) {
+	for header, name := range mapping {
+		if _, ok := attachments[name]; ok {
+			continue
+		}
+		if value := request.HeaderValue(header); "" != value {
+			attachments[name] = value
+		}
+	}
+}
+
+// newResponseAttachmentsMapping 读取Attachment与Header的映射配置；Attachment名称不区分大小写
+func newResponseAttachmentsMapping(config *flux.Configuration) map[string]string {
+	mapping := make(map[string]string, 4)
+	for name, header := range config.GetStringMapString(configKeyResponseAttachments) {
+		if "" != header {
+			mapping[strings.ToLower(name)] = http.CanonicalHeaderKey(header)
+		}
+	}
+	return mapping
+}
+
+// mapResponseAttachments 按映射配置转换响应Attachments，未配置的Attachment被丢弃
+func mapResponseAttachments(attachments map[string]string, mapping map[string]string) http.Header {
+	header := make(http.Header, len(mapping))
+	for name, value := range attachments {
+		if key, ok := mapping[strings.ToLower(name)]; ok {
+			header.Set(key, value)
+		}
+	}
+	return header
+}
@@ -15,7 +15,7 @@ func NewDubboBackendTransportDecodeFuncWith(codeKey, headerKey, bodyKey string)
 	return func(ctx flux.Context, input interface{}) (int, http.Header, interface{}, error) {
 		bodyValues, ok := WrapBodyValues(input)
 		if !ok {
-			return flux.StatusOK, make(http.Header, 0), input, nil
+			return flux.StatusOK, withAttachmentHeaders(ctx, make(http.Header, 0)), input, nil
 		}
 		// Header
 		header, err := bodyValues.ReadHeaderValue(headerKey)
@@ -29,10 +29,27 @@ func NewDubboBackendTransportDecodeFuncWith(codeKey, headerKey, bodyKey string)
 		}
 		// Body
 		body := bodyValues.ReadBodyValue(bodyKey)
-		return status, header, body, nil
+		return status, withAttachmentHeaders(ctx, header), body, nil
 	}
 }
 
+// withAttachmentHeaders 合并响应Attachment映射的Header；响应数据中定义的Header优先
+func withAttachmentHeaders(ctx flux.Context, header http.Header) http.Header {
+	if nil == ctx {
+		return header
+	}
+	if v, ok := ctx.GetValue(ContextKeyResponseAttachmentHeaders); ok {
+		if attached, ok := v.(http.Header); ok {
+			for key, values := range attached {
+				if _, exists := header[key]; !exists {
+					header[key] = values
+				}
+			}
+		}
+	}
+	return header
+}
+
 func NewDubboBackendTransportDecodeFunc() flux.BackendTransportDecodeFunc {
 	return NewDubboBackendTransportDecodeFuncWith(ResponseKeyStatusCode, ResponseKeyHeaders, ResponseKeyBody)
 }
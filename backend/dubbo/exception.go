@@ -0,0 +1,111 @@
+package dubbo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/apache/dubbo-go-hessian2/java_exception"
+	"github.com/bytepowered/flux"
+	"github.com/spf13/cast"
+	"path"
+	"reflect"
+	"regexp"
+)
+
+const (
+	// Dubbo业务异常映射配置
+	configKeyExceptionMappings = "exception-mappings"
+)
+
+// ExceptionMapping Dubbo业务异常映射规则：异常类名和消息均匹配时，转换为指定的Http状态码和错误码。
+// Class 支持 * 通配，例如 com.foo.*Exception；Message 为正则表达式，为空时不匹配消息。
+type ExceptionMapping struct {
+	Class         string
+	Message       *regexp.Regexp
+	StatusCode    int
+	ErrorCode     string
+	ExposeMessage bool // 是否将异常消息作为错误消息返回给客户端
+}
+
+// ExceptionTranslator 按顺序匹配的Dubbo业务异常映射表
+type ExceptionTranslator []ExceptionMapping
+
+// NewExceptionTranslator 读取异常映射配置：
+// exception-mappings: [{class: com.foo.NotFoundException, message: "^user", status-code: 404, error-code: USER_NOT_FOUND, expose-message: true}]
+func NewExceptionTranslator(config *flux.Configuration) (ExceptionTranslator, error) {
+	items := cast.ToSlice(config.Get(configKeyExceptionMappings))
+	out := make(ExceptionTranslator, 0, len(items))
+	for i, item := range items {
+		values := cast.ToStringMap(item)
+		mapping := ExceptionMapping{
+			Class:         cast.ToString(values["class"]),
+			StatusCode:    cast.ToInt(values["status-code"]),
+			ErrorCode:     cast.ToString(values["error-code"]),
+			ExposeMessage: cast.ToBool(values["expose-message"]),
+		}
+		if "" == mapping.Class {
+			return nil, fmt.Errorf("exception mapping[%d]: class is required", i)
+		}
+		if _, err := path.Match(mapping.Class, ""); nil != err {
+			return nil, fmt.Errorf("exception mapping[%d]: invalid class pattern: %w", i, err)
+		}
+		if pattern := cast.ToString(values["message"]); "" != pattern {
+			regex, err := regexp.Compile(pattern)
+			if nil != err {
+				return nil, fmt.Errorf("exception mapping[%d]: invalid message pattern: %w", i, err)
+			}
+			mapping.Message = regex
+		}
+		if mapping.StatusCode <= 0 {
+			mapping.StatusCode = flux.StatusServerError
+		}
+		if "" == mapping.ErrorCode {
+			mapping.ErrorCode = flux.ErrorCodeGatewayBackend
+		}
+		out = append(out, mapping)
+	}
+	return out, nil
+}
+
+// Translate 将Dubbo业务异常转换为ServeError；非Java异常或未匹配映射规则时返回false
+func (t ExceptionTranslator) Translate(err error) (*flux.ServeError, bool) {
+	var throwable java_exception.Throwabler
+	if len(t) == 0 || !errors.As(err, &throwable) {
+		return nil, false
+	}
+	class, message := throwable.JavaClassName(), exceptionMessageOf(throwable)
+	for _, mapping := range t {
+		if ok, _ := path.Match(mapping.Class, class); !ok {
+			continue
+		}
+		if nil != mapping.Message && !mapping.Message.MatchString(message) {
+			continue
+		}
+		serr := &flux.ServeError{
+			StatusCode:        mapping.StatusCode,
+			ErrorCode:         mapping.ErrorCode,
+			Message:           flux.ErrorMessageDubboBusinessException,
+			Internal:          err,
+			Category:          flux.ErrorCategoryUpstream,
+			UpstreamErrorCode: class,
+		}
+		if mapping.ExposeMessage {
+			serr.Message = message
+		}
+		return serr, true
+	}
+	return nil, false
+}
+
+// exceptionMessageOf 返回Java异常的DetailMessage；异常类型未定义该字段时，返回Error()
+func exceptionMessageOf(throwable java_exception.Throwabler) string {
+	v := reflect.ValueOf(throwable)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct {
+		if f := v.FieldByName("DetailMessage"); f.IsValid() && f.Kind() == reflect.String {
+			return f.String()
+		}
+	}
+	return throwable.Error()
+}
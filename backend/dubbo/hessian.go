@@ -0,0 +1,208 @@
+package dubbo
+
+import (
+	"encoding/json"
+	"fmt"
+	hessian "github.com/apache/dubbo-go-hessian2"
+	"github.com/apache/dubbo-go-hessian2/java8_time"
+	"github.com/bytepowered/flux"
+	gostbig "github.com/dubbogo/gost/math/big"
+	"github.com/spf13/cast"
+	"math/big"
+	"reflect"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	// hessian2 字段名Tag
+	hessianTagName = "hessian"
+	// Complex参数值Map中的Java类名Key
+	complexClassKey = "class"
+)
+
+var (
+	hessianPOJOTypes   = make(map[string]reflect.Type, 16)
+	hessianPOJOTypesMu sync.RWMutex
+)
+
+// RegisterHessianPOJO 注册Java类对应的Go结构体。
+// Complex参数的Class已注册时，参数值转换为POJO实例，按hessian2对象格式（包含类元数据）编码；
+// 未注册的Class仍使用泛化Map，由Provider侧转换。
+func RegisterHessianPOJO(pojos ...hessian.POJO) {
+	hessianPOJOTypesMu.Lock()
+	defer hessianPOJOTypesMu.Unlock()
+	for _, pojo := range pojos {
+		hessian.RegisterPOJO(pojo)
+		t := reflect.TypeOf(pojo)
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		hessianPOJOTypes[pojo.JavaClassName()] = t
+	}
+}
+
+// LoadHessianPOJOType 查找Java类对应的Go结构体类型
+func LoadHessianPOJOType(class string) (reflect.Type, bool) {
+	hessianPOJOTypesMu.RLock()
+	defer hessianPOJOTypesMu.RUnlock()
+	t, ok := hessianPOJOTypes[class]
+	return t, ok
+}
+
+// ToHessianObject 将Complex参数值Map转换为已注册的POJO实例；Class未注册时返回原Map
+func ToHessianObject(class string, values map[string]interface{}) (interface{}, error) {
+	t, ok := LoadHessianPOJOType(class)
+	if !ok {
+		return values, nil
+	}
+	return newHessianPOJO(t, values)
+}
+
+// ToHessianType 返回参数类型在hessian2协议中的类型名：字节数组类型转换为 [B，其它类型不变
+func ToHessianType(class string) string {
+	switch class {
+	case "bytes", "[]byte", "byte[]":
+		return flux.JavaByteArrayClassName
+	default:
+		return class
+	}
+}
+
+// ToHessianValue 将参数值转换为hessian2对应Java类型的对象：
+// java.time.LocalDateTime 类型的time.Time值转换为LocalDateTime对象（time.Time值默认按 java.util.Date 编码）；
+// java.math.BigDecimal 类型的json.Number值、java.math.BigInteger 类型的*big.Int值转换为高精度数值对象。其它值返回原值
+func ToHessianValue(class string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case time.Time:
+		if flux.JavaTimeLocalDateTimeClassName == class {
+			return java8_time.LocalDateTime{
+				Date: java8_time.LocalDate{Year: int32(v.Year()), Month: int32(v.Month()), Day: int32(v.Day())},
+				Time: java8_time.LocalTime{Hour: int32(v.Hour()), Minute: int32(v.Minute()), Second: int32(v.Second()), Nano: int32(v.Nanosecond())},
+			}, nil
+		}
+	case json.Number:
+		if flux.JavaMathBigDecimalClassName == class {
+			decimal := gostbig.Decimal{}
+			if err := decimal.FromString(v.String()); nil != err {
+				return nil, fmt.Errorf("invalid decimal: %s, error: %w", v, err)
+			}
+			return decimal, nil
+		}
+	case *big.Int:
+		if flux.JavaMathBigIntegerClassName == class {
+			integer := gostbig.Integer{}
+			integer.SetValue(v)
+			return integer, nil
+		}
+	}
+	return value, nil
+}
+
+func newHessianPOJO(t reflect.Type, values map[string]interface{}) (interface{}, error) {
+	ptr := reflect.New(t)
+	if err := setHessianFields(ptr.Elem(), values); nil != err {
+		return nil, err
+	}
+	return ptr.Interface(), nil
+}
+
+func setHessianFields(obj reflect.Value, values map[string]interface{}) error {
+	t := obj.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if "" != sf.PkgPath {
+			continue
+		}
+		tag, hasTag := sf.Tag.Lookup(hessianTagName)
+		if "-" == tag {
+			continue
+		}
+		field := obj.Field(i)
+		// 与hessian2一致：展开匿名结构体字段
+		if sf.Anonymous && field.Kind() == reflect.Struct {
+			if err := setHessianFields(field, values); nil != err {
+				return err
+			}
+			continue
+		}
+		name := tag
+		if !hasTag {
+			name = lowerCamelCase(sf.Name)
+		}
+		value, ok := values[name]
+		if !ok || nil == value {
+			continue
+		}
+		if err := setHessianValue(field, value); nil != err {
+			return fmt.Errorf("set pojo field %s.%s: %w", t.Name(), name, err)
+		}
+	}
+	return nil
+}
+
+func setHessianValue(field reflect.Value, value interface{}) error {
+	if nested, ok := value.(map[string]interface{}); ok {
+		switch {
+		case field.Kind() == reflect.Struct:
+			return setHessianFields(field, nested)
+		case field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.Struct:
+			obj, err := newHessianPOJO(field.Type().Elem(), nested)
+			if nil != err {
+				return err
+			}
+			field.Set(reflect.ValueOf(obj))
+			return nil
+		case field.Kind() == reflect.Interface:
+			obj, err := ToHessianObject(cast.ToString(nested[complexClassKey]), nested)
+			if nil != err {
+				return err
+			}
+			field.Set(reflect.ValueOf(obj))
+			return nil
+		}
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Type().AssignableTo(field.Type()) {
+		field.Set(rv)
+		return nil
+	}
+	var (
+		out interface{}
+		err error
+	)
+	switch field.Kind() {
+	case reflect.String:
+		out, err = cast.ToStringE(value)
+	case reflect.Bool:
+		out, err = cast.ToBoolE(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		out, err = cast.ToInt64E(value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		out, err = cast.ToUint64E(value)
+	case reflect.Float32, reflect.Float64:
+		out, err = cast.ToFloat64E(value)
+	default:
+		if rv.Type().ConvertibleTo(field.Type()) {
+			field.Set(rv.Convert(field.Type()))
+			return nil
+		}
+		return fmt.Errorf("unsupported value type: %T, field type: %s", value, field.Type())
+	}
+	if nil != err {
+		return err
+	}
+	field.Set(reflect.ValueOf(out).Convert(field.Type()))
+	return nil
+}
+
+// lowerCamelCase 与hessian2默认字段名规则一致：首字母小写
+func lowerCamelCase(s string) string {
+	if "" == s {
+		return s
+	}
+	runes := []rune(s)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
@@ -6,14 +6,17 @@ import (
 	"fmt"
 	jsoniter "github.com/json-iterator/go"
 	"reflect"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/apache/dubbo-go-hessian2/java_exception"
 	"github.com/apache/dubbo-go/common/constant"
 	dubgo "github.com/apache/dubbo-go/config"
 	"github.com/apache/dubbo-go/protocol/dubbo"
 	"github.com/bytepowered/flux"
 	"github.com/bytepowered/flux/backend"
+	"github.com/bytepowered/flux/ext"
 	"github.com/bytepowered/flux/logger"
 	"github.com/bytepowered/flux/pkg"
 	"github.com/spf13/cast"
@@ -22,6 +25,7 @@ import (
 const (
 	configKeyTraceEnable    = "trace-enable"
 	configKeyReferenceDelay = "reference-delay"
+	configKeyWarmupServices = "warmup-services"
 )
 
 var (
@@ -36,6 +40,7 @@ var (
 
 var (
 	_ flux.BackendTransport = new(BackendTransportService)
+	_ flux.Warmer           = new(BackendTransportService)
 )
 
 func init() {
@@ -73,9 +78,13 @@ type BackendTransportService struct {
 	ReferenceOptionsFuncs []ReferenceOptionsFunc
 	ArgumentsAssembleFunc ArgumentsAssembleFunc
 	// 内部私有
-	traceEnable   bool
-	configuration *flux.Configuration
-	serviceMutex  sync.RWMutex
+	traceEnable         bool
+	requestAttachments  map[string]string
+	responseAttachments map[string]string
+	exceptionTranslator ExceptionTranslator
+	configuration       *flux.Configuration
+	serviceMutex        sync.RWMutex
+	triple              *tripleClient
 }
 
 // NewDubboBackendTransport New dubbo backend instance
@@ -83,6 +92,7 @@ func NewDubboBackendTransport() flux.BackendTransport {
 	return &BackendTransportService{
 		ReferenceOptionsFuncs: make([]ReferenceOptionsFunc, 0),
 		ArgumentsAssembleFunc: DefaultArgumentsAssembleFunc,
+		triple:                newTripleClient(nil, backend.NewClientPool(tripleClientPool, backend.DefaultPoolOptions())),
 	}
 }
 
@@ -106,6 +116,15 @@ func (b *BackendTransportService) Init(config *flux.Configuration) error {
 	b.configuration = config
 	b.traceEnable = config.GetBool(configKeyTraceEnable)
 	logger.Infow("Dubbo backend transport request trace", "enable", b.traceEnable)
+	b.requestAttachments = newRequestAttachmentsMapping(config)
+	logger.Infow("Dubbo backend transport request attachments", "mapping", b.requestAttachments)
+	b.responseAttachments = newResponseAttachmentsMapping(config)
+	logger.Infow("Dubbo backend transport response attachments", "mapping", b.responseAttachments)
+	if translator, err := NewExceptionTranslator(config); nil != err {
+		return err
+	} else {
+		b.exceptionTranslator = translator
+	}
 	// Set default impl if not present
 	if nil == b.ReferenceOptionsFuncs {
 		b.ReferenceOptionsFuncs = make([]ReferenceOptionsFunc, 0)
@@ -113,6 +132,8 @@ func (b *BackendTransportService) Init(config *flux.Configuration) error {
 	if pkg.IsNil(b.ArgumentsAssembleFunc) {
 		b.ArgumentsAssembleFunc = DefaultArgumentsAssembleFunc
 	}
+	triple := config.Sub(configKeyTriple)
+	b.triple = newTripleClient(triple, backend.NewClientPool(tripleClientPool, backend.NewPoolOptions(triple.Sub(configKeyPool))))
 	// 修改默认Consumer配置
 	consumerc := dubgo.GetConsumerConfig()
 	// 支持定义Registry
@@ -131,6 +152,28 @@ func (b *BackendTransportService) Startup() error {
 	return nil
 }
 
+// Warmup 预加载配置项 warmup-services 指定的BackendService的Dubbo泛化引用，避免首个请求创建引用的延迟
+func (b *BackendTransportService) Warmup(ctx context.Context) error {
+	if nil == b.configuration {
+		return nil
+	}
+	for _, id := range b.configuration.GetStringSlice(configKeyWarmupServices) {
+		if err := ctx.Err(); nil != err {
+			return err
+		}
+		service, ok := ext.RegistryOf(ctx).LoadBackendService(id)
+		if !ok {
+			logger.Warnw("Dubbo warmup service not found", "service-id", id)
+			continue
+		}
+		if ProtocolTriple == b.ProtocolOf(service) {
+			continue
+		}
+		b.LoadGenericService(&service)
+	}
+	return nil
+}
+
 // Shutdown shutdown service
 func (b *BackendTransportService) Shutdown(_ context.Context) error {
 	dubgo.BeforeShutdown()
@@ -177,18 +220,37 @@ func (b *BackendTransportService) ExecuteWith(types []string, values interface{}
 			Internal:   err,
 		}
 	}
+	mapRequestAttachments(ctx.Request(), b.requestAttachments, attachments)
 	goctx := context.WithValue(ctx.Context(), constant.AttachmentKey, attachments)
-	generic := b.LoadGenericService(&service)
-	if resp, err := generic.Invoke(goctx, []interface{}{service.Method, types, values}); err != nil {
+	// 响应Attachments由Filter写入
+	responseAttachments := make(map[string]string)
+	if len(b.responseAttachments) > 0 {
+		goctx = context.WithValue(goctx, responseAttachmentsKey{}, &responseAttachments)
+	}
+	if resp, err := b.invoke(goctx, service, types, values, attachments); err != nil {
 		logger.TraceContext(ctx).Errorw("Dubbo rpc error",
 			"backend-service", service.ServiceID(), "error", err)
-		return nil, &flux.ServeError{
+		// 业务异常按映射表转换
+		if serr, ok := b.exceptionTranslator.Translate(err); ok {
+			return nil, serr
+		}
+		serr := &flux.ServeError{
 			StatusCode: flux.StatusBadGateway,
 			ErrorCode:  flux.ErrorCodeGatewayBackend,
 			Message:    flux.ErrorMessageDubboInvokeFailed,
 			Internal:   err,
+			Category:   flux.ErrorCategoryUpstream,
+		}
+		// 未映射的Java异常：记录异常类名
+		var throwable java_exception.Throwabler
+		if errors.As(err, &throwable) {
+			serr.UpstreamErrorCode = throwable.JavaClassName()
 		}
+		return nil, serr
 	} else {
+		if len(responseAttachments) > 0 {
+			ctx.SetValue(ContextKeyResponseAttachmentHeaders, mapResponseAttachments(responseAttachments, b.responseAttachments))
+		}
 		if b.traceEnable {
 			text, err := internalJSON.MarshalToString(resp)
 			ctxLogger := logger.TraceContext(ctx)
@@ -203,6 +265,30 @@ func (b *BackendTransportService) ExecuteWith(types []string, values interface{}
 	}
 }
 
+// ProtocolOf 返回BackendService的调用协议：扩展属性 dubbo-protocol 优先，未设置时使用全局配置
+func (b *BackendTransportService) ProtocolOf(service flux.BackendService) string {
+	if protocol := service.ExtString(ServiceExtKeyProtocol); "" != protocol {
+		return strings.ToLower(protocol)
+	}
+	if nil != b.configuration {
+		return strings.ToLower(b.configuration.GetString("protocol"))
+	}
+	return ProtocolDubbo
+}
+
+// invoke 按BackendService的协议执行泛化调用
+func (b *BackendTransportService) invoke(goctx context.Context, service flux.BackendService, types []string, values interface{}, attachments map[string]string) (interface{}, error) {
+	if ProtocolTriple != b.ProtocolOf(service) {
+		generic := b.LoadGenericService(&service)
+		return generic.Invoke(goctx, []interface{}{service.Method, types, values})
+	}
+	resp, responseAttachments, err := b.triple.Invoke(goctx, service, types, values, attachments)
+	if holder, ok := goctx.Value(responseAttachmentsKey{}).(*map[string]string); ok && nil == err {
+		*holder = responseAttachments
+	}
+	return resp, err
+}
+
 // LoadGenericService create and cache dubbo generic service
 func (b *BackendTransportService) LoadGenericService(definition *flux.BackendService) *dubgo.GenericService {
 	b.serviceMutex.Lock()
@@ -267,5 +353,8 @@ func NewReference(refid string, service *flux.BackendService, config *flux.Confi
 	ref.Protocol = config.GetString("protocol")
 	ref.Loadbalance = config.GetString("load-balance")
 	ref.Generic = true
+	if len(config.GetStringMapString(configKeyResponseAttachments)) > 0 {
+		ref.Filter = responseAttachmentsFilterName
+	}
 	return ref
 }
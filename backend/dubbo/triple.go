@@ -0,0 +1,279 @@
+package dubbo
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	hessian "github.com/apache/dubbo-go-hessian2"
+	"github.com/apache/dubbo-go-hessian2/java_exception"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/backend"
+	"github.com/golang/protobuf/proto"
+	"github.com/spf13/cast"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// BackendService扩展属性：调用协议，dubbo（默认）或 tri（Dubbo3 Triple）；未设置时使用全局配置 protocol
+	ServiceExtKeyProtocol = "dubbo-protocol"
+)
+
+const (
+	ProtocolDubbo  = "dubbo"
+	ProtocolTriple = "tri"
+)
+
+const (
+	// Triple协议配置
+	configKeyTriple          = "triple"
+	configKeyTripleTLSEnable = "tls-enable"
+	configKeyPool            = "pool"
+	// Triple协议客户端使用的连接池名称
+	tripleClientPool = "DUBBO.TRIPLE"
+)
+
+const (
+	tripleGenericMethod = "$invoke"
+	tripleSerializeType = "hessian2"
+)
+
+var (
+	// 泛化调用 $invoke(String method, String[] parameterTypes, Object[] args) 的参数类型
+	tripleGenericArgTypes = []string{"java.lang.String", "[Ljava.lang.String;", "[Ljava.lang.Object;"}
+)
+
+var (
+	// 响应中不作为Attachment的HTTP/2字段
+	tripleReservedHeaders = map[string]struct{}{
+		"content-type": {}, "content-length": {}, "date": {}, "trailer": {}, "te": {},
+	}
+)
+
+var (
+	errTripleTruncatedMessage = errors.New("triple truncated message")
+)
+
+// TripleStatusError Triple服务端返回的非OK状态
+type TripleStatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *TripleStatusError) Error() string {
+	return fmt.Sprintf("triple status: %d, message: %s", e.Code, e.Message)
+}
+
+// tripleClient Dubbo3 Triple协议（兼容gRPC的HTTP/2协议）泛化调用客户端：
+// 请求路径为 /{Interface}/$invoke，请求和响应消息为Wrapper模式的Protobuf消息，参数和返回值使用hessian2序列化；
+// 与dubbo协议的泛化调用语义一致，Endpoint定义无需修改。Triple协议不支持注册中心发现，需要指定 RemoteHost。
+type tripleClient struct {
+	httpClient *http.Client
+	scheme     string
+}
+
+func newTripleClient(config *flux.Configuration, pool *backend.ClientPool) *tripleClient {
+	tlsEnabled := nil != config && config.GetBool(configKeyTripleTLSEnable)
+	client := &tripleClient{httpClient: pool.Http2Client(!tlsEnabled), scheme: "https"}
+	if !tlsEnabled {
+		// h2c: 明文HTTP/2
+		client.scheme = "http"
+	}
+	return client
+}
+
+// Invoke 执行Triple泛化调用；响应Header和Trailer中的自定义字段作为响应Attachments
+func (c *tripleClient) Invoke(ctx context.Context, service flux.BackendService, types []string, values interface{}, attachments map[string]string) (interface{}, map[string]string, error) {
+	host := service.RemoteHost
+	if i := strings.Index(host, "://"); i > 0 {
+		host = host[i+3:]
+	}
+	if host = strings.TrimSuffix(host, "/"); "" == host {
+		return nil, nil, fmt.Errorf("triple remote-host is required, service: %s", service.ServiceID())
+	}
+	message, err := encodeTripleRequest(service.Method, types, values)
+	if nil != err {
+		return nil, nil, err
+	}
+	toctx, cancel := context.WithTimeout(ctx, tripleTimeoutOf(service))
+	defer cancel()
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	frame = append(frame, message...)
+	request, err := http.NewRequestWithContext(toctx, http.MethodPost, c.scheme+"://"+host+"/"+service.Interface+"/"+tripleGenericMethod, bytes.NewReader(frame))
+	if nil != err {
+		return nil, nil, err
+	}
+	for k, v := range attachments {
+		request.Header.Set(k, v)
+	}
+	request.Header.Set("Content-Type", "application/grpc+proto")
+	request.Header.Set("TE", "trailers")
+	request.Header.Set("User-Agent", "FluxGo/Backend/v1")
+	request.Header.Set("generic", "true")
+	if version := service.AttrRpcVersion(); "" != version {
+		request.Header.Set("tri-service-version", version)
+	}
+	if group := service.AttrRpcGroup(); "" != group {
+		request.Header.Set("tri-service-group", group)
+	}
+	if deadline, ok := toctx.Deadline(); ok {
+		request.Header.Set("Grpc-Timeout", strconv.FormatInt(time.Until(deadline).Milliseconds(), 10)+"m")
+	}
+	response, err := c.httpClient.Do(request)
+	if nil != err {
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+	data, err := ioutil.ReadAll(response.Body)
+	if nil != err {
+		return nil, nil, err
+	}
+	if http.StatusOK != response.StatusCode {
+		return nil, nil, fmt.Errorf("unexpected http status: %d", response.StatusCode)
+	}
+	// Trailers-Only 响应的状态在Header中
+	status := response.Trailer.Get("Grpc-Status")
+	if "" == status {
+		status = response.Header.Get("Grpc-Status")
+	}
+	if code, _ := strconv.Atoi(status); "" == status || 0 != code {
+		msg := response.Trailer.Get("Grpc-Message")
+		if "" == msg {
+			msg = response.Header.Get("Grpc-Message")
+		}
+		msg, _ = url.PathUnescape(msg)
+		if "" == status {
+			code, msg = 2, "grpc-status not returned"
+		}
+		return nil, nil, &TripleStatusError{Code: code, Message: msg}
+	}
+	if len(data) < 5 {
+		return nil, nil, errTripleTruncatedMessage
+	}
+	if 0 != data[0] {
+		return nil, nil, errors.New("compressed triple message not supported")
+	}
+	size := binary.BigEndian.Uint32(data[1:5])
+	if uint32(len(data)-5) < size {
+		return nil, nil, errTripleTruncatedMessage
+	}
+	result, err := decodeTripleResponse(data[5 : 5+size])
+	if nil != err {
+		return nil, nil, err
+	}
+	// 业务异常作为错误返回，由异常映射表转换
+	if throwable, ok := result.(java_exception.Throwabler); ok {
+		return nil, nil, throwable
+	}
+	return result, tripleAttachmentsOf(response.Header, response.Trailer), nil
+}
+
+// encodeTripleRequest 编码泛化调用的 TripleRequestWrapper：
+// message TripleRequestWrapper { string serializeType = 1; repeated bytes args = 2; repeated string argTypes = 3; }
+func encodeTripleRequest(method string, types []string, values interface{}) ([]byte, error) {
+	buf := proto.NewBuffer(nil)
+	_ = buf.EncodeVarint(1<<3 | proto.WireBytes)
+	_ = buf.EncodeStringBytes(tripleSerializeType)
+	for _, arg := range []interface{}{method, types, values} {
+		encoder := hessian.NewEncoder()
+		if err := encoder.Encode(arg); nil != err {
+			return nil, err
+		}
+		_ = buf.EncodeVarint(2<<3 | proto.WireBytes)
+		_ = buf.EncodeRawBytes(encoder.Buffer())
+	}
+	for _, t := range tripleGenericArgTypes {
+		_ = buf.EncodeVarint(3<<3 | proto.WireBytes)
+		_ = buf.EncodeStringBytes(t)
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeTripleResponse 解码 TripleResponseWrapper，返回hessian2反序列化的返回值：
+// message TripleResponseWrapper { string serializeType = 1; bytes data = 2; string type = 3; }
+func decodeTripleResponse(message []byte) (interface{}, error) {
+	var data []byte
+	serialize := tripleSerializeType
+	for len(message) > 0 {
+		key, n := proto.DecodeVarint(message)
+		if 0 == n {
+			return nil, errTripleTruncatedMessage
+		}
+		message = message[n:]
+		switch key & 7 {
+		case proto.WireBytes:
+			size, n := proto.DecodeVarint(message)
+			if 0 == n || uint64(len(message)-n) < size {
+				return nil, errTripleTruncatedMessage
+			}
+			b := message[n : n+int(size)]
+			message = message[n+int(size):]
+			switch key >> 3 {
+			case 1:
+				serialize = string(b)
+			case 2:
+				data = b
+			}
+		case proto.WireVarint:
+			_, n := proto.DecodeVarint(message)
+			if 0 == n {
+				return nil, errTripleTruncatedMessage
+			}
+			message = message[n:]
+		case proto.WireFixed64, proto.WireFixed32:
+			size := 8
+			if proto.WireFixed32 == key&7 {
+				size = 4
+			}
+			if len(message) < size {
+				return nil, errTripleTruncatedMessage
+			}
+			message = message[size:]
+		default:
+			return nil, fmt.Errorf("triple unsupported wire type: %d", key&7)
+		}
+	}
+	if tripleSerializeType != serialize {
+		return nil, fmt.Errorf("triple unsupported serialize type: %s", serialize)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	// 未注册的Java类型解码为Map
+	return hessian.NewDecoderWithSkip(data).Decode()
+}
+
+// tripleAttachmentsOf 读取响应Header和Trailer中的Attachments，忽略协议保留字段
+func tripleAttachmentsOf(headers ...http.Header) map[string]string {
+	attachments := make(map[string]string, 4)
+	for _, header := range headers {
+		for name, values := range header {
+			lower := strings.ToLower(name)
+			if _, reserved := tripleReservedHeaders[lower]; reserved || len(values) == 0 ||
+				strings.HasPrefix(lower, "grpc-") || strings.HasPrefix(lower, "tri-") {
+				continue
+			}
+			attachments[lower] = values[0]
+		}
+	}
+	return attachments
+}
+
+// tripleTimeoutOf 返回调用超时：支持Duration格式（例如 3s）和毫秒数，默认5秒
+func tripleTimeoutOf(service flux.BackendService) time.Duration {
+	to := service.AttrRpcTimeout()
+	if d, err := time.ParseDuration(to); nil == err && d > 0 {
+		return d
+	}
+	if ms := cast.ToInt64(to); ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return time.Second * 5
+}
@@ -0,0 +1,44 @@
+package backend
+
+import (
+	"errors"
+	"github.com/bytepowered/flux"
+	"net"
+	"syscall"
+)
+
+const (
+	// Endpoint扩展属性：开启上游集群的故障转移。调用上游地址发生连接错误时，使用集群中的下一个地址重新调用；
+	// 连接错误发生在请求发送前，非幂等请求也可以安全地转移。
+	EndpointExtKeyFailover = "failover"
+	// Endpoint扩展属性：故障转移的最大调用次数（包含首次调用）；默认3，不超过集群的地址数量
+	EndpointExtKeyFailoverMaxAttempts = "failover-max-attempts"
+)
+
+const (
+	defaultFailoverMaxAttempts = 3
+)
+
+// failoverAttemptsOf 返回请求的最大调用次数；未开启故障转移时返回1
+func failoverAttemptsOf(ctx flux.Context) int {
+	endpoint := ctx.Endpoint()
+	if !endpoint.ExtBool(EndpointExtKeyFailover) {
+		return 1
+	}
+	if attempts := endpoint.ExtInt(EndpointExtKeyFailoverMaxAttempts); attempts > 0 {
+		return attempts
+	}
+	return defaultFailoverMaxAttempts
+}
+
+// connectionFailed 返回错误是否为建立连接时的错误：拨号失败、连接被拒绝或连接超时
+func connectionFailed(serr *flux.ServeError) bool {
+	if nil == serr || nil == serr.Internal {
+		return false
+	}
+	var opErr *net.OpError
+	if errors.As(serr.Internal, &opErr) && "dial" == opErr.Op {
+		return true
+	}
+	return errors.Is(serr.Internal, syscall.ECONNREFUSED)
+}
@@ -0,0 +1,73 @@
+package backend
+
+import (
+	"errors"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	assert2 "github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"testing"
+)
+
+type failoverTestTransport struct {
+	down    map[string]bool
+	invoked []string
+}
+
+func (b *failoverTestTransport) Exchange(flux.Context) *flux.ServeError {
+	return nil
+}
+
+func (b *failoverTestTransport) Invoke(service flux.BackendService, _ flux.Context) (interface{}, *flux.ServeError) {
+	b.invoked = append(b.invoked, service.RemoteHost)
+	if b.down[service.RemoteHost] {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusBadGateway,
+			ErrorCode:  flux.ErrorCodeGatewayBackend,
+			Internal:   &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+		}
+	}
+	return service.RemoteHost, nil
+}
+
+func TestInvokeUpstream_Failover(t *testing.T) {
+	assert := assert2.New(t)
+	service := flux.BackendService{RemoteHost: "upstream://failover-cluster"}
+	// 每个用例使用新的集群定义，避免负载均衡状态影响首次选择的地址
+	store := func(weight int) {
+		ext.StoreUpstream(flux.Upstream{Name: "failover-cluster", Balancer: UpstreamBalancerWeighted, Addresses: []flux.UpstreamAddress{
+			{Address: "failover-a", Weight: weight}, {Address: "failover-b", Weight: 1}, {Address: "failover-c", Weight: 1},
+		}})
+	}
+	defer ext.RemoveUpstream("failover-cluster")
+	endpoint := flux.Endpoint{}
+	endpoint.Extensions = map[string]interface{}{EndpointExtKeyFailover: true, EndpointExtKeyFailoverMaxAttempts: 2}
+	cases := []struct {
+		down     map[string]bool
+		expected interface{}
+		invoked  int
+	}{
+		{down: map[string]bool{}, expected: "failover-a", invoked: 1},
+		{down: map[string]bool{"failover-a": true}, invoked: 2},
+		{down: map[string]bool{"failover-a": true, "failover-b": true, "failover-c": true}, invoked: 2},
+	}
+	for i, c := range cases {
+		store(i + 3)
+		transport := &failoverTestTransport{down: c.down}
+		resp, err := invokeUpstream(transport, service, &hedgeTestContext{method: http.MethodPost, endpoint: endpoint})
+		assert.Equal(c.invoked, len(transport.invoked))
+		if len(c.down) < 3 {
+			assert.Nil(err)
+			assert.False(c.down[resp.(string)])
+		} else {
+			assert.True(connectionFailed(err))
+		}
+	}
+	// 未开启故障转移时不重新调用
+	store(10)
+	transport := &failoverTestTransport{down: map[string]bool{"failover-a": true, "failover-b": true, "failover-c": true}}
+	_, err := invokeUpstream(transport, service, &hedgeTestContext{method: http.MethodGet})
+	assert.NotNil(err)
+	assert.Equal(1, len(transport.invoked))
+}
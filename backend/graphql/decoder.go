@@ -0,0 +1,57 @@
+package graphql
+
+import (
+	"errors"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"net/http"
+)
+
+var (
+	ErrUnknownGraphQLBackendResponse = errors.New("BACKEND:UNKNOWN_GRAPHQL_RESPONSE")
+)
+
+// NewGraphQLBackendTransportDecodeFunc 解析GraphQL响应：
+// 无errors时返回data（自动生成文档时展开根字段）；data为空时返回errors和502状态码；部分成功时返回data和errors。
+func NewGraphQLBackendTransportDecodeFunc() flux.BackendTransportDecodeFunc {
+	return func(ctx flux.Context, value interface{}) (statusCode int, headers http.Header, body interface{}, err error) {
+		resp, ok := value.(*Response)
+		if !ok {
+			return http.StatusInternalServerError, http.Header{}, nil, ErrUnknownGraphQLBackendResponse
+		}
+		result := struct {
+			Data   map[string]interface{} `json:"data"`
+			Errors []interface{}          `json:"errors"`
+		}{}
+		if err := ext.JSONUnmarshal(resp.Body, &result); nil != err {
+			// 非GraphQL格式的上游错误响应，原样返回
+			if resp.StatusCode >= http.StatusBadRequest {
+				return resp.StatusCode, http.Header{}, string(resp.Body), nil
+			}
+			return http.StatusInternalServerError, http.Header{}, nil, err
+		}
+		if len(result.Errors) == 0 {
+			if field, ok := result.Data[resp.Field]; ok && "" != resp.Field {
+				return http.StatusOK, http.Header{}, field, nil
+			}
+			return http.StatusOK, http.Header{}, result.Data, nil
+		}
+		if isEmptyData(result.Data) {
+			status := resp.StatusCode
+			if status < http.StatusBadRequest {
+				status = http.StatusBadGateway
+			}
+			return status, http.Header{}, map[string]interface{}{"errors": result.Errors}, nil
+		}
+		return http.StatusOK, http.Header{}, map[string]interface{}{"data": result.Data, "errors": result.Errors}, nil
+	}
+}
+
+func isEmptyData(data map[string]interface{}) bool {
+	for _, v := range data {
+		if nil != v {
+			return false
+		}
+	}
+	return true
+}
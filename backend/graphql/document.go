@@ -0,0 +1,105 @@
+package graphql
+
+import (
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/spf13/cast"
+	"strings"
+)
+
+const (
+	// BackendService扩展属性：完整的GraphQL文档；设置后不再根据Method和参数生成
+	ServiceExtKeyQuery = "graphql-query"
+	// BackendService扩展属性：操作类型，query（默认）或 mutation
+	ServiceExtKeyOperationType = "graphql-operation-type"
+	// BackendService扩展属性：操作名称
+	ServiceExtKeyOperationName = "graphql-operation-name"
+	// BackendService扩展属性：根字段的选择集，例如：id name email
+	ServiceExtKeySelection = "graphql-selection"
+	// BackendService扩展属性：变量的GraphQL类型，例如：{id: "ID!"}；未设置时按参数的Java类型转换
+	ServiceExtKeyVariableTypes = "graphql-variable-types"
+)
+
+const (
+	OperationQuery    = "query"
+	OperationMutation = "mutation"
+)
+
+// BuildDocument 根据BackendService生成GraphQL文档，返回文档和根字段名：
+// Method 为根字段名，Arguments 映射为同名变量和根字段参数。例如：
+// query($id: Int) { user(id: $id) { id name } }
+// 设置了 graphql-query 扩展属性时，直接使用该文档，根字段名为空。
+func BuildDocument(service flux.BackendService) (string, string, error) {
+	if query := service.ExtString(ServiceExtKeyQuery); "" != query {
+		return query, "", nil
+	}
+	if "" == service.Method {
+		return "", "", fmt.Errorf("graphql root field (method) is required, service: %s", service.ServiceID())
+	}
+	operation := strings.ToLower(service.ExtString(ServiceExtKeyOperationType))
+	switch operation {
+	case "":
+		operation = OperationQuery
+	case OperationQuery, OperationMutation:
+	default:
+		return "", "", fmt.Errorf("unsupported graphql operation type: %s", operation)
+	}
+	types := make(map[string]string, len(service.Arguments))
+	if v, ok := service.Ext(ServiceExtKeyVariableTypes); ok {
+		types = cast.ToStringMapString(v)
+	}
+	var sb strings.Builder
+	sb.WriteString(operation)
+	if name := service.ExtString(ServiceExtKeyOperationName); "" != name {
+		sb.WriteString(" " + name)
+	}
+	if len(service.Arguments) > 0 {
+		vars := make([]string, len(service.Arguments))
+		args := make([]string, len(service.Arguments))
+		for i, arg := range service.Arguments {
+			t, ok := types[arg.Name]
+			if !ok {
+				// Viper读取的配置Key为小写
+				t, ok = types[strings.ToLower(arg.Name)]
+			}
+			if !ok {
+				t = graphqlTypeOf(arg.Class, arg.Generic)
+			}
+			vars[i] = "$" + arg.Name + ": " + t
+			args[i] = arg.Name + ": $" + arg.Name
+		}
+		sb.WriteString("(" + strings.Join(vars, ", ") + ")")
+		sb.WriteString(" { " + service.Method + "(" + strings.Join(args, ", ") + ")")
+	} else {
+		sb.WriteString(" { " + service.Method)
+	}
+	if selection := service.ExtString(ServiceExtKeySelection); "" != selection {
+		sb.WriteString(" { " + selection + " }")
+	}
+	sb.WriteString(" }")
+	return sb.String(), service.Method, nil
+}
+
+// graphqlTypeOf 将Java类型转换为GraphQL类型；自定义类型使用类的简单名称，例如：com.foo.UserInput -> UserInput
+func graphqlTypeOf(class string, generic []string) string {
+	switch class {
+	case flux.JavaLangStringClassName, "string", "char", "java.lang.Character":
+		return "String"
+	case flux.JavaLangIntegerClassName, flux.JavaLangLongClassName, "int", "long", "short", "byte",
+		"java.lang.Short", "java.lang.Byte":
+		return "Int"
+	case flux.JavaLangFloatClassName, flux.JavaLangDoubleClassName, "float", "double":
+		return "Float"
+	case flux.JavaLangBooleanClassName, "boolean":
+		return "Boolean"
+	case flux.JavaUtilListClassName, "java.util.ArrayList", "java.util.Set":
+		if len(generic) > 0 {
+			return "[" + graphqlTypeOf(generic[0], nil) + "]"
+		}
+		return "[String]"
+	case "":
+		return "String"
+	default:
+		return class[strings.LastIndex(class, ".")+1:]
+	}
+}
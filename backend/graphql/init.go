@@ -0,0 +1,11 @@
+package graphql
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+)
+
+func init() {
+	ext.StoreBackendTransport(flux.ProtoGraphQL, NewGraphQLBackendTransport())
+	ext.StoreBackendTransportDecodeFunc(flux.ProtoGraphQL, NewGraphQLBackendTransportDecodeFunc())
+}
@@ -0,0 +1,130 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/backend"
+	"github.com/bytepowered/flux/ext"
+	"github.com/spf13/cast"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	_ flux.BackendTransport = new(BackendTransportService)
+)
+
+// Response GraphQL上游的原始响应，由DecodeFunc解析 data/errors
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// 自动生成文档时的根字段名，用于展开响应数据
+	Field string
+}
+
+// BackendTransportService 转发到GraphQL上游的BackendService：
+// BackendService.RemoteHost 为上游地址（可包含scheme，默认http），Interface 为GraphQL接口路径，Method 为根字段名。
+type BackendTransportService struct {
+	httpClient *http.Client
+}
+
+// NewGraphQLBackendTransport New GraphQL backend instance
+func NewGraphQLBackendTransport() flux.BackendTransport {
+	return &BackendTransportService{
+		httpClient: &http.Client{
+			Timeout: time.Second * 10,
+		},
+	}
+}
+
+// Exchange do exchange with context
+func (b *BackendTransportService) Exchange(ctx flux.Context) *flux.ServeError {
+	return backend.DoExchange(ctx, b)
+}
+
+// Invoke invoke backend service with context
+func (b *BackendTransportService) Invoke(service flux.BackendService, ctx flux.Context) (interface{}, *flux.ServeError) {
+	newRequest, field, err := b.Assemble(service, ctx)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageGraphQLAssembleFailed,
+			Internal:   err,
+		}
+	}
+	to := service.AttrRpcTimeout()
+	timeout, err := time.ParseDuration(to)
+	if err != nil {
+		timeout = time.Second * 10
+	}
+	toctx, cancel := context.WithTimeout(newRequest.Context(), timeout)
+	defer cancel()
+	resp, err := b.httpClient.Do(newRequest.WithContext(toctx))
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayBackend,
+			Message:    flux.ErrorMessageGraphQLInvokeFailed,
+			Internal:   err,
+		}
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayBackend,
+			Message:    flux.ErrorMessageGraphQLInvokeFailed,
+			Internal:   err,
+		}
+	}
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: data, Field: field}, nil
+}
+
+// Assemble 生成GraphQL请求：文档由BackendService定义生成，参数值作为variables
+func (b *BackendTransportService) Assemble(service flux.BackendService, ctx flux.Context) (*http.Request, string, error) {
+	document, field, err := BuildDocument(service)
+	if nil != err {
+		return nil, "", err
+	}
+	variables, err := backend.ResolveArgumentValues(service.Arguments, ctx)
+	if nil != err {
+		return nil, "", err
+	}
+	payload := map[string]interface{}{
+		"query":     document,
+		"variables": variables,
+	}
+	if name := service.ExtString(ServiceExtKeyOperationName); "" != name {
+		payload["operationName"] = name
+	}
+	data, err := ext.JSONMarshal(payload)
+	if nil != err {
+		return nil, "", err
+	}
+	url := service.RemoteHost + service.Interface
+	if !strings.Contains(service.RemoteHost, "://") {
+		url = "http://" + url
+	}
+	newRequest, err := http.NewRequestWithContext(ctx.Context(), http.MethodPost, url, bytes.NewReader(data))
+	if nil != err {
+		return nil, "", err
+	}
+	// Header透传以及传递AttrValues
+	header, _ := ctx.Request().HeaderValues()
+	newRequest.Header = header.Clone()
+	for k, v := range ctx.Attributes() {
+		newRequest.Header.Set(k, cast.ToString(v))
+	}
+	// 由Transport处理压缩
+	newRequest.Header.Del("Accept-Encoding")
+	newRequest.Header.Set("Content-Type", "application/json")
+	newRequest.Header.Set("Accept", "application/json")
+	newRequest.Header.Set("User-Agent", "FluxGo/Backend/v1")
+	return newRequest, field, nil
+}
@@ -0,0 +1,34 @@
+package grpc
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/backend"
+	"github.com/bytepowered/flux/ext"
+	"io/ioutil"
+)
+
+// DefaultArgumentsAssembleFunc gRPC默认参数封装处理：Endpoint参数按参数名映射为请求消息的字段，Complex参数映射为嵌套消息；
+// 未定义参数时，透传请求Body（JSON）作为请求消息。
+func DefaultArgumentsAssembleFunc(arguments []flux.Argument, ctx flux.Context) (map[string]interface{}, error) {
+	if len(arguments) == 0 {
+		return readJSONBody(ctx)
+	}
+	return backend.ResolveArgumentValues(arguments, ctx)
+}
+
+func readJSONBody(ctx flux.Context) (map[string]interface{}, error) {
+	reader, err := ctx.Request().RequestBodyReader()
+	if nil != err {
+		return nil, err
+	}
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	if nil != err {
+		return nil, err
+	}
+	values := make(map[string]interface{}, 8)
+	if len(data) == 0 {
+		return values, nil
+	}
+	return values, ext.JSONUnmarshal(data, &values)
+}
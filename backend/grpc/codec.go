@@ -0,0 +1,44 @@
+package grpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+var (
+	errTruncatedMessage = errors.New("grpc: truncated message")
+)
+
+// EncodeMessage 按Message定义将参数值编码为Protobuf二进制数据；
+// 参数值按Protobuf JSON映射规则转换：字段名支持proto字段名和JSON名称，64位整数可为字符串，bytes为Base64字符串，enum可为名称；
+// 未定义的字段被忽略。
+func EncodeMessage(md *desc.MessageDescriptor, values map[string]interface{}) ([]byte, error) {
+	data, err := json.Marshal(values)
+	if nil != err {
+		return nil, err
+	}
+	msg := dynamic.NewMessage(md)
+	if err := msg.UnmarshalJSONPB(&jsonpb.Unmarshaler{AllowUnknownFields: true}, data); nil != err {
+		return nil, fmt.Errorf("encode message %s: %w", md.GetFullyQualifiedName(), err)
+	}
+	return msg.Marshal()
+}
+
+// DecodeMessage 按Message定义将Protobuf二进制数据解码为Map；
+// 按Protobuf JSON映射规则转换：字段名使用JSON名称，64位整数为字符串，enum为名称；未定义的字段被忽略。
+func DecodeMessage(md *desc.MessageDescriptor, data []byte) (map[string]interface{}, error) {
+	msg := dynamic.NewMessage(md)
+	if err := msg.Unmarshal(data); nil != err {
+		return nil, fmt.Errorf("decode message %s: %w", md.GetFullyQualifiedName(), err)
+	}
+	text, err := msg.MarshalJSONPB(&jsonpb.Marshaler{})
+	if nil != err {
+		return nil, err
+	}
+	out := make(map[string]interface{}, len(md.GetFields()))
+	return out, json.Unmarshal(text, &out)
+}
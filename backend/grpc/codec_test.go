@@ -0,0 +1,94 @@
+package grpc
+
+import (
+	pb "github.com/golang/protobuf/jsonpb/jsonpb_test_proto"
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/desc"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEncodeMessage(t *testing.T) {
+	assert := assert2.New(t)
+	md, err := desc.LoadMessageDescriptorForMessage(new(pb.Widget))
+	if !assert.NoError(err) {
+		return
+	}
+	// 字段名支持proto字段名和JSON名称；64位整数为字符串，bytes为Base64，enum为名称
+	data, err := EncodeMessage(md, map[string]interface{}{
+		"color":  "BLUE",
+		"rColor": []interface{}{"RED", 1},
+		"simple": map[string]interface{}{
+			"o_bool":    true,
+			"oInt32":    -12,
+			"oInt64Str": "9007199254740993",
+			"o_uint64":  "18446744073709551615",
+			"oSint32":   -7,
+			"oDouble":   1.5,
+			"oString":   "flux",
+			"oBytes":    "AQID",
+		},
+		"r_simple": []interface{}{map[string]interface{}{"oString": "a"}},
+		"unknown":  "ignored",
+	})
+	if !assert.NoError(err) {
+		return
+	}
+	actual := new(pb.Widget)
+	assert.NoError(proto.Unmarshal(data, actual))
+	expected := &pb.Widget{
+		Color:  pb.Widget_BLUE.Enum(),
+		RColor: []pb.Widget_Color{pb.Widget_RED, pb.Widget_GREEN},
+		Simple: &pb.Simple{
+			OBool:     proto.Bool(true),
+			OInt32:    proto.Int32(-12),
+			OInt64Str: proto.Int64(9007199254740993),
+			OUint64:   proto.Uint64(18446744073709551615),
+			OSint32:   proto.Int32(-7),
+			ODouble:   proto.Float64(1.5),
+			OString:   proto.String("flux"),
+			OBytes:    []byte{1, 2, 3},
+		},
+		RSimple: []*pb.Simple{{OString: proto.String("a")}},
+	}
+	assert.True(proto.Equal(expected, actual), actual.String())
+	// 值类型与字段定义不匹配
+	_, err = EncodeMessage(md, map[string]interface{}{"color": "PINK"})
+	assert.Error(err)
+}
+
+func TestDecodeMessage(t *testing.T) {
+	assert := assert2.New(t)
+	md, err := desc.LoadMessageDescriptorForMessage(new(pb.Mappy))
+	if !assert.NoError(err) {
+		return
+	}
+	data, err := proto.Marshal(&pb.Mappy{
+		Nummy: map[int64]int32{-1: 2},
+		Strry: map[string]string{"k": "v"},
+		Objjy: map[int32]*pb.Simple3{1: {Dub: 2.5}},
+		Enumy: map[string]pb.Numeral{"x": pb.Numeral_ROMAN},
+		Booly: map[bool]bool{true: true},
+	})
+	if !assert.NoError(err) {
+		return
+	}
+	values, err := DecodeMessage(md, data)
+	assert.NoError(err)
+	assert.Equal(map[string]interface{}{
+		"nummy": map[string]interface{}{"-1": float64(2)},
+		"strry": map[string]interface{}{"k": "v"},
+		"objjy": map[string]interface{}{"1": map[string]interface{}{"dub": 2.5}},
+		"enumy": map[string]interface{}{"x": "ROMAN"},
+		"booly": map[string]interface{}{"true": true},
+	}, values)
+	// 64位整数为字符串，字段名使用JSON名称
+	md, _ = desc.LoadMessageDescriptorForMessage(new(pb.Simple))
+	data, _ = proto.Marshal(&pb.Simple{OInt64: proto.Int64(-5), OBytes: []byte{1, 2, 3}})
+	values, err = DecodeMessage(md, data)
+	assert.NoError(err)
+	assert.Equal(map[string]interface{}{"oInt64": "-5", "oBytes": "AQID"}, values)
+	// 截断的数据
+	_, err = DecodeMessage(md, data[:len(data)-1])
+	assert.Error(err)
+}
@@ -0,0 +1,26 @@
+package grpc
+
+import (
+	"errors"
+	"github.com/bytepowered/flux"
+	"net/http"
+)
+
+var (
+	ErrUnknownGrpcBackendResponse = errors.New("BACKEND:UNKNOWN_GRPC_RESPONSE")
+)
+
+// NewGrpcBackendTransportDecodeFunc 按方法的响应消息定义，将Protobuf响应转换为JSON对象
+func NewGrpcBackendTransportDecodeFunc() flux.BackendTransportDecodeFunc {
+	return func(ctx flux.Context, value interface{}) (statusCode int, headers http.Header, body interface{}, err error) {
+		resp, ok := value.(*Response)
+		if !ok || nil == resp.Type {
+			return http.StatusInternalServerError, http.Header{}, nil, ErrUnknownGrpcBackendResponse
+		}
+		values, err := DecodeMessage(resp.Type, resp.Message)
+		if nil != err {
+			return http.StatusInternalServerError, http.Header{}, nil, err
+		}
+		return http.StatusOK, http.Header{}, values, nil
+	}
+}
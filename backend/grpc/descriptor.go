@@ -0,0 +1,136 @@
+package grpc
+
+import (
+	"fmt"
+	"github.com/bytepowered/flux/logger"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// DescriptorPool Protobuf描述符仓库，按全限定名索引Service和Message定义；
+// Proto文件在其依赖文件全部注册后才生效，依赖文件未注册时从已链接的golang/protobuf生成代码中查找。
+type DescriptorPool struct {
+	pending  map[string]*descriptor.FileDescriptorProto
+	files    map[string]*desc.FileDescriptor
+	services map[string]*desc.ServiceDescriptor
+	messages map[string]*desc.MessageDescriptor
+	mu       sync.RWMutex
+}
+
+func NewDescriptorPool() *DescriptorPool {
+	return &DescriptorPool{
+		pending:  make(map[string]*descriptor.FileDescriptorProto, 16),
+		files:    make(map[string]*desc.FileDescriptor, 16),
+		services: make(map[string]*desc.ServiceDescriptor, 16),
+		messages: make(map[string]*desc.MessageDescriptor, 64),
+	}
+}
+
+// LoadDescriptorSetFile 加载 protoc --descriptor_set_out --include_imports 生成的描述符文件
+func (p *DescriptorPool) LoadDescriptorSetFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if nil != err {
+		return fmt.Errorf("read descriptor set: %w", err)
+	}
+	set := new(descriptor.FileDescriptorSet)
+	if err := proto.Unmarshal(data, set); nil != err {
+		return fmt.Errorf("decode descriptor set, path: %s, err: %w", path, err)
+	}
+	for _, file := range set.File {
+		p.AddFile(file)
+	}
+	return nil
+}
+
+// AddFile 注册Proto文件中定义的Service和Message；同名文件只注册一次
+func (p *DescriptorPool) AddFile(file *descriptor.FileDescriptorProto) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.files[file.GetName()]; ok {
+		return
+	}
+	p.pending[file.GetName()] = file
+	p.resolve()
+}
+
+// resolve 创建依赖已就绪的Proto文件描述符，直到没有可创建的文件
+func (p *DescriptorPool) resolve() {
+	for resolved := true; resolved; {
+		resolved = false
+		for name, file := range p.pending {
+			deps, ok := p.dependencies(file)
+			if !ok {
+				continue
+			}
+			delete(p.pending, name)
+			fd, err := desc.CreateFileDescriptor(file, deps...)
+			if nil != err {
+				logger.Warnw("gRPC descriptor invalid", "file", name, "error", err)
+				continue
+			}
+			p.addFile(fd)
+			resolved = true
+		}
+	}
+}
+
+func (p *DescriptorPool) dependencies(file *descriptor.FileDescriptorProto) ([]*desc.FileDescriptor, bool) {
+	deps := make([]*desc.FileDescriptor, 0, len(file.Dependency))
+	for _, name := range file.Dependency {
+		if fd, ok := p.files[name]; ok {
+			deps = append(deps, fd)
+			continue
+		}
+		if _, ok := p.pending[name]; ok {
+			return nil, false
+		}
+		fd, err := desc.LoadFileDescriptor(name)
+		if nil != err {
+			return nil, false
+		}
+		p.addFile(fd)
+		deps = append(deps, fd)
+	}
+	return deps, true
+}
+
+func (p *DescriptorPool) addFile(fd *desc.FileDescriptor) {
+	p.files[fd.GetName()] = fd
+	for _, sd := range fd.GetServices() {
+		p.services[sd.GetFullyQualifiedName()] = sd
+	}
+	for _, md := range fd.GetMessageTypes() {
+		p.addMessage(md)
+	}
+}
+
+func (p *DescriptorPool) addMessage(md *desc.MessageDescriptor) {
+	p.messages[md.GetFullyQualifiedName()] = md
+	for _, nested := range md.GetNestedMessageTypes() {
+		p.addMessage(nested)
+	}
+}
+
+// FindMethod 查找Service的方法定义；service为全限定名，例如 foo.v1.UserService
+func (p *DescriptorPool) FindMethod(service, method string) (*desc.MethodDescriptor, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	sd, ok := p.services[strings.TrimPrefix(service, ".")]
+	if !ok {
+		return nil, false
+	}
+	md := sd.FindMethodByName(method)
+	return md, nil != md
+}
+
+// FindMessage 查找Message定义；name为全限定名，可包含 . 前缀
+func (p *DescriptorPool) FindMessage(name string) (*desc.MessageDescriptor, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	md, ok := p.messages[strings.TrimPrefix(name, ".")]
+	return md, ok
+}
@@ -0,0 +1,11 @@
+package grpc
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+)
+
+func init() {
+	ext.StoreBackendTransport(flux.ProtoGRPC, NewGrpcBackendTransport())
+	ext.StoreBackendTransportDecodeFunc(flux.ProtoGRPC, NewGrpcBackendTransportDecodeFunc())
+}
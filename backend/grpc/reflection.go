@@ -0,0 +1,81 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+const (
+	// gRPC服务端反射接口
+	reflectionMethodPath = "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"
+)
+
+// reflectionRequest ServerReflectionRequest：只使用 file_containing_symbol 查询
+type reflectionRequest struct {
+	Host                 string `protobuf:"bytes,1,opt,name=host,proto3"`
+	FileContainingSymbol string `protobuf:"bytes,4,opt,name=file_containing_symbol,proto3"`
+}
+
+func (m *reflectionRequest) Reset()         { *m = reflectionRequest{} }
+func (m *reflectionRequest) String() string { return proto.CompactTextString(m) }
+func (*reflectionRequest) ProtoMessage()    {}
+
+// reflectionResponse ServerReflectionResponse：只解析 file_descriptor_response 和 error_response
+type reflectionResponse struct {
+	FileDescriptorResponse *fileDescriptorResponse `protobuf:"bytes,4,opt,name=file_descriptor_response"`
+	ErrorResponse          *reflectionError        `protobuf:"bytes,7,opt,name=error_response"`
+}
+
+func (m *reflectionResponse) Reset()         { *m = reflectionResponse{} }
+func (m *reflectionResponse) String() string { return proto.CompactTextString(m) }
+func (*reflectionResponse) ProtoMessage()    {}
+
+type fileDescriptorResponse struct {
+	FileDescriptorProto [][]byte `protobuf:"bytes,1,rep,name=file_descriptor_proto"`
+}
+
+func (m *fileDescriptorResponse) Reset()         { *m = fileDescriptorResponse{} }
+func (m *fileDescriptorResponse) String() string { return proto.CompactTextString(m) }
+func (*fileDescriptorResponse) ProtoMessage()    {}
+
+type reflectionError struct {
+	ErrorCode    int32  `protobuf:"varint,1,opt,name=error_code,proto3"`
+	ErrorMessage string `protobuf:"bytes,2,opt,name=error_message,proto3"`
+}
+
+func (m *reflectionError) Reset()         { *m = reflectionError{} }
+func (m *reflectionError) String() string { return proto.CompactTextString(m) }
+func (*reflectionError) ProtoMessage()    {}
+
+// resolveByReflection 通过服务端反射接口查询定义了symbol的Proto文件（包含其依赖文件），注册到描述符仓库
+func (b *BackendTransportService) resolveByReflection(ctx context.Context, host, symbol string) error {
+	request, err := proto.Marshal(&reflectionRequest{Host: host, FileContainingSymbol: symbol})
+	if nil != err {
+		return err
+	}
+	data, err := b.unary(ctx, host, reflectionMethodPath, nil, request)
+	if nil != err {
+		return fmt.Errorf("server reflection, host: %s, err: %w", host, err)
+	}
+	response := new(reflectionResponse)
+	if err := proto.Unmarshal(data.Message, response); nil != err {
+		return fmt.Errorf("decode server reflection response: %w", err)
+	}
+	if nil != response.ErrorResponse {
+		return fmt.Errorf("server reflection, symbol: %s, code: %d, message: %s",
+			symbol, response.ErrorResponse.ErrorCode, response.ErrorResponse.ErrorMessage)
+	}
+	if nil == response.FileDescriptorResponse {
+		return fmt.Errorf("server reflection, symbol: %s, file descriptor not returned", symbol)
+	}
+	for _, raw := range response.FileDescriptorResponse.FileDescriptorProto {
+		file := new(descriptor.FileDescriptorProto)
+		if err := proto.Unmarshal(raw, file); nil != err {
+			return fmt.Errorf("decode file descriptor: %w", err)
+		}
+		b.pool.AddFile(file)
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/backend"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/pkg"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/spf13/cast"
+	"golang.org/x/net/http2"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	configKeyDescriptorFiles = "descriptor-files"
+	configKeyReflection      = "reflection"
+	configKeyTLSEnable       = "tls-enable"
+	configKeyTimeout         = "timeout"
+)
+
+var (
+	_ flux.BackendTransport = new(BackendTransportService)
+)
+
+type (
+	// ArgumentsAssembleFunc gRPC请求消息封装函数，返回按字段名组织的消息值
+	ArgumentsAssembleFunc func(arguments []flux.Argument, context flux.Context) (map[string]interface{}, error)
+)
+
+// Response gRPC调用结果：响应消息的Protobuf数据及其类型，由DecodeFunc转换为JSON对象
+type Response struct {
+	Header  http.Header
+	Trailer http.Header
+	Message []byte
+	Type    *desc.MessageDescriptor
+}
+
+// StatusError gRPC服务端返回的非OK状态
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("grpc status: %d, message: %s", e.Code, e.Message)
+}
+
+// BackendTransportService 基于HTTP/2的gRPC协议BackendService：
+// BackendService.Interface 为gRPC服务全限定名（例如 foo.v1.UserService），Method 为方法名；
+// 方法定义从描述符文件加载，未找到时通过服务端反射接口查询。
+type BackendTransportService struct {
+	// 可外部配置
+	ArgumentsAssembleFunc ArgumentsAssembleFunc
+	// 内部私有
+	pool       *DescriptorPool
+	httpClient *http.Client
+	scheme     string
+	reflection bool
+	timeout    time.Duration
+}
+
+// NewGrpcBackendTransport New gRPC backend instance
+func NewGrpcBackendTransport() flux.BackendTransport {
+	return &BackendTransportService{
+		ArgumentsAssembleFunc: DefaultArgumentsAssembleFunc,
+		pool:                  NewDescriptorPool(),
+	}
+}
+
+// DescriptorPool 返回描述符仓库，可手动注册Proto文件定义
+func (b *BackendTransportService) DescriptorPool() *DescriptorPool {
+	return b.pool
+}
+
+// Init init backend
+func (b *BackendTransportService) Init(config *flux.Configuration) error {
+	logger.Info("gRPC backend transport initializing")
+	config.SetDefaults(map[string]interface{}{
+		configKeyReflection: true,
+		configKeyTLSEnable:  false,
+		configKeyTimeout:    time.Second * 10,
+	})
+	for _, path := range config.GetStringSlice(configKeyDescriptorFiles) {
+		if err := b.pool.LoadDescriptorSetFile(path); nil != err {
+			return err
+		}
+		logger.Infow("gRPC backend transport load descriptor set", "path", path)
+	}
+	b.reflection = config.GetBool(configKeyReflection)
+	b.timeout = config.GetDuration(configKeyTimeout)
+	transport := &http2.Transport{}
+	if config.GetBool(configKeyTLSEnable) {
+		b.scheme = "https"
+		transport.DialTLS = func(network, addr string, tc *tls.Config) (net.Conn, error) {
+			conn, err := net.Dial(network, addr)
+			if nil != err {
+				return nil, err
+			}
+			return backend.UpstreamTLSClient(context.Background(), conn, addr, tc)
+		}
+	} else {
+		// h2c: 明文HTTP/2
+		b.scheme = "http"
+		transport.AllowHTTP = true
+		transport.DialTLS = func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		}
+	}
+	b.httpClient = &http.Client{Transport: transport}
+	if pkg.IsNil(b.ArgumentsAssembleFunc) {
+		b.ArgumentsAssembleFunc = DefaultArgumentsAssembleFunc
+	}
+	return nil
+}
+
+// Exchange do exchange with context
+func (b *BackendTransportService) Exchange(ctx flux.Context) *flux.ServeError {
+	return backend.DoExchange(ctx, b)
+}
+
+// Invoke invoke backend service with context
+func (b *BackendTransportService) Invoke(service flux.BackendService, ctx flux.Context) (interface{}, *flux.ServeError) {
+	method, err := b.lookupMethod(ctx.Context(), service)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageGrpcMethodNotFound,
+			Internal:   err,
+		}
+	}
+	message, err := b.assemble(method, service, ctx)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusBadRequest,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageGrpcAssembleFailed,
+			Internal:   err,
+		}
+	}
+	metadata := make(http.Header, 4)
+	for k, v := range ctx.Attributes() {
+		metadata.Set(k, cast.ToString(v))
+	}
+	timeout := b.timeout
+	if to := service.AttrRpcTimeout(); "" != to {
+		if d, err := time.ParseDuration(to); nil == err {
+			timeout = d
+		} else {
+			logger.Warnf("Illegal endpoint rpc-timeout: ", to)
+		}
+	}
+	toctx, cancel := context.WithTimeout(ctx.Context(), timeout)
+	defer cancel()
+	path := "/" + service.Interface + "/" + service.Method
+	resp, err := b.unary(toctx, service.RemoteHost, path, metadata, message)
+	if nil != err {
+		serr := &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayBackend,
+			Message:    flux.ErrorMessageGrpcInvokeFailed,
+			Internal:   err,
+		}
+		if status, ok := err.(*StatusError); ok {
+			serr.StatusCode = httpStatusOf(status.Code)
+			serr.UpstreamStatus = serr.StatusCode
+			serr.UpstreamErrorCode = strconv.Itoa(status.Code)
+			// Unavailable
+			serr.Retryable = 14 == status.Code
+		} else {
+			serr.Retryable = true
+		}
+		return nil, serr
+	}
+	resp.Type = method.GetOutputType()
+	return resp, nil
+}
+
+func (b *BackendTransportService) lookupMethod(ctx context.Context, service flux.BackendService) (*desc.MethodDescriptor, error) {
+	if method, ok := b.pool.FindMethod(service.Interface, service.Method); ok {
+		return method, nil
+	}
+	if b.reflection {
+		if err := b.resolveByReflection(ctx, service.RemoteHost, service.Interface); nil != err {
+			return nil, err
+		}
+		if method, ok := b.pool.FindMethod(service.Interface, service.Method); ok {
+			return method, nil
+		}
+	}
+	return nil, fmt.Errorf("grpc method not found: %s/%s", service.Interface, service.Method)
+}
+
+func (b *BackendTransportService) assemble(method *desc.MethodDescriptor, service flux.BackendService, ctx flux.Context) ([]byte, error) {
+	if method.IsClientStreaming() || method.IsServerStreaming() {
+		return nil, fmt.Errorf("grpc streaming method not supported: %s/%s", service.Interface, service.Method)
+	}
+	values, err := b.ArgumentsAssembleFunc(service.Arguments, ctx)
+	if nil != err {
+		return nil, err
+	}
+	return EncodeMessage(method.GetInputType(), values)
+}
+
+// unary 执行gRPC一元调用：发送一个请求消息，读取第一个响应消息和grpc-status
+func (b *BackendTransportService) unary(ctx context.Context, host, path string, metadata http.Header, message []byte) (*Response, error) {
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	frame = append(frame, message...)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, b.scheme+"://"+host+path, bytes.NewReader(frame))
+	if nil != err {
+		return nil, err
+	}
+	for k, v := range metadata {
+		request.Header[k] = v
+	}
+	request.Header.Set("Content-Type", "application/grpc")
+	request.Header.Set("TE", "trailers")
+	request.Header.Set("User-Agent", "FluxGo/Backend/v1")
+	if deadline, ok := ctx.Deadline(); ok {
+		request.Header.Set("Grpc-Timeout", strconv.FormatInt(time.Until(deadline).Milliseconds(), 10)+"m")
+	}
+	response, err := b.httpClient.Do(request)
+	if nil != err {
+		return nil, err
+	}
+	defer response.Body.Close()
+	data, err := ioutil.ReadAll(response.Body)
+	if nil != err {
+		return nil, err
+	}
+	if http.StatusOK != response.StatusCode {
+		return nil, fmt.Errorf("unexpected http status: %d", response.StatusCode)
+	}
+	// Trailers-Only 响应的状态在Header中
+	status := response.Trailer.Get("Grpc-Status")
+	if "" == status {
+		status = response.Header.Get("Grpc-Status")
+	}
+	if code, _ := strconv.Atoi(status); "" == status || 0 != code {
+		msg := response.Trailer.Get("Grpc-Message")
+		if "" == msg {
+			msg = response.Header.Get("Grpc-Message")
+		}
+		msg, _ = url.PathUnescape(msg)
+		if "" == status {
+			code, msg = 2, "grpc-status not returned"
+		}
+		return nil, &StatusError{Code: code, Message: msg}
+	}
+	if len(data) < 5 {
+		return nil, errTruncatedMessage
+	}
+	if 0 != data[0] {
+		return nil, fmt.Errorf("compressed grpc message not supported")
+	}
+	size := binary.BigEndian.Uint32(data[1:5])
+	if uint32(len(data)-5) < size {
+		return nil, errTruncatedMessage
+	}
+	return &Response{
+		Header:  response.Header,
+		Trailer: response.Trailer,
+		Message: data[5 : 5+size],
+	}, nil
+}
+
+// httpStatusOf 将gRPC状态码转换为Http状态码
+func httpStatusOf(code int) int {
+	switch code {
+	case 3, 9, 11: // InvalidArgument, FailedPrecondition, OutOfRange
+		return http.StatusBadRequest
+	case 4: // DeadlineExceeded
+		return http.StatusGatewayTimeout
+	case 5: // NotFound
+		return http.StatusNotFound
+	case 6, 10: // AlreadyExists, Aborted
+		return http.StatusConflict
+	case 7: // PermissionDenied
+		return http.StatusForbidden
+	case 8: // ResourceExhausted
+		return http.StatusTooManyRequests
+	case 12: // Unimplemented
+		return http.StatusNotImplemented
+	case 14: // Unavailable
+		return http.StatusServiceUnavailable
+	case 16: // Unauthenticated
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
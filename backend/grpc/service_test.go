@@ -0,0 +1,119 @@
+package grpc
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/bytepowered/flux"
+	pb "github.com/golang/protobuf/jsonpb/jsonpb_test_proto"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	assert2 "github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fluxContext = flux.Context
+
+type grpcTestContext struct {
+	fluxContext
+}
+
+func (c *grpcTestContext) Context() context.Context { return context.Background() }
+func (c *grpcTestContext) Attributes() map[string]interface{} {
+	return map[string]interface{}{"x-user": "u-1"}
+}
+
+// newGrpcTestServer 测试用gRPC服务端：WidgetService.Get 返回包含请求消息的Widget；请求oString为missing时返回NotFound
+func newGrpcTestServer(t *testing.T) *httptest.Server {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		assert2.Equal(t, "/flux.test.WidgetService/Get", r.URL.Path)
+		assert2.Equal(t, "u-1", r.Header.Get("X-User"))
+		req := new(pb.Simple)
+		if len(data) < 5 || nil != proto.Unmarshal(data[5:], req) {
+			w.Header().Set("Grpc-Status", "13")
+			return
+		}
+		if "missing" == req.GetOString() {
+			w.Header().Set("Grpc-Status", "5")
+			w.Header().Set("Grpc-Message", "widget%20not%20found")
+			return
+		}
+		message, _ := proto.Marshal(&pb.Widget{Color: pb.Widget_GREEN.Enum(), Simple: req})
+		frame := make([]byte, 5, 5+len(message))
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Header().Set("Content-Type", "application/grpc")
+		_, _ = w.Write(append(frame, message...))
+		w.Header().Set("Grpc-Status", "0")
+	})
+	return httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+}
+
+func newGrpcTestTransport(t *testing.T, values map[string]interface{}) *BackendTransportService {
+	transport := NewGrpcBackendTransport().(*BackendTransportService)
+	config := flux.NewConfiguration(nil)
+	config.Set(configKeyReflection, false)
+	assert2.NoError(t, transport.Init(config))
+	transport.ArgumentsAssembleFunc = func([]flux.Argument, flux.Context) (map[string]interface{}, error) {
+		return values, nil
+	}
+	// 依赖的test_objects.proto从golang/protobuf已注册的描述符中加载
+	transport.DescriptorPool().AddFile(&descriptor.FileDescriptorProto{
+		Name:       proto.String("widget_service.proto"),
+		Package:    proto.String("flux.test"),
+		Dependency: []string{"test_objects.proto"},
+		Service: []*descriptor.ServiceDescriptorProto{{
+			Name: proto.String("WidgetService"),
+			Method: []*descriptor.MethodDescriptorProto{{
+				Name:       proto.String("Get"),
+				InputType:  proto.String(".jsonpb.Simple"),
+				OutputType: proto.String(".jsonpb.Widget"),
+			}},
+		}},
+	})
+	return transport
+}
+
+func TestBackendTransportService_Invoke(t *testing.T) {
+	assert := assert2.New(t)
+	server := newGrpcTestServer(t)
+	defer server.Close()
+	service := flux.BackendService{
+		RemoteHost: strings.TrimPrefix(server.URL, "http://"),
+		Interface:  "flux.test.WidgetService",
+		Method:     "Get",
+	}
+	transport := newGrpcTestTransport(t, map[string]interface{}{"oString": "flux", "o_int64": "42"})
+	resp, serr := transport.Invoke(service, &grpcTestContext{})
+	if !assert.Nil(serr) {
+		return
+	}
+	status, _, body, err := NewGrpcBackendTransportDecodeFunc()(&grpcTestContext{}, resp)
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, status)
+	assert.Equal(map[string]interface{}{
+		"color":  "GREEN",
+		"simple": map[string]interface{}{"oString": "flux", "oInt64": "42"},
+	}, body)
+	// 服务端返回非OK状态
+	transport = newGrpcTestTransport(t, map[string]interface{}{"oString": "missing"})
+	_, serr = transport.Invoke(service, &grpcTestContext{})
+	if assert.NotNil(serr) {
+		assert.Equal(http.StatusNotFound, serr.StatusCode)
+		assert.Equal("5", serr.UpstreamErrorCode)
+		assert.Equal("widget not found", serr.Internal.(*StatusError).Message)
+		assert.False(serr.Retryable)
+	}
+	// 方法未定义
+	service.Method = "List"
+	_, serr = transport.Invoke(service, &grpcTestContext{})
+	if assert.NotNil(serr) {
+		assert.Equal(flux.ErrorMessageGrpcMethodNotFound, serr.Message)
+	}
+}
@@ -0,0 +1,85 @@
+package backend
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/spf13/cast"
+	"net/http"
+	"strings"
+)
+
+const (
+	// Endpoint扩展属性：上游响应Header的转发策略，默认为 allow
+	EndpointExtKeyResponseHeaderPolicy = "response-header-policy"
+	// Endpoint扩展属性：deny 策略下允许转发的Header列表
+	EndpointExtKeyResponseHeaderAllowlist = "response-header-allowlist"
+	// Endpoint扩展属性：allow 策略下禁止转发的Header列表
+	EndpointExtKeyResponseHeaderDenylist = "response-header-denylist"
+)
+
+const (
+	// 默认转发全部Header，丢弃 denylist 中的Header
+	ResponseHeaderPolicyAllow = "allow"
+	// 默认丢弃全部Header，只转发 allowlist 中的Header
+	ResponseHeaderPolicyDeny = "deny"
+)
+
+// 响应Body依赖的实体Header，deny 策略下始终转发
+var entityHeaders = []string{flux.HeaderContentType, flux.HeaderContentEncoding, flux.HeaderContentLength}
+
+// FilterResponseHeaders 按Endpoint配置的策略过滤上游响应Header，返回新的Header。
+// 列表项不区分大小写，支持以 * 结尾的前缀匹配，例如：X-Internal-*
+func FilterResponseHeaders(endpoint flux.Endpoint, headers http.Header) http.Header {
+	if len(headers) == 0 {
+		return headers
+	}
+	switch endpoint.ExtString(EndpointExtKeyResponseHeaderPolicy) {
+	case ResponseHeaderPolicyDeny:
+		allowlist := append(headerListOf(endpoint, EndpointExtKeyResponseHeaderAllowlist), entityHeaders...)
+		return selectHeaders(headers, allowlist, true)
+	default:
+		denylist := headerListOf(endpoint, EndpointExtKeyResponseHeaderDenylist)
+		if len(denylist) == 0 {
+			return headers
+		}
+		return selectHeaders(headers, denylist, false)
+	}
+}
+
+func headerListOf(endpoint flux.Endpoint, key string) []string {
+	v, ok := endpoint.Ext(key)
+	if !ok {
+		return nil
+	}
+	if s, ok := v.(string); ok {
+		return strings.Split(s, ",")
+	}
+	return cast.ToStringSlice(v)
+}
+
+// selectHeaders 返回匹配（matched=true）或不匹配（matched=false）列表的Header
+func selectHeaders(headers http.Header, patterns []string, matched bool) http.Header {
+	out := make(http.Header, len(headers))
+	for name, values := range headers {
+		if matched == matchHeader(name, patterns) {
+			out[name] = values
+		}
+	}
+	return out
+}
+
+func matchHeader(name string, patterns []string) bool {
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if "" == p {
+			continue
+		}
+		if strings.HasSuffix(p, "*") {
+			if prefix := p[:len(p)-1]; len(name) >= len(prefix) && strings.EqualFold(name[:len(prefix)], prefix) {
+				return true
+			}
+		} else if strings.EqualFold(name, p) {
+			return true
+		}
+	}
+	return false
+}
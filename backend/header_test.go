@@ -0,0 +1,61 @@
+package backend
+
+import (
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestFilterResponseHeaders(t *testing.T) {
+	upstream := http.Header{
+		"Content-Type":    []string{"application/json"},
+		"X-Powered-By":    []string{"Express"},
+		"X-Internal-Node": []string{"node-1"},
+		"X-Trace-Id":      []string{"t123"},
+	}
+	cases := []struct {
+		extensions map[string]interface{}
+		expect     []string
+	}{
+		{
+			extensions: nil,
+			expect:     []string{"Content-Type", "X-Powered-By", "X-Internal-Node", "X-Trace-Id"},
+		},
+		{
+			extensions: map[string]interface{}{
+				EndpointExtKeyResponseHeaderDenylist: []string{"x-powered-by", "X-Internal-*"},
+			},
+			expect: []string{"Content-Type", "X-Trace-Id"},
+		},
+		{
+			extensions: map[string]interface{}{
+				EndpointExtKeyResponseHeaderPolicy:   ResponseHeaderPolicyAllow,
+				EndpointExtKeyResponseHeaderDenylist: "X-Powered-By, X-Trace-Id",
+			},
+			expect: []string{"Content-Type", "X-Internal-Node"},
+		},
+		{
+			extensions: map[string]interface{}{
+				EndpointExtKeyResponseHeaderPolicy: ResponseHeaderPolicyDeny,
+			},
+			expect: []string{"Content-Type"},
+		},
+		{
+			extensions: map[string]interface{}{
+				EndpointExtKeyResponseHeaderPolicy:    ResponseHeaderPolicyDeny,
+				EndpointExtKeyResponseHeaderAllowlist: []interface{}{"x-trace-id"},
+			},
+			expect: []string{"Content-Type", "X-Trace-Id"},
+		},
+	}
+	assert := assert2.New(t)
+	for _, c := range cases {
+		endpoint := flux.Endpoint{EmbeddedExtensions: flux.EmbeddedExtensions{Extensions: c.extensions}}
+		headers := FilterResponseHeaders(endpoint, upstream)
+		assert.Equal(len(c.expect), len(headers), "headers: %v", headers)
+		for _, name := range c.expect {
+			assert.Equal(upstream.Get(name), headers.Get(name), "header: %s", name)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	HealthCheckTypeHttp  = "http"
+	HealthCheckTypeTcp   = "tcp"
+	HealthCheckTypeDubbo = "dubbo"
+)
+
+const (
+	// 主动检查的调度周期
+	healthCheckTick = time.Second
+)
+
+var (
+	_ flux.Startuper  = new(HealthChecker)
+	_ flux.Shutdowner = new(HealthChecker)
+)
+
+var (
+	dubboTelnetPrompt = []byte("dubbo>")
+	healthChecker     = NewHealthChecker()
+	healthProbes      = map[string]healthProbe{
+		HealthCheckTypeHttp:  probeHttp,
+		HealthCheckTypeTcp:   probeTcp,
+		HealthCheckTypeDubbo: probeDubbo,
+	}
+)
+
+func init() {
+	ext.StoreHookFunc(healthChecker)
+}
+
+// healthProbe 对上游地址执行一次主动检查，返回nil表示健康
+type healthProbe func(ctx context.Context, address string, check flux.UpstreamHealthCheck) error
+
+// upstreamHealth 上游地址的健康状态
+type upstreamHealth struct {
+	unhealthy bool
+	failures  int
+	successes int
+	ejectedAt time.Time
+	probing   bool
+	probedAt  time.Time
+}
+
+// HealthChecker 上游地址健康检查：主动检查按Upstream配置的类型定时探测地址；被动检查根据调用结果统计连续失败。
+// 连续失败达到阈值的地址被标记为不健康，负载均衡时跳过；主动检查连续成功达到阈值后恢复，
+// 未开启主动检查的地址在摘除时长后恢复。健康状态按地址记录，多个上游集群中的相同地址共享状态。
+type HealthChecker struct {
+	mu     sync.Mutex
+	states map[string]*upstreamHealth
+	stop   chan struct{}
+	once   sync.Once
+}
+
+func NewHealthChecker() *HealthChecker {
+	return &HealthChecker{
+		states: make(map[string]*upstreamHealth, 16),
+		stop:   make(chan struct{}),
+	}
+}
+
+func (h *HealthChecker) Startup() error {
+	go h.loop()
+	return nil
+}
+
+func (h *HealthChecker) Shutdown(_ context.Context) error {
+	h.once.Do(func() {
+		close(h.stop)
+	})
+	return nil
+}
+
+func (h *HealthChecker) loop() {
+	ticker := time.NewTicker(healthCheckTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.stop:
+			return
+		case now := <-ticker.C:
+			for _, upstream := range ext.LoadUpstreams() {
+				h.probeAll(upstream, now)
+			}
+		}
+	}
+}
+
+// probeAll 对到达检查间隔的地址执行主动检查；上一次检查未结束的地址不重复检查
+func (h *HealthChecker) probeAll(upstream flux.Upstream, now time.Time) {
+	check := healthCheckOf(upstream)
+	probe, ok := healthProbes[strings.ToLower(check.Type)]
+	if !ok {
+		return
+	}
+	for _, addr := range upstream.Addresses {
+		address := addr.Address
+		h.mu.Lock()
+		state := h.stateOf(address)
+		due := !state.probing && now.Sub(state.probedAt) >= check.Interval
+		if due {
+			state.probing, state.probedAt = true, now
+		}
+		h.mu.Unlock()
+		if !due {
+			continue
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), check.Timeout)
+			defer cancel()
+			err := probe(ctx, address, check)
+			h.mu.Lock()
+			h.stateOf(address).probing = false
+			h.mu.Unlock()
+			if nil != err {
+				logger.Debugw("Upstream health check failed", "upstream", upstream.Name, "address", address, "error", err)
+			}
+			h.report(address, check, nil == err, true)
+		}()
+	}
+}
+
+// Healthy 返回地址是否健康；未开启主动检查时，不健康的地址在摘除时长后恢复
+func (h *HealthChecker) Healthy(address string, check flux.UpstreamHealthCheck) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	state, ok := h.states[address]
+	if !ok || !state.unhealthy {
+		return true
+	}
+	if "" == check.Type && time.Since(state.ejectedAt) >= check.EjectDuration {
+		state.unhealthy, state.failures = false, 0
+		logger.Infow("Upstream address recovered after eject duration", "address", address)
+		return true
+	}
+	return false
+}
+
+// report 记录一次检查或调用结果
+func (h *HealthChecker) report(address string, check flux.UpstreamHealthCheck, success bool, active bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	state := h.stateOf(address)
+	if success {
+		state.failures = 0
+		state.successes++
+		// 被动检查的成功调用不恢复不健康的地址：不健康的地址不会被选中，成功调用来自标记前已发出的请求
+		if state.unhealthy && active && state.successes >= check.HealthyThreshold {
+			state.unhealthy = false
+			logger.Infow("Upstream address marked healthy", "address", address)
+		}
+		return
+	}
+	state.successes = 0
+	state.failures++
+	if !state.unhealthy && state.failures >= check.UnhealthyThreshold {
+		state.unhealthy, state.ejectedAt = true, time.Now()
+		logger.Warnw("Upstream address marked unhealthy", "address", address, "failures", state.failures, "active", active)
+	}
+}
+
+func (h *HealthChecker) stateOf(address string) *upstreamHealth {
+	state, ok := h.states[address]
+	if !ok {
+		state = &upstreamHealth{}
+		h.states[address] = state
+	}
+	return state
+}
+
+// healthCheckOf 返回填充默认值后的健康检查配置
+func healthCheckOf(upstream flux.Upstream) flux.UpstreamHealthCheck {
+	check := upstream.HealthCheck
+	if check.Interval <= 0 {
+		check.Interval = 10 * time.Second
+	}
+	if check.Timeout <= 0 {
+		check.Timeout = 2 * time.Second
+	}
+	if check.UnhealthyThreshold < 1 {
+		check.UnhealthyThreshold = 3
+	}
+	if check.HealthyThreshold < 1 {
+		check.HealthyThreshold = 2
+	}
+	if check.EjectDuration <= 0 {
+		check.EjectDuration = 30 * time.Second
+	}
+	if "" == check.Path {
+		check.Path = "/"
+	}
+	return check
+}
+
+// healthyAddressesOf 返回上游集群中健康的地址；全部地址不健康时返回全部地址，避免健康检查误判导致集群完全不可用
+func healthyAddressesOf(upstream flux.Upstream) []flux.UpstreamAddress {
+	check := healthCheckOf(upstream)
+	healthy := make([]flux.UpstreamAddress, 0, len(upstream.Addresses))
+	for _, addr := range upstream.Addresses {
+		if healthChecker.Healthy(addr.Address, check) {
+			healthy = append(healthy, addr)
+		}
+	}
+	if 0 == len(healthy) {
+		logger.Warnw("Upstream has no healthy address, use all addresses", "upstream", upstream.Name)
+		return upstream.Addresses
+	}
+	return healthy
+}
+
+// reportUpstream 被动检查：记录上游集群地址的调用结果；网关5xx错误或上游5xx响应视为失败
+func reportUpstream(upstream flux.Upstream, address string, resp interface{}, err *flux.ServeError) {
+	healthChecker.report(address, healthCheckOf(upstream), !breakerFailed(resp, err), false)
+}
+
+// hostPortOf 去除地址的scheme和路径，返回host:port
+func hostPortOf(address string) string {
+	if i := strings.Index(address, "://"); i >= 0 {
+		address = address[i+3:]
+	}
+	if i := strings.IndexAny(address, "/?"); i >= 0 {
+		address = address[:i]
+	}
+	return address
+}
+
+func probeTcp(ctx context.Context, address string, _ flux.UpstreamHealthCheck) error {
+	conn, err := new(net.Dialer).DialContext(ctx, "tcp", hostPortOf(address))
+	if nil != err {
+		return err
+	}
+	return conn.Close()
+}
+
+func probeHttp(ctx context.Context, address string, check flux.UpstreamHealthCheck) error {
+	url := strings.TrimSuffix(address, "/") + check.Path
+	if !strings.Contains(address, "://") {
+		url = "http://" + url
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if nil != err {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(request)
+	if nil != err {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("health check response status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// probeDubbo 通过Dubbo的telnet命令端口检查：发送空行，响应中包含 dubbo> 提示符时视为健康
+func probeDubbo(ctx context.Context, address string, _ flux.UpstreamHealthCheck) error {
+	conn, err := new(net.Dialer).DialContext(ctx, "tcp", hostPortOf(address))
+	if nil != err {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write([]byte("\r\n")); nil != err {
+		return err
+	}
+	reader := bufio.NewReader(conn)
+	buf := make([]byte, 0, 64)
+	for {
+		b, err := reader.ReadByte()
+		if nil != err {
+			return fmt.Errorf("dubbo telnet prompt not found: %w", err)
+		}
+		if buf = append(buf, b); bytes.HasSuffix(buf, dubboTelnetPrompt) {
+			return nil
+		}
+		if len(buf) >= 1024 {
+			return fmt.Errorf("dubbo telnet prompt not found")
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package backend
+
+import (
+	"context"
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestHealthChecker_Report(t *testing.T) {
+	assert := assert2.New(t)
+	checker := NewHealthChecker()
+	active := healthCheckOf(flux.Upstream{HealthCheck: flux.UpstreamHealthCheck{Type: HealthCheckTypeTcp}})
+	for i := 0; i < active.UnhealthyThreshold; i++ {
+		assert.True(checker.Healthy("a", active))
+		checker.report("a", active, false, false)
+	}
+	assert.False(checker.Healthy("a", active))
+	// 被动检查的成功调用不恢复；主动检查连续成功后恢复
+	checker.report("a", active, true, false)
+	assert.False(checker.Healthy("a", active))
+	checker.report("a", active, false, true)
+	checker.report("a", active, true, true)
+	assert.False(checker.Healthy("a", active))
+	checker.report("a", active, true, true)
+	assert.True(checker.Healthy("a", active))
+	// 未开启主动检查的地址在摘除时长后恢复
+	passive := healthCheckOf(flux.Upstream{HealthCheck: flux.UpstreamHealthCheck{UnhealthyThreshold: 1, EjectDuration: time.Millisecond}})
+	checker.report("b", passive, false, false)
+	assert.False(checker.Healthy("b", passive))
+	time.Sleep(2 * time.Millisecond)
+	assert.True(checker.Healthy("b", passive))
+}
+
+func TestHealthyAddressesOf(t *testing.T) {
+	assert := assert2.New(t)
+	upstream := flux.Upstream{Name: "health-test", HealthCheck: flux.UpstreamHealthCheck{UnhealthyThreshold: 1},
+		Addresses: []flux.UpstreamAddress{{Address: "health-test-a"}, {Address: "health-test-b"}}}
+	reportUpstream(upstream, "health-test-a", nil, &flux.ServeError{StatusCode: flux.StatusBadGateway})
+	assert.Equal([]flux.UpstreamAddress{{Address: "health-test-b"}}, healthyAddressesOf(upstream))
+	// 全部地址不健康时使用全部地址
+	reportUpstream(upstream, "health-test-b", nil, &flux.ServeError{StatusCode: flux.StatusBadGateway})
+	assert.Equal(upstream.Addresses, healthyAddressesOf(upstream))
+}
+
+func TestHealthProbes(t *testing.T) {
+	assert := assert2.New(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if nil != err {
+				return
+			}
+			_, _ = conn.Write([]byte("\r\ndubbo>"))
+			_ = conn.Close()
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	check := healthCheckOf(flux.Upstream{})
+	assert.Nil(probeTcp(ctx, "tcp://"+listener.Addr().String(), check))
+	assert.Nil(probeDubbo(ctx, "dubbo://"+listener.Addr().String()+"/com.example.OrderService", check))
+}
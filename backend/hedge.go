@@ -0,0 +1,120 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"github.com/spf13/cast"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// Endpoint扩展属性：对冲请求的延迟，例如 "50ms"。首个请求超过延迟未返回时，发起一个重复请求，使用先成功返回的响应；
+	// 只对幂等的GET/HEAD请求生效。落后的请求通过Context取消，上游调用需响应Context取消以便及时结束。
+	EndpointExtKeyHedgeDelay = "hedge-delay"
+)
+
+type hedgeResult struct {
+	ctx  *hedgeContext
+	resp interface{}
+	err  *flux.ServeError
+}
+
+// hedgeContext 对冲请求使用的Context：使用独立的可取消Context，胜出请求的数据在调用结束后合并到原Context
+type hedgeContext struct {
+	*OverlayContext
+	cancel context.CancelFunc
+}
+
+func newHedgeContext(ctx flux.Context) *hedgeContext {
+	goctx, cancel := context.WithCancel(ctx.Context())
+	return &hedgeContext{OverlayContext: NewOverlayContext(ctx, goctx), cancel: cancel}
+}
+
+// hedgeDelayOf 返回请求的对冲延迟；未配置或非幂等请求返回0
+func hedgeDelayOf(ctx flux.Context) time.Duration {
+	v, ok := ctx.Endpoint().Ext(EndpointExtKeyHedgeDelay)
+	if !ok {
+		return 0
+	}
+	if method := ctx.Method(); http.MethodGet != method && http.MethodHead != method {
+		return 0
+	}
+	return cast.ToDuration(v)
+}
+
+// invokeHedged 发起首个请求，超过延迟未返回时发起对冲请求，返回先成功的响应；全部失败时返回首个错误。
+// 首个请求在延迟内失败时直接返回错误，不发起对冲请求。返回前取消并等待落后的请求结束，释放其响应。
+func invokeHedged(exchange flux.BackendTransport, service flux.BackendService, ctx flux.Context, delay time.Duration) (interface{}, *flux.ServeError) {
+	results := make(chan hedgeResult, 2)
+	attempts := make([]*hedgeContext, 0, 2)
+	launch := func() {
+		hctx := newHedgeContext(ctx)
+		attempts = append(attempts, hctx)
+		go func() {
+			resp, err := invokeRecovered(exchange, service, hctx)
+			results <- hedgeResult{ctx: hctx, resp: resp, err: err}
+		}()
+	}
+	launch()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	var failed *hedgeResult
+	for pending := 1; pending > 0; {
+		select {
+		case <-timer.C:
+			launch()
+			pending++
+			logger.TraceContext(ctx).Infow("Backend hedging, issue hedged request", "delay", delay)
+		case r := <-results:
+			pending--
+			if nil != r.err {
+				if nil == failed {
+					failed = &r
+				}
+				continue
+			}
+			// 胜出请求的Context不取消：响应Body可能仍依赖其Context读取
+			for _, a := range attempts {
+				if a != r.ctx {
+					a.cancel()
+				}
+			}
+			for ; pending > 0; pending-- {
+				closeResponse((<-results).resp)
+			}
+			r.ctx.Commit()
+			return r.resp, nil
+		}
+	}
+	failed.ctx.Commit()
+	return failed.resp, failed.err
+}
+
+func invokeRecovered(exchange flux.BackendTransport, service flux.BackendService, ctx flux.Context) (resp interface{}, serr *flux.ServeError) {
+	defer func() {
+		if r := recover(); nil != r {
+			serr = &flux.ServeError{
+				StatusCode: flux.StatusServerError,
+				ErrorCode:  flux.ErrorCodeGatewayInternal,
+				Message:    flux.ErrorMessageBackendHedgeInvoke,
+				Internal:   fmt.Errorf("hedged invoke panics: %v", r),
+			}
+		}
+	}()
+	// 每个对冲请求分别选择上游集群地址
+	return invokeUpstream(exchange, service, ctx)
+}
+
+// closeResponse 释放落后请求的响应
+func closeResponse(resp interface{}) {
+	switch r := resp.(type) {
+	case *http.Response:
+		_ = r.Body.Close()
+	case io.Closer:
+		_ = r.Close()
+	}
+}
@@ -0,0 +1,90 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type hedgeTestContext struct {
+	fluxContext
+	method   string
+	endpoint flux.Endpoint
+	values   map[string]interface{}
+}
+
+func (c *hedgeTestContext) Method() string                        { return c.method }
+func (c *hedgeTestContext) Endpoint() flux.Endpoint               { return c.endpoint }
+func (c *hedgeTestContext) Context() context.Context              { return context.Background() }
+func (c *hedgeTestContext) SetValue(name string, v interface{})   { c.values[name] = v }
+func (c *hedgeTestContext) GetContextLogger() (flux.Logger, bool) { return logger.SimpleLogger(), true }
+func (c *hedgeTestContext) GetValue(name string) (interface{}, bool) {
+	v, ok := c.values[name]
+	return v, ok
+}
+
+// hedgeTestTransport 按调用次序返回延迟：超过延迟前Context被取消时，返回取消错误
+type hedgeTestTransport struct {
+	calls     int32
+	delays    []time.Duration
+	cancelled int32
+}
+
+func (b *hedgeTestTransport) Exchange(flux.Context) *flux.ServeError {
+	return nil
+}
+
+func (b *hedgeTestTransport) Invoke(_ flux.BackendService, ctx flux.Context) (interface{}, *flux.ServeError) {
+	n := atomic.AddInt32(&b.calls, 1)
+	select {
+	case <-time.After(b.delays[n-1]):
+		ctx.SetValue("attempt", n)
+		return n, nil
+	case <-ctx.Context().Done():
+		atomic.AddInt32(&b.cancelled, 1)
+		return nil, &flux.ServeError{Internal: errors.New("cancelled")}
+	}
+}
+
+func TestInvokeHedged(t *testing.T) {
+	cases := []struct {
+		delays    []time.Duration
+		calls     int32
+		cancelled int32
+		winner    int32
+	}{
+		// 首个请求在延迟内返回，不发起对冲请求
+		{delays: []time.Duration{0}, calls: 1, winner: 1},
+		// 首个请求过慢，对冲请求胜出，首个请求被取消
+		{delays: []time.Duration{time.Second, 0}, calls: 2, cancelled: 1, winner: 2},
+		// 对冲请求更慢，首个请求胜出，对冲请求被取消
+		{delays: []time.Duration{50 * time.Millisecond, time.Second}, calls: 2, cancelled: 1, winner: 1},
+	}
+	for _, tc := range cases {
+		assert := assert2.New(t)
+		ctx := &hedgeTestContext{method: http.MethodGet, values: map[string]interface{}{}}
+		transport := &hedgeTestTransport{delays: tc.delays}
+		resp, err := invokeHedged(transport, flux.BackendService{}, ctx, 20*time.Millisecond)
+		assert.Nil(err)
+		assert.Equal(tc.winner, resp)
+		assert.Equal(tc.calls, atomic.LoadInt32(&transport.calls))
+		assert.Equal(tc.cancelled, atomic.LoadInt32(&transport.cancelled))
+		// 只合并胜出请求写入的Value
+		assert.Equal(tc.winner, ctx.values["attempt"])
+	}
+}
+
+func TestHedgeDelayOf(t *testing.T) {
+	assert := assert2.New(t)
+	endpoint := flux.Endpoint{}
+	endpoint.Extensions = map[string]interface{}{EndpointExtKeyHedgeDelay: "50ms"}
+	assert.Equal(50*time.Millisecond, hedgeDelayOf(&hedgeTestContext{method: http.MethodGet, endpoint: endpoint}))
+	assert.Equal(time.Duration(0), hedgeDelayOf(&hedgeTestContext{method: http.MethodPost, endpoint: endpoint}))
+	assert.Equal(time.Duration(0), hedgeDelayOf(&hedgeTestContext{method: http.MethodGet}))
+}
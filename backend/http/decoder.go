@@ -2,20 +2,93 @@ package http
 
 import (
 	"errors"
+	"fmt"
 	"github.com/bytepowered/flux"
+	"github.com/spf13/cast"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"strconv"
 )
 
 var (
 	ErrUnknownHttpBackendResponse = errors.New("BACKEND:UNKNOWN_HTTP_RESPONSE")
 )
 
+const (
+	// Endpoint扩展属性：上游4xx/5xx响应的处理策略，默认为 passthrough
+	EndpointExtKeyErrorPolicy = "http-error-policy"
+	// Endpoint扩展属性：translate 策略的状态码转换表，Key为状态码或状态码类别（4xx/5xx），例如：{"404": 200, "5xx": 502}
+	EndpointExtKeyStatusMappings = "http-status-mappings"
+)
+
+const (
+	// 原样返回上游的状态码、Header和Body
+	ErrorPolicyPassthrough = "passthrough"
+	// 按转换表修改状态码，Header和Body原样返回；未匹配的状态码原样返回
+	ErrorPolicyTranslate = "translate"
+	// 丢弃上游响应，返回网关统一格式的错误响应；上游5xx转换为502
+	ErrorPolicyWrap = "wrap"
+)
+
+const (
+	// wrap 策略记录的上游响应Body最大长度
+	maxWrappedBodySize = 512
+)
+
 func NewHttpBackendTransportDecodeFunc() flux.BackendTransportDecodeFunc {
 	return func(ctx flux.Context, value interface{}) (statusCode int, headers http.Header, body interface{}, err error) {
 		resp, ok := value.(*http.Response)
 		if !ok {
 			return http.StatusInternalServerError, http.Header{}, nil, ErrUnknownHttpBackendResponse
 		}
-		return resp.StatusCode, resp.Header, resp.Body, nil
+		if resp.StatusCode < http.StatusBadRequest || nil == ctx {
+			return resp.StatusCode, resp.Header, resp.Body, nil
+		}
+		endpoint := ctx.Endpoint()
+		switch policy := endpoint.ExtString(EndpointExtKeyErrorPolicy); policy {
+		case ErrorPolicyTranslate:
+			return translateStatus(resp.StatusCode, endpoint), resp.Header, resp.Body, nil
+		case ErrorPolicyWrap:
+			return http.StatusInternalServerError, http.Header{}, nil, wrapUpstreamError(resp)
+		default:
+			return resp.StatusCode, resp.Header, resp.Body, nil
+		}
+	}
+}
+
+// translateStatus 按Endpoint的状态码转换表转换状态码：先匹配状态码，再匹配状态码类别
+func translateStatus(status int, endpoint flux.Endpoint) int {
+	v, ok := endpoint.Ext(EndpointExtKeyStatusMappings)
+	if !ok {
+		return status
+	}
+	mappings := cast.ToStringMap(v)
+	for _, key := range []string{strconv.Itoa(status), fmt.Sprintf("%dxx", status/100)} {
+		if mapped, ok := mappings[key]; ok {
+			if code := cast.ToInt(mapped); code > 0 {
+				return code
+			}
+		}
+	}
+	return status
+}
+
+// wrapUpstreamError 将上游错误响应转换为网关错误；上游响应Body记录在内部错误中
+func wrapUpstreamError(resp *http.Response) *flux.ServeError {
+	defer resp.Body.Close()
+	data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxWrappedBodySize))
+	status := resp.StatusCode
+	if status >= http.StatusInternalServerError {
+		status = http.StatusBadGateway
+	}
+	return &flux.ServeError{
+		StatusCode:     status,
+		ErrorCode:      flux.ErrorCodeGatewayBackend,
+		Message:        flux.ErrorMessageHttpUpstreamStatus,
+		Internal:       fmt.Errorf("upstream status: %d, body: %s", resp.StatusCode, data),
+		Category:       flux.ErrorCategoryUpstream,
+		Retryable:      http.StatusBadGateway == resp.StatusCode || http.StatusServiceUnavailable == resp.StatusCode || http.StatusGatewayTimeout == resp.StatusCode,
+		UpstreamStatus: resp.StatusCode,
 	}
 }
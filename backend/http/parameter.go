@@ -10,8 +10,9 @@ import (
 
 func _toHttpUrlValues(arguments []flux.Argument, ctx flux.Context) (url.Values, error) {
 	values := make(url.Values, len(arguments))
-	lookup := ext.LoadArgumentValueLookupFunc()
-	resolver := ext.LoadArgumentValueResolveFunc()
+	registry := ext.RegistryOf(ctx.Context())
+	lookup := registry.LoadArgumentValueLookupFunc()
+	resolver := registry.LoadArgumentValueResolveFunc()
 	for _, arg := range arguments {
 		if value, err := backend.LookupResolveWith(arg, lookup, resolver, ctx); nil != err {
 			return nil, err
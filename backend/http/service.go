@@ -14,11 +14,15 @@ import (
 	"time"
 )
 
+const (
+	configKeyTimeout = "timeout"
+	configKeyPool    = "pool"
+)
+
 func NewHttpBackendTransport() *BackendTransportService {
+	pool := backend.NewClientPool(flux.ProtoHttp, backend.DefaultPoolOptions())
 	return &BackendTransportService{
-		httpClient: &http.Client{
-			Timeout: time.Second * 10,
-		},
+		httpClient: pool.HttpClient(time.Second * 10),
 	}
 }
 
@@ -26,6 +30,16 @@ type BackendTransportService struct {
 	httpClient *http.Client
 }
 
+// Init 使用配置的连接池创建Http客户端，同一上游的请求复用连接
+func (ex *BackendTransportService) Init(config *flux.Configuration) error {
+	config.SetDefaults(map[string]interface{}{
+		configKeyTimeout: time.Second * 10,
+	})
+	pool := backend.NewClientPool(flux.ProtoHttp, backend.NewPoolOptions(config.Sub(configKeyPool)))
+	ex.httpClient = pool.HttpClient(config.GetDuration(configKeyTimeout))
+	return nil
+}
+
 func (ex *BackendTransportService) Exchange(ctx flux.Context) *flux.ServeError {
 	return backend.DoExchange(ctx, ex)
 }
@@ -45,32 +59,61 @@ func (ex *BackendTransportService) Invoke(service flux.BackendService, ctx flux.
 	return ex.ExecuteRequest(newRequest, service, ctx)
 }
 
-func (ex *BackendTransportService) ExecuteRequest(newRequest *http.Request, _ flux.BackendService, ctx flux.Context) (interface{}, *flux.ServeError) {
-	// Header透传以及传递AttrValues
-	if header, writable := ctx.Request().HeaderValues(); writable {
-		newRequest.Header = header.Clone()
-	} else {
-		newRequest.Header = header
+func (ex *BackendTransportService) ExecuteRequest(newRequest *http.Request, service flux.BackendService, ctx flux.Context) (interface{}, *flux.ServeError) {
+	// Header透传以及传递AttrValues；透传前检查请求边界并移除逐跳Header，防御请求走私
+	header, _ := ctx.Request().HeaderValues()
+	sanitized, err := backend.SanitizeProxyRequestHeader(header)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusBadRequest,
+			ErrorCode:  flux.ErrorCodeRequestInvalid,
+			Message:    flux.ErrorMessageHttpRequestFraming,
+			Internal:   err,
+		}
 	}
+	newRequest.Header = sanitized
 	for k, v := range ctx.Attributes() {
 		newRequest.Header.Set(k, cast.ToString(v))
 	}
-	resp, err := ex.httpClient.Do(newRequest)
+	to := service.AttrRpcTimeout()
+	timeout, err := time.ParseDuration(to)
+	if err != nil {
+		logger.Warnf("Illegal endpoint rpc-timeout: ", to)
+		timeout = time.Second * 10
+	}
+	// 超时Context在响应Body关闭时释放
+	toctx, cancel := context.WithTimeout(newRequest.Context(), timeout)
+	resp, err := ex.httpClient.Do(newRequest.WithContext(toctx))
 	if nil != err {
+		cancel()
 		msg := flux.ErrorMessageHttpInvokeFailed
 		if uErr, ok := err.(*url.Error); ok {
 			msg = fmt.Sprintf("HTTPEX:REMOTE_ERROR:%s", uErr.Error())
 		}
+		// 连接失败等传输错误：上游未返回响应，可重试
 		return nil, &flux.ServeError{
 			StatusCode: flux.StatusServerError,
 			ErrorCode:  flux.ErrorCodeGatewayBackend,
 			Message:    msg,
 			Internal:   err,
+			Category:   flux.ErrorCategoryUpstream,
+			Retryable:  true,
 		}
 	}
+	resp.Body = &cancelReadCloser{ReadCloser: resp.Body, cancel: cancel}
 	return resp, nil
 }
 
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelReadCloser) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
 func (ex *BackendTransportService) Assemble(service *flux.BackendService, inURL *url.URL, bodyReader io.ReadCloser, ctx flux.Context) (*http.Request, error) {
 	inParams := service.Arguments
 	newQuery := inURL.RawQuery
@@ -111,14 +154,7 @@ func (ex *BackendTransportService) Assemble(service *flux.BackendService, inURL
 		RawQuery:   newQuery,
 		Fragment:   inURL.Fragment,
 	}
-	to := service.AttrRpcTimeout()
-	timeout, err := time.ParseDuration(to)
-	if err != nil {
-		logger.Warnf("Illegal endpoint rpc-timeout: ", to)
-		timeout = time.Second * 10
-	}
-	toctx, _ := context.WithTimeout(ctx.Context(), timeout)
-	newRequest, err := http.NewRequestWithContext(toctx, service.Method, newUrl.String(), newBodyReader)
+	newRequest, err := http.NewRequestWithContext(ctx.Context(), service.Method, newUrl.String(), newBodyReader)
 	if nil != err {
 		return nil, fmt.Errorf("new request, method: %s, url: %s, err: %w", service.Method, newUrl, err)
 	}
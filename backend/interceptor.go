@@ -0,0 +1,44 @@
+package backend
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"net/http"
+	"strconv"
+)
+
+// invokeIntercepted 经过已注册的调用拦截器执行一次后端服务调用；按注册顺序由外向内执行
+func invokeIntercepted(exchange flux.BackendTransport, invocation flux.BackendInvocation, ctx flux.Context) (interface{}, *flux.ServeError) {
+	invoke := func(invocation flux.BackendInvocation, ctx flux.Context) (interface{}, *flux.ServeError) {
+		resp, serr := exchange.Invoke(invocation.Service, ctx)
+		return resp, requestErrorOf(serr)
+	}
+	interceptors := ext.RegistryOf(ctx.Context()).LoadBackendInterceptors()
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		invoke = interceptors[i](invoke)
+	}
+	return invoke(invocation, ctx)
+}
+
+// RequestPayloadSize 返回请求体的大小，来自请求的Content-Length；未知时返回-1
+func RequestPayloadSize(ctx flux.Context) int64 {
+	header, _ := ctx.Request().HeaderValues()
+	if size, err := strconv.ParseInt(header.Get(flux.HeaderContentLength), 10, 64); nil == err {
+		return size
+	}
+	return -1
+}
+
+// ResponsePayloadSize 返回后端服务响应结果的大小：Http响应返回Content-Length，字节数组和字符串返回长度；未知时返回-1
+func ResponsePayloadSize(resp interface{}) int64 {
+	switch r := resp.(type) {
+	case *http.Response:
+		return r.ContentLength
+	case []byte:
+		return int64(len(r))
+	case string:
+		return int64(len(r))
+	default:
+		return -1
+	}
+}
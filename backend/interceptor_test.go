@@ -0,0 +1,59 @@
+package backend
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestInvokeUpstream_Interceptor(t *testing.T) {
+	assert := assert2.New(t)
+	ext.StoreUpstream(flux.Upstream{Name: "intercept-cluster", Addresses: []flux.UpstreamAddress{
+		{Address: "intercept-a"}, {Address: "intercept-b"},
+	}})
+	defer ext.RemoveUpstream("intercept-cluster")
+	var invocations []flux.BackendInvocation
+	var order []string
+	// 拦截器注册到默认注册表，只记录本用例的调用
+	newInterceptor := func(name string) flux.BackendInterceptor {
+		return func(next flux.BackendInvokeFunc) flux.BackendInvokeFunc {
+			return func(invocation flux.BackendInvocation, ctx flux.Context) (interface{}, *flux.ServeError) {
+				if "intercept-cluster" != invocation.Upstream {
+					return next(invocation, ctx)
+				}
+				order = append(order, name)
+				resp, err := next(invocation, ctx)
+				if "outer" == name {
+					invocations = append(invocations, invocation)
+				}
+				return resp, err
+			}
+		}
+	}
+	ext.StoreBackendInterceptor(newInterceptor("outer"))
+	ext.StoreBackendInterceptor(newInterceptor("inner"))
+	endpoint := flux.Endpoint{}
+	endpoint.Extensions = map[string]interface{}{EndpointExtKeyFailover: true}
+	transport := &failoverTestTransport{down: map[string]bool{"intercept-a": true, "intercept-b": true}}
+	service := flux.BackendService{RemoteHost: "upstream://intercept-cluster"}
+	service.Attributes = []flux.Attribute{{Tag: flux.ServiceAttrTagRpcProto, Name: "RpcProto", Value: flux.ProtoHttp}}
+	_, err := invokeUpstream(transport, service, &hedgeTestContext{method: http.MethodPost, endpoint: endpoint})
+	assert.NotNil(err)
+	assert.Equal([]string{"outer", "inner", "outer", "inner"}, order)
+	assert.Equal(2, len(invocations))
+	for i, invocation := range invocations {
+		assert.Equal(flux.ProtoHttp, invocation.RpcProto)
+		assert.Equal(i+1, invocation.Attempt)
+		assert.Equal(transport.invoked[i], invocation.Service.RemoteHost)
+	}
+}
+
+func TestResponsePayloadSize(t *testing.T) {
+	assert := assert2.New(t)
+	assert.Equal(int64(3), ResponsePayloadSize([]byte("abc")))
+	assert.Equal(int64(2), ResponsePayloadSize("ab"))
+	assert.Equal(int64(10), ResponsePayloadSize(&http.Response{ContentLength: 10}))
+	assert.Equal(int64(-1), ResponsePayloadSize(map[string]interface{}{}))
+}
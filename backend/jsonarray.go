@@ -0,0 +1,109 @@
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"io"
+	"reflect"
+)
+
+const (
+	// Endpoint扩展属性：以流式JSON数组输出列表类型的响应体，逐个编码元素并分块写入客户端，不在网关构建完整的JSON文档；
+	// 响应体为 flux.ResultIterator 时，不需要配置即按流式JSON数组输出
+	EndpointExtKeyStreamJSONArray = "stream-json-array"
+)
+
+// jsonArrayStreamOf 返回响应体的流式JSON数组Reader：迭代器响应体，以及开启 stream-json-array 的Endpoint的列表响应体
+func jsonArrayStreamOf(endpoint flux.Endpoint, body interface{}) (io.ReadCloser, bool) {
+	if iterator, ok := body.(flux.ResultIterator); ok {
+		return NewJSONArrayReader(iterator), true
+	}
+	if !endpoint.ExtBool(EndpointExtKeyStreamJSONArray) {
+		return nil, false
+	}
+	rv := reflect.ValueOf(body)
+	if reflect.Slice != rv.Kind() && reflect.Array != rv.Kind() {
+		return nil, false
+	}
+	if reflect.Slice == rv.Kind() && reflect.Uint8 == rv.Type().Elem().Kind() {
+		return nil, false
+	}
+	return NewJSONArrayReader(&sliceIterator{values: rv}), true
+}
+
+// NewJSONArrayReader 创建按需编码迭代器元素的JSON数组Reader；关闭Reader时关闭迭代器
+func NewJSONArrayReader(iterator flux.ResultIterator) io.ReadCloser {
+	return &jsonArrayReader{iterator: iterator}
+}
+
+// jsonArrayReader 流式JSON数组：缓冲区读取完毕时才编码下一个元素，内存占用不超过单个元素的编码结果
+type jsonArrayReader struct {
+	iterator flux.ResultIterator
+	buf      bytes.Buffer
+	count    int
+	started  bool
+	done     bool
+}
+
+func (r *jsonArrayReader) Read(p []byte) (int, error) {
+	for 0 == r.buf.Len() {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.next(); nil != err {
+			return 0, err
+		}
+	}
+	return r.buf.Read(p)
+}
+
+func (r *jsonArrayReader) Close() error {
+	return r.iterator.Close()
+}
+
+// next 编码下一个元素到缓冲区；没有更多元素时写入数组结束符
+func (r *jsonArrayReader) next() error {
+	if !r.started {
+		r.started = true
+		r.buf.WriteByte('[')
+	}
+	item, err := r.iterator.Next()
+	if io.EOF == err {
+		r.done = true
+		r.buf.WriteByte(']')
+		return nil
+	}
+	if nil != err {
+		return fmt.Errorf("json array iterate, index: %d, error: %w", r.count, err)
+	}
+	data, err := ext.JSONMarshal(item)
+	if nil != err {
+		return fmt.Errorf("json array encode, index: %d, error: %w", r.count, err)
+	}
+	if r.count > 0 {
+		r.buf.WriteByte(',')
+	}
+	r.buf.Write(data)
+	r.count++
+	return nil
+}
+
+// sliceIterator 列表响应体的迭代器
+type sliceIterator struct {
+	values reflect.Value
+	index  int
+}
+
+func (s *sliceIterator) Next() (interface{}, error) {
+	if s.index >= s.values.Len() {
+		return nil, io.EOF
+	}
+	s.index++
+	return s.values.Index(s.index - 1).Interface(), nil
+}
+
+func (s *sliceIterator) Close() error {
+	return nil
+}
@@ -0,0 +1,74 @@
+package backend
+
+import (
+	"errors"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	assert2 "github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+type testResultIterator struct {
+	items  []interface{}
+	err    error
+	closed bool
+}
+
+func (t *testResultIterator) Next() (interface{}, error) {
+	if 0 == len(t.items) {
+		if nil != t.err {
+			return nil, t.err
+		}
+		return nil, io.EOF
+	}
+	item := t.items[0]
+	t.items = t.items[1:]
+	return item, nil
+}
+
+func (t *testResultIterator) Close() error {
+	t.closed = true
+	return nil
+}
+
+func TestJSONArrayReader(t *testing.T) {
+	assert := assert2.New(t)
+	ext.StoreSerializer(ext.TypeNameSerializerJson, flux.NewJsonSerializer())
+	iterator := &testResultIterator{items: []interface{}{1, "a", map[string]interface{}{"id": 2}}}
+	reader := NewJSONArrayReader(iterator)
+	data, err := ioutil.ReadAll(reader)
+	assert.NoError(err)
+	assert.Equal(`[1,"a",{"id":2}]`, string(data))
+	assert.NoError(reader.Close())
+	assert.True(iterator.closed)
+	// 空迭代器输出空数组
+	data, err = ioutil.ReadAll(NewJSONArrayReader(&testResultIterator{}))
+	assert.NoError(err)
+	assert.Equal(`[]`, string(data))
+	// 迭代失败时返回错误
+	_, err = ioutil.ReadAll(NewJSONArrayReader(&testResultIterator{items: []interface{}{1}, err: errors.New("failed")}))
+	assert.Error(err)
+}
+
+func TestJSONArrayStreamOf(t *testing.T) {
+	assert := assert2.New(t)
+	ext.StoreSerializer(ext.TypeNameSerializerJson, flux.NewJsonSerializer())
+	endpoint := flux.Endpoint{EmbeddedExtensions: flux.EmbeddedExtensions{
+		Extensions: map[string]interface{}{EndpointExtKeyStreamJSONArray: true},
+	}}
+	reader, ok := jsonArrayStreamOf(endpoint, []string{"a", "b"})
+	if assert.True(ok) {
+		data, _ := ioutil.ReadAll(reader)
+		assert.Equal(`["a","b"]`, string(data))
+	}
+	_, ok = jsonArrayStreamOf(endpoint, map[string]interface{}{"a": 1})
+	assert.False(ok)
+	_, ok = jsonArrayStreamOf(endpoint, []byte("raw"))
+	assert.False(ok)
+	_, ok = jsonArrayStreamOf(flux.Endpoint{}, []string{"a"})
+	assert.False(ok)
+	_, ok = jsonArrayStreamOf(flux.Endpoint{}, &testResultIterator{})
+	assert.True(ok)
+}
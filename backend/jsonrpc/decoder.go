@@ -0,0 +1,93 @@
+package jsonrpc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/spf13/cast"
+	"net/http"
+	"strconv"
+)
+
+// JSON-RPC 2.0 预定义错误码
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+var (
+	ErrUnknownJsonRpcBackendResponse = errors.New("BACKEND:UNKNOWN_JSONRPC_RESPONSE")
+)
+
+// Error JSON-RPC 2.0 错误对象
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc error: %d, message: %s", e.Code, e.Message)
+}
+
+// NewJsonRpcBackendTransportDecodeFunc 解析JSON-RPC响应：返回 result；error 对象转换为网关错误，错误码为上游错误码
+func NewJsonRpcBackendTransportDecodeFunc() flux.BackendTransportDecodeFunc {
+	return func(ctx flux.Context, value interface{}) (statusCode int, headers http.Header, body interface{}, err error) {
+		resp, ok := value.(*Response)
+		if !ok {
+			return http.StatusInternalServerError, http.Header{}, nil, ErrUnknownJsonRpcBackendResponse
+		}
+		result := struct {
+			JsonRpc string      `json:"jsonrpc"`
+			Result  interface{} `json:"result"`
+			Error   *Error      `json:"error"`
+			Id      interface{} `json:"id"`
+		}{}
+		if err := ext.JSONUnmarshal(resp.Body, &result); nil != err {
+			// 非JSON-RPC格式的上游错误响应，原样返回
+			if resp.StatusCode >= http.StatusBadRequest {
+				return resp.StatusCode, http.Header{}, string(resp.Body), nil
+			}
+			return http.StatusInternalServerError, http.Header{}, nil, err
+		}
+		if Version != result.JsonRpc {
+			return http.StatusInternalServerError, http.Header{}, nil, fmt.Errorf("jsonrpc unsupported version: %s", result.JsonRpc)
+		}
+		// 无法解析请求时，上游返回的ID为null
+		if nil != result.Id && cast.ToString(result.Id) != resp.Id {
+			return http.StatusInternalServerError, http.Header{}, nil, fmt.Errorf("jsonrpc response id mismatch, request: %s, response: %v", resp.Id, result.Id)
+		}
+		if nil == result.Error {
+			return http.StatusOK, http.Header{}, result.Result, nil
+		}
+		serr := &flux.ServeError{
+			StatusCode: statusOf(result.Error.Code),
+			ErrorCode:  strconv.Itoa(result.Error.Code),
+			Message:    flux.ErrorMessageJsonRpcError,
+			Internal:   result.Error,
+		}
+		if nil != result.Error.Data {
+			serr.ExtraTrace = map[string]interface{}{"jsonrpc.data": result.Error.Data}
+		}
+		if resp.ExposeMessage && "" != result.Error.Message {
+			serr.Message = result.Error.Message
+		}
+		return serr.StatusCode, http.Header{}, nil, serr
+	}
+}
+
+// statusOf 将JSON-RPC错误码转换为HTTP状态码：参数错误为400，其它预定义错误为502，应用自定义错误为500
+func statusOf(code int) int {
+	switch {
+	case CodeInvalidParams == code:
+		return http.StatusBadRequest
+	case code >= -32768 && code <= -32000:
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,11 @@
+package jsonrpc
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+)
+
+func init() {
+	ext.StoreBackendTransport(flux.ProtoJsonRpc, NewJsonRpcBackendTransport())
+	ext.StoreBackendTransportDecodeFunc(flux.ProtoJsonRpc, NewJsonRpcBackendTransportDecodeFunc())
+}
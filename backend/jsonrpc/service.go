@@ -0,0 +1,170 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/backend"
+	"github.com/bytepowered/flux/ext"
+	"github.com/spf13/cast"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// BackendService扩展属性：参数传递方式，named（默认，参数名为Key的对象）或 positional（按Arguments顺序的数组）
+	ServiceExtKeyParams = "jsonrpc-params"
+	// BackendService扩展属性：是否向客户端输出上游错误对象的message
+	ServiceExtKeyExposeMessage = "jsonrpc-expose-message"
+)
+
+const (
+	ParamsNamed      = "named"
+	ParamsPositional = "positional"
+)
+
+const (
+	Version = "2.0"
+)
+
+var (
+	_ flux.BackendTransport = new(BackendTransportService)
+)
+
+// Request JSON-RPC 2.0 请求对象
+type Request struct {
+	JsonRpc string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+	Id      string      `json:"id"`
+}
+
+// Response JSON-RPC上游的原始响应，由DecodeFunc解析 result/error
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// 请求对象的ID，用于校验响应对象
+	Id string
+	// 是否输出上游错误对象的message
+	ExposeMessage bool
+}
+
+// BackendTransportService 转发到JSON-RPC 2.0上游（HTTP传输）的BackendService：
+// BackendService.RemoteHost 为上游地址（可包含scheme，默认http），Interface 为接口路径，Method 为RPC方法名。
+type BackendTransportService struct {
+	httpClient *http.Client
+}
+
+// NewJsonRpcBackendTransport New JSON-RPC backend instance
+func NewJsonRpcBackendTransport() flux.BackendTransport {
+	return &BackendTransportService{
+		httpClient: &http.Client{
+			Timeout: time.Second * 10,
+		},
+	}
+}
+
+// Exchange do exchange with context
+func (b *BackendTransportService) Exchange(ctx flux.Context) *flux.ServeError {
+	return backend.DoExchange(ctx, b)
+}
+
+// Invoke invoke backend service with context
+func (b *BackendTransportService) Invoke(service flux.BackendService, ctx flux.Context) (interface{}, *flux.ServeError) {
+	newRequest, id, err := b.Assemble(service, ctx)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageJsonRpcAssembleFailed,
+			Internal:   err,
+		}
+	}
+	to := service.AttrRpcTimeout()
+	timeout, err := time.ParseDuration(to)
+	if err != nil {
+		timeout = time.Second * 10
+	}
+	toctx, cancel := context.WithTimeout(newRequest.Context(), timeout)
+	defer cancel()
+	resp, err := b.httpClient.Do(newRequest.WithContext(toctx))
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayBackend,
+			Message:    flux.ErrorMessageJsonRpcInvokeFailed,
+			Internal:   err,
+		}
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayBackend,
+			Message:    flux.ErrorMessageJsonRpcInvokeFailed,
+			Internal:   err,
+		}
+	}
+	return &Response{
+		StatusCode:    resp.StatusCode,
+		Header:        resp.Header,
+		Body:          data,
+		Id:            id,
+		ExposeMessage: service.ExtBool(ServiceExtKeyExposeMessage),
+	}, nil
+}
+
+// Assemble 生成JSON-RPC请求：参数值按配置封装为 params，请求ID使用网关的RequestId
+func (b *BackendTransportService) Assemble(service flux.BackendService, ctx flux.Context) (*http.Request, string, error) {
+	if "" == service.Method {
+		return nil, "", fmt.Errorf("jsonrpc method is required, service: %s", service.ServiceID())
+	}
+	values, err := backend.ResolveArgumentValues(service.Arguments, ctx)
+	if nil != err {
+		return nil, "", err
+	}
+	payload := Request{JsonRpc: Version, Method: service.Method, Id: ctx.RequestId()}
+	switch mode := service.ExtString(ServiceExtKeyParams); mode {
+	case ParamsPositional:
+		params := make([]interface{}, len(service.Arguments))
+		for i, arg := range service.Arguments {
+			params[i] = values[arg.Name]
+		}
+		payload.Params = params
+	case "", ParamsNamed:
+		if len(values) > 0 {
+			payload.Params = values
+		}
+	default:
+		return nil, "", fmt.Errorf("unsupported jsonrpc params mode: %s", mode)
+	}
+	data, err := ext.JSONMarshal(payload)
+	if nil != err {
+		return nil, "", err
+	}
+	url := service.RemoteHost + service.Interface
+	if !strings.Contains(service.RemoteHost, "://") {
+		url = "http://" + url
+	}
+	newRequest, err := http.NewRequestWithContext(ctx.Context(), http.MethodPost, url, bytes.NewReader(data))
+	if nil != err {
+		return nil, "", err
+	}
+	// Header透传以及传递AttrValues
+	header, _ := ctx.Request().HeaderValues()
+	newRequest.Header = header.Clone()
+	for k, v := range ctx.Attributes() {
+		newRequest.Header.Set(k, cast.ToString(v))
+	}
+	// 由Transport处理压缩
+	newRequest.Header.Del("Accept-Encoding")
+	newRequest.Header.Set("Content-Type", "application/json")
+	newRequest.Header.Set("Accept", "application/json")
+	newRequest.Header.Set("User-Agent", "FluxGo/Backend/v1")
+	return newRequest, payload.Id, nil
+}
@@ -0,0 +1,22 @@
+package kafka
+
+import (
+	"errors"
+	"github.com/bytepowered/flux"
+	"net/http"
+)
+
+var (
+	ErrUnknownKafkaBackendResponse = errors.New("BACKEND:UNKNOWN_KAFKA_RESPONSE")
+)
+
+// NewKafkaBackendTransportDecodeFunc 消息已写入Topic，由下游异步处理，返回 202 和确认信息
+func NewKafkaBackendTransportDecodeFunc() flux.BackendTransportDecodeFunc {
+	return func(ctx flux.Context, value interface{}) (statusCode int, headers http.Header, body interface{}, err error) {
+		ack, ok := value.(*Ack)
+		if !ok {
+			return http.StatusInternalServerError, http.Header{}, nil, ErrUnknownKafkaBackendResponse
+		}
+		return http.StatusAccepted, http.Header{}, ack, nil
+	}
+}
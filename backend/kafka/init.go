@@ -0,0 +1,11 @@
+package kafka
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+)
+
+func init() {
+	ext.StoreBackendTransport(flux.ProtoKafka, NewKafkaBackendTransport())
+	ext.StoreBackendTransportDecodeFunc(flux.ProtoKafka, NewKafkaBackendTransportDecodeFunc())
+}
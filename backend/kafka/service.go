@@ -0,0 +1,214 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/backend"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/cast"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	configKeyBrokers      = "brokers"
+	configKeyRequiredAcks = "required-acks"
+	configKeyBatchTimeout = "batch-timeout"
+	configKeyTimeout      = "timeout"
+)
+
+const (
+	// BackendService扩展属性：作为消息Key的参数名；未设置时消息Key为空
+	ServiceExtKeyMessageKey = "kafka-key"
+)
+
+var (
+	_ flux.BackendTransport = new(BackendTransportService)
+)
+
+// Ack 消息写入成功后返回的确认信息
+type Ack struct {
+	Topic     string `json:"topic"`
+	Key       string `json:"key,omitempty"`
+	RequestId string `json:"requestId"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// BackendTransportService 将请求数据发布到Kafka的BackendService：
+// BackendService.Interface 为Topic，RemoteHost 为Broker地址列表（逗号分隔，未设置时使用全局配置）；
+// 定义了Arguments时，消息为参数值组成的JSON对象，否则为原始请求Body。Attributes作为消息Header传递。
+type BackendTransportService struct {
+	brokers      []string
+	requiredAcks int
+	batchTimeout time.Duration
+	timeout      time.Duration
+	writers      map[string]*kafka.Writer
+	mu           sync.RWMutex
+}
+
+// NewKafkaBackendTransport New kafka backend instance
+func NewKafkaBackendTransport() flux.BackendTransport {
+	return &BackendTransportService{
+		writers: make(map[string]*kafka.Writer, 4),
+	}
+}
+
+// Init init backend
+func (b *BackendTransportService) Init(config *flux.Configuration) error {
+	logger.Info("Kafka backend transport initializing")
+	config.SetDefaults(map[string]interface{}{
+		configKeyRequiredAcks: -1,
+		configKeyBatchTimeout: time.Millisecond * 10,
+		configKeyTimeout:      time.Second * 10,
+	})
+	b.brokers = config.GetStringSlice(configKeyBrokers)
+	b.requiredAcks = config.GetInt(configKeyRequiredAcks)
+	b.batchTimeout = config.GetDuration(configKeyBatchTimeout)
+	b.timeout = config.GetDuration(configKeyTimeout)
+	return nil
+}
+
+// Shutdown shutdown backend
+func (b *BackendTransportService) Shutdown(_ context.Context) error {
+	logger.Info("Kafka backend transport shutdown")
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id, w := range b.writers {
+		if err := w.Close(); nil != err {
+			logger.Warnw("Kafka backend transport close writer", "writer", id, "error", err)
+		}
+	}
+	b.writers = make(map[string]*kafka.Writer, 4)
+	return nil
+}
+
+// Exchange do exchange with context
+func (b *BackendTransportService) Exchange(ctx flux.Context) *flux.ServeError {
+	return backend.DoExchange(ctx, b)
+}
+
+// Invoke 将请求数据写入Topic，Broker确认后返回 Ack
+func (b *BackendTransportService) Invoke(service flux.BackendService, ctx flux.Context) (interface{}, *flux.ServeError) {
+	message, err := b.Assemble(service, ctx)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusBadRequest,
+			ErrorCode:  flux.ErrorCodeRequestInvalid,
+			Message:    flux.ErrorMessageKafkaAssembleFailed,
+			Internal:   err,
+		}
+	}
+	writer, err := b.writerOf(service)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageKafkaProduceFailed,
+			Internal:   err,
+		}
+	}
+	timeout := b.timeout
+	if to := service.AttrRpcTimeout(); "" != to {
+		if d, err := time.ParseDuration(to); nil == err {
+			timeout = d
+		} else {
+			logger.Warnf("Illegal endpoint rpc-timeout: ", to)
+		}
+	}
+	toctx, cancel := context.WithTimeout(ctx.Context(), timeout)
+	defer cancel()
+	if err := writer.WriteMessages(toctx, message); nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusBadGateway,
+			ErrorCode:  flux.ErrorCodeGatewayBackend,
+			Message:    flux.ErrorMessageKafkaProduceFailed,
+			Internal:   err,
+		}
+	}
+	return &Ack{
+		Topic:     service.Interface,
+		Key:       string(message.Key),
+		RequestId: ctx.RequestId(),
+		Timestamp: message.Time.UnixNano() / int64(time.Millisecond),
+	}, nil
+}
+
+// Assemble 生成Kafka消息
+func (b *BackendTransportService) Assemble(service flux.BackendService, ctx flux.Context) (kafka.Message, error) {
+	message := kafka.Message{Time: time.Now()}
+	if len(service.Arguments) > 0 {
+		values, err := backend.ResolveArgumentValues(service.Arguments, ctx)
+		if nil != err {
+			return message, err
+		}
+		if name := service.ExtString(ServiceExtKeyMessageKey); "" != name {
+			key, ok := values[name]
+			if !ok {
+				return message, fmt.Errorf("kafka message key argument not found: %s", name)
+			}
+			message.Key = []byte(cast.ToString(key))
+		}
+		data, err := ext.JSONMarshal(values)
+		if nil != err {
+			return message, err
+		}
+		message.Value = data
+	} else {
+		reader, err := ctx.Request().RequestBodyReader()
+		if nil != err {
+			return message, err
+		}
+		data, err := ioutil.ReadAll(reader)
+		_ = reader.Close()
+		if nil != err {
+			return message, err
+		}
+		message.Value = data
+	}
+	message.Headers = append(message.Headers, kafka.Header{Key: flux.HeaderXRequestID, Value: []byte(ctx.RequestId())})
+	for k, v := range ctx.Attributes() {
+		message.Headers = append(message.Headers, kafka.Header{Key: k, Value: []byte(cast.ToString(v))})
+	}
+	return message, nil
+}
+
+// writerOf 按Broker列表和Topic复用Writer
+func (b *BackendTransportService) writerOf(service flux.BackendService) (*kafka.Writer, error) {
+	if "" == service.Interface {
+		return nil, fmt.Errorf("kafka topic (interface) is required, service: %s", service.ServiceID())
+	}
+	brokers := b.brokers
+	if "" != service.RemoteHost {
+		brokers = strings.Split(service.RemoteHost, ",")
+	}
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("kafka brokers is required, service: %s", service.ServiceID())
+	}
+	id := strings.Join(brokers, ",") + "/" + service.Interface
+	b.mu.RLock()
+	writer, ok := b.writers[id]
+	b.mu.RUnlock()
+	if ok {
+		return writer, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if writer, ok := b.writers[id]; ok {
+		return writer, nil
+	}
+	writer = kafka.NewWriter(kafka.WriterConfig{
+		Brokers:      brokers,
+		Topic:        service.Interface,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: b.requiredAcks,
+		BatchTimeout: b.batchTimeout,
+	})
+	b.writers[id] = writer
+	logger.Infow("Kafka backend transport create writer", "brokers", brokers, "topic", service.Interface)
+	return writer, nil
+}
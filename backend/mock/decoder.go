@@ -0,0 +1,22 @@
+package mock
+
+import (
+	"errors"
+	"github.com/bytepowered/flux"
+	"net/http"
+)
+
+var (
+	ErrUnknownMockBackendResponse = errors.New("BACKEND:UNKNOWN_MOCK_RESPONSE")
+)
+
+// NewMockBackendTransportDecodeFunc 返回预设的状态码、Header和Body
+func NewMockBackendTransportDecodeFunc() flux.BackendTransportDecodeFunc {
+	return func(ctx flux.Context, value interface{}) (statusCode int, headers http.Header, body interface{}, err error) {
+		resp, ok := value.(*Response)
+		if !ok {
+			return http.StatusInternalServerError, http.Header{}, nil, ErrUnknownMockBackendResponse
+		}
+		return resp.StatusCode, resp.Header, resp.Body, nil
+	}
+}
@@ -0,0 +1,11 @@
+package mock
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+)
+
+func init() {
+	ext.StoreBackendTransport(flux.ProtoMock, NewMockBackendTransport())
+	ext.StoreBackendTransportDecodeFunc(flux.ProtoMock, NewMockBackendTransportDecodeFunc())
+}
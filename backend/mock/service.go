@@ -0,0 +1,134 @@
+package mock
+
+import (
+	"bytes"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/backend"
+	"github.com/bytepowered/flux/ext"
+	"github.com/spf13/cast"
+	"net/http"
+	"sync"
+	"text/template"
+)
+
+const (
+	// Endpoint/BackendService扩展属性：响应状态码，默认200
+	ExtKeyStatus = "mock-status"
+	// Endpoint/BackendService扩展属性：响应Header，Map结构
+	ExtKeyHeaders = "mock-headers"
+	// Endpoint/BackendService扩展属性：响应Body模板（text/template），模板数据为参数值，例如：{"id":"{{.id}}"}
+	ExtKeyBody = "mock-body"
+	// Endpoint/BackendService扩展属性：响应的Content-Type，默认 application/json
+	ExtKeyContentType = "mock-content-type"
+)
+
+var (
+	_ flux.BackendTransport = new(BackendTransportService)
+)
+
+var templateFuncs = template.FuncMap{
+	// json 输出值的JSON编码，例如：{"user":{{json .user}}}
+	"json": func(v interface{}) (string, error) {
+		data, err := ext.JSONMarshal(v)
+		return string(data), err
+	},
+}
+
+// Response 预设的Mock响应
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// BackendTransportService 返回预设响应的BackendService，用于在上游服务就绪前发布Endpoint：
+// 响应的状态码、Header和Body模板由扩展属性定义，Endpoint的扩展属性优先于BackendService；
+// 模板数据为按 BackendService.Arguments 解析的参数值。
+type BackendTransportService struct {
+	templates sync.Map // 已解析的Body模板，Key为模板文本
+}
+
+// NewMockBackendTransport New mock backend instance
+func NewMockBackendTransport() flux.BackendTransport {
+	return &BackendTransportService{}
+}
+
+// Exchange do exchange with context
+func (b *BackendTransportService) Exchange(ctx flux.Context) *flux.ServeError {
+	return backend.DoExchange(ctx, b)
+}
+
+// Invoke 解析参数并渲染预设响应
+func (b *BackendTransportService) Invoke(service flux.BackendService, ctx flux.Context) (interface{}, *flux.ServeError) {
+	values, err := backend.ResolveArgumentValues(service.Arguments, ctx)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusBadRequest,
+			ErrorCode:  flux.ErrorCodeRequestInvalid,
+			Message:    flux.ErrorMessageMockAssembleFailed,
+			Internal:   err,
+		}
+	}
+	endpoint := ctx.Endpoint()
+	extOf := func(name string) (interface{}, bool) {
+		if v, ok := endpoint.Ext(name); ok {
+			return v, true
+		}
+		return service.Ext(name)
+	}
+	resp := &Response{StatusCode: http.StatusOK, Header: http.Header{}}
+	if v, ok := extOf(ExtKeyStatus); ok {
+		if code := cast.ToInt(v); code > 0 {
+			resp.StatusCode = code
+		}
+	}
+	resp.Header.Set(flux.HeaderContentType, flux.MIMEApplicationJSONCharsetUTF8)
+	if v, ok := extOf(ExtKeyContentType); ok {
+		resp.Header.Set(flux.HeaderContentType, cast.ToString(v))
+	}
+	if v, ok := extOf(ExtKeyHeaders); ok {
+		for name, value := range cast.ToStringMapString(v) {
+			resp.Header.Set(name, value)
+		}
+	}
+	if v, ok := extOf(ExtKeyBody); ok {
+		body, err := b.render(cast.ToString(v), values)
+		if nil != err {
+			return nil, &flux.ServeError{
+				StatusCode: flux.StatusServerError,
+				ErrorCode:  flux.ErrorCodeGatewayInternal,
+				Message:    flux.ErrorMessageMockTemplateFailed,
+				Internal:   err,
+			}
+		}
+		resp.Body = body
+	}
+	return resp, nil
+}
+
+func (b *BackendTransportService) render(text string, values map[string]interface{}) ([]byte, error) {
+	var tpl *template.Template
+	if v, ok := b.templates.Load(text); ok {
+		tpl = v.(*template.Template)
+	} else {
+		parsed, err := template.New("mock").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+		if nil != err {
+			return nil, err
+		}
+		b.templates.Store(text, parsed)
+		tpl = parsed
+	}
+	// 未提供的参数输出为空字符串，而不是 <no value>
+	data := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		if nil == value {
+			value = ""
+		}
+		data[name] = value
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); nil != err {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
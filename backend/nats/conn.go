@@ -0,0 +1,345 @@
+package nats
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrConnectionClosed = errors.New("nats connection closed")
+	ErrNoResponders     = errors.New("nats no responders available for request")
+)
+
+const (
+	headerLine    = "NATS/1.0"
+	statusNoReply = 503
+	maxLineSize   = 64 * 1024
+)
+
+// Msg NATS消息；Status 为请求响应消息中的状态码（例如：503 表示没有订阅者）
+type Msg struct {
+	Subject string
+	Reply   string
+	Header  http.Header
+	Status  int
+	Data    []byte
+}
+
+type serverInfo struct {
+	Headers      bool `json:"headers"`
+	TLSRequired  bool `json:"tls_required"`
+	MaxPayload   int  `json:"max_payload"`
+	AuthRequired bool `json:"auth_required"`
+}
+
+type connectInfo struct {
+	Verbose      bool   `json:"verbose"`
+	Pedantic     bool   `json:"pedantic"`
+	Name         string `json:"name"`
+	Lang         string `json:"lang"`
+	Version      string `json:"version"`
+	Protocol     int    `json:"protocol"`
+	Headers      bool   `json:"headers"`
+	NoResponders bool   `json:"no_responders"`
+	User         string `json:"user,omitempty"`
+	Pass         string `json:"pass,omitempty"`
+	Token        string `json:"auth_token,omitempty"`
+}
+
+// conn 最小实现的NATS客户端连接：支持发布消息和基于 Inbox 通配订阅的请求/响应
+type conn struct {
+	nc      net.Conn
+	reader  *bufio.Reader
+	writer  *bufio.Writer
+	wmu     sync.Mutex
+	info    serverInfo
+	inbox   string
+	seq     uint64
+	pending map[string]chan *Msg
+	pmu     sync.Mutex
+	closed  chan struct{}
+	once    sync.Once
+}
+
+// dial 连接NATS服务端，完成 INFO/CONNECT 握手并订阅响应 Inbox；地址格式：nats://[user:pass@|token@]host:port 或 tls://host:port
+func dial(address string, timeout time.Duration) (*conn, error) {
+	target, err := url.Parse(address)
+	if nil != err {
+		return nil, err
+	}
+	host := target.Host
+	if "" == target.Port() {
+		host = net.JoinHostPort(target.Hostname(), "4222")
+	}
+	nc, err := net.DialTimeout("tcp", host, timeout)
+	if nil != err {
+		return nil, err
+	}
+	_ = nc.SetDeadline(time.Now().Add(timeout))
+	c := &conn{nc: nc, reader: bufio.NewReaderSize(nc, maxLineSize), pending: make(map[string]chan *Msg, 16), closed: make(chan struct{})}
+	if err := c.handshake(target); nil != err {
+		_ = nc.Close()
+		return nil, err
+	}
+	_ = c.nc.SetDeadline(time.Time{})
+	go c.readLoop()
+	return c, nil
+}
+
+func (c *conn) handshake(target *url.URL) error {
+	line, err := c.readLine()
+	if nil != err {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("nats unexpected handshake: %s", line)
+	}
+	if err := json.Unmarshal([]byte(line[5:]), &c.info); nil != err {
+		return err
+	}
+	if c.info.TLSRequired || "tls" == target.Scheme {
+		tc := tls.Client(c.nc, &tls.Config{ServerName: target.Hostname()})
+		if err := tc.Handshake(); nil != err {
+			return err
+		}
+		c.nc, c.reader = tc, bufio.NewReaderSize(tc, maxLineSize)
+	}
+	c.writer = bufio.NewWriter(c.nc)
+	connect := connectInfo{
+		Name: "flux", Lang: "go", Version: "flux", Protocol: 1,
+		Headers: c.info.Headers, NoResponders: c.info.Headers,
+	}
+	if user := target.User; nil != user {
+		if pass, ok := user.Password(); ok {
+			connect.User, connect.Pass = user.Username(), pass
+		} else {
+			connect.Token = user.Username()
+		}
+	}
+	data, _ := json.Marshal(connect)
+	prefix := make([]byte, 8)
+	_, _ = rand.Read(prefix)
+	c.inbox = "_INBOX." + hex.EncodeToString(prefix) + "."
+	fmt.Fprintf(c.writer, "CONNECT %s\r\nPING\r\nSUB %s* 1\r\n", data, c.inbox)
+	if err := c.writer.Flush(); nil != err {
+		return err
+	}
+	for {
+		line, err := c.readLine()
+		if nil != err {
+			return err
+		}
+		switch {
+		case "PONG" == line:
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats connect: %s", line)
+		}
+	}
+}
+
+// Publish 发布消息；服务端支持Header时使用 HPUB 传递Header
+func (c *conn) Publish(subject, reply string, header http.Header, data []byte) error {
+	if c.IsClosed() {
+		return ErrConnectionClosed
+	}
+	if c.info.MaxPayload > 0 && len(data) > c.info.MaxPayload {
+		return fmt.Errorf("nats payload too large: %d > %d", len(data), c.info.MaxPayload)
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if "" != reply {
+		reply = " " + reply
+	}
+	if len(header) > 0 && c.info.Headers {
+		hdr := encodeHeader(header)
+		fmt.Fprintf(c.writer, "HPUB %s%s %d %d\r\n", subject, reply, len(hdr), len(hdr)+len(data))
+		_, _ = c.writer.Write(hdr)
+	} else {
+		fmt.Fprintf(c.writer, "PUB %s%s %d\r\n", subject, reply, len(data))
+	}
+	_, _ = c.writer.Write(data)
+	_, _ = c.writer.WriteString("\r\n")
+	return c.writer.Flush()
+}
+
+// Request 发布请求消息并等待响应
+func (c *conn) Request(ctx context.Context, subject string, header http.Header, data []byte) (*Msg, error) {
+	reply := c.inbox + strconv.FormatUint(atomic.AddUint64(&c.seq, 1), 36)
+	ch := make(chan *Msg, 1)
+	c.pmu.Lock()
+	c.pending[reply] = ch
+	c.pmu.Unlock()
+	defer func() {
+		c.pmu.Lock()
+		delete(c.pending, reply)
+		c.pmu.Unlock()
+	}()
+	if err := c.Publish(subject, reply, header, data); nil != err {
+		return nil, err
+	}
+	select {
+	case msg := <-ch:
+		if statusNoReply == msg.Status {
+			return nil, ErrNoResponders
+		}
+		return msg, nil
+	case <-c.closed:
+		return nil, ErrConnectionClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *conn) IsClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *conn) Close() error {
+	c.once.Do(func() {
+		close(c.closed)
+	})
+	return c.nc.Close()
+}
+
+func (c *conn) readLoop() {
+	defer c.Close()
+	for {
+		line, err := c.readLine()
+		if nil != err {
+			return
+		}
+		switch {
+		case strings.HasPrefix(line, "MSG "), strings.HasPrefix(line, "HMSG "):
+			msg, err := c.readMsg(line)
+			if nil != err {
+				return
+			}
+			c.pmu.Lock()
+			ch, ok := c.pending[msg.Subject]
+			c.pmu.Unlock()
+			if ok {
+				select {
+				case ch <- msg:
+				default:
+				}
+			}
+		case "PING" == line:
+			c.wmu.Lock()
+			_, _ = c.writer.WriteString("PONG\r\n")
+			err = c.writer.Flush()
+			c.wmu.Unlock()
+			if nil != err {
+				return
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			// 服务端发送 -ERR 后关闭连接
+			if strings.Contains(strings.ToLower(line), "permissions violation") {
+				continue
+			}
+			return
+		}
+	}
+}
+
+// readMsg 读取消息：MSG <subject> <sid> [reply] <size> 或 HMSG <subject> <sid> [reply] <header size> <total size>
+func (c *conn) readMsg(line string) (*Msg, error) {
+	args := strings.Fields(line)
+	withHeader := "HMSG" == args[0]
+	args = args[1:]
+	counts := 1
+	if withHeader {
+		counts = 2
+	}
+	if len(args) < 2+counts || len(args) > 3+counts {
+		return nil, fmt.Errorf("nats malformed message: %s", line)
+	}
+	msg := &Msg{Subject: args[0]}
+	if len(args) == 3+counts {
+		msg.Reply = args[2]
+	}
+	total, err := strconv.Atoi(args[len(args)-1])
+	if nil != err || total < 0 {
+		return nil, fmt.Errorf("nats malformed message: %s", line)
+	}
+	hsize := 0
+	if withHeader {
+		if hsize, err = strconv.Atoi(args[len(args)-2]); nil != err || hsize < 0 || hsize > total {
+			return nil, fmt.Errorf("nats malformed message: %s", line)
+		}
+	}
+	payload := make([]byte, total+2)
+	if _, err := io.ReadFull(c.reader, payload); nil != err {
+		return nil, err
+	}
+	if withHeader {
+		if msg.Status, msg.Header, err = decodeHeader(payload[:hsize]); nil != err {
+			return nil, err
+		}
+	}
+	msg.Data = payload[hsize:total]
+	return msg, nil
+}
+
+func (c *conn) readLine() (string, error) {
+	line, err := c.reader.ReadSlice('\n')
+	if nil != err {
+		if bufio.ErrBufferFull == err {
+			return "", fmt.Errorf("nats protocol line exceeds %d bytes", maxLineSize)
+		}
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+func encodeHeader(header http.Header) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(headerLine + "\r\n")
+	for k, vs := range header {
+		for _, v := range vs {
+			buf.WriteString(k + ": " + v + "\r\n")
+		}
+	}
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// decodeHeader 解析消息Header：NATS/1.0 [status [description]]，后续为MIME格式的Header
+func decodeHeader(data []byte) (int, http.Header, error) {
+	reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(data)))
+	line, err := reader.ReadLine()
+	if nil != err || !strings.HasPrefix(line, headerLine) {
+		return 0, nil, fmt.Errorf("nats malformed header: %s", line)
+	}
+	status := 0
+	if fields := strings.Fields(line[len(headerLine):]); len(fields) > 0 {
+		status, _ = strconv.Atoi(fields[0])
+	}
+	header, err := reader.ReadMIMEHeader()
+	if nil != err && io.EOF != err {
+		return 0, nil, err
+	}
+	return status, http.Header(header), nil
+}
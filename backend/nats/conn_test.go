@@ -0,0 +1,205 @@
+package nats
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	assert2 "github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// natsTestServer 按NATS协议脚本应答的测试服务端：
+// 发布到 echo 的请求原样回复Data和Header（无Header时使用MSG）；发布到 none 的请求回复 503 状态；其它消息被丢弃。
+type natsTestServer struct {
+	listener net.Listener
+	info     string
+	reject   string
+	connects chan connectInfo
+	pongs    chan struct{}
+	conns    chan net.Conn
+}
+
+func newNatsTestServer(t *testing.T, info string) *natsTestServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	s := &natsTestServer{listener: listener, info: info,
+		connects: make(chan connectInfo, 1), pongs: make(chan struct{}, 1), conns: make(chan net.Conn, 1)}
+	go func() {
+		for {
+			nc, err := listener.Accept()
+			if nil != err {
+				return
+			}
+			go s.serve(nc)
+		}
+	}()
+	return s
+}
+
+func (s *natsTestServer) address(userinfo string) string {
+	return "nats://" + userinfo + s.listener.Addr().String()
+}
+
+func (s *natsTestServer) serve(nc net.Conn) {
+	defer nc.Close()
+	reader := bufio.NewReader(nc)
+	_, _ = fmt.Fprintf(nc, "INFO %s\r\n", s.info)
+	for {
+		line, err := reader.ReadString('\n')
+		if nil != err {
+			return
+		}
+		args := strings.Fields(line)
+		switch args[0] {
+		case "CONNECT":
+			connect := connectInfo{}
+			_ = json.Unmarshal([]byte(strings.TrimSpace(line[len("CONNECT "):])), &connect)
+			s.connects <- connect
+		case "PING":
+			if "" != s.reject {
+				_, _ = fmt.Fprintf(nc, "-ERR '%s'\r\n", s.reject)
+				return
+			}
+			_, _ = io.WriteString(nc, "PONG\r\n")
+			s.conns <- nc
+		case "PONG":
+			s.pongs <- struct{}{}
+		case "PUB", "HPUB":
+			total, _ := strconv.Atoi(args[len(args)-1])
+			payload := make([]byte, total+2)
+			if _, err := io.ReadFull(reader, payload); nil != err {
+				return
+			}
+			hsize := 0
+			if "HPUB" == args[0] {
+				hsize, _ = strconv.Atoi(args[len(args)-2])
+			}
+			subject, reply := args[1], args[2]
+			switch {
+			case "echo" == subject && 0 == hsize:
+				_, _ = fmt.Fprintf(nc, "MSG %s 1 %d\r\n%s\r\n", reply, total, payload[:total])
+			case "echo" == subject:
+				_, _ = fmt.Fprintf(nc, "HMSG %s 1 %d %d\r\n%s\r\n", reply, hsize, total, payload[:total])
+			case "none" == subject:
+				hdr := "NATS/1.0 503\r\n\r\n"
+				_, _ = fmt.Fprintf(nc, "HMSG %s 1 %d %d\r\n%s\r\n", reply, len(hdr), len(hdr), hdr)
+			}
+		}
+	}
+}
+
+func TestConn_Request(t *testing.T) {
+	assert := assert2.New(t)
+	server := newNatsTestServer(t, `{"headers":true,"max_payload":16}`)
+	defer server.listener.Close()
+	c, err := dial(server.address("flux:secret@"), time.Second)
+	if !assert.NoError(err) {
+		return
+	}
+	defer c.Close()
+	connect := <-server.connects
+	assert.Equal("flux", connect.User)
+	assert.Equal("secret", connect.Pass)
+	assert.True(connect.Headers)
+	assert.True(connect.NoResponders)
+	// HPUB 请求，HMSG 响应
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, err := c.Request(ctx, "echo", http.Header{"X-Request-Id": []string{"req-1"}}, []byte("hello"))
+	if assert.NoError(err) {
+		assert.Equal("hello", string(msg.Data))
+		assert.Equal("req-1", msg.Header.Get("X-Request-Id"))
+		assert.True(strings.HasPrefix(msg.Subject, c.inbox))
+	}
+	// PUB 请求，无Header
+	msg, err = c.Request(ctx, "echo", nil, []byte("world"))
+	if assert.NoError(err) {
+		assert.Equal("world", string(msg.Data))
+	}
+	// 没有订阅者
+	_, err = c.Request(ctx, "none", nil, []byte("x"))
+	assert.Equal(ErrNoResponders, err)
+	// 超过服务端限制的消息
+	assert.Error(c.Publish("echo", "", nil, make([]byte, 17)))
+	// 未回复的请求等待超时
+	toctx, tocancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer tocancel()
+	_, err = c.Request(toctx, "drop", nil, []byte("x"))
+	assert.Equal(context.DeadlineExceeded, err)
+}
+
+func TestConn_PingAndClose(t *testing.T) {
+	assert := assert2.New(t)
+	server := newNatsTestServer(t, `{}`)
+	defer server.listener.Close()
+	c, err := dial(server.address("token@"), time.Second)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal("token", (<-server.connects).Token)
+	nc := <-server.conns
+	// 服务端PING，客户端回复PONG
+	_, _ = io.WriteString(nc, "PING\r\n")
+	select {
+	case <-server.pongs:
+	case <-time.After(time.Second):
+		assert.Fail("PONG not received")
+	}
+	// 服务端关闭连接后，等待中的请求返回连接已关闭
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = io.WriteString(nc, "-ERR 'Stale Connection'\r\n")
+	}()
+	_, err = c.Request(ctx, "drop", nil, []byte("x"))
+	assert.Equal(ErrConnectionClosed, err)
+	assert.True(c.IsClosed())
+	assert.Equal(ErrConnectionClosed, c.Publish("echo", "", nil, nil))
+}
+
+func TestDial_Rejected(t *testing.T) {
+	server := newNatsTestServer(t, `{"auth_required":true}`)
+	server.reject = "Authorization Violation"
+	defer server.listener.Close()
+	_, err := dial(server.address(""), time.Second)
+	if assert2.Error(t, err) {
+		assert2.Contains(t, err.Error(), "Authorization Violation")
+	}
+}
+
+func TestConn_ReadMsg(t *testing.T) {
+	assert := assert2.New(t)
+	newConn := func(data string) *conn {
+		return &conn{reader: bufio.NewReader(strings.NewReader(data))}
+	}
+	msg, err := newConn("hello\r\n").readMsg("MSG foo 1 _INBOX.x 5")
+	if assert.NoError(err) {
+		assert.Equal("foo", msg.Subject)
+		assert.Equal("_INBOX.x", msg.Reply)
+		assert.Equal("hello", string(msg.Data))
+	}
+	hdr := "NATS/1.0 503 No Responders\r\nX-Id: 1\r\n\r\n"
+	msg, err = newConn(hdr + "ok\r\n").readMsg(fmt.Sprintf("HMSG foo 1 %d %d", len(hdr), len(hdr)+2))
+	if assert.NoError(err) {
+		assert.Equal(503, msg.Status)
+		assert.Equal("1", msg.Header.Get("X-Id"))
+		assert.Equal("ok", string(msg.Data))
+	}
+	for _, line := range []string{"MSG foo 5", "MSG foo 1 a b 5", "HMSG foo 1 9 5", "MSG foo 1 -1"} {
+		_, err = newConn("hello\r\n").readMsg(line)
+		assert.Error(err, line)
+	}
+	// 消息数据不完整
+	_, err = newConn("he").readMsg("MSG foo 1 5")
+	assert.Error(err)
+}
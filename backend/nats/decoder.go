@@ -0,0 +1,41 @@
+package nats
+
+import (
+	"errors"
+	"github.com/bytepowered/flux"
+	"github.com/spf13/cast"
+	"net/http"
+)
+
+const (
+	// request 模式下，响应消息中指定HTTP状态码的Header
+	ReplyHeaderStatusCode = "Status-Code"
+)
+
+var (
+	ErrUnknownNatsBackendResponse = errors.New("BACKEND:UNKNOWN_NATS_RESPONSE")
+)
+
+// NewNatsBackendTransportDecodeFunc publish 模式返回 202 和确认信息；request 模式返回响应消息数据
+func NewNatsBackendTransportDecodeFunc() flux.BackendTransportDecodeFunc {
+	return func(ctx flux.Context, value interface{}) (statusCode int, headers http.Header, body interface{}, err error) {
+		switch resp := value.(type) {
+		case *Ack:
+			return http.StatusAccepted, http.Header{}, resp, nil
+		case *Msg:
+			header := http.Header{}
+			status := http.StatusOK
+			if nil != resp.Header {
+				if ct := resp.Header.Get(flux.HeaderContentType); "" != ct {
+					header.Set(flux.HeaderContentType, ct)
+				}
+				if code := cast.ToInt(resp.Header.Get(ReplyHeaderStatusCode)); code > 0 {
+					status = code
+				}
+			}
+			return status, header, resp.Data, nil
+		default:
+			return http.StatusInternalServerError, http.Header{}, nil, ErrUnknownNatsBackendResponse
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package nats
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+)
+
+func init() {
+	ext.StoreBackendTransport(flux.ProtoNats, NewNatsBackendTransport())
+	ext.StoreBackendTransportDecodeFunc(flux.ProtoNats, NewNatsBackendTransportDecodeFunc())
+}
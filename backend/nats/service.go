@@ -0,0 +1,204 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/backend"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"github.com/spf13/cast"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	configKeyURL     = "url"
+	configKeyTimeout = "timeout"
+)
+
+const (
+	// BackendService扩展属性：调用模式，request（默认）或 publish
+	ServiceExtKeyMode = "nats-mode"
+)
+
+const (
+	// 发布请求消息并等待响应消息
+	ModeRequest = "request"
+	// 发布消息后立即返回，不等待订阅者处理
+	ModePublish = "publish"
+)
+
+var (
+	_ flux.BackendTransport = new(BackendTransportService)
+)
+
+// Ack publish 模式下，消息发布成功后返回的确认信息
+type Ack struct {
+	Subject   string `json:"subject"`
+	RequestId string `json:"requestId"`
+}
+
+// BackendTransportService 基于NATS的BackendService：
+// BackendService.Interface 为Subject，RemoteHost 为NATS服务地址（未设置时使用全局配置）；
+// 定义了Arguments时，消息为参数值组成的JSON对象，否则为原始请求Body。Attributes作为消息Header传递。
+type BackendTransportService struct {
+	url     string
+	timeout time.Duration
+	conns   map[string]*conn
+	mu      sync.Mutex
+}
+
+// NewNatsBackendTransport New nats backend instance
+func NewNatsBackendTransport() flux.BackendTransport {
+	return &BackendTransportService{
+		conns: make(map[string]*conn, 2),
+	}
+}
+
+// Init init backend
+func (b *BackendTransportService) Init(config *flux.Configuration) error {
+	logger.Info("NATS backend transport initializing")
+	config.SetDefaults(map[string]interface{}{
+		configKeyURL:     "nats://127.0.0.1:4222",
+		configKeyTimeout: time.Second * 10,
+	})
+	b.url = config.GetString(configKeyURL)
+	b.timeout = config.GetDuration(configKeyTimeout)
+	return nil
+}
+
+// Shutdown shutdown backend
+func (b *BackendTransportService) Shutdown(_ context.Context) error {
+	logger.Info("NATS backend transport shutdown")
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, c := range b.conns {
+		_ = c.Close()
+	}
+	b.conns = make(map[string]*conn, 2)
+	return nil
+}
+
+// Exchange do exchange with context
+func (b *BackendTransportService) Exchange(ctx flux.Context) *flux.ServeError {
+	return backend.DoExchange(ctx, b)
+}
+
+// Invoke 发布消息；request 模式下返回响应消息 Msg，publish 模式下返回 Ack
+func (b *BackendTransportService) Invoke(service flux.BackendService, ctx flux.Context) (interface{}, *flux.ServeError) {
+	header, data, err := b.Assemble(service, ctx)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusBadRequest,
+			ErrorCode:  flux.ErrorCodeRequestInvalid,
+			Message:    flux.ErrorMessageNatsAssembleFailed,
+			Internal:   err,
+		}
+	}
+	timeout := b.timeout
+	if to := service.AttrRpcTimeout(); "" != to {
+		if d, err := time.ParseDuration(to); nil == err {
+			timeout = d
+		} else {
+			logger.Warnf("Illegal endpoint rpc-timeout: ", to)
+		}
+	}
+	c, err := b.connOf(service, timeout)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusBadGateway,
+			ErrorCode:  flux.ErrorCodeGatewayBackend,
+			Message:    flux.ErrorMessageNatsInvokeFailed,
+			Internal:   err,
+		}
+	}
+	if ModePublish == service.ExtString(ServiceExtKeyMode) {
+		if err := c.Publish(service.Interface, "", header, data); nil != err {
+			return nil, &flux.ServeError{
+				StatusCode: flux.StatusBadGateway,
+				ErrorCode:  flux.ErrorCodeGatewayBackend,
+				Message:    flux.ErrorMessageNatsInvokeFailed,
+				Internal:   err,
+			}
+		}
+		return &Ack{Subject: service.Interface, RequestId: ctx.RequestId()}, nil
+	}
+	toctx, cancel := context.WithTimeout(ctx.Context(), timeout)
+	defer cancel()
+	msg, err := c.Request(toctx, service.Interface, header, data)
+	if nil != err {
+		serr := &flux.ServeError{
+			StatusCode: flux.StatusBadGateway,
+			ErrorCode:  flux.ErrorCodeGatewayBackend,
+			Message:    flux.ErrorMessageNatsInvokeFailed,
+			Internal:   err,
+		}
+		switch err {
+		case context.DeadlineExceeded:
+			serr.StatusCode = flux.StatusGatewayTimeout
+		case ErrNoResponders:
+			serr.StatusCode = http.StatusServiceUnavailable
+		}
+		return nil, serr
+	}
+	return msg, nil
+}
+
+// Assemble 生成NATS消息的Header和数据
+func (b *BackendTransportService) Assemble(service flux.BackendService, ctx flux.Context) (http.Header, []byte, error) {
+	if "" == service.Interface {
+		return nil, nil, fmt.Errorf("nats subject (interface) is required, service: %s", service.ServiceID())
+	}
+	header := http.Header{}
+	header.Set(flux.HeaderXRequestID, ctx.RequestId())
+	for k, v := range ctx.Attributes() {
+		header.Set(k, cast.ToString(v))
+	}
+	if len(service.Arguments) > 0 {
+		values, err := backend.ResolveArgumentValues(service.Arguments, ctx)
+		if nil != err {
+			return nil, nil, err
+		}
+		data, err := ext.JSONMarshal(values)
+		if nil != err {
+			return nil, nil, err
+		}
+		header.Set(flux.HeaderContentType, flux.MIMEApplicationJSON)
+		return header, data, nil
+	}
+	reader, err := ctx.Request().RequestBodyReader()
+	if nil != err {
+		return nil, nil, err
+	}
+	data, err := ioutil.ReadAll(reader)
+	_ = reader.Close()
+	if nil != err {
+		return nil, nil, err
+	}
+	if ct := ctx.Request().HeaderValue(flux.HeaderContentType); "" != ct {
+		header.Set(flux.HeaderContentType, ct)
+	}
+	return header, data, nil
+}
+
+// connOf 复用到NATS服务的连接；连接断开时重新连接
+func (b *BackendTransportService) connOf(service flux.BackendService, timeout time.Duration) (*conn, error) {
+	url := b.url
+	if "" != service.RemoteHost {
+		url = service.RemoteHost
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c, ok := b.conns[url]; ok && !c.IsClosed() {
+		return c, nil
+	}
+	c, err := dial(url, timeout)
+	if nil != err {
+		return nil, err
+	}
+	b.conns[url] = c
+	return c, nil
+}
@@ -0,0 +1,132 @@
+package backend
+
+import (
+	"context"
+	"github.com/bytepowered/flux"
+	"github.com/spf13/cast"
+	"time"
+)
+
+// fluxContext 别名用于嵌入flux.Context：嵌入字段以别名命名，避免与Context()方法同名
+type fluxContext = flux.Context
+
+var _ flux.Context = new(OverlayContext)
+
+// OverlayContext 叠加在原Context之上的Context，用于并发的后端调用（对冲请求、组合编排步骤）：
+// 使用独立的Go Context，可替换Endpoint的后端服务；Attribute、Value和Metric写入本地，读取时本地优先，
+// 避免并发的调用修改原Context。调用结束后由调用方通过 Commit/CommitMetrics 合并到原Context。
+type OverlayContext struct {
+	fluxContext
+	goctx      context.Context
+	service    *flux.BackendService
+	attributes map[string]interface{}
+	values     map[string]interface{}
+	metrics    []flux.Metric
+}
+
+func NewOverlayContext(ctx flux.Context, goctx context.Context) *OverlayContext {
+	return &OverlayContext{
+		fluxContext: ctx,
+		goctx:       goctx,
+		attributes:  make(map[string]interface{}, 4),
+		values:      make(map[string]interface{}, 4),
+	}
+}
+
+// WithService 替换Endpoint的后端服务
+func (c *OverlayContext) WithService(service flux.BackendService) *OverlayContext {
+	c.service = &service
+	return c
+}
+
+func (c *OverlayContext) Context() context.Context {
+	return c.goctx
+}
+
+func (c *OverlayContext) Endpoint() flux.Endpoint {
+	endpoint := c.fluxContext.Endpoint()
+	if nil != c.service {
+		endpoint.Service = *c.service
+	}
+	return endpoint
+}
+
+func (c *OverlayContext) ServiceInterface() (proto, host, interfaceName, methodName string) {
+	if nil == c.service {
+		return c.fluxContext.ServiceInterface()
+	}
+	return c.service.AttrRpcProto(), c.service.RemoteHost, c.service.Interface, c.service.Method
+}
+
+func (c *OverlayContext) ServiceProto() string {
+	if nil == c.service {
+		return c.fluxContext.ServiceProto()
+	}
+	return c.service.AttrRpcProto()
+}
+
+func (c *OverlayContext) ServiceName() (interfaceName, methodName string) {
+	if nil == c.service {
+		return c.fluxContext.ServiceName()
+	}
+	return c.service.Interface, c.service.Method
+}
+
+func (c *OverlayContext) SetAttribute(name string, value interface{}) {
+	c.attributes[name] = value
+}
+
+func (c *OverlayContext) GetAttribute(name string) (interface{}, bool) {
+	if v, ok := c.attributes[name]; ok {
+		return v, true
+	}
+	return c.fluxContext.GetAttribute(name)
+}
+
+func (c *OverlayContext) GetAttributeString(name string, defaultValue string) string {
+	if v, ok := c.GetAttribute(name); ok {
+		return cast.ToString(v)
+	}
+	return defaultValue
+}
+
+func (c *OverlayContext) SetValue(name string, value interface{}) {
+	c.values[name] = value
+}
+
+func (c *OverlayContext) GetValue(name string) (interface{}, bool) {
+	if v, ok := c.values[name]; ok {
+		return v, true
+	}
+	return c.fluxContext.GetValue(name)
+}
+
+func (c *OverlayContext) GetValueString(name string, defaultValue string) string {
+	if v, ok := c.GetValue(name); ok {
+		return cast.ToString(v)
+	}
+	return defaultValue
+}
+
+func (c *OverlayContext) AddMetric(name string, elapsed time.Duration) {
+	c.metrics = append(c.metrics, flux.Metric{Name: name, Elapsed: elapsed, Elapses: elapsed.String()})
+}
+
+// Commit 将本地写入的Attribute、Value和Metric合并到原Context
+func (c *OverlayContext) Commit() {
+	for k, v := range c.attributes {
+		c.fluxContext.SetAttribute(k, v)
+	}
+	for k, v := range c.values {
+		c.fluxContext.SetValue(k, v)
+	}
+	c.CommitMetrics()
+}
+
+// CommitMetrics 只将本地的Metric合并到原Context
+func (c *OverlayContext) CommitMetrics() {
+	for _, m := range c.metrics {
+		c.fluxContext.AddMetric(m.Name, m.Elapsed)
+	}
+	c.metrics = nil
+}
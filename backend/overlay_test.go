@@ -0,0 +1,82 @@
+package backend
+
+import (
+	"context"
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type overlayTestContext struct {
+	fluxContext
+	endpoint   flux.Endpoint
+	attributes map[string]interface{}
+	values     map[string]interface{}
+	metrics    []flux.Metric
+}
+
+func newOverlayTestContext() *overlayTestContext {
+	return &overlayTestContext{
+		endpoint:   flux.Endpoint{HttpPattern: "/users", Service: flux.BackendService{Interface: "user", Method: "get"}},
+		attributes: map[string]interface{}{"origin": "a"},
+		values:     map[string]interface{}{"origin": "v"},
+	}
+}
+
+func (c *overlayTestContext) Endpoint() flux.Endpoint                 { return c.endpoint }
+func (c *overlayTestContext) Context() context.Context                { return context.Background() }
+func (c *overlayTestContext) SetAttribute(name string, v interface{}) { c.attributes[name] = v }
+func (c *overlayTestContext) SetValue(name string, v interface{})     { c.values[name] = v }
+func (c *overlayTestContext) GetAttribute(name string) (interface{}, bool) {
+	v, ok := c.attributes[name]
+	return v, ok
+}
+func (c *overlayTestContext) GetValue(name string) (interface{}, bool) {
+	v, ok := c.values[name]
+	return v, ok
+}
+func (c *overlayTestContext) AddMetric(name string, elapsed time.Duration) {
+	c.metrics = append(c.metrics, flux.Metric{Name: name, Elapsed: elapsed})
+}
+
+func TestOverlayContext_Service(t *testing.T) {
+	assert := assert2.New(t)
+	origin := newOverlayTestContext()
+	goctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx := NewOverlayContext(origin, goctx).WithService(flux.BackendService{Interface: "order", Method: "list"})
+	assert.Equal(goctx, ctx.Context())
+	assert.Equal("order", ctx.Endpoint().Service.Interface)
+	assert.Equal("/users", ctx.Endpoint().HttpPattern)
+	_, _, iface, method := ctx.ServiceInterface()
+	assert.Equal("order", iface)
+	assert.Equal("list", method)
+	// 原Context的后端服务不变
+	assert.Equal("user", origin.Endpoint().Service.Interface)
+}
+
+func TestOverlayContext_Commit(t *testing.T) {
+	assert := assert2.New(t)
+	origin := newOverlayTestContext()
+	ctx := NewOverlayContext(origin, context.Background())
+	ctx.SetAttribute("local", 1)
+	ctx.SetValue("origin", "overlay")
+	ctx.AddMetric("M-Test", time.Millisecond)
+	// 读取时本地优先，未写入原Context
+	assert.Equal("overlay", ctx.GetValueString("origin", ""))
+	assert.Equal("a", ctx.GetAttributeString("origin", ""))
+	assert.Equal("1", ctx.GetAttributeString("local", ""))
+	assert.Equal("v", origin.values["origin"])
+	assert.NotContains(origin.attributes, "local")
+	assert.Empty(origin.metrics)
+	// 只合并Metric
+	ctx.CommitMetrics()
+	assert.Len(origin.metrics, 1)
+	assert.NotContains(origin.attributes, "local")
+	// 全部合并，Metric不重复合并
+	ctx.Commit()
+	assert.Len(origin.metrics, 1)
+	assert.Equal(1, origin.attributes["local"])
+	assert.Equal("overlay", origin.values["origin"])
+}
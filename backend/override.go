@@ -0,0 +1,22 @@
+package backend
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/spf13/cast"
+)
+
+const (
+	// Context.Value：覆盖本次请求的后端服务地址（host:port），由调试Filter在验证后设置；
+	// 设置后不解析上游集群，不使用响应缓存、请求合并和流量镜像
+	ValueKeyUpstreamOverride = "backend.upstream-override"
+)
+
+// upstreamOverrideOf 返回请求覆盖的后端服务地址
+func upstreamOverrideOf(ctx flux.Context) (string, bool) {
+	v, ok := ctx.GetValue(ValueKeyUpstreamOverride)
+	if !ok {
+		return "", false
+	}
+	host := cast.ToString(v)
+	return host, "" != host
+}
@@ -0,0 +1,330 @@
+package backend
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/http2"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	PoolConfigKeyMaxConnsPerUpstream     = "max-conns-per-upstream"
+	PoolConfigKeyMaxIdleConnsPerUpstream = "max-idle-conns-per-upstream"
+	PoolConfigKeyIdleTimeout             = "idle-timeout"
+	PoolConfigKeyDialTimeout             = "dial-timeout"
+	PoolConfigKeyKeepAlive               = "keep-alive"
+)
+
+var (
+	ErrPoolExhausted = errors.New("backend pool: max connections per upstream exceeded")
+)
+
+var (
+	poolOpenConns = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "flux",
+		Subsystem: "backend",
+		Name:      "pool_open_conns",
+		Help:      "Number of open connections to upstream",
+	}, []string{"Pool", "Upstream"})
+	poolDials = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flux",
+		Subsystem: "backend",
+		Name:      "pool_dials_total",
+		Help:      "Number of connection dials to upstream",
+	}, []string{"Pool", "Upstream", "Result"})
+)
+
+var (
+	pools sync.Map // 已创建的连接池，Key为连接池名称
+)
+
+func init() {
+	prometheus.MustRegister(poolOpenConns, poolDials)
+}
+
+// PoolOptions 上游连接池配置；连接数限制按上游地址（host:port）计算，0表示不限制
+type PoolOptions struct {
+	MaxConnsPerUpstream     int
+	MaxIdleConnsPerUpstream int
+	IdleTimeout             time.Duration
+	DialTimeout             time.Duration
+	KeepAlive               time.Duration
+}
+
+// DefaultPoolOptions 返回默认的连接池配置
+func DefaultPoolOptions() PoolOptions {
+	return PoolOptions{
+		MaxIdleConnsPerUpstream: 64,
+		IdleTimeout:             time.Second * 90,
+		DialTimeout:             time.Second * 5,
+		KeepAlive:               time.Second * 30,
+	}
+}
+
+// NewPoolOptions 从配置中读取连接池配置
+func NewPoolOptions(config *flux.Configuration) PoolOptions {
+	defaults := DefaultPoolOptions()
+	config.SetDefaults(map[string]interface{}{
+		PoolConfigKeyMaxConnsPerUpstream:     defaults.MaxConnsPerUpstream,
+		PoolConfigKeyMaxIdleConnsPerUpstream: defaults.MaxIdleConnsPerUpstream,
+		PoolConfigKeyIdleTimeout:             defaults.IdleTimeout,
+		PoolConfigKeyDialTimeout:             defaults.DialTimeout,
+		PoolConfigKeyKeepAlive:               defaults.KeepAlive,
+	})
+	return PoolOptions{
+		MaxConnsPerUpstream:     config.GetInt(PoolConfigKeyMaxConnsPerUpstream),
+		MaxIdleConnsPerUpstream: config.GetInt(PoolConfigKeyMaxIdleConnsPerUpstream),
+		IdleTimeout:             config.GetDuration(PoolConfigKeyIdleTimeout),
+		DialTimeout:             config.GetDuration(PoolConfigKeyDialTimeout),
+		KeepAlive:               config.GetDuration(PoolConfigKeyKeepAlive),
+	}
+}
+
+// ClientPool 后端共享的上游连接池：统一管理到上游的连接建立、每个上游的连接数限制、空闲连接回收和连接Metrics。
+// 同名的连接池在进程内共享，后端通过 HttpClient/Http2Client 获取复用连接的客户端，避免高QPS时耗尽Socket。
+type ClientPool struct {
+	name    string
+	options PoolOptions
+	dialer  *net.Dialer
+	mu      sync.Mutex
+	conns   map[*pooledConn]struct{}
+	counts  map[string]int
+	clients []*http.Client
+	stop    chan struct{}
+	once    sync.Once
+	reaper  sync.Once
+}
+
+// NewClientPool 创建并注册指定名称的连接池；已存在同名连接池时，关闭并替换旧连接池
+func NewClientPool(name string, options PoolOptions) *ClientPool {
+	pool := &ClientPool{
+		name:    name,
+		options: options,
+		dialer:  &net.Dialer{Timeout: options.DialTimeout, KeepAlive: options.KeepAlive},
+		conns:   make(map[*pooledConn]struct{}, 16),
+		counts:  make(map[string]int, 8),
+		stop:    make(chan struct{}),
+	}
+	if old, loaded := pools.Load(name); loaded {
+		_ = old.(*ClientPool).Close()
+	}
+	pools.Store(name, pool)
+	logger.Infow("Backend client pool created", "pool", name, "max-conns-per-upstream", options.MaxConnsPerUpstream,
+		"max-idle-conns-per-upstream", options.MaxIdleConnsPerUpstream, "idle-timeout", options.IdleTimeout)
+	return pool
+}
+
+// LoadClientPool 返回指定名称的连接池
+func LoadClientPool(name string) (*ClientPool, bool) {
+	if v, ok := pools.Load(name); ok {
+		return v.(*ClientPool), true
+	}
+	return nil, false
+}
+
+func (p *ClientPool) Name() string {
+	return p.name
+}
+
+// HttpClient 返回复用连接池的HTTP/1.x客户端；连接数达到上限时，请求等待可用连接
+func (p *ClientPool) HttpClient(timeout time.Duration) *http.Client {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return p.dialContext(ctx, network, addr, nil)
+		},
+		DialTLSContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := p.dialContext(ctx, network, addr, nil)
+			if nil != err {
+				return nil, err
+			}
+			return UpstreamTLSClient(ctx, conn, addr, nil)
+		},
+		MaxConnsPerHost:       p.options.MaxConnsPerUpstream,
+		MaxIdleConns:          0,
+		MaxIdleConnsPerHost:   p.options.MaxIdleConnsPerUpstream,
+		IdleConnTimeout:       p.options.IdleTimeout,
+		TLSHandshakeTimeout:   p.options.DialTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+	return p.track(&http.Client{Transport: transport, Timeout: timeout})
+}
+
+// Http2Client 返回复用连接池的HTTP/2客户端；plaintext 为true时使用h2c明文连接。
+// HTTP/2连接多路复用，连接数达到上限时新建连接返回 ErrPoolExhausted；
+// HTTP/2客户端不支持空闲超时，客户端的全部连接超过 idle-timeout 未读写时，由连接池关闭其中没有进行中请求的连接。
+func (p *ClientPool) Http2Client(plaintext bool) *http.Client {
+	if p.options.IdleTimeout > 0 {
+		p.reaper.Do(func() {
+			go p.reapLoop()
+		})
+	}
+	transport := &http2.Transport{}
+	if plaintext {
+		transport.AllowHTTP = true
+		transport.DialTLS = func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return p.dialContext(context.Background(), network, addr, transport)
+		}
+	} else {
+		transport.DialTLS = func(network, addr string, config *tls.Config) (net.Conn, error) {
+			conn, err := p.dialContext(context.Background(), network, addr, transport)
+			if nil != err {
+				return nil, err
+			}
+			return UpstreamTLSClient(context.Background(), conn, addr, config)
+		}
+	}
+	return p.track(&http.Client{Transport: transport})
+}
+
+// Stats 返回每个上游的已打开连接数
+func (p *ClientPool) Stats() map[string]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make(map[string]int, len(p.counts))
+	for upstream, n := range p.counts {
+		out[upstream] = n
+	}
+	return out
+}
+
+// Close 停止空闲连接回收，并关闭客户端的空闲连接；进行中的请求不受影响
+func (p *ClientPool) Close() error {
+	p.once.Do(func() {
+		close(p.stop)
+	})
+	p.mu.Lock()
+	clients := p.clients
+	p.mu.Unlock()
+	for _, client := range clients {
+		client.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (p *ClientPool) track(client *http.Client) *http.Client {
+	p.mu.Lock()
+	p.clients = append(p.clients, client)
+	p.mu.Unlock()
+	return client
+}
+
+func (p *ClientPool) dialContext(ctx context.Context, network, addr string, owner *http2.Transport) (net.Conn, error) {
+	p.mu.Lock()
+	if max := p.options.MaxConnsPerUpstream; max > 0 && p.counts[addr] >= max {
+		p.mu.Unlock()
+		poolDials.WithLabelValues(p.name, addr, "exhausted").Inc()
+		return nil, ErrPoolExhausted
+	}
+	// 预占连接数，避免并发建立连接时超过上限
+	p.counts[addr]++
+	p.mu.Unlock()
+	conn, err := p.dialer.DialContext(ctx, network, addr)
+	if nil != err {
+		p.mu.Lock()
+		p.decrement(addr)
+		p.mu.Unlock()
+		poolDials.WithLabelValues(p.name, addr, "error").Inc()
+		return nil, err
+	}
+	poolDials.WithLabelValues(p.name, addr, "ok").Inc()
+	poolOpenConns.WithLabelValues(p.name, addr).Inc()
+	pc := &pooledConn{Conn: conn, pool: p, upstream: addr, owner: owner}
+	pc.touch()
+	p.mu.Lock()
+	p.conns[pc] = struct{}{}
+	p.mu.Unlock()
+	return pc, nil
+}
+
+func (p *ClientPool) release(pc *pooledConn) {
+	p.mu.Lock()
+	if _, ok := p.conns[pc]; ok {
+		delete(p.conns, pc)
+		p.decrement(pc.upstream)
+		poolOpenConns.WithLabelValues(p.name, pc.upstream).Dec()
+	}
+	p.mu.Unlock()
+}
+
+func (p *ClientPool) decrement(addr string) {
+	if n := p.counts[addr] - 1; n > 0 {
+		p.counts[addr] = n
+	} else {
+		delete(p.counts, addr)
+	}
+}
+
+// reapLoop 回收HTTP/2客户端的空闲连接：客户端的全部连接超过 idle-timeout 未读写时，关闭没有进行中请求的连接；
+// HTTP/1.x客户端由 Transport.IdleConnTimeout 回收。
+func (p *ClientPool) reapLoop() {
+	ticker := time.NewTicker(p.options.IdleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case now := <-ticker.C:
+			idles := make(map[*http2.Transport]bool, 2)
+			p.mu.Lock()
+			for pc := range p.conns {
+				if nil == pc.owner {
+					continue
+				}
+				if idle, seen := idles[pc.owner]; !seen || idle {
+					idles[pc.owner] = now.Sub(pc.lastActive()) > p.options.IdleTimeout
+				}
+			}
+			p.mu.Unlock()
+			for owner, idle := range idles {
+				if idle {
+					owner.CloseIdleConnections()
+				}
+			}
+		}
+	}
+}
+
+// pooledConn 记录最后读写时间和所属HTTP/2客户端的连接；关闭时更新连接池的连接数
+type pooledConn struct {
+	net.Conn
+	pool     *ClientPool
+	upstream string
+	owner    *http2.Transport
+	active   int64
+	once     sync.Once
+}
+
+func (c *pooledConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.touch()
+	return n, err
+}
+
+func (c *pooledConn) Write(b []byte) (int, error) {
+	c.touch()
+	return c.Conn.Write(b)
+}
+
+func (c *pooledConn) Close() error {
+	c.once.Do(func() {
+		c.pool.release(c)
+	})
+	return c.Conn.Close()
+}
+
+func (c *pooledConn) touch() {
+	atomic.StoreInt64(&c.active, time.Now().UnixNano())
+}
+
+func (c *pooledConn) lastActive() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&c.active))
+}
@@ -0,0 +1,49 @@
+package backend
+
+import (
+	assert2 "github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClientPool_HttpClient(t *testing.T) {
+	assert := assert2.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	upstream := strings.TrimPrefix(server.URL, "http://")
+	options := DefaultPoolOptions()
+	options.MaxConnsPerUpstream = 2
+	pool := NewClientPool("test-http", options)
+	client := pool.HttpClient(time.Second)
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(server.URL)
+			if assert.NoError(err) {
+				_, _ = ioutil.ReadAll(resp.Body)
+				_ = resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	// 请求排队复用连接，连接数不超过上限
+	assert.Equal(2, pool.Stats()[upstream])
+	// 同名连接池替换旧连接池，旧连接池关闭空闲连接
+	replaced := NewClientPool("test-http", options)
+	loaded, ok := LoadClientPool("test-http")
+	assert.True(ok)
+	assert.Equal(replaced, loaded)
+	assert.Eventually(func() bool {
+		return 0 == len(pool.Stats())
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,157 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrConflictingFraming     = errors.New("request has both content-length and transfer-encoding")
+	ErrInvalidContentLength   = errors.New("request has invalid or conflicting content-length")
+	ErrInvalidTransferEncoder = errors.New("request has unsupported transfer-encoding")
+	ErrInvalidHeaderValue     = errors.New("request header contains control characters")
+)
+
+// 逐跳Header，代理时不转发到上游。Ref: RFC 7230 6.1
+var hopByHopHeaders = []string{
+	flux.HeaderConnection,
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	flux.HeaderUpgrade,
+}
+
+// SanitizeProxyRequestHeader 检查并规范化代理到上游的请求Header，返回新的Header：
+// 1. 拒绝同时存在 Content-Length 和 Transfer-Encoding、多个不一致或非法的 Content-Length、非 chunked 的 Transfer-Encoding；
+// 2. 将过时的折行（obs-fold）替换为单个空格，拒绝包含其它控制字符的Header值；
+// 3. 移除逐跳Header，以及 Connection 中声明的Header。
+func SanitizeProxyRequestHeader(header http.Header) (http.Header, error) {
+	if err := checkMessageFraming(header); nil != err {
+		return nil, err
+	}
+	out := make(http.Header, len(header))
+	for name, values := range header {
+		copied := make([]string, len(values))
+		for i, value := range values {
+			v, err := unfoldHeaderValue(value)
+			if nil != err {
+				return nil, fmt.Errorf("%w: %s", err, name)
+			}
+			copied[i] = v
+		}
+		out[name] = copied
+	}
+	removeHopByHopHeaders(out)
+	return out, nil
+}
+
+// checkMessageFraming 检查请求Body的长度声明，防止代理与上游对请求边界的解析不一致
+func checkMessageFraming(header http.Header) error {
+	lengths := header.Values(flux.HeaderContentLength)
+	encodings := header.Values("Transfer-Encoding")
+	if len(lengths) > 0 && len(encodings) > 0 {
+		return ErrConflictingFraming
+	}
+	first := ""
+	for _, value := range lengths {
+		for _, v := range strings.Split(value, ",") {
+			v = strings.Trim(v, " \t")
+			if !isDigits(v) {
+				return ErrInvalidContentLength
+			}
+			if "" == first {
+				first = v
+			} else if first != v {
+				return ErrInvalidContentLength
+			}
+		}
+	}
+	chunked := 0
+	for _, value := range encodings {
+		for _, v := range strings.Split(value, ",") {
+			if !strings.EqualFold(strings.Trim(v, " \t"), "chunked") {
+				return ErrInvalidTransferEncoder
+			}
+			chunked++
+		}
+	}
+	if chunked > 1 {
+		return ErrInvalidTransferEncoder
+	}
+	return nil
+}
+
+func isDigits(s string) bool {
+	if "" == s {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// unfoldHeaderValue 将过时的折行（CRLF后跟空白字符）替换为单个空格；其它CR、LF和NUL字符视为非法
+func unfoldHeaderValue(value string) (string, error) {
+	if !strings.ContainsAny(value, "\r\n\x00") {
+		return value, nil
+	}
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch c {
+		case '\r', '\n':
+			j := i
+			if '\r' == c && j+1 < len(value) && '\n' == value[j+1] {
+				j++
+			}
+			if j+1 >= len(value) || (' ' != value[j+1] && '\t' != value[j+1]) {
+				return "", ErrInvalidHeaderValue
+			}
+			for j+1 < len(value) && (' ' == value[j+1] || '\t' == value[j+1]) {
+				j++
+			}
+			sb.WriteByte(' ')
+			i = j
+		case 0:
+			return "", ErrInvalidHeaderValue
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), nil
+}
+
+// removeHopByHopHeaders 移除逐跳Header和 Connection 中声明的Header；保留 Te: trailers
+func removeHopByHopHeaders(header http.Header) {
+	for _, value := range header.Values(flux.HeaderConnection) {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); "" != name {
+				header.Del(name)
+			}
+		}
+	}
+	trailers := false
+	for _, value := range header.Values("Te") {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), "trailers") {
+				trailers = true
+			}
+		}
+	}
+	for _, name := range hopByHopHeaders {
+		header.Del(name)
+	}
+	if trailers {
+		header.Set("Te", "trailers")
+	}
+}
@@ -0,0 +1,127 @@
+package backend
+
+import (
+	"errors"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestSanitizeProxyRequestHeader_Framing(t *testing.T) {
+	cases := []struct {
+		name   string
+		header http.Header
+		expect error
+	}{
+		{
+			name:   "content-length only",
+			header: http.Header{"Content-Length": []string{"12"}},
+			expect: nil,
+		},
+		{
+			name:   "chunked only",
+			header: http.Header{"Transfer-Encoding": []string{"chunked"}},
+			expect: nil,
+		},
+		{
+			name:   "CL.TE",
+			header: http.Header{"Content-Length": []string{"6"}, "Transfer-Encoding": []string{"chunked"}},
+			expect: ErrConflictingFraming,
+		},
+		{
+			name:   "duplicate content-length",
+			header: http.Header{"Content-Length": []string{"6", "5"}},
+			expect: ErrInvalidContentLength,
+		},
+		{
+			name:   "duplicate content-length in list",
+			header: http.Header{"Content-Length": []string{"6, 5"}},
+			expect: ErrInvalidContentLength,
+		},
+		{
+			name:   "same content-length repeated",
+			header: http.Header{"Content-Length": []string{"6", "6"}},
+			expect: nil,
+		},
+		{
+			name:   "signed content-length",
+			header: http.Header{"Content-Length": []string{"+6"}},
+			expect: ErrInvalidContentLength,
+		},
+		{
+			name:   "empty content-length",
+			header: http.Header{"Content-Length": []string{""}},
+			expect: ErrInvalidContentLength,
+		},
+		{
+			name:   "obfuscated transfer-encoding",
+			header: http.Header{"Transfer-Encoding": []string{"xchunked"}},
+			expect: ErrInvalidTransferEncoder,
+		},
+		{
+			name:   "TE.TE double chunked",
+			header: http.Header{"Transfer-Encoding": []string{"chunked", "chunked"}},
+			expect: ErrInvalidTransferEncoder,
+		},
+		{
+			name:   "chunked with identity",
+			header: http.Header{"Transfer-Encoding": []string{"chunked, identity"}},
+			expect: ErrInvalidTransferEncoder,
+		},
+		{
+			name:   "transfer-encoding with vertical tab",
+			header: http.Header{"Transfer-Encoding": []string{"\vchunked"}},
+			expect: ErrInvalidTransferEncoder,
+		},
+		{
+			name:   "header injection",
+			header: http.Header{"X-Foo": []string{"bar\r\nContent-Length: 0"}},
+			expect: ErrInvalidHeaderValue,
+		},
+		{
+			name:   "bare line feed",
+			header: http.Header{"X-Foo": []string{"bar\nbaz"}},
+			expect: ErrInvalidHeaderValue,
+		},
+		{
+			name:   "nul character",
+			header: http.Header{"X-Foo": []string{"bar\x00"}},
+			expect: ErrInvalidHeaderValue,
+		},
+	}
+	assert := assert2.New(t)
+	for _, c := range cases {
+		_, err := SanitizeProxyRequestHeader(c.header)
+		if nil == c.expect {
+			assert.NoError(err, c.name)
+		} else {
+			assert.True(errors.Is(err, c.expect), c.name)
+		}
+	}
+}
+
+func TestSanitizeProxyRequestHeader_Normalize(t *testing.T) {
+	header := http.Header{
+		"Connection":        []string{"keep-alive, X-Secret"},
+		"Keep-Alive":        []string{"timeout=5"},
+		"X-Secret":          []string{"s"},
+		"Proxy-Connection":  []string{"keep-alive"},
+		"Upgrade":           []string{"h2c"},
+		"Te":                []string{"trailers, deflate"},
+		"Transfer-Encoding": []string{"chunked"},
+		"X-Folded":          []string{"first\r\n \t second", "a\n\tb"},
+		"Accept":            []string{"application/json"},
+	}
+	assert := assert2.New(t)
+	out, err := SanitizeProxyRequestHeader(header)
+	assert.NoError(err)
+	for _, name := range []string{"Connection", "Keep-Alive", "X-Secret", "Proxy-Connection", "Upgrade", "Transfer-Encoding"} {
+		assert.Empty(out.Values(name), "header: %s", name)
+	}
+	assert.Equal([]string{"trailers"}, out.Values("Te"))
+	assert.Equal([]string{"first second", "a b"}, out.Values("X-Folded"))
+	assert.Equal("application/json", out.Get("Accept"))
+	// 原Header不被修改
+	assert.Equal("s", header.Get("X-Secret"))
+	assert.Equal("first\r\n \t second", header.Get("X-Folded"))
+}
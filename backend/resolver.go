@@ -1,10 +1,19 @@
 package backend
 
 import (
+	"errors"
 	"fmt"
 	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
 	"github.com/bytepowered/flux/logger"
 	"github.com/bytepowered/flux/pkg"
+	"strings"
+)
+
+const (
+	// 参数查找和解析失败的错误码
+	ArgumentErrorCodeLookup  = "BACKEND:LOOKUP"
+	ArgumentErrorCodeResolve = "BACKEND:RESOLVE"
 )
 
 func LookupResolveWith(arg flux.Argument, lookup flux.ArgumentValueLookupFunc, resolver flux.ArgumentValueResolveFunc, ctx flux.Context) (interface{}, error) {
@@ -16,7 +25,7 @@ func LookupResolveWith(arg flux.Argument, lookup flux.ArgumentValueLookupFunc, r
 		if mtv, err := lookup(arg.HttpScope, arg.HttpName, ctx); nil != err {
 			logger.TraceContext(ctx).Warnw("Failed to lookup argument",
 				"http.scope", arg.HttpScope, "http.name", arg.HttpName, "arg.name", arg.Name, "error", err)
-			return nil, fmt.Errorf("BACKEND:LOOKUP:%w", err)
+			return nil, fmt.Errorf("%s:%w", ArgumentErrorCodeLookup, err)
 		} else {
 			mtValue = mtv
 		}
@@ -26,7 +35,69 @@ func LookupResolveWith(arg flux.Argument, lookup flux.ArgumentValueLookupFunc, r
 	if nil != err {
 		logger.TraceContext(ctx).Warnw("Failed to resolve argument",
 			"mime-value", mtValue, "arg.class", arg.Class, "error", err)
-		return nil, fmt.Errorf("BACKEND:RESOLVE:%w", err)
+		return nil, fmt.Errorf("%s:%w", ArgumentErrorCodeResolve, err)
 	}
 	return value, err
 }
+
+// LookupResolveValues 按参数名解析参数值：Primitive参数解析为参数值，Complex参数按字段解析为嵌套Map。
+// 解析全部参数后返回错误；多个参数解析失败时返回 *flux.MultiError，每个错误的字段名为参数路径（例如 user.name）
+func LookupResolveValues(args []flux.Argument, lookup flux.ArgumentValueLookupFunc, resolver flux.ArgumentValueResolveFunc, ctx flux.Context) (map[string]interface{}, error) {
+	errs := new(flux.MultiError)
+	m := lookupResolveValues("", args, lookup, resolver, ctx, errs)
+	if err := errs.ErrorOrNil(); nil != err {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ResolveArgumentValues 使用请求所属引擎注册的参数查找和解析函数，按参数名解析参数值
+func ResolveArgumentValues(args []flux.Argument, ctx flux.Context) (map[string]interface{}, error) {
+	registry := ext.RegistryOf(ctx.Context())
+	return LookupResolveValues(args, registry.LoadArgumentValueLookupFunc(), registry.LoadArgumentValueResolveFunc(), ctx)
+}
+
+func lookupResolveValues(prefix string, args []flux.Argument, lookup flux.ArgumentValueLookupFunc, resolver flux.ArgumentValueResolveFunc,
+	ctx flux.Context, errs *flux.MultiError) map[string]interface{} {
+	m := make(map[string]interface{}, len(args))
+	for _, arg := range args {
+		if flux.ArgumentTypePrimitive == arg.Type {
+			if value, err := LookupResolveWith(arg, lookup, resolver, ctx); nil != err {
+				errs.Append(flux.NewFieldError(prefix+arg.Name, argumentErrorCodeOf(err), err))
+			} else {
+				m[arg.Name] = value
+			}
+		} else if flux.ArgumentTypeComplex == arg.Type {
+			m[arg.Name] = lookupResolveValues(prefix+arg.Name+".", arg.Fields, lookup, resolver, ctx, errs)
+		} else {
+			logger.TraceContext(ctx).Warnw("Unsupported parameter type", "argument", arg.Name, "argument-type", arg.Type)
+		}
+	}
+	return m
+}
+
+// requestErrorOf 返回参数解析错误中的请求错误（4xx），例如枚举参数值无效：传输协议将参数组装失败统一响应为500，
+// 请求错误应按其状态码响应请求端；内部错误保留原错误，用于输出全部参数错误
+func requestErrorOf(serr *flux.ServeError) *flux.ServeError {
+	if nil == serr || nil == serr.Internal {
+		return serr
+	}
+	var inner *flux.ServeError
+	if !errors.As(serr.Internal, &inner) || inner.StatusCode < flux.StatusBadRequest || inner.StatusCode >= flux.StatusServerError {
+		return serr
+	}
+	out := *inner
+	out.Internal = serr.Internal
+	return &out
+}
+
+// argumentErrorCodeOf 返回参数解析错误的错误码
+func argumentErrorCodeOf(err error) string {
+	text := err.Error()
+	for _, code := range []string{ArgumentErrorCodeLookup, ArgumentErrorCodeResolve} {
+		if strings.HasPrefix(text, code) {
+			return code
+		}
+	}
+	return ""
+}
@@ -1,8 +1,10 @@
 package backend
 
 import (
+	"errors"
 	"github.com/bytepowered/flux"
 	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
 	"github.com/bytepowered/flux/support"
 	assert2 "github.com/stretchr/testify/assert"
 	"testing"
@@ -34,3 +36,74 @@ func TestLookupResolveWith(t *testing.T) {
 		assert.Equal(c.expect, value)
 	}
 }
+
+func TestLookupResolveValues(t *testing.T) {
+	context := support.NewValuesContext(map[string]interface{}{
+		"userId":  123,
+		"name":    "yongjia",
+		"enabled": true,
+	})
+	user := ext.NewComplexArgument("com.foo.User", "user")
+	user.Fields = []flux.Argument{ext.NewStringArgument("name"), ext.NewBooleanArgument("enabled")}
+	values, err := LookupResolveValues(
+		[]flux.Argument{ext.NewIntegerArgument("userId"), user},
+		support.DefaultArgumentValueLookupFunc, support.DefaultArgumentValueResolveFunc,
+		context)
+	assert := assert2.New(t)
+	assert.NoError(err, "must no error")
+	assert.Equal(map[string]interface{}{
+		"userId": 123,
+		"user":   map[string]interface{}{"name": "yongjia", "enabled": true},
+	}, values)
+}
+
+func TestLookupResolveValues_MultiError(t *testing.T) {
+	context := support.NewValuesContext(map[string]interface{}{
+		"amount": "abc",
+		"count":  "1.5",
+		"name":   "yongjia",
+	}).(*support.ValuesContext)
+	context.SetContextLogger(logger.SimpleLogger())
+	user := ext.NewComplexArgument("com.foo.User", "user")
+	user.Fields = []flux.Argument{ext.NewStringArgument("name"), ext.NewPrimitiveArgument(flux.JavaMathBigIntegerClassName, "count")}
+	_, err := LookupResolveValues(
+		[]flux.Argument{ext.NewPrimitiveArgument(flux.JavaMathBigDecimalClassName, "amount"), user},
+		support.DefaultArgumentValueLookupFunc, support.DefaultArgumentValueResolveFunc,
+		context)
+	assert := assert2.New(t)
+	multi, ok := err.(*flux.MultiError)
+	if assert.True(ok, "must be multi error") {
+		items := multi.Items()
+		assert.Equal(2, len(items))
+		assert.Equal("amount", items[0].Field)
+		assert.Equal(ArgumentErrorCodeResolve, items[0].Code)
+		assert.Equal("user.count", items[1].Field)
+		assert.Equal(ArgumentErrorCodeResolve, items[1].Code)
+	}
+}
+
+func TestRequestErrorOf_EnumInvalid(t *testing.T) {
+	context := support.NewValuesContext(map[string]interface{}{
+		"color": "PURPLE",
+	}).(*support.ValuesContext)
+	context.SetContextLogger(logger.SimpleLogger())
+	color := ext.NewPrimitiveArgument("enum", "color")
+	color.Generic = []string{"RED", "GREEN"}
+	_, err := LookupResolveValues([]flux.Argument{color},
+		support.DefaultArgumentValueLookupFunc, support.DefaultArgumentValueResolveFunc,
+		context)
+	assert := assert2.New(t)
+	assert.Error(err)
+	serr := requestErrorOf(&flux.ServeError{
+		StatusCode: flux.StatusServerError,
+		ErrorCode:  flux.ErrorCodeGatewayInternal,
+		Internal:   err,
+	})
+	assert.Equal(flux.StatusBadRequest, serr.StatusCode)
+	assert.Equal(flux.ErrorCodeRequestInvalid, serr.ErrorCode)
+	assert.Contains(serr.Message, "PURPLE")
+	assert.Contains(serr.Message, "RED, GREEN")
+	assert.Equal(err, serr.Internal)
+	internal := &flux.ServeError{StatusCode: flux.StatusServerError, Internal: errors.New("assemble")}
+	assert.Equal(internal, requestErrorOf(internal))
+}
@@ -0,0 +1,140 @@
+package backend
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"github.com/spf13/cast"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// Endpoint扩展属性：最大调用次数（包含首次调用）；小于等于1时不重试
+	EndpointExtKeyRetryMaxAttempts = "retry-max-attempts"
+	// Endpoint扩展属性：首次重试的退避时间，之后每次翻倍；默认100ms
+	EndpointExtKeyRetryBackoff = "retry-backoff"
+	// Endpoint扩展属性：退避时间上限；默认2s
+	EndpointExtKeyRetryMaxBackoff = "retry-max-backoff"
+	// Endpoint扩展属性：可重试的状态码列表，匹配网关错误和上游Http响应的状态码；默认为 502,503,504
+	EndpointExtKeyRetryStatusCodes = "retry-status-codes"
+	// Endpoint扩展属性：可重试的网关错误码列表，例如 GATEWAY:BACKEND
+	EndpointExtKeyRetryErrorCodes = "retry-error-codes"
+	// Endpoint扩展属性：是否允许重试非幂等请求（POST/PATCH等）；默认只重试幂等请求
+	EndpointExtKeyRetryNonIdempotent = "retry-non-idempotent"
+)
+
+const (
+	defaultRetryBackoff    = 100 * time.Millisecond
+	defaultRetryMaxBackoff = 2 * time.Second
+)
+
+var (
+	defaultRetryStatusCodes = []string{"502", "503", "504"}
+	idempotentMethods       = map[string]bool{
+		http.MethodGet: true, http.MethodHead: true, http.MethodOptions: true,
+		http.MethodPut: true, http.MethodDelete: true, http.MethodTrace: true,
+	}
+)
+
+// retryPolicy Endpoint的重试策略：按指数退避重试可重试的错误和上游响应
+type retryPolicy struct {
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	statusCodes map[int]bool
+	errorCodes  map[string]bool
+}
+
+// retryPolicyOf 返回请求的重试策略；未配置重试或非幂等请求未允许重试时，返回false
+func retryPolicyOf(ctx flux.Context) (retryPolicy, bool) {
+	endpoint := ctx.Endpoint()
+	attempts := endpoint.ExtInt(EndpointExtKeyRetryMaxAttempts)
+	if attempts <= 1 {
+		return retryPolicy{}, false
+	}
+	if !idempotentMethods[ctx.Method()] && !endpoint.ExtBool(EndpointExtKeyRetryNonIdempotent) {
+		return retryPolicy{}, false
+	}
+	policy := retryPolicy{
+		maxAttempts: attempts,
+		backoff:     defaultRetryBackoff,
+		maxBackoff:  defaultRetryMaxBackoff,
+		statusCodes: make(map[int]bool, 4),
+		errorCodes:  make(map[string]bool, 4),
+	}
+	if v, ok := endpoint.Ext(EndpointExtKeyRetryBackoff); ok {
+		policy.backoff = cast.ToDuration(v)
+	}
+	if v, ok := endpoint.Ext(EndpointExtKeyRetryMaxBackoff); ok {
+		policy.maxBackoff = cast.ToDuration(v)
+	}
+	codes := headerListOf(endpoint, EndpointExtKeyRetryStatusCodes)
+	if nil == codes {
+		codes = defaultRetryStatusCodes
+	}
+	for _, code := range codes {
+		if status := cast.ToInt(strings.TrimSpace(code)); status > 0 {
+			policy.statusCodes[status] = true
+		}
+	}
+	for _, code := range headerListOf(endpoint, EndpointExtKeyRetryErrorCodes) {
+		if code = strings.TrimSpace(code); "" != code {
+			policy.errorCodes[code] = true
+		}
+	}
+	return policy, true
+}
+
+// retryable 返回调用结果是否可重试：网关错误匹配状态码或错误码，或标记为可重试的非请求端错误；上游Http响应匹配状态码；
+// 熔断错误不重试
+func (p retryPolicy) retryable(resp interface{}, err *flux.ServeError) bool {
+	if nil != err {
+		if flux.ErrorCodeGatewayCircuited == err.GetErrorCode() {
+			return false
+		}
+		if err.Retryable && flux.ErrorCategoryClient != err.GetCategory() {
+			return true
+		}
+		return p.statusCodes[err.StatusCode] || p.errorCodes[err.GetErrorCode()]
+	}
+	if r, ok := resp.(*http.Response); ok {
+		return p.statusCodes[r.StatusCode]
+	}
+	return false
+}
+
+// backoffOf 返回第n次重试前的退避时间：指数增长，不超过上限；在退避时间的后半段随机取值，避免重试请求同时到达上游
+func (p retryPolicy) backoffOf(n int) time.Duration {
+	d := p.backoff
+	for i := 1; i < n && d < p.maxBackoff; i++ {
+		d *= 2
+	}
+	if p.maxBackoff > 0 && d > p.maxBackoff {
+		d = p.maxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// invokeRetryable 按重试策略调用；重试前释放上一次调用的响应，请求被取消时停止重试并返回最后一次调用的结果
+func invokeRetryable(ctx flux.Context, policy retryPolicy, invoke func() (interface{}, *flux.ServeError)) (interface{}, *flux.ServeError) {
+	resp, err := invoke()
+	for n := 1; n < policy.maxAttempts && policy.retryable(resp, err); n++ {
+		backoff := policy.backoffOf(n)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Context().Done():
+			timer.Stop()
+			return resp, err
+		case <-timer.C:
+		}
+		logger.TraceContext(ctx).Infow("Backend retry, invoke again", "attempt", n+1, "backoff", backoff, "error", err)
+		closeResponse(resp)
+		resp, err = invoke()
+	}
+	return resp, err
+}
@@ -0,0 +1,97 @@
+package backend
+
+import (
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyOf(t *testing.T) {
+	assert := assert2.New(t)
+	endpoint := flux.Endpoint{}
+	endpoint.Extensions = map[string]interface{}{
+		EndpointExtKeyRetryMaxAttempts: 3,
+		EndpointExtKeyRetryErrorCodes:  "GATEWAY:BACKEND, GATEWAY:CIRCUITED",
+	}
+	policy, ok := retryPolicyOf(&hedgeTestContext{method: http.MethodGet, endpoint: endpoint})
+	assert.True(ok)
+	assert.Equal(3, policy.maxAttempts)
+	assert.Equal(map[int]bool{502: true, 503: true, 504: true}, policy.statusCodes)
+	assert.Equal(map[string]bool{"GATEWAY:BACKEND": true, "GATEWAY:CIRCUITED": true}, policy.errorCodes)
+	// 非幂等请求默认不重试
+	_, ok = retryPolicyOf(&hedgeTestContext{method: http.MethodPost, endpoint: endpoint})
+	assert.False(ok)
+	endpoint.Extensions[EndpointExtKeyRetryNonIdempotent] = true
+	_, ok = retryPolicyOf(&hedgeTestContext{method: http.MethodPost, endpoint: endpoint})
+	assert.True(ok)
+	_, ok = retryPolicyOf(&hedgeTestContext{method: http.MethodGet})
+	assert.False(ok)
+}
+
+func TestRetryPolicy_BackoffOf(t *testing.T) {
+	assert := assert2.New(t)
+	policy := retryPolicy{backoff: 100 * time.Millisecond, maxBackoff: 300 * time.Millisecond}
+	cases := []struct {
+		n   int
+		max time.Duration
+	}{
+		{n: 1, max: 100 * time.Millisecond},
+		{n: 2, max: 200 * time.Millisecond},
+		{n: 3, max: 300 * time.Millisecond},
+		{n: 10, max: 300 * time.Millisecond},
+	}
+	for _, tc := range cases {
+		d := policy.backoffOf(tc.n)
+		assert.True(d >= tc.max/2 && d <= tc.max, "n: %d, backoff: %s", tc.n, d)
+	}
+}
+
+func TestInvokeRetryable(t *testing.T) {
+	unavailable := &flux.ServeError{StatusCode: http.StatusServiceUnavailable}
+	badRequest := &flux.ServeError{StatusCode: http.StatusBadRequest}
+	transient := &flux.ServeError{StatusCode: http.StatusInternalServerError, ErrorCode: flux.ErrorCodeGatewayBackend, Retryable: true}
+	retryableClient := &flux.ServeError{StatusCode: http.StatusBadRequest, Retryable: true}
+	upstream := func(status int) interface{} {
+		return &http.Response{StatusCode: status, Body: ioutil.NopCloser(strings.NewReader(""))}
+	}
+	cases := []struct {
+		results []interface{}
+		calls   int
+		status  int
+	}{
+		// 可重试错误，重试后成功
+		{results: []interface{}{unavailable, upstream(http.StatusOK)}, calls: 2, status: http.StatusOK},
+		// 上游可重试状态码，达到最大次数后返回最后一次结果
+		{results: []interface{}{upstream(502), upstream(503), upstream(504)}, calls: 3, status: 504},
+		// 不可重试错误
+		{results: []interface{}{badRequest}, calls: 1, status: http.StatusBadRequest},
+		// 标记为可重试的上游错误，不匹配状态码也重试
+		{results: []interface{}{transient, upstream(http.StatusOK)}, calls: 2, status: http.StatusOK},
+		// 标记为可重试的请求端错误不重试
+		{results: []interface{}{retryableClient}, calls: 1, status: http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		assert := assert2.New(t)
+		policy := retryPolicy{maxAttempts: 3, backoff: time.Millisecond, maxBackoff: time.Millisecond,
+			statusCodes: map[int]bool{502: true, 503: true, 504: true}}
+		calls := 0
+		resp, err := invokeRetryable(&hedgeTestContext{method: http.MethodGet}, policy, func() (interface{}, *flux.ServeError) {
+			result := tc.results[calls]
+			calls++
+			if serr, ok := result.(*flux.ServeError); ok {
+				return nil, serr
+			}
+			return result, nil
+		})
+		assert.Equal(tc.calls, calls)
+		if nil != err {
+			assert.Equal(tc.status, err.StatusCode)
+		} else {
+			assert.Equal(tc.status, resp.(*http.Response).StatusCode)
+		}
+	}
+}
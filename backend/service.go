@@ -4,6 +4,15 @@ import (
 	"fmt"
 	"github.com/bytepowered/flux"
 	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"io"
+	"net/http"
+)
+
+const (
+	// Endpoint扩展属性：以流的方式向客户端转发Backend返回的io.Reader响应体，不在网关缓存完整响应；
+	// 开启后，Filter无法在Backend调用后修改响应体
+	EndpointExtKeyStreamResponse = "stream-response"
 )
 
 var (
@@ -16,34 +25,129 @@ var (
 
 func DoExchange(ctx flux.Context, exchange flux.BackendTransport) *flux.ServeError {
 	endpoint := ctx.Endpoint()
-	resp, err := exchange.Invoke(endpoint.Service, ctx)
+	// 流式转发的响应不缓存
+	cache, cacheKey, cacheable := responseCacheOf(ctx)
+	cacheable = cacheable && !endpoint.ExtBool(EndpointExtKeyStreamResponse)
+	if cacheable {
+		if code, headers, body, ok := cache.Load(cacheKey); ok {
+			logger.TraceContext(ctx).Debugw("Backend response cache hit", "key", cacheKey)
+			ctx.Response().SetStatusCode(code)
+			ctx.Response().SetHeaders(FilterResponseHeaders(endpoint, headers))
+			ctx.Response().SetBody(body)
+			return nil
+		}
+	}
+	var result exchangeResult
+	if key, ok := coalesceKeyOf(ctx, cacheKey); ok {
+		result = coalesceCalls.Do(ctx, key, func() exchangeResult {
+			return exchangeDecode(ctx, exchange)
+		})
+	} else {
+		result = exchangeDecode(ctx, exchange)
+	}
+	if nil != result.err {
+		// stale-while-error：上游调用失败时返回最近一次成功的缓存响应
+		if cacheable && cache.maxStale > 0 && result.err.StatusCode >= flux.StatusServerError && serveStale(ctx, cache, cacheKey) {
+			logger.TraceContext(ctx).Warnw("Backend upstream failed, serve stale response", "key", cacheKey, "error", result.err)
+			return nil
+		}
+		return result.err
+	}
+	code, headers, body := result.code, result.headers, result.body
+	// 迭代器和大列表响应体按流式JSON数组输出，不缓存
+	reader, streamJSON := jsonArrayStreamOf(endpoint, body)
+	if streamJSON {
+		body = reader
+	} else if cacheable {
+		body = cache.Store(cacheKey, code, headers, body)
+	}
+	ctx.Response().SetStatusCode(code)
+	ctx.Response().SetHeaders(FilterResponseHeaders(endpoint, headers))
+	if streamJSON {
+		ctx.Response().SetHeader(flux.HeaderContentType, flux.MIMEApplicationJSONCharsetUTF8)
+	}
+	if reader, ok := body.(io.Reader); ok && (streamJSON || endpoint.ExtBool(EndpointExtKeyStreamResponse)) {
+		if sc, ok := ctx.(flux.StreamingContext); ok {
+			return streamResponse(sc, code, reader)
+		}
+	}
+	ctx.Response().SetBody(body)
+	return nil
+}
+
+// exchangeDecode 按Endpoint的流量镜像、对冲、超时、熔断和重试策略调用后端服务，并解码响应结果
+func exchangeDecode(ctx flux.Context, exchange flux.BackendTransport) exchangeResult {
+	endpoint := ctx.Endpoint()
+	if shadow, ok := shadowOf(ctx); ok {
+		mirror(ctx, shadow)
+	}
+	call := func(ctx flux.Context) (interface{}, *flux.ServeError) {
+		if delay := hedgeDelayOf(ctx); delay > 0 {
+			return invokeHedged(exchange, endpoint.Service, ctx, delay)
+		}
+		// 每次调用（包括重试）重新选择上游集群地址
+		return invokeUpstream(exchange, endpoint.Service, ctx)
+	}
+	timed := func() (interface{}, *flux.ServeError) {
+		if timeout := invokeTimeoutOf(endpoint.Service); timeout > 0 {
+			return invokeWithTimeout(ctx, timeout, call)
+		}
+		return call(ctx)
+	}
+	invoke := timed
+	if breaker, ok := breakerOf(endpoint.Service); ok {
+		invoke = func() (interface{}, *flux.ServeError) {
+			return invokeWithBreaker(breaker, timed)
+		}
+	}
+	var resp interface{}
+	var err *flux.ServeError
+	if policy, ok := retryPolicyOf(ctx); ok {
+		resp, err = invokeRetryable(ctx, policy, invoke)
+	} else {
+		resp, err = invoke()
+	}
 	if err != nil {
-		return err
+		return exchangeResult{err: err}
 	}
-	// decode responseWriter
-	decoder, ok := ext.LoadBackendTransportDecodeFunc(endpoint.Service.AttrRpcProto())
+	code, headers, body, err := DoDecode(endpoint.Service, ctx, resp)
+	return exchangeResult{code: code, headers: headers, body: body, err: err}
+}
+
+// DoDecode 按服务协议和响应Content-Type选择解码函数，解析后端服务的响应结果
+func DoDecode(service flux.BackendService, ctx flux.Context, resp interface{}) (int, http.Header, interface{}, *flux.ServeError) {
+	decoder, ok := ext.RegistryOf(ctx.Context()).LoadBackendTransportDecodeFuncOf(service.AttrRpcProto(), responseContentType(resp))
 	if !ok {
-		return ErrBackendTransportDecodeFuncNotFound
+		return 0, nil, nil, ErrBackendTransportDecodeFuncNotFound
 	}
-	if code, headers, body, err := decoder(ctx, resp); nil == err {
-		ctx.Response().SetStatusCode(code)
-		ctx.Response().SetHeaders(headers)
-		ctx.Response().SetBody(body)
-		return nil
-	} else {
-		return &flux.ServeError{
-			StatusCode: flux.StatusServerError,
-			ErrorCode:  flux.ErrorCodeGatewayInternal,
-			Message:    flux.ErrorMessageBackendDecodeResponse,
-			Internal:   err,
-		}
+	code, headers, body, err := decoder(ctx, resp)
+	if nil == err {
+		return code, headers, body, nil
+	}
+	if serr, ok := err.(*flux.ServeError); ok {
+		// Decoder返回的网关错误直接响应
+		return 0, nil, nil, serr
 	}
+	return 0, nil, nil, &flux.ServeError{
+		StatusCode: flux.StatusServerError,
+		ErrorCode:  flux.ErrorCodeGatewayInternal,
+		Message:    flux.ErrorMessageBackendDecodeResponse,
+		Internal:   err,
+	}
+}
+
+// responseContentType 返回上游响应的Content-Type，用于选择解码函数；非HTTP响应返回空
+func responseContentType(resp interface{}) string {
+	if r, ok := resp.(*http.Response); ok && nil != r.Header {
+		return r.Header.Get(flux.HeaderContentType)
+	}
+	return ""
 }
 
 // DoInvoke 执行后端服务，获取响应结果；
 func DoInvoke(service flux.BackendService, ctx flux.Context) (interface{}, *flux.ServeError) {
 	rpcProto := service.AttrRpcProto()
-	backend, ok := ext.LoadBackendTransport(rpcProto)
+	backend, ok := ext.RegistryOf(ctx.Context()).LoadBackendTransport(rpcProto)
 	if !ok {
 		return nil, &flux.ServeError{
 			StatusCode: flux.StatusServerError,
@@ -52,5 +156,19 @@ func DoInvoke(service flux.BackendService, ctx flux.Context) (interface{}, *flux
 			Internal:   fmt.Errorf("unknown protocol:%s", rpcProto),
 		}
 	}
-	return backend.Invoke(service, ctx)
+	return invokeUpstream(backend, service, ctx)
+}
+
+// streamResponse 分块写入流式响应体并关闭；响应头已写出，写入错误只记录日志，不再返回网关错误
+func streamResponse(sc flux.StreamingContext, code int, reader io.Reader) *flux.ServeError {
+	if c, ok := reader.(io.Closer); ok {
+		defer func() {
+			_ = c.Close()
+		}()
+	}
+	contentType := sc.Response().HeaderValues().Get(flux.HeaderContentType)
+	if err := sc.WriteStream(code, contentType, reader); nil != err {
+		logger.TraceContext(sc).Warnw("Backend stream response, write failed", "error", err)
+	}
+	return nil
 }
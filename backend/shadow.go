@@ -0,0 +1,78 @@
+package backend
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"github.com/spf13/cast"
+	"math/rand"
+	"time"
+)
+
+const (
+	// Endpoint扩展属性：影子服务的ServiceId。请求的副本异步发送到影子服务，影子服务的响应被丢弃，不影响原请求；
+	// 用于将生产流量回放到新的服务。影子请求不执行重试、对冲和调用超时。
+	EndpointExtKeyShadowService = "shadow-service"
+	// Endpoint扩展属性：影子请求的采样百分比（0-100），默认100
+	EndpointExtKeyShadowRatio = "shadow-ratio"
+)
+
+const (
+	// 同时执行的影子请求上限，超出时丢弃影子请求
+	maxShadowInflight = 256
+)
+
+var (
+	shadowInflight = make(chan struct{}, maxShadowInflight)
+)
+
+// shadowOf 返回请求的影子服务；未配置、未命中采样或服务不存在时返回false
+func shadowOf(ctx flux.Context) (flux.BackendService, bool) {
+	endpoint := ctx.Endpoint()
+	id := endpoint.ExtString(EndpointExtKeyShadowService)
+	if _, override := upstreamOverrideOf(ctx); "" == id || override {
+		return flux.BackendService{}, false
+	}
+	if v, ok := endpoint.Ext(EndpointExtKeyShadowRatio); ok && rand.Float64()*100 >= cast.ToFloat64(v) {
+		return flux.BackendService{}, false
+	}
+	service, ok := ext.RegistryOf(ctx.Context()).LoadBackendService(id)
+	if !ok {
+		logger.TraceContext(ctx).Warnw("Backend shadow, service not found", "service-id", id)
+	}
+	return service, ok
+}
+
+// mirror 复制请求并异步调用影子服务，丢弃响应；Context不支持复制或影子请求已达上限时不发送
+func mirror(ctx flux.Context, service flux.BackendService) {
+	dc, ok := ctx.(flux.DetachableContext)
+	if !ok {
+		return
+	}
+	select {
+	case shadowInflight <- struct{}{}:
+	default:
+		logger.TraceContext(ctx).Warnw("Backend shadow, inflight overflow, dropped", "service-id", service.ServiceID())
+		return
+	}
+	detached, release, err := dc.Detach()
+	if nil != err {
+		<-shadowInflight
+		logger.TraceContext(ctx).Warnw("Backend shadow, detach context", "service-id", service.ServiceID(), "error", err)
+		return
+	}
+	go func() {
+		defer func() {
+			if r := recover(); nil != r {
+				logger.TraceContext(detached).Errorw("Backend shadow, invoke panic", "service-id", service.ServiceID(), "r", r)
+			}
+			release()
+			<-shadowInflight
+		}()
+		start := time.Now()
+		resp, serr := DoInvoke(service, detached)
+		closeResponse(resp)
+		logger.TraceContext(detached).Debugw("Backend shadow, invoked", "service-id", service.ServiceID(),
+			"elapsed", time.Since(start).String(), "error", serr)
+	}()
+}
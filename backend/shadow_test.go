@@ -0,0 +1,67 @@
+package backend
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type shadowTestContext struct {
+	hedgeTestContext
+	released chan struct{}
+}
+
+func (c *shadowTestContext) Detach() (flux.Context, func(), error) {
+	detached := &hedgeTestContext{method: c.method, endpoint: c.endpoint, values: map[string]interface{}{}}
+	return detached, func() { close(c.released) }, nil
+}
+
+type shadowTestTransport struct {
+	invoked chan flux.BackendService
+}
+
+func (b *shadowTestTransport) Exchange(flux.Context) *flux.ServeError {
+	return nil
+}
+
+func (b *shadowTestTransport) Invoke(service flux.BackendService, _ flux.Context) (interface{}, *flux.ServeError) {
+	b.invoked <- service
+	return nil, nil
+}
+
+func TestMirror(t *testing.T) {
+	assert := assert2.New(t)
+	transport := &shadowTestTransport{invoked: make(chan flux.BackendService, 1)}
+	ext.StoreBackendTransport("SHADOW_TEST", transport)
+	shadow := flux.BackendService{ServiceId: "svc:shadow"}
+	shadow.Attributes = []flux.Attribute{{Tag: flux.ServiceAttrTagRpcProto, Name: "RpcProto", Value: "SHADOW_TEST"}}
+	ext.StoreBackendServiceById("svc:shadow", shadow)
+	defer ext.RemoveBackendService("svc:shadow")
+	endpoint := flux.Endpoint{}
+	endpoint.Extensions = map[string]interface{}{EndpointExtKeyShadowService: "svc:shadow"}
+	ctx := &shadowTestContext{
+		hedgeTestContext: hedgeTestContext{method: http.MethodGet, endpoint: endpoint},
+		released:         make(chan struct{}),
+	}
+	service, ok := shadowOf(ctx)
+	assert.True(ok)
+	mirror(ctx, service)
+	select {
+	case invoked := <-transport.invoked:
+		assert.Equal("svc:shadow", invoked.ServiceId)
+	case <-time.After(time.Second):
+		assert.Fail("shadow service not invoked")
+	}
+	select {
+	case <-ctx.released:
+	case <-time.After(time.Second):
+		assert.Fail("detached context not released")
+	}
+	// 采样为0时不发送
+	endpoint.Extensions[EndpointExtKeyShadowRatio] = 0
+	_, ok = shadowOf(&hedgeTestContext{method: http.MethodGet, endpoint: endpoint})
+	assert.False(ok)
+}
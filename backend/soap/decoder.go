@@ -0,0 +1,106 @@
+package soap
+
+import (
+	"errors"
+	"github.com/bytepowered/flux"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrUnknownSoapBackendResponse = errors.New("BACKEND:UNKNOWN_SOAP_RESPONSE")
+)
+
+// Fault SOAP Fault元素；兼容 SOAP 1.1（faultcode/faultstring/detail）和 SOAP 1.2（Code/Reason/Detail）
+type Fault struct {
+	Code   string
+	String string
+	Detail interface{}
+}
+
+func (f *Fault) Error() string {
+	return "soap fault: " + f.Code + ", message: " + f.String
+}
+
+// NewSoapBackendTransportDecodeFunc 解析SOAP响应：返回 Body 中响应元素转换的JSON数据；
+// Fault 元素转换为网关错误，错误码为Fault代码。
+func NewSoapBackendTransportDecodeFunc() flux.BackendTransportDecodeFunc {
+	return func(ctx flux.Context, value interface{}) (statusCode int, headers http.Header, body interface{}, err error) {
+		resp, ok := value.(*Response)
+		if !ok {
+			return http.StatusInternalServerError, http.Header{}, nil, ErrUnknownSoapBackendResponse
+		}
+		root, err := ParseXML(resp.Body)
+		if nil == err && "Envelope" != root.Name.Local {
+			err = errors.New("soap envelope element not found")
+		}
+		if nil != err {
+			// 非SOAP格式的上游错误响应，原样返回
+			if resp.StatusCode >= http.StatusBadRequest {
+				return resp.StatusCode, http.Header{}, string(resp.Body), nil
+			}
+			return http.StatusInternalServerError, http.Header{}, nil, err
+		}
+		soapBody := root.Child("Body")
+		if nil == soapBody || len(soapBody.Children) == 0 {
+			return http.StatusOK, http.Header{}, nil, nil
+		}
+		element := soapBody.Children[0]
+		if "Fault" != element.Name.Local {
+			return http.StatusOK, http.Header{}, element.Value(), nil
+		}
+		fault := faultOf(element)
+		serr := &flux.ServeError{
+			StatusCode: statusOf(fault.Code),
+			ErrorCode:  fault.Code,
+			Message:    flux.ErrorMessageSoapFault,
+			Internal:   fault,
+		}
+		if nil != fault.Detail {
+			serr.ExtraTrace = map[string]interface{}{"soap.detail": fault.Detail}
+		}
+		if resp.ExposeFault && "" != fault.String {
+			serr.Message = fault.String
+		}
+		return serr.StatusCode, http.Header{}, nil, serr
+	}
+}
+
+func faultOf(element *Node) *Fault {
+	fault := &Fault{}
+	if code := element.Child("faultcode"); nil != code {
+		// SOAP 1.1
+		fault.Code = strings.TrimSpace(code.Text)
+		if str := element.Child("faultstring"); nil != str {
+			fault.String = strings.TrimSpace(str.Text)
+		}
+		if detail := element.Child("detail"); nil != detail {
+			fault.Detail = detail.Value()
+		}
+		return fault
+	}
+	// SOAP 1.2
+	if code := element.Child("Code"); nil != code {
+		if v := code.Child("Value"); nil != v {
+			fault.Code = strings.TrimSpace(v.Text)
+		}
+	}
+	if reason := element.Child("Reason"); nil != reason {
+		if text := reason.Child("Text"); nil != text {
+			fault.String = strings.TrimSpace(text.Text)
+		}
+	}
+	if detail := element.Child("Detail"); nil != detail {
+		fault.Detail = detail.Value()
+	}
+	return fault
+}
+
+// statusOf 将Fault代码转换为HTTP状态码：客户端错误（Client/Sender）为400，其它为500
+func statusOf(code string) int {
+	local := code[strings.LastIndex(code, ":")+1:]
+	if strings.HasPrefix(local, "Client") || strings.HasPrefix(local, "Sender") {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
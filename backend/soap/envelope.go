@@ -0,0 +1,167 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/spf13/cast"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+const (
+	Version11 = "1.1"
+	Version12 = "1.2"
+)
+
+const (
+	NamespaceSoap11 = "http://schemas.xmlsoap.org/soap/envelope/"
+	NamespaceSoap12 = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+var templates sync.Map // 已解析的Envelope模板，Key为模板文本
+
+// BuildEnvelope 根据BackendService生成SOAP Envelope：
+// 设置了 soap-envelope 扩展属性时，使用该模板渲染，模板数据为已转义的参数值，例如：<ns:id>{{.id}}</ns:id>；
+// 否则 Method 为操作元素名，Arguments 映射为同名子元素，Map 参数生成嵌套元素，List 参数生成重复元素。
+func BuildEnvelope(service flux.BackendService, version string, values map[string]interface{}) ([]byte, error) {
+	if text := service.ExtString(ServiceExtKeyEnvelope); "" != text {
+		tpl, err := templateOf(text)
+		if nil != err {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := tpl.Execute(&buf, escapeValue(values)); nil != err {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	if "" == service.Method {
+		return nil, fmt.Errorf("soap operation (method) is required, service: %s", service.ServiceID())
+	}
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>`)
+	buf.WriteString(`<soap:Envelope xmlns:soap="` + namespaceOf(version) + `"><soap:Body>`)
+	if ns := service.ExtString(ServiceExtKeyNamespace); "" != ns {
+		buf.WriteString("<m:" + service.Method + ` xmlns:m="`)
+		_ = xml.EscapeText(&buf, []byte(ns))
+		buf.WriteString(`">`)
+		for _, arg := range service.Arguments {
+			writeElement(&buf, "m:"+arg.Name, values[arg.Name])
+		}
+		buf.WriteString("</m:" + service.Method + ">")
+	} else {
+		buf.WriteString("<" + service.Method + ">")
+		for _, arg := range service.Arguments {
+			writeElement(&buf, arg.Name, values[arg.Name])
+		}
+		buf.WriteString("</" + service.Method + ">")
+	}
+	buf.WriteString(`</soap:Body></soap:Envelope>`)
+	return buf.Bytes(), nil
+}
+
+func templateOf(text string) (*template.Template, error) {
+	if v, ok := templates.Load(text); ok {
+		return v.(*template.Template), nil
+	}
+	tpl, err := template.New("envelope").Option("missingkey=zero").Parse(text)
+	if nil != err {
+		return nil, err
+	}
+	templates.Store(text, tpl)
+	return tpl, nil
+}
+
+func namespaceOf(version string) string {
+	if Version12 == version {
+		return NamespaceSoap12
+	}
+	return NamespaceSoap11
+}
+
+// writeElement 写入参数元素；Map 的Key按名称排序生成子元素，List 的每个元素生成同名元素，nil 生成空元素
+func writeElement(buf *bytes.Buffer, name string, value interface{}) {
+	if nil == value {
+		buf.WriteString("<" + name + "/>")
+		return
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if _, ok := value.([]byte); !ok {
+			for i := 0; i < rv.Len(); i++ {
+				writeElement(buf, name, rv.Index(i).Interface())
+			}
+			return
+		}
+	case reflect.Map:
+		fields := cast.ToStringMap(value)
+		keys := make([]string, 0, len(fields))
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		prefix := ""
+		if i := strings.Index(name, ":"); i > 0 {
+			prefix = name[:i+1]
+		}
+		buf.WriteString("<" + name + ">")
+		for _, k := range keys {
+			writeElement(buf, prefix+k, fields[k])
+		}
+		buf.WriteString("</" + name + ">")
+		return
+	}
+	buf.WriteString("<" + name + ">")
+	_ = xml.EscapeText(buf, []byte(cast.ToString(value)))
+	buf.WriteString("</" + name + ">")
+}
+
+// escapeValue 转义模板数据中的字符串值，防止参数值破坏Envelope结构
+func escapeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return escapeText(v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			out[k] = escapeValue(e)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = escapeValue(e)
+		}
+		return out
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Map:
+		return escapeValue(cast.ToStringMap(value))
+	case reflect.Slice, reflect.Array:
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			out[i] = escapeValue(rv.Index(i).Interface())
+		}
+		return out
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return value
+	default:
+		return escapeText(cast.ToString(value))
+	}
+}
+
+func escapeText(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
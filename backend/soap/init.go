@@ -0,0 +1,11 @@
+package soap
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+)
+
+func init() {
+	ext.StoreBackendTransport(flux.ProtoSoap, NewSoapBackendTransport())
+	ext.StoreBackendTransportDecodeFunc(flux.ProtoSoap, NewSoapBackendTransportDecodeFunc())
+}
@@ -0,0 +1,157 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/backend"
+	"github.com/spf13/cast"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// BackendService扩展属性：SOAP Envelope模板（text/template）；设置后不再根据Method和参数生成
+	ServiceExtKeyEnvelope = "soap-envelope"
+	// BackendService扩展属性：操作元素的命名空间
+	ServiceExtKeyNamespace = "soap-namespace"
+	// BackendService扩展属性：SOAPAction
+	ServiceExtKeyAction = "soap-action"
+	// BackendService扩展属性：SOAP版本，1.1（默认）或 1.2
+	ServiceExtKeyVersion = "soap-version"
+	// BackendService扩展属性：是否向客户端输出上游Fault的faultstring
+	ServiceExtKeyExposeFault = "soap-expose-fault"
+)
+
+var (
+	_ flux.BackendTransport = new(BackendTransportService)
+)
+
+// Response SOAP上游的原始响应，由DecodeFunc解析 Body/Fault
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// 是否输出上游Fault的faultstring
+	ExposeFault bool
+}
+
+// BackendTransportService 转发到SOAP上游（HTTP传输）的BackendService：
+// BackendService.RemoteHost 为上游地址（可包含scheme，默认http），Interface 为接口路径，Method 为操作名称。
+type BackendTransportService struct {
+	httpClient *http.Client
+}
+
+// NewSoapBackendTransport New SOAP backend instance
+func NewSoapBackendTransport() flux.BackendTransport {
+	return &BackendTransportService{
+		httpClient: &http.Client{
+			Timeout: time.Second * 10,
+		},
+	}
+}
+
+// Exchange do exchange with context
+func (b *BackendTransportService) Exchange(ctx flux.Context) *flux.ServeError {
+	return backend.DoExchange(ctx, b)
+}
+
+// Invoke invoke backend service with context
+func (b *BackendTransportService) Invoke(service flux.BackendService, ctx flux.Context) (interface{}, *flux.ServeError) {
+	newRequest, err := b.Assemble(service, ctx)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageSoapAssembleFailed,
+			Internal:   err,
+		}
+	}
+	to := service.AttrRpcTimeout()
+	timeout, err := time.ParseDuration(to)
+	if err != nil {
+		timeout = time.Second * 10
+	}
+	toctx, cancel := context.WithTimeout(newRequest.Context(), timeout)
+	defer cancel()
+	resp, err := b.httpClient.Do(newRequest.WithContext(toctx))
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayBackend,
+			Message:    flux.ErrorMessageSoapInvokeFailed,
+			Internal:   err,
+		}
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayBackend,
+			Message:    flux.ErrorMessageSoapInvokeFailed,
+			Internal:   err,
+		}
+	}
+	return &Response{
+		StatusCode:  resp.StatusCode,
+		Header:      resp.Header,
+		Body:        data,
+		ExposeFault: service.ExtBool(ServiceExtKeyExposeFault),
+	}, nil
+}
+
+// Assemble 生成SOAP请求：参数值按模板或Method生成Envelope
+func (b *BackendTransportService) Assemble(service flux.BackendService, ctx flux.Context) (*http.Request, error) {
+	version := service.ExtString(ServiceExtKeyVersion)
+	switch version {
+	case "":
+		version = Version11
+	case Version11, Version12:
+	default:
+		return nil, fmt.Errorf("unsupported soap version: %s", version)
+	}
+	values, err := backend.ResolveArgumentValues(service.Arguments, ctx)
+	if nil != err {
+		return nil, err
+	}
+	envelope, err := BuildEnvelope(service, version, values)
+	if nil != err {
+		return nil, err
+	}
+	url := service.RemoteHost + service.Interface
+	if !strings.Contains(service.RemoteHost, "://") {
+		url = "http://" + url
+	}
+	newRequest, err := http.NewRequestWithContext(ctx.Context(), http.MethodPost, url, bytes.NewReader(envelope))
+	if nil != err {
+		return nil, err
+	}
+	// Header透传以及传递AttrValues
+	header, _ := ctx.Request().HeaderValues()
+	newRequest.Header = header.Clone()
+	for k, v := range ctx.Attributes() {
+		newRequest.Header.Set(k, cast.ToString(v))
+	}
+	// 由Transport处理压缩
+	newRequest.Header.Del("Accept-Encoding")
+	newRequest.Header.Del("Content-Length")
+	action := service.ExtString(ServiceExtKeyAction)
+	if Version12 == version {
+		contentType := "application/soap+xml; charset=utf-8"
+		if "" != action {
+			contentType += `; action="` + action + `"`
+		}
+		newRequest.Header.Set("Content-Type", contentType)
+		newRequest.Header.Set("Accept", "application/soap+xml, text/xml")
+	} else {
+		newRequest.Header.Set("Content-Type", "text/xml; charset=utf-8")
+		newRequest.Header.Set("SOAPAction", `"`+action+`"`)
+		newRequest.Header.Set("Accept", "text/xml")
+	}
+	newRequest.Header.Set("User-Agent", "FluxGo/Backend/v1")
+	return newRequest, nil
+}
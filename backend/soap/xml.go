@@ -0,0 +1,106 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+const (
+	namespaceXsi = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// Node 解析后的XML元素
+type Node struct {
+	Name     xml.Name
+	Attrs    []xml.Attr
+	Text     string
+	Children []*Node
+}
+
+// Child 返回指定本地名称的第一个子元素
+func (n *Node) Child(local string) *Node {
+	for _, c := range n.Children {
+		if local == c.Name.Local {
+			return c
+		}
+	}
+	return nil
+}
+
+// Value 将元素转换为JSON数据：
+// 无子元素和属性的元素转换为文本；xsi:nil="true" 转换为 null；
+// 其它元素转换为对象，子元素以本地名称为Key（重复元素转换为数组），属性以 @ 为前缀，文本内容的Key为 #text。
+func (n *Node) Value() interface{} {
+	attrs := make(map[string]interface{}, len(n.Attrs))
+	for _, attr := range n.Attrs {
+		if namespaceXsi == attr.Name.Space && "nil" == attr.Name.Local && "true" == attr.Value {
+			return nil
+		}
+		// 忽略命名空间声明和XSI类型属性
+		if "xmlns" == attr.Name.Space || "xmlns" == attr.Name.Local || namespaceXsi == attr.Name.Space {
+			continue
+		}
+		attrs["@"+attr.Name.Local] = attr.Value
+	}
+	if len(n.Children) == 0 && len(attrs) == 0 {
+		return n.Text
+	}
+	out := attrs
+	for _, c := range n.Children {
+		value := c.Value()
+		if prev, ok := out[c.Name.Local]; ok {
+			// 元素的值不会是数组，已是数组表示重复元素
+			if list, ok := prev.([]interface{}); ok {
+				out[c.Name.Local] = append(list, value)
+			} else {
+				out[c.Name.Local] = []interface{}{prev, value}
+			}
+		} else {
+			out[c.Name.Local] = value
+		}
+	}
+	if text := strings.TrimSpace(n.Text); "" != text {
+		out["#text"] = text
+	}
+	return out
+}
+
+// ParseXML 解析XML文档，返回根元素
+func ParseXML(data []byte) (*Node, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	stack := make([]*Node, 0, 8)
+	var root *Node
+	for {
+		token, err := decoder.Token()
+		if io.EOF == err {
+			break
+		}
+		if nil != err {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &Node{Name: t.Name, Attrs: t.Attr}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, node)
+			} else if nil == root {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].Text += string(t)
+			}
+		}
+	}
+	if nil == root {
+		return nil, errors.New("soap empty xml document")
+	}
+	return root, nil
+}
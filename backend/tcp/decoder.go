@@ -0,0 +1,28 @@
+package tcp
+
+import (
+	"errors"
+	"github.com/bytepowered/flux"
+	"net/http"
+)
+
+var (
+	ErrUnknownTcpBackendResponse = errors.New("BACKEND:UNKNOWN_TCP_RESPONSE")
+)
+
+// NewTcpBackendTransportDecodeFunc 返回响应报文数据；Content-Type 默认为 application/octet-stream
+func NewTcpBackendTransportDecodeFunc() flux.BackendTransportDecodeFunc {
+	return func(ctx flux.Context, value interface{}) (statusCode int, headers http.Header, body interface{}, err error) {
+		resp, ok := value.(*Response)
+		if !ok {
+			return http.StatusInternalServerError, http.Header{}, nil, ErrUnknownTcpBackendResponse
+		}
+		contentType := resp.ContentType
+		if "" == contentType {
+			contentType = "application/octet-stream"
+		}
+		header := http.Header{}
+		header.Set(flux.HeaderContentType, contentType)
+		return http.StatusOK, header, resp.Data, nil
+	}
+}
@@ -0,0 +1,144 @@
+package tcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+)
+
+const (
+	// 请求和响应报文使用定长的长度前缀（大端序）
+	FramingLength = "length"
+	// 请求报文以分隔符结尾，读取响应直到分隔符
+	FramingDelimiter = "delimiter"
+	// 写入请求后关闭写方向，读取响应直到上游关闭连接
+	FramingClose = "close"
+)
+
+var (
+	ErrFrameTooLarge        = errors.New("tcp frame exceeds max frame size")
+	ErrFrameContainsDelimit = errors.New("tcp request body contains frame delimiter")
+)
+
+// Framing 报文分帧方式
+type Framing struct {
+	Mode string
+	// LengthBytes 长度前缀的字节数：1、2、4 或 8
+	LengthBytes int
+	// Delimiter 报文分隔符，不包含在响应数据中
+	Delimiter []byte
+	// MaxSize 响应报文的最大字节数
+	MaxSize int64
+}
+
+// NewFraming 校验并创建分帧方式；分隔符支持Go字符串转义，例如：\r\n、\x03
+func NewFraming(mode string, lengthBytes int, delimiter string, maxSize int64) (Framing, error) {
+	f := Framing{Mode: mode, LengthBytes: lengthBytes, MaxSize: maxSize}
+	switch mode {
+	case FramingLength:
+		switch lengthBytes {
+		case 1, 2, 4, 8:
+		default:
+			return f, fmt.Errorf("tcp invalid length-bytes: %d", lengthBytes)
+		}
+	case FramingDelimiter:
+		unquoted, err := strconv.Unquote(`"` + delimiter + `"`)
+		if nil != err || "" == unquoted {
+			return f, fmt.Errorf("tcp invalid delimiter: %q", delimiter)
+		}
+		f.Delimiter = []byte(unquoted)
+	case FramingClose:
+	default:
+		return f, fmt.Errorf("tcp unknown framing: %s", mode)
+	}
+	return f, nil
+}
+
+// Check 检查请求报文能否按分帧方式写入：长度不超过前缀的表示范围，不包含分隔符
+func (f Framing) Check(data []byte) error {
+	switch f.Mode {
+	case FramingLength:
+		if f.LengthBytes < 8 && uint64(len(data)) >= uint64(1)<<(8*uint(f.LengthBytes)) {
+			return ErrFrameTooLarge
+		}
+	case FramingDelimiter:
+		if bytes.Contains(data, f.Delimiter) {
+			return ErrFrameContainsDelimit
+		}
+	}
+	return nil
+}
+
+// Write 按分帧方式写入请求报文
+func (f Framing) Write(conn net.Conn, data []byte) error {
+	if err := f.Check(data); nil != err {
+		return err
+	}
+	var buf bytes.Buffer
+	switch f.Mode {
+	case FramingLength:
+		prefix := make([]byte, 8)
+		binary.BigEndian.PutUint64(prefix, uint64(len(data)))
+		buf.Write(prefix[8-f.LengthBytes:])
+		buf.Write(data)
+	case FramingDelimiter:
+		buf.Write(data)
+		buf.Write(f.Delimiter)
+	default:
+		buf.Write(data)
+	}
+	if _, err := conn.Write(buf.Bytes()); nil != err {
+		return err
+	}
+	if FramingClose == f.Mode {
+		if tc, ok := conn.(interface{ CloseWrite() error }); ok {
+			return tc.CloseWrite()
+		}
+	}
+	return nil
+}
+
+// Read 按分帧方式读取一个响应报文
+func (f Framing) Read(reader *bufio.Reader) ([]byte, error) {
+	switch f.Mode {
+	case FramingLength:
+		prefix := make([]byte, 8)
+		if _, err := io.ReadFull(reader, prefix[8-f.LengthBytes:]); nil != err {
+			return nil, err
+		}
+		size := binary.BigEndian.Uint64(prefix)
+		if size > uint64(f.MaxSize) {
+			return nil, ErrFrameTooLarge
+		}
+		data := make([]byte, size)
+		_, err := io.ReadFull(reader, data)
+		return data, err
+	case FramingDelimiter:
+		var buf bytes.Buffer
+		for {
+			c, err := reader.ReadByte()
+			if nil != err {
+				return nil, err
+			}
+			buf.WriteByte(c)
+			if bytes.HasSuffix(buf.Bytes(), f.Delimiter) {
+				return buf.Bytes()[:buf.Len()-len(f.Delimiter)], nil
+			}
+			if int64(buf.Len()) > f.MaxSize+int64(len(f.Delimiter)) {
+				return nil, ErrFrameTooLarge
+			}
+		}
+	default:
+		data, err := ioutil.ReadAll(io.LimitReader(reader, f.MaxSize+1))
+		if nil == err && int64(len(data)) > f.MaxSize {
+			return nil, ErrFrameTooLarge
+		}
+		return data, err
+	}
+}
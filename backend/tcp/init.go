@@ -0,0 +1,11 @@
+package tcp
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+)
+
+func init() {
+	ext.StoreBackendTransport(flux.ProtoTcp, NewTcpBackendTransport())
+	ext.StoreBackendTransportDecodeFunc(flux.ProtoTcp, NewTcpBackendTransportDecodeFunc())
+}
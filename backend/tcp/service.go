@@ -0,0 +1,190 @@
+package tcp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/backend"
+	"github.com/bytepowered/flux/logger"
+	"io/ioutil"
+	"net"
+	"time"
+)
+
+const (
+	configKeyTimeout      = "timeout"
+	configKeyFraming      = "framing"
+	configKeyMaxFrameSize = "max-frame-size"
+)
+
+const (
+	// BackendService扩展属性：报文分帧方式，length、delimiter 或 close；未设置时使用全局配置
+	ServiceExtKeyFraming = "tcp-framing"
+	// BackendService扩展属性：length 分帧的长度前缀字节数，默认4
+	ServiceExtKeyLengthBytes = "tcp-length-bytes"
+	// BackendService扩展属性：delimiter 分帧的分隔符，默认 \n
+	ServiceExtKeyDelimiter = "tcp-delimiter"
+	// BackendService扩展属性：响应的Content-Type，默认 application/octet-stream
+	ServiceExtKeyContentType = "tcp-content-type"
+)
+
+var (
+	_ flux.BackendTransport = new(BackendTransportService)
+)
+
+// Response 上游返回的响应报文数据，不包含长度前缀和分隔符
+type Response struct {
+	Data        []byte
+	ContentType string
+}
+
+// BackendTransportService 透传到TCP上游的BackendService：
+// BackendService.RemoteHost 为上游地址（host:port），请求Body按分帧方式写入上游连接，返回读取的响应报文。
+// 每个请求使用独立的连接，避免上游协议状态在请求之间串扰。
+type BackendTransportService struct {
+	timeout      time.Duration
+	framing      string
+	maxFrameSize int64
+}
+
+// NewTcpBackendTransport New tcp backend instance
+func NewTcpBackendTransport() flux.BackendTransport {
+	return &BackendTransportService{
+		timeout:      time.Second * 10,
+		framing:      FramingClose,
+		maxFrameSize: 4 * 1024 * 1024,
+	}
+}
+
+// Init init backend
+func (b *BackendTransportService) Init(config *flux.Configuration) error {
+	logger.Info("TCP backend transport initializing")
+	config.SetDefaults(map[string]interface{}{
+		configKeyTimeout:      time.Second * 10,
+		configKeyFraming:      FramingClose,
+		configKeyMaxFrameSize: 4 * 1024 * 1024,
+	})
+	b.timeout = config.GetDuration(configKeyTimeout)
+	b.framing = config.GetString(configKeyFraming)
+	b.maxFrameSize = config.GetInt64(configKeyMaxFrameSize)
+	return nil
+}
+
+// Exchange do exchange with context
+func (b *BackendTransportService) Exchange(ctx flux.Context) *flux.ServeError {
+	return backend.DoExchange(ctx, b)
+}
+
+// Invoke 写入请求报文并读取响应报文
+func (b *BackendTransportService) Invoke(service flux.BackendService, ctx flux.Context) (interface{}, *flux.ServeError) {
+	framing, err := b.FramingOf(service)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageTcpAssembleFailed,
+			Internal:   err,
+		}
+	}
+	data, err := b.Assemble(service, ctx)
+	if nil == err {
+		// 请求报文无法按分帧方式写入时，无需连接上游
+		err = framing.Check(data)
+	}
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusBadRequest,
+			ErrorCode:  flux.ErrorCodeRequestInvalid,
+			Message:    flux.ErrorMessageTcpAssembleFailed,
+			Internal:   err,
+		}
+	}
+	timeout := b.timeout
+	if to := service.AttrRpcTimeout(); "" != to {
+		if d, err := time.ParseDuration(to); nil == err {
+			timeout = d
+		} else {
+			logger.Warnf("Illegal endpoint rpc-timeout: ", to)
+		}
+	}
+	toctx, cancel := context.WithTimeout(ctx.Context(), timeout)
+	defer cancel()
+	resp, err := b.Execute(toctx, service.RemoteHost, framing, data)
+	if nil != err {
+		serr := &flux.ServeError{
+			StatusCode: flux.StatusBadGateway,
+			ErrorCode:  flux.ErrorCodeGatewayBackend,
+			Message:    flux.ErrorMessageTcpInvokeFailed,
+			Internal:   err,
+		}
+		var nerr net.Error
+		if errors.As(err, &nerr) && nerr.Timeout() || context.DeadlineExceeded == toctx.Err() {
+			serr.StatusCode = flux.StatusGatewayTimeout
+		}
+		return nil, serr
+	}
+	resp.ContentType = service.ExtString(ServiceExtKeyContentType)
+	return resp, nil
+}
+
+// FramingOf 返回BackendService的分帧方式：扩展属性优先，未设置时使用全局配置
+func (b *BackendTransportService) FramingOf(service flux.BackendService) (Framing, error) {
+	mode := service.ExtString(ServiceExtKeyFraming)
+	if "" == mode {
+		mode = b.framing
+	}
+	lengthBytes := 4
+	if _, ok := service.Ext(ServiceExtKeyLengthBytes); ok {
+		lengthBytes = service.ExtInt(ServiceExtKeyLengthBytes)
+	}
+	delimiter := service.ExtString(ServiceExtKeyDelimiter)
+	if "" == delimiter {
+		delimiter = `\n`
+	}
+	return NewFraming(mode, lengthBytes, delimiter, b.maxFrameSize)
+}
+
+// Assemble 读取原始请求Body作为请求报文
+func (b *BackendTransportService) Assemble(service flux.BackendService, ctx flux.Context) ([]byte, error) {
+	if "" == service.RemoteHost {
+		return nil, fmt.Errorf("tcp remote host is required, service: %s", service.ServiceID())
+	}
+	reader, err := ctx.Request().RequestBodyReader()
+	if nil != err {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+// Execute 连接上游，写入请求报文并读取一个响应报文；Context取消或超时时中断读写
+func (b *BackendTransportService) Execute(ctx context.Context, address string, framing Framing, data []byte) (*Response, error) {
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if nil != err {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+	if err := framing.Write(conn, data); nil != err {
+		return nil, err
+	}
+	resp, err := framing.Read(bufio.NewReader(conn))
+	if nil != err {
+		return nil, err
+	}
+	return &Response{Data: resp}, nil
+}
@@ -0,0 +1,69 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/spf13/cast"
+	"time"
+)
+
+const (
+	// BackendService扩展属性：调用超时，例如 "3s"。超时后取消上游调用并返回504；
+	// 超时只作用于调用过程，不包含调用返回后读取响应Body。WebSocket等长连接服务不应配置。
+	ServiceExtKeyInvokeTimeout = "invoke-timeout"
+)
+
+// deadlineContext 带调用截止时间的Context；截止时间通过 context.Context 传递给上游调用
+type deadlineContext struct {
+	fluxContext
+	goctx context.Context
+}
+
+func (c *deadlineContext) Context() context.Context {
+	return c.goctx
+}
+
+// invokeDeadline 在可取消的Context上报告截止时间：上游调用可读取截止时间（例如gRPC的grpc-timeout），
+// 取消由调用超时计时器控制，调用返回后不再取消
+type invokeDeadline struct {
+	context.Context
+	deadline time.Time
+}
+
+func (d invokeDeadline) Deadline() (time.Time, bool) {
+	if parent, ok := d.Context.Deadline(); ok && parent.Before(d.deadline) {
+		return parent, true
+	}
+	return d.deadline, true
+}
+
+// invokeTimeoutOf 返回BackendService声明的调用超时；未声明返回0
+func invokeTimeoutOf(service flux.BackendService) time.Duration {
+	v, ok := service.Ext(ServiceExtKeyInvokeTimeout)
+	if !ok {
+		return 0
+	}
+	return cast.ToDuration(v)
+}
+
+// invokeWithTimeout 在调用超时内执行调用；超时后取消调用的Context，释放迟到的响应并返回504错误
+func invokeWithTimeout(ctx flux.Context, timeout time.Duration, call func(ctx flux.Context) (interface{}, *flux.ServeError)) (interface{}, *flux.ServeError) {
+	goctx, cancel := context.WithCancel(ctx.Context())
+	timer := time.AfterFunc(timeout, cancel)
+	resp, err := call(&deadlineContext{
+		fluxContext: ctx,
+		goctx:       invokeDeadline{Context: goctx, deadline: time.Now().Add(timeout)},
+	})
+	// 调用返回后不取消Context：响应Body可能仍依赖其Context读取；计时器已触发表示调用超时
+	if timer.Stop() {
+		return resp, err
+	}
+	closeResponse(resp)
+	return nil, &flux.ServeError{
+		StatusCode: flux.StatusGatewayTimeout,
+		ErrorCode:  flux.ErrorCodeGatewayTimeout,
+		Message:    flux.ErrorMessageBackendInvokeTimeout,
+		Internal:   fmt.Errorf("backend invoke timeout: %s", timeout),
+	}
+}
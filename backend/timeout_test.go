@@ -0,0 +1,51 @@
+package backend
+
+import (
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestInvokeWithTimeout(t *testing.T) {
+	cases := []struct {
+		delay  time.Duration
+		status int
+	}{
+		// 调用在超时内返回
+		{delay: 0, status: http.StatusOK},
+		// 调用超时，Context被取消，返回504
+		{delay: time.Second, status: http.StatusGatewayTimeout},
+	}
+	for _, tc := range cases {
+		assert := assert2.New(t)
+		ctx := &hedgeTestContext{method: http.MethodGet, values: map[string]interface{}{}}
+		resp, err := invokeWithTimeout(ctx, 20*time.Millisecond, func(ctx flux.Context) (interface{}, *flux.ServeError) {
+			deadline, ok := ctx.Context().Deadline()
+			assert.True(ok)
+			assert.True(time.Until(deadline) <= 20*time.Millisecond)
+			select {
+			case <-time.After(tc.delay):
+				return http.StatusOK, nil
+			case <-ctx.Context().Done():
+				return nil, &flux.ServeError{StatusCode: http.StatusBadGateway}
+			}
+		})
+		if http.StatusOK == tc.status {
+			assert.Nil(err)
+			assert.Equal(tc.status, resp)
+		} else {
+			assert.Equal(tc.status, err.StatusCode)
+			assert.Equal(flux.ErrorCodeGatewayTimeout, err.ErrorCode)
+		}
+	}
+}
+
+func TestInvokeTimeoutOf(t *testing.T) {
+	assert := assert2.New(t)
+	service := flux.BackendService{}
+	assert.Equal(time.Duration(0), invokeTimeoutOf(service))
+	service.Extensions = map[string]interface{}{ServiceExtKeyInvokeTimeout: "3s"}
+	assert.Equal(3*time.Second, invokeTimeoutOf(service))
+}
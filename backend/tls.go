@@ -0,0 +1,52 @@
+package backend
+
+import (
+	"context"
+	"crypto/tls"
+	"github.com/bytepowered/flux/ext"
+	"net"
+	"time"
+)
+
+// UpstreamTLSClient 在已建立的上游连接上完成TLS握手；设置了 UpstreamTLSConfigFunc 时使用其返回的配置，
+// 并保留默认配置中的ServerName和应用层协议（例如HTTP/2的h2）。握手失败时关闭连接。
+func UpstreamTLSClient(ctx context.Context, conn net.Conn, addr string, config *tls.Config) (net.Conn, error) {
+	if nil == config {
+		config = &tls.Config{}
+	}
+	if f := ext.RegistryOf(ctx).LoadUpstreamTLSConfigFunc(); nil != f {
+		upstream, err := f(addr)
+		if nil != err {
+			_ = conn.Close()
+			return nil, err
+		}
+		if nil != upstream {
+			upstream = upstream.Clone()
+			if 0 == len(upstream.NextProtos) {
+				upstream.NextProtos = config.NextProtos
+			}
+			if "" == upstream.ServerName {
+				upstream.ServerName = config.ServerName
+			}
+			config = upstream
+		}
+	}
+	if "" == config.ServerName {
+		config = config.Clone()
+		if host, _, err := net.SplitHostPort(addr); nil == err {
+			config.ServerName = host
+		} else {
+			config.ServerName = addr
+		}
+	}
+	tc := tls.Client(conn, config)
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	if err := tc.Handshake(); nil != err {
+		_ = conn.Close()
+		return nil, err
+	}
+	return tc, nil
+}
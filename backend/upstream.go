@@ -0,0 +1,252 @@
+package backend
+
+import (
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/support"
+	"github.com/spf13/cast"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// 轮询：按顺序选择地址，忽略权重
+	UpstreamBalancerRoundRobin = "round-robin"
+	// 加权轮询：按权重平滑地选择地址
+	UpstreamBalancerWeighted = "weighted"
+	// 一致性哈希：按 Upstream.HashKey 查找的请求参数值选择地址，参数值为空时随机选择
+	UpstreamBalancerConsistentHash = "consistent-hash"
+)
+
+var (
+	// 负载均衡策略实例按上游集群定义区分；定义变更后使用新的实例
+	upstreamBalancers sync.Map
+)
+
+func init() {
+	ext.StoreUpstreamBalancerFactory(UpstreamBalancerRoundRobin, newRoundRobinBalancer)
+	ext.StoreUpstreamBalancerFactory(UpstreamBalancerWeighted, newWeightedBalancer)
+	ext.StoreUpstreamBalancerFactory(UpstreamBalancerConsistentHash, newConsistentHashBalancer)
+}
+
+// resolveUpstream 解析BackendService引用的上游集群：RemoteHost 以 upstream:// 开头时，
+// 由集群的负载均衡策略在健康且未排除的地址中选择一个，返回替换 RemoteHost 后的服务副本和上游集群；否则原样返回
+func resolveUpstream(service flux.BackendService, ctx flux.Context, excluded map[string]bool) (flux.BackendService, *flux.Upstream, *flux.ServeError) {
+	if !strings.HasPrefix(service.RemoteHost, flux.UpstreamHostPrefix) {
+		return service, nil, nil
+	}
+	name := strings.TrimPrefix(service.RemoteHost, flux.UpstreamHostPrefix)
+	upstream, ok := ext.RegistryOf(ctx.Context()).LoadUpstream(name)
+	if !ok {
+		return service, nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageUpstreamNotFound,
+			Internal:   fmt.Errorf("upstream not found, name: %s", name),
+		}
+	}
+	// 负载均衡策略只在健康的地址中选择；健康地址变化时使用新的策略实例
+	healthy := upstream
+	healthy.Addresses = make([]flux.UpstreamAddress, 0, len(upstream.Addresses))
+	for _, addr := range healthyAddressesOf(upstream) {
+		if !excluded[addr.Address] {
+			healthy.Addresses = append(healthy.Addresses, addr)
+		}
+	}
+	balancer, err := balancerOf(healthy)
+	if nil != err {
+		return service, nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageUpstreamBalancerInvalid,
+			Internal:   err,
+		}
+	}
+	selected, err := balancer.Select(ctx)
+	if nil != err {
+		return service, nil, &flux.ServeError{
+			StatusCode: http.StatusServiceUnavailable,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageUpstreamUnavailable,
+			Internal:   err,
+		}
+	}
+	logger.TraceContext(ctx).Debugw("Backend upstream selected", "upstream", name, "address", selected.Address)
+	service.RemoteHost = selected.Address
+	return service, &upstream, nil
+}
+
+// invokeUpstream 选择上游集群地址后，经过调用拦截器调用后端服务，并记录调用结果用于被动健康检查；
+// 开启故障转移时，连接错误的地址被排除，使用集群中的其它地址重新调用。请求覆盖了后端服务地址时，直接调用覆盖的地址
+func invokeUpstream(exchange flux.BackendTransport, service flux.BackendService, ctx flux.Context) (interface{}, *flux.ServeError) {
+	if host, ok := upstreamOverrideOf(ctx); ok {
+		logger.TraceContext(ctx).Infow("Backend upstream overridden", "remote-host", service.RemoteHost, "override", host)
+		service.RemoteHost = host
+		return invokeIntercepted(exchange, flux.BackendInvocation{RpcProto: service.AttrRpcProto(), Attempt: 1, Service: service}, ctx)
+	}
+	attempts := failoverAttemptsOf(ctx)
+	var excluded map[string]bool
+	var failed *flux.ServeError
+	for attempt := 1; ; attempt++ {
+		resolved, upstream, serr := resolveUpstream(service, ctx, excluded)
+		if nil != serr {
+			// 没有可转移的地址时，返回上一次调用的连接错误
+			if nil != failed {
+				return nil, failed
+			}
+			return nil, serr
+		}
+		invocation := flux.BackendInvocation{RpcProto: service.AttrRpcProto(), Attempt: attempt, Service: resolved}
+		if nil != upstream {
+			invocation.Upstream = upstream.Name
+		}
+		resp, serr := invokeIntercepted(exchange, invocation, ctx)
+		if nil == upstream {
+			return resp, serr
+		}
+		reportUpstream(*upstream, resolved.RemoteHost, resp, serr)
+		if attempt >= attempts || attempt >= len(upstream.Addresses) || !connectionFailed(serr) {
+			return resp, serr
+		}
+		if nil == excluded {
+			excluded = make(map[string]bool, attempts)
+		}
+		excluded[resolved.RemoteHost], failed = true, serr
+		logger.TraceContext(ctx).Warnw("Backend upstream failover", "upstream", upstream.Name,
+			"address", resolved.RemoteHost, "attempt", attempt, "error", serr.Internal)
+	}
+}
+
+// balancerOf 返回上游集群的负载均衡策略实例；未指定策略时使用轮询
+func balancerOf(upstream flux.Upstream) (flux.UpstreamBalancer, error) {
+	key := fmt.Sprintf("%v", upstream)
+	if v, ok := upstreamBalancers.Load(key); ok {
+		return v.(flux.UpstreamBalancer), nil
+	}
+	name := upstream.Balancer
+	if "" == name {
+		name = UpstreamBalancerRoundRobin
+	}
+	factory, ok := ext.LoadUpstreamBalancerFactory(name)
+	if !ok {
+		return nil, fmt.Errorf("upstream balancer not found, upstream: %s, balancer: %s", upstream.Name, name)
+	}
+	balancer, err := factory(upstream)
+	if nil != err {
+		return nil, err
+	}
+	v, _ := upstreamBalancers.LoadOrStore(key, balancer)
+	return v.(flux.UpstreamBalancer), nil
+}
+
+func errUpstreamEmpty(upstream flux.Upstream) error {
+	return fmt.Errorf("upstream has no address, name: %s", upstream.Name)
+}
+
+type roundRobinBalancer struct {
+	upstream flux.Upstream
+	next     uint32
+}
+
+func newRoundRobinBalancer(upstream flux.Upstream) (flux.UpstreamBalancer, error) {
+	return &roundRobinBalancer{upstream: upstream}, nil
+}
+
+func (b *roundRobinBalancer) Select(flux.Context) (flux.UpstreamAddress, error) {
+	size := len(b.upstream.Addresses)
+	if 0 == size {
+		return flux.UpstreamAddress{}, errUpstreamEmpty(b.upstream)
+	}
+	next := atomic.AddUint32(&b.next, 1) - 1
+	return b.upstream.Addresses[next%uint32(size)], nil
+}
+
+// weightedBalancer 平滑加权轮询：每次选择当前权重最大的地址，并减去总权重；权重小于1的地址按1计算
+type weightedBalancer struct {
+	upstream flux.Upstream
+	mu       sync.Mutex
+	weights  []int
+	current  []int
+	total    int
+}
+
+func newWeightedBalancer(upstream flux.Upstream) (flux.UpstreamBalancer, error) {
+	b := &weightedBalancer{
+		upstream: upstream,
+		weights:  make([]int, len(upstream.Addresses)),
+		current:  make([]int, len(upstream.Addresses)),
+	}
+	for i, addr := range upstream.Addresses {
+		if b.weights[i] = addr.Weight; b.weights[i] < 1 {
+			b.weights[i] = 1
+		}
+		b.total += b.weights[i]
+	}
+	return b, nil
+}
+
+func (b *weightedBalancer) Select(flux.Context) (flux.UpstreamAddress, error) {
+	if 0 == len(b.weights) {
+		return flux.UpstreamAddress{}, errUpstreamEmpty(b.upstream)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	selected := 0
+	for i := range b.current {
+		b.current[i] += b.weights[i]
+		if b.current[i] > b.current[selected] {
+			selected = i
+		}
+	}
+	b.current[selected] -= b.total
+	return b.upstream.Addresses[selected], nil
+}
+
+type consistentHashBalancer struct {
+	upstream flux.Upstream
+	scope    string
+	key      string
+	ring     *support.HashRing
+	indexes  map[string]int
+}
+
+func newConsistentHashBalancer(upstream flux.Upstream) (flux.UpstreamBalancer, error) {
+	scope, _, ok := support.ParseLookupExpr(upstream.HashKey)
+	if !ok {
+		return nil, fmt.Errorf("upstream hash-key is invalid, upstream: %s, hash-key: %s", upstream.Name, upstream.HashKey)
+	}
+	weights := make(map[string]int, len(upstream.Addresses))
+	indexes := make(map[string]int, len(upstream.Addresses))
+	for i, addr := range upstream.Addresses {
+		weights[addr.Address] = addr.Weight
+		indexes[addr.Address] = i
+	}
+	return &consistentHashBalancer{
+		upstream: upstream,
+		scope:    scope,
+		// key 可能包含冒号
+		key:     upstream.HashKey[strings.Index(upstream.HashKey, ":")+1:],
+		ring:    support.NewHashRing(weights, support.DefaultHashRingReplicas),
+		indexes: indexes,
+	}, nil
+}
+
+func (b *consistentHashBalancer) Select(ctx flux.Context) (flux.UpstreamAddress, error) {
+	if 0 == len(b.upstream.Addresses) {
+		return flux.UpstreamAddress{}, errUpstreamEmpty(b.upstream)
+	}
+	value, err := support.NewContextPredicateLookup(ctx)(b.scope, b.key)
+	hashKey := cast.ToString(value)
+	if nil != err || "" == hashKey {
+		logger.TraceContext(ctx).Debugw("Backend upstream, hash key is empty, select randomly",
+			"upstream", b.upstream.Name, "hash-key", b.upstream.HashKey, "error", err)
+		return b.upstream.Addresses[rand.Intn(len(b.upstream.Addresses))], nil
+	}
+	address, _ := b.ring.Get(hashKey)
+	return b.upstream.Addresses[b.indexes[address]], nil
+}
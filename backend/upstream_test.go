@@ -0,0 +1,72 @@
+package backend
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestResolveUpstream(t *testing.T) {
+	assert := assert2.New(t)
+	ext.StoreUpstream(flux.Upstream{Name: "test-cluster", Addresses: []flux.UpstreamAddress{
+		{Address: "10.0.0.1:8080"}, {Address: "10.0.0.2:8080"},
+	}})
+	defer ext.RemoveUpstream("test-cluster")
+	ctx := &hedgeTestContext{}
+	cases := []struct {
+		host     string
+		expected string
+		err      string
+	}{
+		{host: "10.0.0.9:8080", expected: "10.0.0.9:8080"},
+		{host: "upstream://test-cluster", expected: "10.0.0.1:8080"},
+		{host: "upstream://test-cluster", expected: "10.0.0.2:8080"},
+		{host: "upstream://test-cluster", expected: "10.0.0.1:8080"},
+		{host: "upstream://not-found", err: flux.ErrorMessageUpstreamNotFound},
+	}
+	for _, c := range cases {
+		service, _, err := resolveUpstream(flux.BackendService{RemoteHost: c.host}, ctx, nil)
+		if "" != c.err {
+			assert.NotNil(err)
+			assert.Equal(c.err, err.Message)
+			continue
+		}
+		assert.Nil(err)
+		assert.Equal(c.expected, service.RemoteHost)
+	}
+}
+
+func TestWeightedBalancer(t *testing.T) {
+	assert := assert2.New(t)
+	balancer, _ := newWeightedBalancer(flux.Upstream{Name: "weighted", Addresses: []flux.UpstreamAddress{
+		{Address: "a", Weight: 5}, {Address: "b", Weight: 1}, {Address: "c", Weight: 1},
+	}})
+	selected := make([]string, 0, 7)
+	for i := 0; i < 7; i++ {
+		addr, err := balancer.Select(nil)
+		assert.Nil(err)
+		selected = append(selected, addr.Address)
+	}
+	// 平滑加权：权重大的地址不会连续集中选择
+	assert.Equal([]string{"a", "a", "b", "a", "c", "a", "a"}, selected)
+	empty, _ := newWeightedBalancer(flux.Upstream{Name: "empty"})
+	_, err := empty.Select(nil)
+	assert.Error(err)
+}
+
+func TestConsistentHashBalancer(t *testing.T) {
+	assert := assert2.New(t)
+	_, err := newConsistentHashBalancer(flux.Upstream{Name: "hash"})
+	assert.Error(err)
+	balancer, err := newConsistentHashBalancer(flux.Upstream{Name: "hash", HashKey: "header:X-User-Id",
+		Addresses: []flux.UpstreamAddress{{Address: "a"}, {Address: "b"}}})
+	assert.Nil(err)
+	hb := balancer.(*consistentHashBalancer)
+	assert.Equal("HEADER", hb.scope)
+	assert.Equal("X-User-Id", hb.key)
+	// 相同的Key总是选择相同的地址
+	first, _ := hb.ring.Get("user-1")
+	second, _ := hb.ring.Get("user-1")
+	assert.Equal(first, second)
+}
@@ -0,0 +1,13 @@
+package websocket
+
+import (
+	"github.com/bytepowered/flux"
+	"net/http"
+)
+
+// NewWebSocketBackendTransportDecodeFunc WebSocket会话已直接写入客户端连接，只返回协议升级状态码
+func NewWebSocketBackendTransportDecodeFunc() flux.BackendTransportDecodeFunc {
+	return func(ctx flux.Context, value interface{}) (statusCode int, headers http.Header, body interface{}, err error) {
+		return http.StatusSwitchingProtocols, http.Header{}, nil, nil
+	}
+}
@@ -0,0 +1,68 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+)
+
+const (
+	opcodeClose = 0x8
+	// 对端连接异常断开时，发送给另一端的关闭状态码：Going Away
+	closeGoingAway = 1001
+)
+
+// copyFrames 按WebSocket帧原样转发数据，直到读取失败或连接关闭；返回是否已转发Close帧
+func copyFrames(dst io.Writer, src io.Reader) (closed bool, err error) {
+	header := make([]byte, 14)
+	for {
+		if _, err := io.ReadFull(src, header[:2]); nil != err {
+			return closed, err
+		}
+		opcode := header[0] & 0x0f
+		extra := 0
+		switch header[1] & 0x7f {
+		case 126:
+			extra = 2
+		case 127:
+			extra = 8
+		}
+		if 0 != header[1]&0x80 {
+			extra += 4
+		}
+		if _, err := io.ReadFull(src, header[2:2+extra]); nil != err {
+			return closed, err
+		}
+		size := uint64(header[1] & 0x7f)
+		switch size {
+		case 126:
+			size = uint64(binary.BigEndian.Uint16(header[2:4]))
+		case 127:
+			size = binary.BigEndian.Uint64(header[2:10])
+		}
+		if _, err := dst.Write(header[:2+extra]); nil != err {
+			return closed, err
+		}
+		if _, err := io.CopyN(dst, src, int64(size)); nil != err {
+			return closed, err
+		}
+		if opcodeClose == opcode {
+			closed = true
+		}
+	}
+}
+
+// closeFrame 生成Close帧；客户端发送到服务端的帧必须使用掩码
+func closeFrame(code int, masked bool) []byte {
+	payload := []byte{byte(code >> 8), byte(code)}
+	if !masked {
+		return append([]byte{0x80 | opcodeClose, byte(len(payload))}, payload...)
+	}
+	key := make([]byte, 4)
+	_, _ = rand.Read(key)
+	for i := range payload {
+		payload[i] ^= key[i%4]
+	}
+	frame := append([]byte{0x80 | opcodeClose, 0x80 | byte(len(payload))}, key...)
+	return append(frame, payload...)
+}
@@ -0,0 +1,11 @@
+package websocket
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+)
+
+func init() {
+	ext.StoreBackendTransport(flux.ProtoWebSocket, NewWebSocketBackendTransport())
+	ext.StoreBackendTransportDecodeFunc(flux.ProtoWebSocket, NewWebSocketBackendTransportDecodeFunc())
+}
@@ -0,0 +1,225 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/backend"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/support"
+	"github.com/spf13/cast"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	_ flux.BackendTransport = new(BackendTransportService)
+)
+
+// 握手请求中不透传的逐跳Header
+var hopHeaders = []string{"Keep-Alive", "Proxy-Connection", "Proxy-Authorization", "Te", "Trailer", "Transfer-Encoding"}
+
+// BackendTransportService WebSocket代理：BackendService.RemoteHost 为上游地址（ws://host:port 或 wss://host:port，未指定scheme时为ws），
+// Interface 为上游路径。握手请求透传客户端Header和Attributes；握手成功后接管客户端连接，双向原样转发WebSocket帧（包括Close帧及其状态码）。
+type BackendTransportService struct {
+	dialer *net.Dialer
+}
+
+// NewWebSocketBackendTransport New websocket backend instance
+func NewWebSocketBackendTransport() flux.BackendTransport {
+	return &BackendTransportService{
+		dialer: &net.Dialer{Timeout: time.Second * 10},
+	}
+}
+
+// Exchange do exchange with context
+func (b *BackendTransportService) Exchange(ctx flux.Context) *flux.ServeError {
+	return backend.DoExchange(ctx, b)
+}
+
+// Invoke 完成上游握手并代理WebSocket会话；会话结束后返回上游的握手响应
+func (b *BackendTransportService) Invoke(service flux.BackendService, ctx flux.Context) (interface{}, *flux.ServeError) {
+	uc, ok := ctx.(flux.UpgradeContext)
+	if !ok || !flux.IsWebSocketUpgrade(ctx.Request()) {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusBadRequest,
+			ErrorCode:  flux.ErrorCodeRequestInvalid,
+			Message:    flux.ErrorMessageWebSocketUpgradeRequired,
+		}
+	}
+	timeout, err := time.ParseDuration(service.AttrRpcTimeout())
+	if nil != err {
+		timeout = time.Second * 10
+	}
+	target := upstreamURLOf(service, ctx)
+	upstream, resp, err := b.handshake(target, ctx, timeout)
+	if nil != err {
+		serr := &flux.ServeError{
+			StatusCode: flux.StatusBadGateway,
+			ErrorCode:  flux.ErrorCodeGatewayBackend,
+			Message:    flux.ErrorMessageWebSocketHandshakeFailed,
+			Internal:   err,
+		}
+		// 上游拒绝握手的客户端错误原样返回状态码
+		if nil != resp && resp.StatusCode >= http.StatusBadRequest && resp.StatusCode < http.StatusInternalServerError {
+			serr.StatusCode = resp.StatusCode
+		}
+		return nil, serr
+	}
+	client, clientrw, err := uc.Upgrade()
+	if nil != err {
+		_ = upstream.Close()
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageWebSocketUpgradeFailed,
+			Internal:   err,
+		}
+	}
+	// 清除Web服务设置的读写超时
+	_ = client.SetDeadline(time.Time{})
+	if err := writeHandshakeResponse(clientrw.Writer, resp); nil != err {
+		logger.TraceContext(ctx).Warnw("WebSocket write handshake response failed", "error", err)
+		_ = client.Close()
+		_ = upstream.Close()
+		return resp, nil
+	}
+	logger.TraceContext(ctx).Infow("WebSocket session start", "upstream", target.String())
+	tunnel(client, clientrw.Reader, upstream.conn, upstream.reader)
+	logger.TraceContext(ctx).Infow("WebSocket session end", "upstream", target.String())
+	return resp, nil
+}
+
+type upstreamConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func (u *upstreamConn) Close() error {
+	return u.conn.Close()
+}
+
+// handshake 连接上游并发送握手请求；返回的错误不为空时，响应可能为上游拒绝握手的响应
+func (b *BackendTransportService) handshake(target *url.URL, ctx flux.Context, timeout time.Duration) (*upstreamConn, *http.Response, error) {
+	conn, err := b.dial(target, timeout)
+	if nil != err {
+		return nil, nil, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	request := newHandshakeRequest(target, ctx)
+	if err := request.Write(conn); nil != err {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, request)
+	if nil != err {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+	if http.StatusSwitchingProtocols != resp.StatusCode {
+		_ = resp.Body.Close()
+		_ = conn.Close()
+		return nil, resp, fmt.Errorf("upstream handshake status: %d", resp.StatusCode)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return &upstreamConn{conn: conn, reader: reader}, resp, nil
+}
+
+func (b *BackendTransportService) dial(target *url.URL, timeout time.Duration) (net.Conn, error) {
+	dialer := *b.dialer
+	dialer.Timeout = timeout
+	if "wss" == target.Scheme {
+		addr := target.Host
+		if "" == target.Port() {
+			addr = net.JoinHostPort(target.Hostname(), "443")
+		}
+		return tls.DialWithDialer(&dialer, "tcp", addr, &tls.Config{ServerName: target.Hostname()})
+	}
+	addr := target.Host
+	if "" == target.Port() {
+		addr = net.JoinHostPort(target.Hostname(), "80")
+	}
+	return dialer.Dial("tcp", addr)
+}
+
+func upstreamURLOf(service flux.BackendService, ctx flux.Context) *url.URL {
+	host, scheme := service.RemoteHost, "ws"
+	if i := strings.Index(host, "://"); i > 0 {
+		scheme, host = host[:i], host[i+3:]
+	}
+	target := &url.URL{Scheme: scheme, Host: strings.TrimSuffix(host, "/"), Path: service.Interface}
+	if inurl, _ := ctx.Request().RequestURL(); nil != inurl {
+		target.RawQuery = inurl.RawQuery
+	}
+	return target
+}
+
+// newHandshakeRequest 生成上游握手请求：透传客户端Header（包括Sec-WebSocket-*）和Attributes
+func newHandshakeRequest(target *url.URL, ctx flux.Context) *http.Request {
+	header, _ := ctx.Request().HeaderValues()
+	header = header.Clone()
+	for _, name := range hopHeaders {
+		header.Del(name)
+	}
+	for k, v := range ctx.Attributes() {
+		header.Set(k, cast.ToString(v))
+	}
+	header.Set(flux.HeaderConnection, "Upgrade")
+	header.Set(flux.HeaderUpgrade, "websocket")
+	if ip := support.ClientIPOf(ctx); "" != ip {
+		if prior := header.Get(flux.HeaderXForwardedFor); "" != prior {
+			ip = prior + ", " + ip
+		}
+		header.Set(flux.HeaderXForwardedFor, ip)
+	}
+	return &http.Request{
+		Method:     http.MethodGet,
+		URL:        target,
+		Host:       target.Host,
+		Header:     header,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+	}
+}
+
+func writeHandshakeResponse(w *bufio.Writer, resp *http.Response) error {
+	if _, err := w.WriteString("HTTP/1.1 101 Switching Protocols\r\n"); nil != err {
+		return err
+	}
+	if err := resp.Header.Write(w); nil != err {
+		return err
+	}
+	if _, err := w.WriteString("\r\n"); nil != err {
+		return err
+	}
+	return w.Flush()
+}
+
+// tunnel 双向转发WebSocket帧；任一方向结束后关闭两端连接。
+// 一端未发送Close帧而断开连接时，向另一端发送 1001(Going Away) Close帧。
+func tunnel(client net.Conn, clientReader io.Reader, upstream net.Conn, upstreamReader io.Reader) {
+	done := make(chan struct{}, 2)
+	go func() {
+		if closed, _ := copyFrames(upstream, clientReader); !closed {
+			_, _ = upstream.Write(closeFrame(closeGoingAway, true))
+		}
+		done <- struct{}{}
+	}()
+	go func() {
+		if closed, _ := copyFrames(client, upstreamReader); !closed {
+			_, _ = client.Write(closeFrame(closeGoingAway, false))
+		}
+		done <- struct{}{}
+	}()
+	<-done
+	_ = client.Close()
+	_ = upstream.Close()
+	<-done
+}
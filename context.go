@@ -1,8 +1,11 @@
 package flux
 
 import (
+	"bufio"
 	"context"
 	"io"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/url"
 	"time"
@@ -16,6 +19,8 @@ const (
 	XJwtSubject   = "X-Jwt-Subject"
 	XJwtIssuer    = "X-Jwt-Issuer"
 	XJwtToken     = "X-Jwt-Token"
+	XClientIp     = "X-Client-Ip"
+	TraceParent   = "traceparent"
 )
 
 // Request 定义请求参数读取接口
@@ -179,6 +184,56 @@ type Context interface {
 	GetContextLogger() (Logger, bool)
 }
 
+// Context的能力接口。Context核心接口保持稳定，新增能力以独立接口定义，由Context实现按需支持；
+// 使用方通过类型断言发现能力，例如：
+//
+//	if sc, ok := ctx.(flux.StreamingContext); ok { ... }
+type (
+	// StreamingContext 支持以流的方式直接写入响应
+	StreamingContext interface {
+		Context
+		// WriteStream 直接写入响应状态码和流数据；写入后ResponseWriter中的响应数据被忽略
+		WriteStream(statusCode int, contentType string, reader io.Reader) error
+	}
+
+	// MultipartContext 支持读取multipart/form-data请求
+	MultipartContext interface {
+		Context
+		// MultipartForm 解析multipart表单；超出maxMemory的文件部分保存到临时文件
+		MultipartForm(maxMemory int64) (*multipart.Form, error)
+	}
+
+	// UpgradeContext 支持协议升级（例如WebSocket）
+	UpgradeContext interface {
+		Context
+		// Upgrade 接管客户端连接；接管后ResponseWriter中的响应数据被忽略，由调用方写入响应并关闭连接
+		Upgrade() (net.Conn, *bufio.ReadWriter, error)
+	}
+
+	// DetachableContext 支持复制请求数据，创建不关联客户端连接的Context，可在请求结束后继续使用
+	DetachableContext interface {
+		Context
+		// Detach 复制请求数据；使用结束后调用 release 释放复制的Context
+		Detach() (detached Context, release func(), err error)
+	}
+
+	// ClientIPContext 支持读取客户端IP
+	ClientIPContext interface {
+		Context
+		// ClientIP 返回客户端IP；仅经由可信代理时才解析X-Forwarded-For
+		ClientIP() string
+	}
+
+	// TracingContext 支持读取分布式追踪信息（W3C Trace Context）
+	TracingContext interface {
+		Context
+		// TraceId 返回追踪ID；请求未携带traceparent时，返回RequestId
+		TraceId() string
+		// ParentSpanId 返回上游Span ID；请求未携带traceparent时，返回空字符串
+		ParentSpanId() string
+	}
+)
+
 // Metrics 请求路由的的统计数据
 type Metric struct {
 	Name    string        `json:"name"`
@@ -0,0 +1,15 @@
+package flux
+
+import "context"
+
+// CredentialProvider 用户凭证验证接口；用于BasicAuth等基于用户名/密码的认证方式
+type CredentialProvider interface {
+	// Authenticate 验证用户名和密码；凭证无效时返回false，验证过程发生错误时返回error
+	Authenticate(ctx context.Context, username, password string) (ok bool, err error)
+}
+
+// SubjectGroupsResolver 查询主体所属用户组的接口；用于权限验证时解析Subject的组成员关系
+type SubjectGroupsResolver interface {
+	// ResolveGroups 返回Subject所属的用户组列表
+	ResolveGroups(ctx context.Context, subject string) (groups []string, err error)
+}
@@ -57,10 +57,20 @@ const (
 
 // Support protocols
 const (
-	ProtoDubbo = "DUBBO"
-	ProtoGRPC  = "GRPC"
-	ProtoHttp  = "HTTP"
-	ProtoEcho  = "ECHO"
+	ProtoDubbo     = "DUBBO"
+	ProtoGRPC      = "GRPC"
+	ProtoHttp      = "HTTP"
+	ProtoEcho      = "ECHO"
+	ProtoGraphQL   = "GRAPHQL"
+	ProtoWebSocket = "WEBSOCKET"
+	ProtoKafka     = "KAFKA"
+	ProtoAmqp      = "AMQP"
+	ProtoNats      = "NATS"
+	ProtoJsonRpc   = "JSONRPC"
+	ProtoSoap      = "SOAP"
+	ProtoTcp       = "TCP"
+	ProtoMock      = "MOCK"
+	ProtoCompose   = "COMPOSE"
 )
 
 // ServiceAttributes
@@ -11,7 +11,7 @@ func TestParseJsonTextToEndpoint(t *testing.T) {
     "application":"testapp",
 		"authorize":true,
     "service": {
-			"protocol":"DUBBO",
+			"rpcProto":"DUBBO",
 			"group":"myg",
 			"version":"1.0.0",
 			"retries":0,
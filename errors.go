@@ -7,22 +7,82 @@ const (
 	ErrorCodeGatewayBackend   = "GATEWAY:BACKEND"
 	ErrorCodeGatewayEndpoint  = "GATEWAY:ENDPOINT"
 	ErrorCodeGatewayCircuited = "GATEWAY:CIRCUITED"
+	ErrorCodeGatewayReadOnly  = "GATEWAY:READ_ONLY"
+	ErrorCodeGatewayOverload  = "GATEWAY:OVERLOAD"
+	ErrorCodeGatewayTimeout   = "GATEWAY:TIMEOUT"
 	ErrorCodeRequestInvalid   = "REQUEST:INVALID"
 	ErrorCodeRequestNotFound  = "REQUEST:NOT_FOUND"
 	ErrorCodePermissionDenied = "PERMISSION:ACCESS_DENIED"
 )
 
+const (
+	// 错误分类：请求端错误，例如参数无效、权限不足
+	ErrorCategoryClient = "client"
+	// 错误分类：上游服务错误，例如调用失败、超时、上游返回错误响应
+	ErrorCategoryUpstream = "upstream"
+	// 错误分类：网关内部错误，例如配置错误、熔断、限流
+	ErrorCategoryGateway = "gateway"
+)
+
 const (
 	ErrorMessageBackendDecodeResponse  = "BACKEND:DECODE_RESPONSE"
 	ErrorMessageBackendDecoderNotFound = "BACKEND:DECODER:NOT_FOUND"
+	ErrorMessageBackendHedgeInvoke     = "BACKEND:HEDGE:INVOKE"
+	ErrorMessageBackendInvokeTimeout   = "BACKEND:INVOKE:TIMEOUT"
+	ErrorMessageBackendCircuitOpen     = "BACKEND:CIRCUIT:OPEN"
+
+	ErrorMessageUpstreamNotFound        = "BACKEND:UPSTREAM:NOT_FOUND"
+	ErrorMessageUpstreamBalancerInvalid = "BACKEND:UPSTREAM:BALANCER_INVALID"
+	ErrorMessageUpstreamUnavailable     = "BACKEND:UPSTREAM:UNAVAILABLE"
 
 	ErrorMessageDubboInvokeFailed        = "BACKEND:DU:INVOKE"
 	ErrorMessageDubboAssembleFailed      = "BACKEND:DU:ASSEMBLE"
 	ErrorMessageDubboDecodeInvalidHeader = "BACKEND:DU:DECODE:INVALID_HEADERS"
 	ErrorMessageDubboDecodeInvalidStatus = "BACKEND:DU:DECODE:INVALID_STATUS"
+	ErrorMessageDubboBusinessException   = "BACKEND:DU:EXCEPTION"
 
 	ErrorMessageHttpInvokeFailed   = "BACKEND:HT:INVOKE"
 	ErrorMessageHttpAssembleFailed = "BACKEND:HT:ASSEMBLE"
+	ErrorMessageHttpUpstreamStatus = "BACKEND:HT:UPSTREAM_STATUS"
+	ErrorMessageHttpRequestFraming = "BACKEND:HT:REQUEST_FRAMING"
+
+	ErrorMessageGrpcInvokeFailed   = "BACKEND:GR:INVOKE"
+	ErrorMessageGrpcAssembleFailed = "BACKEND:GR:ASSEMBLE"
+	ErrorMessageGrpcMethodNotFound = "BACKEND:GR:METHOD_NOT_FOUND"
+
+	ErrorMessageGraphQLInvokeFailed   = "BACKEND:GQ:INVOKE"
+	ErrorMessageGraphQLAssembleFailed = "BACKEND:GQ:ASSEMBLE"
+
+	ErrorMessageWebSocketUpgradeRequired = "BACKEND:WS:UPGRADE_REQUIRED"
+	ErrorMessageWebSocketUpgradeFailed   = "BACKEND:WS:UPGRADE"
+	ErrorMessageWebSocketHandshakeFailed = "BACKEND:WS:HANDSHAKE"
+
+	ErrorMessageKafkaProduceFailed  = "BACKEND:KF:PRODUCE"
+	ErrorMessageKafkaAssembleFailed = "BACKEND:KF:ASSEMBLE"
+
+	ErrorMessageAmqpPublishFailed  = "BACKEND:AM:PUBLISH"
+	ErrorMessageAmqpAssembleFailed = "BACKEND:AM:ASSEMBLE"
+
+	ErrorMessageNatsInvokeFailed   = "BACKEND:NA:INVOKE"
+	ErrorMessageNatsAssembleFailed = "BACKEND:NA:ASSEMBLE"
+
+	ErrorMessageJsonRpcInvokeFailed   = "BACKEND:JR:INVOKE"
+	ErrorMessageJsonRpcAssembleFailed = "BACKEND:JR:ASSEMBLE"
+	ErrorMessageJsonRpcError          = "BACKEND:JR:ERROR"
+
+	ErrorMessageSoapInvokeFailed   = "BACKEND:SO:INVOKE"
+	ErrorMessageSoapAssembleFailed = "BACKEND:SO:ASSEMBLE"
+	ErrorMessageSoapFault          = "BACKEND:SO:FAULT"
+
+	ErrorMessageTcpInvokeFailed   = "BACKEND:TC:INVOKE"
+	ErrorMessageTcpAssembleFailed = "BACKEND:TC:ASSEMBLE"
+
+	ErrorMessageMockAssembleFailed = "BACKEND:MK:ASSEMBLE"
+	ErrorMessageMockTemplateFailed = "BACKEND:MK:TEMPLATE"
+
+	ErrorMessageComposeSpecInvalid     = "BACKEND:CP:SPEC_INVALID"
+	ErrorMessageComposeStepFailed      = "BACKEND:CP:STEP_FAILED"
+	ErrorMessageComposeServiceNotFound = "BACKEND:CP:SERVICE_NOT_FOUND"
 
 	ErrorMessageHystrixCircuited = "HYSTRIX:CIRCUITED"
 
@@ -30,12 +90,62 @@ const (
 	ErrorMessagePermissionServiceNotFound = "PERMISSION:SERVICE:NOT_FOUND"
 	ErrorMessagePermissionVerifyError     = "PERMISSION:VERIFY:ERROR"
 
-	ErrorMessageEndpointVersionNotFound  = "ENDPOINT:VERSION:NOT_FOUND"
-	ErrorMessageWebServerResponseMarshal = "SERVER:RESPONSE:MARSHAL"
-	ErrorMessageWebServerRequestNotFound = "SERVER:REQUEST:NOT_FOUND"
+	ErrorMessageCryptoDecryptFailed = "CRYPTO:DECRYPT:FAILED"
+	ErrorMessageCryptoEncryptFailed = "CRYPTO:ENCRYPT:FAILED"
+
+	ErrorMessageSignatureVerifyFailed = "SIGNATURE:VERIFY:FAILED"
+	ErrorMessageSignatureSignFailed   = "SIGNATURE:SIGN:FAILED"
+
+	ErrorMessageTokenExchangeFailed   = "TOKEN:EXCHANGE:FAILED"
+	ErrorMessageTokenExchangeRejected = "TOKEN:EXCHANGE:REJECTED"
+
+	ErrorMessageSamlAssertionInvalid = "SAML:ASSERTION:INVALID"
+
+	ErrorMessageBasicAuthUnauthorized = "BASIC_AUTH:UNAUTHORIZED"
+
+	ErrorMessageLoginLocked = "LOGIN:LOCKED"
+
+	ErrorMessageConsentRequired = "CONSENT:REQUIRED"
+
+	ErrorMessageRateLimited = "RATE_LIMIT:EXCEEDED"
+
+	ErrorMessageArgumentEnumInvalid = "ARGUMENT:ENUM:INVALID"
+
+	ErrorMessageConcurrencyEndpointLimited = "CONCURRENCY:ENDPOINT:EXCEEDED"
+	ErrorMessageConcurrencyConsumerLimited = "CONCURRENCY:CONSUMER:EXCEEDED"
+
+	ErrorMessageGatewayReadOnly = "GATEWAY:READ_ONLY"
+
+	ErrorMessageDebugUpstreamInvalid    = "DEBUG_UPSTREAM:INVALID"
+	ErrorMessageDebugUpstreamNotAllowed = "DEBUG_UPSTREAM:NOT_ALLOWED"
+
+	ErrorMessageMemoryBudgetExceeded = "GATEWAY:MEMORY:BUDGET_EXCEEDED"
+	ErrorMessageRequestBodyTooLarge  = "REQUEST:BODY:TOO_LARGE"
+
+	ErrorMessageInvokeLimitOverflow = "GATEWAY:INVOKE_LIMIT:OVERFLOW"
+
+	ErrorMessageAsyncInvokeOverflow     = "GATEWAY:ASYNC_INVOKE:OVERFLOW"
+	ErrorMessageAsyncInvokeRequest      = "GATEWAY:ASYNC_INVOKE:REQUEST"
+	ErrorMessageAsyncInvokeFailed       = "GATEWAY:ASYNC_INVOKE:FAILED"
+	ErrorMessageAsyncInvokeTimeout      = "GATEWAY:ASYNC_INVOKE:TIMEOUT"
+	ErrorMessageAsyncInvocationNotFound = "GATEWAY:ASYNC_INVOCATION:NOT_FOUND"
+	ErrorMessageAsyncInvocationStore    = "GATEWAY:ASYNC_INVOCATION:STORE"
+	ErrorMessageAsyncCallbackInvalid    = "GATEWAY:ASYNC_CALLBACK:INVALID"
+	ErrorMessageAsyncCallbackNotAllowed = "GATEWAY:ASYNC_CALLBACK:NOT_ALLOWED"
+
+	ErrorMessageContentRouteServiceNotFound = "ROUTE:CONTENT:SERVICE_NOT_FOUND"
+	ErrorMessageShardServiceNotFound        = "ROUTE:SHARD:SERVICE_NOT_FOUND"
+
+	ErrorMessageEndpointVersionNotFound   = "ENDPOINT:VERSION:NOT_FOUND"
+	ErrorMessageWebServerResponseMarshal  = "SERVER:RESPONSE:MARSHAL"
+	ErrorMessageWebServerRequestNotFound  = "SERVER:REQUEST:NOT_FOUND"
+	ErrorMessageWebServerMethodNotAllowed = "SERVER:REQUEST:METHOD_NOT_ALLOWED"
 
-	ErrorMessageRequestPrepare = "REQUEST:BODY:PREPARE"
-	ErrorMessageRequestParsing = "REQUEST:BODY:PARSING"
+	ErrorMessageRequestPrepare          = "REQUEST:BODY:PREPARE"
+	ErrorMessageRequestParsing          = "REQUEST:BODY:PARSING"
+	ErrorMessageRequestUriTooLong       = "REQUEST:URI:TOO_LONG"
+	ErrorMessageRequestTooManyHeaders   = "REQUEST:HEADERS:TOO_MANY"
+	ErrorMessageRequestTooManyQueryArgs = "REQUEST:QUERY:TOO_MANY"
 )
 
 var (
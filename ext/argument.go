@@ -7,25 +7,37 @@ import (
 
 // 提供一种可扩展的参数查找实现。
 // 通过替换参数值查找函数，可以允许某些非规范Http参数系统的自定义参数值查找逻辑。
-var (
-	argumentValueLookupFunc  flux.ArgumentValueLookupFunc
-	argumentValueResolveFunc flux.ArgumentValueResolveFunc
-)
 
 func StoreArgumentValueLookupFunc(f flux.ArgumentValueLookupFunc) {
-	argumentValueLookupFunc = pkg.RequireNotNil(f, "ArgumentValueLookupFunc is nil").(flux.ArgumentValueLookupFunc)
+	defaultRegistry.StoreArgumentValueLookupFunc(f)
 }
 
 func LoadArgumentValueLookupFunc() flux.ArgumentValueLookupFunc {
-	return argumentValueLookupFunc
+	return defaultRegistry.LoadArgumentValueLookupFunc()
 }
 
 func StoreArgumentValueResolveFunc(f flux.ArgumentValueResolveFunc) {
-	argumentValueResolveFunc = pkg.RequireNotNil(f, "ArgumentValueResolveFunc is nil").(flux.ArgumentValueResolveFunc)
+	defaultRegistry.StoreArgumentValueResolveFunc(f)
 }
 
 func LoadArgumentValueResolveFunc() flux.ArgumentValueResolveFunc {
-	return argumentValueResolveFunc
+	return defaultRegistry.LoadArgumentValueResolveFunc()
+}
+
+func (r *Registry) StoreArgumentValueLookupFunc(f flux.ArgumentValueLookupFunc) {
+	r.argumentValueLookupFunc = pkg.RequireNotNil(f, "ArgumentValueLookupFunc is nil").(flux.ArgumentValueLookupFunc)
+}
+
+func (r *Registry) LoadArgumentValueLookupFunc() flux.ArgumentValueLookupFunc {
+	return r.argumentValueLookupFunc
+}
+
+func (r *Registry) StoreArgumentValueResolveFunc(f flux.ArgumentValueResolveFunc) {
+	r.argumentValueResolveFunc = pkg.RequireNotNil(f, "ArgumentValueResolveFunc is nil").(flux.ArgumentValueResolveFunc)
+}
+
+func (r *Registry) LoadArgumentValueResolveFunc() flux.ArgumentValueResolveFunc {
+	return r.argumentValueResolveFunc
 }
 
 //// 构建参数值对象工具函数
@@ -3,39 +3,128 @@ package ext
 import (
 	"github.com/bytepowered/flux"
 	"github.com/bytepowered/flux/pkg"
+	"mime"
+	"strings"
 )
 
-var (
-	protoBackendTransports   = make(map[string]flux.BackendTransport, 4)
-	protoBackendDecoderFuncs = make(map[string]flux.BackendTransportDecodeFunc, 4)
-)
+// backendDecoderKey 按协议和响应Content-Type（MediaType，不包含参数）注册的解码函数Key
+type backendDecoderKey struct {
+	protoName string
+	mediaType string
+}
 
 func StoreBackendTransport(protoName string, backend flux.BackendTransport) {
-	protoName = pkg.RequireNotEmpty(protoName, "protoName is empty")
-	protoBackendTransports[protoName] = pkg.RequireNotNil(backend, "BackendTransport is nil").(flux.BackendTransport)
+	defaultRegistry.StoreBackendTransport(protoName, backend)
 }
 
 func LoadBackendTransport(protoName string) (flux.BackendTransport, bool) {
+	return defaultRegistry.LoadBackendTransport(protoName)
+}
+
+func StoreBackendTransportDecodeFunc(protoName string, decoder flux.BackendTransportDecodeFunc) {
+	defaultRegistry.StoreBackendTransportDecodeFunc(protoName, decoder)
+}
+
+func LoadBackendTransportDecodeFunc(protoName string) (flux.BackendTransportDecodeFunc, bool) {
+	return defaultRegistry.LoadBackendTransportDecodeFunc(protoName)
+}
+
+// StoreBackendTransportMediaDecodeFunc 注册指定协议和响应Content-Type的解码函数；
+// mediaType 支持类型通配，例如 "text/*"
+func StoreBackendTransportMediaDecodeFunc(protoName, mediaType string, decoder flux.BackendTransportDecodeFunc) {
+	defaultRegistry.StoreBackendTransportMediaDecodeFunc(protoName, mediaType, decoder)
+}
+
+// LoadBackendTransportDecodeFuncOf 按协议和响应Content-Type查找解码函数：
+// 依次匹配 MediaType、类型通配（例如 "text/*"），未匹配时返回协议的默认解码函数
+func LoadBackendTransportDecodeFuncOf(protoName, contentType string) (flux.BackendTransportDecodeFunc, bool) {
+	return defaultRegistry.LoadBackendTransportDecodeFuncOf(protoName, contentType)
+}
+
+func LoadBackendTransports() map[string]flux.BackendTransport {
+	return defaultRegistry.LoadBackendTransports()
+}
+
+// StoreBackendInterceptor 添加后端服务调用拦截器；按添加顺序由外向内执行
+func StoreBackendInterceptor(interceptor flux.BackendInterceptor) {
+	defaultRegistry.StoreBackendInterceptor(interceptor)
+}
+
+// LoadBackendInterceptors 获取全部后端服务调用拦截器
+func LoadBackendInterceptors() []flux.BackendInterceptor {
+	return defaultRegistry.LoadBackendInterceptors()
+}
+
+func (r *Registry) StoreBackendTransport(protoName string, backend flux.BackendTransport) {
 	protoName = pkg.RequireNotEmpty(protoName, "protoName is empty")
-	backend, ok := protoBackendTransports[protoName]
+	r.protoBackendTransports[protoName] = pkg.RequireNotNil(backend, "BackendTransport is nil").(flux.BackendTransport)
+}
+
+func (r *Registry) LoadBackendTransport(protoName string) (flux.BackendTransport, bool) {
+	protoName = pkg.RequireNotEmpty(protoName, "protoName is empty")
+	backend, ok := r.protoBackendTransports[protoName]
 	return backend, ok
 }
 
-func StoreBackendTransportDecodeFunc(protoName string, decoder flux.BackendTransportDecodeFunc) {
+func (r *Registry) StoreBackendTransportDecodeFunc(protoName string, decoder flux.BackendTransportDecodeFunc) {
 	protoName = pkg.RequireNotEmpty(protoName, "protoName is empty")
-	protoBackendDecoderFuncs[protoName] = pkg.RequireNotNil(decoder, "BackendTransportDecodeFunc is nil").(flux.BackendTransportDecodeFunc)
+	r.protoBackendDecoderFuncs[protoName] = pkg.RequireNotNil(decoder, "BackendTransportDecodeFunc is nil").(flux.BackendTransportDecodeFunc)
 }
 
-func LoadBackendTransportDecodeFunc(protoName string) (flux.BackendTransportDecodeFunc, bool) {
+func (r *Registry) LoadBackendTransportDecodeFunc(protoName string) (flux.BackendTransportDecodeFunc, bool) {
 	protoName = pkg.RequireNotEmpty(protoName, "protoName is empty")
-	decoder, ok := protoBackendDecoderFuncs[protoName]
+	decoder, ok := r.protoBackendDecoderFuncs[protoName]
 	return decoder, ok
 }
 
-func LoadBackendTransports() map[string]flux.BackendTransport {
-	m := make(map[string]flux.BackendTransport, len(protoBackendTransports))
-	for p, e := range protoBackendTransports {
+func (r *Registry) StoreBackendTransportMediaDecodeFunc(protoName, mediaType string, decoder flux.BackendTransportDecodeFunc) {
+	protoName = pkg.RequireNotEmpty(protoName, "protoName is empty")
+	mediaType = pkg.RequireNotEmpty(mediaTypeOf(mediaType), "mediaType is empty")
+	r.mediaBackendDecoderFuncs[backendDecoderKey{protoName: protoName, mediaType: mediaType}] =
+		pkg.RequireNotNil(decoder, "BackendTransportDecodeFunc is nil").(flux.BackendTransportDecodeFunc)
+}
+
+func (r *Registry) LoadBackendTransportDecodeFuncOf(protoName, contentType string) (flux.BackendTransportDecodeFunc, bool) {
+	protoName = pkg.RequireNotEmpty(protoName, "protoName is empty")
+	if mediaType := mediaTypeOf(contentType); "" != mediaType && len(r.mediaBackendDecoderFuncs) > 0 {
+		if decoder, ok := r.mediaBackendDecoderFuncs[backendDecoderKey{protoName: protoName, mediaType: mediaType}]; ok {
+			return decoder, true
+		}
+		if i := strings.IndexByte(mediaType, '/'); i > 0 {
+			wildcard := mediaType[:i] + "/*"
+			if decoder, ok := r.mediaBackendDecoderFuncs[backendDecoderKey{protoName: protoName, mediaType: wildcard}]; ok {
+				return decoder, true
+			}
+		}
+	}
+	return r.LoadBackendTransportDecodeFunc(protoName)
+}
+
+// mediaTypeOf 返回Content-Type的MediaType，小写且不包含参数
+func mediaTypeOf(contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); nil == err {
+		return mediaType
+	}
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+func (r *Registry) LoadBackendTransports() map[string]flux.BackendTransport {
+	m := make(map[string]flux.BackendTransport, len(r.protoBackendTransports))
+	for p, e := range r.protoBackendTransports {
 		m[p] = e
 	}
 	return m
 }
+
+func (r *Registry) StoreBackendInterceptor(interceptor flux.BackendInterceptor) {
+	r.backendInterceptors = append(r.backendInterceptors, pkg.RequireNotNil(interceptor, "BackendInterceptor is nil").(flux.BackendInterceptor))
+}
+
+func (r *Registry) LoadBackendInterceptors() []flux.BackendInterceptor {
+	dst := make([]flux.BackendInterceptor, len(r.backendInterceptors))
+	copy(dst, r.backendInterceptors)
+	return dst
+}
@@ -0,0 +1,40 @@
+package ext
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/pkg"
+)
+
+// StoreCredentialProvider 注册指定ID的用户凭证验证接口
+func StoreCredentialProvider(id string, provider flux.CredentialProvider) {
+	defaultRegistry.StoreCredentialProvider(id, provider)
+}
+
+// LoadCredentialProvider 获取指定ID的用户凭证验证接口
+func LoadCredentialProvider(id string) (flux.CredentialProvider, bool) {
+	return defaultRegistry.LoadCredentialProvider(id)
+}
+
+// LoadCredentialProviders 获取全部用户凭证验证接口
+func LoadCredentialProviders() map[string]flux.CredentialProvider {
+	return defaultRegistry.LoadCredentialProviders()
+}
+
+func (r *Registry) StoreCredentialProvider(id string, provider flux.CredentialProvider) {
+	id = pkg.RequireNotEmpty(id, "CredentialProvider id is empty")
+	r.credentialProviders[id] = pkg.RequireNotNil(provider, "CredentialProvider is nil").(flux.CredentialProvider)
+}
+
+func (r *Registry) LoadCredentialProvider(id string) (flux.CredentialProvider, bool) {
+	id = pkg.RequireNotEmpty(id, "CredentialProvider id is empty")
+	provider, ok := r.credentialProviders[id]
+	return provider, ok
+}
+
+func (r *Registry) LoadCredentialProviders() map[string]flux.CredentialProvider {
+	m := make(map[string]flux.CredentialProvider, len(r.credentialProviders))
+	for id, p := range r.credentialProviders {
+		m[id] = p
+	}
+	return m
+}
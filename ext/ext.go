@@ -0,0 +1,156 @@
+package ext
+
+import (
+	"context"
+	"github.com/bytepowered/flux"
+	"sync"
+)
+
+var (
+	defaultRegistry = NewRegistry()
+)
+
+// Registry 扩展组件注册表；每个Registry实例相互隔离，可用于在同一进程内运行多个网关引擎。
+// ext包的全局函数均使用默认Registry实例。
+type Registry struct {
+	argumentValueLookupFunc         flux.ArgumentValueLookupFunc
+	argumentValueResolveFunc        flux.ArgumentValueResolveFunc
+	backendInterceptors             []flux.BackendInterceptor
+	protoBackendTransports          map[string]flux.BackendTransport
+	protoBackendDecoderFuncs        map[string]flux.BackendTransportDecodeFunc
+	mediaBackendDecoderFuncs        map[backendDecoderKey]flux.BackendTransportDecodeFunc
+	credentialProviders             map[string]flux.CredentialProvider
+	typedFactories                  map[string]flux.Factory
+	featureFlagProvider             flux.FeatureFlagProvider
+	globalFilter                    []filterWrapper
+	selectiveFilter                 []filterWrapper
+	hooksPrepare                    []flux.PrepareHookFunc
+	hooksStartup                    []flux.Startuper
+	hooksShutdown                   []flux.Shutdowner
+	hooksWarmup                     []flux.Warmer
+	loggerFactory                   flux.LoggerFactory
+	mediaTypeValueResolvers         map[string]flux.MTValueResolver
+	mediaTypeValueResolverFactories map[string]MTValueResolverFactory
+	pojoTypes                       map[string][]flux.POJOField
+	identityRegistryFactories       map[string]EndpointRegistryFactory
+	secretProvider                  flux.SecretProvider
+	hostedSelectors                 map[string][]flux.Selector
+	hostedSelectorLock              sync.RWMutex
+	typedSerializers                map[string]flux.Serializer
+	servicesMap                     *sync.Map
+	upstreamsMap                    *sync.Map
+	upstreamBalancerFactories       map[string]flux.UpstreamBalancerFactory
+	upstreamTLSConfigFunc           flux.UpstreamTLSConfigFunc
+	webServerFactory                WebServerFactory
+}
+
+// NewRegistry 创建空的扩展组件注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		protoBackendTransports:          make(map[string]flux.BackendTransport, 4),
+		protoBackendDecoderFuncs:        make(map[string]flux.BackendTransportDecodeFunc, 4),
+		mediaBackendDecoderFuncs:        make(map[backendDecoderKey]flux.BackendTransportDecodeFunc, 4),
+		credentialProviders:             make(map[string]flux.CredentialProvider, 4),
+		typedFactories:                  make(map[string]flux.Factory, 16),
+		globalFilter:                    make([]filterWrapper, 0, 16),
+		selectiveFilter:                 make([]filterWrapper, 0, 16),
+		backendInterceptors:             make([]flux.BackendInterceptor, 0, 4),
+		hooksPrepare:                    make([]flux.PrepareHookFunc, 0, 16),
+		hooksStartup:                    make([]flux.Startuper, 0, 16),
+		hooksShutdown:                   make([]flux.Shutdowner, 0, 16),
+		hooksWarmup:                     make([]flux.Warmer, 0, 16),
+		mediaTypeValueResolvers:         make(map[string]flux.MTValueResolver, 16),
+		mediaTypeValueResolverFactories: make(map[string]MTValueResolverFactory, 4),
+		pojoTypes:                       make(map[string][]flux.POJOField, 16),
+		identityRegistryFactories:       make(map[string]EndpointRegistryFactory, 2),
+		hostedSelectors:                 make(map[string][]flux.Selector, 16),
+		typedSerializers:                make(map[string]flux.Serializer, 2),
+		servicesMap:                     new(sync.Map),
+		upstreamsMap:                    new(sync.Map),
+		upstreamBalancerFactories:       make(map[string]flux.UpstreamBalancerFactory, 4),
+	}
+}
+
+// Default 返回ext包全局函数使用的默认注册表
+func Default() *Registry {
+	return defaultRegistry
+}
+
+type registryContextKey struct{}
+
+// WithRegistry 返回绑定扩展组件注册表的Context；网关引擎为每个请求绑定引擎的注册表
+func WithRegistry(ctx context.Context, r *Registry) context.Context {
+	return context.WithValue(ctx, registryContextKey{}, r)
+}
+
+// RegistryOf 返回Context绑定的扩展组件注册表；未绑定时返回默认注册表。
+// 请求处理过程中加载扩展组件应使用 RegistryOf(ctx.Context())，而不是ext包的全局函数
+func RegistryOf(ctx context.Context) *Registry {
+	if nil != ctx {
+		if r, ok := ctx.Value(registryContextKey{}).(*Registry); ok && nil != r {
+			return r
+		}
+	}
+	return defaultRegistry
+}
+
+// Clone 复制注册表中已注册的扩展组件，返回新的注册表；
+// 通常用于基于默认注册表（已包含内置组件）创建隔离的网关引擎。BackendService和Upstream不会被复制；
+// 通过 MTValueResolverFactory 注册的值类型解析函数绑定新的注册表。
+func (r *Registry) Clone() *Registry {
+	out := NewRegistry()
+	out.argumentValueLookupFunc = r.argumentValueLookupFunc
+	out.argumentValueResolveFunc = r.argumentValueResolveFunc
+	out.featureFlagProvider = r.featureFlagProvider
+	out.loggerFactory = r.loggerFactory
+	out.secretProvider = r.secretProvider
+	out.webServerFactory = r.webServerFactory
+	out.upstreamTLSConfigFunc = r.upstreamTLSConfigFunc
+	for k, v := range r.protoBackendTransports {
+		out.protoBackendTransports[k] = v
+	}
+	for k, v := range r.protoBackendDecoderFuncs {
+		out.protoBackendDecoderFuncs[k] = v
+	}
+	for k, v := range r.mediaBackendDecoderFuncs {
+		out.mediaBackendDecoderFuncs[k] = v
+	}
+	for k, v := range r.credentialProviders {
+		out.credentialProviders[k] = v
+	}
+	for k, v := range r.typedFactories {
+		out.typedFactories[k] = v
+	}
+	for k, v := range r.mediaTypeValueResolvers {
+		out.mediaTypeValueResolvers[k] = v
+	}
+	for k, f := range r.mediaTypeValueResolverFactories {
+		out.mediaTypeValueResolvers[k] = f(out)
+		out.mediaTypeValueResolverFactories[k] = f
+	}
+	for k, v := range r.pojoTypes {
+		out.pojoTypes[k] = v
+	}
+	for k, v := range r.identityRegistryFactories {
+		out.identityRegistryFactories[k] = v
+	}
+	for k, v := range r.typedSerializers {
+		out.typedSerializers[k] = v
+	}
+	for k, v := range r.upstreamBalancerFactories {
+		out.upstreamBalancerFactories[k] = v
+	}
+	out.globalFilter = append(out.globalFilter, r.globalFilter...)
+	out.selectiveFilter = append(out.selectiveFilter, r.selectiveFilter...)
+	out.backendInterceptors = append(out.backendInterceptors, r.backendInterceptors...)
+	out.hooksPrepare = append(out.hooksPrepare, r.hooksPrepare...)
+	out.hooksStartup = append(out.hooksStartup, r.hooksStartup...)
+	out.hooksShutdown = append(out.hooksShutdown, r.hooksShutdown...)
+	out.hooksWarmup = append(out.hooksWarmup, r.hooksWarmup...)
+	r.hostedSelectorLock.RLock()
+	for k, v := range r.hostedSelectors {
+		out.hostedSelectors[k] = _newSelectors(v)
+	}
+	r.hostedSelectorLock.RUnlock()
+	return out
+}
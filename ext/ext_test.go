@@ -0,0 +1,99 @@
+package ext
+
+import (
+	"context"
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestRegistry_Isolated(t *testing.T) {
+	assert := assert2.New(t)
+	r1 := NewRegistry()
+	r2 := NewRegistry()
+	r1.StoreGlobalFilter(&TestFilter{id: "a"})
+	r1.StoreBackendService(flux.BackendService{ServiceId: "svc:a"})
+	assert.Equal(1, len(r1.LoadGlobalFilters()))
+	assert.Equal(0, len(r2.LoadGlobalFilters()))
+	assert.True(r1.HasBackendService("svc:a"))
+	assert.False(r2.HasBackendService("svc:a"))
+}
+
+func TestRegistry_Clone(t *testing.T) {
+	assert := assert2.New(t)
+	src := NewRegistry()
+	src.StoreSelectiveFilter(&TestFilter{id: "a"})
+	src.StoreBackendService(flux.BackendService{ServiceId: "svc:a"})
+	clone := src.Clone()
+	clone.StoreSelectiveFilter(&TestFilter{id: "b"})
+	_, ok := clone.LoadSelectiveFilter("a")
+	assert.True(ok)
+	_, ok = src.LoadSelectiveFilter("b")
+	assert.False(ok, "clone must not affect source")
+	assert.False(clone.HasBackendService("svc:a"), "services are not cloned")
+}
+
+func TestRegistry_LoadBackendTransportDecodeFuncOf(t *testing.T) {
+	assert := assert2.New(t)
+	r := NewRegistry()
+	newDecoder := func(name string) flux.BackendTransportDecodeFunc {
+		return func(flux.Context, interface{}) (int, http.Header, interface{}, error) {
+			return 200, nil, name, nil
+		}
+	}
+	r.StoreBackendTransportDecodeFunc(flux.ProtoHttp, newDecoder("default"))
+	r.StoreBackendTransportMediaDecodeFunc(flux.ProtoHttp, "application/xml", newDecoder("xml"))
+	r.StoreBackendTransportMediaDecodeFunc(flux.ProtoHttp, "text/*", newDecoder("text"))
+	cases := []struct {
+		proto       string
+		contentType string
+		expected    string
+	}{
+		{proto: flux.ProtoHttp, contentType: "application/xml; charset=UTF-8", expected: "xml"},
+		{proto: flux.ProtoHttp, contentType: "Application/XML", expected: "xml"},
+		{proto: flux.ProtoHttp, contentType: "text/plain", expected: "text"},
+		{proto: flux.ProtoHttp, contentType: "application/json", expected: "default"},
+		{proto: flux.ProtoHttp, contentType: "", expected: "default"},
+		{proto: flux.ProtoGRPC, contentType: "application/xml", expected: ""},
+	}
+	for _, tc := range cases {
+		decoder, ok := r.LoadBackendTransportDecodeFuncOf(tc.proto, tc.contentType)
+		if "" == tc.expected {
+			assert.False(ok)
+			continue
+		}
+		assert.True(ok)
+		_, _, body, _ := decoder(nil, nil)
+		assert.Equal(tc.expected, body, "content-type: %s", tc.contentType)
+	}
+}
+
+func TestRegistryOf(t *testing.T) {
+	assert := assert2.New(t)
+	r := NewRegistry()
+	assert.True(Default() == RegistryOf(nil))
+	assert.True(Default() == RegistryOf(context.Background()))
+	assert.True(r == RegistryOf(WithRegistry(context.Background(), r)))
+}
+
+func TestRegistry_CloneResolverFactory(t *testing.T) {
+	assert := assert2.New(t)
+	src := NewRegistry()
+	src.RegisterMTValueResolverFactory("Bound", func(r *Registry) flux.MTValueResolver {
+		return func(flux.MTValue, string, []string) (interface{}, error) {
+			return r, nil
+		}
+	})
+	clone := src.Clone()
+	bound, _ := src.LoadMTValueResolver("bound")(flux.MTValue{}, "", nil)
+	assert.True(src == bound)
+	bound, _ = clone.LoadMTValueResolver("bound")(flux.MTValue{}, "", nil)
+	assert.True(clone == bound, "clone must rebind resolver to the cloned registry")
+	// 覆盖为普通解析函数后，复制时不再重新创建
+	clone.RegisterMTValueResolver("bound", func(flux.MTValue, string, []string) (interface{}, error) {
+		return "plain", nil
+	})
+	bound, _ = clone.Clone().LoadMTValueResolver("bound")(flux.MTValue{}, "", nil)
+	assert.Equal("plain", bound)
+}
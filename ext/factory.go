@@ -5,17 +5,21 @@ import (
 	"github.com/bytepowered/flux/pkg"
 )
 
-var (
-	typedFactories = make(map[string]flux.Factory, 16)
-)
-
 func StoreTypedFactory(typeName string, factory flux.Factory) {
-	typeName = pkg.RequireNotEmpty(typeName, "typeName is empty")
-	typedFactories[typeName] = pkg.RequireNotNil(factory, "Factory is nil").(flux.Factory)
+	defaultRegistry.StoreTypedFactory(typeName, factory)
 }
 
 func LoadTypedFactory(typeName string) (flux.Factory, bool) {
+	return defaultRegistry.LoadTypedFactory(typeName)
+}
+
+func (r *Registry) StoreTypedFactory(typeName string, factory flux.Factory) {
+	typeName = pkg.RequireNotEmpty(typeName, "typeName is empty")
+	r.typedFactories[typeName] = pkg.RequireNotNil(factory, "Factory is nil").(flux.Factory)
+}
+
+func (r *Registry) LoadTypedFactory(typeName string) (flux.Factory, bool) {
 	typeName = pkg.RequireNotEmpty(typeName, "typeName is empty")
-	f, o := typedFactories[typeName]
+	f, o := r.typedFactories[typeName]
 	return f, o
 }
@@ -0,0 +1,24 @@
+package ext
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/pkg"
+)
+
+// StoreFeatureFlagProvider 设置全局特性开关接口
+func StoreFeatureFlagProvider(p flux.FeatureFlagProvider) {
+	defaultRegistry.StoreFeatureFlagProvider(p)
+}
+
+// LoadFeatureFlagProvider 获取全局特性开关接口
+func LoadFeatureFlagProvider() flux.FeatureFlagProvider {
+	return defaultRegistry.LoadFeatureFlagProvider()
+}
+
+func (r *Registry) StoreFeatureFlagProvider(p flux.FeatureFlagProvider) {
+	r.featureFlagProvider = pkg.RequireNotNil(p, "FeatureFlagProvider is nil").(flux.FeatureFlagProvider)
+}
+
+func (r *Registry) LoadFeatureFlagProvider() flux.FeatureFlagProvider {
+	return r.featureFlagProvider
+}
@@ -17,50 +17,52 @@ func (s filterArray) Len() int           { return len(s) }
 func (s filterArray) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 func (s filterArray) Less(i, j int) bool { return s[i].order < s[j].order }
 
-var (
-	globalFilter    = make([]filterWrapper, 0, 16)
-	selectiveFilter = make([]filterWrapper, 0, 16)
-)
-
 // StoreGlobalFilter 注册全局Filter；
 func StoreGlobalFilter(v interface{}) {
-	globalFilter = _checkedAppendFilter(v, globalFilter)
-	sort.Sort(filterArray(globalFilter))
+	defaultRegistry.StoreGlobalFilter(v)
 }
 
 // StoreSelectiveFilter 注册可选Filter；
 func StoreSelectiveFilter(v interface{}) {
-	selectiveFilter = _checkedAppendFilter(v, selectiveFilter)
-	sort.Sort(filterArray(selectiveFilter))
-}
-
-func _checkedAppendFilter(v interface{}, in []filterWrapper) (out []filterWrapper) {
-	f := pkg.RequireNotNil(v, "Not a valid Filter").(flux.Filter)
-	return append(in, filterWrapper{filter: f, order: orderOf(v)})
+	defaultRegistry.StoreSelectiveFilter(v)
 }
 
 // LoadSelectiveFilters 获取已排序的Filter列表
 func LoadSelectiveFilters() []flux.Filter {
-	return getFilters(selectiveFilter)
+	return defaultRegistry.LoadSelectiveFilters()
 }
 
 // LoadGlobalFilters 获取已排序的全局Filter列表
 func LoadGlobalFilters() []flux.Filter {
-	return getFilters(globalFilter)
-}
-
-func getFilters(in []filterWrapper) []flux.Filter {
-	out := make([]flux.Filter, len(in))
-	for i, v := range in {
-		out[i] = v.filter
-	}
-	return out
+	return defaultRegistry.LoadGlobalFilters()
 }
 
 // LoadSelectiveFilter 获取已排序的可选Filter列表
 func LoadSelectiveFilter(filterId string) (flux.Filter, bool) {
+	return defaultRegistry.LoadSelectiveFilter(filterId)
+}
+
+func (r *Registry) StoreGlobalFilter(v interface{}) {
+	r.globalFilter = _checkedAppendFilter(v, r.globalFilter)
+	sort.Sort(filterArray(r.globalFilter))
+}
+
+func (r *Registry) StoreSelectiveFilter(v interface{}) {
+	r.selectiveFilter = _checkedAppendFilter(v, r.selectiveFilter)
+	sort.Sort(filterArray(r.selectiveFilter))
+}
+
+func (r *Registry) LoadSelectiveFilters() []flux.Filter {
+	return getFilters(r.selectiveFilter)
+}
+
+func (r *Registry) LoadGlobalFilters() []flux.Filter {
+	return getFilters(r.globalFilter)
+}
+
+func (r *Registry) LoadSelectiveFilter(filterId string) (flux.Filter, bool) {
 	filterId = pkg.RequireNotEmpty(filterId, "filterId is empty")
-	for _, f := range selectiveFilter {
+	for _, f := range r.selectiveFilter {
 		if filterId == f.filter.TypeId() {
 			return f.filter, true
 		}
@@ -68,6 +70,19 @@ func LoadSelectiveFilter(filterId string) (flux.Filter, bool) {
 	return nil, false
 }
 
+func _checkedAppendFilter(v interface{}, in []filterWrapper) (out []filterWrapper) {
+	f := pkg.RequireNotNil(v, "Not a valid Filter").(flux.Filter)
+	return append(in, filterWrapper{filter: f, order: orderOf(v)})
+}
+
+func getFilters(in []filterWrapper) []flux.Filter {
+	out := make([]flux.Filter, len(in))
+	for i, v := range in {
+		out[i] = v.filter
+	}
+	return out
+}
+
 func orderOf(v interface{}) int {
 	if v, ok := v.(flux.Orderer); ok {
 		return v.Order()
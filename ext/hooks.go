@@ -5,42 +5,69 @@ import (
 	"github.com/bytepowered/flux/pkg"
 )
 
-var (
-	hooksPrepare  = make([]flux.PrepareHookFunc, 0, 16)
-	hooksStartup  = make([]flux.Startuper, 0, 16)
-	hooksShutdown = make([]flux.Shutdowner, 0, 16)
-)
-
-// StoreHookFunc 添加生命周期启动与停止的钩子接口
+// StoreHookFunc 添加生命周期启动、预热与停止的钩子接口
 func StoreHookFunc(hook interface{}) {
+	defaultRegistry.StoreHookFunc(hook)
+}
+
+// StorePrepareHook 添加预备阶段钩子函数
+func StorePrepareHook(pf flux.PrepareHookFunc) {
+	defaultRegistry.StorePrepareHook(pf)
+}
+
+func LoadPrepareHooks() []flux.PrepareHookFunc {
+	return defaultRegistry.LoadPrepareHooks()
+}
+
+func LoadStartupHooks() []flux.Startuper {
+	return defaultRegistry.LoadStartupHooks()
+}
+
+func LoadShutdownHooks() []flux.Shutdowner {
+	return defaultRegistry.LoadShutdownHooks()
+}
+
+func LoadWarmupHooks() []flux.Warmer {
+	return defaultRegistry.LoadWarmupHooks()
+}
+
+func (r *Registry) StoreHookFunc(hook interface{}) {
 	pkg.RequireNotNil(hook, "Hook is nil")
 	if startup, ok := hook.(flux.Startuper); ok {
-		hooksStartup = append(hooksStartup, startup)
+		r.hooksStartup = append(r.hooksStartup, startup)
 	}
 	if shutdown, ok := hook.(flux.Shutdowner); ok {
-		hooksShutdown = append(hooksShutdown, shutdown)
+		r.hooksShutdown = append(r.hooksShutdown, shutdown)
+	}
+	if warmup, ok := hook.(flux.Warmer); ok {
+		r.hooksWarmup = append(r.hooksWarmup, warmup)
 	}
 }
 
-// StorePrepareHook 添加预备阶段钩子函数
-func StorePrepareHook(pf flux.PrepareHookFunc) {
-	hooksPrepare = append(hooksPrepare, pkg.RequireNotNil(pf, "PrepareHookFunc is nil").(flux.PrepareHookFunc))
+func (r *Registry) StorePrepareHook(pf flux.PrepareHookFunc) {
+	r.hooksPrepare = append(r.hooksPrepare, pkg.RequireNotNil(pf, "PrepareHookFunc is nil").(flux.PrepareHookFunc))
 }
 
-func LoadPrepareHooks() []flux.PrepareHookFunc {
-	dst := make([]flux.PrepareHookFunc, len(hooksPrepare))
-	copy(dst, hooksPrepare)
+func (r *Registry) LoadPrepareHooks() []flux.PrepareHookFunc {
+	dst := make([]flux.PrepareHookFunc, len(r.hooksPrepare))
+	copy(dst, r.hooksPrepare)
 	return dst
 }
 
-func LoadStartupHooks() []flux.Startuper {
-	dst := make([]flux.Startuper, len(hooksStartup))
-	copy(dst, hooksStartup)
+func (r *Registry) LoadStartupHooks() []flux.Startuper {
+	dst := make([]flux.Startuper, len(r.hooksStartup))
+	copy(dst, r.hooksStartup)
 	return dst
 }
 
-func LoadShutdownHooks() []flux.Shutdowner {
-	dst := make([]flux.Shutdowner, len(hooksShutdown))
-	copy(dst, hooksShutdown)
+func (r *Registry) LoadShutdownHooks() []flux.Shutdowner {
+	dst := make([]flux.Shutdowner, len(r.hooksShutdown))
+	copy(dst, r.hooksShutdown)
+	return dst
+}
+
+func (r *Registry) LoadWarmupHooks() []flux.Warmer {
+	dst := make([]flux.Warmer, len(r.hooksWarmup))
+	copy(dst, r.hooksWarmup)
 	return dst
 }
@@ -6,20 +6,24 @@ import (
 	"github.com/bytepowered/flux/pkg"
 )
 
-var (
-	loggerFactory flux.LoggerFactory
-)
-
 func StoreLoggerFactory(f flux.LoggerFactory) {
-	loggerFactory = pkg.RequireNotNil(f, "LoggerFactory is nil").(flux.LoggerFactory)
+	defaultRegistry.StoreLoggerFactory(f)
 }
 
 // NewLoggerWith
 func NewLoggerWith(values context.Context) flux.Logger {
-	return loggerFactory(values)
+	return defaultRegistry.NewLoggerWith(values)
 }
 
 // NewLogger ...
 func NewLogger() flux.Logger {
 	return NewLoggerWith(context.TODO())
 }
+
+func (r *Registry) StoreLoggerFactory(f flux.LoggerFactory) {
+	r.loggerFactory = pkg.RequireNotNil(f, "LoggerFactory is nil").(flux.LoggerFactory)
+}
+
+func (r *Registry) NewLoggerWith(values context.Context) flux.Logger {
+	return r.loggerFactory(values)
+}
@@ -10,22 +10,26 @@ const (
 	EndpointRegistryIdZookeeper = "zookeeper"
 )
 
-var (
-	identityRegistryFactories = make(map[string]EndpointRegistryFactory, 2)
-)
-
 // EndpointRegistryFactory 用于构建EndpointRegistry的工厂函数。
 type EndpointRegistryFactory func() flux.EndpointRegistry
 
 // StoreEndpointRegistryFactory 设置指定ID名的EndpointRegistry工厂函数。
 func StoreEndpointRegistryFactory(id string, factory EndpointRegistryFactory) {
-	id = pkg.RequireNotEmpty(id, "factory id is empty")
-	identityRegistryFactories[id] = pkg.RequireNotNil(factory, "EndpointRegistryFactory is nil").(EndpointRegistryFactory)
+	defaultRegistry.StoreEndpointRegistryFactory(id, factory)
 }
 
 // LoadEndpointRegistryFactory 根据ID名，获取EndpointRegistry的工厂函数
 func LoadEndpointRegistryFactory(id string) (EndpointRegistryFactory, bool) {
+	return defaultRegistry.LoadEndpointRegistryFactory(id)
+}
+
+func (r *Registry) StoreEndpointRegistryFactory(id string, factory EndpointRegistryFactory) {
+	id = pkg.RequireNotEmpty(id, "factory id is empty")
+	r.identityRegistryFactories[id] = pkg.RequireNotNil(factory, "EndpointRegistryFactory is nil").(EndpointRegistryFactory)
+}
+
+func (r *Registry) LoadEndpointRegistryFactory(id string) (EndpointRegistryFactory, bool) {
 	id = pkg.RequireNotEmpty(id, "factory id is empty")
-	e, ok := identityRegistryFactories[id]
+	e, ok := r.identityRegistryFactories[id]
 	return e, ok
 }
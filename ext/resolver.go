@@ -10,25 +10,70 @@ const (
 	DefaultMTValueResolverName = "default"
 )
 
-var (
-	mediaTypeValueResolvers = make(map[string]flux.MTValueResolver, 16)
-)
+// MTValueResolverFactory 创建绑定注册表的值类型解析函数；用于需要加载注册表中POJO类型或其它值类型解析函数的解析函数。
+// 复制注册表时，使用新的注册表重新创建解析函数
+type MTValueResolverFactory func(r *Registry) flux.MTValueResolver
 
 // RegisterMTValueResolver 添加实际值类型解析函数
 func RegisterMTValueResolver(actualTypeName string, resolver flux.MTValueResolver) {
-	actualTypeName = pkg.RequireNotEmpty(actualTypeName, "actualTypeName is empty")
-	actualTypeName = strings.ToLower(actualTypeName)
-	mediaTypeValueResolvers[actualTypeName] = resolver
+	defaultRegistry.RegisterMTValueResolver(actualTypeName, resolver)
+}
+
+// RegisterMTValueResolverFactory 添加绑定注册表的实际值类型解析函数
+func RegisterMTValueResolverFactory(actualTypeName string, factory MTValueResolverFactory) {
+	defaultRegistry.RegisterMTValueResolverFactory(actualTypeName, factory)
 }
 
 // LoadMTValueResolver 获取值类型解析函数
 func LoadMTValueResolver(actualTypeName string) flux.MTValueResolver {
-	actualTypeName = pkg.RequireNotEmpty(actualTypeName, "actualTypeName is empty")
-	actualTypeName = strings.ToLower(actualTypeName)
-	return mediaTypeValueResolvers[actualTypeName]
+	return defaultRegistry.LoadMTValueResolver(actualTypeName)
 }
 
 // LoadMTValueDefaultResolver 获取默认的值类型解析函数
 func LoadMTValueDefaultResolver() flux.MTValueResolver {
-	return mediaTypeValueResolvers[DefaultMTValueResolverName]
+	return defaultRegistry.LoadMTValueDefaultResolver()
+}
+
+// RegisterPOJOType 注册用户POJO类型的字段定义；参数类型为该POJO类型时，按字段类型递归解析为Map
+func RegisterPOJOType(class string, fields []flux.POJOField) {
+	defaultRegistry.RegisterPOJOType(class, fields)
+}
+
+// LoadPOJOType 获取用户POJO类型的字段定义
+func LoadPOJOType(class string) ([]flux.POJOField, bool) {
+	return defaultRegistry.LoadPOJOType(class)
+}
+
+func (r *Registry) RegisterMTValueResolver(actualTypeName string, resolver flux.MTValueResolver) {
+	actualTypeName = pkg.RequireNotEmpty(actualTypeName, "actualTypeName is empty")
+	actualTypeName = strings.ToLower(actualTypeName)
+	r.mediaTypeValueResolvers[actualTypeName] = resolver
+	delete(r.mediaTypeValueResolverFactories, actualTypeName)
+}
+
+func (r *Registry) RegisterMTValueResolverFactory(actualTypeName string, factory MTValueResolverFactory) {
+	actualTypeName = pkg.RequireNotEmpty(actualTypeName, "actualTypeName is empty")
+	actualTypeName = strings.ToLower(actualTypeName)
+	r.mediaTypeValueResolvers[actualTypeName] = factory(r)
+	r.mediaTypeValueResolverFactories[actualTypeName] = factory
+}
+
+func (r *Registry) LoadMTValueResolver(actualTypeName string) flux.MTValueResolver {
+	actualTypeName = pkg.RequireNotEmpty(actualTypeName, "actualTypeName is empty")
+	actualTypeName = strings.ToLower(actualTypeName)
+	return r.mediaTypeValueResolvers[actualTypeName]
+}
+
+func (r *Registry) LoadMTValueDefaultResolver() flux.MTValueResolver {
+	return r.mediaTypeValueResolvers[DefaultMTValueResolverName]
+}
+
+func (r *Registry) RegisterPOJOType(class string, fields []flux.POJOField) {
+	class = pkg.RequireNotEmpty(class, "class is empty")
+	r.pojoTypes[class] = fields
+}
+
+func (r *Registry) LoadPOJOType(class string) ([]flux.POJOField, bool) {
+	fields, ok := r.pojoTypes[class]
+	return fields, ok
 }
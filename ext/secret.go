@@ -0,0 +1,24 @@
+package ext
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/pkg"
+)
+
+// StoreSecretProvider 设置全局密钥提供接口
+func StoreSecretProvider(p flux.SecretProvider) {
+	defaultRegistry.StoreSecretProvider(p)
+}
+
+// LoadSecretProvider 获取全局密钥提供接口
+func LoadSecretProvider() flux.SecretProvider {
+	return defaultRegistry.LoadSecretProvider()
+}
+
+func (r *Registry) StoreSecretProvider(p flux.SecretProvider) {
+	r.secretProvider = pkg.RequireNotNil(p, "SecretProvider is nil").(flux.SecretProvider)
+}
+
+func (r *Registry) LoadSecretProvider() flux.SecretProvider {
+	return r.secretProvider
+}
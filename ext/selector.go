@@ -3,43 +3,49 @@ package ext
 import (
 	"github.com/bytepowered/flux"
 	"github.com/bytepowered/flux/pkg"
-	"sync"
 )
 
 const (
 	anyHost = "*"
 )
 
-var (
-	hostedSelectors    = make(map[string][]flux.Selector, 16)
-	hostedSelectorLock sync.RWMutex
-)
-
 func StoreSelector(s flux.Selector) {
-	pkg.RequireNotNil(s, "Selector is nil")
-	StoreHostedSelector(anyHost, s)
+	defaultRegistry.StoreSelector(s)
 }
 
 func StoreHostedSelector(host string, s flux.Selector) {
+	defaultRegistry.StoreHostedSelector(host, s)
+}
+
+func FindSelectors(host string) []flux.Selector {
+	return defaultRegistry.FindSelectors(host)
+}
+
+func (r *Registry) StoreSelector(s flux.Selector) {
+	pkg.RequireNotNil(s, "Selector is nil")
+	r.StoreHostedSelector(anyHost, s)
+}
+
+func (r *Registry) StoreHostedSelector(host string, s flux.Selector) {
 	host = pkg.RequireNotEmpty(host, "host is empty")
 	pkg.RequireNotNil(s, "Selector is nil")
-	hostedSelectorLock.Lock()
-	defer hostedSelectorLock.Unlock()
-	if l, ok := hostedSelectors[host]; ok {
-		hostedSelectors[host] = append(l, s)
+	r.hostedSelectorLock.Lock()
+	defer r.hostedSelectorLock.Unlock()
+	if l, ok := r.hostedSelectors[host]; ok {
+		r.hostedSelectors[host] = append(l, s)
 	} else {
-		hostedSelectors[host] = []flux.Selector{s}
+		r.hostedSelectors[host] = []flux.Selector{s}
 	}
 }
 
-func FindSelectors(host string) []flux.Selector {
+func (r *Registry) FindSelectors(host string) []flux.Selector {
 	host = pkg.RequireNotEmpty(host, "host is empty")
-	hostedSelectorLock.RLock()
-	defer hostedSelectorLock.RUnlock()
-	if hosted, ok := hostedSelectors[host]; ok {
+	r.hostedSelectorLock.RLock()
+	defer r.hostedSelectorLock.RUnlock()
+	if hosted, ok := r.hostedSelectors[host]; ok {
 		return _newSelectors(hosted)
 	} else if anyHost != host {
-		if any, ok := hostedSelectors[anyHost]; ok {
+		if any, ok := r.hostedSelectors[anyHost]; ok {
 			return _newSelectors(any)
 		}
 	}
@@ -51,3 +57,14 @@ func _newSelectors(src []flux.Selector) []flux.Selector {
 	copy(out, src)
 	return out
 }
+
+// LoadSelectors 返回按Host分组的全部Selector
+func (r *Registry) LoadSelectors() map[string][]flux.Selector {
+	r.hostedSelectorLock.RLock()
+	defer r.hostedSelectorLock.RUnlock()
+	out := make(map[string][]flux.Selector, len(r.hostedSelectors))
+	for host, selectors := range r.hostedSelectors {
+		out[host] = _newSelectors(selectors)
+	}
+	return out
+}
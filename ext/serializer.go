@@ -12,24 +12,18 @@ const (
 	TypeNameSerializerJson    = "json"
 )
 
-var (
-	typedSerializers = make(map[string]flux.Serializer, 2)
-)
-
 ////
 
 func StoreSerializer(typeName string, serializer flux.Serializer) {
-	typeName = pkg.RequireNotEmpty(typeName, "typeName is empty")
-	typedSerializers[typeName] = pkg.RequireNotNil(serializer, "Serializer is nil").(flux.Serializer)
+	defaultRegistry.StoreSerializer(typeName, serializer)
 }
 
 func LoadSerializer(typeName string) flux.Serializer {
-	typeName = pkg.RequireNotEmpty(typeName, "typeName is empty")
-	return typedSerializers[typeName]
+	return defaultRegistry.LoadSerializer(typeName)
 }
 
 func JSONMarshal(data interface{}) ([]byte, error) {
-	json := typedSerializers[TypeNameSerializerJson]
+	json := defaultRegistry.typedSerializers[TypeNameSerializerJson]
 	if nil == json {
 		return nil, errors.New("JSON serializer not found")
 	}
@@ -37,9 +31,19 @@ func JSONMarshal(data interface{}) ([]byte, error) {
 }
 
 func JSONUnmarshal(data []byte, out interface{}) error {
-	json := typedSerializers[TypeNameSerializerJson]
+	json := defaultRegistry.typedSerializers[TypeNameSerializerJson]
 	if nil == json {
 		return errors.New("JSON serializer not found")
 	}
 	return json.Unmarshal(data, out)
 }
+
+func (r *Registry) StoreSerializer(typeName string, serializer flux.Serializer) {
+	typeName = pkg.RequireNotEmpty(typeName, "typeName is empty")
+	r.typedSerializers[typeName] = pkg.RequireNotNil(serializer, "Serializer is nil").(flux.Serializer)
+}
+
+func (r *Registry) LoadSerializer(typeName string) flux.Serializer {
+	typeName = pkg.RequireNotEmpty(typeName, "typeName is empty")
+	return r.typedSerializers[typeName]
+}
@@ -2,43 +2,75 @@ package ext
 
 import (
 	"fmt"
-	"sync"
 
 	"github.com/bytepowered/flux"
 )
 
 var (
 	serviceNotFound flux.BackendService
-	servicesMap     *sync.Map = new(sync.Map)
 )
 
 func StoreBackendServiceById(id string, service flux.BackendService) {
-	servicesMap.Store(id, service)
+	defaultRegistry.StoreBackendServiceById(id, service)
 }
 
 // StoreBackendService store backend service
 func StoreBackendService(service flux.BackendService) {
-	id := _ensureServiceID(&service)
-	StoreBackendServiceById(id, service)
+	defaultRegistry.StoreBackendService(service)
 }
 
 // LoadBackendService load backend service by serviceId
 func LoadBackendService(serviceID string) (flux.BackendService, bool) {
-	v, ok := servicesMap.Load(serviceID)
-	if ok {
-		return v.(flux.BackendService), true
-	}
-	return serviceNotFound, false
+	return defaultRegistry.LoadBackendService(serviceID)
 }
 
 // RemoveBackendService remove backend service by serviceId
 func RemoveBackendService(serviceID string) {
-	servicesMap.Delete(serviceID)
+	defaultRegistry.RemoveBackendService(serviceID)
+}
+
+// LoadBackendServices load all backend services, keyed by service id or alias id
+func LoadBackendServices() map[string]flux.BackendService {
+	return defaultRegistry.LoadBackendServices()
 }
 
 // HasBackendService check service exists by service id
 func HasBackendService(serviceID string) bool {
-	_, ok := servicesMap.Load(serviceID)
+	return defaultRegistry.HasBackendService(serviceID)
+}
+
+func (r *Registry) StoreBackendServiceById(id string, service flux.BackendService) {
+	r.servicesMap.Store(id, service)
+}
+
+func (r *Registry) StoreBackendService(service flux.BackendService) {
+	id := _ensureServiceID(&service)
+	r.StoreBackendServiceById(id, service)
+}
+
+func (r *Registry) LoadBackendService(serviceID string) (flux.BackendService, bool) {
+	v, ok := r.servicesMap.Load(serviceID)
+	if ok {
+		return v.(flux.BackendService), true
+	}
+	return serviceNotFound, false
+}
+
+func (r *Registry) RemoveBackendService(serviceID string) {
+	r.servicesMap.Delete(serviceID)
+}
+
+func (r *Registry) LoadBackendServices() map[string]flux.BackendService {
+	out := make(map[string]flux.BackendService, 32)
+	r.servicesMap.Range(func(key, value interface{}) bool {
+		out[key.(string)] = value.(flux.BackendService)
+		return true
+	})
+	return out
+}
+
+func (r *Registry) HasBackendService(serviceID string) bool {
+	_, ok := r.servicesMap.Load(serviceID)
 	return ok
 }
 
@@ -0,0 +1,91 @@
+package ext
+
+import (
+	"strings"
+
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/pkg"
+)
+
+// StoreUpstream 添加或更新命名的上游集群
+func StoreUpstream(upstream flux.Upstream) {
+	defaultRegistry.StoreUpstream(upstream)
+}
+
+// LoadUpstream 获取指定名称的上游集群
+func LoadUpstream(name string) (flux.Upstream, bool) {
+	return defaultRegistry.LoadUpstream(name)
+}
+
+// RemoveUpstream 删除指定名称的上游集群
+func RemoveUpstream(name string) {
+	defaultRegistry.RemoveUpstream(name)
+}
+
+// LoadUpstreams 获取全部上游集群
+func LoadUpstreams() map[string]flux.Upstream {
+	return defaultRegistry.LoadUpstreams()
+}
+
+// StoreUpstreamBalancerFactory 注册指定名称的上游负载均衡策略
+func StoreUpstreamBalancerFactory(name string, factory flux.UpstreamBalancerFactory) {
+	defaultRegistry.StoreUpstreamBalancerFactory(name, factory)
+}
+
+// LoadUpstreamBalancerFactory 获取指定名称的上游负载均衡策略
+func LoadUpstreamBalancerFactory(name string) (flux.UpstreamBalancerFactory, bool) {
+	return defaultRegistry.LoadUpstreamBalancerFactory(name)
+}
+
+// StoreUpstreamTLSConfigFunc 设置连接上游使用的TLS客户端配置函数
+func StoreUpstreamTLSConfigFunc(f flux.UpstreamTLSConfigFunc) {
+	defaultRegistry.StoreUpstreamTLSConfigFunc(f)
+}
+
+// LoadUpstreamTLSConfigFunc 获取连接上游使用的TLS客户端配置函数；未设置时返回nil
+func LoadUpstreamTLSConfigFunc() flux.UpstreamTLSConfigFunc {
+	return defaultRegistry.LoadUpstreamTLSConfigFunc()
+}
+
+func (r *Registry) StoreUpstream(upstream flux.Upstream) {
+	name := pkg.RequireNotEmpty(upstream.Name, "Upstream name is empty")
+	r.upstreamsMap.Store(name, upstream)
+}
+
+func (r *Registry) LoadUpstream(name string) (flux.Upstream, bool) {
+	if v, ok := r.upstreamsMap.Load(name); ok {
+		return v.(flux.Upstream), true
+	}
+	return flux.Upstream{}, false
+}
+
+func (r *Registry) RemoveUpstream(name string) {
+	r.upstreamsMap.Delete(name)
+}
+
+func (r *Registry) LoadUpstreams() map[string]flux.Upstream {
+	out := make(map[string]flux.Upstream, 8)
+	r.upstreamsMap.Range(func(key, value interface{}) bool {
+		out[key.(string)] = value.(flux.Upstream)
+		return true
+	})
+	return out
+}
+
+func (r *Registry) StoreUpstreamBalancerFactory(name string, factory flux.UpstreamBalancerFactory) {
+	name = strings.ToLower(pkg.RequireNotEmpty(name, "UpstreamBalancer name is empty"))
+	r.upstreamBalancerFactories[name] = pkg.RequireNotNil(factory, "UpstreamBalancerFactory is nil").(flux.UpstreamBalancerFactory)
+}
+
+func (r *Registry) LoadUpstreamBalancerFactory(name string) (flux.UpstreamBalancerFactory, bool) {
+	factory, ok := r.upstreamBalancerFactories[strings.ToLower(name)]
+	return factory, ok
+}
+
+func (r *Registry) StoreUpstreamTLSConfigFunc(f flux.UpstreamTLSConfigFunc) {
+	r.upstreamTLSConfigFunc = pkg.RequireNotNil(f, "UpstreamTLSConfigFunc is nil").(flux.UpstreamTLSConfigFunc)
+}
+
+func (r *Registry) LoadUpstreamTLSConfigFunc() flux.UpstreamTLSConfigFunc {
+	return r.upstreamTLSConfigFunc
+}
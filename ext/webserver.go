@@ -4,16 +4,20 @@ import (
 	"github.com/bytepowered/flux"
 )
 
-var (
-	webServerFactory WebServerFactory
-)
-
 type WebServerFactory func(*flux.Configuration) flux.WebServer
 
 func StoreWebServerFactory(f WebServerFactory) {
-	webServerFactory = f
+	defaultRegistry.StoreWebServerFactory(f)
 }
 
 func LoadWebServerFactory() WebServerFactory {
-	return webServerFactory
+	return defaultRegistry.LoadWebServerFactory()
+}
+
+func (r *Registry) StoreWebServerFactory(f WebServerFactory) {
+	r.webServerFactory = f
+}
+
+func (r *Registry) LoadWebServerFactory() WebServerFactory {
+	return r.webServerFactory
 }
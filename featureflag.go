@@ -0,0 +1,20 @@
+package flux
+
+// FeatureFlagProvider 特性开关接口；在路由时判断Endpoint或特定行为是否对当前请求开启。
+// 可适配LaunchDarkly、Unleash等特性开关服务，通常根据请求的Subject/租户计算开关状态。
+type FeatureFlagProvider interface {
+	// IsEnabled 判断指定特性开关对当前请求是否开启
+	IsEnabled(ctx Context, flag string) (enabled bool, err error)
+}
+
+// FeatureFlagProviderFunc 函数形式的FeatureFlagProvider实现
+type FeatureFlagProviderFunc func(ctx Context, flag string) (enabled bool, err error)
+
+func (f FeatureFlagProviderFunc) IsEnabled(ctx Context, flag string) (bool, error) {
+	return f(ctx, flag)
+}
+
+const (
+	// Endpoint扩展属性：Endpoint关联的特性开关；开关关闭时，Endpoint对当前请求不可见（404）
+	EndpointExtKeyFeatureFlag = "feature-flag"
+)
@@ -9,26 +9,50 @@ import (
 // ServeError 定义网关处理请求的服务错误；
 // 它包含：错误定义的状态码、错误消息、内部错误等元数据
 type ServeError struct {
-	StatusCode int                    // 响应状态码
-	Message    string                 // 错误消息
-	ErrorCode  interface{}            // 业务错误码
-	Header     http.Header            // 响应Header
-	Internal   error                  // 内部错误对象；错误对象不会被输出到请求端；
-	ExtraTrace map[string]interface{} // 用于定义和跟踪的额外信息；额外信息不会被输出到请求端；
+	StatusCode        int                    // 响应状态码
+	Message           string                 // 错误消息
+	ErrorCode         interface{}            // 业务错误码
+	Header            http.Header            // 响应Header
+	Internal          error                  // 内部错误对象；错误对象不会被输出到请求端；
+	ExtraTrace        map[string]interface{} // 用于定义和跟踪的额外信息；额外信息不会被输出到请求端；
+	Category          string                 // 错误分类：client、upstream、gateway；为空时按错误码和状态码推断，见 GetCategory
+	Retryable         bool                   // 错误是否为可重试的临时错误，例如上游连接失败、上游暂不可用
+	UpstreamStatus    int                    // 上游响应的状态码；非Http协议为映射后的Http状态码；未收到上游响应时为0
+	UpstreamErrorCode string                 // 上游协议的原始错误码，例如gRPC状态码、Dubbo异常类名
 }
 
 func (e *ServeError) Error() string {
+	text := fmt.Sprintf("ServeError: StatusCode=%d, ErrorCode=%s, Message=%s", e.StatusCode, e.ErrorCode, e.Message)
+	if len(e.ExtraTrace) > 0 {
+		text += fmt.Sprintf(", ExtraTrace=%+v", e.ExtraTrace)
+	}
 	if nil != e.Internal {
-		return fmt.Sprintf("ServeError: StatusCode=%d, ErrorCode=%s, Message=%s, ExtraTrace=%+v, Error=%s", e.StatusCode, e.ErrorCode, e.Message, e.ExtraTrace, e.Internal)
-	} else {
-		return fmt.Sprintf("ServeError: StatusCode=%d, ErrorCode=%s, Message=%s, ExtraTrace=%+v", e.StatusCode, e.ErrorCode, e.Message, e.ExtraTrace)
+		text += fmt.Sprintf(", Error=%s", e.Internal)
 	}
+	return text
 }
 
 func (e *ServeError) GetErrorCode() string {
 	return cast.ToString(e.ErrorCode)
 }
 
+// GetCategory 返回错误分类：未指定分类时，上游错误码或包含上游错误信息的为upstream，4xx状态码为client，其它为gateway
+func (e *ServeError) GetCategory() string {
+	if "" != e.Category {
+		return e.Category
+	}
+	switch {
+	case e.UpstreamStatus > 0 || "" != e.UpstreamErrorCode:
+		return ErrorCategoryUpstream
+	case ErrorCodeGatewayBackend == e.ErrorCode || ErrorCodeGatewayTimeout == e.ErrorCode:
+		return ErrorCategoryUpstream
+	case e.StatusCode >= http.StatusBadRequest && e.StatusCode < http.StatusInternalServerError:
+		return ErrorCategoryClient
+	default:
+		return ErrorCategoryGateway
+	}
+}
+
 func (e *ServeError) GetExtraTrace(key string) interface{} {
 	return e.ExtraTrace[key]
 }
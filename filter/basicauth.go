@@ -0,0 +1,176 @@
+package filter
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/pkg"
+	"github.com/bytepowered/flux/support"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	TypeIdBasicAuthFilter = "BasicAuthFilter"
+)
+
+const (
+	BasicAuthConfigKeyRealm           = "realm"
+	BasicAuthConfigKeyProvider        = "provider"
+	BasicAuthConfigKeyHtpasswdFile    = "htpasswd-file"
+	BasicAuthConfigKeyUsers           = "users"
+	BasicAuthConfigKeyLoginProtection = "login-protection"
+)
+
+// BasicAuthConfig BasicAuth认证配置
+type BasicAuthConfig struct {
+	SkipFunc flux.FilterSkipper
+	// CredentialProvider 自定义凭证验证；未设置时按配置 provider > htpasswd-file/users 的顺序创建
+	CredentialProvider flux.CredentialProvider
+	realm              string
+}
+
+func NewBasicAuthFilter(c BasicAuthConfig) *BasicAuthFilter {
+	return &BasicAuthFilter{
+		Configs: c,
+	}
+}
+
+// BasicAuthFilter 标准HTTP Basic认证。认证成功后设置 X-Jwt-Subject 为用户名，并移除请求中的Authorization Header；
+// 认证失败次数由 LoginProtectionFilter 按用户名和客户端IP统计并锁定，配置项为 login-protection。
+type BasicAuthFilter struct {
+	Disabled   bool
+	Configs    BasicAuthConfig
+	protection *LoginProtectionFilter
+}
+
+func (b *BasicAuthFilter) Init(config *flux.Configuration) error {
+	config.SetDefaults(map[string]interface{}{
+		ConfigKeyDisabled:       false,
+		BasicAuthConfigKeyRealm: "Restricted",
+	})
+	b.Disabled = config.GetBool(ConfigKeyDisabled)
+	if b.Disabled {
+		logger.Info("Endpoint BasicAuthFilter was DISABLED!!")
+		return nil
+	}
+	b.Configs.realm = config.GetString(BasicAuthConfigKeyRealm)
+	b.protection = NewLoginProtectionFilter(LoginProtectionConfig{
+		UsernameFunc: func(ctx flux.Context) string {
+			username, _, _ := ParseBasicAuth(ctx.Request().HeaderValue(flux.HeaderAuthorization))
+			return username
+		},
+	})
+	if err := b.protection.Init(config.Sub(BasicAuthConfigKeyLoginProtection)); nil != err {
+		return fmt.Errorf("BasicAuthFilter.login-protection: %w", err)
+	}
+	if pkg.IsNil(b.Configs.SkipFunc) {
+		b.Configs.SkipFunc = func(_ flux.Context) bool {
+			return false
+		}
+	}
+	if pkg.IsNil(b.Configs.CredentialProvider) {
+		provider, err := b.newCredentialProvider(config)
+		if nil != err {
+			return err
+		}
+		b.Configs.CredentialProvider = provider
+	}
+	return nil
+}
+
+func (b *BasicAuthFilter) newCredentialProvider(config *flux.Configuration) (flux.CredentialProvider, error) {
+	if id := config.GetString(BasicAuthConfigKeyProvider); "" != id {
+		if provider, ok := ext.LoadCredentialProvider(id); ok {
+			return provider, nil
+		}
+		return nil, fmt.Errorf("BasicAuthFilter.provider not found, id: %s", id)
+	}
+	if file := config.GetString(BasicAuthConfigKeyHtpasswdFile); "" != file {
+		return support.NewHtpasswdCredentialProvider(file)
+	}
+	if users := config.GetStringMapString(BasicAuthConfigKeyUsers); len(users) > 0 {
+		return support.NewStaticCredentialProvider(users)
+	}
+	return nil, errors.New("BasicAuthFilter.CredentialProvider is nil, requires provider, htpasswd-file or users")
+}
+
+func (*BasicAuthFilter) TypeId() string {
+	return TypeIdBasicAuthFilter
+}
+
+func (b *BasicAuthFilter) DoFilter(next flux.FilterHandler) flux.FilterHandler {
+	if b.Disabled {
+		return next
+	}
+	// 登录保护只统计认证结果，不包含后续Filter和后端调用
+	authenticate := b.protection.DoFilter(b.authenticate)
+	return func(ctx flux.Context) *flux.ServeError {
+		if b.Configs.SkipFunc(ctx) {
+			return next(ctx)
+		}
+		if err := authenticate(ctx); nil != err {
+			return err
+		}
+		return next(ctx)
+	}
+}
+
+func (b *BasicAuthFilter) authenticate(ctx flux.Context) *flux.ServeError {
+	username, password, ok := ParseBasicAuth(ctx.Request().HeaderValue(flux.HeaderAuthorization))
+	if !ok {
+		return b.unauthorized(nil)
+	}
+	valid, err := b.Configs.CredentialProvider.Authenticate(ctx.Context(), username, password)
+	ctx.AddMetric("M-"+b.TypeId(), ctx.ElapsedTime())
+	if nil != err {
+		return &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageBasicAuthUnauthorized,
+			Internal:   err,
+		}
+	}
+	if !valid {
+		return b.unauthorized(errors.New("basic-auth: invalid credentials, username: " + username))
+	}
+	if header, writable := ctx.Request().HeaderValues(); writable {
+		header.Del(flux.HeaderAuthorization)
+	}
+	ctx.SetAttribute(flux.XJwtSubject, username)
+	return nil
+}
+
+func (b *BasicAuthFilter) unauthorized(err error) *flux.ServeError {
+	header := http.Header{}
+	header.Set(flux.HeaderWWWAuthenticate, `Basic realm=`+strconv.Quote(b.Configs.realm)+`, charset="UTF-8"`)
+	return &flux.ServeError{
+		StatusCode: flux.StatusUnauthorized,
+		ErrorCode:  flux.ErrorCodeRequestInvalid,
+		Message:    flux.ErrorMessageBasicAuthUnauthorized,
+		Header:     header,
+		Internal:   err,
+	}
+}
+
+// ParseBasicAuth 解析Basic认证Header，返回用户名和密码
+func ParseBasicAuth(auth string) (username, password string, ok bool) {
+	const prefix = "basic "
+	if len(auth) <= len(prefix) || strings.ToLower(auth[:len(prefix)]) != prefix {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(auth[len(prefix):]))
+	if nil != err {
+		return "", "", false
+	}
+	cred := string(decoded)
+	idx := strings.IndexByte(cred, ':')
+	if idx <= 0 {
+		return "", "", false
+	}
+	return cred[:idx], cred[idx+1:], true
+}
@@ -0,0 +1,86 @@
+package filter
+
+import (
+	"encoding/base64"
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestParseBasicAuth(t *testing.T) {
+	cases := []struct {
+		auth     string
+		username string
+		password string
+		ok       bool
+	}{
+		{auth: "Basic " + base64.StdEncoding.EncodeToString([]byte("alice:pa:ss")), username: "alice", password: "pa:ss", ok: true},
+		{auth: "basic " + base64.StdEncoding.EncodeToString([]byte("alice:")), username: "alice", password: "", ok: true},
+		{auth: "Basic " + base64.StdEncoding.EncodeToString([]byte(":pass")), ok: false},
+		{auth: "Basic !!", ok: false},
+		{auth: "Bearer abc", ok: false},
+		{auth: "", ok: false},
+	}
+	for _, c := range cases {
+		username, password, ok := ParseBasicAuth(c.auth)
+		assert2.Equal(t, c.ok, ok, c.auth)
+		assert2.Equal(t, c.username, username, c.auth)
+		assert2.Equal(t, c.password, password, c.auth)
+	}
+}
+
+func TestBasicAuthFilter_DoFilter(t *testing.T) {
+	assert := assert2.New(t)
+	config := flux.NewConfiguration(nil)
+	config.Set(BasicAuthConfigKeyRealm, "flux")
+	config.Set(BasicAuthConfigKeyUsers, map[string]string{"alice": "$apr1$r31.....$HqJZimcKQFAMYayBlzkrA/"})
+	config.Set(BasicAuthConfigKeyLoginProtection, map[string]interface{}{
+		LoginProtectionConfigKeyMaxUserAttempts: 2,
+	})
+	filter := NewBasicAuthFilter(BasicAuthConfig{})
+	assert.NoError(filter.Init(config))
+	next := func(ctx flux.Context) *flux.ServeError {
+		return nil
+	}
+	newContext := func(password string) *cryptoTestContext {
+		auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("alice:"+password))
+		return newCryptoTestContext(map[string]interface{}{flux.HeaderAuthorization: auth}, flux.Endpoint{})
+	}
+	ctx := newContext("myPassword")
+	assert.Nil(filter.DoFilter(next)(ctx))
+	assert.Equal("alice", ctx.GetAttributeString(flux.XJwtSubject, ""))
+	// 缺少认证信息
+	err := filter.DoFilter(next)(newCryptoTestContext(map[string]interface{}{}, flux.Endpoint{}))
+	assert.NotNil(err)
+	assert.Equal(flux.StatusUnauthorized, err.StatusCode)
+	assert.Equal(`Basic realm="flux", charset="UTF-8"`, err.Header.Get(flux.HeaderWWWAuthenticate))
+	// 连续失败后由登录保护锁定，锁定期间正确密码也被拒绝
+	assert.Equal(flux.StatusUnauthorized, filter.DoFilter(next)(newContext("wrong")).StatusCode)
+	assert.Equal(flux.StatusUnauthorized, filter.DoFilter(next)(newContext("wrong")).StatusCode)
+	err = filter.DoFilter(next)(newContext("myPassword"))
+	assert.NotNil(err)
+	assert.Equal(http.StatusTooManyRequests, err.StatusCode)
+	assert.Equal(flux.ErrorMessageLoginLocked, err.Message)
+	assert.NotEmpty(err.Header.Get("Retry-After"))
+	// 后续Filter返回的401不计入认证失败
+	failed := func(ctx flux.Context) *flux.ServeError {
+		return &flux.ServeError{StatusCode: flux.StatusUnauthorized}
+	}
+	filter = NewBasicAuthFilter(BasicAuthConfig{})
+	assert.NoError(filter.Init(config))
+	for i := 0; i < 3; i++ {
+		assert.Equal(flux.StatusUnauthorized, filter.DoFilter(failed)(newContext("myPassword")).StatusCode)
+	}
+}
+
+func TestBasicAuthFilter_Init(t *testing.T) {
+	assert := assert2.New(t)
+	assert.Error(NewBasicAuthFilter(BasicAuthConfig{}).Init(flux.NewConfiguration(nil)))
+	config := flux.NewConfiguration(nil)
+	config.Set(BasicAuthConfigKeyProvider, "not-exists")
+	assert.Error(NewBasicAuthFilter(BasicAuthConfig{}).Init(config))
+	config = flux.NewConfiguration(nil)
+	config.Set(BasicAuthConfigKeyUsers, map[string]string{"alice": "plain-text"})
+	assert.Error(NewBasicAuthFilter(BasicAuthConfig{}).Init(config))
+}
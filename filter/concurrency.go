@@ -0,0 +1,169 @@
+package filter
+
+import (
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/pkg"
+	"github.com/bytepowered/flux/support"
+	"github.com/spf13/cast"
+	"net/http"
+	"sync"
+)
+
+const (
+	TypeIdConcurrencyLimitFilter = "ConcurrencyLimitFilter"
+)
+
+const (
+	ConcurrencyConfigKeyEndpointLimit = "endpoint-limit"
+	ConcurrencyConfigKeyConsumerLimit = "consumer-limit"
+	ConcurrencyConfigKeyKeyLookup     = "key-lookup"
+)
+
+const (
+	// Endpoint扩展属性：覆盖Endpoint的并发请求数限制
+	ConcurrencyExtKeyEndpointLimit = "concurrency-limit"
+	// Endpoint扩展属性：覆盖单个调用方在Endpoint上的并发请求数限制
+	ConcurrencyExtKeyConsumerLimit = "consumer-concurrency-limit"
+)
+
+// ConcurrencyConfig 并发限制配置
+type ConcurrencyConfig struct {
+	SkipFunc      flux.FilterSkipper
+	endpointLimit int
+	consumerLimit int
+	keyLookup     string
+}
+
+func NewConcurrencyLimitFilter(c ConcurrencyConfig) *ConcurrencyLimitFilter {
+	return &ConcurrencyLimitFilter{
+		Configs:  c,
+		inflight: make(map[string]int, 64),
+	}
+}
+
+// ConcurrencyLimitFilter 限制Endpoint和调用方（默认为JWT Subject，不存在时为客户端IP）在Endpoint上的进行中请求数；
+// 限制值为0时不限制。Endpoint并发超限时返回503，调用方并发超限时返回429，避免单个调用方占满昂贵Endpoint的处理能力。
+type ConcurrencyLimitFilter struct {
+	Disabled bool
+	Configs  ConcurrencyConfig
+	mu       sync.Mutex
+	inflight map[string]int
+}
+
+func (c *ConcurrencyLimitFilter) Init(config *flux.Configuration) error {
+	config.SetDefaults(map[string]interface{}{
+		ConfigKeyDisabled:                 false,
+		ConcurrencyConfigKeyEndpointLimit: 0,
+		ConcurrencyConfigKeyConsumerLimit: 0,
+		ConcurrencyConfigKeyKeyLookup:     flux.ScopeAttr + ":" + flux.XJwtSubject,
+	})
+	c.Disabled = config.GetBool(ConfigKeyDisabled)
+	if c.Disabled {
+		logger.Info("Endpoint ConcurrencyLimitFilter was DISABLED!!")
+		return nil
+	}
+	c.Configs.endpointLimit = config.GetInt(ConcurrencyConfigKeyEndpointLimit)
+	c.Configs.consumerLimit = config.GetInt(ConcurrencyConfigKeyConsumerLimit)
+	c.Configs.keyLookup = config.GetString(ConcurrencyConfigKeyKeyLookup)
+	if c.Configs.endpointLimit < 0 || c.Configs.consumerLimit < 0 {
+		return fmt.Errorf("ConcurrencyLimitFilter.limit is invalid: endpoint=%d, consumer=%d",
+			c.Configs.endpointLimit, c.Configs.consumerLimit)
+	}
+	if _, _, ok := support.ParseLookupExpr(c.Configs.keyLookup); !ok {
+		return fmt.Errorf("ConcurrencyLimitFilter.key-lookup is invalid: %s", c.Configs.keyLookup)
+	}
+	if pkg.IsNil(c.Configs.SkipFunc) {
+		c.Configs.SkipFunc = func(_ flux.Context) bool {
+			return false
+		}
+	}
+	if nil == c.inflight {
+		c.inflight = make(map[string]int, 64)
+	}
+	return nil
+}
+
+func (*ConcurrencyLimitFilter) TypeId() string {
+	return TypeIdConcurrencyLimitFilter
+}
+
+func (c *ConcurrencyLimitFilter) DoFilter(next flux.FilterHandler) flux.FilterHandler {
+	if c.Disabled {
+		return next
+	}
+	return func(ctx flux.Context) *flux.ServeError {
+		if c.Configs.SkipFunc(ctx) {
+			return next(ctx)
+		}
+		endpoint := ctx.Endpoint()
+		endpointLimit, consumerLimit := c.Configs.endpointLimit, c.Configs.consumerLimit
+		if v, ok := endpoint.Ext(ConcurrencyExtKeyEndpointLimit); ok {
+			endpointLimit = cast.ToInt(v)
+		}
+		if v, ok := endpoint.Ext(ConcurrencyExtKeyConsumerLimit); ok {
+			consumerLimit = cast.ToInt(v)
+		}
+		endpointKey := endpoint.HttpMethod + ":" + endpoint.HttpPattern
+		v, _ := support.LookupContextByExpr(c.Configs.keyLookup, ctx)
+		consumer := cast.ToString(v)
+		if "" == consumer {
+			// 匿名请求按客户端IP计数
+			consumer = support.ClientIPOf(ctx)
+		}
+		consumerKey := endpointKey + "|" + consumer
+		if err := c.acquire(endpointKey, endpointLimit, consumerKey, consumerLimit); nil != err {
+			return err
+		}
+		defer c.release(endpointKey, endpointLimit, consumerKey, consumerLimit)
+		return next(ctx)
+	}
+}
+
+// acquire 同时占用Endpoint和调用方的并发配额；任一超限时均不占用
+func (c *ConcurrencyLimitFilter) acquire(endpointKey string, endpointLimit int, consumerKey string, consumerLimit int) *flux.ServeError {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if consumerLimit > 0 && c.inflight[consumerKey] >= consumerLimit {
+		return &flux.ServeError{
+			StatusCode: http.StatusTooManyRequests,
+			ErrorCode:  flux.ErrorCodeRequestInvalid,
+			Message:    flux.ErrorMessageConcurrencyConsumerLimited,
+		}
+	}
+	if endpointLimit > 0 && c.inflight[endpointKey] >= endpointLimit {
+		return &flux.ServeError{
+			StatusCode: http.StatusServiceUnavailable,
+			ErrorCode:  flux.ErrorCodeGatewayCircuited,
+			Message:    flux.ErrorMessageConcurrencyEndpointLimited,
+		}
+	}
+	if endpointLimit > 0 {
+		c.inflight[endpointKey]++
+	}
+	if consumerLimit > 0 {
+		c.inflight[consumerKey]++
+	}
+	return nil
+}
+
+func (c *ConcurrencyLimitFilter) release(endpointKey string, endpointLimit int, consumerKey string, consumerLimit int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if endpointLimit > 0 {
+		c.decrement(endpointKey)
+	}
+	if consumerLimit > 0 {
+		c.decrement(consumerKey)
+	}
+}
+
+// decrement 计数归零时删除Key，避免调用方Key无限增长
+func (c *ConcurrencyLimitFilter) decrement(key string) {
+	if n := c.inflight[key] - 1; n > 0 {
+		c.inflight[key] = n
+	} else {
+		delete(c.inflight, key)
+	}
+}
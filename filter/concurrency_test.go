@@ -0,0 +1,88 @@
+package filter
+
+import (
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestConcurrencyLimitFilter_DoFilter(t *testing.T) {
+	assert := assert2.New(t)
+	config := flux.NewConfiguration(nil)
+	config.Set(ConcurrencyConfigKeyEndpointLimit, 3)
+	config.Set(ConcurrencyConfigKeyConsumerLimit, 2)
+	filter := NewConcurrencyLimitFilter(ConcurrencyConfig{})
+	assert.NoError(filter.Init(config))
+	endpoint := flux.Endpoint{HttpMethod: http.MethodGet, HttpPattern: "/report"}
+	request := func(subject string, next flux.FilterHandler) *flux.ServeError {
+		ctx := newCryptoTestContext(map[string]interface{}{flux.XJwtSubject: subject}, endpoint)
+		return filter.DoFilter(next)(ctx)
+	}
+	ok := func(ctx flux.Context) *flux.ServeError {
+		return nil
+	}
+	// 在进行中的请求内发起嵌套请求，模拟并发
+	var inner []*flux.ServeError
+	holding := func(subject string, then flux.FilterHandler) flux.FilterHandler {
+		return func(ctx flux.Context) *flux.ServeError {
+			inner = append(inner, request(subject, then))
+			return nil
+		}
+	}
+	cases := []struct {
+		name    string
+		handler flux.FilterHandler
+		expects []int
+	}{
+		{
+			name:    "consumer limited",
+			handler: holding("alice", holding("alice", ok)),
+			expects: []int{http.StatusTooManyRequests, 0},
+		},
+		{
+			name:    "other consumer allowed",
+			handler: holding("alice", holding("bob", ok)),
+			expects: []int{0, 0},
+		},
+		{
+			name:    "endpoint limited",
+			handler: holding("bob", holding("carol", holding("dave", ok))),
+			expects: []int{http.StatusServiceUnavailable, 0, 0},
+		},
+	}
+	for _, tc := range cases {
+		inner = nil
+		assert.Nil(request("alice", tc.handler), tc.name)
+		codes := make([]int, 0, len(inner))
+		for _, err := range inner {
+			if nil == err {
+				codes = append(codes, 0)
+			} else {
+				codes = append(codes, err.StatusCode)
+			}
+		}
+		assert.Equal(tc.expects, codes, tc.name)
+	}
+	// 请求结束后释放全部配额
+	assert.Equal(0, len(filter.inflight))
+}
+
+func TestConcurrencyLimitFilter_EndpointOverride(t *testing.T) {
+	assert := assert2.New(t)
+	filter := NewConcurrencyLimitFilter(ConcurrencyConfig{})
+	assert.NoError(filter.Init(flux.NewConfiguration(nil)))
+	endpoint := flux.Endpoint{HttpMethod: http.MethodPost, HttpPattern: "/export"}
+	endpoint.Extensions = map[string]interface{}{ConcurrencyExtKeyEndpointLimit: 1}
+	var inner *flux.ServeError
+	ctx := newCryptoTestContext(map[string]interface{}{flux.XJwtSubject: "alice"}, endpoint)
+	err := filter.DoFilter(func(ctx flux.Context) *flux.ServeError {
+		inner = filter.DoFilter(func(ctx flux.Context) *flux.ServeError {
+			return nil
+		})(ctx)
+		return nil
+	})(ctx)
+	assert.Nil(err)
+	assert.NotNil(inner)
+	assert.Equal(flux.ErrorMessageConcurrencyEndpointLimited, inner.Message)
+}
@@ -0,0 +1,226 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/pkg"
+	"github.com/bytepowered/flux/support"
+	"github.com/spf13/cast"
+	"net/http"
+	"strings"
+)
+
+const (
+	TypeIdConsentFilter = "ConsentFilter"
+)
+
+const (
+	ConsentConfigKeyConsumerLookup    = "consumer-lookup"
+	ConsentConfigKeyStatusCode        = "status-code"
+	ConsentConfigKeyDocuments         = "documents"
+	ConsentConfigKeyRequiredDocuments = "required-documents"
+	ConsentConfigKeyConsumers         = "consumers"
+	ConsentConfigKeyVersion           = "version"
+	ConsentConfigKeyURL               = "url"
+)
+
+const (
+	// Endpoint扩展属性：需要接受的条款文档ID列表
+	ConsentExtKeyDocuments = "consent-documents"
+)
+
+const (
+	// 响应Header：未接受的条款，格式为 document=version
+	HeaderXConsentRequired = "X-Consent-Required"
+)
+
+type (
+	// ConsentAcceptedFunc 查询消费者已接受的条款版本；未接受时返回空字符串
+	ConsentAcceptedFunc func(ctx flux.Context, consumer string, document string) (version string, err error)
+)
+
+// ConsentDocument 条款文档的当前版本
+type ConsentDocument struct {
+	Id      string
+	Version string
+	URL     string
+}
+
+// ConsentConfig 条款接受检查配置
+type ConsentConfig struct {
+	SkipFunc flux.FilterSkipper
+	// AcceptedFunc 查询消费者已接受的条款版本；未设置时使用配置 consumers.<consumer>.<document> = version
+	AcceptedFunc      ConsentAcceptedFunc
+	consumerLookup    string
+	statusCode        int
+	documents         map[string]ConsentDocument
+	requiredDocuments []string
+}
+
+func NewConsentFilter(c ConsentConfig) *ConsentFilter {
+	return &ConsentFilter{
+		Configs: c,
+	}
+}
+
+// ConsentFilter 检查消费者是否已接受指定版本的服务条款（ToS）。
+// 需要接受的条款由Endpoint扩展属性 consent-documents 或配置 required-documents 指定；
+// 未接受时返回403（或配置为451），并通过 Link 和 X-Consent-Required Header 返回条款地址和版本。
+type ConsentFilter struct {
+	Disabled bool
+	Configs  ConsentConfig
+}
+
+func (c *ConsentFilter) Init(config *flux.Configuration) error {
+	config.SetDefaults(map[string]interface{}{
+		ConfigKeyDisabled:              false,
+		ConsentConfigKeyConsumerLookup: flux.ScopeAttr + ":" + flux.XJwtSubject,
+		ConsentConfigKeyStatusCode:     http.StatusForbidden,
+	})
+	c.Disabled = config.GetBool(ConfigKeyDisabled)
+	if c.Disabled {
+		logger.Info("Endpoint ConsentFilter was DISABLED!!")
+		return nil
+	}
+	c.Configs.consumerLookup = config.GetString(ConsentConfigKeyConsumerLookup)
+	c.Configs.statusCode = config.GetInt(ConsentConfigKeyStatusCode)
+	for _, id := range config.GetStringSlice(ConsentConfigKeyRequiredDocuments) {
+		c.Configs.requiredDocuments = append(c.Configs.requiredDocuments, strings.ToLower(id))
+	}
+	if _, _, ok := support.ParseLookupExpr(c.Configs.consumerLookup); !ok {
+		return fmt.Errorf("ConsentFilter.consumer-lookup is invalid: %s", c.Configs.consumerLookup)
+	}
+	if c.Configs.statusCode != http.StatusForbidden && c.Configs.statusCode != http.StatusUnavailableForLegalReasons {
+		return fmt.Errorf("ConsentFilter.status-code must be 403 or 451: %d", c.Configs.statusCode)
+	}
+	c.Configs.documents = make(map[string]ConsentDocument, 4)
+	for id := range config.GetStringMap(ConsentConfigKeyDocuments) {
+		doc := config.Sub(ConsentConfigKeyDocuments + "." + id)
+		c.Configs.documents[id] = ConsentDocument{
+			Id:      id,
+			Version: doc.GetString(ConsentConfigKeyVersion),
+			URL:     doc.GetString(ConsentConfigKeyURL),
+		}
+		if "" == c.Configs.documents[id].Version {
+			return fmt.Errorf("ConsentFilter.documents.%s.version is empty", id)
+		}
+	}
+	for _, id := range c.Configs.requiredDocuments {
+		if _, ok := c.Configs.documents[id]; !ok {
+			return fmt.Errorf("ConsentFilter.required-documents not defined: %s", id)
+		}
+	}
+	if pkg.IsNil(c.Configs.SkipFunc) {
+		c.Configs.SkipFunc = func(_ flux.Context) bool {
+			return false
+		}
+	}
+	if pkg.IsNil(c.Configs.AcceptedFunc) {
+		consumers := config.Sub(ConsentConfigKeyConsumers)
+		c.Configs.AcceptedFunc = func(_ flux.Context, consumer string, document string) (string, error) {
+			return consumers.GetString(consumer + "." + document), nil
+		}
+	}
+	return nil
+}
+
+func (*ConsentFilter) TypeId() string {
+	return TypeIdConsentFilter
+}
+
+func (c *ConsentFilter) DoFilter(next flux.FilterHandler) flux.FilterHandler {
+	if c.Disabled {
+		return next
+	}
+	return func(ctx flux.Context) *flux.ServeError {
+		if c.Configs.SkipFunc(ctx) {
+			return next(ctx)
+		}
+		required := c.requiredOf(ctx.Endpoint())
+		if len(required) == 0 {
+			return next(ctx)
+		}
+		v, _ := support.LookupContextByExpr(c.Configs.consumerLookup, ctx)
+		consumer := cast.ToString(v)
+		if "" == consumer {
+			return &flux.ServeError{
+				StatusCode: flux.StatusUnauthorized,
+				ErrorCode:  flux.ErrorCodeRequestInvalid,
+				Message:    flux.ErrorMessageConsentRequired,
+				Internal:   errors.New("consent: consumer is required"),
+			}
+		}
+		pending := make([]ConsentDocument, 0, len(required))
+		for _, id := range required {
+			doc, ok := c.Configs.documents[id]
+			if !ok {
+				return &flux.ServeError{
+					StatusCode: flux.StatusServerError,
+					ErrorCode:  flux.ErrorCodeGatewayInternal,
+					Message:    flux.ErrorMessageConsentRequired,
+					Internal:   fmt.Errorf("consent: document not defined: %s", id),
+				}
+			}
+			accepted, err := c.Configs.AcceptedFunc(ctx, consumer, id)
+			if nil != err {
+				return &flux.ServeError{
+					StatusCode: flux.StatusServerError,
+					ErrorCode:  flux.ErrorCodeGatewayInternal,
+					Message:    flux.ErrorMessageConsentRequired,
+					Internal:   err,
+				}
+			}
+			if accepted != doc.Version {
+				pending = append(pending, doc)
+			}
+		}
+		ctx.AddMetric("M-"+c.TypeId(), ctx.ElapsedTime())
+		if len(pending) > 0 {
+			return c.consentRequired(pending)
+		}
+		return next(ctx)
+	}
+}
+
+func (c *ConsentFilter) requiredOf(endpoint flux.Endpoint) []string {
+	v, ok := endpoint.Ext(ConsentExtKeyDocuments)
+	if !ok {
+		return c.Configs.requiredDocuments
+	}
+	var ids []string
+	if s, ok := v.(string); ok {
+		ids = strings.Split(s, ",")
+	} else {
+		ids = cast.ToStringSlice(v)
+	}
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		// 配置Key不区分大小写
+		if id = strings.ToLower(strings.TrimSpace(id)); "" != id {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// consentRequired 返回机器可读的条款接受指引：每个未接受的条款对应一个 X-Consent-Required 和 Link Header
+func (c *ConsentFilter) consentRequired(pending []ConsentDocument) *flux.ServeError {
+	header := http.Header{}
+	ids := make([]string, 0, len(pending))
+	for _, doc := range pending {
+		ids = append(ids, doc.Id+"="+doc.Version)
+		header.Add(HeaderXConsentRequired, doc.Id+"="+doc.Version)
+		if "" != doc.URL {
+			header.Add("Link", "<"+doc.URL+`>; rel="terms-of-service"; title="`+doc.Id+`"`)
+		}
+	}
+	return &flux.ServeError{
+		StatusCode: c.Configs.statusCode,
+		ErrorCode:  flux.ErrorCodePermissionDenied,
+		Message:    flux.ErrorMessageConsentRequired,
+		Header:     header,
+		Internal:   errors.New("consent: acceptance required: " + strings.Join(ids, ",")),
+	}
+}
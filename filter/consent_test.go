@@ -0,0 +1,66 @@
+package filter
+
+import (
+	"errors"
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestConsentFilter_DoFilter(t *testing.T) {
+	assert := assert2.New(t)
+	config := flux.NewConfiguration(nil)
+	config.Set(ConsentConfigKeyStatusCode, http.StatusUnavailableForLegalReasons)
+	config.Set(ConsentConfigKeyDocuments, map[string]interface{}{
+		"tos":     map[string]interface{}{"version": "2021-01", "url": "https://flux.io/tos"},
+		"privacy": map[string]interface{}{"version": "v2"},
+	})
+	config.Set(ConsentConfigKeyRequiredDocuments, []string{"tos"})
+	config.Set(ConsentConfigKeyConsumers, map[string]interface{}{
+		"alice": map[string]interface{}{"tos": "2021-01"},
+		"bob":   map[string]interface{}{"tos": "2020-01", "privacy": "v2"},
+	})
+	filter := NewConsentFilter(ConsentConfig{})
+	assert.NoError(filter.Init(config))
+	next := func(ctx flux.Context) *flux.ServeError {
+		return nil
+	}
+	withPrivacy := flux.Endpoint{EmbeddedExtensions: flux.EmbeddedExtensions{
+		Extensions: map[string]interface{}{ConsentExtKeyDocuments: "tos, Privacy"},
+	}}
+	consumer := func(name string, endpoint flux.Endpoint) *flux.ServeError {
+		return filter.DoFilter(next)(newCryptoTestContext(map[string]interface{}{flux.XJwtSubject: name}, endpoint))
+	}
+	assert.Nil(consumer("alice", flux.Endpoint{}))
+	err := consumer("bob", flux.Endpoint{})
+	assert.NotNil(err)
+	assert.Equal(http.StatusUnavailableForLegalReasons, err.StatusCode)
+	assert.Equal(flux.ErrorMessageConsentRequired, err.Message)
+	assert.Equal("tos=2021-01", err.Header.Get(HeaderXConsentRequired))
+	assert.Equal(`<https://flux.io/tos>; rel="terms-of-service"; title="tos"`, err.Header.Get("Link"))
+	err = consumer("alice", withPrivacy)
+	assert.NotNil(err)
+	assert.Equal([]string{"privacy=v2"}, err.Header.Values(HeaderXConsentRequired))
+	assert.Equal(flux.StatusUnauthorized, consumer("", flux.Endpoint{}).StatusCode)
+}
+
+func TestConsentFilter_AcceptedFunc(t *testing.T) {
+	assert := assert2.New(t)
+	config := flux.NewConfiguration(nil)
+	config.Set(ConsentConfigKeyDocuments, map[string]interface{}{"tos": map[string]interface{}{"version": "v1"}})
+	config.Set(ConsentConfigKeyRequiredDocuments, []string{"tos"})
+	filter := NewConsentFilter(ConsentConfig{
+		AcceptedFunc: func(ctx flux.Context, consumer string, document string) (string, error) {
+			return "", errors.New("registry unavailable")
+		},
+	})
+	assert.NoError(filter.Init(config))
+	err := filter.DoFilter(func(ctx flux.Context) *flux.ServeError {
+		return nil
+	})(newCryptoTestContext(map[string]interface{}{flux.XJwtSubject: "alice"}, flux.Endpoint{}))
+	assert.NotNil(err)
+	assert.Equal(flux.StatusServerError, err.StatusCode)
+	config.Set(ConsentConfigKeyStatusCode, 400)
+	assert.Error(NewConsentFilter(ConsentConfig{}).Init(config))
+}
@@ -0,0 +1,182 @@
+package filter
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/pkg"
+	"github.com/spf13/cast"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	TypeIdCostFilter = "CostFilter"
+)
+
+const (
+	CostConfigKeyHeader         = "header"
+	CostConfigKeyEndpointWeight = "endpoint-weight"
+	CostConfigKeyPerKiB         = "per-kib"
+	CostConfigKeyPerSecond      = "per-second"
+)
+
+const (
+	// Endpoint扩展属性：Endpoint的基础成本权重
+	CostExtKeyWeight = "cost-weight"
+	// 设置到Context.Value中的请求成本，类型为float64
+	ValueKeyRequestCost = "request-cost"
+)
+
+const (
+	HeaderXCost = "X-Cost"
+)
+
+type (
+	// CostUsage 请求的资源使用量
+	CostUsage struct {
+		Weight        float64       // Endpoint权重
+		RequestBytes  int64         // 请求体字节数，来自Content-Length
+		ResponseBytes int64         // 响应体字节数；响应体为流时无法统计
+		BackendTime   time.Duration // 后续Filter及后端调用耗时
+		StatusCode    int
+	}
+	// CostFunc 根据资源使用量计算请求成本
+	CostFunc func(ctx flux.Context, usage CostUsage) float64
+	// CostMeteringFunc 接收请求成本计量事件
+	CostMeteringFunc func(ctx flux.Context, usage CostUsage, cost float64)
+)
+
+// CostConfig 请求成本配置
+type CostConfig struct {
+	SkipFunc flux.FilterSkipper
+	// CostFunc 自定义成本模型；默认为线性模型：weight + per-kib * KiB + per-second * 秒
+	CostFunc CostFunc
+	// MeteringFunc 计量事件；默认输出到日志
+	MeteringFunc   CostMeteringFunc
+	header         string
+	endpointWeight float64
+	perKiB         float64
+	perSecond      float64
+}
+
+func NewCostFilter(c CostConfig) *CostFilter {
+	return &CostFilter{
+		Configs: c,
+	}
+}
+
+// CostFilter 计算每个请求的成本，通过响应Header（默认 X-Cost）返回给消费者，并输出计量事件；
+// 成本模型考虑Endpoint权重、请求和响应字节数、后端耗时。
+type CostFilter struct {
+	Disabled bool
+	Configs  CostConfig
+}
+
+func (c *CostFilter) Init(config *flux.Configuration) error {
+	config.SetDefaults(map[string]interface{}{
+		ConfigKeyDisabled:           false,
+		CostConfigKeyHeader:         HeaderXCost,
+		CostConfigKeyEndpointWeight: 1.0,
+		CostConfigKeyPerKiB:         0.01,
+		CostConfigKeyPerSecond:      1.0,
+	})
+	c.Disabled = config.GetBool(ConfigKeyDisabled)
+	if c.Disabled {
+		logger.Info("Endpoint CostFilter was DISABLED!!")
+		return nil
+	}
+	c.Configs.header = config.GetString(CostConfigKeyHeader)
+	c.Configs.endpointWeight = config.GetFloat64(CostConfigKeyEndpointWeight)
+	c.Configs.perKiB = config.GetFloat64(CostConfigKeyPerKiB)
+	c.Configs.perSecond = config.GetFloat64(CostConfigKeyPerSecond)
+	if pkg.IsNil(c.Configs.SkipFunc) {
+		c.Configs.SkipFunc = func(_ flux.Context) bool {
+			return false
+		}
+	}
+	if pkg.IsNil(c.Configs.CostFunc) {
+		c.Configs.CostFunc = c.linearCost
+	}
+	if pkg.IsNil(c.Configs.MeteringFunc) {
+		c.Configs.MeteringFunc = func(ctx flux.Context, usage CostUsage, cost float64) {
+			logger.TraceContext(ctx).Infow("COST:METERING", "cost", cost, "weight", usage.Weight,
+				"request-bytes", usage.RequestBytes, "response-bytes", usage.ResponseBytes,
+				"backend-time", usage.BackendTime.String(), "status", usage.StatusCode)
+		}
+	}
+	return nil
+}
+
+func (*CostFilter) TypeId() string {
+	return TypeIdCostFilter
+}
+
+func (c *CostFilter) DoFilter(next flux.FilterHandler) flux.FilterHandler {
+	if c.Disabled {
+		return next
+	}
+	return func(ctx flux.Context) *flux.ServeError {
+		if c.Configs.SkipFunc(ctx) {
+			return next(ctx)
+		}
+		start := time.Now()
+		serr := next(ctx)
+		usage := CostUsage{
+			Weight:       c.weightOf(ctx.Endpoint()),
+			RequestBytes: cast.ToInt64(ctx.Request().HeaderValue("Content-Length")),
+			BackendTime:  time.Since(start),
+		}
+		if nil != serr {
+			usage.StatusCode = serr.StatusCode
+		} else if resp := ctx.Response(); nil != resp {
+			usage.StatusCode = resp.StatusCode()
+			usage.ResponseBytes = bodySizeOf(resp.Body())
+		}
+		cost := c.Configs.CostFunc(ctx, usage)
+		ctx.SetValue(ValueKeyRequestCost, cost)
+		c.Configs.MeteringFunc(ctx, usage, cost)
+		if "" == c.Configs.header {
+			return serr
+		}
+		value := strconv.FormatFloat(cost, 'f', -1, 64)
+		if nil != serr {
+			if nil == serr.Header {
+				serr.Header = http.Header{}
+			}
+			serr.Header.Set(c.Configs.header, value)
+		} else if resp := ctx.Response(); nil != resp {
+			resp.SetHeader(c.Configs.header, value)
+		}
+		return serr
+	}
+}
+
+func (c *CostFilter) weightOf(endpoint flux.Endpoint) float64 {
+	if v, ok := endpoint.Ext(CostExtKeyWeight); ok {
+		if w, err := cast.ToFloat64E(v); nil == err && w >= 0 {
+			return w
+		}
+	}
+	return c.Configs.endpointWeight
+}
+
+// linearCost 线性成本模型，保留4位小数
+func (c *CostFilter) linearCost(_ flux.Context, usage CostUsage) float64 {
+	cost := usage.Weight +
+		c.Configs.perKiB*float64(usage.RequestBytes+usage.ResponseBytes)/1024 +
+		c.Configs.perSecond*usage.BackendTime.Seconds()
+	return math.Round(cost*10000) / 10000
+}
+
+func bodySizeOf(body interface{}) int64 {
+	switch b := body.(type) {
+	case []byte:
+		return int64(len(b))
+	case string:
+		return int64(len(b))
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,40 @@
+package filter
+
+import (
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestCostFilter_DoFilter(t *testing.T) {
+	assert := assert2.New(t)
+	config := flux.NewConfiguration(nil)
+	config.Set(CostConfigKeyPerKiB, 1.0)
+	config.Set(CostConfigKeyPerSecond, 0)
+	var metered float64
+	filter := NewCostFilter(CostConfig{
+		MeteringFunc: func(ctx flux.Context, usage CostUsage, cost float64) {
+			metered = cost
+		},
+	})
+	assert.NoError(filter.Init(config))
+	endpoint := flux.Endpoint{EmbeddedExtensions: flux.EmbeddedExtensions{
+		Extensions: map[string]interface{}{CostExtKeyWeight: "3"},
+	}}
+	ctx := newCryptoTestContext(map[string]interface{}{"Content-Length": "1024"}, endpoint)
+	assert.Nil(filter.DoFilter(func(ctx flux.Context) *flux.ServeError {
+		ctx.Response().SetBody([]byte(strings.Repeat("x", 512)))
+		return nil
+	})(ctx))
+	assert.Equal("4.5", ctx.response.header.Get(HeaderXCost))
+	assert.Equal(4.5, metered)
+	v, _ := ctx.GetValue(ValueKeyRequestCost)
+	assert.Equal(4.5, v)
+	// 错误响应
+	err := filter.DoFilter(func(ctx flux.Context) *flux.ServeError {
+		return &flux.ServeError{StatusCode: flux.StatusBadGateway}
+	})(newCryptoTestContext(map[string]interface{}{}, flux.Endpoint{}))
+	assert.NotNil(err)
+	assert.Equal("1", err.Header.Get(HeaderXCost))
+}
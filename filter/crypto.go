@@ -0,0 +1,382 @@
+package filter
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/pkg"
+	"github.com/bytepowered/flux/support"
+	"github.com/spf13/cast"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+const (
+	TypeIdCryptoFilter = "CryptoFilter"
+)
+
+const (
+	CryptoConfigKeyKeyId          = "key-id"
+	CryptoConfigKeyRotationKeyIds = "rotation-key-ids"
+	CryptoConfigKeyDecryptFields  = "decrypt-fields"
+	CryptoConfigKeyEncryptFields  = "encrypt-fields"
+)
+
+const (
+	// Endpoint扩展信息：需要解密的请求字段列表，覆盖Filter配置的 decrypt-fields；
+	// 字段格式为LookupExpr，例如：QUERY:cardNo。
+	// 解密后的明文不会替换原始请求值，而是以 CryptoValueKey(scope, key) 为键设置到Context中，
+	// Endpoint参数需要使用VALUE域引用明文，例如：httpScope=VALUE, httpName=crypto.QUERY.cardNo
+	EndpointExtKeyCryptoDecryptFields = "crypto-decrypt-fields"
+	// Endpoint扩展信息：需要加密的响应字段列表，覆盖Filter配置的 encrypt-fields；
+	// 支持以.分隔的嵌套字段，例如：user.idCard；字段值必须为字符串、数值或布尔类型。
+	EndpointExtKeyCryptoEncryptFields = "crypto-encrypt-fields"
+)
+
+const (
+	// 解密明文在Context中的键前缀
+	cryptoValueKeyPrefix = "crypto."
+	// 密文格式：{keyId}:{base64(nonce+ciphertext)}
+	cryptoKeyIdSeparator = ":"
+	// AAD格式：{keyId}|{field}，将密文绑定到KeyId和字段
+	cryptoAADSeparator = "|"
+)
+
+var (
+	ErrCryptoInvalidCipherText = errors.New("crypto: invalid cipher text")
+	ErrCryptoKeyIdNotAllowed   = errors.New("crypto: key-id not allowed")
+	ErrCryptoUnsupportedValue  = errors.New("crypto: unsupported non-scalar field value")
+)
+
+// CryptoConfig 字段加解密配置
+type CryptoConfig struct {
+	SkipFunc       flux.FilterSkipper
+	SecretProvider flux.SecretProvider
+	keyIds         []string // 第一个为当前加密使用的KeyId，其余为允许解密的轮换KeyId
+	decryptFields  []string
+	encryptFields  []string
+}
+
+func NewCryptoFilter(c CryptoConfig) *CryptoFilter {
+	return &CryptoFilter{
+		Configs: c,
+	}
+}
+
+// CryptoFilter 提供请求/响应字段级别的加解密功能（AES-GCM）。
+// 请求字段：按LookupExpr查找密文并解密，明文设置到Context，见 EndpointExtKeyCryptoDecryptFields；
+// 响应字段：对响应Body中指定字段的明文进行加密；无法处理的响应Body将返回错误，确保敏感数据不以明文形式到达客户端。
+type CryptoFilter struct {
+	Disabled bool
+	Configs  CryptoConfig
+}
+
+func (c *CryptoFilter) Init(config *flux.Configuration) error {
+	config.SetDefaults(map[string]interface{}{
+		ConfigKeyDisabled: false,
+	})
+	c.Disabled = config.GetBool(ConfigKeyDisabled)
+	if c.Disabled {
+		logger.Info("Endpoint CryptoFilter was DISABLED!!")
+		return nil
+	}
+	keyId := config.GetString(CryptoConfigKeyKeyId)
+	if "" == keyId {
+		return errors.New("CryptoFilter.key-id is empty")
+	}
+	c.Configs.keyIds = append([]string{keyId}, config.GetStringSlice(CryptoConfigKeyRotationKeyIds)...)
+	c.Configs.decryptFields = config.GetStringSlice(CryptoConfigKeyDecryptFields)
+	c.Configs.encryptFields = config.GetStringSlice(CryptoConfigKeyEncryptFields)
+	if pkg.IsNil(c.Configs.SkipFunc) {
+		c.Configs.SkipFunc = func(_ flux.Context) bool {
+			return false
+		}
+	}
+	if pkg.IsNil(c.Configs.SecretProvider) {
+		c.Configs.SecretProvider = ext.LoadSecretProvider()
+	}
+	if pkg.IsNil(c.Configs.SecretProvider) {
+		return errors.New("CryptoFilter.SecretProvider is nil")
+	}
+	return nil
+}
+
+func (*CryptoFilter) TypeId() string {
+	return TypeIdCryptoFilter
+}
+
+func (c *CryptoFilter) DoFilter(next flux.FilterHandler) flux.FilterHandler {
+	if c.Disabled {
+		return next
+	}
+	return func(ctx flux.Context) *flux.ServeError {
+		if c.Configs.SkipFunc(ctx) {
+			return next(ctx)
+		}
+		endpoint := ctx.Endpoint()
+		if err := c.decryptRequest(ctx, c.fieldsOf(endpoint, EndpointExtKeyCryptoDecryptFields, c.Configs.decryptFields)); nil != err {
+			return err
+		}
+		ctx.AddMetric("M-"+c.TypeId(), ctx.ElapsedTime())
+		if err := next(ctx); nil != err {
+			return err
+		}
+		return c.encryptResponse(ctx, c.fieldsOf(endpoint, EndpointExtKeyCryptoEncryptFields, c.Configs.encryptFields))
+	}
+}
+
+func (c *CryptoFilter) decryptRequest(ctx flux.Context, fields []string) *flux.ServeError {
+	for _, expr := range fields {
+		scope, key, ok := support.ParseLookupExpr(expr)
+		if !ok {
+			logger.TraceContext(ctx).Warnw("CryptoFilter illegal decrypt field", "field", expr)
+			continue
+		}
+		value, err := support.LookupContextByExpr(expr, ctx)
+		if nil != err {
+			logger.TraceContext(ctx).Warnw("CryptoFilter lookup decrypt field", "field", expr, "error", err)
+			continue
+		}
+		text := cast.ToString(value)
+		if "" == text {
+			logger.TraceContext(ctx).Warnw("CryptoFilter decrypt field is missing or empty", "field", expr)
+			continue
+		}
+		plain, err := DecryptFieldValue(c.Configs.SecretProvider, c.Configs.keyIds, key, text)
+		if nil != err {
+			return &flux.ServeError{
+				StatusCode: flux.StatusBadRequest,
+				ErrorCode:  flux.ErrorCodeRequestInvalid,
+				Message:    flux.ErrorMessageCryptoDecryptFailed,
+				Internal:   fmt.Errorf("decrypt field: %s, error: %w", expr, err),
+			}
+		}
+		ctx.SetValue(CryptoValueKey(scope, key), plain)
+	}
+	return nil
+}
+
+func (c *CryptoFilter) encryptResponse(ctx flux.Context, fields []string) *flux.ServeError {
+	if len(fields) == 0 {
+		return nil
+	}
+	newEncryptError := func(err error) *flux.ServeError {
+		return &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageCryptoEncryptFailed,
+			Internal:   err,
+		}
+	}
+	body, err := toCryptoBody(ctx.Response().Body())
+	if nil != err {
+		return newEncryptError(fmt.Errorf("decode response body: %w", err))
+	}
+	keyId := c.Configs.keyIds[0]
+	for _, field := range fields {
+		if err := encryptBodyField(body, strings.Split(field, "."), func(plain string) (string, error) {
+			return EncryptFieldValue(c.Configs.SecretProvider, keyId, field, plain)
+		}); nil != err {
+			return newEncryptError(fmt.Errorf("encrypt field: %s, error: %w", field, err))
+		}
+	}
+	// 响应Body已被重新编码，原始长度失效
+	ctx.Response().HeaderValues().Del(flux.HeaderContentLength)
+	ctx.Response().SetBody(body)
+	return nil
+}
+
+func (c *CryptoFilter) fieldsOf(endpoint flux.Endpoint, extKey string, defaults []string) []string {
+	if v, ok := endpoint.Ext(extKey); ok {
+		return cast.ToStringSlice(v)
+	}
+	return defaults
+}
+
+// CryptoValueKey 返回请求字段解密后明文在Context中的键：crypto.{SCOPE}.{key}
+func CryptoValueKey(scope, key string) string {
+	return cryptoValueKeyPrefix + strings.ToUpper(scope) + "." + key
+}
+
+// EncryptFieldValue 使用指定KeyId的密钥加密字段值，返回格式为：{keyId}:{base64(nonce+ciphertext)}；
+// 密文通过AAD绑定到KeyId和字段名，不能在其它字段中重放。
+func EncryptFieldValue(provider flux.SecretProvider, keyId string, field string, plain string) (string, error) {
+	secret, err := provider.LoadSecret(keyId)
+	if nil != err {
+		return "", err
+	}
+	data, err := aesGCMEncrypt(secret, []byte(plain), cryptoAAD(keyId, field))
+	if nil != err {
+		return "", err
+	}
+	return keyId + cryptoKeyIdSeparator + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// DecryptFieldValue 解密字段值。密文携带的KeyId必须在允许列表中；未携带KeyId时，使用列表中的第一个KeyId。
+func DecryptFieldValue(provider flux.SecretProvider, allowedKeyIds []string, field string, text string) (string, error) {
+	if len(allowedKeyIds) == 0 {
+		return "", ErrCryptoKeyIdNotAllowed
+	}
+	keyId, encoded := allowedKeyIds[0], text
+	if idx := strings.LastIndex(text, cryptoKeyIdSeparator); idx >= 0 {
+		keyId, encoded = text[:idx], text[idx+1:]
+		if !pkg.StringSliceContains(allowedKeyIds, keyId) {
+			return "", ErrCryptoKeyIdNotAllowed
+		}
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if nil != err {
+		return "", ErrCryptoInvalidCipherText
+	}
+	secret, err := provider.LoadSecret(keyId)
+	if nil != err {
+		return "", err
+	}
+	plain, err := aesGCMDecrypt(secret, data, cryptoAAD(keyId, field))
+	if nil != err {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+func cryptoAAD(keyId, field string) []byte {
+	return []byte(keyId + cryptoAADSeparator + field)
+}
+
+// toCryptoBody 将响应Body转换为可按字段处理的通用结构；
+// Reader/[]byte/string 按JSON解析，其它非通用结构的对象经JSON序列化后再解析。
+func toCryptoBody(body interface{}) (interface{}, error) {
+	var data []byte
+	switch v := body.(type) {
+	case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+		return body, nil
+	case nil:
+		return nil, errors.New("response body is nil")
+	case io.Reader:
+		bytes, err := ioutil.ReadAll(v)
+		if closer, ok := v.(io.Closer); ok {
+			_ = closer.Close()
+		}
+		if nil != err {
+			return nil, err
+		}
+		data = bytes
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		bytes, err := ext.JSONMarshal(v)
+		if nil != err {
+			return nil, err
+		}
+		data = bytes
+	}
+	var out interface{}
+	if err := ext.JSONUnmarshal(data, &out); nil != err {
+		return nil, err
+	}
+	switch out.(type) {
+	case map[string]interface{}, []interface{}:
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unsupported response body, type: %T", out)
+	}
+}
+
+func encryptBodyField(body interface{}, path []string, encrypt func(string) (string, error)) error {
+	if len(path) == 0 {
+		return nil
+	}
+	key, last := path[0], len(path) == 1
+	var (
+		value interface{}
+		found bool
+		store func(string)
+	)
+	switch m := body.(type) {
+	case map[string]interface{}:
+		value, found = m[key]
+		store = func(s string) { m[key] = s }
+	case map[interface{}]interface{}:
+		value, found = m[key]
+		store = func(s string) { m[key] = s }
+	case []interface{}:
+		for _, item := range m {
+			if err := encryptBodyField(item, path, encrypt); nil != err {
+				return err
+			}
+		}
+		return nil
+	default:
+		return nil
+	}
+	if !found || nil == value {
+		return nil
+	}
+	if !last {
+		return encryptBodyField(value, path[1:], encrypt)
+	}
+	plain, ok := toScalarString(value)
+	if !ok {
+		return fmt.Errorf("%w, type: %T", ErrCryptoUnsupportedValue, value)
+	}
+	if text, err := encrypt(plain); nil != err {
+		return err
+	} else {
+		store(text)
+	}
+	return nil
+}
+
+func toScalarString(v interface{}) (string, bool) {
+	switch v.(type) {
+	case string, json.Number, bool,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64,
+		float32, float64:
+		return cast.ToString(v), true
+	default:
+		return "", false
+	}
+}
+
+func aesGCMEncrypt(secret, plain, aad []byte) ([]byte, error) {
+	gcm, err := newAesGCM(secret)
+	if nil != err {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); nil != err {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, aad), nil
+}
+
+func aesGCMDecrypt(secret, data, aad []byte) ([]byte, error) {
+	gcm, err := newAesGCM(secret)
+	if nil != err {
+		return nil, err
+	}
+	size := gcm.NonceSize()
+	if len(data) < size {
+		return nil, ErrCryptoInvalidCipherText
+	}
+	return gcm.Open(nil, data[:size], data[size:], aad)
+}
+
+func newAesGCM(secret []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(secret)
+	if nil != err {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
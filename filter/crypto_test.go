@@ -0,0 +1,236 @@
+package filter
+
+import (
+	"errors"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/support"
+	assert2 "github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+var testSecretProvider = flux.SecretProviderFunc(func(keyId string) ([]byte, error) {
+	switch keyId {
+	case "k1":
+		return []byte("0123456789abcdef"), nil
+	case "k2":
+		return []byte("fedcba9876543210fedcba9876543210"), nil
+	case "k3":
+		return []byte("abcdef0123456789"), nil
+	default:
+		return nil, errors.New("not found")
+	}
+})
+
+func TestCryptoFieldValue(t *testing.T) {
+	assert := assert2.New(t)
+	allowed := []string{"k1", "k2"}
+	for _, keyId := range allowed {
+		text, err := EncryptFieldValue(testSecretProvider, keyId, "cardNo", "6222000011112222")
+		assert.NoError(err)
+		assert.True(strings.HasPrefix(text, keyId+":"))
+		plain, err := DecryptFieldValue(testSecretProvider, allowed, "cardNo", text)
+		assert.NoError(err)
+		assert.Equal("6222000011112222", plain)
+		// AAD绑定字段名
+		_, err = DecryptFieldValue(testSecretProvider, allowed, "phone", text)
+		assert.Error(err)
+	}
+	// 不在允许列表中的KeyId
+	text, err := EncryptFieldValue(testSecretProvider, "k3", "cardNo", "6222000011112222")
+	assert.NoError(err)
+	_, err = DecryptFieldValue(testSecretProvider, allowed, "cardNo", text)
+	assert.Equal(ErrCryptoKeyIdNotAllowed, err)
+	_, err = DecryptFieldValue(testSecretProvider, allowed, "cardNo", "not-base64!")
+	assert.Error(err)
+}
+
+func TestCryptoEncryptBodyField(t *testing.T) {
+	assert := assert2.New(t)
+	body := map[string]interface{}{
+		"name": "yongjia",
+		"age":  18,
+		"user": map[interface{}]interface{}{
+			"idCard": "330100",
+		},
+		"list": []interface{}{
+			map[string]interface{}{"phone": "138"},
+		},
+	}
+	encrypt := func(plain string) (string, error) {
+		return "enc(" + plain + ")", nil
+	}
+	for _, field := range []string{"user.idCard", "list.phone", "age", "missing.field"} {
+		assert.NoError(encryptBodyField(body, strings.Split(field, "."), encrypt))
+	}
+	assert.Equal("yongjia", body["name"])
+	assert.Equal("enc(18)", body["age"])
+	assert.Equal("enc(330100)", body["user"].(map[interface{}]interface{})["idCard"])
+	assert.Equal("enc(138)", body["list"].([]interface{})[0].(map[string]interface{})["phone"])
+	// 非标量字段
+	err := encryptBodyField(body, []string{"user"}, encrypt)
+	assert.True(errors.Is(err, ErrCryptoUnsupportedValue))
+}
+
+func TestCryptoFilter_Init(t *testing.T) {
+	cases := []struct {
+		provider flux.SecretProvider
+		keyId    string
+		isError  bool
+	}{
+		{provider: testSecretProvider, keyId: "k1", isError: false},
+		{provider: testSecretProvider, keyId: "", isError: true},
+		{provider: nil, keyId: "k1", isError: true},
+	}
+	assert := assert2.New(t)
+	for _, c := range cases {
+		config := flux.NewConfiguration(nil)
+		config.Set(CryptoConfigKeyKeyId, c.keyId)
+		f := NewCryptoFilter(CryptoConfig{SecretProvider: c.provider})
+		err := f.Init(config)
+		if c.isError {
+			assert.Error(err)
+		} else {
+			assert.NoError(err)
+		}
+	}
+}
+
+func TestCryptoFilter_DoFilter(t *testing.T) {
+	assert := assert2.New(t)
+	filter := newTestCryptoFilter(t)
+	// 使用轮换KeyId加密的密文
+	cardNo, _ := EncryptFieldValue(testSecretProvider, "k2", "cardNo", "6222000011112222")
+	ctx := newCryptoTestContext(map[string]interface{}{"cardNo": cardNo}, flux.Endpoint{})
+	err := filter.DoFilter(func(ctx flux.Context) *flux.ServeError {
+		ctx.Response().SetBody(map[string]interface{}{"idCard": "330100", "name": "yongjia"})
+		return nil
+	})(ctx)
+	assert.Nil(err)
+	assert.Equal("6222000011112222", ctx.GetValueString(CryptoValueKey(flux.ScopeQuery, "cardNo"), ""))
+	body := ctx.response.Body().(map[string]interface{})
+	assert.Equal("yongjia", body["name"])
+	plain, derr := DecryptFieldValue(testSecretProvider, []string{"k1"}, "idCard", body["idCard"].(string))
+	assert.NoError(derr)
+	assert.Equal("330100", plain)
+}
+
+func TestCryptoFilter_DoFilterErrors(t *testing.T) {
+	assert := assert2.New(t)
+	filter := newTestCryptoFilter(t)
+	// 错误的密文
+	ctx := newCryptoTestContext(map[string]interface{}{"cardNo": "k1:AAAAAAAAAAAAAAAAAAAAAAAA"}, flux.Endpoint{})
+	err := filter.DoFilter(func(ctx flux.Context) *flux.ServeError {
+		assert.Fail("should not invoke next")
+		return nil
+	})(ctx)
+	assert.NotNil(err)
+	assert.Equal(flux.StatusBadRequest, err.StatusCode)
+	assert.Equal(flux.ErrorMessageCryptoDecryptFailed, err.Message)
+	// 不支持的响应Body
+	ctx = newCryptoTestContext(map[string]interface{}{}, flux.Endpoint{})
+	err = filter.DoFilter(func(ctx flux.Context) *flux.ServeError {
+		ctx.Response().SetBody(ioutil.NopCloser(strings.NewReader("not-json")))
+		return nil
+	})(ctx)
+	assert.NotNil(err)
+	assert.Equal(flux.StatusServerError, err.StatusCode)
+	assert.Equal(flux.ErrorMessageCryptoEncryptFailed, err.Message)
+}
+
+func TestCryptoFilter_DoFilterReaderBody(t *testing.T) {
+	assert := assert2.New(t)
+	filter := newTestCryptoFilter(t)
+	ctx := newCryptoTestContext(map[string]interface{}{}, flux.Endpoint{})
+	err := filter.DoFilter(func(ctx flux.Context) *flux.ServeError {
+		ctx.Response().SetHeader(flux.HeaderContentLength, "35")
+		ctx.Response().SetBody(ioutil.NopCloser(strings.NewReader(`{"idCard":"330100","name":"yongjia"}`)))
+		return nil
+	})(ctx)
+	assert.Nil(err)
+	assert.Equal("", ctx.response.HeaderValues().Get(flux.HeaderContentLength))
+	body := ctx.response.Body().(map[string]interface{})
+	assert.Equal("yongjia", body["name"])
+	assert.True(strings.HasPrefix(body["idCard"].(string), "k1:"))
+}
+
+func TestCryptoFilter_DoFilterEndpointExt(t *testing.T) {
+	assert := assert2.New(t)
+	filter := newTestCryptoFilter(t)
+	phone, _ := EncryptFieldValue(testSecretProvider, "k1", "phone", "13800001111")
+	endpoint := flux.Endpoint{}
+	endpoint.Extensions = map[string]interface{}{
+		EndpointExtKeyCryptoDecryptFields: []string{"HEADER:phone"},
+		EndpointExtKeyCryptoEncryptFields: []string{"user.name"},
+	}
+	ctx := newCryptoTestContext(map[string]interface{}{"phone": phone}, endpoint)
+	err := filter.DoFilter(func(ctx flux.Context) *flux.ServeError {
+		ctx.Response().SetBody(map[string]interface{}{
+			"idCard": "330100",
+			"user":   map[string]interface{}{"name": "yongjia"},
+		})
+		return nil
+	})(ctx)
+	assert.Nil(err)
+	assert.Equal("13800001111", ctx.GetValueString(CryptoValueKey(flux.ScopeHeader, "phone"), ""))
+	_, ok := ctx.GetValue(CryptoValueKey(flux.ScopeQuery, "cardNo"))
+	assert.False(ok)
+	body := ctx.response.Body().(map[string]interface{})
+	assert.Equal("330100", body["idCard"])
+	assert.True(strings.HasPrefix(body["user"].(map[string]interface{})["name"].(string), "k1:"))
+}
+
+func newTestCryptoFilter(t *testing.T) *CryptoFilter {
+	ext.StoreSerializer(ext.TypeNameSerializerJson, flux.NewJsonSerializer())
+	config := flux.NewConfiguration(nil)
+	config.Set(CryptoConfigKeyKeyId, "k1")
+	config.Set(CryptoConfigKeyRotationKeyIds, []string{"k2"})
+	config.Set(CryptoConfigKeyDecryptFields, []string{"QUERY:cardNo"})
+	config.Set(CryptoConfigKeyEncryptFields, []string{"idCard"})
+	f := NewCryptoFilter(CryptoConfig{SecretProvider: testSecretProvider})
+	assert2.NoError(t, f.Init(config))
+	return f
+}
+
+type cryptoTestContext struct {
+	*support.ValuesContext
+	endpoint flux.Endpoint
+	response *cryptoTestResponse
+}
+
+func newCryptoTestContext(values map[string]interface{}, endpoint flux.Endpoint) *cryptoTestContext {
+	ctx := support.NewValuesContext(values).(*support.ValuesContext)
+	ctx.SetContextLogger(logger.SimpleLogger())
+	return &cryptoTestContext{
+		ValuesContext: ctx,
+		endpoint:      endpoint,
+		response:      &cryptoTestResponse{header: http.Header{}},
+	}
+}
+
+func (c *cryptoTestContext) Endpoint() flux.Endpoint {
+	return c.endpoint
+}
+
+func (c *cryptoTestContext) Response() flux.ResponseWriter {
+	return c.response
+}
+
+type cryptoTestResponse struct {
+	status int
+	header http.Header
+	body   interface{}
+}
+
+func (r *cryptoTestResponse) SetStatusCode(status int)       { r.status = status }
+func (r *cryptoTestResponse) StatusCode() int                { return r.status }
+func (r *cryptoTestResponse) HeaderValues() http.Header      { return r.header }
+func (r *cryptoTestResponse) AddHeader(name, value string)   { r.header.Add(name, value) }
+func (r *cryptoTestResponse) SetHeader(name, value string)   { r.header.Set(name, value) }
+func (r *cryptoTestResponse) SetHeaders(headers http.Header) { r.header = headers }
+func (r *cryptoTestResponse) SetBody(body interface{})       { r.body = body }
+func (r *cryptoTestResponse) Body() interface{}              { return r.body }
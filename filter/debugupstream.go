@@ -0,0 +1,226 @@
+package filter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/backend"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/pkg"
+	"github.com/spf13/cast"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TypeIdDebugUpstreamFilter = "DebugUpstreamFilter"
+)
+
+const (
+	DebugUpstreamConfigKeyProfile         = "profile"
+	DebugUpstreamConfigKeyAllowedProfiles = "allowed-profiles"
+	DebugUpstreamConfigKeySecretKeyId     = "secret-key-id"
+	DebugUpstreamConfigKeyMaxAge          = "max-age"
+)
+
+const (
+	// Endpoint扩展属性：允许覆盖的后端服务地址列表，按 path.Match 规则匹配 host:port，例如 ["127.0.0.1:*", "*.dev.local:8080"]；
+	// 未配置时Endpoint不允许覆盖后端服务地址
+	EndpointExtKeyDebugUpstreamHosts = "debug-upstream-hosts"
+)
+
+const (
+	// 覆盖的后端服务地址（host:port）
+	HeaderXDebugUpstream = "X-Debug-Upstream"
+	// 签名的过期时间，Unix秒
+	HeaderXDebugUpstreamExpires = "X-Debug-Upstream-Expires"
+	// 签名：hex(HMAC-SHA256(secret, host + "\n" + expires))
+	HeaderXDebugUpstreamSignature = "X-Debug-Upstream-Signature"
+	// 网关运行环境的环境变量；未配置 profile 时使用
+	EnvKeyFluxProfile = "FLUX_PROFILE"
+)
+
+var (
+	ErrDebugUpstreamSignatureInvalid = errors.New("debug-upstream: signature invalid")
+	ErrDebugUpstreamExpired          = errors.New("debug-upstream: signature expired")
+)
+
+// DebugUpstreamConfig 调试覆盖后端服务地址的配置
+type DebugUpstreamConfig struct {
+	SkipFunc flux.FilterSkipper
+	// SecretProvider 签名密钥的提供接口；默认使用全局SecretProvider
+	SecretProvider  flux.SecretProvider
+	profile         string
+	allowedProfiles []string
+	secretKeyId     string
+	maxAge          time.Duration
+}
+
+func NewDebugUpstreamFilter(c DebugUpstreamConfig) *DebugUpstreamFilter {
+	return &DebugUpstreamFilter{
+		Configs: c,
+	}
+}
+
+// DebugUpstreamFilter 调试功能：携带有效签名Header（X-Debug-Upstream）的请求，可将本次请求的后端服务地址覆盖为指定地址，
+// 使开发者通过与生产一致的网关链路测试本地服务。默认关闭；只在 allowed-profiles 指定的非生产环境中生效，
+// 且目标地址需匹配Endpoint声明的 debug-upstream-hosts 列表。
+type DebugUpstreamFilter struct {
+	Disabled bool
+	Configs  DebugUpstreamConfig
+}
+
+func (d *DebugUpstreamFilter) Init(config *flux.Configuration) error {
+	config.SetDefaults(map[string]interface{}{
+		ConfigKeyDisabled:                     true,
+		DebugUpstreamConfigKeyProfile:         os.Getenv(EnvKeyFluxProfile),
+		DebugUpstreamConfigKeyAllowedProfiles: []string{"dev", "test"},
+		DebugUpstreamConfigKeyMaxAge:          "1h",
+	})
+	d.Disabled = config.GetBool(ConfigKeyDisabled)
+	if d.Disabled {
+		logger.Info("Endpoint DebugUpstreamFilter was DISABLED!!")
+		return nil
+	}
+	d.Configs.profile = config.GetString(DebugUpstreamConfigKeyProfile)
+	d.Configs.allowedProfiles = config.GetStringSlice(DebugUpstreamConfigKeyAllowedProfiles)
+	if !d.profileAllowed() {
+		// 生产等未允许的环境中强制关闭
+		d.Disabled = true
+		logger.Warnw("Endpoint DebugUpstreamFilter was DISABLED, profile not allowed",
+			"profile", d.Configs.profile, "allowed-profiles", d.Configs.allowedProfiles)
+		return nil
+	}
+	d.Configs.secretKeyId = config.GetString(DebugUpstreamConfigKeySecretKeyId)
+	if "" == d.Configs.secretKeyId {
+		return errors.New("DebugUpstreamFilter.secret-key-id is required")
+	}
+	d.Configs.maxAge = config.GetDuration(DebugUpstreamConfigKeyMaxAge)
+	if pkg.IsNil(d.Configs.SecretProvider) {
+		d.Configs.SecretProvider = ext.LoadSecretProvider()
+	}
+	if pkg.IsNil(d.Configs.SecretProvider) {
+		return errors.New("DebugUpstreamFilter.SecretProvider is nil")
+	}
+	if pkg.IsNil(d.Configs.SkipFunc) {
+		d.Configs.SkipFunc = func(_ flux.Context) bool {
+			return false
+		}
+	}
+	logger.Warnw("Endpoint DebugUpstreamFilter was ENABLED", "profile", d.Configs.profile)
+	return nil
+}
+
+func (*DebugUpstreamFilter) TypeId() string {
+	return TypeIdDebugUpstreamFilter
+}
+
+func (d *DebugUpstreamFilter) DoFilter(next flux.FilterHandler) flux.FilterHandler {
+	if d.Disabled {
+		return next
+	}
+	return func(ctx flux.Context) *flux.ServeError {
+		if d.Configs.SkipFunc(ctx) {
+			return next(ctx)
+		}
+		header, writable := ctx.Request().HeaderValues()
+		host := header.Get(HeaderXDebugUpstream)
+		if "" == host {
+			return next(ctx)
+		}
+		if err := d.verify(host, header.Get(HeaderXDebugUpstreamExpires), header.Get(HeaderXDebugUpstreamSignature), time.Now()); nil != err {
+			return &flux.ServeError{
+				StatusCode: flux.StatusBadRequest,
+				ErrorCode:  flux.ErrorCodeRequestInvalid,
+				Message:    flux.ErrorMessageDebugUpstreamInvalid,
+				Internal:   err,
+			}
+		}
+		endpoint := ctx.Endpoint()
+		if !debugUpstreamAllowed(endpoint, host) {
+			return &flux.ServeError{
+				StatusCode: flux.StatusAccessDenied,
+				ErrorCode:  flux.ErrorCodePermissionDenied,
+				Message:    flux.ErrorMessageDebugUpstreamNotAllowed,
+				Internal:   fmt.Errorf("debug-upstream: host not allowed, host: %s, pattern: %s", host, endpoint.HttpPattern),
+			}
+		}
+		// 调试Header不转发到后端服务
+		if writable {
+			header.Del(HeaderXDebugUpstream)
+			header.Del(HeaderXDebugUpstreamExpires)
+			header.Del(HeaderXDebugUpstreamSignature)
+		}
+		logger.TraceContext(ctx).Warnw("DebugUpstreamFilter override upstream", "host", host, "pattern", endpoint.HttpPattern)
+		ctx.SetValue(backend.ValueKeyUpstreamOverride, host)
+		return next(ctx)
+	}
+}
+
+// verify 验证签名和过期时间；过期时间不能超过当前时间加 max-age
+func (d *DebugUpstreamFilter) verify(host, expires, signature string, now time.Time) error {
+	if _, _, err := net.SplitHostPort(host); nil != err {
+		return fmt.Errorf("debug-upstream: invalid host: %w", err)
+	}
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if nil != err {
+		return ErrDebugUpstreamExpired
+	}
+	at := time.Unix(exp, 0)
+	if now.After(at) || (d.Configs.maxAge > 0 && at.Sub(now) > d.Configs.maxAge) {
+		return ErrDebugUpstreamExpired
+	}
+	sig, err := hex.DecodeString(signature)
+	if nil != err {
+		return ErrDebugUpstreamSignatureInvalid
+	}
+	secret, err := d.Configs.SecretProvider.LoadSecret(d.Configs.secretKeyId)
+	if nil != err {
+		return err
+	}
+	if !hmac.Equal(sig, SignDebugUpstream(secret, host, exp)) {
+		return ErrDebugUpstreamSignatureInvalid
+	}
+	return nil
+}
+
+func (d *DebugUpstreamFilter) profileAllowed() bool {
+	if "" == d.Configs.profile {
+		return false
+	}
+	for _, p := range d.Configs.allowedProfiles {
+		if strings.EqualFold(p, d.Configs.profile) {
+			return true
+		}
+	}
+	return false
+}
+
+// SignDebugUpstream 计算覆盖后端服务地址的签名：HMAC-SHA256(secret, host + "\n" + expires)
+func SignDebugUpstream(secret []byte, host string, expires int64) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(host + "\n" + strconv.FormatInt(expires, 10)))
+	return mac.Sum(nil)
+}
+
+// debugUpstreamAllowed 返回目标地址是否匹配Endpoint允许的地址列表
+func debugUpstreamAllowed(endpoint flux.Endpoint, host string) bool {
+	v, ok := endpoint.Ext(EndpointExtKeyDebugUpstreamHosts)
+	if !ok {
+		return false
+	}
+	for _, pattern := range cast.ToStringSlice(v) {
+		if matched, err := path.Match(strings.TrimSpace(pattern), host); nil == err && matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package filter
+
+import (
+	"encoding/hex"
+	"errors"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/backend"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestDebugUpstreamFilter(t *testing.T, profile string) *DebugUpstreamFilter {
+	config := flux.NewConfiguration(nil)
+	config.Set(ConfigKeyDisabled, false)
+	config.Set(DebugUpstreamConfigKeyProfile, profile)
+	config.Set(DebugUpstreamConfigKeySecretKeyId, "debug")
+	filter := NewDebugUpstreamFilter(DebugUpstreamConfig{
+		SecretProvider: flux.SecretProviderFunc(func(keyId string) ([]byte, error) {
+			if "debug" == keyId {
+				return []byte("debug-secret"), nil
+			}
+			return nil, errors.New("not found")
+		}),
+	})
+	assert2.NoError(t, filter.Init(config))
+	return filter
+}
+
+func newDebugUpstreamHeader(host string, expires int64, secret string) http.Header {
+	header := http.Header{}
+	header.Set(HeaderXDebugUpstream, host)
+	header.Set(HeaderXDebugUpstreamExpires, strconv.FormatInt(expires, 10))
+	header.Set(HeaderXDebugUpstreamSignature, hex.EncodeToString(SignDebugUpstream([]byte(secret), host, expires)))
+	return header
+}
+
+func TestDebugUpstreamFilter_DoFilter(t *testing.T) {
+	assert := assert2.New(t)
+	filter := newTestDebugUpstreamFilter(t, "dev")
+	assert.False(filter.Disabled)
+	endpoint := flux.Endpoint{HttpPattern: "/debug", EmbeddedExtensions: flux.EmbeddedExtensions{
+		Extensions: map[string]interface{}{EndpointExtKeyDebugUpstreamHosts: []string{"127.0.0.1:*"}},
+	}}
+	expires := time.Now().Add(time.Minute).Unix()
+	cases := []struct {
+		header   http.Header
+		endpoint flux.Endpoint
+		status   int
+		override string
+	}{
+		{header: http.Header{}, endpoint: endpoint},
+		{header: newDebugUpstreamHeader("127.0.0.1:8080", expires, "debug-secret"), endpoint: endpoint, override: "127.0.0.1:8080"},
+		{header: newDebugUpstreamHeader("127.0.0.1:8080", expires, "other-secret"), endpoint: endpoint, status: flux.StatusBadRequest},
+		{header: newDebugUpstreamHeader("127.0.0.1:8080", time.Now().Add(-time.Minute).Unix(), "debug-secret"), endpoint: endpoint, status: flux.StatusBadRequest},
+		{header: newDebugUpstreamHeader("127.0.0.1:8080", time.Now().Add(2*time.Hour).Unix(), "debug-secret"), endpoint: endpoint, status: flux.StatusBadRequest},
+		{header: newDebugUpstreamHeader("10.0.0.1:8080", expires, "debug-secret"), endpoint: endpoint, status: flux.StatusAccessDenied},
+		{header: newDebugUpstreamHeader("127.0.0.1:8080", expires, "debug-secret"), endpoint: flux.Endpoint{}, status: flux.StatusAccessDenied},
+	}
+	for i, c := range cases {
+		ctx := newCryptoTestContext(map[string]interface{}{"header-values": c.header}, c.endpoint)
+		err := filter.DoFilter(func(ctx flux.Context) *flux.ServeError {
+			return nil
+		})(ctx)
+		if 0 == c.status {
+			assert.Nil(err, "case: %d", i)
+		} else if assert.NotNil(err, "case: %d", i) {
+			assert.Equal(c.status, err.StatusCode, "case: %d", i)
+		}
+		v, ok := ctx.GetValue(backend.ValueKeyUpstreamOverride)
+		assert.Equal("" != c.override, ok, "case: %d", i)
+		if ok {
+			assert.Equal(c.override, v)
+		}
+	}
+}
+
+func TestDebugUpstreamFilter_ProfileNotAllowed(t *testing.T) {
+	assert := assert2.New(t)
+	for _, profile := range []string{"", "prod"} {
+		filter := newTestDebugUpstreamFilter(t, profile)
+		assert.True(filter.Disabled, profile)
+	}
+	assert.False(newTestDebugUpstreamFilter(t, "TEST").Disabled)
+}
@@ -0,0 +1,380 @@
+package filter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/pkg"
+	"github.com/bytepowered/flux/support"
+	"github.com/spf13/cast"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	TypeIdLoginProtectionFilter = "LoginProtectionFilter"
+)
+
+const (
+	LoginProtectionConfigKeyUsernameLookup     = "username-lookup"
+	LoginProtectionConfigKeyFingerprintHeaders = "fingerprint-headers"
+	LoginProtectionConfigKeyFingerprintSample  = "fingerprint-sample-rate"
+	LoginProtectionConfigKeyFailureStatus      = "failure-status-codes"
+	LoginProtectionConfigKeyGroups             = "groups"
+	LoginProtectionConfigKeyMaxUserAttempts    = "max-username-attempts"
+	LoginProtectionConfigKeyMaxIpAttempts      = "max-ip-attempts"
+	LoginProtectionConfigKeyWindow             = "window"
+	LoginProtectionConfigKeyBaseLockout        = "base-lockout"
+	LoginProtectionConfigKeyMaxLockout         = "max-lockout"
+)
+
+const (
+	// Endpoint扩展属性：登录保护分组；未设置时使用Endpoint.Application
+	LoginProtectionExtKeyGroup = "login-protection-group"
+)
+
+const (
+	LoginAuditEventFailure = "LOGIN:FAILURE"
+	LoginAuditEventSuccess = "LOGIN:SUCCESS"
+	LoginAuditEventLocked  = "LOGIN:LOCKED"
+	LoginAuditEventBlocked = "LOGIN:BLOCKED"
+)
+
+const (
+	// 计数表的最大条目数，超过时清理过期条目
+	loginProtectionMaxCounters = 50000
+)
+
+type (
+	// LoginAuditEvent 登录保护审计事件
+	LoginAuditEvent struct {
+		Event       string
+		Group       string
+		Username    string
+		ClientIp    string
+		Fingerprint string
+		Lockout     time.Duration
+	}
+	// LoginAuditFunc 接收登录保护审计事件；默认输出到日志
+	LoginAuditFunc func(ctx flux.Context, event LoginAuditEvent)
+)
+
+// LoginProtectionPolicy 登录保护策略；按Endpoint分组配置
+type LoginProtectionPolicy struct {
+	MaxUsernameAttempts int
+	MaxIpAttempts       int
+	Window              time.Duration
+	BaseLockout         time.Duration
+	MaxLockout          time.Duration
+}
+
+// LoginProtectionConfig 登录保护配置
+type LoginProtectionConfig struct {
+	SkipFunc  flux.FilterSkipper
+	AuditFunc LoginAuditFunc
+	// ClientIpFunc 获取客户端IP；未设置时使用support.ClientIPOf(ctx)，由网关可信代理配置解析
+	ClientIpFunc func(ctx flux.Context) string
+	// UsernameFunc 获取登录用户名；未设置时按username-lookup查找
+	UsernameFunc       func(ctx flux.Context) string
+	usernameLookup     string
+	fingerprintHeaders []string
+	fingerprintSample  float64
+	failureStatus      []int
+	defaults           LoginProtectionPolicy
+	groups             map[string]LoginProtectionPolicy
+}
+
+func NewLoginProtectionFilter(c LoginProtectionConfig) *LoginProtectionFilter {
+	return &LoginProtectionFilter{
+		Configs: c,
+	}
+}
+
+// LoginProtectionFilter 登录接口的撞库防护：按用户名和客户端IP分别统计登录失败次数，
+// 超过阈值后锁定，连续锁定时锁定时长指数增长；登录结果和锁定事件输出到审计。
+// 登录失败由后端响应状态码判定（默认 401/403）。
+type LoginProtectionFilter struct {
+	Disabled bool
+	Configs  LoginProtectionConfig
+	counters *loginCounters
+}
+
+func (l *LoginProtectionFilter) Init(config *flux.Configuration) error {
+	config.SetDefaults(map[string]interface{}{
+		ConfigKeyDisabled:                      false,
+		LoginProtectionConfigKeyUsernameLookup: "FORM:username",
+		LoginProtectionConfigKeyFingerprintHeaders: []string{
+			"User-Agent", "Accept-Language", "Accept-Encoding",
+		},
+		LoginProtectionConfigKeyFingerprintSample: 0.1,
+		LoginProtectionConfigKeyFailureStatus:     []int{http.StatusUnauthorized, http.StatusForbidden},
+		LoginProtectionConfigKeyMaxUserAttempts:   5,
+		LoginProtectionConfigKeyMaxIpAttempts:     50,
+		LoginProtectionConfigKeyWindow:            "10m",
+		LoginProtectionConfigKeyBaseLockout:       "1m",
+		LoginProtectionConfigKeyMaxLockout:        "1h",
+	})
+	l.Disabled = config.GetBool(ConfigKeyDisabled)
+	if l.Disabled {
+		logger.Info("Endpoint LoginProtectionFilter was DISABLED!!")
+		return nil
+	}
+	l.Configs.usernameLookup = config.GetString(LoginProtectionConfigKeyUsernameLookup)
+	l.Configs.fingerprintHeaders = config.GetStringSlice(LoginProtectionConfigKeyFingerprintHeaders)
+	l.Configs.fingerprintSample = config.GetFloat64(LoginProtectionConfigKeyFingerprintSample)
+	l.Configs.failureStatus = config.GetIntSlice(LoginProtectionConfigKeyFailureStatus)
+	if _, _, ok := support.ParseLookupExpr(l.Configs.usernameLookup); !ok {
+		return fmt.Errorf("LoginProtectionFilter.username-lookup is invalid: %s", l.Configs.usernameLookup)
+	}
+	defaults, err := loginProtectionPolicyOf(config, LoginProtectionPolicy{})
+	if nil != err {
+		return err
+	}
+	l.Configs.defaults = defaults
+	l.Configs.groups = make(map[string]LoginProtectionPolicy, 4)
+	for name := range config.GetStringMap(LoginProtectionConfigKeyGroups) {
+		policy, err := loginProtectionPolicyOf(config.Sub(LoginProtectionConfigKeyGroups+"."+name), defaults)
+		if nil != err {
+			return fmt.Errorf("LoginProtectionFilter.groups.%s: %w", name, err)
+		}
+		l.Configs.groups[name] = policy
+	}
+	if pkg.IsNil(l.Configs.SkipFunc) {
+		l.Configs.SkipFunc = func(_ flux.Context) bool {
+			return false
+		}
+	}
+	if pkg.IsNil(l.Configs.AuditFunc) {
+		l.Configs.AuditFunc = func(ctx flux.Context, event LoginAuditEvent) {
+			logger.TraceContext(ctx).Infow("LOGIN:AUDIT", "event", event.Event, "group", event.Group,
+				"username", event.Username, "client-ip", event.ClientIp, "fingerprint", event.Fingerprint,
+				"lockout", event.Lockout.String())
+		}
+	}
+	if pkg.IsNil(l.Configs.ClientIpFunc) {
+		l.Configs.ClientIpFunc = func(ctx flux.Context) string {
+			return support.ClientIPOf(ctx)
+		}
+	}
+	if pkg.IsNil(l.Configs.UsernameFunc) {
+		l.Configs.UsernameFunc = func(ctx flux.Context) string {
+			if v, _ := support.LookupContextByExpr(l.Configs.usernameLookup, ctx); nil != v {
+				return cast.ToString(v)
+			}
+			return ""
+		}
+	}
+	l.counters = &loginCounters{entries: make(map[string]*loginCounter, 64)}
+	return nil
+}
+
+func loginProtectionPolicyOf(config *flux.Configuration, parent LoginProtectionPolicy) (LoginProtectionPolicy, error) {
+	policy := parent
+	if config.IsSet(LoginProtectionConfigKeyMaxUserAttempts) {
+		policy.MaxUsernameAttempts = config.GetInt(LoginProtectionConfigKeyMaxUserAttempts)
+	}
+	if config.IsSet(LoginProtectionConfigKeyMaxIpAttempts) {
+		policy.MaxIpAttempts = config.GetInt(LoginProtectionConfigKeyMaxIpAttempts)
+	}
+	if config.IsSet(LoginProtectionConfigKeyWindow) {
+		policy.Window = config.GetDuration(LoginProtectionConfigKeyWindow)
+	}
+	if config.IsSet(LoginProtectionConfigKeyBaseLockout) {
+		policy.BaseLockout = config.GetDuration(LoginProtectionConfigKeyBaseLockout)
+	}
+	if config.IsSet(LoginProtectionConfigKeyMaxLockout) {
+		policy.MaxLockout = config.GetDuration(LoginProtectionConfigKeyMaxLockout)
+	}
+	if policy.Window <= 0 || policy.BaseLockout <= 0 || policy.MaxLockout < policy.BaseLockout {
+		return policy, fmt.Errorf("invalid window/lockout: %+v", policy)
+	}
+	return policy, nil
+}
+
+func (*LoginProtectionFilter) TypeId() string {
+	return TypeIdLoginProtectionFilter
+}
+
+func (l *LoginProtectionFilter) DoFilter(next flux.FilterHandler) flux.FilterHandler {
+	if l.Disabled {
+		return next
+	}
+	return func(ctx flux.Context) *flux.ServeError {
+		if l.Configs.SkipFunc(ctx) {
+			return next(ctx)
+		}
+		group, policy := l.policyOf(ctx.Endpoint())
+		event := LoginAuditEvent{Group: group, Username: l.Configs.UsernameFunc(ctx), ClientIp: l.Configs.ClientIpFunc(ctx)}
+		userKey, ipKey := "", ""
+		if "" != event.Username {
+			userKey = group + "|u|" + strings.ToLower(event.Username)
+		}
+		if "" != event.ClientIp {
+			ipKey = group + "|ip|" + event.ClientIp
+		}
+		now := time.Now()
+		if wait := l.counters.lockedFor(now, userKey, ipKey); wait > 0 {
+			event.Event, event.Lockout = LoginAuditEventBlocked, wait
+			event.Fingerprint = l.fingerprint(ctx, event.ClientIp)
+			l.Configs.AuditFunc(ctx, event)
+			return &flux.ServeError{
+				StatusCode: http.StatusTooManyRequests,
+				ErrorCode:  flux.ErrorCodeRequestInvalid,
+				Message:    flux.ErrorMessageLoginLocked,
+				Header:     http.Header{"Retry-After": []string{strconv.Itoa(int(wait/time.Second) + 1)}},
+			}
+		}
+		serr := next(ctx)
+		if !l.isFailure(ctx, serr) {
+			l.counters.reset(userKey)
+			event.Event = LoginAuditEventSuccess
+			if l.Configs.fingerprintSample > 0 && rand.Float64() < l.Configs.fingerprintSample {
+				event.Fingerprint = l.fingerprint(ctx, event.ClientIp)
+			}
+			l.Configs.AuditFunc(ctx, event)
+			return serr
+		}
+		event.Event = LoginAuditEventFailure
+		event.Fingerprint = l.fingerprint(ctx, event.ClientIp)
+		l.Configs.AuditFunc(ctx, event)
+		userLock := l.counters.fail(now, userKey, policy.MaxUsernameAttempts, policy)
+		ipLock := l.counters.fail(now, ipKey, policy.MaxIpAttempts, policy)
+		if userLock > 0 || ipLock > 0 {
+			event.Event, event.Lockout = LoginAuditEventLocked, userLock
+			if ipLock > userLock {
+				event.Lockout = ipLock
+			}
+			l.Configs.AuditFunc(ctx, event)
+		}
+		return serr
+	}
+}
+
+func (l *LoginProtectionFilter) policyOf(endpoint flux.Endpoint) (string, LoginProtectionPolicy) {
+	group := endpoint.ExtString(LoginProtectionExtKeyGroup)
+	if "" == group {
+		group = endpoint.Application
+	}
+	if policy, ok := l.Configs.groups[group]; ok {
+		return group, policy
+	}
+	return group, l.Configs.defaults
+}
+
+func (l *LoginProtectionFilter) isFailure(ctx flux.Context, serr *flux.ServeError) bool {
+	status := 0
+	if nil != serr {
+		status = serr.StatusCode
+	} else if resp := ctx.Response(); nil != resp {
+		status = resp.StatusCode()
+	}
+	for _, s := range l.Configs.failureStatus {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// fingerprint 基于客户端IP和指定请求Header计算设备指纹
+func (l *LoginProtectionFilter) fingerprint(ctx flux.Context, clientIp string) string {
+	hash := sha256.New()
+	hash.Write([]byte(clientIp))
+	for _, name := range l.Configs.fingerprintHeaders {
+		hash.Write([]byte{0})
+		hash.Write([]byte(ctx.Request().HeaderValue(name)))
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:8])
+}
+
+type loginCounter struct {
+	failures    int
+	windowStart time.Time
+	lockouts    uint
+	lockedUntil time.Time
+	expireAt    time.Time
+}
+
+type loginCounters struct {
+	mu      sync.Mutex
+	entries map[string]*loginCounter
+}
+
+func (c *loginCounters) lockedFor(now time.Time, keys ...string) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var wait time.Duration
+	for _, key := range keys {
+		if e, ok := c.entries[key]; ok && now.Before(e.lockedUntil) {
+			if d := e.lockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	return wait
+}
+
+// fail 记录一次失败；达到阈值时按 base-lockout * 2^n 锁定，返回锁定时长
+func (c *loginCounters) fail(now time.Time, key string, threshold int, policy LoginProtectionPolicy) time.Duration {
+	if "" == key || threshold <= 0 {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		if len(c.entries) >= loginProtectionMaxCounters {
+			if c.sweep(now); len(c.entries) >= loginProtectionMaxCounters {
+				return 0
+			}
+		}
+		e = &loginCounter{windowStart: now}
+		c.entries[key] = e
+	}
+	if now.Sub(e.windowStart) > policy.Window {
+		e.failures, e.windowStart = 0, now
+	}
+	e.failures++
+	// 锁定历史保留到最长锁定时长之后，用于指数增长
+	e.expireAt = now.Add(policy.Window + policy.MaxLockout)
+	if e.failures < threshold {
+		return 0
+	}
+	lockout := policy.BaseLockout
+	for i := uint(0); i < e.lockouts && lockout < policy.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > policy.MaxLockout {
+		lockout = policy.MaxLockout
+	}
+	e.lockouts++
+	e.failures, e.windowStart = 0, now
+	e.lockedUntil = now.Add(lockout)
+	if e.lockedUntil.After(e.expireAt) {
+		e.expireAt = e.lockedUntil
+	}
+	return lockout
+}
+
+func (c *loginCounters) reset(key string) {
+	if "" == key {
+		return
+	}
+	c.mu.Lock()
+	delete(c.entries, key)
+	c.mu.Unlock()
+}
+
+func (c *loginCounters) sweep(now time.Time) {
+	for key, e := range c.entries {
+		if now.After(e.expireAt) {
+			delete(c.entries, key)
+		}
+	}
+}
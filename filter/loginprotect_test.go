@@ -0,0 +1,64 @@
+package filter
+
+import (
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLoginProtectionFilter_DoFilter(t *testing.T) {
+	assert := assert2.New(t)
+	config := flux.NewConfiguration(nil)
+	config.Set(LoginProtectionConfigKeyMaxUserAttempts, 2)
+	config.Set(LoginProtectionConfigKeyGroups, map[string]interface{}{
+		"admin": map[string]interface{}{LoginProtectionConfigKeyMaxUserAttempts: 1},
+	})
+	events := make([]LoginAuditEvent, 0)
+	filter := NewLoginProtectionFilter(LoginProtectionConfig{
+		AuditFunc: func(ctx flux.Context, event LoginAuditEvent) {
+			events = append(events, event)
+		},
+	})
+	assert.NoError(filter.Init(config))
+	login := func(username, password string, endpoint flux.Endpoint) *flux.ServeError {
+		ctx := newCryptoTestContext(map[string]interface{}{
+			"username": username, "client-ip": "10.0.0.1", "User-Agent": "test",
+		}, endpoint)
+		return filter.DoFilter(func(ctx flux.Context) *flux.ServeError {
+			if "secret" == password {
+				return nil
+			}
+			return &flux.ServeError{StatusCode: flux.StatusUnauthorized}
+		})(ctx)
+	}
+	assert.Nil(login("alice", "secret", flux.Endpoint{}))
+	assert.Equal(flux.StatusUnauthorized, login("alice", "wrong", flux.Endpoint{}).StatusCode)
+	assert.Equal(flux.StatusUnauthorized, login("Alice", "wrong", flux.Endpoint{}).StatusCode)
+	err := login("alice", "secret", flux.Endpoint{})
+	assert.NotNil(err)
+	assert.Equal(http.StatusTooManyRequests, err.StatusCode)
+	assert.Equal(flux.ErrorMessageLoginLocked, err.Message)
+	assert.Equal(LoginAuditEventBlocked, events[len(events)-1].Event)
+	assert.NotEmpty(events[len(events)-1].Fingerprint)
+	// 分组策略独立计数
+	admin := flux.Endpoint{EmbeddedExtensions: flux.EmbeddedExtensions{
+		Extensions: map[string]interface{}{LoginProtectionExtKeyGroup: "admin"},
+	}}
+	assert.Equal(flux.StatusUnauthorized, login("alice", "wrong", admin).StatusCode)
+	assert.Equal(http.StatusTooManyRequests, login("alice", "secret", admin).StatusCode)
+}
+
+func TestLoginCounters_ExponentialLockout(t *testing.T) {
+	assert := assert2.New(t)
+	policy := LoginProtectionPolicy{Window: time.Minute, BaseLockout: time.Minute, MaxLockout: 3 * time.Minute}
+	counters := &loginCounters{entries: make(map[string]*loginCounter)}
+	now := time.Now()
+	assert.Equal(time.Minute, counters.fail(now, "k", 1, policy))
+	assert.Equal(2*time.Minute, counters.fail(now, "k", 1, policy))
+	assert.Equal(3*time.Minute, counters.fail(now, "k", 1, policy))
+	assert.True(counters.lockedFor(now, "k", "other") > 0)
+	counters.reset("k")
+	assert.Equal(time.Duration(0), counters.lockedFor(now, "k"))
+}
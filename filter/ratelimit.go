@@ -0,0 +1,385 @@
+package filter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/pkg"
+	"github.com/bytepowered/flux/support"
+	"github.com/go-redis/redis"
+	"github.com/spf13/cast"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	TypeIdRateLimitFilter = "RateLimitFilter"
+)
+
+const (
+	RateLimitConfigKeyLimit         = "limit"
+	RateLimitConfigKeyWindow        = "window"
+	RateLimitConfigKeyKeyLookup     = "key-lookup"
+	RateLimitConfigKeyPerEndpoint   = "per-endpoint"
+	RateLimitConfigKeyHeaders       = "headers"
+	RateLimitConfigKeyLegacyHeaders = "legacy-headers"
+	RateLimitConfigKeyMaxKeys       = "max-keys"
+	// 限流状态持久化：定期保存令牌桶状态快照，重启时恢复，避免发布部署重置限流
+	RateLimitConfigKeyPersistEnable        = "persist-enable"
+	RateLimitConfigKeyPersistStore         = "persist-store"
+	RateLimitConfigKeyPersistPath          = "persist-path"
+	RateLimitConfigKeyPersistRedisAddress  = "persist-redis-address"
+	RateLimitConfigKeyPersistRedisPassword = "persist-redis-password"
+	RateLimitConfigKeyPersistRedisDB       = "persist-redis-db"
+	RateLimitConfigKeyPersistRedisKey      = "persist-redis-key"
+	RateLimitConfigKeyPersistInterval      = "persist-interval"
+	RateLimitConfigKeyPersistMaxStale      = "persist-max-stale"
+)
+
+const (
+	// Endpoint扩展属性：覆盖窗口内的请求数限制
+	RateLimitExtKeyLimit = "rate-limit"
+)
+
+const (
+	HeaderRateLimitLimit      = "RateLimit-Limit"
+	HeaderRateLimitRemaining  = "RateLimit-Remaining"
+	HeaderRateLimitReset      = "RateLimit-Reset"
+	HeaderXRateLimitLimit     = "X-RateLimit-Limit"
+	HeaderXRateLimitRemaining = "X-RateLimit-Remaining"
+	HeaderXRateLimitReset     = "X-RateLimit-Reset"
+	HeaderRetryAfter          = "Retry-After"
+)
+
+// RateLimitState 限流器对某个Key执行一次请求后的状态
+type RateLimitState struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // 配额完全恢复的时间
+	RetryAfter time.Duration // 被拒绝时，下一个请求可用的等待时间
+}
+
+// RateLimiter 限流器
+type RateLimiter interface {
+	// Take 对指定Key消耗一个配额，返回消耗后的状态
+	Take(key string, limit int, now time.Time) RateLimitState
+}
+
+// RateLimitConfig 限流配置
+type RateLimitConfig struct {
+	SkipFunc flux.FilterSkipper
+	// Limiter 自定义限流器；默认为令牌桶实现
+	Limiter RateLimiter
+	// StateStore 自定义限流状态快照的存储；开启持久化时默认按 persist-store 创建文件或Redis存储。
+	// 限流器需实现 RateLimitStateExporter 接口
+	StateStore    RateLimitStateStore
+	limit         int
+	keyLookup     string
+	perEndpoint   bool
+	headers       bool
+	legacyHeaders bool
+	persist       bool
+	interval      time.Duration
+	maxStale      time.Duration
+}
+
+func NewRateLimitFilter(c RateLimitConfig) *RateLimitFilter {
+	return &RateLimitFilter{
+		Configs: c,
+	}
+}
+
+// RateLimitFilter 按Key（默认为JWT Subject，不存在时为客户端IP）限制窗口内的请求数；超过限制时返回429和Retry-After。
+// 每个请求的响应均包含限流状态Header：RateLimit-Limit/Remaining/Reset（IETF draft）以及 X-RateLimit-* 兼容Header。
+// 开启 persist-enable 时，令牌桶状态定期保存到本地文件或Redis，重启时恢复未超过 persist-max-stale 的快照。
+type RateLimitFilter struct {
+	Disabled bool
+	Configs  RateLimitConfig
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func (r *RateLimitFilter) Init(config *flux.Configuration) error {
+	config.SetDefaults(map[string]interface{}{
+		ConfigKeyDisabled:                 false,
+		RateLimitConfigKeyLimit:           100,
+		RateLimitConfigKeyWindow:          "1m",
+		RateLimitConfigKeyKeyLookup:       flux.ScopeAttr + ":" + flux.XJwtSubject,
+		RateLimitConfigKeyPerEndpoint:     true,
+		RateLimitConfigKeyHeaders:         true,
+		RateLimitConfigKeyLegacyHeaders:   true,
+		RateLimitConfigKeyMaxKeys:         100000,
+		RateLimitConfigKeyPersistEnable:   false,
+		RateLimitConfigKeyPersistStore:    RateLimitStoreFile,
+		RateLimitConfigKeyPersistPath:     "./data/ratelimit.json",
+		RateLimitConfigKeyPersistInterval: "10s",
+		RateLimitConfigKeyPersistMaxStale: "10m",
+	})
+	r.Disabled = config.GetBool(ConfigKeyDisabled)
+	if r.Disabled {
+		logger.Info("Endpoint RateLimitFilter was DISABLED!!")
+		return nil
+	}
+	r.Configs.limit = config.GetInt(RateLimitConfigKeyLimit)
+	r.Configs.keyLookup = config.GetString(RateLimitConfigKeyKeyLookup)
+	r.Configs.perEndpoint = config.GetBool(RateLimitConfigKeyPerEndpoint)
+	r.Configs.headers = config.GetBool(RateLimitConfigKeyHeaders)
+	r.Configs.legacyHeaders = config.GetBool(RateLimitConfigKeyLegacyHeaders)
+	if r.Configs.limit <= 0 {
+		return fmt.Errorf("RateLimitFilter.limit is invalid: %d", r.Configs.limit)
+	}
+	if _, _, ok := support.ParseLookupExpr(r.Configs.keyLookup); !ok {
+		return fmt.Errorf("RateLimitFilter.key-lookup is invalid: %s", r.Configs.keyLookup)
+	}
+	if pkg.IsNil(r.Configs.SkipFunc) {
+		r.Configs.SkipFunc = func(_ flux.Context) bool {
+			return false
+		}
+	}
+	if pkg.IsNil(r.Configs.Limiter) {
+		window := config.GetDuration(RateLimitConfigKeyWindow)
+		if window <= 0 {
+			return errors.New("RateLimitFilter.window is invalid")
+		}
+		r.Configs.Limiter = NewTokenBucketLimiter(window, config.GetInt(RateLimitConfigKeyMaxKeys))
+	}
+	return r.initPersist(config)
+}
+
+// initPersist 初始化限流状态持久化配置和存储
+func (r *RateLimitFilter) initPersist(config *flux.Configuration) error {
+	r.Configs.persist = config.GetBool(RateLimitConfigKeyPersistEnable)
+	if !r.Configs.persist {
+		return nil
+	}
+	if _, ok := r.Configs.Limiter.(RateLimitStateExporter); !ok {
+		return errors.New("RateLimitFilter.persist-enable requires limiter implements RateLimitStateExporter")
+	}
+	r.Configs.interval = config.GetDuration(RateLimitConfigKeyPersistInterval)
+	r.Configs.maxStale = config.GetDuration(RateLimitConfigKeyPersistMaxStale)
+	if r.Configs.interval <= 0 {
+		return errors.New("RateLimitFilter.persist-interval is invalid")
+	}
+	if pkg.IsNotNil(r.Configs.StateStore) {
+		return nil
+	}
+	switch store := config.GetString(RateLimitConfigKeyPersistStore); store {
+	case RateLimitStoreFile:
+		path := config.GetString(RateLimitConfigKeyPersistPath)
+		if "" == path {
+			return errors.New("RateLimitFilter.persist-path is required")
+		}
+		r.Configs.StateStore = NewFileRateLimitStateStore(path)
+	case RateLimitStoreRedis:
+		address := config.GetString(RateLimitConfigKeyPersistRedisAddress)
+		if "" == address {
+			return errors.New("RateLimitFilter.persist-redis-address is required")
+		}
+		// 每个网关实例的限流状态相互独立，默认按主机名区分快照Key
+		key := config.GetString(RateLimitConfigKeyPersistRedisKey)
+		if "" == key {
+			hostname, _ := os.Hostname()
+			key = "flux:ratelimit:" + hostname
+		}
+		client := redis.NewClient(&redis.Options{
+			Addr:     address,
+			Password: config.GetString(RateLimitConfigKeyPersistRedisPassword),
+			DB:       config.GetInt(RateLimitConfigKeyPersistRedisDB),
+		})
+		r.Configs.StateStore = NewRedisRateLimitStateStore(client, key, r.Configs.maxStale)
+	default:
+		return fmt.Errorf("RateLimitFilter.persist-store is invalid: %s", store)
+	}
+	return nil
+}
+
+// Startup 开启持久化时，恢复未过期的状态快照，并定期保存状态快照
+func (r *RateLimitFilter) Startup() error {
+	if r.Disabled || !r.Configs.persist {
+		return nil
+	}
+	exporter := r.Configs.Limiter.(RateLimitStateExporter)
+	if snapshot, ok, err := r.Configs.StateStore.Load(); nil != err {
+		logger.Warnw("RateLimitFilter load state snapshot failed", "error", err)
+	} else if ok {
+		if age := time.Since(snapshot.SavedAt); r.Configs.maxStale > 0 && age > r.Configs.maxStale {
+			logger.Infow("RateLimitFilter state snapshot is stale, ignored", "saved-at", snapshot.SavedAt)
+		} else {
+			logger.Infow("RateLimitFilter state snapshot restored", "saved-at", snapshot.SavedAt, "buckets", exporter.Import(snapshot))
+		}
+	}
+	r.stop, r.done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.Configs.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				r.save(exporter)
+			}
+		}
+	}()
+	return nil
+}
+
+// Shutdown 停止定期保存，并保存最后的状态快照
+func (r *RateLimitFilter) Shutdown(ctx context.Context) error {
+	if nil == r.stop {
+		return nil
+	}
+	close(r.stop)
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	r.stop = nil
+	r.save(r.Configs.Limiter.(RateLimitStateExporter))
+	if closer, ok := r.Configs.StateStore.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (r *RateLimitFilter) save(exporter RateLimitStateExporter) {
+	if err := r.Configs.StateStore.Save(exporter.Export(time.Now())); nil != err {
+		logger.Warnw("RateLimitFilter save state snapshot failed", "error", err)
+	}
+}
+
+func (*RateLimitFilter) TypeId() string {
+	return TypeIdRateLimitFilter
+}
+
+func (r *RateLimitFilter) DoFilter(next flux.FilterHandler) flux.FilterHandler {
+	if r.Disabled {
+		return next
+	}
+	return func(ctx flux.Context) *flux.ServeError {
+		if r.Configs.SkipFunc(ctx) {
+			return next(ctx)
+		}
+		endpoint := ctx.Endpoint()
+		limit := r.Configs.limit
+		if v, ok := endpoint.Ext(RateLimitExtKeyLimit); ok {
+			if l := cast.ToInt(v); l > 0 {
+				limit = l
+			}
+		}
+		v, _ := support.LookupContextByExpr(r.Configs.keyLookup, ctx)
+		key := cast.ToString(v)
+		if "" == key {
+			// 匿名请求按客户端IP限流
+			key = support.ClientIPOf(ctx)
+		}
+		if r.Configs.perEndpoint {
+			key = endpoint.HttpMethod + ":" + endpoint.HttpPattern + "|" + key
+		}
+		state := r.Configs.Limiter.Take(key, limit, time.Now())
+		header := http.Header{}
+		r.writeHeaders(header, state)
+		if resp := ctx.Response(); nil != resp {
+			for name, values := range header {
+				resp.SetHeader(name, values[0])
+			}
+		}
+		if !state.Allowed {
+			return &flux.ServeError{
+				StatusCode: http.StatusTooManyRequests,
+				ErrorCode:  flux.ErrorCodeRequestInvalid,
+				Message:    flux.ErrorMessageRateLimited,
+				Header: http.Header{
+					HeaderRetryAfter: []string{strconv.Itoa(ceilSeconds(state.RetryAfter))},
+				},
+			}
+		}
+		return next(ctx)
+	}
+}
+
+func (r *RateLimitFilter) writeHeaders(header http.Header, state RateLimitState) {
+	limit, remaining, reset := strconv.Itoa(state.Limit), strconv.Itoa(state.Remaining), strconv.Itoa(ceilSeconds(state.Reset))
+	if r.Configs.headers {
+		header.Set(HeaderRateLimitLimit, limit)
+		header.Set(HeaderRateLimitRemaining, remaining)
+		header.Set(HeaderRateLimitReset, reset)
+	}
+	if r.Configs.legacyHeaders {
+		header.Set(HeaderXRateLimitLimit, limit)
+		header.Set(HeaderXRateLimitRemaining, remaining)
+		header.Set(HeaderXRateLimitReset, reset)
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// TokenBucketLimiter 令牌桶限流器：容量为limit，每个window补充limit个令牌
+type TokenBucketLimiter struct {
+	window  time.Duration
+	maxKeys int
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens  float64
+	limit   int
+	updated time.Time
+}
+
+func NewTokenBucketLimiter(window time.Duration, maxKeys int) *TokenBucketLimiter {
+	return &TokenBucketLimiter{
+		window:  window,
+		maxKeys: maxKeys,
+		buckets: make(map[string]*tokenBucket, 64),
+	}
+}
+
+func (l *TokenBucketLimiter) Take(key string, limit int, now time.Time) RateLimitState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[key]
+	if !ok {
+		if l.maxKeys > 0 && len(l.buckets) >= l.maxKeys {
+			l.sweep(now)
+		}
+		b = &tokenBucket{tokens: float64(limit), limit: limit, updated: now}
+		l.buckets[key] = b
+	}
+	rate := float64(limit) / float64(l.window)
+	b.limit = limit
+	b.tokens = math.Min(float64(limit), b.tokens+float64(now.Sub(b.updated))*rate)
+	b.updated = now
+	state := RateLimitState{Limit: limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		state.Allowed = true
+	} else {
+		state.RetryAfter = time.Duration((1 - b.tokens) / rate)
+	}
+	state.Remaining = int(b.tokens)
+	state.Reset = time.Duration((float64(limit) - b.tokens) / rate)
+	return state
+}
+
+// sweep 清理已补满的令牌桶；补满的令牌桶与新建的令牌桶状态一致
+func (l *TokenBucketLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= l.window {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package filter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/go-redis/redis"
+	assert2 "github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimitFilter_DoFilter(t *testing.T) {
+	assert := assert2.New(t)
+	config := flux.NewConfiguration(nil)
+	config.Set(RateLimitConfigKeyLimit, 2)
+	config.Set(RateLimitConfigKeyWindow, "1h")
+	filter := NewRateLimitFilter(RateLimitConfig{})
+	assert.NoError(filter.Init(config))
+	next := func(ctx flux.Context) *flux.ServeError {
+		return nil
+	}
+	request := func(subject string) (*cryptoTestContext, *flux.ServeError) {
+		ctx := newCryptoTestContext(map[string]interface{}{flux.XJwtSubject: subject}, flux.Endpoint{})
+		return ctx, filter.DoFilter(next)(ctx)
+	}
+	ctx, err := request("alice")
+	assert.Nil(err)
+	assert.Equal("2", ctx.response.header.Get(HeaderRateLimitLimit))
+	assert.Equal("1", ctx.response.header.Get(HeaderRateLimitRemaining))
+	assert.Equal("1800", ctx.response.header.Get(HeaderRateLimitReset))
+	assert.Equal("1", ctx.response.header.Get(HeaderXRateLimitRemaining))
+	_, err = request("alice")
+	assert.Nil(err)
+	ctx, err = request("alice")
+	assert.NotNil(err)
+	assert.Equal(http.StatusTooManyRequests, err.StatusCode)
+	assert.Equal("1800", err.Header.Get(HeaderRetryAfter))
+	assert.Equal("0", ctx.response.header.Get(HeaderRateLimitRemaining))
+	// 不同Key独立计数
+	_, err = request("bob")
+	assert.Nil(err)
+}
+
+func TestTokenBucketLimiter_Take(t *testing.T) {
+	assert := assert2.New(t)
+	limiter := NewTokenBucketLimiter(time.Second, 1)
+	now := time.Now()
+	assert.True(limiter.Take("k", 2, now).Allowed)
+	assert.True(limiter.Take("k", 2, now).Allowed)
+	state := limiter.Take("k", 2, now)
+	assert.False(state.Allowed)
+	assert.InDelta(float64(500*time.Millisecond), float64(state.RetryAfter), float64(time.Millisecond))
+	state = limiter.Take("k", 2, now.Add(600*time.Millisecond))
+	assert.True(state.Allowed)
+	assert.Equal(0, state.Remaining)
+	// 超过最大Key数时清理已补满的令牌桶
+	assert.True(limiter.Take("other", 2, now.Add(2*time.Second)).Allowed)
+	assert.Equal(1, len(limiter.buckets))
+}
+
+func TestRateLimitFilter_Persist(t *testing.T) {
+	assert := assert2.New(t)
+	dir, err := ioutil.TempDir("", "ratelimit")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	newFilter := func() *RateLimitFilter {
+		config := flux.NewConfiguration(nil)
+		config.Set(RateLimitConfigKeyLimit, 2)
+		config.Set(RateLimitConfigKeyWindow, "1h")
+		config.Set(RateLimitConfigKeyPersistEnable, true)
+		config.Set(RateLimitConfigKeyPersistPath, filepath.Join(dir, "ratelimit.json"))
+		filter := NewRateLimitFilter(RateLimitConfig{})
+		assert.NoError(filter.Init(config))
+		assert.NoError(filter.Startup())
+		return filter
+	}
+	next := func(ctx flux.Context) *flux.ServeError {
+		return nil
+	}
+	request := func(filter *RateLimitFilter) *flux.ServeError {
+		ctx := newCryptoTestContext(map[string]interface{}{flux.XJwtSubject: "alice"}, flux.Endpoint{})
+		return filter.DoFilter(next)(ctx)
+	}
+	filter := newFilter()
+	assert.Nil(request(filter))
+	assert.Nil(request(filter))
+	assert.NoError(filter.Shutdown(context.Background()))
+	// 重启后恢复令牌桶状态，配额不被重置
+	restarted := newFilter()
+	defer restarted.Shutdown(context.Background())
+	assert.NotNil(request(restarted))
+}
+
+func TestTokenBucketLimiter_ExportImport(t *testing.T) {
+	assert := assert2.New(t)
+	now := time.Now()
+	limiter := NewTokenBucketLimiter(time.Second, 0)
+	limiter.Take("full", 2, now.Add(-2*time.Second))
+	limiter.Take("used", 2, now)
+	snapshot := limiter.Export(now)
+	assert.Equal(1, len(snapshot.Buckets))
+	assert.Equal("used", snapshot.Buckets[0].Key)
+	assert.Equal(1, NewTokenBucketLimiter(time.Second, 0).Import(snapshot))
+	// 限流窗口变化时不恢复
+	assert.Equal(0, NewTokenBucketLimiter(time.Minute, 0).Import(snapshot))
+}
+
+// serveTestRedis 测试用Redis服务端：内存保存SET/GET的值，未认证时拒绝读写；commands 记录收到的命令
+func serveTestRedis(t *testing.T, password string, commands chan<- string) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	values := make(map[string]string)
+	readArgs := func(r *bufio.Reader) ([]string, error) {
+		line, err := r.ReadString('\n')
+		if nil != err {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := make([]string, size)
+		for i := range args {
+			if line, err = r.ReadString('\n'); nil != err {
+				return nil, err
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+			data := make([]byte, n+2)
+			if _, err := io.ReadFull(r, data); nil != err {
+				return nil, err
+			}
+			args[i] = string(data[:n])
+		}
+		return args, nil
+	}
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		authed := "" == password
+		for {
+			args, err := readArgs(reader)
+			if nil != err {
+				return
+			}
+			commands <- strings.ToUpper(strings.Join(args, " "))
+			switch cmd := strings.ToUpper(args[0]); {
+			case "AUTH" == cmd && password == args[1]:
+				authed = true
+				_, _ = io.WriteString(conn, "+OK\r\n")
+			case "AUTH" == cmd:
+				_, _ = io.WriteString(conn, "-WRONGPASS invalid password\r\n")
+			case !authed:
+				_, _ = io.WriteString(conn, "-NOAUTH Authentication required\r\n")
+			case "SELECT" == cmd:
+				_, _ = io.WriteString(conn, "+OK\r\n")
+			case "SET" == cmd:
+				mu.Lock()
+				values[args[1]] = args[2]
+				mu.Unlock()
+				_, _ = io.WriteString(conn, "+OK\r\n")
+			case "GET" == cmd:
+				mu.Lock()
+				v, ok := values[args[1]]
+				mu.Unlock()
+				if ok {
+					_, _ = fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(v), v)
+				} else {
+					_, _ = io.WriteString(conn, "$-1\r\n")
+				}
+			}
+		}
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if nil != err {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return listener.Addr().String(), func() {
+		_ = listener.Close()
+	}
+}
+
+func TestRedisRateLimitStateStore(t *testing.T) {
+	assert := assert2.New(t)
+	commands := make(chan string, 16)
+	address, closer := serveTestRedis(t, "secret", commands)
+	defer closer()
+	store := NewRedisRateLimitStateStore(redis.NewClient(&redis.Options{Addr: address, Password: "secret", DB: 1}),
+		"flux:ratelimit:test", time.Minute)
+	defer store.Close()
+	_, ok, err := store.Load()
+	assert.NoError(err)
+	assert.False(ok)
+	snapshot := RateLimitSnapshot{SavedAt: time.Now().Truncate(time.Second), Window: time.Hour,
+		Buckets: []RateLimitBucket{{Key: "alice", Tokens: 1, Limit: 2}}}
+	assert.NoError(store.Save(snapshot))
+	loaded, ok, err := store.Load()
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(snapshot.Buckets, loaded.Buckets)
+	assert.True(snapshot.SavedAt.Equal(loaded.SavedAt))
+	// 新连接先认证并选择DB；快照按ttl过期
+	assert.Equal("AUTH SECRET", <-commands)
+	assert.Equal("SELECT 1", <-commands)
+	assert.Equal("GET FLUX:RATELIMIT:TEST", <-commands)
+	assert.True(strings.HasSuffix(<-commands, " EX 60"))
+	// 认证失败
+	_, _, err = NewRedisRateLimitStateStore(redis.NewClient(&redis.Options{Addr: address, Password: "wrong"}),
+		"flux:ratelimit:test", time.Minute).Load()
+	assert.Error(err)
+}
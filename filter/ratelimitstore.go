@@ -0,0 +1,154 @@
+package filter
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-redis/redis"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	RateLimitStoreFile  = "file"
+	RateLimitStoreRedis = "redis"
+)
+
+// RateLimitBucket 令牌桶的状态
+type RateLimitBucket struct {
+	Key     string    `json:"key"`
+	Tokens  float64   `json:"tokens"`
+	Limit   int       `json:"limit"`
+	Updated time.Time `json:"updated"`
+}
+
+// RateLimitSnapshot 限流器的状态快照；Window 用于在恢复时校验限流窗口配置是否变化
+type RateLimitSnapshot struct {
+	SavedAt time.Time         `json:"savedAt"`
+	Window  time.Duration     `json:"window"`
+	Buckets []RateLimitBucket `json:"buckets"`
+}
+
+// RateLimitStateExporter 支持导出和恢复状态的限流器
+type RateLimitStateExporter interface {
+	// Export 导出未补满的令牌桶状态
+	Export(now time.Time) RateLimitSnapshot
+	// Import 恢复令牌桶状态；返回恢复的令牌桶数量
+	Import(snapshot RateLimitSnapshot) int
+}
+
+// RateLimitStateStore 限流器状态快照的存储
+type RateLimitStateStore interface {
+	// Save 保存状态快照
+	Save(snapshot RateLimitSnapshot) error
+	// Load 读取状态快照；不存在时返回false
+	Load() (RateLimitSnapshot, bool, error)
+}
+
+// Export 导出未补满的令牌桶；补满的令牌桶与新建的令牌桶状态一致，无需保存
+func (l *TokenBucketLimiter) Export(now time.Time) RateLimitSnapshot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	snapshot := RateLimitSnapshot{SavedAt: now, Window: l.window, Buckets: make([]RateLimitBucket, 0, len(l.buckets))}
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= l.window {
+			continue
+		}
+		snapshot.Buckets = append(snapshot.Buckets, RateLimitBucket{Key: key, Tokens: b.tokens, Limit: b.limit, Updated: b.updated})
+	}
+	return snapshot
+}
+
+// Import 恢复令牌桶；令牌按停机期间经过的时间补充。限流窗口不同的快照被忽略
+func (l *TokenBucketLimiter) Import(snapshot RateLimitSnapshot) int {
+	if snapshot.Window != l.window {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	count := 0
+	for _, b := range snapshot.Buckets {
+		if l.maxKeys > 0 && len(l.buckets) >= l.maxKeys {
+			break
+		}
+		if _, ok := l.buckets[b.Key]; ok {
+			continue
+		}
+		l.buckets[b.Key] = &tokenBucket{tokens: b.Tokens, limit: b.Limit, updated: b.Updated}
+		count++
+	}
+	return count
+}
+
+// FileRateLimitStateStore 保存到本地文件的状态快照；通过临时文件和重命名原子地替换
+type FileRateLimitStateStore struct {
+	path string
+}
+
+func NewFileRateLimitStateStore(path string) *FileRateLimitStateStore {
+	return &FileRateLimitStateStore{path: path}
+}
+
+func (s *FileRateLimitStateStore) Save(snapshot RateLimitSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if nil != err {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); nil != err {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); nil != err {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *FileRateLimitStateStore) Load() (RateLimitSnapshot, bool, error) {
+	var snapshot RateLimitSnapshot
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return snapshot, false, nil
+	}
+	if nil != err {
+		return snapshot, false, err
+	}
+	return snapshot, true, json.Unmarshal(data, &snapshot)
+}
+
+// RedisRateLimitStateStore 保存到Redis的状态快照；快照的过期时间为ttl
+type RedisRateLimitStateStore struct {
+	client *redis.Client
+	key    string
+	ttl    time.Duration
+}
+
+func NewRedisRateLimitStateStore(client *redis.Client, key string, ttl time.Duration) *RedisRateLimitStateStore {
+	return &RedisRateLimitStateStore{client: client, key: key, ttl: ttl}
+}
+
+func (s *RedisRateLimitStateStore) Save(snapshot RateLimitSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if nil != err {
+		return err
+	}
+	return s.client.Set(s.key, data, s.ttl).Err()
+}
+
+func (s *RedisRateLimitStateStore) Load() (RateLimitSnapshot, bool, error) {
+	var snapshot RateLimitSnapshot
+	data, err := s.client.Get(s.key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return snapshot, false, nil
+	}
+	if nil != err {
+		return snapshot, false, err
+	}
+	return snapshot, true, json.Unmarshal(data, &snapshot)
+}
+
+// Close 关闭Redis客户端
+func (s *RedisRateLimitStateStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,611 @@
+package filter
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/pkg"
+	"github.com/bytepowered/flux/support"
+	"github.com/spf13/cast"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	TypeIdSamlFilter = "SamlFilter"
+)
+
+const (
+	SamlConfigKeyAssertionLookup = "assertion-lookup"
+	SamlConfigKeyIdpSsoURL       = "idp-sso-url"
+	SamlConfigKeyIdpIssuer       = "idp-issuer"
+	SamlConfigKeySpEntityId      = "sp-entity-id"
+	SamlConfigKeyAcsURL          = "acs-url"
+	SamlConfigKeyClockSkew       = "clock-skew"
+	SamlConfigKeySubjectAttr     = "subject-attribute"
+	// 是否接受IdP发起的登录（断言不包含InResponseTo）；默认false
+	SamlConfigKeyAllowIdpInitiated = "allow-idp-initiated"
+	// SP发出的AuthnRequest的有效时长
+	SamlConfigKeyRequestTTL = "request-ttl"
+	// 断言验证通过后创建的会话：Cookie名称、有效时长、签名密钥ID（HS256，由SecretProvider提供）
+	SamlConfigKeySessionCookie = "session-cookie"
+	SamlConfigKeySessionTTL    = "session-ttl"
+	SamlConfigKeySessionKeyId  = "session-key-id"
+	SamlConfigKeySessionSecure = "session-secure"
+)
+
+const (
+	// SAML断言中的全部属性，设置到Context.Value中，类型为map[string][]string
+	ValueKeySamlAttributes = "saml-attributes"
+)
+
+const (
+	samlStatusSuccess      = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	samlNamespaceProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	samlNamespaceAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	samlSessionAlgorithm   = "HS256"
+)
+
+var (
+	ErrSamlAssertionMissing = errors.New("saml: assertion is missing")
+	ErrSamlStatusNotSuccess = errors.New("saml: response status not success")
+	ErrSamlIssuerMismatch   = errors.New("saml: issuer mismatch")
+	ErrSamlAudienceMismatch = errors.New("saml: audience mismatch")
+	ErrSamlExpired          = errors.New("saml: assertion expired or not yet valid")
+	ErrSamlSubjectMissing   = errors.New("saml: subject is missing")
+	ErrSamlSignedInvalid    = errors.New("saml: signed element is not a response or assertion")
+	ErrSamlAssertionCount   = errors.New("saml: response must contain exactly one assertion")
+	ErrSamlAssertionIdEmpty = errors.New("saml: assertion id is missing")
+	ErrSamlAssertionReplay  = errors.New("saml: assertion replayed")
+	ErrSamlDestination      = errors.New("saml: destination mismatch")
+	ErrSamlRecipient        = errors.New("saml: recipient mismatch")
+	ErrSamlInResponseTo     = errors.New("saml: in-response-to is unknown or already used")
+	ErrSamlSessionInvalid   = errors.New("saml: session is invalid or expired")
+	ErrSamlStateStoreFull   = errors.New("saml: state store is full")
+)
+
+type (
+	// SamlSignatureVerifyFunc 验证SAMLResponse原始XML的签名（XML-DSig），返回签名覆盖的元素（Response或Assertion）
+	// 规范化后的XML，需包含该元素使用的命名空间声明。断言数据只从返回的已签名元素中读取，防止签名包装攻击（XSW）。
+	// 签名验证依赖IdP证书与XML规范化实现，由使用方提供，例如基于 goxmldsig 的 ValidatingContext.Validate 实现。
+	SamlSignatureVerifyFunc func(ctx flux.Context, rawXML []byte) (signedXML []byte, err error)
+)
+
+// SamlAssertion SAML断言中用于身份映射和校验的数据
+type SamlAssertion struct {
+	Id           string
+	Issuer       string
+	Subject      string
+	Audiences    []string
+	NotBefore    time.Time
+	NotOnOrAfter time.Time
+	Attributes   map[string][]string
+	// Response的Destination
+	Destination string
+	// SubjectConfirmationData 的 InResponseTo、Recipient、NotOnOrAfter
+	InResponseTo        string
+	Recipient           string
+	ConfirmNotOnOrAfter time.Time
+}
+
+// SamlConfig SAML断言验证配置
+type SamlConfig struct {
+	SkipFunc        flux.FilterSkipper
+	SignatureVerify SamlSignatureVerifyFunc
+	// StateStore AuthnRequest ID与断言ID的存储；未设置时使用进程内存储
+	StateStore        SamlStateStore
+	SecretProvider    flux.SecretProvider
+	assertionLookup   string
+	idpSsoURL         string
+	idpIssuer         string
+	spEntityId        string
+	acsURL            string
+	clockSkew         time.Duration
+	subjectAttr       string
+	allowIdpInitiated bool
+	requestTTL        time.Duration
+	sessionCookie     string
+	sessionTTL        time.Duration
+	sessionKeyId      string
+	sessionSecure     bool
+}
+
+func NewSamlFilter(c SamlConfig) *SamlFilter {
+	return &SamlFilter{
+		Configs: c,
+	}
+}
+
+// SamlFilter 验证企业SSO的SAML断言，并将身份映射到Context：
+// Attributes: X-Jwt-Subject/X-Jwt-Issuer（与JWT验证及权限验证兼容）；Values: saml-attributes。
+// 断言验证通过后创建签名的会话Cookie，后续请求通过会话认证；
+// 需要授权的Endpoint缺少断言和会话时，浏览器请求将被重定向到IdP登录。
+type SamlFilter struct {
+	Disabled bool
+	Configs  SamlConfig
+}
+
+func (s *SamlFilter) Init(config *flux.Configuration) error {
+	config.SetDefaults(map[string]interface{}{
+		ConfigKeyDisabled:            false,
+		SamlConfigKeyAssertionLookup: "FORM:SAMLResponse",
+		SamlConfigKeyClockSkew:       "60s",
+		SamlConfigKeyRequestTTL:      "5m",
+		SamlConfigKeySessionCookie:   "flux_saml_session",
+		SamlConfigKeySessionTTL:      "8h",
+		SamlConfigKeySessionSecure:   true,
+	})
+	s.Disabled = config.GetBool(ConfigKeyDisabled)
+	if s.Disabled {
+		logger.Info("Endpoint SamlFilter was DISABLED!!")
+		return nil
+	}
+	s.Configs.assertionLookup = config.GetString(SamlConfigKeyAssertionLookup)
+	s.Configs.idpSsoURL = config.GetString(SamlConfigKeyIdpSsoURL)
+	s.Configs.idpIssuer = config.GetString(SamlConfigKeyIdpIssuer)
+	s.Configs.spEntityId = config.GetString(SamlConfigKeySpEntityId)
+	s.Configs.acsURL = config.GetString(SamlConfigKeyAcsURL)
+	s.Configs.clockSkew = config.GetDuration(SamlConfigKeyClockSkew)
+	s.Configs.subjectAttr = config.GetString(SamlConfigKeySubjectAttr)
+	s.Configs.allowIdpInitiated = config.GetBool(SamlConfigKeyAllowIdpInitiated)
+	s.Configs.requestTTL = config.GetDuration(SamlConfigKeyRequestTTL)
+	s.Configs.sessionCookie = config.GetString(SamlConfigKeySessionCookie)
+	s.Configs.sessionTTL = config.GetDuration(SamlConfigKeySessionTTL)
+	s.Configs.sessionKeyId = config.GetString(SamlConfigKeySessionKeyId)
+	s.Configs.sessionSecure = config.GetBool(SamlConfigKeySessionSecure)
+	if _, _, ok := support.ParseLookupExpr(s.Configs.assertionLookup); !ok {
+		return fmt.Errorf("SamlFilter.assertion-lookup is invalid: %s", s.Configs.assertionLookup)
+	}
+	if "" == s.Configs.idpIssuer {
+		return errors.New("SamlFilter.idp-issuer is empty")
+	}
+	if "" == s.Configs.spEntityId {
+		return errors.New("SamlFilter.sp-entity-id is empty")
+	}
+	if "" == s.Configs.acsURL {
+		return errors.New("SamlFilter.acs-url is empty")
+	}
+	if s.Configs.requestTTL <= 0 {
+		return errors.New("SamlFilter.request-ttl is invalid")
+	}
+	if "" == s.Configs.sessionCookie || s.Configs.sessionTTL <= 0 {
+		return errors.New("SamlFilter.session-cookie/session-ttl is invalid")
+	}
+	if "" == s.Configs.sessionKeyId {
+		return errors.New("SamlFilter.session-key-id is empty")
+	}
+	if pkg.IsNil(s.Configs.SecretProvider) {
+		s.Configs.SecretProvider = ext.LoadSecretProvider()
+	}
+	if pkg.IsNil(s.Configs.SecretProvider) {
+		return errors.New("SamlFilter.SecretProvider is nil")
+	}
+	if pkg.IsNil(s.Configs.StateStore) {
+		s.Configs.StateStore = NewMemorySamlStateStore()
+	}
+	if pkg.IsNil(s.Configs.SkipFunc) {
+		s.Configs.SkipFunc = func(_ flux.Context) bool {
+			return false
+		}
+	}
+	if pkg.IsNil(s.Configs.SignatureVerify) {
+		return errors.New("SamlFilter.SignatureVerify is nil")
+	}
+	return nil
+}
+
+func (*SamlFilter) TypeId() string {
+	return TypeIdSamlFilter
+}
+
+func (s *SamlFilter) DoFilter(next flux.FilterHandler) flux.FilterHandler {
+	if s.Disabled {
+		return next
+	}
+	return func(ctx flux.Context) *flux.ServeError {
+		if s.Configs.SkipFunc(ctx) {
+			return next(ctx)
+		}
+		value, _ := support.LookupContextByExpr(s.Configs.assertionLookup, ctx)
+		encoded := cast.ToString(value)
+		if "" == encoded {
+			if session, err := s.loadSession(ctx); nil == err {
+				s.setIdentity(ctx, session.Subject, session.Issuer, session.Attributes)
+				return next(ctx)
+			}
+			if !ctx.Authorize() {
+				return next(ctx)
+			}
+			return s.redirectToIdp(ctx)
+		}
+		assertion, err := s.validate(ctx, encoded)
+		ctx.AddMetric("M-"+s.TypeId(), ctx.ElapsedTime())
+		if nil != err {
+			return &flux.ServeError{
+				StatusCode: flux.StatusUnauthorized,
+				ErrorCode:  flux.ErrorCodeRequestInvalid,
+				Message:    flux.ErrorMessageSamlAssertionInvalid,
+				Internal:   err,
+			}
+		}
+		if err := s.issueSession(ctx, assertion); nil != err {
+			logger.TraceContext(ctx).Warnw("SamlFilter create session failed", "error", err)
+		}
+		s.setIdentity(ctx, assertion.Subject, assertion.Issuer, assertion.Attributes)
+		return next(ctx)
+	}
+}
+
+func (s *SamlFilter) setIdentity(ctx flux.Context, subject, issuer string, attributes map[string][]string) {
+	ctx.SetAttribute(flux.XJwtSubject, subject)
+	ctx.SetAttribute(flux.XJwtIssuer, issuer)
+	ctx.SetValue(ValueKeySamlAttributes, attributes)
+}
+
+func (s *SamlFilter) validate(ctx flux.Context, encoded string) (*SamlAssertion, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if nil != err {
+		return nil, fmt.Errorf("saml: decode response: %w", err)
+	}
+	signed, err := s.Configs.SignatureVerify(ctx, raw)
+	if nil != err {
+		return nil, fmt.Errorf("saml: verify signature: %w", err)
+	}
+	assertion, err := ParseSignedSamlResponse(raw, signed, s.Configs.subjectAttr)
+	if nil != err {
+		return nil, err
+	}
+	if assertion.Issuer != s.Configs.idpIssuer {
+		return nil, ErrSamlIssuerMismatch
+	}
+	if !pkg.StringSliceContains(assertion.Audiences, s.Configs.spEntityId) {
+		return nil, ErrSamlAudienceMismatch
+	}
+	now := time.Now()
+	if (!assertion.NotBefore.IsZero() && now.Add(s.Configs.clockSkew).Before(assertion.NotBefore)) ||
+		(!assertion.NotOnOrAfter.IsZero() && !now.Add(-s.Configs.clockSkew).Before(assertion.NotOnOrAfter)) ||
+		(!assertion.ConfirmNotOnOrAfter.IsZero() && !now.Add(-s.Configs.clockSkew).Before(assertion.ConfirmNotOnOrAfter)) {
+		return nil, ErrSamlExpired
+	}
+	if "" != assertion.Destination && assertion.Destination != s.Configs.acsURL {
+		return nil, ErrSamlDestination
+	}
+	if assertion.Recipient != s.Configs.acsURL {
+		return nil, ErrSamlRecipient
+	}
+	if "" == assertion.Id {
+		return nil, ErrSamlAssertionIdEmpty
+	}
+	// 请求ID与断言ID只能使用一次
+	if "" == assertion.InResponseTo {
+		if !s.Configs.allowIdpInitiated {
+			return nil, ErrSamlInResponseTo
+		}
+	} else if ok, err := s.Configs.StateStore.ConsumeRequest(assertion.InResponseTo); nil != err {
+		return nil, err
+	} else if !ok {
+		return nil, ErrSamlInResponseTo
+	}
+	if ok, err := s.Configs.StateStore.MarkAssertion(assertion.Id, s.replayExpiresAt(now, assertion)); nil != err {
+		return nil, err
+	} else if !ok {
+		return nil, ErrSamlAssertionReplay
+	}
+	return assertion, nil
+}
+
+// replayExpiresAt 断言ID的防重放记录保留到断言失效之后
+func (s *SamlFilter) replayExpiresAt(now time.Time, assertion *SamlAssertion) time.Time {
+	expiresAt := now.Add(s.Configs.requestTTL)
+	for _, t := range []time.Time{assertion.NotOnOrAfter, assertion.ConfirmNotOnOrAfter} {
+		if t.After(expiresAt) {
+			expiresAt = t
+		}
+	}
+	return expiresAt.Add(s.Configs.clockSkew)
+}
+
+// redirectToIdp 使用HTTP-Redirect Binding将请求重定向到IdP登录，RelayState为当前请求URI
+func (s *SamlFilter) redirectToIdp(ctx flux.Context) *flux.ServeError {
+	if "" == s.Configs.idpSsoURL {
+		return &flux.ServeError{
+			StatusCode: flux.StatusUnauthorized,
+			ErrorCode:  flux.ErrorCodeRequestInvalid,
+			Message:    flux.ErrorMessageSamlAssertionInvalid,
+			Internal:   ErrSamlAssertionMissing,
+		}
+	}
+	requestId, err := newSamlRequestId()
+	if nil == err {
+		err = s.Configs.StateStore.SaveRequest("_"+requestId, time.Now().Add(s.Configs.requestTTL))
+	}
+	var request string
+	if nil == err {
+		request, err = NewSamlAuthnRequest(s.Configs.spEntityId, s.Configs.acsURL, requestId)
+	}
+	if nil != err {
+		return &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageSamlAssertionInvalid,
+			Internal:   err,
+		}
+	}
+	query := url.Values{}
+	query.Set("SAMLRequest", request)
+	query.Set("RelayState", ctx.RequestURI())
+	location := s.Configs.idpSsoURL
+	if strings.Contains(location, "?") {
+		location += "&" + query.Encode()
+	} else {
+		location += "?" + query.Encode()
+	}
+	return &flux.ServeError{
+		StatusCode: http.StatusFound,
+		ErrorCode:  flux.ErrorCodeRequestInvalid,
+		Message:    flux.ErrorMessageSamlAssertionInvalid,
+		Header:     http.Header{flux.HeaderLocation: []string{location}},
+		Internal:   ErrSamlAssertionMissing,
+	}
+}
+
+// NewSamlAuthnRequest 创建HTTP-Redirect Binding编码的AuthnRequest：base64(deflate(xml))
+func NewSamlAuthnRequest(spEntityId, acsURL, requestId string) (string, error) {
+	var doc bytes.Buffer
+	doc.WriteString(`<samlp:AuthnRequest xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion"`)
+	doc.WriteString(` ID="_` + xmlEscape(requestId) + `" Version="2.0" IssueInstant="` + time.Now().UTC().Format(time.RFC3339) + `"`)
+	if "" != acsURL {
+		doc.WriteString(` AssertionConsumerServiceURL="` + xmlEscape(acsURL) + `"`)
+	}
+	doc.WriteString(`><saml:Issuer>` + xmlEscape(spEntityId) + `</saml:Issuer></samlp:AuthnRequest>`)
+	var out bytes.Buffer
+	writer, err := flate.NewWriter(&out, flate.DefaultCompression)
+	if nil != err {
+		return "", err
+	}
+	if _, err := writer.Write(doc.Bytes()); nil != err {
+		return "", err
+	}
+	if err := writer.Close(); nil != err {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(out.Bytes()), nil
+}
+
+// ParseSamlResponse 解析SAMLResponse中的断言数据；不验证签名
+func ParseSamlResponse(raw []byte, subjectAttr string) (*SamlAssertion, error) {
+	return ParseSignedSamlResponse(raw, raw, subjectAttr)
+}
+
+// ParseSignedSamlResponse 解析SAMLResponse中的断言数据：断言只从已签名的元素 signed（Response或Assertion）中读取；
+// 只签名Assertion时，Response的Status和Destination从原始XML中读取。原始XML必须只包含一个断言。
+func ParseSignedSamlResponse(raw []byte, signed []byte, subjectAttr string) (*SamlAssertion, error) {
+	envelope := samlResponse{}
+	if err := xml.Unmarshal(raw, &envelope); nil != err {
+		return nil, fmt.Errorf("saml: parse response: %w", err)
+	}
+	if count, err := countSamlAssertions(raw); nil != err {
+		return nil, fmt.Errorf("saml: parse response: %w", err)
+	} else if 1 != count {
+		return nil, ErrSamlAssertionCount
+	}
+	root, err := rootElementOf(signed)
+	if nil != err {
+		return nil, fmt.Errorf("saml: parse signed element: %w", err)
+	}
+	var a *samlAssertion
+	switch root {
+	case xml.Name{Space: samlNamespaceProtocol, Local: "Response"}:
+		resp := samlResponse{}
+		if err := xml.Unmarshal(signed, &resp); nil != err {
+			return nil, fmt.Errorf("saml: parse signed response: %w", err)
+		}
+		envelope, a = resp, resp.Assertion
+	case xml.Name{Space: samlNamespaceAssertion, Local: "Assertion"}:
+		a = &samlAssertion{}
+		if err := xml.Unmarshal(signed, a); nil != err {
+			return nil, fmt.Errorf("saml: parse signed assertion: %w", err)
+		}
+	default:
+		return nil, ErrSamlSignedInvalid
+	}
+	if envelope.Status.StatusCode.Value != samlStatusSuccess {
+		return nil, ErrSamlStatusNotSuccess
+	}
+	if nil == a {
+		return nil, ErrSamlAssertionMissing
+	}
+	confirm := a.Subject.Confirmation.Data
+	if "" != envelope.InResponseTo && envelope.InResponseTo != confirm.InResponseTo {
+		return nil, ErrSamlInResponseTo
+	}
+	out := &SamlAssertion{
+		Id:                  a.Id,
+		Issuer:              strings.TrimSpace(a.Issuer),
+		Subject:             strings.TrimSpace(a.Subject.NameID),
+		NotBefore:           a.Conditions.NotBefore,
+		NotOnOrAfter:        a.Conditions.NotOnOrAfter,
+		Attributes:          make(map[string][]string, len(a.Attributes)),
+		Destination:         envelope.Destination,
+		InResponseTo:        confirm.InResponseTo,
+		Recipient:           confirm.Recipient,
+		ConfirmNotOnOrAfter: confirm.NotOnOrAfter,
+	}
+	for _, aud := range a.Conditions.Audiences {
+		out.Audiences = append(out.Audiences, strings.TrimSpace(aud))
+	}
+	for _, attr := range a.Attributes {
+		values := make([]string, 0, len(attr.Values))
+		for _, v := range attr.Values {
+			values = append(values, strings.TrimSpace(v))
+		}
+		out.Attributes[attr.Name] = values
+	}
+	if "" != subjectAttr {
+		if values := out.Attributes[subjectAttr]; len(values) > 0 {
+			out.Subject = values[0]
+		}
+	}
+	if "" == out.Subject {
+		return nil, ErrSamlSubjectMissing
+	}
+	return out, nil
+}
+
+// countSamlAssertions 统计XML中任意位置的Assertion元素数量
+func countSamlAssertions(data []byte) (int, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	count := 0
+	for {
+		token, err := decoder.Token()
+		if io.EOF == err {
+			return count, nil
+		} else if nil != err {
+			return 0, err
+		}
+		if se, ok := token.(xml.StartElement); ok && "Assertion" == se.Name.Local {
+			count++
+		}
+	}
+}
+
+func rootElementOf(data []byte) (xml.Name, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if nil != err {
+			return xml.Name{}, err
+		}
+		if se, ok := token.(xml.StartElement); ok {
+			return se.Name, nil
+		}
+	}
+}
+
+func newSamlRequestId() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); nil != err {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// samlSession 断言验证通过后创建的会话数据
+type samlSession struct {
+	Subject    string              `json:"sub"`
+	Issuer     string              `json:"iss"`
+	Attributes map[string][]string `json:"attrs,omitempty"`
+	ExpiresAt  int64               `json:"exp"`
+}
+
+// issueSession 创建会话Cookie：base64url(payload).{Detached JWS}
+func (s *SamlFilter) issueSession(ctx flux.Context, assertion *SamlAssertion) error {
+	expiresAt := time.Now().Add(s.Configs.sessionTTL)
+	payload, err := json.Marshal(samlSession{
+		Subject:    assertion.Subject,
+		Issuer:     assertion.Issuer,
+		Attributes: assertion.Attributes,
+		ExpiresAt:  expiresAt.Unix(),
+	})
+	if nil != err {
+		return err
+	}
+	jws, err := SignDetachedJWS(s.Configs.SecretProvider, samlSessionAlgorithm, s.Configs.sessionKeyId, payload)
+	if nil != err {
+		return err
+	}
+	cookie := &http.Cookie{
+		Name:     s.Configs.sessionCookie,
+		Value:    base64.RawURLEncoding.EncodeToString(payload) + "." + jws,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   s.Configs.sessionSecure,
+		SameSite: http.SameSiteLaxMode,
+	}
+	ctx.Response().AddHeader(flux.HeaderSetCookie, cookie.String())
+	return nil
+}
+
+// loadSession 读取并验证会话Cookie
+func (s *SamlFilter) loadSession(ctx flux.Context) (*samlSession, error) {
+	cookie, ok := ctx.Request().CookieValue(s.Configs.sessionCookie)
+	if !ok || "" == cookie.Value {
+		return nil, ErrSamlSessionInvalid
+	}
+	parts := strings.SplitN(cookie.Value, ".", 2)
+	if len(parts) != 2 {
+		return nil, ErrSamlSessionInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if nil != err {
+		return nil, ErrSamlSessionInvalid
+	}
+	if err := VerifyDetachedJWS(s.Configs.SecretProvider, []string{samlSessionAlgorithm},
+		[]string{s.Configs.sessionKeyId}, parts[1], payload); nil != err {
+		return nil, err
+	}
+	session := &samlSession{}
+	if err := json.Unmarshal(payload, session); nil != err {
+		return nil, ErrSamlSessionInvalid
+	}
+	if "" == session.Subject || time.Now().Unix() >= session.ExpiresAt {
+		return nil, ErrSamlSessionInvalid
+	}
+	return session, nil
+}
+
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+type samlResponse struct {
+	XMLName      xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol Response"`
+	Destination  string   `xml:"Destination,attr"`
+	InResponseTo string   `xml:"InResponseTo,attr"`
+	Status       struct {
+		StatusCode struct {
+			Value string `xml:"Value,attr"`
+		} `xml:"StatusCode"`
+	} `xml:"Status"`
+	Assertion *samlAssertion `xml:"urn:oasis:names:tc:SAML:2.0:assertion Assertion"`
+}
+
+type samlAssertion struct {
+	XMLName xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:assertion Assertion"`
+	Id      string   `xml:"ID,attr"`
+	Issuer  string   `xml:"Issuer"`
+	Subject struct {
+		NameID       string `xml:"NameID"`
+		Confirmation struct {
+			Data struct {
+				InResponseTo string    `xml:"InResponseTo,attr"`
+				Recipient    string    `xml:"Recipient,attr"`
+				NotOnOrAfter time.Time `xml:"NotOnOrAfter,attr"`
+			} `xml:"SubjectConfirmationData"`
+		} `xml:"SubjectConfirmation"`
+	} `xml:"Subject"`
+	Conditions struct {
+		NotBefore    time.Time `xml:"NotBefore,attr"`
+		NotOnOrAfter time.Time `xml:"NotOnOrAfter,attr"`
+		Audiences    []string  `xml:"AudienceRestriction>Audience"`
+	} `xml:"Conditions"`
+	Attributes []struct {
+		Name   string   `xml:"Name,attr"`
+		Values []string `xml:"AttributeValue"`
+	} `xml:"AttributeStatement>Attribute"`
+}
@@ -0,0 +1,232 @@
+package filter
+
+import (
+	"encoding/base64"
+	"errors"
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testSamlAcsURL = "https://gateway.corp/saml/acs"
+)
+
+type testSamlAssertion struct {
+	id           string
+	issuer       string
+	subject      string
+	audience     string
+	recipient    string
+	inResponseTo string
+	notOnOrAfter time.Time
+}
+
+func newTestSamlAssertion() testSamlAssertion {
+	return testSamlAssertion{
+		id:           "a-001",
+		issuer:       "https://idp.corp",
+		subject:      "yongjia@corp",
+		audience:     "flux",
+		recipient:    testSamlAcsURL,
+		inResponseTo: "_req-001",
+		notOnOrAfter: time.Now().Add(time.Hour),
+	}
+}
+
+func (a testSamlAssertion) xml() string {
+	return `<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="` + a.id + `">
+    <saml:Issuer>` + a.issuer + `</saml:Issuer>
+    <saml:Subject><saml:NameID>` + a.subject + `</saml:NameID>
+      <saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">
+        <saml:SubjectConfirmationData InResponseTo="` + a.inResponseTo + `" Recipient="` + a.recipient + `" NotOnOrAfter="` + a.notOnOrAfter.UTC().Format(time.RFC3339) + `"/>
+      </saml:SubjectConfirmation>
+    </saml:Subject>
+    <saml:Conditions NotBefore="` + time.Now().Add(-time.Minute).UTC().Format(time.RFC3339) + `" NotOnOrAfter="` + a.notOnOrAfter.UTC().Format(time.RFC3339) + `">
+      <saml:AudienceRestriction><saml:Audience>` + a.audience + `</saml:Audience></saml:AudienceRestriction>
+    </saml:Conditions>
+    <saml:AttributeStatement>
+      <saml:Attribute Name="groups"><saml:AttributeValue>admin</saml:AttributeValue><saml:AttributeValue>dev</saml:AttributeValue></saml:Attribute>
+    </saml:AttributeStatement>
+  </saml:Assertion>`
+}
+
+func newTestSamlResponseXML(destination string, body string) string {
+	return `<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" Destination="` + destination + `">
+  <samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/></samlp:Status>
+  ` + body + `
+</samlp:Response>`
+}
+
+func newTestSamlResponse(a testSamlAssertion) string {
+	return base64.StdEncoding.EncodeToString([]byte(newTestSamlResponseXML(testSamlAcsURL, a.xml())))
+}
+
+// newTestSamlFilter 测试用签名验证：包含forged的响应验证失败；签名覆盖整个Response
+func newTestSamlFilter(t *testing.T, store SamlStateStore, verify SamlSignatureVerifyFunc, idpInitiated bool) *SamlFilter {
+	config := flux.NewConfiguration(nil)
+	config.Set(SamlConfigKeyIdpIssuer, "https://idp.corp")
+	config.Set(SamlConfigKeySpEntityId, "flux")
+	config.Set(SamlConfigKeyIdpSsoURL, "https://idp.corp/sso")
+	config.Set(SamlConfigKeyAcsURL, testSamlAcsURL)
+	config.Set(SamlConfigKeySessionKeyId, "saml-session")
+	config.Set(SamlConfigKeyAllowIdpInitiated, idpInitiated)
+	if nil == verify {
+		verify = func(ctx flux.Context, raw []byte) ([]byte, error) {
+			if strings.Contains(string(raw), "forged") {
+				return nil, errors.New("bad signature")
+			}
+			return raw, nil
+		}
+	}
+	filter := NewSamlFilter(SamlConfig{
+		SignatureVerify: verify,
+		StateStore:      store,
+		SecretProvider: flux.SecretProviderFunc(func(keyId string) ([]byte, error) {
+			return []byte("saml-session-secret"), nil
+		}),
+	})
+	assert2.NoError(t, filter.Init(config))
+	return filter
+}
+
+func TestSamlFilter_DoFilter(t *testing.T) {
+	assert := assert2.New(t)
+	store := NewMemorySamlStateStore()
+	filter := newTestSamlFilter(t, store, nil, false)
+	next := func(ctx flux.Context) *flux.ServeError {
+		return nil
+	}
+	// 有效断言
+	assert.NoError(store.SaveRequest("_req-001", time.Now().Add(time.Minute)))
+	valid := newTestSamlResponse(newTestSamlAssertion())
+	ctx := newCryptoTestContext(map[string]interface{}{"SAMLResponse": valid}, flux.Endpoint{})
+	assert.Nil(filter.DoFilter(next)(ctx))
+	assert.Equal("yongjia@corp", ctx.GetAttributeString(flux.XJwtSubject, ""))
+	attrs, _ := ctx.GetValue(ValueKeySamlAttributes)
+	assert.Equal([]string{"admin", "dev"}, attrs.(map[string][]string)["groups"])
+	assert.Contains(ctx.response.header.Get(flux.HeaderSetCookie), "flux_saml_session=")
+	// 重复提交：请求ID已使用
+	ctx = newCryptoTestContext(map[string]interface{}{"SAMLResponse": valid}, flux.Endpoint{})
+	if err := filter.DoFilter(next)(ctx); assert.NotNil(err) {
+		assert.True(errors.Is(err.Internal, ErrSamlInResponseTo))
+	}
+	// 无效断言
+	expired := newTestSamlAssertion()
+	expired.notOnOrAfter = time.Now().Add(-time.Hour)
+	forged, audience, recipient, unknown, idpInitiated := newTestSamlAssertion(), newTestSamlAssertion(),
+		newTestSamlAssertion(), newTestSamlAssertion(), newTestSamlAssertion()
+	forged.issuer = "https://forged.idp"
+	audience.audience = "other"
+	recipient.recipient = "https://evil.corp/acs"
+	unknown.inResponseTo = "_req-unknown"
+	idpInitiated.inResponseTo = ""
+	cases := []struct {
+		response string
+		expect   error
+	}{
+		{response: newTestSamlResponse(forged)},
+		{response: newTestSamlResponse(audience), expect: ErrSamlAudienceMismatch},
+		{response: newTestSamlResponse(expired), expect: ErrSamlExpired},
+		{response: newTestSamlResponse(recipient), expect: ErrSamlRecipient},
+		{response: newTestSamlResponse(unknown), expect: ErrSamlInResponseTo},
+		{response: newTestSamlResponse(idpInitiated), expect: ErrSamlInResponseTo},
+		{response: base64.StdEncoding.EncodeToString([]byte(newTestSamlResponseXML("https://evil.corp/acs",
+			newTestSamlAssertion().xml()))), expect: ErrSamlDestination},
+		{response: "not-base64!"},
+	}
+	for _, c := range cases {
+		ctx := newCryptoTestContext(map[string]interface{}{"SAMLResponse": c.response}, flux.Endpoint{})
+		err := filter.DoFilter(next)(ctx)
+		if assert.NotNil(err) {
+			assert.Equal(flux.StatusUnauthorized, err.StatusCode)
+			if nil != c.expect {
+				assert.True(errors.Is(err.Internal, c.expect), err.Internal.Error())
+			}
+		}
+	}
+	// 需要授权且缺少断言：重定向到IdP，并保存请求ID
+	ctx = newCryptoTestContext(map[string]interface{}{"authorize": true}, flux.Endpoint{})
+	err := filter.DoFilter(next)(ctx)
+	assert.NotNil(err)
+	assert.Equal(http.StatusFound, err.StatusCode)
+	assert.True(strings.HasPrefix(err.Header.Get(flux.HeaderLocation), "https://idp.corp/sso?"))
+	assert.Len(store.requests, 1)
+}
+
+func TestSamlFilter_Session(t *testing.T) {
+	assert := assert2.New(t)
+	store := NewMemorySamlStateStore()
+	filter := newTestSamlFilter(t, store, nil, false)
+	next := func(ctx flux.Context) *flux.ServeError {
+		return nil
+	}
+	assert.NoError(store.SaveRequest("_req-001", time.Now().Add(time.Minute)))
+	ctx := newCryptoTestContext(map[string]interface{}{"SAMLResponse": newTestSamlResponse(newTestSamlAssertion())}, flux.Endpoint{})
+	assert.Nil(filter.DoFilter(next)(ctx))
+	cookies := (&http.Response{Header: ctx.response.header}).Cookies()
+	if !assert.Len(cookies, 1) {
+		return
+	}
+	cookie := cookies[0]
+	assert.True(cookie.HttpOnly)
+	assert.True(cookie.Secure)
+	// 后续请求通过会话认证
+	ctx = newCryptoTestContext(map[string]interface{}{
+		"authorize": true, "cookie-values": []*http.Cookie{cookie},
+	}, flux.Endpoint{})
+	assert.Nil(filter.DoFilter(next)(ctx))
+	assert.Equal("yongjia@corp", ctx.GetAttributeString(flux.XJwtSubject, ""))
+	// 篡改的会话无效
+	tampered := *cookie
+	tampered.Value = base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"admin","exp":9999999999}`)) +
+		cookie.Value[strings.Index(cookie.Value, "."):]
+	ctx = newCryptoTestContext(map[string]interface{}{
+		"authorize": true, "cookie-values": []*http.Cookie{&tampered},
+	}, flux.Endpoint{})
+	if serr := filter.DoFilter(next)(ctx); assert.NotNil(serr) {
+		assert.Equal(http.StatusFound, serr.StatusCode)
+	}
+}
+
+func TestSamlFilter_IdpInitiatedReplay(t *testing.T) {
+	assert := assert2.New(t)
+	filter := newTestSamlFilter(t, NewMemorySamlStateStore(), nil, true)
+	next := func(ctx flux.Context) *flux.ServeError {
+		return nil
+	}
+	a := newTestSamlAssertion()
+	a.inResponseTo = ""
+	response := newTestSamlResponse(a)
+	ctx := newCryptoTestContext(map[string]interface{}{"SAMLResponse": response}, flux.Endpoint{})
+	assert.Nil(filter.DoFilter(next)(ctx))
+	ctx = newCryptoTestContext(map[string]interface{}{"SAMLResponse": response}, flux.Endpoint{})
+	if err := filter.DoFilter(next)(ctx); assert.NotNil(err) {
+		assert.True(errors.Is(err.Internal, ErrSamlAssertionReplay))
+	}
+}
+
+func TestParseSignedSamlResponse_SignatureWrapping(t *testing.T) {
+	assert := assert2.New(t)
+	signed := newTestSamlAssertion()
+	injected := newTestSamlAssertion()
+	injected.id = "a-evil"
+	injected.subject = "admin@corp"
+	// 断言数据只从已签名元素读取
+	raw := newTestSamlResponseXML(testSamlAcsURL, injected.xml())
+	assertion, err := ParseSignedSamlResponse([]byte(raw), []byte(signed.xml()), "")
+	assert.NoError(err)
+	assert.Equal("yongjia@corp", assertion.Subject)
+	assert.Equal("a-001", assertion.Id)
+	// 已签名断言被包装在其它元素中，同时注入伪造断言
+	wrapped := newTestSamlResponseXML(testSamlAcsURL,
+		`<samlp:Extensions>`+signed.xml()+`</samlp:Extensions>`+injected.xml())
+	_, err = ParseSignedSamlResponse([]byte(wrapped), []byte(signed.xml()), "")
+	assert.True(errors.Is(err, ErrSamlAssertionCount))
+	// 已签名元素不是Response或Assertion
+	_, err = ParseSignedSamlResponse([]byte(raw), []byte(`<Object>`+signed.xml()+`</Object>`), "")
+	assert.True(errors.Is(err, ErrSamlSignedInvalid))
+}
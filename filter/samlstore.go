@@ -0,0 +1,84 @@
+package filter
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// 进程内存储的最大条目数，超过时清理过期条目
+	samlStateMaxEntries = 100000
+)
+
+// SamlStateStore 保存SP发出的AuthnRequest ID和已使用的断言ID，用于InResponseTo校验和断言防重放。
+// 多实例部署时需使用共享存储，否则IdP回调到其它实例的请求将校验失败。
+type SamlStateStore interface {
+	// SaveRequest 保存已发出的AuthnRequest ID，到期后失效
+	SaveRequest(id string, expiresAt time.Time) error
+	// ConsumeRequest 使用AuthnRequest ID，每个ID只能使用一次；ID不存在、已过期或已使用时返回false
+	ConsumeRequest(id string) (bool, error)
+	// MarkAssertion 记录已使用的断言ID，到期后失效；断言ID已使用时返回false
+	MarkAssertion(id string, expiresAt time.Time) (bool, error)
+}
+
+var _ SamlStateStore = new(MemorySamlStateStore)
+
+// MemorySamlStateStore 进程内的SamlStateStore实现
+type MemorySamlStateStore struct {
+	mu         sync.Mutex
+	requests   map[string]time.Time
+	assertions map[string]time.Time
+}
+
+func NewMemorySamlStateStore() *MemorySamlStateStore {
+	return &MemorySamlStateStore{
+		requests:   make(map[string]time.Time, 64),
+		assertions: make(map[string]time.Time, 64),
+	}
+}
+
+func (m *MemorySamlStateStore) SaveRequest(id string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !putSamlState(m.requests, id, expiresAt) {
+		return ErrSamlStateStoreFull
+	}
+	return nil
+}
+
+func (m *MemorySamlStateStore) ConsumeRequest(id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	expiresAt, ok := m.requests[id]
+	delete(m.requests, id)
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+func (m *MemorySamlStateStore) MarkAssertion(id string, expiresAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if used, ok := m.assertions[id]; ok && time.Now().Before(used) {
+		return false, nil
+	}
+	if !putSamlState(m.assertions, id, expiresAt) {
+		return false, ErrSamlStateStoreFull
+	}
+	return true, nil
+}
+
+// putSamlState 写入条目；条目数达到上限时先清理过期条目，仍达到上限时返回false
+func putSamlState(entries map[string]time.Time, id string, expiresAt time.Time) bool {
+	if _, ok := entries[id]; !ok && len(entries) >= samlStateMaxEntries {
+		now := time.Now()
+		for k, v := range entries {
+			if !now.Before(v) {
+				delete(entries, k)
+			}
+		}
+		if len(entries) >= samlStateMaxEntries {
+			return false
+		}
+	}
+	entries[id] = expiresAt
+	return true
+}
@@ -0,0 +1,472 @@
+package filter
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/pkg"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"strings"
+)
+
+const (
+	TypeIdSignatureFilter = "SignatureFilter"
+)
+
+const (
+	SignatureConfigKeyHeader            = "header"
+	SignatureConfigKeyAlgorithm         = "algorithm"
+	SignatureConfigKeyAllowedAlgorithms = "allowed-algorithms"
+	SignatureConfigKeySignKeyId         = "sign-key-id"
+	SignatureConfigKeyVerifyKeyIds      = "verify-key-ids"
+	SignatureConfigKeySignResponse      = "sign-response"
+	SignatureConfigKeyVerifyRequest     = "verify-request"
+)
+
+const (
+	// Endpoint扩展信息：是否验证请求Body签名，覆盖Filter配置的 verify-request
+	EndpointExtKeySignatureVerifyRequest = "signature-verify-request"
+	// Endpoint扩展信息：是否对响应Body签名，覆盖Filter配置的 sign-response
+	EndpointExtKeySignatureSignResponse = "signature-sign-response"
+)
+
+const (
+	// 默认签名Header；值为Detached JWS：{base64url(protected)}..{base64url(signature)}
+	SignatureHeaderDefault = "X-Jws-Signature"
+)
+
+var (
+	ErrSignatureInvalidFormat     = errors.New("signature: invalid detached jws")
+	ErrSignatureAlgNotAllowed     = errors.New("signature: algorithm not allowed")
+	ErrSignatureKeyIdNotAllowed   = errors.New("signature: key-id not allowed")
+	ErrSignatureVerifyFailed      = errors.New("signature: verify failed")
+	ErrSignatureUnsupportedSecret = errors.New("signature: unsupported secret key")
+)
+
+// SignatureAlgorithm 签名算法接口；密钥数据由SecretProvider提供
+type SignatureAlgorithm interface {
+	// Sign 使用密钥对数据签名
+	Sign(secret []byte, data []byte) ([]byte, error)
+	// Verify 使用密钥验证数据签名
+	Verify(secret []byte, data []byte, signature []byte) error
+}
+
+var (
+	signatureAlgorithms = map[string]SignatureAlgorithm{
+		"HS256": &hmacSignatureAlgorithm{hash: crypto.SHA256},
+		"HS384": &hmacSignatureAlgorithm{hash: crypto.SHA384},
+		"HS512": &hmacSignatureAlgorithm{hash: crypto.SHA512},
+		"RS256": &rsaSignatureAlgorithm{hash: crypto.SHA256},
+		"RS384": &rsaSignatureAlgorithm{hash: crypto.SHA384},
+		"RS512": &rsaSignatureAlgorithm{hash: crypto.SHA512},
+		"ES256": &ecdsaSignatureAlgorithm{hash: crypto.SHA256, size: 32},
+		"ES384": &ecdsaSignatureAlgorithm{hash: crypto.SHA384, size: 48},
+	}
+)
+
+// RegisterSignatureAlgorithm 注册签名算法，用于扩展JWS的alg；
+func RegisterSignatureAlgorithm(alg string, algorithm SignatureAlgorithm) {
+	alg = pkg.RequireNotEmpty(alg, "signature alg is empty")
+	signatureAlgorithms[alg] = pkg.RequireNotNil(algorithm, "SignatureAlgorithm is nil").(SignatureAlgorithm)
+}
+
+// SignatureConfig 请求/响应签名配置
+type SignatureConfig struct {
+	SkipFunc          flux.FilterSkipper
+	SecretProvider    flux.SecretProvider
+	header            string
+	algorithm         string
+	allowedAlgorithms []string
+	signKeyId         string
+	verifyKeyIds      []string
+	signResponse      bool
+	verifyRequest     bool
+}
+
+func NewSignatureFilter(c SignatureConfig) *SignatureFilter {
+	return &SignatureFilter{
+		Configs: c,
+	}
+}
+
+// SignatureFilter 提供请求Body签名验证和响应Body签名功能，签名格式为Detached JWS（RFC7515 Appendix F）。
+type SignatureFilter struct {
+	Disabled bool
+	Configs  SignatureConfig
+}
+
+func (s *SignatureFilter) Init(config *flux.Configuration) error {
+	config.SetDefaults(map[string]interface{}{
+		ConfigKeyDisabled:                   false,
+		SignatureConfigKeyHeader:            SignatureHeaderDefault,
+		SignatureConfigKeyAlgorithm:         "HS256",
+		SignatureConfigKeySignResponse:      false,
+		SignatureConfigKeyVerifyRequest:     false,
+		SignatureConfigKeyAllowedAlgorithms: []string{"HS256", "RS256", "ES256"},
+	})
+	s.Disabled = config.GetBool(ConfigKeyDisabled)
+	if s.Disabled {
+		logger.Info("Endpoint SignatureFilter was DISABLED!!")
+		return nil
+	}
+	s.Configs.header = config.GetString(SignatureConfigKeyHeader)
+	s.Configs.algorithm = config.GetString(SignatureConfigKeyAlgorithm)
+	s.Configs.allowedAlgorithms = config.GetStringSlice(SignatureConfigKeyAllowedAlgorithms)
+	s.Configs.signKeyId = config.GetString(SignatureConfigKeySignKeyId)
+	s.Configs.verifyKeyIds = config.GetStringSlice(SignatureConfigKeyVerifyKeyIds)
+	s.Configs.signResponse = config.GetBool(SignatureConfigKeySignResponse)
+	s.Configs.verifyRequest = config.GetBool(SignatureConfigKeyVerifyRequest)
+	if s.Configs.signResponse && "" == s.Configs.signKeyId {
+		return errors.New("SignatureFilter.sign-key-id is empty")
+	}
+	if s.Configs.verifyRequest && len(s.Configs.verifyKeyIds) == 0 {
+		return errors.New("SignatureFilter.verify-key-ids is empty")
+	}
+	if _, ok := signatureAlgorithms[s.Configs.algorithm]; !ok {
+		return fmt.Errorf("SignatureFilter.algorithm not supported: %s", s.Configs.algorithm)
+	}
+	for _, alg := range s.Configs.allowedAlgorithms {
+		if _, ok := signatureAlgorithms[alg]; !ok {
+			return fmt.Errorf("SignatureFilter.allowed-algorithms not supported: %s", alg)
+		}
+	}
+	if pkg.IsNil(s.Configs.SkipFunc) {
+		s.Configs.SkipFunc = func(_ flux.Context) bool {
+			return false
+		}
+	}
+	if pkg.IsNil(s.Configs.SecretProvider) {
+		s.Configs.SecretProvider = ext.LoadSecretProvider()
+	}
+	if pkg.IsNil(s.Configs.SecretProvider) {
+		return errors.New("SignatureFilter.SecretProvider is nil")
+	}
+	return nil
+}
+
+func (*SignatureFilter) TypeId() string {
+	return TypeIdSignatureFilter
+}
+
+func (s *SignatureFilter) DoFilter(next flux.FilterHandler) flux.FilterHandler {
+	if s.Disabled {
+		return next
+	}
+	return func(ctx flux.Context) *flux.ServeError {
+		if s.Configs.SkipFunc(ctx) {
+			return next(ctx)
+		}
+		endpoint := ctx.Endpoint()
+		if extBoolOf(endpoint, EndpointExtKeySignatureVerifyRequest, s.Configs.verifyRequest) {
+			if err := s.verifyRequest(ctx); nil != err {
+				return &flux.ServeError{
+					StatusCode: flux.StatusUnauthorized,
+					ErrorCode:  flux.ErrorCodeRequestInvalid,
+					Message:    flux.ErrorMessageSignatureVerifyFailed,
+					Internal:   err,
+				}
+			}
+		}
+		ctx.AddMetric("M-"+s.TypeId(), ctx.ElapsedTime())
+		if err := next(ctx); nil != err {
+			return err
+		}
+		if extBoolOf(endpoint, EndpointExtKeySignatureSignResponse, s.Configs.signResponse) {
+			if err := s.signResponse(ctx); nil != err {
+				return &flux.ServeError{
+					StatusCode: flux.StatusServerError,
+					ErrorCode:  flux.ErrorCodeGatewayInternal,
+					Message:    flux.ErrorMessageSignatureSignFailed,
+					Internal:   err,
+				}
+			}
+		}
+		return nil
+	}
+}
+
+func (s *SignatureFilter) verifyRequest(ctx flux.Context) error {
+	jws := ctx.Request().HeaderValue(s.Configs.header)
+	if "" == jws {
+		return fmt.Errorf("signature header is missing, header: %s", s.Configs.header)
+	}
+	reader, err := ctx.Request().RequestBodyReader()
+	if nil != err {
+		return fmt.Errorf("read request body: %w", err)
+	}
+	var payload []byte
+	if nil != reader {
+		payload, err = ioutil.ReadAll(reader)
+		_ = reader.Close()
+		if nil != err {
+			return fmt.Errorf("read request body: %w", err)
+		}
+	}
+	return VerifyDetachedJWS(s.Configs.SecretProvider, s.Configs.allowedAlgorithms, s.Configs.verifyKeyIds, jws, payload)
+}
+
+func (s *SignatureFilter) signResponse(ctx flux.Context) error {
+	payload, err := toSignaturePayload(ctx.Response().Body())
+	if nil != err {
+		return fmt.Errorf("encode response body: %w", err)
+	}
+	jws, err := SignDetachedJWS(s.Configs.SecretProvider, s.Configs.algorithm, s.Configs.signKeyId, payload)
+	if nil != err {
+		return err
+	}
+	// 签名后的响应Body必须按原始字节输出
+	ctx.Response().HeaderValues().Del(flux.HeaderContentLength)
+	ctx.Response().SetHeader(s.Configs.header, jws)
+	ctx.Response().SetBody(ioutil.NopCloser(bytes.NewReader(payload)))
+	return nil
+}
+
+// SignDetachedJWS 对Payload生成Detached JWS：{base64url(protected)}..{base64url(signature)}
+func SignDetachedJWS(provider flux.SecretProvider, alg string, keyId string, payload []byte) (string, error) {
+	algorithm, ok := signatureAlgorithms[alg]
+	if !ok {
+		return "", ErrSignatureAlgNotAllowed
+	}
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": keyId})
+	if nil != err {
+		return "", err
+	}
+	protected := base64.RawURLEncoding.EncodeToString(header)
+	secret, err := provider.LoadSecret(keyId)
+	if nil != err {
+		return "", err
+	}
+	signature, err := algorithm.Sign(secret, signingInputOf(protected, payload))
+	if nil != err {
+		return "", err
+	}
+	return protected + ".." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// VerifyDetachedJWS 验证Payload的Detached JWS签名；alg和kid必须在允许列表中
+func VerifyDetachedJWS(provider flux.SecretProvider, allowedAlgs []string, allowedKeyIds []string, jws string, payload []byte) error {
+	parts := strings.Split(jws, ".")
+	if len(parts) != 3 || "" != parts[1] {
+		return ErrSignatureInvalidFormat
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if nil != err {
+		return ErrSignatureInvalidFormat
+	}
+	header := struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+	if err := json.Unmarshal(data, &header); nil != err {
+		return ErrSignatureInvalidFormat
+	}
+	algorithm, ok := signatureAlgorithms[header.Alg]
+	if !ok || !pkg.StringSliceContains(allowedAlgs, header.Alg) {
+		return ErrSignatureAlgNotAllowed
+	}
+	if !pkg.StringSliceContains(allowedKeyIds, header.Kid) {
+		return ErrSignatureKeyIdNotAllowed
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if nil != err {
+		return ErrSignatureInvalidFormat
+	}
+	secret, err := provider.LoadSecret(header.Kid)
+	if nil != err {
+		return err
+	}
+	return algorithm.Verify(secret, signingInputOf(parts[0], payload), signature)
+}
+
+func signingInputOf(protected string, payload []byte) []byte {
+	return []byte(protected + "." + base64.RawURLEncoding.EncodeToString(payload))
+}
+
+func toSignaturePayload(body interface{}) ([]byte, error) {
+	switch v := body.(type) {
+	case nil:
+		return []byte{}, nil
+	case io.Reader:
+		data, err := ioutil.ReadAll(v)
+		if closer, ok := v.(io.Closer); ok {
+			_ = closer.Close()
+		}
+		return data, err
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return ext.JSONMarshal(v)
+	}
+}
+
+func extBoolOf(endpoint flux.Endpoint, extKey string, defaults bool) bool {
+	if _, ok := endpoint.Ext(extKey); ok {
+		return endpoint.ExtBool(extKey)
+	}
+	return defaults
+}
+
+////
+
+type hmacSignatureAlgorithm struct {
+	hash crypto.Hash
+}
+
+func (h *hmacSignatureAlgorithm) Sign(secret []byte, data []byte) ([]byte, error) {
+	mac := hmac.New(h.hash.New, secret)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+func (h *hmacSignatureAlgorithm) Verify(secret []byte, data []byte, signature []byte) error {
+	expected, _ := h.Sign(secret, data)
+	if !hmac.Equal(expected, signature) {
+		return ErrSignatureVerifyFailed
+	}
+	return nil
+}
+
+type rsaSignatureAlgorithm struct {
+	hash crypto.Hash
+}
+
+func (r *rsaSignatureAlgorithm) Sign(secret []byte, data []byte) ([]byte, error) {
+	key, err := parsePrivateKey(secret)
+	if nil != err {
+		return nil, err
+	}
+	if rk, ok := key.(*rsa.PrivateKey); ok {
+		return rsa.SignPKCS1v15(rand.Reader, rk, r.hash, hashOf(r.hash, data))
+	}
+	return nil, ErrSignatureUnsupportedSecret
+}
+
+func (r *rsaSignatureAlgorithm) Verify(secret []byte, data []byte, signature []byte) error {
+	key, err := parsePublicKey(secret)
+	if nil != err {
+		return err
+	}
+	if rk, ok := key.(*rsa.PublicKey); ok {
+		if err := rsa.VerifyPKCS1v15(rk, r.hash, hashOf(r.hash, data), signature); nil != err {
+			return ErrSignatureVerifyFailed
+		}
+		return nil
+	}
+	return ErrSignatureUnsupportedSecret
+}
+
+type ecdsaSignatureAlgorithm struct {
+	hash crypto.Hash
+	size int
+}
+
+func (e *ecdsaSignatureAlgorithm) Sign(secret []byte, data []byte) ([]byte, error) {
+	key, err := parsePrivateKey(secret)
+	if nil != err {
+		return nil, err
+	}
+	ek, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, ErrSignatureUnsupportedSecret
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, ek, hashOf(e.hash, data))
+	if nil != err {
+		return nil, err
+	}
+	// JWS的ECDSA签名为定长 R||S
+	out := make([]byte, 2*e.size)
+	rb, sb := r.Bytes(), s.Bytes()
+	copy(out[e.size-len(rb):e.size], rb)
+	copy(out[2*e.size-len(sb):], sb)
+	return out, nil
+}
+
+func (e *ecdsaSignatureAlgorithm) Verify(secret []byte, data []byte, signature []byte) error {
+	key, err := parsePublicKey(secret)
+	if nil != err {
+		return err
+	}
+	ek, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return ErrSignatureUnsupportedSecret
+	}
+	if len(signature) != 2*e.size {
+		return ErrSignatureVerifyFailed
+	}
+	r := new(big.Int).SetBytes(signature[:e.size])
+	s := new(big.Int).SetBytes(signature[e.size:])
+	if !ecdsa.Verify(ek, hashOf(e.hash, data), r, s) {
+		return ErrSignatureVerifyFailed
+	}
+	return nil
+}
+
+func hashOf(hash crypto.Hash, data []byte) []byte {
+	switch hash {
+	case crypto.SHA384:
+		sum := sha512.Sum384(data)
+		return sum[:]
+	case crypto.SHA512:
+		sum := sha512.Sum512(data)
+		return sum[:]
+	default:
+		sum := sha256.Sum256(data)
+		return sum[:]
+	}
+}
+
+// parsePrivateKey 解析PEM格式的私钥：PKCS8/PKCS1/EC
+func parsePrivateKey(secret []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(secret)
+	if nil == block {
+		return nil, ErrSignatureUnsupportedSecret
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); nil == err {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); nil == err {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); nil == err {
+		return key, nil
+	}
+	return nil, ErrSignatureUnsupportedSecret
+}
+
+// parsePublicKey 解析PEM格式的公钥：PKIX/PKCS1；也支持从私钥中获取公钥
+func parsePublicKey(secret []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(secret)
+	if nil == block {
+		return nil, ErrSignatureUnsupportedSecret
+	}
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); nil == err {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); nil == err {
+		return key, nil
+	}
+	if key, err := parsePrivateKey(secret); nil == err {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer.Public(), nil
+		}
+	}
+	return nil, ErrSignatureUnsupportedSecret
+}
@@ -0,0 +1,95 @@
+package filter
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func newTestSignatureSecrets(t *testing.T) flux.SecretProvider {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert2.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert2.NoError(t, err)
+	ecBytes, err := x509.MarshalECPrivateKey(ecKey)
+	assert2.NoError(t, err)
+	secrets := map[string][]byte{
+		"hs": []byte("signature-secret"),
+		"rs": pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}),
+		"es": pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecBytes}),
+	}
+	return flux.SecretProviderFunc(func(keyId string) ([]byte, error) {
+		if v, ok := secrets[keyId]; ok {
+			return v, nil
+		}
+		return nil, errors.New("not found")
+	})
+}
+
+func TestSignatureDetachedJWS(t *testing.T) {
+	assert := assert2.New(t)
+	secrets := newTestSignatureSecrets(t)
+	payload := []byte(`{"amount":100}`)
+	algs := []string{"HS256", "RS256", "ES256"}
+	for alg, kid := range map[string]string{"HS256": "hs", "RS256": "rs", "ES256": "es"} {
+		jws, err := SignDetachedJWS(secrets, alg, kid, payload)
+		assert.NoError(err, alg)
+		assert.Equal(3, len(strings.Split(jws, ".")))
+		assert.NoError(VerifyDetachedJWS(secrets, algs, []string{kid}, jws, payload), alg)
+		assert.Error(VerifyDetachedJWS(secrets, algs, []string{kid}, jws, []byte(`{"amount":999}`)), alg)
+		assert.Equal(ErrSignatureKeyIdNotAllowed, VerifyDetachedJWS(secrets, algs, []string{"other"}, jws, payload))
+		assert.Equal(ErrSignatureAlgNotAllowed, VerifyDetachedJWS(secrets, []string{"HS512"}, []string{kid}, jws, payload))
+	}
+	assert.Equal(ErrSignatureInvalidFormat, VerifyDetachedJWS(secrets, algs, []string{"hs"}, "a.b.c", payload))
+}
+
+func TestSignatureFilter_DoFilter(t *testing.T) {
+	assert := assert2.New(t)
+	secrets := newTestSignatureSecrets(t)
+	config := flux.NewConfiguration(nil)
+	config.Set(SignatureConfigKeySignKeyId, "rs")
+	config.Set(SignatureConfigKeyAlgorithm, "RS256")
+	config.Set(SignatureConfigKeyVerifyKeyIds, []string{"hs"})
+	config.Set(SignatureConfigKeyVerifyRequest, true)
+	config.Set(SignatureConfigKeySignResponse, true)
+	filter := NewSignatureFilter(SignatureConfig{SecretProvider: secrets})
+	assert.NoError(filter.Init(config))
+	handler := filter.DoFilter(func(ctx flux.Context) *flux.ServeError {
+		ctx.Response().SetBody(map[string]interface{}{"ok": true})
+		return nil
+	})
+	payload := `{"amount":100}`
+	jws, _ := SignDetachedJWS(secrets, "HS256", "hs", []byte(payload))
+	// 签名正确
+	ctx := newCryptoTestContext(map[string]interface{}{
+		SignatureHeaderDefault: jws,
+		"body":                 ioutil.NopCloser(strings.NewReader(payload)),
+	}, flux.Endpoint{})
+	assert.Nil(handler(ctx))
+	signature := ctx.response.HeaderValues().Get(SignatureHeaderDefault)
+	body, _ := ioutil.ReadAll(ctx.response.Body().(io.Reader))
+	assert.NoError(VerifyDetachedJWS(secrets, []string{"RS256"}, []string{"rs"}, signature, body))
+	// 签名不匹配
+	ctx = newCryptoTestContext(map[string]interface{}{
+		SignatureHeaderDefault: jws,
+		"body":                 ioutil.NopCloser(strings.NewReader(`{"amount":999}`)),
+	}, flux.Endpoint{})
+	err := handler(ctx)
+	assert.NotNil(err)
+	assert.Equal(flux.StatusUnauthorized, err.StatusCode)
+	// Endpoint关闭请求验证
+	endpoint := flux.Endpoint{}
+	endpoint.Extensions = map[string]interface{}{EndpointExtKeySignatureVerifyRequest: false}
+	ctx = newCryptoTestContext(map[string]interface{}{}, endpoint)
+	assert.Nil(handler(ctx))
+}
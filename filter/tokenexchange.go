@@ -0,0 +1,224 @@
+package filter
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/pkg"
+	"time"
+)
+
+const (
+	TypeIdTokenExchangeFilter = "TokenExchangeFilter"
+)
+
+const (
+	TokenExchangeConfigKeyAlgorithm   = "algorithm"
+	TokenExchangeConfigKeySignKeyId   = "sign-key-id"
+	TokenExchangeConfigKeyIssuer      = "issuer"
+	TokenExchangeConfigKeyAudience    = "audience"
+	TokenExchangeConfigKeyTokenTTL    = "token-ttl"
+	TokenExchangeConfigKeyClaims      = "forward-claims"
+	TokenExchangeConfigKeyStripHeader = "strip-header"
+)
+
+var (
+	// ErrTokenExchangeRejected Token服务拒绝交换外部Token；TokenExchangeFunc 返回此错误（或包装此错误）时响应401
+	ErrTokenExchangeRejected = errors.New("token exchange: token rejected")
+)
+
+const (
+	// JWT验证Filter设置到Context.Value中的Token Claims，类型为map[string]interface{}
+	ValueKeyJwtClaims = "jwt-claims"
+)
+
+type (
+	// TokenExchangeFunc 将外部Token交换为内部Token的函数；例如调用内部Token服务。
+	// Token被拒绝时返回 ErrTokenExchangeRejected；其它错误视为Token服务故障，响应502。
+	// @param subject 外部Token的subject；
+	// @param claims 外部Token的Claims，可能为nil；
+	TokenExchangeFunc func(ctx flux.Context, subject string, claims map[string]interface{}) (token string, err error)
+)
+
+// TokenExchangeConfig Token交换配置
+type TokenExchangeConfig struct {
+	SkipFunc       flux.FilterSkipper
+	SecretProvider flux.SecretProvider
+	// ExchangeFunc 自定义Token交换函数；未设置时，使用sign-key-id指定的密钥在本地签发内部JWT
+	ExchangeFunc  TokenExchangeFunc
+	algorithm     string
+	signKeyId     string
+	issuer        string
+	audience      string
+	tokenTTL      time.Duration
+	forwardClaims []string
+	stripHeader   string
+}
+
+func NewTokenExchangeFilter(c TokenExchangeConfig) *TokenExchangeFilter {
+	return &TokenExchangeFilter{
+		Configs: c,
+	}
+}
+
+// TokenExchangeFilter 将已验证的外部JWT交换为短期有效的内部Token。
+// 内部Token替换Context.Attributes中的 X-Jwt-Token，并移除请求中携带外部Token的Header，
+// 确保后端服务不会接收到外部Token。
+type TokenExchangeFilter struct {
+	Disabled bool
+	Configs  TokenExchangeConfig
+}
+
+func (t *TokenExchangeFilter) Init(config *flux.Configuration) error {
+	config.SetDefaults(map[string]interface{}{
+		ConfigKeyDisabled:                 false,
+		TokenExchangeConfigKeyAlgorithm:   "HS256",
+		TokenExchangeConfigKeyIssuer:      "flux",
+		TokenExchangeConfigKeyTokenTTL:    "60s",
+		TokenExchangeConfigKeyStripHeader: flux.HeaderAuthorization,
+	})
+	t.Disabled = config.GetBool(ConfigKeyDisabled)
+	if t.Disabled {
+		logger.Info("Endpoint TokenExchangeFilter was DISABLED!!")
+		return nil
+	}
+	t.Configs.algorithm = config.GetString(TokenExchangeConfigKeyAlgorithm)
+	t.Configs.signKeyId = config.GetString(TokenExchangeConfigKeySignKeyId)
+	t.Configs.issuer = config.GetString(TokenExchangeConfigKeyIssuer)
+	t.Configs.audience = config.GetString(TokenExchangeConfigKeyAudience)
+	t.Configs.tokenTTL = config.GetDuration(TokenExchangeConfigKeyTokenTTL)
+	t.Configs.forwardClaims = config.GetStringSlice(TokenExchangeConfigKeyClaims)
+	t.Configs.stripHeader = config.GetString(TokenExchangeConfigKeyStripHeader)
+	if pkg.IsNil(t.Configs.SkipFunc) {
+		t.Configs.SkipFunc = func(_ flux.Context) bool {
+			return false
+		}
+	}
+	if !pkg.IsNil(t.Configs.ExchangeFunc) {
+		return nil
+	}
+	// 本地签发
+	if _, ok := signatureAlgorithms[t.Configs.algorithm]; !ok {
+		return fmt.Errorf("TokenExchangeFilter.algorithm not supported: %s", t.Configs.algorithm)
+	}
+	if "" == t.Configs.signKeyId {
+		return errors.New("TokenExchangeFilter.sign-key-id is empty")
+	}
+	if t.Configs.tokenTTL <= 0 {
+		return errors.New("TokenExchangeFilter.token-ttl is invalid")
+	}
+	if pkg.IsNil(t.Configs.SecretProvider) {
+		t.Configs.SecretProvider = ext.LoadSecretProvider()
+	}
+	if pkg.IsNil(t.Configs.SecretProvider) {
+		return errors.New("TokenExchangeFilter.SecretProvider is nil")
+	}
+	t.Configs.ExchangeFunc = t.signInternalToken
+	return nil
+}
+
+func (*TokenExchangeFilter) TypeId() string {
+	return TypeIdTokenExchangeFilter
+}
+
+func (t *TokenExchangeFilter) DoFilter(next flux.FilterHandler) flux.FilterHandler {
+	if t.Disabled {
+		return next
+	}
+	return func(ctx flux.Context) *flux.ServeError {
+		if t.Configs.SkipFunc(ctx) {
+			return next(ctx)
+		}
+		subject := ctx.GetAttributeString(flux.XJwtSubject, "")
+		if "" == subject {
+			// 未经过JWT验证的请求，不允许外部Token透传到后端
+			t.stripExternalToken(ctx)
+			return next(ctx)
+		}
+		var claims map[string]interface{}
+		if v, ok := ctx.GetValue(ValueKeyJwtClaims); ok {
+			claims, _ = v.(map[string]interface{})
+		}
+		token, err := t.Configs.ExchangeFunc(ctx, subject, claims)
+		ctx.AddMetric("M-"+t.TypeId(), ctx.ElapsedTime())
+		if errors.Is(err, ErrTokenExchangeRejected) {
+			return &flux.ServeError{
+				StatusCode: flux.StatusUnauthorized,
+				ErrorCode:  flux.ErrorCodeRequestInvalid,
+				Message:    flux.ErrorMessageTokenExchangeRejected,
+				Internal:   err,
+			}
+		} else if nil != err {
+			return &flux.ServeError{
+				StatusCode: flux.StatusBadGateway,
+				ErrorCode:  flux.ErrorCodeGatewayBackend,
+				Message:    flux.ErrorMessageTokenExchangeFailed,
+				Internal:   err,
+			}
+		}
+		t.stripExternalToken(ctx)
+		ctx.SetAttribute(flux.XJwtToken, token)
+		return next(ctx)
+	}
+}
+
+func (t *TokenExchangeFilter) stripExternalToken(ctx flux.Context) {
+	ctx.SetAttribute(flux.XJwtToken, "")
+	if "" == t.Configs.stripHeader {
+		return
+	}
+	if header, writable := ctx.Request().HeaderValues(); writable {
+		header.Del(t.Configs.stripHeader)
+	} else if "" != ctx.Request().HeaderValue(t.Configs.stripHeader) {
+		logger.TraceContext(ctx).Warnw("TokenExchangeFilter cannot strip read-only header", "header", t.Configs.stripHeader)
+	}
+}
+
+// signInternalToken 使用本地密钥签发内部JWT
+func (t *TokenExchangeFilter) signInternalToken(_ flux.Context, subject string, claims map[string]interface{}) (string, error) {
+	now := time.Now()
+	payload := make(map[string]interface{}, 6+len(t.Configs.forwardClaims))
+	for _, name := range t.Configs.forwardClaims {
+		if v, ok := claims[name]; ok {
+			payload[name] = v
+		}
+	}
+	payload["iss"] = t.Configs.issuer
+	payload["sub"] = subject
+	payload["iat"] = now.Unix()
+	payload["exp"] = now.Add(t.Configs.tokenTTL).Unix()
+	if "" != t.Configs.audience {
+		payload["aud"] = t.Configs.audience
+	}
+	return SignJWT(t.Configs.SecretProvider, t.Configs.algorithm, t.Configs.signKeyId, payload)
+}
+
+// SignJWT 使用指定算法和KeyId的密钥签发JWT
+func SignJWT(provider flux.SecretProvider, alg string, keyId string, claims map[string]interface{}) (string, error) {
+	algorithm, ok := signatureAlgorithms[alg]
+	if !ok {
+		return "", ErrSignatureAlgNotAllowed
+	}
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": keyId})
+	if nil != err {
+		return "", err
+	}
+	body, err := json.Marshal(claims)
+	if nil != err {
+		return "", err
+	}
+	secret, err := provider.LoadSecret(keyId)
+	if nil != err {
+		return "", err
+	}
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	signature, err := algorithm.Sign(secret, []byte(input))
+	if nil != err {
+		return "", err
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,85 @@
+package filter
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestTokenExchangeFilter_DoFilter(t *testing.T) {
+	assert := assert2.New(t)
+	config := flux.NewConfiguration(nil)
+	config.Set(TokenExchangeConfigKeySignKeyId, "k1")
+	config.Set(TokenExchangeConfigKeyAudience, "internal")
+	config.Set(TokenExchangeConfigKeyClaims, []string{"role"})
+	filter := NewTokenExchangeFilter(TokenExchangeConfig{SecretProvider: testSecretProvider})
+	assert.NoError(filter.Init(config))
+	ctx := newCryptoTestContext(map[string]interface{}{
+		flux.XJwtSubject:  "yongjia",
+		flux.XJwtToken:    "external-token",
+		ValueKeyJwtClaims: map[string]interface{}{"role": "admin", "email": "a@b.c"},
+	}, flux.Endpoint{})
+	var token string
+	assert.Nil(filter.DoFilter(func(ctx flux.Context) *flux.ServeError {
+		token = ctx.GetAttributeString(flux.XJwtToken, "")
+		return nil
+	})(ctx))
+	parts := strings.Split(token, ".")
+	assert.Equal(3, len(parts))
+	data, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	claims := map[string]interface{}{}
+	assert.NoError(json.Unmarshal(data, &claims))
+	assert.Equal("yongjia", claims["sub"])
+	assert.Equal("internal", claims["aud"])
+	assert.Equal("admin", claims["role"])
+	assert.Nil(claims["email"])
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	secret, _ := testSecretProvider.LoadSecret("k1")
+	assert.NoError(signatureAlgorithms["HS256"].Verify(secret, []byte(parts[0]+"."+parts[1]), signature))
+}
+
+func TestTokenExchangeFilter_ExchangeFunc(t *testing.T) {
+	assert := assert2.New(t)
+	filter := NewTokenExchangeFilter(TokenExchangeConfig{
+		ExchangeFunc: func(ctx flux.Context, subject string, claims map[string]interface{}) (string, error) {
+			switch subject {
+			case "denied":
+				return "", fmt.Errorf("subject %s: %w", subject, ErrTokenExchangeRejected)
+			case "unavailable":
+				return "", errors.New("token service unavailable")
+			}
+			return "internal:" + subject, nil
+		},
+	})
+	assert.NoError(filter.Init(flux.NewConfiguration(nil)))
+	next := func(ctx flux.Context) *flux.ServeError {
+		return nil
+	}
+	// 匿名请求：外部Token不透传
+	ctx := newCryptoTestContext(map[string]interface{}{flux.XJwtToken: "external-token"}, flux.Endpoint{})
+	assert.Nil(filter.DoFilter(next)(ctx))
+	assert.Equal("", ctx.GetAttributeString(flux.XJwtToken, ""))
+	ctx = newCryptoTestContext(map[string]interface{}{flux.XJwtSubject: "yongjia"}, flux.Endpoint{})
+	assert.Nil(filter.DoFilter(next)(ctx))
+	assert.Equal("internal:yongjia", ctx.GetAttributeString(flux.XJwtToken, ""))
+	// Token被拒绝
+	ctx = newCryptoTestContext(map[string]interface{}{flux.XJwtSubject: "denied"}, flux.Endpoint{})
+	err := filter.DoFilter(next)(ctx)
+	if assert.NotNil(err) {
+		assert.Equal(flux.StatusUnauthorized, err.StatusCode)
+		assert.Equal(flux.ErrorMessageTokenExchangeRejected, err.Message)
+	}
+	// Token服务故障
+	ctx = newCryptoTestContext(map[string]interface{}{flux.XJwtSubject: "unavailable"}, flux.Endpoint{})
+	err = filter.DoFilter(next)(ctx)
+	if assert.NotNil(err) {
+		assert.Equal(flux.StatusBadGateway, err.StatusCode)
+		assert.Equal(flux.ErrorCodeGatewayBackend, err.ErrorCode)
+		assert.Equal(flux.ErrorMessageTokenExchangeFailed, err.Message)
+	}
+}
@@ -28,3 +28,22 @@ func TestStateError_ErrorPresent(t *testing.T) {
 	assert := assert2.New(t)
 	assert.Equal("ServeError: StatusCode=500, ErrorCode=SERVER_ERROR, Message=Server internal error, Error=error", emsg)
 }
+
+func TestServeError_GetCategory(t *testing.T) {
+	assert := assert2.New(t)
+	cases := []struct {
+		err      *ServeError
+		category string
+	}{
+		{err: &ServeError{StatusCode: 400, ErrorCode: ErrorCodeRequestInvalid}, category: ErrorCategoryClient},
+		{err: &ServeError{StatusCode: 500, ErrorCode: ErrorCodeGatewayInternal}, category: ErrorCategoryGateway},
+		{err: &ServeError{StatusCode: 502, ErrorCode: ErrorCodeGatewayBackend}, category: ErrorCategoryUpstream},
+		{err: &ServeError{StatusCode: 504, ErrorCode: ErrorCodeGatewayTimeout}, category: ErrorCategoryUpstream},
+		{err: &ServeError{StatusCode: 404, UpstreamStatus: 404}, category: ErrorCategoryUpstream},
+		{err: &ServeError{StatusCode: 500, UpstreamErrorCode: "com.foo.BizException"}, category: ErrorCategoryUpstream},
+		{err: &ServeError{StatusCode: 502, ErrorCode: ErrorCodeGatewayBackend, Category: ErrorCategoryGateway}, category: ErrorCategoryGateway},
+	}
+	for i, c := range cases {
+		assert.Equal(c.category, c.err.GetCategory(), "case: %d", i)
+	}
+}
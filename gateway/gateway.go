@@ -0,0 +1,185 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/registry"
+	"github.com/bytepowered/flux/server"
+	"github.com/bytepowered/flux/webecho"
+	"github.com/spf13/viper"
+	"net"
+)
+
+const (
+	// 嵌入式网关使用的EndpointRegistry ID
+	EndpointRegistryIdEmbedded = "embedded"
+)
+
+var (
+	ErrEndpointRegistryNotMemory = errors.New("gateway: endpoint registry is not a MemoryEndpointRegistry")
+)
+
+// Option 网关配置选项
+type Option func(*Gateway)
+
+// WithEndpointRegistry 使用指定的EndpointRegistry；默认为内存注册中心，通过AddEndpoint添加Endpoint
+func WithEndpointRegistry(r flux.EndpointRegistry) Option {
+	return func(g *Gateway) {
+		g.registry = r
+	}
+}
+
+// WithGlobalFilter 添加全局Filter
+func WithGlobalFilter(filters ...flux.Filter) Option {
+	return func(g *Gateway) {
+		for _, f := range filters {
+			g.extensions.StoreGlobalFilter(f)
+		}
+	}
+}
+
+// WithSelectiveFilter 添加可选Filter
+func WithSelectiveFilter(filters ...flux.Filter) Option {
+	return func(g *Gateway) {
+		for _, f := range filters {
+			g.extensions.StoreSelectiveFilter(f)
+		}
+	}
+}
+
+// WithBackendTransport 添加指定协议的后端
+func WithBackendTransport(protoName string, backend flux.BackendTransport) Option {
+	return func(g *Gateway) {
+		g.extensions.StoreBackendTransport(protoName, backend)
+	}
+}
+
+// WithWebServerFactory 使用指定的WebServer实现；默认为echo实现
+func WithWebServerFactory(factory ext.WebServerFactory) Option {
+	return func(g *Gateway) {
+		g.extensions.StoreWebServerFactory(factory)
+	}
+}
+
+// WithConfig 设置网关的配置项，例如 HttpWebServer.read-only；配置项只作用于当前网关
+func WithConfig(key string, value interface{}) Option {
+	return func(g *Gateway) {
+		g.config.Set(key, value)
+	}
+}
+
+// WithConfiguration 使用指定的根配置；默认为网关私有的空配置。使用Viper全局配置时传入 flux.NewGlobalConfiguration()
+func WithConfiguration(config *flux.Configuration) Option {
+	return func(g *Gateway) {
+		g.config = config
+	}
+}
+
+// WithPrepareHooks 添加网关初始化前执行的Hook
+func WithPrepareHooks(hooks ...flux.PrepareHookFunc) Option {
+	return func(g *Gateway) {
+		g.prepareHooks = append(g.prepareHooks, hooks...)
+	}
+}
+
+// WithBuildInfo 设置网关版本信息
+func WithBuildInfo(info flux.BuildInfo) Option {
+	return func(g *Gateway) {
+		g.buildInfo = info
+	}
+}
+
+// Gateway 以编程方式嵌入网关引擎的门面：
+//
+//	gateway.New(options...).AddEndpoint(endpoint).Serve(listener)
+//
+// 每个网关使用私有的配置；通过 NewWith 使用独立的扩展组件注册表时，同一进程内的多个网关相互隔离。
+type Gateway struct {
+	engine       *server.HttpServeEngine
+	extensions   *ext.Registry
+	config       *flux.Configuration
+	registry     flux.EndpointRegistry
+	prepareHooks []flux.PrepareHookFunc
+	buildInfo    flux.BuildInfo
+	err          error
+}
+
+// New 根据配置选项创建网关；扩展组件注册到ext包的默认注册表
+func New(options ...Option) *Gateway {
+	return NewWith(ext.Default(), options...)
+}
+
+// NewWith 使用指定的扩展组件注册表创建网关；通过 ext.Default().Clone() 创建的注册表包含内置组件，
+// 可在同一进程内运行多个相互隔离的网关。
+func NewWith(extensions *ext.Registry, options ...Option) *Gateway {
+	g := &Gateway{
+		engine:     server.NewHttpServeEngineOf(extensions, server.DefaultServerResponseWriter, server.DefaultServerErrorsWriter),
+		extensions: extensions,
+		config:     flux.NewConfiguration(viper.New()),
+		registry:   registry.NewMemoryEndpointRegistry(),
+		buildInfo:  flux.BuildInfo{Version: "embedded"},
+	}
+	for _, opt := range options {
+		opt(g)
+	}
+	// 未指定WebServer实现时，使用echo实现
+	if nil == g.extensions.LoadWebServerFactory() {
+		g.extensions.StoreWebServerFactory(webecho.NewAdaptWebServer)
+	}
+	g.engine.SetConfiguration(g.config)
+	return g
+}
+
+// AddEndpoint 添加Endpoint；仅支持默认的内存注册中心
+func (g *Gateway) AddEndpoint(endpoints ...flux.Endpoint) *Gateway {
+	if r, ok := g.registry.(*registry.MemoryEndpointRegistry); ok {
+		for _, ep := range endpoints {
+			r.PublishEndpoint(flux.EventTypeAdded, ep)
+		}
+	} else if nil == g.err {
+		g.err = ErrEndpointRegistryNotMemory
+	}
+	return g
+}
+
+// AddBackendService 添加后端服务；仅支持默认的内存注册中心
+func (g *Gateway) AddBackendService(services ...flux.BackendService) *Gateway {
+	if r, ok := g.registry.(*registry.MemoryEndpointRegistry); ok {
+		for _, svc := range services {
+			r.PublishBackendService(flux.EventTypeAdded, svc)
+		}
+	} else if nil == g.err {
+		g.err = ErrEndpointRegistryNotMemory
+	}
+	return g
+}
+
+// Engine 返回网关引擎
+func (g *Gateway) Engine() *server.HttpServeEngine {
+	return g.engine
+}
+
+// Serve 初始化网关，并在指定Listener上启动服务；阻塞直到服务停止
+func (g *Gateway) Serve(listener net.Listener) error {
+	if nil != g.err {
+		return g.err
+	}
+	g.extensions.StoreEndpointRegistryFactory(EndpointRegistryIdEmbedded, func() flux.EndpointRegistry {
+		return g.registry
+	})
+	g.config.Set(flux.KeyConfigRootEndpointRegistry+"."+flux.KeyConfigEndpointRegistryId, EndpointRegistryIdEmbedded)
+	if err := g.engine.Prepare(g.prepareHooks...); nil != err {
+		return err
+	}
+	if err := g.engine.Initial(); nil != err {
+		return err
+	}
+	return g.engine.ServeListener(g.buildInfo, listener)
+}
+
+// Shutdown 停止网关服务
+func (g *Gateway) Shutdown(ctx context.Context) error {
+	return g.engine.Shutdown(ctx)
+}
@@ -0,0 +1,35 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/spf13/viper"
+	assert2 "github.com/stretchr/testify/assert"
+)
+
+func TestWithConfig_Private(t *testing.T) {
+	assert := assert2.New(t)
+	key := "HttpWebServer.gateway-test-key"
+	g1 := NewWith(ext.Default().Clone(), WithConfig(key, "g1"))
+	g2 := NewWith(ext.Default().Clone(), WithConfig(key, "g2"))
+	assert.Equal("g1", g1.config.GetString(key))
+	assert.Equal("g2", g2.config.GetString(key))
+	assert.False(viper.IsSet(key), "must not leak into global viper")
+}
+
+func TestNew_DefaultWebServerFactory(t *testing.T) {
+	assert := assert2.New(t)
+	g := NewWith(ext.Default().Clone())
+	assert.NotNil(g.extensions.LoadWebServerFactory())
+	assert.Same(g.config, g.engine.Configuration())
+}
+
+func TestWithConfiguration(t *testing.T) {
+	assert := assert2.New(t)
+	config := flux.NewConfiguration(viper.New())
+	g := NewWith(ext.Default().Clone(), WithConfiguration(config), WithConfig("a.b", 1))
+	assert.Same(config, g.engine.Configuration())
+	assert.Equal(1, config.GetInt("a.b"))
+}
@@ -8,16 +8,25 @@ require (
 	github.com/apache/dubbo-go-hessian2 v1.7.0
 	github.com/bwmarrin/snowflake v0.3.0
 	github.com/dubbogo/go-zookeeper v1.0.1
+	github.com/dubbogo/gost v1.9.1
+	github.com/go-asn1-ber/asn1-ber v1.5.1
+	github.com/go-redis/redis v6.15.9+incompatible
+	github.com/golang/protobuf v1.3.2
+	github.com/jhump/protoreflect v1.6.0
 	github.com/jinzhu/copier v0.0.0-20190924061706-b57f9002281a // indirect
 	github.com/json-iterator/go v1.1.9
 	github.com/labstack/echo/v4 v4.1.16
+	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/pkg/errors v0.9.1
 	github.com/prometheus/client_golang v1.1.0
+	github.com/segmentio/kafka-go v0.3.5
 	github.com/spf13/cast v1.3.0
 	github.com/spf13/viper v1.7.1
+	github.com/streadway/amqp v1.0.0
 	github.com/stretchr/testify v1.5.1
 	go.uber.org/zap v1.15.0
-	golang.org/x/net v0.0.0-20200602114024-627f9648deb9 // indirect
+	golang.org/x/crypto v0.0.0-20200221231518-2aa609cf4a9d
+	golang.org/x/net v0.0.0-20200602114024-627f9648deb9
 	golang.org/x/sys v0.0.0-20200602225109-6fdc65e7d980 // indirect
 	gopkg.in/yaml.v2 v2.3.0 // indirect
 )
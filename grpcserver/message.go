@@ -0,0 +1,43 @@
+package grpcserver
+
+import (
+	"github.com/golang/protobuf/proto"
+)
+
+// InvokeRequest 调用Endpoint的请求消息：
+//
+//	message InvokeRequest {
+//	  string endpoint_id = 1;           // Endpoint路由Key，格式为 METHOD#pattern
+//	  string version = 2;               // Endpoint版本号
+//	  string path = 3;                  // 请求路径（可包含Query）；为空时使用Endpoint的pattern
+//	  bytes payload = 4;                // 请求Body
+//	  map<string, string> metadata = 5; // 请求Header
+//	}
+type InvokeRequest struct {
+	EndpointId string            `protobuf:"bytes,1,opt,name=endpoint_id,json=endpointId,proto3"`
+	Version    string            `protobuf:"bytes,2,opt,name=version,proto3"`
+	Path       string            `protobuf:"bytes,3,opt,name=path,proto3"`
+	Payload    []byte            `protobuf:"bytes,4,opt,name=payload,proto3"`
+	Metadata   map[string]string `protobuf:"bytes,5,rep,name=metadata,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *InvokeRequest) Reset()         { *m = InvokeRequest{} }
+func (m *InvokeRequest) String() string { return proto.CompactTextString(m) }
+func (*InvokeRequest) ProtoMessage()    {}
+
+// InvokeResponse 调用Endpoint的响应消息：
+//
+//	message InvokeResponse {
+//	  int32 status_code = 1;           // Http响应状态码
+//	  bytes payload = 2;               // 响应Body
+//	  map<string, string> headers = 3; // 响应Header
+//	}
+type InvokeResponse struct {
+	StatusCode int32             `protobuf:"varint,1,opt,name=status_code,json=statusCode,proto3"`
+	Payload    []byte            `protobuf:"bytes,2,opt,name=payload,proto3"`
+	Headers    map[string]string `protobuf:"bytes,3,rep,name=headers,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *InvokeResponse) Reset()         { *m = InvokeResponse{} }
+func (m *InvokeResponse) String() string { return proto.CompactTextString(m) }
+func (*InvokeResponse) ProtoMessage()    {}
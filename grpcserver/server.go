@@ -0,0 +1,249 @@
+package grpcserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/bytepowered/flux/logger"
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// InvokeFullMethod 通用调用服务的gRPC方法
+	InvokeFullMethod = "/flux.gateway.Gateway/Invoke"
+)
+
+const (
+	contentTypeGrpc      = "application/grpc"
+	headerGrpcStatus     = "Grpc-Status"
+	headerGrpcMessage    = "Grpc-Message"
+	maxInvokeMessageSize = 4 << 20
+)
+
+// gRPC status codes
+const (
+	codeOK               = 0
+	codeInvalidArgument  = 3
+	codeNotFound         = 5
+	codeResourcesExhaust = 8
+	codeUnimplemented    = 12
+	codeInternal         = 13
+)
+
+type (
+	// EndpointLookupFunc 根据EndpointId查找Endpoint的Http方法和路由Pattern
+	EndpointLookupFunc func(endpointId string) (method, pattern string, ok bool)
+)
+
+// GrpcFrontServer 面向内部调用方的gRPC服务，以通用的 Invoke(EndpointId, payload) 方法暴露已注册的Endpoint；
+// 请求被转换为Http请求交由网关的Http处理链执行，复用相同的拦截器、Filter和后端。
+// 服务基于HTTP/2明文协议（h2c），不支持消息压缩。
+type GrpcFrontServer struct {
+	handler       http.Handler
+	lookup        EndpointLookupFunc
+	versionHeader string
+	server        *http.Server
+}
+
+func NewGrpcFrontServer(handler http.Handler, lookup EndpointLookupFunc, versionHeader string) *GrpcFrontServer {
+	s := &GrpcFrontServer{
+		handler:       handler,
+		lookup:        lookup,
+		versionHeader: versionHeader,
+	}
+	s.server = &http.Server{Handler: h2c.NewHandler(s, &http2.Server{})}
+	return s
+}
+
+// Serve 在指定Listener上启动服务
+func (s *GrpcFrontServer) Serve(listener net.Listener) error {
+	return s.server.Serve(listener)
+}
+
+// Shutdown 停止服务
+func (s *GrpcFrontServer) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+func (s *GrpcFrontServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeGrpc) {
+		http.Error(w, "grpc: unsupported request", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", contentTypeGrpc)
+	if r.URL.Path != InvokeFullMethod {
+		writeError(w, codeUnimplemented, "unknown method: "+r.URL.Path)
+		return
+	}
+	data, code, err := readMessage(r.Body)
+	if nil != err {
+		writeError(w, code, err.Error())
+		return
+	}
+	req := new(InvokeRequest)
+	if err := proto.Unmarshal(data, req); nil != err {
+		writeError(w, codeInvalidArgument, err.Error())
+		return
+	}
+	method, pattern, ok := s.lookup(req.EndpointId)
+	if !ok {
+		writeError(w, codeNotFound, "endpoint not found: "+req.EndpointId)
+		return
+	}
+	resp, err := s.invoke(r, req, method, pattern)
+	if nil != err {
+		logger.Errorw("GrpcFrontServer invoke", "endpoint-id", req.EndpointId, "error", err)
+		writeError(w, codeInternal, err.Error())
+		return
+	}
+	out, err := proto.Marshal(resp)
+	if nil != err {
+		logger.Errorw("GrpcFrontServer marshal response", "endpoint-id", req.EndpointId, "error", err)
+		writeError(w, codeInternal, err.Error())
+		return
+	}
+	w.Header().Set("Trailer", headerGrpcStatus)
+	w.WriteHeader(http.StatusOK)
+	if err := writeMessage(w, out); nil != err {
+		logger.Errorw("GrpcFrontServer write response", "endpoint-id", req.EndpointId, "error", err)
+		return
+	}
+	w.Header().Set(headerGrpcStatus, strconv.Itoa(codeOK))
+}
+
+// invoke 将gRPC调用转换为Http请求，交由网关Http处理链执行
+func (s *GrpcFrontServer) invoke(r *http.Request, req *InvokeRequest, method, pattern string) (*InvokeResponse, error) {
+	path := req.Path
+	if "" == path {
+		path = pattern
+	}
+	hreq, err := http.NewRequest(method, path, bytes.NewReader(req.Payload))
+	if nil != err {
+		return nil, fmt.Errorf("invalid path: %s, error: %w", path, err)
+	}
+	hreq = hreq.WithContext(r.Context())
+	hreq.RemoteAddr = r.RemoteAddr
+	hreq.Host = r.Host
+	// gRPC Metadata -> Http Header
+	for name, values := range r.Header {
+		if isReservedHeader(name) {
+			continue
+		}
+		for _, v := range values {
+			hreq.Header.Add(name, v)
+		}
+	}
+	for name, value := range req.Metadata {
+		hreq.Header.Set(name, value)
+	}
+	if "" != req.Version && "" != s.versionHeader {
+		hreq.Header.Set(s.versionHeader, req.Version)
+	}
+	recorder := newInvokeRecorder()
+	s.handler.ServeHTTP(recorder, hreq)
+	resp := &InvokeResponse{
+		StatusCode: int32(recorder.status),
+		Payload:    recorder.body.Bytes(),
+		Headers:    make(map[string]string, len(recorder.header)),
+	}
+	for name := range recorder.header {
+		resp.Headers[name] = recorder.header.Get(name)
+	}
+	return resp, nil
+}
+
+// writeError 以Trailers-Only格式返回gRPC错误状态
+func writeError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set(headerGrpcStatus, strconv.Itoa(code))
+	w.Header().Set(headerGrpcMessage, encodeGrpcMessage(message))
+	w.WriteHeader(http.StatusOK)
+}
+
+// encodeGrpcMessage 按gRPC协议对状态消息进行百分号编码
+func encodeGrpcMessage(msg string) string {
+	var sb strings.Builder
+	for i := 0; i < len(msg); i++ {
+		if c := msg[i]; c >= ' ' && c <= '~' && c != '%' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+// isReservedHeader gRPC协议保留的Header，不传递给Http处理链
+func isReservedHeader(name string) bool {
+	switch strings.ToLower(name) {
+	case "content-type", "te", "user-agent", "grpc-timeout", "grpc-encoding", "grpc-accept-encoding":
+		return true
+	default:
+		return strings.HasPrefix(strings.ToLower(name), "grpc-")
+	}
+}
+
+// readMessage 读取一个gRPC Length-Prefixed消息
+func readMessage(r io.Reader) ([]byte, int, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); nil != err {
+		return nil, codeInvalidArgument, fmt.Errorf("read message header: %w", err)
+	}
+	if header[0] != 0 {
+		return nil, codeUnimplemented, fmt.Errorf("compressed message not supported")
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxInvokeMessageSize {
+		return nil, codeResourcesExhaust, fmt.Errorf("message too large: %d", size)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r, int64(size)))
+	if nil != err {
+		return nil, codeInvalidArgument, fmt.Errorf("read message: %w", err)
+	}
+	if uint32(len(data)) != size {
+		return nil, codeInvalidArgument, fmt.Errorf("truncated message")
+	}
+	return data, codeOK, nil
+}
+
+// writeMessage 写入一个gRPC Length-Prefixed消息
+func writeMessage(w io.Writer, data []byte) error {
+	var header [5]byte
+	binary.BigEndian.PutUint32(header[1:], uint32(len(data)))
+	if _, err := w.Write(header[:]); nil != err {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// invokeRecorder 记录Http处理链的响应
+type invokeRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newInvokeRecorder() *invokeRecorder {
+	return &invokeRecorder{header: http.Header{}, status: http.StatusOK}
+}
+
+func (r *invokeRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *invokeRecorder) Write(data []byte) (int, error) {
+	return r.body.Write(data)
+}
+
+func (r *invokeRecorder) WriteHeader(status int) {
+	r.status = status
+}
@@ -0,0 +1,141 @@
+package grpcserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"github.com/golang/protobuf/proto"
+	assert2 "github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInvokeMessage_Marshal(t *testing.T) {
+	assert := assert2.New(t)
+	// 与 InvokeRequest 的Proto定义一致的编码：endpoint_id = 1，metadata = 5
+	data, err := proto.Marshal(&InvokeRequest{EndpointId: "a", Metadata: map[string]string{"k": "v"}})
+	assert.NoError(err)
+	assert.Equal([]byte{0x0a, 0x01, 'a', 0x2a, 0x06, 0x0a, 0x01, 'k', 0x12, 0x01, 'v'}, data)
+	req := &InvokeRequest{
+		EndpointId: "POST#/api/users/{id}",
+		Version:    "v1",
+		Path:       "/api/users/1?verbose=true",
+		Payload:    []byte(`{"name":"flux"}`),
+		Metadata:   map[string]string{"X-Tenant-Id": "t-001", "empty": ""},
+	}
+	data, err = proto.Marshal(req)
+	assert.NoError(err)
+	decodedReq := new(InvokeRequest)
+	assert.NoError(proto.Unmarshal(data, decodedReq))
+	assert.Equal(req, decodedReq)
+	resp := &InvokeResponse{StatusCode: 201, Payload: []byte("ok"), Headers: map[string]string{"X-Cost": "1"}}
+	data, err = proto.Marshal(resp)
+	assert.NoError(err)
+	decodedResp := new(InvokeResponse)
+	assert.NoError(proto.Unmarshal(data, decodedResp))
+	assert.Equal(resp, decodedResp)
+	// 跳过未知字段；截断的消息返回错误
+	assert.NoError(proto.Unmarshal(append([]byte{9<<3 | 0, 0x01}, data...), new(InvokeResponse)))
+	assert.Error(proto.Unmarshal([]byte{1<<3 | 2, 10, 'a'}, new(InvokeRequest)))
+}
+
+func TestGrpcFrontServer_ServeHTTP(t *testing.T) {
+	assert := assert2.New(t)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("X-Echo-Method", r.Method)
+		w.Header().Set("X-Echo-Path", r.URL.RequestURI())
+		w.Header().Set("X-Echo-Version", r.Header.Get("X-Version"))
+		w.Header().Set("X-Echo-Tenant", r.Header.Get("X-Tenant-Id"))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	})
+	lookup := func(id string) (string, string, bool) {
+		if "POST#/api/users" == id {
+			return http.MethodPost, "/api/users", true
+		}
+		return "", "", false
+	}
+	server := NewGrpcFrontServer(handler, lookup, "X-Version")
+	invoke := func(path string, req *InvokeRequest) *httptest.ResponseRecorder {
+		data, err := proto.Marshal(req)
+		assert.NoError(err)
+		body := new(bytes.Buffer)
+		assert.NoError(writeMessage(body, data))
+		hreq := httptest.NewRequest(http.MethodPost, path, body)
+		hreq.Header.Set("Content-Type", contentTypeGrpc)
+		hreq.Header.Set("X-Tenant-Id", "t-001")
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, hreq)
+		return rec
+	}
+	// 正常调用
+	rec := invoke(InvokeFullMethod, &InvokeRequest{EndpointId: "POST#/api/users", Version: "v2", Payload: []byte("hello")})
+	assert.Equal("0", rec.Header().Get(headerGrpcStatus))
+	data, code, err := readMessage(rec.Body)
+	assert.NoError(err)
+	assert.Equal(codeOK, code)
+	resp := new(InvokeResponse)
+	assert.NoError(proto.Unmarshal(data, resp))
+	assert.Equal(int32(http.StatusCreated), resp.StatusCode)
+	assert.Equal("hello", string(resp.Payload))
+	assert.Equal(http.MethodPost, resp.Headers["X-Echo-Method"])
+	assert.Equal("/api/users", resp.Headers["X-Echo-Path"])
+	assert.Equal("v2", resp.Headers["X-Echo-Version"])
+	assert.Equal("t-001", resp.Headers["X-Echo-Tenant"])
+	// Endpoint不存在
+	rec = invoke(InvokeFullMethod, &InvokeRequest{EndpointId: "GET#/not-found"})
+	assert.Equal("5", rec.Header().Get(headerGrpcStatus))
+	// 未知方法
+	rec = invoke("/flux.gateway.Gateway/Unknown", &InvokeRequest{EndpointId: "POST#/api/users"})
+	assert.Equal("12", rec.Header().Get(headerGrpcStatus))
+	assert.Equal("unknown method: /flux.gateway.Gateway/Unknown", rec.Header().Get(headerGrpcMessage))
+}
+
+func TestGrpcFrontServer_Serve(t *testing.T) {
+	assert := assert2.New(t)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("pong"))
+	})
+	lookup := func(id string) (string, string, bool) {
+		return http.MethodGet, "/ping", "GET#/ping" == id
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(err) {
+		return
+	}
+	server := NewGrpcFrontServer(handler, lookup, "")
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Shutdown(context.TODO())
+	// 使用h2c明文HTTP/2调用，状态码通过Trailer返回
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	data, err := proto.Marshal(&InvokeRequest{EndpointId: "GET#/ping"})
+	assert.NoError(err)
+	body := new(bytes.Buffer)
+	assert.NoError(writeMessage(body, data))
+	hresp, err := client.Post("http://"+listener.Addr().String()+InvokeFullMethod, contentTypeGrpc, body)
+	if !assert.NoError(err) {
+		return
+	}
+	defer hresp.Body.Close()
+	data, _, err = readMessage(hresp.Body)
+	assert.NoError(err)
+	_, _ = ioutil.ReadAll(hresp.Body)
+	assert.Equal("0", hresp.Trailer.Get(headerGrpcStatus))
+	resp := new(InvokeResponse)
+	assert.NoError(proto.Unmarshal(data, resp))
+	assert.Equal(int32(http.StatusOK), resp.StatusCode)
+	assert.Equal("pong", string(resp.Payload))
+}
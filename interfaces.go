@@ -22,6 +22,10 @@ type (
 	Shutdowner interface {
 		Shutdown(ctx context.Context) error // 当服务停止时，调用此函数
 	}
+	// Warmer 用于在服务启动后、就绪前预热缓存的Hook，通常与 Orderer 接口一起使用。
+	Warmer interface {
+		Warmup(ctx context.Context) error // 当服务启动完成、就绪之前，调用此函数
+	}
 	// Initializer 用于介入服务停止生命周期的Hook，通常与 Orderer 接口一起使用。
 	Initializer interface {
 		Init(configuration *Configuration) error // 当服务初始化时，调用此函数
@@ -2,11 +2,23 @@ package main
 
 import (
 	"github.com/bytepowered/flux"
+	_ "github.com/bytepowered/flux/backend/amqp"
+	_ "github.com/bytepowered/flux/backend/compose"
 	_ "github.com/bytepowered/flux/backend/dubbo"
 	_ "github.com/bytepowered/flux/backend/echo"
+	_ "github.com/bytepowered/flux/backend/graphql"
+	_ "github.com/bytepowered/flux/backend/grpc"
 	_ "github.com/bytepowered/flux/backend/http"
+	_ "github.com/bytepowered/flux/backend/jsonrpc"
+	_ "github.com/bytepowered/flux/backend/kafka"
+	_ "github.com/bytepowered/flux/backend/mock"
+	_ "github.com/bytepowered/flux/backend/nats"
+	_ "github.com/bytepowered/flux/backend/soap"
+	_ "github.com/bytepowered/flux/backend/tcp"
+	_ "github.com/bytepowered/flux/backend/websocket"
 	"github.com/bytepowered/flux/server"
 	_ "github.com/bytepowered/flux/webecho"
+	"os"
 )
 
 var (
@@ -19,5 +31,27 @@ var (
 // 或者导入 _ "github.com/bytepowered/flux/webecho" 自动注册WebServer；
 func main() {
 	server.InitDefaultLogger()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate":
+			// flux validate [-endpoints file]：校验配置后退出
+			os.Exit(server.RunValidate(os.Args[2:]))
+		case "migrate":
+			// flux migrate -source zookeeper -target file:endpoints.json [-dry-run]：迁移Endpoint元数据后退出
+			os.Exit(server.RunMetadataMigrate(os.Args[2:]))
+		case "generate-dubbo":
+			// flux generate-dubbo -target file:drafts.json：根据Dubbo注册中心生成草稿Endpoint后退出
+			os.Exit(server.RunDubboGenerate(os.Args[2:]))
+		case "import-routes":
+			// flux import-routes -format scg|zuul -file routes.yml -target file:endpoints.json：导入Java网关路由后退出
+			os.Exit(server.RunRouteImport(os.Args[2:]))
+		case "export-edge":
+			// flux export-edge -format nginx|envoy -source file:endpoints.json：导出边缘代理路由配置后退出
+			os.Exit(server.RunEdgeExport(os.Args[2:]))
+		case "generate-sdk":
+			// flux generate-sdk -lang go|typescript -source file:endpoints.json [-package name]：生成客户端SDK后退出
+			os.Exit(server.RunSDKGenerate(os.Args[2:]))
+		}
+	}
 	server.Run(flux.BuildInfo{CommitId: GitCommit, Version: Version, Date: BuildDate})
 }
@@ -9,6 +9,16 @@ const (
 	JavaLangBooleanClassName = "java.lang.Boolean"
 	JavaUtilMapClassName     = "java.util.Map"
 	JavaUtilListClassName    = "java.util.List"
+	// 日期时间类型；Java端为 java.util.Date 和 java.time.LocalDateTime
+	JavaUtilDateClassName          = "java.util.Date"
+	JavaTimeLocalDateTimeClassName = "java.time.LocalDateTime"
+	// 高精度数值类型；按字符串解析，不经过float64转换
+	JavaMathBigDecimalClassName = "java.math.BigDecimal"
+	JavaMathBigIntegerClassName = "java.math.BigInteger"
+	// 字节数组类型；Java端为 byte[]，类型名为 [B
+	JavaByteArrayClassName = "[B"
+	// 枚举类型；参数的泛型类型声明允许的枚举常量
+	JavaLangEnumClassName = "java.lang.Enum"
 )
 
 const (
@@ -47,6 +57,14 @@ func WrapStrValuesMapMTValue(value map[string][]string) MTValue {
 	return MTValue{Value: value, MediaType: ValueMediaTypeGoStringValuesMap}
 }
 
+// POJOField 用户POJO类型的字段定义：字段名、字段类型及其泛型类型；
+// 字段类型可以是已注册解析函数的类型，或者另一个已注册的POJO类型
+type POJOField struct {
+	Name    string   `json:"name" yaml:"name"`
+	Class   string   `json:"class" yaml:"class"`
+	Generic []string `json:"generic" yaml:"generic"`
+}
+
 // MTValueResolver 将未定类型的值，按指定类型以及泛型类型转换为实际类型
 // @param mtValue Http请求指示媒体类型的值
 // @param toClass 目标值类型
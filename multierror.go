@@ -0,0 +1,120 @@
+package flux
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// FieldError 关联字段名和错误码的单个错误；字段名可以是参数名、编排步骤名或组件名
+type FieldError struct {
+	Field     string // 错误关联的字段名
+	ErrorCode string // 错误码；为空时使用内部错误的错误码
+	Err       error  // 内部错误
+}
+
+func NewFieldError(field, code string, err error) *FieldError {
+	return &FieldError{Field: field, ErrorCode: code, Err: err}
+}
+
+func (e *FieldError) Error() string {
+	text := e.Field
+	if "" != e.ErrorCode {
+		text += "(" + e.ErrorCode + ")"
+	}
+	if nil != e.Err {
+		text += ": " + e.Err.Error()
+	}
+	return text
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorItem 错误的输出结构
+type ErrorItem struct {
+	Field   string `json:"field,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+// MultiError 聚合多个错误，保留每个错误的字段名和错误码；JSON编码为错误项列表
+type MultiError struct {
+	Errors []error
+}
+
+// Append 添加错误；忽略nil错误，嵌套的MultiError被展开
+func (m *MultiError) Append(errs ...error) {
+	for _, err := range errs {
+		if nil == err {
+			continue
+		}
+		if multi, ok := err.(*MultiError); ok {
+			m.Errors = append(m.Errors, multi.Errors...)
+		} else {
+			m.Errors = append(m.Errors, err)
+		}
+	}
+}
+
+// ErrorOrNil 没有错误时返回nil，只有一个错误时返回该错误，否则返回MultiError自身
+func (m *MultiError) ErrorOrNil() error {
+	switch len(m.Errors) {
+	case 0:
+		return nil
+	case 1:
+		return m.Errors[0]
+	default:
+		return m
+	}
+}
+
+func (m *MultiError) Error() string {
+	texts := make([]string, len(m.Errors))
+	for i, err := range m.Errors {
+		texts[i] = err.Error()
+	}
+	return "MultiError: [" + strings.Join(texts, "; ") + "]"
+}
+
+// Unwrap 返回第一个错误，用于 errors.Is / errors.As 判断
+func (m *MultiError) Unwrap() error {
+	if 0 == len(m.Errors) {
+		return nil
+	}
+	return m.Errors[0]
+}
+
+// Items 返回每个错误的字段名、错误码和消息
+func (m *MultiError) Items() []ErrorItem {
+	items := make([]ErrorItem, len(m.Errors))
+	for i, err := range m.Errors {
+		items[i] = ErrorItemOf(err)
+	}
+	return items
+}
+
+func (m *MultiError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Items())
+}
+
+// ErrorItemOf 返回错误的输出结构：ServeError使用其错误码和消息，FieldError使用其字段名和错误码
+func ErrorItemOf(err error) ErrorItem {
+	item := ErrorItem{Message: err.Error()}
+	var field *FieldError
+	if errors.As(err, &field) {
+		item.Field, item.Code = field.Field, field.ErrorCode
+		if nil != field.Err {
+			item.Message = field.Err.Error()
+		}
+	}
+	var serr *ServeError
+	if errors.As(err, &serr) {
+		if "" == item.Code {
+			item.Code = serr.GetErrorCode()
+		}
+		item.Message = serr.Message
+	}
+	return item
+}
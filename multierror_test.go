@@ -0,0 +1,30 @@
+package flux
+
+import (
+	"encoding/json"
+	"errors"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMultiError(t *testing.T) {
+	assert := assert2.New(t)
+	errs := new(MultiError)
+	assert.Nil(errs.ErrorOrNil())
+	first := errors.New("first")
+	errs.Append(nil, first)
+	assert.Equal(first, errs.ErrorOrNil())
+	nested := &MultiError{Errors: []error{
+		NewFieldError("userId", "BACKEND:RESOLVE", errors.New("invalid")),
+		NewFieldError("step1", "", &ServeError{ErrorCode: "GATEWAY:BACKEND", Message: "BACKEND:INVOKE"}),
+	}}
+	errs.Append(nested)
+	assert.Equal(3, len(errs.Errors))
+	assert.True(errors.Is(errs.ErrorOrNil(), first))
+	assert.Equal("MultiError: [first; userId(BACKEND:RESOLVE): invalid; step1: "+
+		"ServeError: StatusCode=0, ErrorCode=GATEWAY:BACKEND, Message=BACKEND:INVOKE]", errs.Error())
+	data, err := json.Marshal(errs)
+	assert.NoError(err)
+	assert.Equal(`[{"message":"first"},{"field":"userId","code":"BACKEND:RESOLVE","message":"invalid"},`+
+		`{"field":"step1","code":"GATEWAY:BACKEND","message":"BACKEND:INVOKE"}]`, string(data))
+}
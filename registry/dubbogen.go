@@ -0,0 +1,268 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/remoting/zk"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// Dubbo在ZK注册的根节点
+	dubboZkRootPath = "/dubbo"
+)
+
+const (
+	// Endpoint扩展属性：自动生成的草稿Endpoint，需要人工审核后发布
+	EndpointExtKeyDraft = "draft"
+)
+
+// DubboProvider 从Dubbo注册中心读取的服务提供者元数据
+type DubboProvider struct {
+	Application string
+	Interface   string
+	Group       string
+	Version     string
+	Methods     []DubboMethod
+}
+
+// DubboMethod Dubbo服务方法；ParameterTypes来自元数据中心，未读取到时为空
+type DubboMethod struct {
+	Name           string   `json:"name"`
+	ParameterTypes []string `json:"parameterTypes"`
+}
+
+// ParseDubboProviderURL 解析Dubbo注册中心的Provider URL，例如：
+// dubbo://10.0.0.1:20880/com.foo.UserService?application=user&methods=get,list&version=1.0.0
+func ParseDubboProviderURL(raw string) (DubboProvider, error) {
+	u, err := url.Parse(raw)
+	if nil != err {
+		return DubboProvider{}, fmt.Errorf("parse dubbo provider url: %w", err)
+	}
+	query := u.Query()
+	provider := DubboProvider{
+		Application: query.Get("application"),
+		Interface:   query.Get("interface"),
+		Group:       query.Get("group"),
+		Version:     query.Get("version"),
+	}
+	if "" == provider.Interface {
+		provider.Interface = strings.TrimPrefix(u.Path, "/")
+	}
+	if "" == provider.Interface {
+		return DubboProvider{}, fmt.Errorf("dubbo provider url without interface: %s", raw)
+	}
+	for _, name := range strings.Split(query.Get("methods"), ",") {
+		if name = strings.TrimSpace(name); "" != name {
+			provider.Methods = append(provider.Methods, DubboMethod{Name: name})
+		}
+	}
+	sort.Slice(provider.Methods, func(i, j int) bool {
+		return provider.Methods[i].Name < provider.Methods[j].Name
+	})
+	return provider, nil
+}
+
+// ApplyDubboServiceDefinition 使用Dubbo元数据中心的服务定义（FullServiceDefinition JSON）补充方法参数类型
+func ApplyDubboServiceDefinition(provider *DubboProvider, data []byte) error {
+	definition := struct {
+		Methods []DubboMethod `json:"methods"`
+	}{}
+	if err := json.Unmarshal(data, &definition); nil != err {
+		return fmt.Errorf("decode dubbo service definition: %w", err)
+	}
+	types := make(map[string][]string, len(definition.Methods))
+	for _, method := range definition.Methods {
+		types[method.Name] = method.ParameterTypes
+	}
+	for i, method := range provider.Methods {
+		provider.Methods[i].ParameterTypes = types[method.Name]
+	}
+	return nil
+}
+
+////
+
+// DubboZookeeperProviderSource 从ZK注册中心读取Dubbo服务提供者和元数据。Retriever由调用方完成Init和Startup。
+type DubboZookeeperProviderSource struct {
+	retriever *zk.ZookeeperRetriever
+	rootPath  string
+}
+
+func NewDubboZookeeperProviderSource(retriever *zk.ZookeeperRetriever, rootPath string) *DubboZookeeperProviderSource {
+	if "" == rootPath {
+		rootPath = dubboZkRootPath
+	}
+	return &DubboZookeeperProviderSource{retriever: retriever, rootPath: rootPath}
+}
+
+// LoadProviders 读取指定接口的服务提供者；interfaces为空时读取全部接口。
+// 同一接口的相同Group和Version只保留一个Provider。
+func (s *DubboZookeeperProviderSource) LoadProviders(interfaces ...string) ([]DubboProvider, error) {
+	if len(interfaces) == 0 {
+		children, err := s.retriever.Children(s.rootPath)
+		if nil != err {
+			return nil, fmt.Errorf("list dubbo interfaces: %w", err)
+		}
+		for _, child := range children {
+			if "metadata" != child && "config" != child {
+				interfaces = append(interfaces, child)
+			}
+		}
+		sort.Strings(interfaces)
+	}
+	out := make([]DubboProvider, 0, len(interfaces))
+	for _, iface := range interfaces {
+		children, err := s.retriever.Children(path.Join(s.rootPath, iface, "providers"))
+		if nil != err {
+			return nil, fmt.Errorf("list dubbo providers, interface: %s: %w", iface, err)
+		}
+		seen := make(map[string]bool, 2)
+		for _, child := range children {
+			raw, err := url.QueryUnescape(child)
+			if nil != err {
+				continue
+			}
+			provider, err := ParseDubboProviderURL(raw)
+			if nil != err || seen[provider.Group+":"+provider.Version] {
+				continue
+			}
+			seen[provider.Group+":"+provider.Version] = true
+			if data, err := s.retriever.GetData(s.metadataPath(provider)); nil == err && len(data) > 0 {
+				if err := ApplyDubboServiceDefinition(&provider, data); nil != err {
+					return nil, err
+				}
+			}
+			out = append(out, provider)
+		}
+	}
+	return out, nil
+}
+
+// metadataPath 返回Dubbo元数据中心的服务定义路径：/dubbo/metadata/interface[/version][/group]/provider/application
+func (s *DubboZookeeperProviderSource) metadataPath(provider DubboProvider) string {
+	parts := []string{s.rootPath, "metadata", provider.Interface}
+	for _, part := range []string{provider.Version, provider.Group} {
+		if "" != part {
+			parts = append(parts, part)
+		}
+	}
+	return path.Join(append(parts, "provider", provider.Application)...)
+}
+
+////
+
+// DubboEndpointGenerator 根据Dubbo服务提供者元数据生成草稿Endpoint：
+// 路径为 PathPrefix/接口简单名/方法名（kebab-case）；get/find/query/list 开头且参数均为简单类型的方法映射为GET，其它映射为POST。
+type DubboEndpointGenerator struct {
+	PathPrefix string
+}
+
+func (g DubboEndpointGenerator) Generate(providers ...DubboProvider) []flux.Endpoint {
+	out := make([]flux.Endpoint, 0, 16)
+	for _, provider := range providers {
+		for _, method := range provider.Methods {
+			out = append(out, g.generate(provider, method))
+		}
+	}
+	return out
+}
+
+func (g DubboEndpointGenerator) generate(provider DubboProvider, method DubboMethod) flux.Endpoint {
+	httpMethod := "POST"
+	if isDubboQueryMethod(method) {
+		httpMethod = "GET"
+	}
+	args := make([]flux.Argument, 0, len(method.ParameterTypes))
+	for i, class := range method.ParameterTypes {
+		arg := flux.Argument{
+			Name:     fmt.Sprintf("arg%d", i),
+			Type:     flux.ArgumentTypePrimitive,
+			Class:    class,
+			HttpName: fmt.Sprintf("arg%d", i),
+		}
+		switch {
+		case !isDubboSimpleType(class):
+			arg.Type, arg.HttpName, arg.HttpScope = flux.ArgumentTypeComplex, "", flux.ScopeBody
+		case "GET" == httpMethod:
+			arg.HttpScope = flux.ScopeQuery
+		default:
+			arg.HttpScope = flux.ScopeAuto
+		}
+		args = append(args, arg)
+	}
+	iface := provider.Interface[strings.LastIndex(provider.Interface, ".")+1:]
+	return flux.Endpoint{
+		Application: provider.Application,
+		Version:     provider.Version,
+		HttpPattern: strings.TrimSuffix(g.PathPrefix, "/") + "/" + toKebabCase(iface) + "/" + toKebabCase(method.Name),
+		HttpMethod:  httpMethod,
+		Service: flux.BackendService{
+			ServiceId: provider.Interface + ":" + method.Name,
+			Interface: provider.Interface,
+			Method:    method.Name,
+			Arguments: args,
+			EmbeddedAttributes: flux.EmbeddedAttributes{
+				Attributes: []flux.Attribute{
+					{Tag: flux.ServiceAttrTagRpcProto, Name: "RpcProto", Value: flux.ProtoDubbo},
+					{Tag: flux.ServiceAttrTagRpcGroup, Name: "RpcGroup", Value: provider.Group},
+					{Tag: flux.ServiceAttrTagRpcVersion, Name: "RpcVersion", Value: provider.Version},
+				},
+			},
+		},
+		EmbeddedAttributes: flux.EmbeddedAttributes{
+			Attributes: []flux.Attribute{
+				{Tag: flux.EndpointAttrTagAuthorize, Name: "Authorize", Value: true},
+			},
+		},
+		EmbeddedExtensions: flux.EmbeddedExtensions{
+			Extensions: map[string]interface{}{EndpointExtKeyDraft: true},
+		},
+	}
+}
+
+func isDubboQueryMethod(method DubboMethod) bool {
+	name := strings.ToLower(method.Name)
+	if !strings.HasPrefix(name, "get") && !strings.HasPrefix(name, "find") &&
+		!strings.HasPrefix(name, "query") && !strings.HasPrefix(name, "list") {
+		return false
+	}
+	for _, class := range method.ParameterTypes {
+		if !isDubboSimpleType(class) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDubboSimpleType(class string) bool {
+	switch class {
+	case "int", "long", "float", "double", "boolean", "short", "byte", "char",
+		flux.JavaLangStringClassName, flux.JavaLangIntegerClassName, flux.JavaLangLongClassName,
+		flux.JavaLangFloatClassName, flux.JavaLangDoubleClassName, flux.JavaLangBooleanClassName:
+		return true
+	default:
+		return false
+	}
+}
+
+// toKebabCase 将驼峰命名转换为kebab-case：UserService -> user-service，HTTPClient -> http-client
+func toKebabCase(name string) string {
+	runes := []rune(name)
+	var sb strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				sb.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
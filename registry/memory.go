@@ -0,0 +1,73 @@
+package registry
+
+import (
+	"github.com/bytepowered/flux"
+	"sync"
+)
+
+const (
+	memoryRegistryEventBuffer = 256
+)
+
+var _ flux.EndpointRegistry = new(MemoryEndpointRegistry)
+
+// MemoryEndpointRegistry 基于内存的Endpoint注册中心，通过编程方式添加Endpoint和后端服务；
+// 用于嵌入式使用和测试。Watch之前添加的数据，在Watch时以Added事件重放。
+type MemoryEndpointRegistry struct {
+	mu               sync.Mutex
+	endpoints        []flux.HttpEndpointEvent
+	services         []flux.BackendServiceEvent
+	endpointWatchers []chan flux.HttpEndpointEvent
+	serviceWatchers  []chan flux.BackendServiceEvent
+}
+
+func NewMemoryEndpointRegistry() *MemoryEndpointRegistry {
+	return &MemoryEndpointRegistry{
+		endpoints: make([]flux.HttpEndpointEvent, 0, 16),
+		services:  make([]flux.BackendServiceEvent, 0, 16),
+	}
+}
+
+// PublishEndpoint 发布Endpoint变更事件
+func (r *MemoryEndpointRegistry) PublishEndpoint(etype flux.EventType, endpoint flux.Endpoint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event := flux.HttpEndpointEvent{EventType: etype, Endpoint: endpoint}
+	r.endpoints = append(r.endpoints, event)
+	for _, watcher := range r.endpointWatchers {
+		watcher <- event
+	}
+}
+
+// PublishBackendService 发布后端服务变更事件
+func (r *MemoryEndpointRegistry) PublishBackendService(etype flux.EventType, service flux.BackendService) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event := flux.BackendServiceEvent{EventType: etype, Service: service}
+	r.services = append(r.services, event)
+	for _, watcher := range r.serviceWatchers {
+		watcher <- event
+	}
+}
+
+func (r *MemoryEndpointRegistry) WatchHttpEndpoints() (<-chan flux.HttpEndpointEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	watcher := make(chan flux.HttpEndpointEvent, len(r.endpoints)+memoryRegistryEventBuffer)
+	for _, event := range r.endpoints {
+		watcher <- event
+	}
+	r.endpointWatchers = append(r.endpointWatchers, watcher)
+	return watcher, nil
+}
+
+func (r *MemoryEndpointRegistry) WatchBackendServices() (<-chan flux.BackendServiceEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	watcher := make(chan flux.BackendServiceEvent, len(r.services)+memoryRegistryEventBuffer)
+	for _, event := range r.services {
+		watcher <- event
+	}
+	r.serviceWatchers = append(r.serviceWatchers, watcher)
+	return watcher, nil
+}
@@ -0,0 +1,244 @@
+package registry
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/remoting"
+	"github.com/bytepowered/flux/remoting/zk"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+)
+
+// EndpointStore 可读写的Endpoint元数据存储，用于在注册中心之间迁移元数据；
+// etcd、Nacos等注册中心通过实现此接口接入迁移工具。
+type EndpointStore interface {
+	// LoadEndpoints 读取全部Endpoint
+	LoadEndpoints() ([]flux.Endpoint, error)
+	// StoreEndpoints 写入（新增或覆盖）指定的Endpoint
+	StoreEndpoints(endpoints []flux.Endpoint) error
+}
+
+// EndpointTransformFunc 迁移时转换Endpoint；返回false时跳过该Endpoint
+type EndpointTransformFunc func(endpoint flux.Endpoint) (flux.Endpoint, bool)
+
+// MigrationDiff 迁移前后目标存储的差异，元素为Endpoint的Key（METHOD#pattern@version）
+type MigrationDiff struct {
+	Added     []string
+	Updated   []string
+	Unchanged []string
+	Skipped   []string
+}
+
+// EndpointMigrator 从源存储读取Endpoint，经过转换后写入目标存储；DryRun时仅计算差异，不写入目标存储。
+type EndpointMigrator struct {
+	Source     EndpointStore
+	Target     EndpointStore
+	Transforms []EndpointTransformFunc
+	DryRun     bool
+}
+
+func (m *EndpointMigrator) Migrate() (*MigrationDiff, error) {
+	sources, err := m.Source.LoadEndpoints()
+	if nil != err {
+		return nil, fmt.Errorf("load source endpoints: %w", err)
+	}
+	targets, err := m.Target.LoadEndpoints()
+	if nil != err {
+		return nil, fmt.Errorf("load target endpoints: %w", err)
+	}
+	existed := make(map[string][]byte, len(targets))
+	for _, endpoint := range targets {
+		data, err := ext.JSONMarshal(endpoint)
+		if nil != err {
+			return nil, err
+		}
+		existed[EndpointKey(endpoint)] = data
+	}
+	diff := new(MigrationDiff)
+	writes := make([]flux.Endpoint, 0, len(sources))
+	for _, endpoint := range sources {
+		key := EndpointKey(endpoint)
+		if endpoint, ok := m.transform(endpoint); !ok {
+			diff.Skipped = append(diff.Skipped, key)
+		} else {
+			data, err := ext.JSONMarshal(endpoint)
+			if nil != err {
+				return nil, err
+			}
+			key = EndpointKey(endpoint)
+			if old, ok := existed[key]; !ok {
+				diff.Added = append(diff.Added, key)
+			} else if !bytes.Equal(old, data) {
+				diff.Updated = append(diff.Updated, key)
+			} else {
+				diff.Unchanged = append(diff.Unchanged, key)
+				continue
+			}
+			existed[key] = data
+			writes = append(writes, endpoint)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Updated)
+	sort.Strings(diff.Unchanged)
+	sort.Strings(diff.Skipped)
+	if m.DryRun || len(writes) == 0 {
+		return diff, nil
+	}
+	if err := m.Target.StoreEndpoints(writes); nil != err {
+		return diff, fmt.Errorf("store target endpoints: %w", err)
+	}
+	return diff, nil
+}
+
+func (m *EndpointMigrator) transform(endpoint flux.Endpoint) (flux.Endpoint, bool) {
+	for _, tf := range m.Transforms {
+		var ok bool
+		if endpoint, ok = tf(endpoint); !ok {
+			return endpoint, false
+		}
+	}
+	return endpoint, true
+}
+
+// EndpointKey 返回标识Endpoint的Key：METHOD#pattern@version
+func EndpointKey(endpoint flux.Endpoint) string {
+	return endpoint.HttpMethod + "#" + endpoint.HttpPattern + "@" + endpoint.Version
+}
+
+////
+
+var _ EndpointStore = new(FileEndpointStore)
+
+// FileEndpointStore 基于JSON文件的Endpoint存储，文件格式与Endpoint快照一致
+type FileEndpointStore struct {
+	file string
+}
+
+func NewFileEndpointStore(file string) *FileEndpointStore {
+	return &FileEndpointStore{file: file}
+}
+
+func (s *FileEndpointStore) LoadEndpoints() ([]flux.Endpoint, error) {
+	data, err := ioutil.ReadFile(s.file)
+	if os.IsNotExist(err) {
+		// 目标文件不存在时视为空存储
+		return nil, nil
+	} else if nil != err {
+		return nil, err
+	}
+	return LoadEndpointSnapshot(data)
+}
+
+func (s *FileEndpointStore) StoreEndpoints(endpoints []flux.Endpoint) error {
+	existed, err := s.LoadEndpoints()
+	if nil != err {
+		return err
+	}
+	merged := mergeEndpoints(existed, endpoints)
+	data, err := ext.JSONMarshal(merged)
+	if nil != err {
+		return err
+	}
+	return ioutil.WriteFile(s.file, data, 0644)
+}
+
+func mergeEndpoints(existed, updates []flux.Endpoint) []flux.Endpoint {
+	indexes := make(map[string]int, len(existed))
+	out := make([]flux.Endpoint, 0, len(existed)+len(updates))
+	for _, endpoint := range append(existed, updates...) {
+		key := EndpointKey(endpoint)
+		if i, ok := indexes[key]; ok {
+			out[i] = endpoint
+		} else {
+			indexes[key] = len(out)
+			out = append(out, endpoint)
+		}
+	}
+	return out
+}
+
+////
+
+var _ EndpointStore = new(ZookeeperEndpointStore)
+
+// ZookeeperEndpointStore 基于ZK节点树的Endpoint存储；每个Endpoint为endpoint-path下的一个数据节点。
+// Retriever由调用方完成Init和Startup。
+type ZookeeperEndpointStore struct {
+	retriever    *zk.ZookeeperRetriever
+	endpointPath string
+}
+
+func NewZookeeperEndpointStore(retriever *zk.ZookeeperRetriever, endpointPath string) *ZookeeperEndpointStore {
+	if "" == endpointPath {
+		endpointPath = zkRegistryHttpEndpointPath
+	}
+	return &ZookeeperEndpointStore{retriever: retriever, endpointPath: endpointPath}
+}
+
+func (s *ZookeeperEndpointStore) LoadEndpoints() ([]flux.Endpoint, error) {
+	_, endpoints, err := s.load()
+	return endpoints, err
+}
+
+func (s *ZookeeperEndpointStore) StoreEndpoints(endpoints []flux.Endpoint) error {
+	nodes, _, err := s.load()
+	if nil != err {
+		return err
+	}
+	if exist, _ := s.retriever.Exists(s.endpointPath); !exist {
+		if err := s.retriever.Create(s.endpointPath); nil != err {
+			return fmt.Errorf("init metadata node: %w", err)
+		}
+	}
+	for _, endpoint := range endpoints {
+		key := EndpointKey(endpoint)
+		node, ok := nodes[key]
+		if !ok {
+			node = path.Join(s.endpointPath, url.PathEscape(key))
+		}
+		data, err := ext.JSONMarshal(endpoint)
+		if nil != err {
+			return err
+		}
+		if err := s.retriever.SetData(node, data); nil != err {
+			return fmt.Errorf("write endpoint node %s: %w", node, err)
+		}
+	}
+	return nil
+}
+
+// load 读取全部Endpoint，同时返回Endpoint的Key与节点路径的映射
+func (s *ZookeeperEndpointStore) load() (map[string]string, []flux.Endpoint, error) {
+	nodes := make(map[string]string, 16)
+	if exist, err := s.retriever.Exists(s.endpointPath); nil != err {
+		return nil, nil, err
+	} else if !exist {
+		return nodes, nil, nil
+	}
+	children, err := s.retriever.Children(s.endpointPath)
+	if nil != err {
+		return nil, nil, err
+	}
+	sort.Strings(children)
+	endpoints := make([]flux.Endpoint, 0, len(children))
+	for _, child := range children {
+		node := path.Join(s.endpointPath, child)
+		data, err := s.retriever.GetData(node)
+		if nil != err {
+			return nil, nil, fmt.Errorf("read endpoint node %s: %w", node, err)
+		}
+		event, ok := NewEndpointEvent(data, remoting.EventTypeNodeAdd)
+		if !ok {
+			continue
+		}
+		nodes[EndpointKey(event.Endpoint)] = node
+		endpoints = append(endpoints, event.Endpoint)
+	}
+	return nodes, endpoints, nil
+}
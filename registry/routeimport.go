@@ -0,0 +1,305 @@
+package registry
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	RouteFormatSpringCloudGateway = "scg"
+	RouteFormatZuul               = "zuul"
+)
+
+const (
+	// Endpoint扩展属性：导入时无法转换的网关Filter定义，需要人工处理
+	EndpointExtKeyImportedFilters = "imported-filters"
+	// Endpoint扩展属性：导入来源的路由ID
+	EndpointExtKeyImportedRoute = "imported-route"
+)
+
+// 路由未限定Method时，生成以下Method的Endpoint
+var importDefaultHttpMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodPatch}
+
+// RouteImportResult Java网关路由导入结果
+type RouteImportResult struct {
+	Endpoints []flux.Endpoint
+	Warnings  []string
+}
+
+func (r *RouteImportResult) warnf(route string, format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, route+": "+fmt.Sprintf(format, args...))
+}
+
+// ImportRoutes 按格式导入Java网关的路由YAML配置
+func ImportRoutes(format string, data []byte) (*RouteImportResult, error) {
+	switch format {
+	case RouteFormatSpringCloudGateway:
+		return ImportSpringCloudGatewayRoutes(data)
+	case RouteFormatZuul:
+		return ImportZuulRoutes(data)
+	default:
+		return nil, fmt.Errorf("unsupported route format: %s", format)
+	}
+}
+
+// ImportSpringCloudGatewayRoutes 将 spring.cloud.gateway.routes 转换为HTTP协议的Endpoint：
+// Path/Method断言转换为路由Pattern和Method；StripPrefix/PrefixPath/SetPath转换为后端路径；
+// 其它断言和Filter无法转换，记录在Endpoint扩展属性和警告信息中。
+func ImportSpringCloudGatewayRoutes(data []byte) (*RouteImportResult, error) {
+	v, err := readRouteYaml(data)
+	if nil != err {
+		return nil, err
+	}
+	result := new(RouteImportResult)
+	for i, item := range cast.ToSlice(v.Get("spring.cloud.gateway.routes")) {
+		route := cast.ToStringMap(item)
+		id := cast.ToString(route["id"])
+		if "" == id {
+			id = fmt.Sprintf("route-%d", i)
+		}
+		target, err := url.Parse(cast.ToString(route["uri"]))
+		if nil != err || "" == target.Host {
+			result.warnf(id, "invalid uri: %v, ignored", route["uri"])
+			continue
+		}
+		if "lb" == target.Scheme {
+			result.warnf(id, "load-balanced uri %s mapped to remote host directly", target)
+		}
+		var patterns, methods []string
+		for _, predicate := range cast.ToSlice(route["predicates"]) {
+			name, args := parseSpringShortcut(predicate)
+			switch strings.ToLower(name) {
+			case "path":
+				patterns = append(patterns, args...)
+			case "method":
+				for _, m := range args {
+					methods = append(methods, strings.ToUpper(m))
+				}
+			default:
+				result.warnf(id, "predicate not supported: %s", name)
+			}
+		}
+		if len(patterns) == 0 {
+			result.warnf(id, "route without Path predicate, ignored")
+			continue
+		}
+		rewrite := func(p string) string { return p }
+		unmapped := make([]string, 0)
+		for _, filter := range cast.ToSlice(route["filters"]) {
+			name, args := parseSpringShortcut(filter)
+			prev := rewrite
+			switch strings.ToLower(name) {
+			case "stripprefix":
+				n := cast.ToInt(firstOf(args))
+				rewrite = func(p string) string { return stripPathSegments(prev(p), n) }
+			case "prefixpath":
+				prefix := firstOf(args)
+				rewrite = func(p string) string { return path.Join(prefix, prev(p)) }
+			case "setpath":
+				setPath := firstOf(args)
+				rewrite = func(string) string { return setPath }
+			default:
+				unmapped = append(unmapped, name+"="+strings.Join(args, ","))
+				result.warnf(id, "filter not supported: %s", name)
+			}
+		}
+		for _, pattern := range patterns {
+			result.add(id, target, toRoutePattern(pattern), path.Join("/", target.Path, rewrite(pattern)), methods, unmapped)
+		}
+	}
+	return result, nil
+}
+
+// ImportZuulRoutes 将 zuul.routes 转换为HTTP协议的Endpoint；支持 zuul.prefix、path、url/serviceId 和 stripPrefix
+func ImportZuulRoutes(data []byte) (*RouteImportResult, error) {
+	v, err := readRouteYaml(data)
+	if nil != err {
+		return nil, err
+	}
+	prefix := v.GetString("zuul.prefix")
+	stripGlobal := zuulStripPrefix(v.Sub("zuul"))
+	result := new(RouteImportResult)
+	routes := v.GetStringMap("zuul.routes")
+	ids := make([]string, 0, len(routes))
+	for id := range routes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		route := v.Sub("zuul.routes." + id)
+		if nil == route {
+			// 简写形式：zuul.routes.<serviceId> = path
+			route = viper.New()
+			route.Set("path", routes[id])
+			route.Set("serviceid", id)
+		}
+		pattern := route.GetString("path")
+		if "" == pattern {
+			pattern = "/" + id + "/**"
+		}
+		raw := route.GetString("url")
+		if "" == raw {
+			serviceId := route.GetString("serviceid")
+			if "" == serviceId {
+				serviceId = id
+			}
+			raw = "lb://" + serviceId
+			result.warnf(id, "serviceId %s mapped to remote host directly", serviceId)
+		}
+		target, err := url.Parse(raw)
+		if nil != err || "" == target.Host {
+			result.warnf(id, "invalid url: %s, ignored", raw)
+			continue
+		}
+		upstream := pattern
+		if zuulStripPrefix(route) {
+			upstream = strings.TrimPrefix(pattern, zuulPathPrefix(pattern))
+		}
+		if "" != prefix && !stripGlobal {
+			upstream = path.Join(prefix, upstream)
+		}
+		result.add(id, target, toRoutePattern(path.Join("/", prefix, pattern)), path.Join("/", target.Path, upstream), nil, nil)
+	}
+	return result, nil
+}
+
+func (r *RouteImportResult) add(id string, target *url.URL, pattern, upstream string, methods, unmapped []string) {
+	if len(methods) == 0 {
+		methods = importDefaultHttpMethods
+	}
+	upstream = toRoutePattern(upstream)
+	if strings.ContainsAny(upstream, ":*") {
+		r.warnf(id, "dynamic upstream path %s requires review", upstream)
+	}
+	extensions := map[string]interface{}{EndpointExtKeyImportedRoute: id}
+	if len(unmapped) > 0 {
+		extensions[EndpointExtKeyImportedFilters] = unmapped
+	}
+	for _, method := range methods {
+		r.Endpoints = append(r.Endpoints, flux.Endpoint{
+			Application: id,
+			HttpPattern: pattern,
+			HttpMethod:  method,
+			Service: flux.BackendService{
+				ServiceId:  id + ":" + method + ":" + pattern,
+				RemoteHost: target.Host,
+				Interface:  upstream,
+				Method:     method,
+				EmbeddedAttributes: flux.EmbeddedAttributes{
+					Attributes: []flux.Attribute{
+						{Tag: flux.ServiceAttrTagRpcProto, Name: "RpcProto", Value: flux.ProtoHttp},
+					},
+				},
+			},
+			EmbeddedAttributes: flux.EmbeddedAttributes{
+				Attributes: []flux.Attribute{
+					{Tag: flux.EndpointAttrTagAuthorize, Name: "Authorize", Value: false},
+				},
+			},
+			EmbeddedExtensions: flux.EmbeddedExtensions{Extensions: extensions},
+		})
+	}
+}
+
+func readRouteYaml(data []byte) (*viper.Viper, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(data)); nil != err {
+		return nil, fmt.Errorf("decode route yaml: %w", err)
+	}
+	return v, nil
+}
+
+// parseSpringShortcut 解析断言/Filter定义：简写形式 Name=arg1,arg2，或完整形式 {name: Name, args: {...}}
+func parseSpringShortcut(def interface{}) (string, []string) {
+	if s, ok := def.(string); ok {
+		kv := strings.SplitN(s, "=", 2)
+		if len(kv) < 2 {
+			return strings.TrimSpace(kv[0]), nil
+		}
+		args := strings.Split(kv[1], ",")
+		for i, arg := range args {
+			args[i] = strings.TrimSpace(arg)
+		}
+		return strings.TrimSpace(kv[0]), args
+	}
+	m := cast.ToStringMap(def)
+	args := cast.ToStringMap(m["args"])
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]string, 0, len(args))
+	for _, k := range keys {
+		if s, ok := args[k].(string); ok {
+			out = append(out, s)
+		} else {
+			out = append(out, cast.ToStringSlice(args[k])...)
+		}
+	}
+	return cast.ToString(m["name"]), out
+}
+
+// toRoutePattern 将Spring路径模式转换为路由Pattern：{id} -> :id，末尾的 ** 或 {*name} -> *
+func toRoutePattern(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	for i, seg := range segments {
+		switch {
+		case "**" == seg || (strings.HasPrefix(seg, "{*") && strings.HasSuffix(seg, "}")):
+			segments = append(segments[:i], "*")
+			return strings.Join(segments, "/")
+		case "*" == seg:
+			segments[i] = fmt.Sprintf(":p%d", i)
+		case strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}"):
+			name := strings.TrimSuffix(strings.TrimPrefix(seg, "{"), "}")
+			// {id:[0-9]+} 正则约束无法转换，只保留参数名
+			segments[i] = ":" + strings.SplitN(name, ":", 2)[0]
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func stripPathSegments(p string, n int) string {
+	segments := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	if n >= len(segments) {
+		return "/"
+	}
+	return "/" + strings.Join(segments[n:], "/")
+}
+
+// zuulPathPrefix 返回Zuul路由路径中通配符之前的前缀：/users/** -> /users
+func zuulPathPrefix(pattern string) string {
+	if i := strings.Index(pattern, "*"); i >= 0 {
+		return strings.TrimSuffix(pattern[:i], "/")
+	}
+	return pattern
+}
+
+// zuulStripPrefix 读取 stripPrefix/strip-prefix 配置，默认为true
+func zuulStripPrefix(v *viper.Viper) bool {
+	if nil == v {
+		return true
+	}
+	for _, key := range []string{"stripprefix", "strip-prefix"} {
+		if v.IsSet(key) {
+			return v.GetBool(key)
+		}
+	}
+	return true
+}
+
+func firstOf(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
@@ -0,0 +1,25 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/remoting"
+)
+
+// LoadEndpointSnapshot 解析Endpoint快照：JSON数组，元素格式与注册中心的Endpoint数据一致
+func LoadEndpointSnapshot(data []byte) ([]flux.Endpoint, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); nil != err {
+		return nil, fmt.Errorf("decode endpoint snapshot: %w", err)
+	}
+	out := make([]flux.Endpoint, 0, len(items))
+	for i, item := range items {
+		event, ok := NewEndpointEvent(item, remoting.EventTypeNodeAdd)
+		if !ok {
+			return nil, fmt.Errorf("invalid endpoint at index %d", i)
+		}
+		out = append(out, event.Endpoint)
+	}
+	return out, nil
+}
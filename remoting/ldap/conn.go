@@ -0,0 +1,288 @@
+package ldap
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"net"
+	"net/url"
+	"time"
+)
+
+const (
+	opBindRequest       = ber.Tag(0)
+	opBindResponse      = ber.Tag(1)
+	opUnbindRequest     = ber.Tag(2)
+	opSearchRequest     = ber.Tag(3)
+	opSearchResultEntry = ber.Tag(4)
+	opSearchResultDone  = ber.Tag(5)
+	opSearchResultRef   = ber.Tag(19)
+	opExtendedRequest   = ber.Tag(23)
+	opExtendedResponse  = ber.Tag(24)
+
+	startTLSOID          = "1.3.6.1.4.1.1466.20037"
+	resultCodeSuccess    = 0
+	resultCodeSizeLimit  = 4
+	resultCodeInvalidCre = 49
+)
+
+const (
+	// 单个LDAP消息字段的最大长度，避免恶意服务端导致内存耗尽
+	berMaxPacketSize = 16 << 20
+)
+
+const (
+	ScopeBaseObject   = 0
+	ScopeSingleLevel  = 1
+	ScopeWholeSubtree = 2
+)
+
+var (
+	ErrInvalidCredentials = errors.New("ldap: invalid credentials")
+)
+
+func init() {
+	// BER编解码使用 go-asn1-ber；限制读取的字段长度
+	ber.MaxPacketLengthBytes = berMaxPacketSize
+}
+
+// ResultError LDAP操作返回的非成功结果
+type ResultError struct {
+	Code    int64
+	Message string
+}
+
+func (e *ResultError) Error() string {
+	return fmt.Sprintf("ldap: result code: %d, message: %s", e.Code, e.Message)
+}
+
+// Entry 搜索结果条目
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// Conn LDAP连接；非并发安全，通过连接池复用
+type Conn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	msgId   int64
+	timeout time.Duration
+}
+
+// DialURL 根据URL建立连接：ldap://host:389 或 ldaps://host:636
+func DialURL(address string, timeout time.Duration, tlsConfig *tls.Config) (*Conn, error) {
+	u, err := url.Parse(address)
+	if nil != err {
+		return nil, fmt.Errorf("ldap: parse url: %w", err)
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		conn, err = dialer.Dial("tcp", hostPortOf(u.Host, "389"))
+	case "ldaps":
+		conn, err = tls.DialWithDialer(dialer, "tcp", hostPortOf(u.Host, "636"), tlsConfig)
+	default:
+		return nil, fmt.Errorf("ldap: unsupported url scheme: %s", u.Scheme)
+	}
+	if nil != err {
+		return nil, err
+	}
+	return NewConn(conn, timeout), nil
+}
+
+func NewConn(conn net.Conn, timeout time.Duration) *Conn {
+	return &Conn{conn: conn, reader: bufio.NewReader(conn), timeout: timeout}
+}
+
+// StartTLS 在明文连接上升级TLS
+func (c *Conn) StartTLS(config *tls.Config) error {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, opExtendedRequest, nil, "Start TLS")
+	op.AppendChild(ber.NewString(ber.ClassContext, ber.TypePrimitive, 0, startTLSOID, "TLS Extended Command"))
+	resp, err := c.request(op)
+	if nil != err {
+		return err
+	}
+	if resp.Tag != opExtendedResponse {
+		return fmt.Errorf("ldap: unexpected start-tls response: %d", resp.Tag)
+	}
+	if err := resultOf(resp); nil != err {
+		return err
+	}
+	tlsConn := tls.Client(c.conn, config)
+	if err := tlsConn.Handshake(); nil != err {
+		return err
+	}
+	c.conn = tlsConn
+	c.reader = bufio.NewReader(tlsConn)
+	return nil
+}
+
+// Bind 简单认证；密码为空时拒绝，避免匿名绑定被误认为认证成功
+func (c *Conn) Bind(dn, password string) error {
+	if "" == password {
+		return ErrInvalidCredentials
+	}
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, opBindRequest, nil, "Bind Request")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, 3, "Version"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, dn, "User Name"))
+	op.AppendChild(ber.NewString(ber.ClassContext, ber.TypePrimitive, 0, password, "Password"))
+	resp, err := c.request(op)
+	if nil != err {
+		return err
+	}
+	if resp.Tag != opBindResponse {
+		return fmt.Errorf("ldap: unexpected bind response: %d", resp.Tag)
+	}
+	err = resultOf(resp)
+	if re, ok := err.(*ResultError); ok && re.Code == resultCodeInvalidCre {
+		return ErrInvalidCredentials
+	}
+	return err
+}
+
+// Search 执行搜索，返回全部条目
+func (c *Conn) Search(baseDN string, scope int, filter string, sizeLimit int, attributes []string) ([]Entry, error) {
+	compiled, err := CompileFilter(filter)
+	if nil != err {
+		return nil, err
+	}
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, opSearchRequest, nil, "Search Request")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, baseDN, "Base DN"))
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, scope, "Scope"))
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, 0, "Deref Aliases"))
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, sizeLimit, "Size Limit"))
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, int(c.timeout/time.Second), "Time Limit"))
+	op.AppendChild(ber.NewLDAPBoolean(ber.ClassUniversal, ber.TypePrimitive, ber.TagBoolean, false, "Types Only"))
+	op.AppendChild(compiled)
+	attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for _, a := range attributes {
+		attrs.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, a, "Attribute"))
+	}
+	op.AppendChild(attrs)
+	msgId, err := c.send(op)
+	if nil != err {
+		return nil, err
+	}
+	entries := make([]Entry, 0, 1)
+	for {
+		resp, err := c.receive(msgId)
+		if nil != err {
+			return nil, err
+		}
+		switch resp.Tag {
+		case opSearchResultEntry:
+			entry, err := entryOf(resp)
+			if nil != err {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case opSearchResultRef:
+			// 不追踪引用
+		case opSearchResultDone:
+			return entries, resultOf(resp)
+		default:
+			return nil, fmt.Errorf("ldap: unexpected search response: %d", resp.Tag)
+		}
+	}
+}
+
+// Close 发送Unbind并关闭连接
+func (c *Conn) Close() error {
+	_, _ = c.send(ber.Encode(ber.ClassApplication, ber.TypePrimitive, opUnbindRequest, nil, "Unbind Request"))
+	return c.conn.Close()
+}
+
+func (c *Conn) request(op *ber.Packet) (*ber.Packet, error) {
+	msgId, err := c.send(op)
+	if nil != err {
+		return nil, err
+	}
+	return c.receive(msgId)
+}
+
+func (c *Conn) send(op *ber.Packet) (int64, error) {
+	c.msgId++
+	if c.timeout > 0 {
+		_ = c.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+	msg := ber.NewSequence("LDAP Request")
+	msg.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, c.msgId, "Message ID"))
+	msg.AppendChild(op)
+	_, err := c.conn.Write(msg.Bytes())
+	return c.msgId, err
+}
+
+func (c *Conn) receive(msgId int64) (*ber.Packet, error) {
+	for {
+		msg, err := ber.ReadPacket(c.reader)
+		if nil != err {
+			return nil, err
+		}
+		if msg.Tag != ber.TagSequence || len(msg.Children) < 2 {
+			return nil, fmt.Errorf("ldap: malformed message: %d", msg.Tag)
+		}
+		if id, ok := msg.Children[0].Value.(int64); ok && id == msgId {
+			op := msg.Children[1]
+			if op.ClassType != ber.ClassApplication {
+				return nil, fmt.Errorf("ldap: malformed protocol op: %d", op.Tag)
+			}
+			return op, nil
+		}
+	}
+}
+
+func resultOf(op *ber.Packet) error {
+	if len(op.Children) < 1 {
+		return fmt.Errorf("ldap: malformed result, op: %d", op.Tag)
+	}
+	code, ok := op.Children[0].Value.(int64)
+	if !ok {
+		return fmt.Errorf("ldap: malformed result code, op: %d", op.Tag)
+	}
+	if code == resultCodeSuccess {
+		return nil
+	}
+	msg := ""
+	if len(op.Children) > 2 {
+		msg = stringOf(op.Children[2])
+	}
+	return &ResultError{Code: code, Message: msg}
+}
+
+func entryOf(op *ber.Packet) (Entry, error) {
+	if len(op.Children) < 2 {
+		return Entry{}, fmt.Errorf("ldap: malformed search entry, op: %d", op.Tag)
+	}
+	entry := Entry{DN: stringOf(op.Children[0]), Attributes: make(map[string][]string)}
+	for _, attr := range op.Children[1].Children {
+		if len(attr.Children) < 2 {
+			return Entry{}, fmt.Errorf("ldap: malformed search entry attribute, dn: %s", entry.DN)
+		}
+		vals := attr.Children[1].Children
+		values := make([]string, len(vals))
+		for i, v := range vals {
+			values[i] = stringOf(v)
+		}
+		entry.Attributes[stringOf(attr.Children[0])] = values
+	}
+	return entry, nil
+}
+
+// stringOf 返回字段的字符串值；非Universal类型的字段没有解析值，读取原始数据
+func stringOf(p *ber.Packet) string {
+	if nil != p.Data {
+		return p.Data.String()
+	}
+	return ""
+}
+
+func hostPortOf(host, defaultPort string) string {
+	if _, _, err := net.SplitHostPort(host); nil == err {
+		return host
+	}
+	return net.JoinHostPort(host, defaultPort)
+}
@@ -0,0 +1,133 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"strings"
+)
+
+// RFC4515搜索过滤器的子集：and(&)、or(|)、not(!)、equality(=)、present(=*)
+
+const (
+	filterTagAnd      = ber.Tag(0)
+	filterTagOr       = ber.Tag(1)
+	filterTagNot      = ber.Tag(2)
+	filterTagEquality = ber.Tag(3)
+	filterTagPresent  = ber.Tag(7)
+)
+
+// EscapeFilter 转义过滤器中的值，防止LDAP注入
+func EscapeFilter(value string) string {
+	var buf strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '*', '(', ')', '\\', 0:
+			buf.WriteString(fmt.Sprintf("\\%02x", c))
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
+
+// CompileFilter 将过滤器字符串编码为BER
+func CompileFilter(filter string) (*ber.Packet, error) {
+	out, rest, err := compileFilter(strings.TrimSpace(filter))
+	if nil != err {
+		return nil, err
+	}
+	if "" != rest {
+		return nil, fmt.Errorf("ldap: unexpected filter tail: %s", rest)
+	}
+	return out, nil
+}
+
+func compileFilter(filter string) (*ber.Packet, string, error) {
+	if len(filter) < 3 || filter[0] != '(' {
+		return nil, "", fmt.Errorf("ldap: invalid filter: %s", filter)
+	}
+	switch filter[1] {
+	case '&', '|':
+		tag := filterTagAnd
+		if filter[1] == '|' {
+			tag = filterTagOr
+		}
+		out := ber.Encode(ber.ClassContext, ber.TypeConstructed, tag, nil, "Filter Set")
+		rest := filter[2:]
+		for len(rest) > 0 && rest[0] == '(' {
+			child, r, err := compileFilter(rest)
+			if nil != err {
+				return nil, "", err
+			}
+			out.AppendChild(child)
+			rest = r
+		}
+		if len(rest) == 0 || rest[0] != ')' || len(out.Children) == 0 {
+			return nil, "", fmt.Errorf("ldap: invalid filter: %s", filter)
+		}
+		return out, rest[1:], nil
+	case '!':
+		child, rest, err := compileFilter(filter[2:])
+		if nil != err {
+			return nil, "", err
+		}
+		if len(rest) == 0 || rest[0] != ')' {
+			return nil, "", fmt.Errorf("ldap: invalid filter: %s", filter)
+		}
+		out := ber.Encode(ber.ClassContext, ber.TypeConstructed, filterTagNot, nil, "Not")
+		out.AppendChild(child)
+		return out, rest[1:], nil
+	default:
+		end := strings.IndexByte(filter, ')')
+		if end < 0 {
+			return nil, "", fmt.Errorf("ldap: invalid filter: %s", filter)
+		}
+		item := filter[1:end]
+		eq := strings.IndexByte(item, '=')
+		if eq <= 0 {
+			return nil, "", fmt.Errorf("ldap: invalid filter item: %s", item)
+		}
+		attr, value := item[:eq], item[eq+1:]
+		if strings.ContainsAny(attr, "~<>:") {
+			return nil, "", fmt.Errorf("ldap: unsupported filter item: %s", item)
+		}
+		if "*" == value {
+			return ber.NewString(ber.ClassContext, ber.TypePrimitive, filterTagPresent, attr, "Present"), filter[end+1:], nil
+		}
+		if strings.Contains(value, "*") {
+			return nil, "", fmt.Errorf("ldap: unsupported substring filter: %s", item)
+		}
+		unescaped, err := unescapeFilter(value)
+		if nil != err {
+			return nil, "", err
+		}
+		out := ber.Encode(ber.ClassContext, ber.TypeConstructed, filterTagEquality, nil, "Equality Match")
+		out.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, attr, "Attribute"))
+		out.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, unescaped, "Value"))
+		return out, filter[end+1:], nil
+	}
+}
+
+func unescapeFilter(value string) (string, error) {
+	if !strings.Contains(value, "\\") {
+		return value, nil
+	}
+	var buf strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			buf.WriteByte(value[i])
+			continue
+		}
+		if i+3 > len(value) {
+			return "", fmt.Errorf("ldap: invalid filter escape: %s", value)
+		}
+		b, err := hex.DecodeString(value[i+1 : i+3])
+		if nil != err {
+			return "", fmt.Errorf("ldap: invalid filter escape: %s", value)
+		}
+		buf.Write(b)
+		i += 2
+	}
+	return buf.String(), nil
+}
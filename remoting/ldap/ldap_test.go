@@ -0,0 +1,185 @@
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"github.com/bytepowered/flux"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	assert2 "github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+)
+
+func TestEscapeFilter(t *testing.T) {
+	assert := assert2.New(t)
+	assert.Equal("alice", EscapeFilter("alice"))
+	assert.Equal("\\2a\\29\\28uid=\\2a", EscapeFilter("*)(uid=*"))
+	assert.Equal("a\\5cb\\00", EscapeFilter("a\\b\x00"))
+}
+
+func TestCompileFilter(t *testing.T) {
+	cases := []struct {
+		filter string
+		ok     bool
+	}{
+		{filter: "(uid=alice)", ok: true},
+		{filter: "(&(objectClass=person)(uid=alice))", ok: true},
+		{filter: "(|(uid=a)(!(cn=b)))", ok: true},
+		{filter: "(mail=*)", ok: true},
+		{filter: "(uid=" + EscapeFilter("*)(uid=*") + ")", ok: true},
+		{filter: "(uid=ali*)", ok: false},
+		{filter: "(uid>=1)", ok: false},
+		{filter: "(&)", ok: false},
+		{filter: "(uid=alice", ok: false},
+		{filter: "(uid=alice))", ok: false},
+	}
+	for _, c := range cases {
+		_, err := CompileFilter(c.filter)
+		assert2.Equal(t, c.ok, nil == err, c.filter)
+	}
+}
+
+func TestCompileFilter_Encoding(t *testing.T) {
+	assert := assert2.New(t)
+	// RFC4511 equalityMatch [3] { attributeDesc, assertionValue }
+	p, err := CompileFilter("(uid=alice)")
+	assert.NoError(err)
+	assert.Equal([]byte{0xa3, 0x0c, 0x04, 0x03, 'u', 'i', 'd', 0x04, 0x05, 'a', 'l', 'i', 'c', 'e'}, p.Bytes())
+	// present [7] attributeDesc
+	p, err = CompileFilter("(mail=*)")
+	assert.NoError(err)
+	assert.Equal([]byte{0x87, 0x04, 'm', 'a', 'i', 'l'}, p.Bytes())
+	// 转义的值按原始字节编码
+	p, err = CompileFilter("(cn=" + EscapeFilter("a*b") + ")")
+	assert.NoError(err)
+	assert.Equal("a*b", stringOf(p.Children[1]))
+}
+
+func TestConn_ReceiveOversized(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		// 声明长度超过上限的OCTET STRING
+		_, _ = server.Write([]byte{0x30, 0x84, 0x7f, 0xff, 0xff, 0xff, 0x02, 0x01, 0x01, 0x04, 0x84, 0x7f, 0xff, 0xff, 0xf0})
+		_ = server.Close()
+	}()
+	conn := NewConn(client, 0)
+	_, err := conn.receive(1)
+	if assert2.Error(t, err) {
+		assert2.Contains(t, err.Error(), "greater than maximum")
+	}
+}
+
+func TestCredentialProvider_Authenticate(t *testing.T) {
+	assert := assert2.New(t)
+	address := serveFakeLdap(t, map[string]string{
+		"cn=svc,dc=example":    "svc-pass",
+		"uid=alice,dc=example": "alice-pass",
+	})
+	provider := NewLdapCredentialProvider()
+	config := flux.NewConfiguration(nil)
+	config.Set(ConfigKeyURL, "ldap://"+address)
+	config.Set(ConfigKeyBindDN, "cn=svc,dc=example")
+	config.Set(ConfigKeyBindPassword, "svc-pass")
+	config.Set(ConfigKeyBaseDN, "dc=example")
+	assert.NoError(provider.Init(config))
+	defer provider.Shutdown(context.TODO())
+	cases := []struct {
+		username string
+		password string
+		ok       bool
+	}{
+		{username: "alice", password: "alice-pass", ok: true},
+		{username: "alice", password: "wrong", ok: false},
+		{username: "alice", password: "", ok: false},
+		{username: "bob", password: "alice-pass", ok: false},
+		{username: "*", password: "alice-pass", ok: false},
+	}
+	for _, c := range cases {
+		ok, err := provider.Authenticate(context.TODO(), c.username, c.password)
+		assert.NoError(err, c.username)
+		assert.Equal(c.ok, ok, c.username+":"+c.password)
+	}
+	groups, err := provider.ResolveGroups(context.TODO(), "alice")
+	assert.NoError(err)
+	assert.Equal([]string{"cn=admins,dc=example"}, groups)
+	_, err = provider.ResolveGroups(context.TODO(), "bob")
+	assert.Equal(ErrUserNotFound, err)
+}
+
+// serveFakeLdap 启动仅支持Bind/Search的LDAP服务端；Search只匹配 uid=alice 的等值过滤器
+func serveFakeLdap(t *testing.T, users map[string]string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if nil != err {
+				return
+			}
+			go serveFakeLdapConn(conn, users)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveFakeLdapConn(conn net.Conn, users map[string]string) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		msg, err := ber.ReadPacket(reader)
+		if nil != err || len(msg.Children) < 2 {
+			return
+		}
+		msgId := msg.Children[0].Value.(int64)
+		op := msg.Children[1]
+		reply := func(ops ...*ber.Packet) {
+			for _, o := range ops {
+				out := ber.NewSequence("LDAP Response")
+				out.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, msgId, "Message ID"))
+				out.AppendChild(o)
+				_, _ = conn.Write(out.Bytes())
+			}
+		}
+		result := func(tag ber.Tag, code int64) *ber.Packet {
+			out := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+			out.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+			out.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+			out.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Message"))
+			return out
+		}
+		switch op.Tag {
+		case opBindRequest:
+			dn, pass := stringOf(op.Children[1]), stringOf(op.Children[2])
+			if expected, ok := users[dn]; ok && expected == pass {
+				reply(result(opBindResponse, resultCodeSuccess))
+			} else {
+				reply(result(opBindResponse, resultCodeInvalidCre))
+			}
+		case opSearchRequest:
+			filter := op.Children[6]
+			if filter.ClassType == ber.ClassContext && filter.Tag == filterTagEquality &&
+				stringOf(filter.Children[0]) == "uid" && stringOf(filter.Children[1]) == "alice" {
+				values := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+				values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "cn=admins,dc=example", "Value"))
+				attr := ber.NewSequence("Attribute")
+				attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "memberOf", "Type"))
+				attr.AppendChild(values)
+				attrs := ber.NewSequence("Attributes")
+				attrs.AppendChild(attr)
+				entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, opSearchResultEntry, nil, "Entry")
+				entry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "uid=alice,dc=example", "DN"))
+				entry.AppendChild(attrs)
+				reply(entry)
+			}
+			reply(result(opSearchResultDone, resultCodeSuccess))
+		case opUnbindRequest:
+			return
+		}
+	}
+}
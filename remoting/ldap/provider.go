@@ -0,0 +1,240 @@
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ConfigKeyURL                   = "url"
+	ConfigKeyBindDN                = "bind-dn"
+	ConfigKeyBindPassword          = "bind-password"
+	ConfigKeyBaseDN                = "base-dn"
+	ConfigKeyUserFilter            = "user-filter"
+	ConfigKeyGroupAttribute        = "group-attribute"
+	ConfigKeyStartTLS              = "start-tls"
+	ConfigKeyTLSInsecureSkipVerify = "tls-insecure-skip-verify"
+	ConfigKeyTimeout               = "timeout"
+	ConfigKeyPoolSize              = "pool-size"
+	ConfigKeyGroupsCacheTTL        = "groups-cache-ttl"
+)
+
+var (
+	ErrUserNotFound  = errors.New("ldap: user not found")
+	ErrUserAmbiguous = errors.New("ldap: user filter matched multiple entries")
+)
+
+var (
+	_ flux.CredentialProvider    = new(CredentialProvider)
+	_ flux.SubjectGroupsResolver = new(CredentialProvider)
+)
+
+func NewLdapCredentialProvider() *CredentialProvider {
+	return &CredentialProvider{}
+}
+
+// CredentialProvider 基于LDAP/AD的用户凭证验证和用户组查询实现；
+// 使用服务账号搜索用户DN，再以用户DN和密码执行Bind验证；连接通过连接池复用，用户组结果按TTL缓存。
+// 需要手动注册：ext.StoreCredentialProvider("ldap", ldap.NewLdapCredentialProvider())，配置位于 CREDENTIAL.ldap 命名空间。
+type CredentialProvider struct {
+	url          string
+	bindDN       string
+	bindPassword string
+	baseDN       string
+	userFilter   string
+	groupAttr    string
+	startTLS     bool
+	tlsConfig    *tls.Config
+	timeout      time.Duration
+	groupsTTL    time.Duration
+	pool         chan *Conn
+	groups       sync.Map
+}
+
+type cachedGroups struct {
+	groups   []string
+	expireAt time.Time
+}
+
+func (p *CredentialProvider) Init(config *flux.Configuration) error {
+	config.SetDefaults(map[string]interface{}{
+		ConfigKeyUserFilter:     "(uid=%s)",
+		ConfigKeyGroupAttribute: "memberOf",
+		ConfigKeyTimeout:        "5s",
+		ConfigKeyPoolSize:       4,
+		ConfigKeyGroupsCacheTTL: "5m",
+	})
+	p.url = config.GetString(ConfigKeyURL)
+	p.bindDN = config.GetString(ConfigKeyBindDN)
+	p.bindPassword = config.GetString(ConfigKeyBindPassword)
+	p.baseDN = config.GetString(ConfigKeyBaseDN)
+	p.userFilter = config.GetString(ConfigKeyUserFilter)
+	p.groupAttr = config.GetString(ConfigKeyGroupAttribute)
+	p.startTLS = config.GetBool(ConfigKeyStartTLS)
+	p.timeout = config.GetDuration(ConfigKeyTimeout)
+	p.groupsTTL = config.GetDuration(ConfigKeyGroupsCacheTTL)
+	p.pool = make(chan *Conn, config.GetInt(ConfigKeyPoolSize))
+	if "" == p.url {
+		return errors.New("LdapCredentialProvider.url is empty")
+	}
+	if "" == p.baseDN {
+		return errors.New("LdapCredentialProvider.base-dn is empty")
+	}
+	if !strings.Contains(p.userFilter, "%s") {
+		return fmt.Errorf("LdapCredentialProvider.user-filter must contains %%s: %s", p.userFilter)
+	}
+	if _, err := CompileFilter(fmt.Sprintf(p.userFilter, "init")); nil != err {
+		return fmt.Errorf("LdapCredentialProvider.user-filter is invalid: %w", err)
+	}
+	p.tlsConfig = &tls.Config{
+		InsecureSkipVerify: config.GetBool(ConfigKeyTLSInsecureSkipVerify),
+	}
+	return nil
+}
+
+func (p *CredentialProvider) Shutdown(_ context.Context) error {
+	for {
+		select {
+		case conn := <-p.pool:
+			_ = conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// Authenticate 验证用户名和密码
+func (p *CredentialProvider) Authenticate(_ context.Context, username, password string) (bool, error) {
+	if "" == username || "" == password {
+		return false, nil
+	}
+	conn, err := p.acquire()
+	if nil != err {
+		return false, err
+	}
+	entry, err := p.findUser(conn, username)
+	if nil != err {
+		p.release(conn, err)
+		if err == ErrUserNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	bindErr := conn.Bind(entry.DN, password)
+	// 恢复服务账号身份后归还连接；匿名搜索模式下无法恢复身份，直接关闭连接
+	if "" == p.bindDN {
+		_ = conn.Close()
+	} else if err := p.bindService(conn); nil != err {
+		_ = conn.Close()
+	} else {
+		p.release(conn, nil)
+	}
+	if bindErr == ErrInvalidCredentials {
+		return false, nil
+	} else if nil != bindErr {
+		return false, bindErr
+	}
+	p.cacheGroups(username, entry.Attributes[p.groupAttr])
+	return true, nil
+}
+
+// ResolveGroups 查询用户所属的用户组
+func (p *CredentialProvider) ResolveGroups(_ context.Context, subject string) ([]string, error) {
+	if v, ok := p.groups.Load(subject); ok {
+		if cached := v.(cachedGroups); time.Now().Before(cached.expireAt) {
+			return cached.groups, nil
+		}
+	}
+	conn, err := p.acquire()
+	if nil != err {
+		return nil, err
+	}
+	entry, err := p.findUser(conn, subject)
+	p.release(conn, err)
+	if nil != err {
+		return nil, err
+	}
+	groups := entry.Attributes[p.groupAttr]
+	p.cacheGroups(subject, groups)
+	return groups, nil
+}
+
+func (p *CredentialProvider) findUser(conn *Conn, username string) (Entry, error) {
+	filter := fmt.Sprintf(p.userFilter, EscapeFilter(username))
+	entries, err := conn.Search(p.baseDN, ScopeWholeSubtree, filter, 2, []string{p.groupAttr})
+	if re, ok := err.(*ResultError); ok && re.Code == resultCodeSizeLimit {
+		return Entry{}, ErrUserAmbiguous
+	} else if nil != err {
+		return Entry{}, err
+	}
+	switch len(entries) {
+	case 0:
+		return Entry{}, ErrUserNotFound
+	case 1:
+		return entries[0], nil
+	default:
+		return Entry{}, ErrUserAmbiguous
+	}
+}
+
+func (p *CredentialProvider) cacheGroups(subject string, groups []string) {
+	if p.groupsTTL > 0 {
+		p.groups.Store(subject, cachedGroups{groups: groups, expireAt: time.Now().Add(p.groupsTTL)})
+	}
+}
+
+func (p *CredentialProvider) acquire() (*Conn, error) {
+	select {
+	case conn := <-p.pool:
+		return conn, nil
+	default:
+	}
+	conn, err := DialURL(p.url, p.timeout, p.tlsConfig)
+	if nil != err {
+		return nil, err
+	}
+	if p.startTLS {
+		if err := conn.StartTLS(p.tlsConfig); nil != err {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	if err := p.bindService(conn); nil != err {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// release 归还连接；操作发生网络或协议错误时关闭连接
+func (p *CredentialProvider) release(conn *Conn, err error) {
+	if nil != err && err != ErrUserNotFound && err != ErrUserAmbiguous {
+		if _, ok := err.(*ResultError); !ok {
+			_ = conn.Close()
+			return
+		}
+	}
+	select {
+	case p.pool <- conn:
+	default:
+		_ = conn.Close()
+	}
+}
+
+func (p *CredentialProvider) bindService(conn *Conn) error {
+	if "" == p.bindDN {
+		return nil
+	}
+	if err := conn.Bind(p.bindDN, p.bindPassword); nil != err {
+		logger.Warnw("LDAP service account bind failed", "bind-dn", p.bindDN, "error", err)
+		return err
+	}
+	return nil
+}
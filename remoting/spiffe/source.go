@@ -0,0 +1,238 @@
+package spiffe
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux/logger"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSourceNotReady = errors.New("spiffe: x509 svid is not ready")
+)
+
+// Authorizer 验证对端的SPIFFE ID，返回nil表示允许
+type Authorizer func(id string) error
+
+// AuthorizeAny 允许任意SPIFFE ID
+func AuthorizeAny() Authorizer {
+	return func(string) error {
+		return nil
+	}
+}
+
+// AuthorizeID 只允许指定的SPIFFE ID
+func AuthorizeID(ids ...string) Authorizer {
+	allowed := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		allowed[id] = true
+	}
+	return func(id string) error {
+		if allowed[id] {
+			return nil
+		}
+		return fmt.Errorf("spiffe: unexpected peer id: %s", id)
+	}
+}
+
+// AuthorizeMemberOf 只允许属于指定信任域的SPIFFE ID
+func AuthorizeMemberOf(trustDomains ...string) Authorizer {
+	allowed := make(map[string]bool, len(trustDomains))
+	for _, td := range trustDomains {
+		allowed[strings.ToLower(strings.TrimPrefix(td, "spiffe://"))] = true
+	}
+	return func(id string) error {
+		td, err := TrustDomainOf(id)
+		if nil != err {
+			return err
+		}
+		if allowed[td] {
+			return nil
+		}
+		return fmt.Errorf("spiffe: unexpected peer trust domain: %s, id: %s", td, id)
+	}
+}
+
+// TrustDomainOf 返回SPIFFE ID的信任域名称，例如 spiffe://example.org/ns/default -> example.org
+func TrustDomainOf(id string) (string, error) {
+	u, err := url.Parse(id)
+	if nil != err || "spiffe" != u.Scheme || "" == u.Host || nil != u.User || "" != u.Port() || "" != u.RawQuery || "" != u.Fragment {
+		return "", fmt.Errorf("spiffe: invalid spiffe id: %s", id)
+	}
+	return strings.ToLower(u.Host), nil
+}
+
+// IDOf 返回证书的SPIFFE ID：证书必须有且只有一个URI SAN
+func IDOf(cert *x509.Certificate) (string, error) {
+	if 1 != len(cert.URIs) {
+		return "", fmt.Errorf("spiffe: certificate must contain exactly one uri san, found: %d", len(cert.URIs))
+	}
+	id := cert.URIs[0].String()
+	if _, err := TrustDomainOf(id); nil != err {
+		return "", err
+	}
+	return id, nil
+}
+
+// X509Source 持续从Workload API获取工作负载的X.509身份：连接断开后按指数退避重连，
+// SPIRE Agent轮换证书时自动更新，之后的TLS握手使用新的证书
+type X509Source struct {
+	client  *Client
+	mu      sync.RWMutex
+	current *X509Context
+	ready   chan struct{}
+	once    sync.Once
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+func NewX509Source(client *Client) *X509Source {
+	return &X509Source{
+		client: client,
+		ready:  make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Start 在后台开始获取身份；通过 WaitReady 等待首次获取完成
+func (s *X509Source) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go func() {
+		defer close(s.done)
+		backoff := time.Second
+		for {
+			err := s.client.WatchX509Context(ctx, s.update)
+			if nil != ctx.Err() {
+				return
+			}
+			if errors.Is(err, io.EOF) {
+				logger.Warn("SPIFFE workload api stream closed, reconnect")
+			} else {
+				logger.Warnw("SPIFFE workload api stream failed, reconnect", "backoff", backoff, "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > 30*time.Second {
+				backoff = 30 * time.Second
+			}
+		}
+	}()
+}
+
+func (s *X509Source) update(x509ctx *X509Context) {
+	s.mu.Lock()
+	s.current = x509ctx
+	s.mu.Unlock()
+	svid := x509ctx.SVIDs[0]
+	logger.Infow("SPIFFE x509 svid updated", "spiffe-id", svid.ID, "expires", svid.Certificates[0].NotAfter)
+	s.once.Do(func() {
+		close(s.ready)
+	})
+}
+
+// WaitReady 等待首次获取身份，直到ctx超时
+func (s *X509Source) WaitReady(ctx context.Context) error {
+	select {
+	case <-s.ready:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %s", ErrSourceNotReady, ctx.Err())
+	}
+}
+
+// X509Context 返回当前的身份和信任域根证书
+func (s *X509Source) X509Context() (*X509Context, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if nil == s.current {
+		return nil, ErrSourceNotReady
+	}
+	return s.current, nil
+}
+
+// Shutdown 停止获取身份
+func (s *X509Source) Shutdown(ctx context.Context) error {
+	if nil == s.cancel {
+		return nil
+	}
+	s.cancel()
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ClientTLSConfig 返回mTLS客户端配置：使用当前的X.509身份作为客户端证书；
+// 对端证书按其SPIFFE ID所属信任域的根证书验证证书链，不验证主机名，再由 authorizer 验证SPIFFE ID
+func (s *X509Source) ClientTLSConfig(authorizer Authorizer) *tls.Config {
+	return &tls.Config{
+		// 由 VerifyPeerCertificate 验证对端证书
+		InsecureSkipVerify: true,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			x509ctx, err := s.X509Context()
+			if nil != err {
+				return nil, err
+			}
+			return x509ctx.SVIDs[0].TLSCertificate(), nil
+		},
+		VerifyPeerCertificate: func(raw [][]byte, _ [][]*x509.Certificate) error {
+			x509ctx, err := s.X509Context()
+			if nil != err {
+				return err
+			}
+			return VerifyPeer(raw, x509ctx.Bundles, authorizer)
+		},
+	}
+}
+
+// VerifyPeer 验证对端的证书链和SPIFFE ID
+func VerifyPeer(raw [][]byte, bundles map[string][]*x509.Certificate, authorizer Authorizer) error {
+	if 0 == len(raw) {
+		return errors.New("spiffe: peer certificate not found")
+	}
+	certs := make([]*x509.Certificate, 0, len(raw))
+	for _, der := range raw {
+		cert, err := x509.ParseCertificate(der)
+		if nil != err {
+			return fmt.Errorf("spiffe: parse peer certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	id, err := IDOf(certs[0])
+	if nil != err {
+		return err
+	}
+	td, _ := TrustDomainOf(id)
+	bundle, ok := bundles[td]
+	if !ok {
+		return fmt.Errorf("spiffe: no bundle for peer trust domain: %s", td)
+	}
+	roots, intermediates := x509.NewCertPool(), x509.NewCertPool()
+	for _, cert := range bundle {
+		roots.AddCert(cert)
+	}
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); nil != err {
+		return fmt.Errorf("spiffe: verify peer certificate: %s, error: %w", id, err)
+	}
+	return authorizer(id)
+}
@@ -0,0 +1,191 @@
+package spiffe
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	assert2 "github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testIdentity struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestIdentity(t *testing.T, id string, parent *testIdentity) *testIdentity {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert2.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: id},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	u, _ := url.Parse(id)
+	template.URIs = []*url.URL{u}
+	signer, signerKey := template, key
+	if nil == parent {
+		template.IsCA, template.BasicConstraintsValid = true, true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	assert2.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert2.NoError(t, err)
+	return &testIdentity{cert: cert, key: key}
+}
+
+func protoField(field int, value []byte) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	out := append([]byte{}, buf[:binary.PutUvarint(buf, uint64(field<<3|2))]...)
+	out = append(out, buf[:binary.PutUvarint(buf, uint64(len(value)))]...)
+	return append(out, value...)
+}
+
+func newTestSVIDResponse(t *testing.T, svid *testIdentity, ca *testIdentity, federated map[string]*testIdentity) []byte {
+	key, err := x509.MarshalPKCS8PrivateKey(svid.key)
+	assert2.NoError(t, err)
+	message := make([]byte, 0, 1024)
+	// 未知的varint字段被跳过
+	message = append(message, 0x10, 0x01)
+	item := protoField(1, []byte(svid.cert.URIs[0].String()))
+	item = append(item, protoField(2, svid.cert.Raw)...)
+	item = append(item, protoField(3, key)...)
+	item = append(item, protoField(4, ca.cert.Raw)...)
+	message = append(message, protoField(1, item)...)
+	for td, root := range federated {
+		entry := append(protoField(1, []byte(td)), protoField(2, root.cert.Raw)...)
+		message = append(message, protoField(3, entry)...)
+	}
+	return message
+}
+
+func TestParseX509SVIDResponse(t *testing.T) {
+	assert := assert2.New(t)
+	ca := newTestIdentity(t, "spiffe://example.org", nil)
+	svid := newTestIdentity(t, "spiffe://example.org/gateway", ca)
+	partner := newTestIdentity(t, "spiffe://partner.org", nil)
+	x509ctx, err := parseX509SVIDResponse(newTestSVIDResponse(t, svid, ca, map[string]*testIdentity{"spiffe://partner.org": partner}))
+	assert.NoError(err)
+	assert.Equal(1, len(x509ctx.SVIDs))
+	assert.Equal("spiffe://example.org/gateway", x509ctx.SVIDs[0].ID)
+	assert.Equal(2, len(x509ctx.Bundles))
+	assert.Equal(partner.cert.Raw, x509ctx.Bundles["partner.org"][0].Raw)
+	assert.NotNil(x509ctx.SVIDs[0].TLSCertificate().PrivateKey)
+	_, err = parseX509SVIDResponse(nil)
+	assert.Error(err)
+	_, err = parseX509SVIDResponse([]byte{0x0a, 0x10, 0x01})
+	assert.Error(err)
+}
+
+func TestVerifyPeer(t *testing.T) {
+	assert := assert2.New(t)
+	ca := newTestIdentity(t, "spiffe://example.org", nil)
+	other := newTestIdentity(t, "spiffe://example.org", nil)
+	peer := newTestIdentity(t, "spiffe://example.org/order", ca)
+	forged := newTestIdentity(t, "spiffe://example.org/order", other)
+	bundles := map[string][]*x509.Certificate{"example.org": {ca.cert}}
+	cases := []struct {
+		raw        [][]byte
+		authorizer Authorizer
+		ok         bool
+	}{
+		{raw: [][]byte{peer.cert.Raw}, authorizer: AuthorizeAny(), ok: true},
+		{raw: [][]byte{peer.cert.Raw}, authorizer: AuthorizeID("spiffe://example.org/order"), ok: true},
+		{raw: [][]byte{peer.cert.Raw}, authorizer: AuthorizeID("spiffe://example.org/user"), ok: false},
+		{raw: [][]byte{peer.cert.Raw}, authorizer: AuthorizeMemberOf("spiffe://example.org"), ok: true},
+		{raw: [][]byte{peer.cert.Raw}, authorizer: AuthorizeMemberOf("partner.org"), ok: false},
+		{raw: [][]byte{forged.cert.Raw}, authorizer: AuthorizeAny(), ok: false},
+		{raw: nil, authorizer: AuthorizeAny(), ok: false},
+	}
+	for i, c := range cases {
+		err := VerifyPeer(c.raw, bundles, c.authorizer)
+		assert.Equal(c.ok, nil == err, "case: %d, error: %v", i, err)
+	}
+}
+
+func TestX509Source_Watch(t *testing.T) {
+	assert := assert2.New(t)
+	ca := newTestIdentity(t, "spiffe://example.org", nil)
+	first := newTestIdentity(t, "spiffe://example.org/gateway", ca)
+	rotated := newTestIdentity(t, "spiffe://example.org/gateway", ca)
+	dir, err := ioutil.TempDir("", "spiffe")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	assert.NoError(err)
+	rotate := make(chan struct{})
+	server := &http.Server{Handler: h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if "true" != r.Header.Get(headerWorkloadSecurity) || pathFetchX509SVID != r.URL.Path {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+		for _, svid := range []*testIdentity{first, rotated} {
+			message := newTestSVIDResponse(t, svid, ca, nil)
+			frame := make([]byte, 5, 5+len(message))
+			binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+			_, _ = w.Write(append(frame, message...))
+			w.(http.Flusher).Flush()
+			select {
+			case <-rotate:
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}), new(http2.Server))}
+	go server.Serve(listener)
+	defer server.Close()
+
+	client, err := NewClient("unix://" + socket)
+	assert.NoError(err)
+	source := NewX509Source(client)
+	source.Start()
+	defer source.Shutdown(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(source.WaitReady(ctx))
+	config := source.ClientTLSConfig(AuthorizeAny())
+	cert, err := config.GetClientCertificate(nil)
+	assert.NoError(err)
+	assert.Equal(first.cert.Raw, cert.Certificate[0])
+	// 证书轮换后使用新的证书
+	close(rotate)
+	assert.Eventually(func() bool {
+		cert, err := config.GetClientCertificate(nil)
+		return nil == err && string(rotated.cert.Raw) == string(cert.Certificate[0])
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestNewClient(t *testing.T) {
+	assert := assert2.New(t)
+	for socket, ok := range map[string]bool{
+		"unix:///run/spire/agent.sock": true,
+		"unix:/run/spire/agent.sock":   true,
+		"tcp://127.0.0.1:8081":         true,
+		"unix://":                      false,
+		"http://127.0.0.1:8081":        false,
+	} {
+		_, err := NewClient(socket)
+		assert.Equal(ok, nil == err, socket)
+	}
+}
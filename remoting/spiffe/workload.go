@@ -0,0 +1,274 @@
+package spiffe
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"golang.org/x/net/http2"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// SPIFFE Workload API地址的环境变量，例如 unix:///run/spire/sockets/agent.sock
+	EnvKeyEndpointSocket = "SPIFFE_ENDPOINT_SOCKET"
+	// Workload API请求必须携带的安全Header
+	headerWorkloadSecurity = "workload.spiffe.io"
+	pathFetchX509SVID      = "/SpiffeWorkloadAPI/FetchX509SVID"
+	maxMessageSize         = 16 << 20
+)
+
+// X509SVID 工作负载的X.509身份：SPIFFE ID、证书链和私钥
+type X509SVID struct {
+	ID           string
+	Certificates []*x509.Certificate
+	PrivateKey   crypto.Signer
+}
+
+// TLSCertificate 返回用于TLS握手的证书
+func (s X509SVID) TLSCertificate() *tls.Certificate {
+	cert := &tls.Certificate{PrivateKey: s.PrivateKey, Leaf: s.Certificates[0]}
+	for _, c := range s.Certificates {
+		cert.Certificate = append(cert.Certificate, c.Raw)
+	}
+	return cert
+}
+
+// X509Context Workload API下发的X.509身份和信任域的根证书；Bundles的Key为信任域名称，包含联邦信任域
+type X509Context struct {
+	SVIDs   []X509SVID
+	Bundles map[string][]*x509.Certificate
+}
+
+// Client SPIFFE Workload API客户端：基于h2c的gRPC调用，通过Unix Socket或TCP连接SPIRE Agent
+type Client struct {
+	network string
+	address string
+	client  *http.Client
+}
+
+// NewClient 创建Workload API客户端；socket 格式为 unix:///path/to/agent.sock 或 tcp://host:port
+func NewClient(socket string) (*Client, error) {
+	u, err := url.Parse(socket)
+	if nil != err {
+		return nil, fmt.Errorf("spiffe: invalid workload api socket: %s, error: %w", socket, err)
+	}
+	c := &Client{network: strings.ToLower(u.Scheme)}
+	switch c.network {
+	case "unix":
+		if c.address = u.Path; "" == c.address {
+			c.address = u.Opaque
+		}
+	case "tcp":
+		c.address = u.Host
+	default:
+		return nil, fmt.Errorf("spiffe: unsupported workload api socket: %s", socket)
+	}
+	if "" == c.address {
+		return nil, fmt.Errorf("spiffe: invalid workload api socket: %s", socket)
+	}
+	dialer := new(net.Dialer)
+	c.client = &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(_, _ string, _ *tls.Config) (net.Conn, error) {
+			return dialer.Dial(c.network, c.address)
+		},
+	}}
+	return c, nil
+}
+
+// WatchX509Context 调用 FetchX509SVID 流式接口，每次收到身份更新（包括证书轮换）时回调 onUpdate；
+// 流结束、出错或ctx取消时返回
+func (c *Client) WatchX509Context(ctx context.Context, onUpdate func(*X509Context)) error {
+	// 请求消息为空消息：未压缩，长度为0
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://localhost"+pathFetchX509SVID,
+		strings.NewReader("\x00\x00\x00\x00\x00"))
+	if nil != err {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/grpc")
+	request.Header.Set("TE", "trailers")
+	request.Header.Set(headerWorkloadSecurity, "true")
+	resp, err := c.client.Do(request)
+	if nil != err {
+		return fmt.Errorf("spiffe: fetch x509 svid: %w", err)
+	}
+	defer resp.Body.Close()
+	if http.StatusOK != resp.StatusCode {
+		return fmt.Errorf("spiffe: fetch x509 svid, http status: %d", resp.StatusCode)
+	}
+	header := make([]byte, 5)
+	for {
+		if _, err := io.ReadFull(resp.Body, header); nil != err {
+			if errors.Is(err, io.EOF) {
+				return statusErrorOf(resp)
+			}
+			return fmt.Errorf("spiffe: read x509 svid response: %w", err)
+		}
+		size := binary.BigEndian.Uint32(header[1:])
+		if 0 != header[0] || size > maxMessageSize {
+			return fmt.Errorf("spiffe: unsupported x509 svid response, compressed: %d, size: %d", header[0], size)
+		}
+		message := make([]byte, size)
+		if _, err := io.ReadFull(resp.Body, message); nil != err {
+			return fmt.Errorf("spiffe: read x509 svid response: %w", err)
+		}
+		x509ctx, err := parseX509SVIDResponse(message)
+		if nil != err {
+			return err
+		}
+		onUpdate(x509ctx)
+	}
+}
+
+// statusErrorOf 返回流结束时的gRPC状态错误；状态为OK时返回 io.EOF
+func statusErrorOf(resp *http.Response) error {
+	status, message := resp.Trailer.Get("grpc-status"), resp.Trailer.Get("grpc-message")
+	if "" == status {
+		// Trailers-Only响应
+		status, message = resp.Header.Get("grpc-status"), resp.Header.Get("grpc-message")
+	}
+	if "" == status || "0" == status {
+		return io.EOF
+	}
+	return fmt.Errorf("spiffe: workload api grpc status: %s, message: %s", status, message)
+}
+
+// parseX509SVIDResponse 解析X509SVIDResponse消息：
+// svids = 1 (X509SVID: spiffe_id = 1, x509_svid = 2, x509_svid_key = 3, bundle = 4)；federated_bundles = 3 (map<string, bytes>)
+func parseX509SVIDResponse(message []byte) (*X509Context, error) {
+	out := &X509Context{Bundles: make(map[string][]*x509.Certificate, 2)}
+	err := walkProto(message, func(field int, value []byte) error {
+		switch field {
+		case 1:
+			svid, bundle, err := parseX509SVID(value)
+			if nil != err {
+				return err
+			}
+			out.SVIDs = append(out.SVIDs, svid)
+			if td, err := TrustDomainOf(svid.ID); nil == err && len(bundle) > 0 {
+				out.Bundles[td] = bundle
+			}
+		case 3:
+			var key string
+			var raw []byte
+			if err := walkProto(value, func(field int, value []byte) error {
+				switch field {
+				case 1:
+					key = string(value)
+				case 2:
+					raw = value
+				}
+				return nil
+			}); nil != err {
+				return err
+			}
+			td, err := TrustDomainOf(key)
+			if nil != err {
+				// 联邦信任域的Key可能不带 spiffe:// 前缀
+				td = strings.ToLower(key)
+			}
+			certs, err := x509.ParseCertificates(raw)
+			if nil != err {
+				return fmt.Errorf("spiffe: parse federated bundle: %s, error: %w", key, err)
+			}
+			if _, ok := out.Bundles[td]; !ok {
+				out.Bundles[td] = certs
+			}
+		}
+		return nil
+	})
+	if nil != err {
+		return nil, err
+	}
+	if 0 == len(out.SVIDs) {
+		return nil, errors.New("spiffe: x509 svid response has no svid")
+	}
+	return out, nil
+}
+
+func parseX509SVID(message []byte) (X509SVID, []*x509.Certificate, error) {
+	var svid X509SVID
+	var chain, key, bundle []byte
+	if err := walkProto(message, func(field int, value []byte) error {
+		switch field {
+		case 1:
+			svid.ID = string(value)
+		case 2:
+			chain = value
+		case 3:
+			key = value
+		case 4:
+			bundle = value
+		}
+		return nil
+	}); nil != err {
+		return svid, nil, err
+	}
+	certs, err := x509.ParseCertificates(chain)
+	if nil != err || 0 == len(certs) {
+		return svid, nil, fmt.Errorf("spiffe: parse svid certificates: %s, error: %v", svid.ID, err)
+	}
+	svid.Certificates = certs
+	pk, err := x509.ParsePKCS8PrivateKey(key)
+	if nil != err {
+		return svid, nil, fmt.Errorf("spiffe: parse svid private key: %s, error: %w", svid.ID, err)
+	}
+	signer, ok := pk.(crypto.Signer)
+	if !ok {
+		return svid, nil, fmt.Errorf("spiffe: svid private key is not a signer: %s", svid.ID)
+	}
+	svid.PrivateKey = signer
+	roots, err := x509.ParseCertificates(bundle)
+	if nil != err {
+		return svid, nil, fmt.Errorf("spiffe: parse svid bundle: %s, error: %w", svid.ID, err)
+	}
+	return svid, roots, nil
+}
+
+// walkProto 遍历Protobuf消息的字段，回调长度分隔类型（wire type 2）的字段值；其它类型的字段被跳过
+func walkProto(message []byte, onField func(field int, value []byte) error) error {
+	for len(message) > 0 {
+		tag, n := binary.Uvarint(message)
+		if n <= 0 {
+			return errors.New("spiffe: invalid protobuf tag")
+		}
+		message = message[n:]
+		switch tag & 0x7 {
+		case 0:
+			if _, n = binary.Uvarint(message); n <= 0 {
+				return errors.New("spiffe: invalid protobuf varint")
+			}
+			message = message[n:]
+		case 1, 5:
+			size := 8
+			if 5 == tag&0x7 {
+				size = 4
+			}
+			if len(message) < size {
+				return errors.New("spiffe: invalid protobuf fixed value")
+			}
+			message = message[size:]
+		case 2:
+			size, n := binary.Uvarint(message)
+			if n <= 0 || size > uint64(len(message)-n) {
+				return errors.New("spiffe: invalid protobuf length")
+			}
+			value := message[n : n+int(size)]
+			message = message[n+int(size):]
+			if err := onField(int(tag>>3), value); nil != err {
+				return err
+			}
+		default:
+			return fmt.Errorf("spiffe: unsupported protobuf wire type: %d", tag&0x7)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,309 @@
+package xds
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux/logger"
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/http2"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	LbPolicyRoundRobin   = "ROUND_ROBIN"
+	LbPolicyLeastRequest = "LEAST_REQUEST"
+	LbPolicyRingHash     = "RING_HASH"
+	LbPolicyRandom       = "RANDOM"
+	LbPolicyMaglev       = "MAGLEV"
+)
+
+const (
+	pathStreamAggregatedResources = "/envoy.service.discovery.v3.AggregatedDiscoveryService/StreamAggregatedResources"
+	maxMessageSize                = 16 << 20
+)
+
+// Options xDS客户端配置
+type Options struct {
+	// 控制面地址，host:port，例如 istiod.istio-system.svc:15010
+	Address string
+	// 连接控制面使用的TLS配置；为nil时使用h2c明文连接
+	TLSConfig *tls.Config
+	// 向控制面标识网关的Node ID和Node Cluster
+	NodeId      string
+	NodeCluster string
+}
+
+// Endpoint 集群中的可用地址
+type Endpoint struct {
+	Address string
+	Weight  int
+}
+
+// Cluster 由CDS和EDS数据合并的集群：Envoy的负载均衡策略名称，和有可用地址的最高优先级中的健康地址
+type Cluster struct {
+	Name      string
+	LbPolicy  string
+	Endpoints []Endpoint
+}
+
+// Client 实验性的xDS客户端：通过ADS（Aggregated Discovery Service）双向流从Istio/Envoy控制面订阅CDS全部集群，
+// 以及EDS类型集群的地址；每次集群或地址变更后，回调完整的集群列表。只支持SotW（State of the World）协议，不订阅LDS/RDS。
+// 连接断开后按指数退避重连，重连后重新订阅全部资源。
+type Client struct {
+	options  Options
+	onUpdate func([]Cluster)
+	client   *http.Client
+	cancel   context.CancelFunc
+	done     chan struct{}
+	once     sync.Once
+}
+
+func NewClient(options Options, onUpdate func([]Cluster)) *Client {
+	dialer := new(net.Dialer)
+	transport := &http2.Transport{TLSClientConfig: options.TLSConfig}
+	if nil == options.TLSConfig {
+		transport.AllowHTTP = true
+		transport.DialTLS = func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return dialer.Dial(network, addr)
+		}
+	}
+	return &Client{
+		options:  options,
+		onUpdate: onUpdate,
+		client:   &http.Client{Transport: transport},
+		done:     make(chan struct{}),
+	}
+}
+
+// Startup 在后台连接控制面并订阅资源
+func (c *Client) Startup() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	go func() {
+		defer close(c.done)
+		backoff := time.Second
+		for {
+			start := time.Now()
+			err := c.stream(ctx)
+			if nil != ctx.Err() {
+				return
+			}
+			// 稳定运行一段时间后断开的连接，从最小退避时间开始重连
+			if time.Since(start) > 30*time.Second {
+				backoff = time.Second
+			}
+			logger.Warnw("xDS stream closed, reconnect", "address", c.options.Address, "backoff", backoff, "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > 30*time.Second {
+				backoff = 30 * time.Second
+			}
+		}
+	}()
+	return nil
+}
+
+// Shutdown 关闭与控制面的连接
+func (c *Client) Shutdown(ctx context.Context) error {
+	if nil == c.cancel {
+		return nil
+	}
+	c.once.Do(c.cancel)
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// adsState 单个ADS流的订阅状态
+type adsState struct {
+	clusters    map[string]clusterResource
+	assignments map[string][]Endpoint
+	cdsVersion  string
+	edsNames    []string
+	edsVersion  string
+	edsNonce    string
+}
+
+func (c *Client) stream(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	reader, writer := io.Pipe()
+	scheme := "http"
+	if nil != c.options.TLSConfig {
+		scheme = "https"
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, scheme+"://"+c.options.Address+pathStreamAggregatedResources, reader)
+	if nil != err {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/grpc")
+	request.Header.Set("TE", "trailers")
+	// 请求Body为双向流的发送端，由独立协程写入；流结束时关闭发送端，使Transport结束请求
+	sends := make(chan *discoveryRequest, 4)
+	go func() {
+		defer writer.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case req := <-sends:
+				message, err := proto.Marshal(req)
+				if nil != err {
+					logger.Warnw("xDS encode request", "error", err)
+					return
+				}
+				frame := make([]byte, 5, 5+len(message))
+				binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+				if _, err := writer.Write(append(frame, message...)); nil != err {
+					return
+				}
+			}
+		}
+	}()
+	send := func(req discoveryRequest) {
+		req.Node = &node{Id: c.options.NodeId, Cluster: c.options.NodeCluster, UserAgentName: "flux"}
+		select {
+		case sends <- &req:
+		case <-ctx.Done():
+		}
+	}
+	// 订阅全部集群
+	send(discoveryRequest{TypeUrl: TypeUrlCluster})
+	resp, err := c.client.Do(request)
+	if nil != err {
+		return err
+	}
+	defer resp.Body.Close()
+	if http.StatusOK != resp.StatusCode {
+		return fmt.Errorf("xds: ads stream, http status: %d", resp.StatusCode)
+	}
+	state := &adsState{clusters: make(map[string]clusterResource), assignments: make(map[string][]Endpoint)}
+	header := make([]byte, 5)
+	for {
+		if _, err := io.ReadFull(resp.Body, header); nil != err {
+			if errors.Is(err, io.EOF) {
+				return statusErrorOf(resp)
+			}
+			return err
+		}
+		size := binary.BigEndian.Uint32(header[1:])
+		if 0 != header[0] || size > maxMessageSize {
+			return fmt.Errorf("xds: unsupported ads response, compressed: %d, size: %d", header[0], size)
+		}
+		message := make([]byte, size)
+		if _, err := io.ReadFull(resp.Body, message); nil != err {
+			return err
+		}
+		response := new(discoveryResponse)
+		if err := proto.Unmarshal(message, response); nil != err {
+			return err
+		}
+		resources := make([][]byte, 0, len(response.Resources))
+		for _, value := range response.Resources {
+			data, err := unwrapAny(value)
+			if nil != err {
+				return err
+			}
+			resources = append(resources, data)
+		}
+		c.handle(state, response, resources, send)
+	}
+}
+
+// handle 处理一次xDS响应：应用成功时ACK，否则NACK并保持之前的配置
+func (c *Client) handle(state *adsState, response *discoveryResponse, resources [][]byte, send func(discoveryRequest)) {
+	switch response.TypeUrl {
+	case TypeUrlCluster:
+		clusters := make(map[string]clusterResource, len(resources))
+		for _, data := range resources {
+			cluster, err := parseCluster(data)
+			if nil != err {
+				logger.Warnw("xDS reject cluster response", "version", response.VersionInfo, "error", err)
+				send(discoveryRequest{TypeUrl: TypeUrlCluster, VersionInfo: state.cdsVersion,
+					ResponseNonce: response.Nonce, ErrorDetail: errorDetailOf(err)})
+				return
+			}
+			clusters[cluster.Name] = cluster
+		}
+		state.clusters, state.cdsVersion = clusters, response.VersionInfo
+		send(discoveryRequest{TypeUrl: TypeUrlCluster, VersionInfo: response.VersionInfo, ResponseNonce: response.Nonce})
+		// EDS类型集群变化时更新订阅
+		names := make([]string, 0, len(clusters))
+		for _, cluster := range clusters {
+			if clusterTypeEds == cluster.Type {
+				names = append(names, cluster.EdsServiceName)
+			}
+		}
+		sort.Strings(names)
+		if strings.Join(names, ",") != strings.Join(state.edsNames, ",") {
+			state.edsNames = names
+			send(discoveryRequest{TypeUrl: TypeUrlClusterLoadAssignment, ResourceNames: names,
+				VersionInfo: state.edsVersion, ResponseNonce: state.edsNonce})
+		}
+	case TypeUrlClusterLoadAssignment:
+		assignments := make(map[string][]Endpoint, len(resources))
+		for _, data := range resources {
+			name, endpoints, err := parseClusterLoadAssignment(data)
+			if nil != err {
+				logger.Warnw("xDS reject endpoint response", "version", response.VersionInfo, "error", err)
+				send(discoveryRequest{TypeUrl: TypeUrlClusterLoadAssignment, ResourceNames: state.edsNames,
+					VersionInfo: state.edsVersion, ResponseNonce: response.Nonce, ErrorDetail: errorDetailOf(err)})
+				return
+			}
+			assignments[name] = endpoints
+		}
+		state.assignments = assignments
+		state.edsVersion, state.edsNonce = response.VersionInfo, response.Nonce
+		send(discoveryRequest{TypeUrl: TypeUrlClusterLoadAssignment, ResourceNames: state.edsNames,
+			VersionInfo: response.VersionInfo, ResponseNonce: response.Nonce})
+	default:
+		logger.Warnw("xDS ignore unsubscribed response", "type-url", response.TypeUrl)
+		return
+	}
+	logger.Infow("xDS resources updated", "type-url", response.TypeUrl, "version", response.VersionInfo)
+	c.onUpdate(state.snapshot())
+}
+
+// snapshot 合并CDS和EDS数据，返回按名称排序的集群列表
+func (s *adsState) snapshot() []Cluster {
+	out := make([]Cluster, 0, len(s.clusters))
+	for _, cluster := range s.clusters {
+		endpoints := cluster.Endpoints
+		if clusterTypeEds == cluster.Type {
+			endpoints = s.assignments[cluster.EdsServiceName]
+		}
+		out = append(out, Cluster{Name: cluster.Name, LbPolicy: cluster.LbPolicy, Endpoints: endpoints})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// statusErrorOf 返回流结束时的gRPC状态错误
+func statusErrorOf(resp *http.Response) error {
+	status, message := resp.Trailer.Get("grpc-status"), resp.Trailer.Get("grpc-message")
+	if "" == status {
+		// Trailers-Only响应
+		status, message = resp.Header.Get("grpc-status"), resp.Header.Get("grpc-message")
+	}
+	if "" == status || "0" == status {
+		return io.EOF
+	}
+	return fmt.Errorf("xds: ads grpc status: %s, message: %s", status, message)
+}
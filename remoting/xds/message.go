@@ -0,0 +1,274 @@
+package xds
+
+import (
+	"errors"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"net"
+	"strconv"
+)
+
+const (
+	TypeUrlCluster               = "type.googleapis.com/envoy.config.cluster.v3.Cluster"
+	TypeUrlClusterLoadAssignment = "type.googleapis.com/envoy.config.endpoint.v3.ClusterLoadAssignment"
+	typeUrlResource              = "type.googleapis.com/envoy.service.discovery.v3.Resource"
+)
+
+const (
+	// envoy.config.cluster.v3.Cluster.DiscoveryType.EDS
+	clusterTypeEds = 3
+	// google.rpc.Code.INVALID_ARGUMENT
+	codeInvalidArgument = 3
+)
+
+var (
+	// envoy.config.cluster.v3.Cluster.LbPolicy
+	lbPolicyNames = map[int32]string{
+		0: LbPolicyRoundRobin,
+		1: LbPolicyLeastRequest,
+		2: LbPolicyRingHash,
+		3: LbPolicyRandom,
+		5: LbPolicyMaglev,
+	}
+)
+
+// 以下为xDS协议消息中网关使用的字段子集，字段编号与Envoy v3 API一致；未定义的字段在解码时被忽略。
+
+// discoveryRequest envoy.service.discovery.v3.DiscoveryRequest
+type discoveryRequest struct {
+	VersionInfo   string     `protobuf:"bytes,1,opt,name=version_info,json=versionInfo,proto3"`
+	Node          *node      `protobuf:"bytes,2,opt,name=node,proto3"`
+	ResourceNames []string   `protobuf:"bytes,3,rep,name=resource_names,json=resourceNames,proto3"`
+	TypeUrl       string     `protobuf:"bytes,4,opt,name=type_url,json=typeUrl,proto3"`
+	ResponseNonce string     `protobuf:"bytes,5,opt,name=response_nonce,json=responseNonce,proto3"`
+	ErrorDetail   *rpcStatus `protobuf:"bytes,6,opt,name=error_detail,json=errorDetail,proto3"`
+}
+
+func (m *discoveryRequest) Reset()         { *m = discoveryRequest{} }
+func (m *discoveryRequest) String() string { return proto.CompactTextString(m) }
+func (*discoveryRequest) ProtoMessage()    {}
+
+// node envoy.config.core.v3.Node
+type node struct {
+	Id            string `protobuf:"bytes,1,opt,name=id,proto3"`
+	Cluster       string `protobuf:"bytes,2,opt,name=cluster,proto3"`
+	UserAgentName string `protobuf:"bytes,6,opt,name=user_agent_name,json=userAgentName,proto3"`
+}
+
+func (m *node) Reset()         { *m = node{} }
+func (m *node) String() string { return proto.CompactTextString(m) }
+func (*node) ProtoMessage()    {}
+
+// rpcStatus google.rpc.Status
+type rpcStatus struct {
+	Code    int32  `protobuf:"varint,1,opt,name=code,proto3"`
+	Message string `protobuf:"bytes,2,opt,name=message,proto3"`
+}
+
+func (m *rpcStatus) Reset()         { *m = rpcStatus{} }
+func (m *rpcStatus) String() string { return proto.CompactTextString(m) }
+func (*rpcStatus) ProtoMessage()    {}
+
+// discoveryResponse envoy.service.discovery.v3.DiscoveryResponse
+type discoveryResponse struct {
+	VersionInfo string     `protobuf:"bytes,1,opt,name=version_info,json=versionInfo,proto3"`
+	Resources   []*any.Any `protobuf:"bytes,2,rep,name=resources,proto3"`
+	TypeUrl     string     `protobuf:"bytes,4,opt,name=type_url,json=typeUrl,proto3"`
+	Nonce       string     `protobuf:"bytes,5,opt,name=nonce,proto3"`
+}
+
+func (m *discoveryResponse) Reset()         { *m = discoveryResponse{} }
+func (m *discoveryResponse) String() string { return proto.CompactTextString(m) }
+func (*discoveryResponse) ProtoMessage()    {}
+
+// resource envoy.service.discovery.v3.Resource
+type resource struct {
+	Version  string   `protobuf:"bytes,1,opt,name=version,proto3"`
+	Resource *any.Any `protobuf:"bytes,2,opt,name=resource,proto3"`
+	Name     string   `protobuf:"bytes,3,opt,name=name,proto3"`
+}
+
+func (m *resource) Reset()         { *m = resource{} }
+func (m *resource) String() string { return proto.CompactTextString(m) }
+func (*resource) ProtoMessage()    {}
+
+// cluster envoy.config.cluster.v3.Cluster
+type cluster struct {
+	Name             string                 `protobuf:"bytes,1,opt,name=name,proto3"`
+	Type             int32                  `protobuf:"varint,2,opt,name=type,proto3"`
+	EdsClusterConfig *edsClusterConfig      `protobuf:"bytes,3,opt,name=eds_cluster_config,json=edsClusterConfig,proto3"`
+	LbPolicy         int32                  `protobuf:"varint,6,opt,name=lb_policy,json=lbPolicy,proto3"`
+	LoadAssignment   *clusterLoadAssignment `protobuf:"bytes,33,opt,name=load_assignment,json=loadAssignment,proto3"`
+}
+
+func (m *cluster) Reset()         { *m = cluster{} }
+func (m *cluster) String() string { return proto.CompactTextString(m) }
+func (*cluster) ProtoMessage()    {}
+
+// edsClusterConfig envoy.config.cluster.v3.Cluster.EdsClusterConfig
+type edsClusterConfig struct {
+	ServiceName string `protobuf:"bytes,2,opt,name=service_name,json=serviceName,proto3"`
+}
+
+func (m *edsClusterConfig) Reset()         { *m = edsClusterConfig{} }
+func (m *edsClusterConfig) String() string { return proto.CompactTextString(m) }
+func (*edsClusterConfig) ProtoMessage()    {}
+
+// clusterLoadAssignment envoy.config.endpoint.v3.ClusterLoadAssignment
+type clusterLoadAssignment struct {
+	ClusterName string                 `protobuf:"bytes,1,opt,name=cluster_name,json=clusterName,proto3"`
+	Endpoints   []*localityLbEndpoints `protobuf:"bytes,2,rep,name=endpoints,proto3"`
+}
+
+func (m *clusterLoadAssignment) Reset()         { *m = clusterLoadAssignment{} }
+func (m *clusterLoadAssignment) String() string { return proto.CompactTextString(m) }
+func (*clusterLoadAssignment) ProtoMessage()    {}
+
+// localityLbEndpoints envoy.config.endpoint.v3.LocalityLbEndpoints
+type localityLbEndpoints struct {
+	LbEndpoints []*lbEndpoint `protobuf:"bytes,2,rep,name=lb_endpoints,json=lbEndpoints,proto3"`
+	Priority    uint32        `protobuf:"varint,5,opt,name=priority,proto3"`
+}
+
+func (m *localityLbEndpoints) Reset()         { *m = localityLbEndpoints{} }
+func (m *localityLbEndpoints) String() string { return proto.CompactTextString(m) }
+func (*localityLbEndpoints) ProtoMessage()    {}
+
+// lbEndpoint envoy.config.endpoint.v3.LbEndpoint
+type lbEndpoint struct {
+	Endpoint            *endpoint             `protobuf:"bytes,1,opt,name=endpoint,proto3"`
+	HealthStatus        int32                 `protobuf:"varint,2,opt,name=health_status,json=healthStatus,proto3"`
+	LoadBalancingWeight *wrappers.UInt32Value `protobuf:"bytes,4,opt,name=load_balancing_weight,json=loadBalancingWeight,proto3"`
+}
+
+func (m *lbEndpoint) Reset()         { *m = lbEndpoint{} }
+func (m *lbEndpoint) String() string { return proto.CompactTextString(m) }
+func (*lbEndpoint) ProtoMessage()    {}
+
+// endpoint envoy.config.endpoint.v3.Endpoint
+type endpoint struct {
+	Address *address `protobuf:"bytes,1,opt,name=address,proto3"`
+}
+
+func (m *endpoint) Reset()         { *m = endpoint{} }
+func (m *endpoint) String() string { return proto.CompactTextString(m) }
+func (*endpoint) ProtoMessage()    {}
+
+// address envoy.config.core.v3.Address；只解码socket_address，Pipe等其它地址类型被忽略
+type address struct {
+	SocketAddress *socketAddress `protobuf:"bytes,1,opt,name=socket_address,json=socketAddress,proto3"`
+}
+
+func (m *address) Reset()         { *m = address{} }
+func (m *address) String() string { return proto.CompactTextString(m) }
+func (*address) ProtoMessage()    {}
+
+// socketAddress envoy.config.core.v3.SocketAddress
+type socketAddress struct {
+	Address   string `protobuf:"bytes,2,opt,name=address,proto3"`
+	PortValue uint32 `protobuf:"varint,3,opt,name=port_value,json=portValue,proto3"`
+}
+
+func (m *socketAddress) Reset()         { *m = socketAddress{} }
+func (m *socketAddress) String() string { return proto.CompactTextString(m) }
+func (*socketAddress) ProtoMessage()    {}
+
+// errorDetailOf 返回NACK请求中的错误详情
+func errorDetailOf(err error) *rpcStatus {
+	return &rpcStatus{Code: codeInvalidArgument, Message: err.Error()}
+}
+
+// unwrapAny 返回Any的消息值；Resource包装被展开
+func unwrapAny(value *any.Any) ([]byte, error) {
+	if typeUrlResource != value.GetTypeUrl() {
+		return value.GetValue(), nil
+	}
+	wrapper := new(resource)
+	if err := proto.Unmarshal(value.GetValue(), wrapper); nil != err {
+		return nil, err
+	}
+	return unwrapAny(wrapper.Resource)
+}
+
+// clusterResource CDS下发的集群定义
+type clusterResource struct {
+	Name           string
+	Type           int32
+	EdsServiceName string
+	LbPolicy       string
+	Endpoints      []Endpoint
+}
+
+func parseCluster(data []byte) (clusterResource, error) {
+	msg := new(cluster)
+	if err := proto.Unmarshal(data, msg); nil != err {
+		return clusterResource{}, err
+	}
+	if "" == msg.Name {
+		return clusterResource{}, errors.New("xds: cluster name is empty")
+	}
+	c := clusterResource{Name: msg.Name, Type: msg.Type, LbPolicy: LbPolicyRoundRobin}
+	if name, ok := lbPolicyNames[msg.LbPolicy]; ok {
+		c.LbPolicy = name
+	}
+	if nil != msg.EdsClusterConfig {
+		c.EdsServiceName = msg.EdsClusterConfig.ServiceName
+	}
+	if clusterTypeEds == c.Type && "" == c.EdsServiceName {
+		c.EdsServiceName = c.Name
+	}
+	if nil != msg.LoadAssignment {
+		c.Endpoints = endpointsOf(msg.LoadAssignment)
+	}
+	return c, nil
+}
+
+// parseClusterLoadAssignment 解析EDS下发的集群地址，返回集群名称和可用地址
+func parseClusterLoadAssignment(data []byte) (string, []Endpoint, error) {
+	msg := new(clusterLoadAssignment)
+	if err := proto.Unmarshal(data, msg); nil != err {
+		return "", nil, err
+	}
+	return msg.ClusterName, endpointsOf(msg), nil
+}
+
+// endpointsOf 只返回健康状态为UNKNOWN、HEALTHY、DEGRADED的Socket地址；存在多个优先级时，返回有可用地址的最高优先级（数值最小）
+func endpointsOf(assignment *clusterLoadAssignment) []Endpoint {
+	var selected []Endpoint
+	var min uint32
+	priorities := make(map[uint32][]Endpoint, 1)
+	for _, locality := range assignment.Endpoints {
+		for _, lbe := range locality.LbEndpoints {
+			if ep, ok := endpointOf(lbe); ok {
+				priorities[locality.Priority] = append(priorities[locality.Priority], ep)
+			}
+		}
+	}
+	for priority, endpoints := range priorities {
+		if nil == selected || priority < min {
+			selected, min = endpoints, priority
+		}
+	}
+	return selected
+}
+
+func endpointOf(lbe *lbEndpoint) (Endpoint, bool) {
+	// HealthStatus: UNKNOWN = 0, HEALTHY = 1, DEGRADED = 5
+	if health := lbe.HealthStatus; 0 != health && 1 != health && 5 != health {
+		return Endpoint{}, false
+	}
+	var socket *socketAddress
+	if nil != lbe.Endpoint && nil != lbe.Endpoint.Address {
+		socket = lbe.Endpoint.Address.SocketAddress
+	}
+	// 非Socket地址（例如Pipe、内部地址）不可由网关直接连接
+	if nil == socket || "" == socket.Address {
+		return Endpoint{}, false
+	}
+	ep := Endpoint{Address: net.JoinHostPort(socket.Address, strconv.FormatUint(uint64(socket.PortValue), 10)), Weight: 1}
+	if weight := lbe.LoadBalancingWeight.GetValue(); weight > 0 {
+		ep.Weight = int(weight)
+	}
+	return ep, true
+}
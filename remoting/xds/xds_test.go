@@ -0,0 +1,174 @@
+package xds
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	assert2 "github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func mustMarshal(msg proto.Message) []byte {
+	data, err := proto.Marshal(msg)
+	if nil != err {
+		panic(err)
+	}
+	return data
+}
+
+func lbEndpointOf(host string, port uint32, health int32, weight uint32) *lbEndpoint {
+	out := &lbEndpoint{
+		Endpoint:     &endpoint{Address: &address{SocketAddress: &socketAddress{Address: host, PortValue: port}}},
+		HealthStatus: health,
+	}
+	if weight > 0 {
+		out.LoadBalancingWeight = &wrappers.UInt32Value{Value: weight}
+	}
+	return out
+}
+
+func assignmentOf(name string, priorities map[uint32][]*lbEndpoint) *clusterLoadAssignment {
+	out := &clusterLoadAssignment{ClusterName: name}
+	for priority, endpoints := range priorities {
+		out.Endpoints = append(out.Endpoints, &localityLbEndpoints{LbEndpoints: endpoints, Priority: priority})
+	}
+	return out
+}
+
+func responseOf(typeUrl, version, nonce string, resources ...proto.Message) []byte {
+	out := &discoveryResponse{VersionInfo: version, TypeUrl: typeUrl, Nonce: nonce}
+	for _, resource := range resources {
+		out.Resources = append(out.Resources, &any.Any{TypeUrl: typeUrl, Value: mustMarshal(resource)})
+	}
+	return mustMarshal(out)
+}
+
+func TestParseClusterLoadAssignment(t *testing.T) {
+	assert := assert2.New(t)
+	name, endpoints, err := parseClusterLoadAssignment(mustMarshal(assignmentOf("reviews", map[uint32][]*lbEndpoint{
+		0: {lbEndpointOf("10.0.0.1", 9080, 2, 0), lbEndpointOf("10.0.0.2", 9080, 3, 0)},
+		1: {lbEndpointOf("10.0.1.1", 9080, 1, 5), lbEndpointOf("10.0.1.2", 9080, 0, 0), lbEndpointOf("10.0.1.3", 9080, 2, 0)},
+		2: {lbEndpointOf("10.0.2.1", 9080, 1, 0)},
+	})))
+	assert.NoError(err)
+	assert.Equal("reviews", name)
+	assert.Equal([]Endpoint{{Address: "10.0.1.1:9080", Weight: 5}, {Address: "10.0.1.2:9080", Weight: 1}}, endpoints)
+	// 非Socket地址被忽略
+	_, endpoints, err = parseClusterLoadAssignment(mustMarshal(assignmentOf("pipe", map[uint32][]*lbEndpoint{
+		0: {{Endpoint: &endpoint{Address: &address{}}}},
+	})))
+	assert.NoError(err)
+	assert.Empty(endpoints)
+	_, _, err = parseClusterLoadAssignment([]byte{0x12, 0x05, 0x01})
+	assert.Error(err)
+}
+
+func TestParseCluster(t *testing.T) {
+	assert := assert2.New(t)
+	eds := mustMarshal(&cluster{Name: "outbound|9080||reviews", Type: clusterTypeEds, LbPolicy: 2})
+	c, err := parseCluster(eds)
+	assert.NoError(err)
+	assert.Equal("outbound|9080||reviews", c.EdsServiceName)
+	assert.Equal(LbPolicyRingHash, c.LbPolicy)
+	static := mustMarshal(&cluster{Name: "static",
+		LoadAssignment: assignmentOf("static", map[uint32][]*lbEndpoint{0: {lbEndpointOf("127.0.0.1", 80, 0, 0)}})})
+	c, err = parseCluster(static)
+	assert.NoError(err)
+	assert.Equal(LbPolicyRoundRobin, c.LbPolicy)
+	assert.Equal([]Endpoint{{Address: "127.0.0.1:80", Weight: 1}}, c.Endpoints)
+	_, err = parseCluster(mustMarshal(&cluster{Type: clusterTypeEds}))
+	assert.Error(err)
+	// Resource包装
+	value, err := unwrapAny(&any.Any{TypeUrl: typeUrlResource, Value: mustMarshal(&resource{Name: "static",
+		Resource: &any.Any{TypeUrl: TypeUrlCluster, Value: static}})})
+	assert.NoError(err)
+	assert.Equal(static, value)
+}
+
+func TestClient_ADS(t *testing.T) {
+	assert := assert2.New(t)
+	requests := make(chan *discoveryRequest, 16)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if pathStreamAggregatedResources != r.URL.Path {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		write := func(message []byte) {
+			frame := make([]byte, 5, 5+len(message))
+			binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+			_, _ = w.Write(append(frame, message...))
+			w.(http.Flusher).Flush()
+		}
+		header := make([]byte, 5)
+		for {
+			if _, err := io.ReadFull(r.Body, header); nil != err {
+				return
+			}
+			message := make([]byte, binary.BigEndian.Uint32(header[1:]))
+			if _, err := io.ReadFull(r.Body, message); nil != err {
+				return
+			}
+			req := new(discoveryRequest)
+			_ = proto.Unmarshal(message, req)
+			requests <- req
+			switch {
+			case TypeUrlCluster == req.TypeUrl && "" == req.ResponseNonce:
+				write(responseOf(TypeUrlCluster, "v1", "n1", &cluster{Name: "reviews", Type: clusterTypeEds}))
+			case TypeUrlClusterLoadAssignment == req.TypeUrl && "" == req.ResponseNonce:
+				write(responseOf(TypeUrlClusterLoadAssignment, "e1", "n2",
+					assignmentOf("reviews", map[uint32][]*lbEndpoint{0: {lbEndpointOf("10.0.0.1", 9080, 1, 0)}})))
+			}
+		}
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(err)
+	server := &http.Server{Handler: h2c.NewHandler(http.HandlerFunc(handler), new(http2.Server))}
+	go server.Serve(listener)
+	defer server.Close()
+
+	updates := make(chan []Cluster, 4)
+	client := NewClient(Options{Address: listener.Addr().String(), NodeId: "flux~test"}, func(clusters []Cluster) {
+		updates <- clusters
+	})
+	assert.NoError(client.Startup())
+	defer client.Shutdown(context.Background())
+	next := func() []Cluster {
+		select {
+		case clusters := <-updates:
+			return clusters
+		case <-time.After(5 * time.Second):
+			t.Fatal("xds update timeout")
+			return nil
+		}
+	}
+	assert.Equal([]Cluster{{Name: "reviews", LbPolicy: LbPolicyRoundRobin}}, next())
+	assert.Equal([]Cluster{{Name: "reviews", LbPolicy: LbPolicyRoundRobin,
+		Endpoints: []Endpoint{{Address: "10.0.0.1:9080", Weight: 1}}}}, next())
+	// 订阅、ACK的顺序
+	nd := &node{Id: "flux~test", UserAgentName: "flux"}
+	expected := []*discoveryRequest{
+		{TypeUrl: TypeUrlCluster, Node: nd},
+		{TypeUrl: TypeUrlCluster, Node: nd, VersionInfo: "v1", ResponseNonce: "n1"},
+		{TypeUrl: TypeUrlClusterLoadAssignment, Node: nd, ResourceNames: []string{"reviews"}},
+		{TypeUrl: TypeUrlClusterLoadAssignment, Node: nd, ResourceNames: []string{"reviews"}, VersionInfo: "e1", ResponseNonce: "n2"},
+	}
+	for _, e := range expected {
+		select {
+		case req := <-requests:
+			assert.Equal(e, req)
+		case <-time.After(5 * time.Second):
+			t.Fatal("xds request timeout")
+		}
+	}
+}
@@ -77,6 +77,32 @@ func (r *ZookeeperRetriever) Create(path string) error {
 	return err
 }
 
+// Children 返回指定Path的子节点名称列表
+func (r *ZookeeperRetriever) Children(path string) ([]string, error) {
+	children, _, err := r.conn.Children(path)
+	return children, err
+}
+
+// GetData 返回指定Path节点的数据
+func (r *ZookeeperRetriever) GetData(path string) ([]byte, error) {
+	data, _, err := r.conn.Get(path)
+	return data, err
+}
+
+// SetData 设置指定Path节点的数据；节点不存在时创建
+func (r *ZookeeperRetriever) SetData(path string, data []byte) error {
+	exists, stat, err := r.conn.Exists(path)
+	if nil != err {
+		return err
+	}
+	if !exists {
+		_, err = r.conn.Create(path, data, 0, zk.WorldACL(zk.PermAll))
+		return err
+	}
+	_, err = r.conn.Set(path, data, stat.Version)
+	return err
+}
+
 func (r *ZookeeperRetriever) AddChildrenNodeChangedListener(groupId, parentNodePath string, nodeChangedListener remoting.NodeChangedListener) error {
 	if init, err := r.setupListener(groupId, parentNodePath, nodeChangedListener); nil != err {
 		return err
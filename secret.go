@@ -0,0 +1,15 @@
+package flux
+
+// SecretProvider 密钥提供接口；根据KeyId查找加解密、签名等功能所使用的密钥数据。
+// 通过KeyId区分不同密钥，以支持密钥轮换。
+type SecretProvider interface {
+	// LoadSecret 根据KeyId查找密钥数据；密钥不存在时返回错误。
+	LoadSecret(keyId string) (secret []byte, err error)
+}
+
+// SecretProviderFunc 函数形式的SecretProvider实现
+type SecretProviderFunc func(keyId string) (secret []byte, err error)
+
+func (f SecretProviderFunc) LoadSecret(keyId string) ([]byte, error) {
+	return f(keyId)
+}
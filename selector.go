@@ -8,3 +8,9 @@ type Activated struct {
 type Selector interface {
 	Select(ctx Context) Activated
 }
+
+// StaticSelector 可选接口：声明Selector可能选中的全部FilterId，用于部署前的配置校验
+type StaticSelector interface {
+	Selector
+	FilterIds() []string
+}
@@ -16,6 +16,12 @@ func (s ShutdownArray) Len() int           { return len(s) }
 func (s ShutdownArray) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 func (s ShutdownArray) Less(i, j int) bool { return orderOf(s[i]) < orderOf(s[j]) }
 
+type WarmupArray []flux.Warmer
+
+func (s WarmupArray) Len() int           { return len(s) }
+func (s WarmupArray) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s WarmupArray) Less(i, j int) bool { return orderOf(s[i]) < orderOf(s[j]) }
+
 func orderOf(v interface{}) int {
 	if v, ok := v.(flux.Orderer); ok {
 		return v.Order()
@@ -0,0 +1,776 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"github.com/spf13/cast"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AsyncConfigKeyEnable        = "enable"
+	AsyncConfigKeyWorkers       = "workers"
+	AsyncConfigKeyQueueSize     = "queue-size"
+	AsyncConfigKeyResultTTL     = "result-ttl"
+	AsyncConfigKeyStatusPath    = "status-path"
+	AsyncConfigKeyMaxResultSize = "max-result-size"
+	AsyncConfigKeyResultPath    = "result-path"
+	AsyncConfigKeyDeadline      = "deadline"
+	AsyncConfigKeyRetryAfter    = "retry-after"
+	AsyncConfigKeyCallback      = "callback"
+)
+
+const (
+	// Endpoint扩展属性：开启异步调用，后端调用入队后立即返回202和调用ID
+	EndpointExtKeyAsyncInvoke = "async-invoke"
+	// Endpoint扩展属性：异步调用的截止时间，例如 "30m"；默认为配置项 async.deadline
+	EndpointExtKeyAsyncDeadline = "async-deadline"
+)
+
+const (
+	AsyncStatusPending   = "PENDING"
+	AsyncStatusRunning   = "RUNNING"
+	AsyncStatusSucceeded = "SUCCEEDED"
+	AsyncStatusFailed    = "FAILED"
+	AsyncStatusTimeout   = "TIMEOUT"
+)
+
+// AsyncInvocation 异步调用的状态和结果
+type AsyncInvocation struct {
+	Id          string         `json:"id"`
+	RequestId   string         `json:"requestId"`
+	Status      string         `json:"status"`
+	StatusCode  int            `json:"statusCode,omitempty"`
+	ContentType string         `json:"contentType,omitempty"`
+	Body        interface{}    `json:"body,omitempty"`
+	ErrorCode   string         `json:"errorCode,omitempty"`
+	Error       string         `json:"error,omitempty"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	Deadline    time.Time      `json:"deadline"`
+	CompletedAt *time.Time     `json:"completedAt,omitempty"`
+	Callback    *AsyncCallback `json:"callback,omitempty"`
+	// 提交调用的Endpoint路由Key（METHOD#pattern）；查询调用时执行该Endpoint的认证Filter
+	Endpoint string `json:"endpoint"`
+	// 提交调用的认证主体；非空时只有相同主体可以查询调用状态和结果
+	Owner string `json:"owner,omitempty"`
+}
+
+// Completed 返回异步调用是否已结束
+func (inv AsyncInvocation) Completed() bool {
+	return AsyncStatusPending != inv.Status && AsyncStatusRunning != inv.Status
+}
+
+// AsyncInvocationStore 异步调用状态存储；默认为进程内存储，多实例部署时可替换为共享存储
+type AsyncInvocationStore interface {
+	// Store 保存异步调用状态
+	Store(inv AsyncInvocation) error
+	// Load 查询异步调用状态，返回是否存在标识
+	Load(id string) (AsyncInvocation, bool, error)
+}
+
+var _ AsyncInvocationStore = new(MemoryAsyncInvocationStore)
+
+// MemoryAsyncInvocationStore 进程内的异步调用状态存储；记录在保存 ttl 时间后过期
+type MemoryAsyncInvocationStore struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	records map[string]memoryAsyncRecord
+	swept   time.Time
+}
+
+type memoryAsyncRecord struct {
+	inv     AsyncInvocation
+	expires time.Time
+}
+
+func NewMemoryAsyncInvocationStore(ttl time.Duration) *MemoryAsyncInvocationStore {
+	return &MemoryAsyncInvocationStore{
+		ttl:     ttl,
+		records: make(map[string]memoryAsyncRecord, 64),
+		swept:   time.Now(),
+	}
+}
+
+func (m *MemoryAsyncInvocationStore) Store(inv AsyncInvocation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	// 每隔半个 ttl 清理过期记录
+	if now.Sub(m.swept) > m.ttl/2 {
+		for id, record := range m.records {
+			if now.After(record.expires) {
+				delete(m.records, id)
+			}
+		}
+		m.swept = now
+	}
+	m.records[inv.Id] = memoryAsyncRecord{inv: inv, expires: now.Add(m.ttl)}
+	return nil
+}
+
+func (m *MemoryAsyncInvocationStore) Load(id string) (AsyncInvocation, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	record, ok := m.records[id]
+	if !ok || time.Now().After(record.expires) {
+		return AsyncInvocation{}, false, nil
+	}
+	return record.inv, true, nil
+}
+
+// asyncInvoker 异步调用：后端调用在脱离请求生命周期的Context中由Worker执行，截止时间后取消调用；
+// 请求立即返回202和调用ID，调用状态通过 status-path 查询，调用结果通过 result-path 按原始响应返回。
+type asyncInvoker struct {
+	tasks         chan *asyncTask
+	store         AsyncInvocationStore
+	statusPath    string
+	resultPath    string
+	deadline      time.Duration
+	retryAfter    time.Duration
+	maxResultSize int64
+	callback      *asyncCallbackNotifier
+	extensions    *ext.Registry
+	stop          chan struct{}
+	wg            sync.WaitGroup
+	once          sync.Once
+}
+
+type asyncTask struct {
+	inv     AsyncInvocation
+	ctx     *WrappedContext
+	backend flux.BackendTransport
+}
+
+func newAsyncInvoker(config *flux.Configuration, extensions *ext.Registry) *asyncInvoker {
+	config.SetDefaults(map[string]interface{}{
+		AsyncConfigKeyEnable:        false,
+		AsyncConfigKeyWorkers:       64,
+		AsyncConfigKeyQueueSize:     1024,
+		AsyncConfigKeyResultTTL:     time.Minute * 10,
+		AsyncConfigKeyStatusPath:    "/async-invocations/:id",
+		AsyncConfigKeyMaxResultSize: 1024 * 1024,
+		AsyncConfigKeyResultPath:    "/async-invocations/:id/result",
+		AsyncConfigKeyDeadline:      time.Minute * 5,
+		AsyncConfigKeyRetryAfter:    time.Second,
+	})
+	if !config.GetBool(AsyncConfigKeyEnable) {
+		return nil
+	}
+	invoker := &asyncInvoker{
+		tasks:         make(chan *asyncTask, config.GetInt(AsyncConfigKeyQueueSize)),
+		store:         NewMemoryAsyncInvocationStore(config.GetDuration(AsyncConfigKeyResultTTL)),
+		statusPath:    config.GetString(AsyncConfigKeyStatusPath),
+		resultPath:    config.GetString(AsyncConfigKeyResultPath),
+		deadline:      config.GetDuration(AsyncConfigKeyDeadline),
+		retryAfter:    config.GetDuration(AsyncConfigKeyRetryAfter),
+		maxResultSize: config.GetInt64(AsyncConfigKeyMaxResultSize),
+		stop:          make(chan struct{}),
+		extensions:    extensions,
+	}
+	invoker.callback = newAsyncCallbackNotifier(config.Sub(AsyncConfigKeyCallback), invoker)
+	workers := config.GetInt(AsyncConfigKeyWorkers)
+	if workers <= 0 {
+		workers = 64
+	}
+	for i := 0; i < workers; i++ {
+		invoker.wg.Add(1)
+		go invoker.work()
+	}
+	logger.Infow("Async invoke enabled", "workers", workers, "queue-size", cap(invoker.tasks),
+		"status-path", invoker.statusPath, "result-path", invoker.resultPath, "deadline", invoker.deadline)
+	return invoker
+}
+
+// enabledFor 返回Endpoint是否开启异步调用
+func (a *asyncInvoker) enabledFor(endpoint flux.Endpoint) bool {
+	return nil != a && endpoint.ExtBool(EndpointExtKeyAsyncInvoke)
+}
+
+// submit 复制请求数据到独立的Context并提交后端调用，在当前请求的响应中写入202和调用ID
+func (a *asyncInvoker) submit(ctx *WrappedContext, backend flux.BackendTransport) *flux.ServeError {
+	callback, serr := a.callback.callbackOf(ctx)
+	if nil != serr {
+		return serr
+	}
+	detached, err := detachContext(ctx)
+	if nil != err {
+		return &flux.ServeError{
+			StatusCode: flux.StatusBadRequest,
+			ErrorCode:  flux.ErrorCodeRequestInvalid,
+			Message:    flux.ErrorMessageAsyncInvokeRequest,
+			Internal:   err,
+		}
+	}
+	now := time.Now()
+	task := &asyncTask{
+		inv: AsyncInvocation{
+			Id:        newAsyncInvocationId(),
+			RequestId: ctx.RequestId(),
+			Status:    AsyncStatusPending,
+			CreatedAt: now,
+			Deadline:  now.Add(a.deadlineOf(ctx.Endpoint())),
+			Callback:  callback,
+			Endpoint:  fmt.Sprintf("%s#%s", strings.ToUpper(ctx.Endpoint().HttpMethod), ctx.Endpoint().HttpPattern),
+			Owner:     ctx.GetAttributeString(flux.XJwtSubject, ""),
+		},
+		ctx:     detached,
+		backend: backend,
+	}
+	if err := a.store.Store(task.inv); nil != err {
+		return a.storeFailed(err)
+	}
+	select {
+	case a.tasks <- task:
+	default:
+		detached.Release()
+		return &flux.ServeError{
+			StatusCode: http.StatusServiceUnavailable,
+			ErrorCode:  flux.ErrorCodeGatewayCircuited,
+			Message:    flux.ErrorMessageAsyncInvokeOverflow,
+		}
+	}
+	location := a.locationOf(task.inv.Id)
+	logger.TraceContext(ctx).Infow("Async invoke submitted", "invocation-id", task.inv.Id)
+	response := ctx.Response()
+	response.SetStatusCode(http.StatusAccepted)
+	response.SetHeader(flux.HeaderLocation, location)
+	response.SetHeader(flux.HeaderRetryAfter, a.retryAfterSeconds())
+	response.SetBody(map[string]interface{}{
+		"id":       task.inv.Id,
+		"status":   task.inv.Status,
+		"location": location,
+		"result":   a.resultLocationOf(task.inv.Id),
+		"deadline": task.inv.Deadline,
+	})
+	return nil
+}
+
+// deadlineOf 返回Endpoint的异步调用截止时间
+func (a *asyncInvoker) deadlineOf(endpoint flux.Endpoint) time.Duration {
+	if v, ok := endpoint.Ext(EndpointExtKeyAsyncDeadline); ok {
+		if d := cast.ToDuration(v); d > 0 {
+			return d
+		}
+	}
+	return a.deadline
+}
+
+// load 查询异步调用状态
+func (a *asyncInvoker) load(id string) (AsyncInvocation, bool, error) {
+	return a.store.Load(id)
+}
+
+func (a *asyncInvoker) work() {
+	defer a.wg.Done()
+	for {
+		select {
+		case <-a.stop:
+			return
+		case task := <-a.tasks:
+			a.run(task)
+		}
+	}
+}
+
+// run 执行后端调用：调用的Context在截止时间后取消；截止时间前未返回的调用记录为超时，
+// 调用实际结束后再释放Context
+func (a *asyncInvoker) run(task *asyncTask) {
+	ctx, inv := task.ctx, task.inv
+	goctx, cancel := context.WithDeadline(context.Background(), inv.Deadline)
+	defer cancel()
+	if webc, ok := ctx.webc.(*detachedWebContext); ok {
+		webc.request = webc.request.WithContext(goctx)
+	}
+	inv.Status = AsyncStatusRunning
+	a.update(ctx, inv)
+	done := make(chan *flux.ServeError, 1)
+	go func() {
+		defer func() {
+			if r := recover(); nil != r {
+				done <- &flux.ServeError{
+					StatusCode: flux.StatusServerError,
+					ErrorCode:  flux.ErrorCodeGatewayInternal,
+					Message:    flux.ErrorMessageAsyncInvokeFailed,
+					Internal:   fmt.Errorf("async invoke panics: %v", r),
+				}
+			}
+		}()
+		done <- task.backend.Exchange(ctx)
+	}()
+	var serr *flux.ServeError
+	select {
+	case serr = <-done:
+		defer ctx.Release()
+	case <-goctx.Done():
+		go func() {
+			<-done
+			ctx.Release()
+		}()
+		completed := time.Now()
+		inv.CompletedAt = &completed
+		inv.Status = AsyncStatusTimeout
+		inv.StatusCode = flux.StatusGatewayTimeout
+		inv.ErrorCode = flux.ErrorCodeGatewayTimeout
+		inv.Error = flux.ErrorMessageAsyncInvokeTimeout
+		logger.TraceContext(ctx).Warnw("Async invoke timeout", "invocation-id", inv.Id, "deadline", inv.Deadline)
+		a.update(ctx, inv)
+		a.callback.notify(inv)
+		return
+	}
+	completed := time.Now()
+	inv.CompletedAt = &completed
+	if nil != serr {
+		inv.Status = AsyncStatusFailed
+		inv.StatusCode = serr.StatusCode
+		inv.ErrorCode = serr.GetErrorCode()
+		inv.Error = serr.Message
+		logger.TraceContext(ctx).Warnw("Async invoke failed", "invocation-id", inv.Id, "error", serr)
+	} else {
+		inv.Status = AsyncStatusSucceeded
+		if webc, ok := ctx.webc.(*detachedWebContext); ok && ctx.Streamed() {
+			inv.StatusCode = webc.status
+			inv.ContentType = webc.header.Get(flux.HeaderContentType)
+			inv.Body = a.resultOf(bytes.NewReader(webc.output.Bytes()))
+		} else {
+			inv.StatusCode = ctx.Response().StatusCode()
+			inv.ContentType = ctx.Response().HeaderValues().Get(flux.HeaderContentType)
+			inv.Body = a.resultOf(ctx.Response().Body())
+		}
+	}
+	a.update(ctx, inv)
+	a.callback.notify(inv)
+}
+
+func (a *asyncInvoker) update(ctx flux.Context, inv AsyncInvocation) {
+	if err := a.store.Store(inv); nil != err {
+		logger.TraceContext(ctx).Errorw("Async invoke, store invocation", "invocation-id", inv.Id, "error", err)
+	}
+}
+
+// resultOf 读取流和字节类型的响应数据；JSON数据原样保存，其它数据保存为字符串
+func (a *asyncInvoker) resultOf(body interface{}) interface{} {
+	var data []byte
+	switch v := body.(type) {
+	case io.Reader:
+		if closer, ok := v.(io.Closer); ok {
+			defer closer.Close()
+		}
+		data, _ = ioutil.ReadAll(io.LimitReader(v, a.maxResultSize))
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return body
+	}
+	if json.Valid(data) {
+		return json.RawMessage(data)
+	}
+	return string(data)
+}
+
+func (a *asyncInvoker) locationOf(id string) string {
+	return strings.Replace(a.statusPath, ":id", id, 1)
+}
+
+func (a *asyncInvoker) resultLocationOf(id string) string {
+	return strings.Replace(a.resultPath, ":id", id, 1)
+}
+
+func (a *asyncInvoker) retryAfterSeconds() string {
+	seconds := int(a.retryAfter / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
+}
+
+func (a *asyncInvoker) storeFailed(err error) *flux.ServeError {
+	return &flux.ServeError{
+		StatusCode: flux.StatusServerError,
+		ErrorCode:  flux.ErrorCodeGatewayInternal,
+		Message:    flux.ErrorMessageAsyncInvocationStore,
+		Internal:   err,
+	}
+}
+
+// shutdown 停止Worker和回调重试；队列中未执行的调用不再执行
+func (a *asyncInvoker) shutdown() {
+	a.once.Do(func() {
+		close(a.stop)
+	})
+	a.wg.Wait()
+}
+
+// SetAsyncInvocationStore 设置异步调用状态存储；默认为进程内存储。需要在 Initial 之前调用
+func (s *HttpServeEngine) SetAsyncInvocationStore(store AsyncInvocationStore) {
+	s.asyncStore = store
+}
+
+// LoadAsyncInvocation 查询异步调用状态；未开启异步调用时返回不存在
+func (s *HttpServeEngine) LoadAsyncInvocation(id string) (AsyncInvocation, bool, error) {
+	if nil == s.router.asyncInvoker {
+		return AsyncInvocation{}, false, nil
+	}
+	return s.router.asyncInvoker.load(id)
+}
+
+// newAsyncStatusHandler 查询异步调用状态
+func (s *HttpServeEngine) newAsyncStatusHandler() flux.WebHandler {
+	return func(webc flux.WebContext) error {
+		inv, serr := s.loadAsyncInvocationOf(webc)
+		if nil != serr {
+			return serr
+		}
+		data, serr := SerializeWith(serverWriterSerializer, inv)
+		if nil != serr {
+			return serr
+		}
+		return WriteHttpResponse(webc, flux.StatusOK, serverResponseContentType, data)
+	}
+}
+
+// newAsyncResultHandler 按原始响应返回异步调用结果：调用未结束时返回202和Retry-After；调用失败或超时时返回调用的错误
+func (s *HttpServeEngine) newAsyncResultHandler() flux.WebHandler {
+	return func(webc flux.WebContext) error {
+		invoker := s.router.asyncInvoker
+		id := webc.PathValue("id")
+		inv, serr := s.loadAsyncInvocationOf(webc)
+		if nil != serr {
+			return serr
+		}
+		if !inv.Completed() {
+			webc.SetResponseHeader(flux.HeaderLocation, invoker.locationOf(id))
+			webc.SetResponseHeader(flux.HeaderRetryAfter, invoker.retryAfterSeconds())
+			data, serr := SerializeWith(serverWriterSerializer, inv)
+			if nil != serr {
+				return serr
+			}
+			return WriteHttpResponse(webc, http.StatusAccepted, serverResponseContentType, data)
+		}
+		if AsyncStatusSucceeded != inv.Status {
+			return &flux.ServeError{
+				StatusCode: inv.StatusCode,
+				ErrorCode:  inv.ErrorCode,
+				Message:    inv.Error,
+			}
+		}
+		contentType := inv.ContentType
+		var data []byte
+		switch body := inv.Body.(type) {
+		case json.RawMessage:
+			data = body
+		case string:
+			data = []byte(body)
+		default:
+			serialized, serr := SerializeWith(serverWriterSerializer, body)
+			if nil != serr {
+				return serr
+			}
+			data, contentType = serialized, serverResponseContentType
+		}
+		if "" == contentType {
+			contentType = serverResponseContentType
+		}
+		return WriteHttpResponse(webc, inv.StatusCode, contentType, data)
+	}
+}
+
+// loadAsyncInvocationOf 查询请求的异步调用，并校验请求者是否可以访问：查询请求执行提交调用的Endpoint的Filter链，
+// 调用记录了认证主体时，请求的认证主体必须相同；不存在或无权访问的调用均返回404
+func (s *HttpServeEngine) loadAsyncInvocationOf(webc flux.WebContext) (AsyncInvocation, *flux.ServeError) {
+	invoker := s.router.asyncInvoker
+	notFound := &flux.ServeError{
+		StatusCode: flux.StatusNotFound,
+		ErrorCode:  flux.ErrorCodeRequestNotFound,
+		Message:    flux.ErrorMessageAsyncInvocationNotFound,
+	}
+	inv, ok, err := invoker.load(webc.PathValue("id"))
+	if nil != err {
+		return AsyncInvocation{}, invoker.storeFailed(err)
+	}
+	if !ok {
+		return AsyncInvocation{}, notFound
+	}
+	mve, ok := s.endpoints.Select(inv.Endpoint)
+	if !ok {
+		return AsyncInvocation{}, notFound
+	}
+	endpoint := mve.Latest()
+	if nil == endpoint {
+		return AsyncInvocation{}, notFound
+	}
+	requestId := cast.ToString(webc.GetValue(flux.HeaderXRequestId))
+	ctxw := s.acquireContext(requestId, s.trustedProxies.ClientIP(webc), webc, endpoint)
+	defer s.releaseContext(ctxw)
+	if serr := s.router.authenticate(ctxw); nil != serr {
+		return AsyncInvocation{}, serr
+	}
+	if "" != inv.Owner && ctxw.GetAttributeString(flux.XJwtSubject, "") != inv.Owner {
+		logger.TraceContext(ctxw).Warnw("Async invocation, owner mismatch", "invocation-id", inv.Id)
+		return AsyncInvocation{}, notFound
+	}
+	return inv, nil
+}
+
+func newAsyncInvocationId() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// detachContext 复制请求数据和Context属性到新的Context；原Context和WebContext在请求结束后被回收，不能在Worker中使用
+func detachContext(ctx *WrappedContext) (*WrappedContext, error) {
+	webc, err := newDetachedWebContext(ctx.webc)
+	if nil != err {
+		return nil, err
+	}
+	endpoint := *ctx.endpoint
+	detached := NewContextWrapper().(*WrappedContext)
+	detached.Reattach(ctx.requestId, ctx.clientIp, webc, &endpoint)
+	for k, v := range ctx.attributes {
+		detached.attributes[k] = v
+	}
+	for k, v := range ctx.values {
+		detached.values[k] = v
+	}
+	detached.ctxLogger = ctx.ctxLogger
+	detached.extensions = ctx.extensions
+	return detached, nil
+}
+
+var _ flux.WebContext = new(detachedWebContext)
+
+// detachedWebContext 复制请求数据的WebContext，不关联客户端连接：请求的Context不会被取消，响应数据写入内存
+type detachedWebContext struct {
+	request    *http.Request
+	body       []byte
+	pathValues url.Values
+	formValues url.Values
+	values     map[string]interface{}
+	header     http.Header
+	status     int
+	output     bytes.Buffer
+}
+
+func newDetachedWebContext(webc flux.WebContext) (*detachedWebContext, error) {
+	reader, err := webc.RequestBodyReader()
+	if nil != err {
+		return nil, err
+	}
+	var body []byte
+	if nil != reader {
+		body, err = ioutil.ReadAll(reader)
+		_ = reader.Close()
+		if nil != err {
+			return nil, err
+		}
+	}
+	var request *http.Request
+	if raw, err := webc.HttpRequest(); nil == err {
+		request = raw.Clone(context.Background())
+	} else {
+		u, _ := webc.RequestURL()
+		header, _ := webc.HeaderValues()
+		request = &http.Request{Method: webc.Method(), Host: webc.Host(), RequestURI: webc.RequestURI(),
+			URL: u, Header: header.Clone()}
+		request = request.WithContext(context.Background())
+	}
+	if nil != request.URL {
+		u := *request.URL
+		request.URL = &u
+	}
+	request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	request.ContentLength = int64(len(body))
+	request.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	return &detachedWebContext{
+		request:    request,
+		body:       body,
+		pathValues: copyValues(webc.PathValues()),
+		formValues: copyValues(webc.FormValues()),
+		values:     make(map[string]interface{}, 4),
+		header:     http.Header{},
+	}, nil
+}
+
+func copyValues(values url.Values) url.Values {
+	out := make(url.Values, len(values))
+	for k, v := range values {
+		out[k] = append([]string(nil), v...)
+	}
+	return out
+}
+
+func (c *detachedWebContext) Method() string {
+	return c.request.Method
+}
+
+func (c *detachedWebContext) Host() string {
+	return c.request.Host
+}
+
+func (c *detachedWebContext) UserAgent() string {
+	return c.request.UserAgent()
+}
+
+func (c *detachedWebContext) RequestURI() string {
+	return c.request.RequestURI
+}
+
+func (c *detachedWebContext) RequestURL() (*url.URL, bool) {
+	return c.request.URL, true
+}
+
+func (c *detachedWebContext) RequestBodyReader() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(c.body)), nil
+}
+
+func (c *detachedWebContext) RequestRewrite(method string, path string) {
+	if "" != method {
+		c.request.Method = method
+	}
+	if "" != path {
+		c.request.URL.Path = path
+	}
+}
+
+func (c *detachedWebContext) SetRequestHeader(name, value string) {
+	c.request.Header.Set(name, value)
+}
+
+func (c *detachedWebContext) AddRequestHeader(name, value string) {
+	c.request.Header.Add(name, value)
+}
+
+func (c *detachedWebContext) RemoveRequestHeader(name string) {
+	c.request.Header.Del(name)
+}
+
+func (c *detachedWebContext) HeaderValues() (http.Header, bool) {
+	return c.request.Header, true
+}
+
+func (c *detachedWebContext) QueryValues() url.Values {
+	return c.request.URL.Query()
+}
+
+func (c *detachedWebContext) PathValues() url.Values {
+	return c.pathValues
+}
+
+func (c *detachedWebContext) FormValues() url.Values {
+	return c.formValues
+}
+
+func (c *detachedWebContext) CookieValues() []*http.Cookie {
+	return c.request.Cookies()
+}
+
+func (c *detachedWebContext) HeaderValue(name string) string {
+	return c.request.Header.Get(name)
+}
+
+func (c *detachedWebContext) QueryValue(name string) string {
+	return c.request.URL.Query().Get(name)
+}
+
+func (c *detachedWebContext) PathValue(name string) string {
+	return c.pathValues.Get(name)
+}
+
+func (c *detachedWebContext) FormValue(name string) string {
+	return c.formValues.Get(name)
+}
+
+func (c *detachedWebContext) CookieValue(name string) (*http.Cookie, bool) {
+	cookie, err := c.request.Cookie(name)
+	return cookie, nil == err
+}
+
+func (c *detachedWebContext) Write(statusCode int, contentType string, data []byte) error {
+	c.status = statusCode
+	c.header.Set(flux.HeaderContentType, contentType)
+	_, err := c.output.Write(data)
+	return err
+}
+
+func (c *detachedWebContext) WriteStream(statusCode int, contentType string, reader io.Reader) error {
+	c.status = statusCode
+	c.header.Set(flux.HeaderContentType, contentType)
+	_, err := io.Copy(&c.output, reader)
+	return err
+}
+
+func (c *detachedWebContext) ResponseHeader() (http.Header, bool) {
+	return c.header, true
+}
+
+func (c *detachedWebContext) GetResponseHeader(name string) string {
+	return c.header.Get(name)
+}
+
+func (c *detachedWebContext) SetResponseHeader(name, value string) {
+	c.header.Set(name, value)
+}
+
+func (c *detachedWebContext) AddResponseHeader(name, value string) {
+	c.header.Add(name, value)
+}
+
+func (c *detachedWebContext) SetResponseWriter(w http.ResponseWriter) error {
+	return flux.ErrHttpResponseNotSupported
+}
+
+func (c *detachedWebContext) SetValue(name string, value interface{}) {
+	c.values[name] = value
+}
+
+func (c *detachedWebContext) GetValue(name string) interface{} {
+	return c.values[name]
+}
+
+func (c *detachedWebContext) HttpRequest() (*http.Request, error) {
+	return c.request, nil
+}
+
+func (c *detachedWebContext) Context() context.Context {
+	return c.request.Context()
+}
+
+func (c *detachedWebContext) HttpResponseWriter() (http.ResponseWriter, error) {
+	return nil, flux.ErrHttpResponseNotSupported
+}
+
+func (c *detachedWebContext) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, flux.ErrHttpHijackNotSupported
+}
+
+func (c *detachedWebContext) RawWebContext() interface{} {
+	return c
+}
+
+func (c *detachedWebContext) RawWebRequest() interface{} {
+	return c.request
+}
+
+func (c *detachedWebContext) RawWebResponse() interface{} {
+	return nil
+}
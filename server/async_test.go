@@ -0,0 +1,107 @@
+package server
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/support"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// asyncTestAuthFilter 测试用认证Filter：从Header读取认证主体，缺少时返回401
+type asyncTestAuthFilter struct{}
+
+func (f *asyncTestAuthFilter) TypeId() string {
+	return "async-test-auth"
+}
+
+func (f *asyncTestAuthFilter) DoFilter(next flux.FilterHandler) flux.FilterHandler {
+	return func(ctx flux.Context) *flux.ServeError {
+		subject := ctx.Request().HeaderValue("X-Test-Subject")
+		if "" == subject {
+			return &flux.ServeError{StatusCode: flux.StatusUnauthorized, ErrorCode: flux.ErrorCodePermissionDenied}
+		}
+		ctx.SetAttribute(flux.XJwtSubject, subject)
+		return next(ctx)
+	}
+}
+
+func newAsyncTestEngine(t *testing.T) *HttpServeEngine {
+	extensions := ext.NewRegistry()
+	extensions.StoreGlobalFilter(new(asyncTestAuthFilter))
+	engine := NewHttpServeEngineOf(extensions, DefaultServerResponseWriter, DefaultServerErrorsWriter)
+	proxies, err := support.NewTrustedProxies(nil)
+	assert2.NoError(t, err)
+	engine.trustedProxies = proxies
+	engine.router.asyncInvoker = &asyncInvoker{
+		store:      NewMemoryAsyncInvocationStore(time.Minute),
+		statusPath: "/async-invocations/:id",
+		resultPath: "/async-invocations/:id/result",
+	}
+	endpoint := &flux.Endpoint{HttpMethod: http.MethodPost, HttpPattern: "/orders", Version: "v1"}
+	engine.endpoints.Register("POST#/orders", endpoint).Update(endpoint.Version, endpoint)
+	return engine
+}
+
+func newAsyncTestWebContext(id, subject string) *detachedWebContext {
+	request := httptest.NewRequest(http.MethodGet, "/async-invocations/"+id, nil)
+	if "" != subject {
+		request.Header.Set("X-Test-Subject", subject)
+	}
+	return &detachedWebContext{
+		request:    request,
+		pathValues: url.Values{"id": []string{id}},
+		formValues: url.Values{},
+		values:     make(map[string]interface{}, 4),
+		header:     http.Header{},
+	}
+}
+
+func TestLoadAsyncInvocationOf(t *testing.T) {
+	assert := assert2.New(t)
+	engine := newAsyncTestEngine(t)
+	store := engine.router.asyncInvoker.store
+	assert.NoError(store.Store(AsyncInvocation{Id: "owned", Status: AsyncStatusPending, Endpoint: "POST#/orders", Owner: "alice"}))
+	assert.NoError(store.Store(AsyncInvocation{Id: "anonymous", Status: AsyncStatusPending, Endpoint: "POST#/orders"}))
+	assert.NoError(store.Store(AsyncInvocation{Id: "removed", Status: AsyncStatusPending, Endpoint: "POST#/removed", Owner: "alice"}))
+	cases := []struct {
+		name    string
+		id      string
+		subject string
+		expect  int
+	}{
+		{name: "owner", id: "owned", subject: "alice", expect: 0},
+		{name: "other subject", id: "owned", subject: "bob", expect: flux.StatusNotFound},
+		{name: "unauthenticated", id: "owned", subject: "", expect: flux.StatusUnauthorized},
+		{name: "anonymous invocation", id: "anonymous", subject: "bob", expect: 0},
+		{name: "endpoint removed", id: "removed", subject: "alice", expect: flux.StatusNotFound},
+		{name: "not found", id: "missing", subject: "alice", expect: flux.StatusNotFound},
+	}
+	for _, c := range cases {
+		inv, serr := engine.loadAsyncInvocationOf(newAsyncTestWebContext(c.id, c.subject))
+		if 0 == c.expect {
+			assert.Nil(serr, c.name)
+			assert.Equal(c.id, inv.Id, c.name)
+		} else if assert.NotNil(serr, c.name) {
+			assert.Equal(c.expect, serr.StatusCode, c.name)
+		}
+	}
+}
+
+func TestAsyncStatusHandler_Owner(t *testing.T) {
+	assert := assert2.New(t)
+	engine := newAsyncTestEngine(t)
+	assert.NoError(engine.router.asyncInvoker.store.Store(AsyncInvocation{
+		Id: "owned", Status: AsyncStatusRunning, Endpoint: "POST#/orders", Owner: "alice"}))
+	status, result := engine.newAsyncStatusHandler(), engine.newAsyncResultHandler()
+	for _, handler := range []flux.WebHandler{status, result} {
+		err := handler(newAsyncTestWebContext("owned", "bob"))
+		if serr, ok := err.(*flux.ServeError); assert.True(ok) {
+			assert.Equal(flux.StatusNotFound, serr.StatusCode)
+		}
+	}
+}
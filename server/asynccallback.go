@@ -0,0 +1,270 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	AsyncCallbackConfigKeyAllowlist   = "allowlist"
+	AsyncCallbackConfigKeyAllowHttp   = "allow-http"
+	AsyncCallbackConfigKeySecretKeyId = "secret-key-id"
+	AsyncCallbackConfigKeyTimeout     = "timeout"
+	AsyncCallbackConfigKeyMaxAttempts = "max-attempts"
+	AsyncCallbackConfigKeyBackoff     = "backoff"
+	AsyncCallbackConfigKeyMaxBackoff  = "max-backoff"
+)
+
+const (
+	// 客户端提交异步调用时指定回调地址的请求头
+	HeaderXAsyncCallbackUrl = "X-Async-Callback-Url"
+	// 回调请求的签名：t=<Unix秒>,v1=<HMAC-SHA256(t + "." + body)的Hex编码>
+	HeaderXAsyncSignature = "X-Async-Signature"
+	// 回调请求的异步调用ID
+	HeaderXAsyncInvocationId = "X-Async-Invocation-Id"
+	// 回调请求的投递次数，从1开始
+	HeaderXAsyncDeliveryAttempt = "X-Async-Delivery-Attempt"
+)
+
+const (
+	AsyncCallbackPending   = "PENDING"
+	AsyncCallbackDelivered = "DELIVERED"
+	AsyncCallbackFailed    = "FAILED"
+)
+
+// AsyncCallback 异步调用结束后的回调投递状态
+type AsyncCallback struct {
+	Url         string     `json:"url"`
+	Status      string     `json:"status"`
+	Attempts    int        `json:"attempts"`
+	LastError   string     `json:"lastError,omitempty"`
+	DeliveredAt *time.Time `json:"deliveredAt,omitempty"`
+}
+
+// asyncCallbackNotifier 异步调用结束后，向客户端指定的回调地址POST调用状态和结果；
+// 回调地址必须匹配白名单，回调请求使用密钥签名，网络错误、429和5xx响应按指数退避重试。
+type asyncCallbackNotifier struct {
+	invoker     *asyncInvoker
+	allowlist   []string
+	allowHttp   bool
+	secretKeyId string
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	client      *http.Client
+}
+
+// newAsyncCallbackNotifier 创建回调投递；未配置回调地址白名单时不开启回调
+func newAsyncCallbackNotifier(config *flux.Configuration, invoker *asyncInvoker) *asyncCallbackNotifier {
+	config.SetDefaults(map[string]interface{}{
+		AsyncCallbackConfigKeyAllowHttp:   false,
+		AsyncCallbackConfigKeyTimeout:     time.Second * 10,
+		AsyncCallbackConfigKeyMaxAttempts: 3,
+		AsyncCallbackConfigKeyBackoff:     time.Second,
+		AsyncCallbackConfigKeyMaxBackoff:  time.Second * 30,
+	})
+	allowlist := make([]string, 0, 4)
+	for _, host := range config.GetStringSlice(AsyncCallbackConfigKeyAllowlist) {
+		if host = strings.ToLower(strings.TrimSpace(host)); "" != host {
+			allowlist = append(allowlist, host)
+		}
+	}
+	if 0 == len(allowlist) {
+		return nil
+	}
+	notifier := &asyncCallbackNotifier{
+		invoker:     invoker,
+		allowlist:   allowlist,
+		allowHttp:   config.GetBool(AsyncCallbackConfigKeyAllowHttp),
+		secretKeyId: config.GetString(AsyncCallbackConfigKeySecretKeyId),
+		maxAttempts: config.GetInt(AsyncCallbackConfigKeyMaxAttempts),
+		backoff:     config.GetDuration(AsyncCallbackConfigKeyBackoff),
+		maxBackoff:  config.GetDuration(AsyncCallbackConfigKeyMaxBackoff),
+		client: &http.Client{
+			Timeout: config.GetDuration(AsyncCallbackConfigKeyTimeout),
+			// 不跟随重定向，避免回调被转发到白名单以外的地址
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+	if notifier.maxAttempts < 1 {
+		notifier.maxAttempts = 1
+	}
+	if "" == notifier.secretKeyId {
+		logger.Warn("Async callback enabled without secret-key-id, callbacks are not signed")
+	}
+	logger.Infow("Async callback enabled", "allowlist", allowlist, "max-attempts", notifier.maxAttempts)
+	return notifier
+}
+
+// callbackOf 返回请求指定的回调地址；未指定时返回nil，地址无效或不在白名单中时返回400错误
+func (n *asyncCallbackNotifier) callbackOf(ctx flux.Context) (*AsyncCallback, *flux.ServeError) {
+	value := strings.TrimSpace(ctx.Request().HeaderValue(HeaderXAsyncCallbackUrl))
+	if "" == value {
+		return nil, nil
+	}
+	if nil == n {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusBadRequest,
+			ErrorCode:  flux.ErrorCodeRequestInvalid,
+			Message:    flux.ErrorMessageAsyncCallbackNotAllowed,
+			Internal:   fmt.Errorf("async callback is not enabled"),
+		}
+	}
+	u, err := url.Parse(value)
+	if nil != err || !u.IsAbs() || "" == u.Hostname() || nil != u.User {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusBadRequest,
+			ErrorCode:  flux.ErrorCodeRequestInvalid,
+			Message:    flux.ErrorMessageAsyncCallbackInvalid,
+			Internal:   fmt.Errorf("invalid callback url: %s", value),
+		}
+	}
+	if !n.allowed(u) {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusBadRequest,
+			ErrorCode:  flux.ErrorCodeRequestInvalid,
+			Message:    flux.ErrorMessageAsyncCallbackNotAllowed,
+			Internal:   fmt.Errorf("callback url not in allowlist: %s", value),
+		}
+	}
+	return &AsyncCallback{Url: u.String(), Status: AsyncCallbackPending}, nil
+}
+
+// allowed 返回回调地址是否匹配白名单：白名单项为主机名，或以 "*." 开头匹配其子域名；默认只允许https
+func (n *asyncCallbackNotifier) allowed(u *url.URL) bool {
+	scheme := strings.ToLower(u.Scheme)
+	if "https" != scheme && ("http" != scheme || !n.allowHttp) {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, pattern := range n.allowlist {
+		if host == pattern {
+			return true
+		}
+		if strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// notify 异步投递回调；调用未指定回调地址时不处理
+func (n *asyncCallbackNotifier) notify(inv AsyncInvocation) {
+	if nil == n || nil == inv.Callback {
+		return
+	}
+	n.invoker.wg.Add(1)
+	go func() {
+		defer n.invoker.wg.Done()
+		n.deliver(inv)
+	}()
+}
+
+// deliver 投递回调，每次投递后更新调用的回调状态；网关停止时放弃剩余的重试
+func (n *asyncCallbackNotifier) deliver(inv AsyncInvocation) {
+	callback := *inv.Callback
+	payload := inv
+	payload.Callback = nil
+	data, serr := SerializeWith(serverWriterSerializer, payload)
+	if nil != serr {
+		callback.Status, callback.LastError = AsyncCallbackFailed, serr.Error()
+		n.store(inv, callback)
+		return
+	}
+	backoff := n.backoff
+	for {
+		callback.Attempts++
+		retryable, err := n.post(callback.Url, inv.Id, callback.Attempts, data)
+		if nil == err {
+			delivered := time.Now()
+			callback.Status, callback.LastError, callback.DeliveredAt = AsyncCallbackDelivered, "", &delivered
+			n.store(inv, callback)
+			return
+		}
+		callback.LastError = err.Error()
+		logger.Trace(inv.RequestId).Warnw("Async callback, deliver failed", "invocation-id", inv.Id,
+			"url", callback.Url, "attempts", callback.Attempts, "error", err)
+		if !retryable || callback.Attempts >= n.maxAttempts {
+			callback.Status = AsyncCallbackFailed
+			n.store(inv, callback)
+			return
+		}
+		n.store(inv, callback)
+		select {
+		case <-n.invoker.stop:
+			callback.Status = AsyncCallbackFailed
+			n.store(inv, callback)
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > n.maxBackoff {
+			backoff = n.maxBackoff
+		}
+	}
+}
+
+// post 发送一次回调请求，返回失败时是否可重试
+func (n *asyncCallbackNotifier) post(target, id string, attempt int, data []byte) (bool, error) {
+	request, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(data))
+	if nil != err {
+		return false, err
+	}
+	request.Header.Set(flux.HeaderContentType, serverResponseContentType)
+	request.Header.Set(flux.HeaderServer, "Flux/Gateway")
+	request.Header.Set(HeaderXAsyncInvocationId, id)
+	request.Header.Set(HeaderXAsyncDeliveryAttempt, strconv.Itoa(attempt))
+	if "" != n.secretKeyId {
+		signature, err := n.sign(time.Now(), data)
+		if nil != err {
+			return false, err
+		}
+		request.Header.Set(HeaderXAsyncSignature, signature)
+	}
+	resp, err := n.client.Do(request)
+	if nil != err {
+		return true, err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retryable := resp.StatusCode >= http.StatusInternalServerError || http.StatusTooManyRequests == resp.StatusCode
+	return retryable, fmt.Errorf("callback response status: %d", resp.StatusCode)
+}
+
+// sign 使用密钥对时间戳和回调数据签名；接收方校验签名并拒绝时间戳过旧的回调，防止重放
+func (n *asyncCallbackNotifier) sign(now time.Time, data []byte) (string, error) {
+	provider := n.invoker.extensions.LoadSecretProvider()
+	if nil == provider {
+		return "", fmt.Errorf("secret provider not found")
+	}
+	secret, err := provider.LoadSecret(n.secretKeyId)
+	if nil != err {
+		return "", err
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(data)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (n *asyncCallbackNotifier) store(inv AsyncInvocation, callback AsyncCallback) {
+	inv.Callback = &callback
+	if err := n.invoker.store.Store(inv); nil != err {
+		logger.Trace(inv.RequestId).Errorw("Async callback, store invocation", "invocation-id", inv.Id, "error", err)
+	}
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	BackendInitConfigKeyParallel         = "parallel"
+	BackendInitConfigKeyProgressInterval = "progress-interval"
+	BackendInitConfigKeySlowThreshold    = "slow-threshold"
+)
+
+const (
+	ComponentKindBackend    = "backend"
+	ComponentKindCredential = "credential"
+	ComponentKindFilter     = "filter"
+	ComponentKindHook       = "hook"
+)
+
+// ComponentInitTiming 组件初始化耗时
+type ComponentInitTiming struct {
+	Kind    string        `json:"kind"`
+	Name    string        `json:"name"`
+	Type    string        `json:"type"`
+	Elapsed time.Duration `json:"-"`
+	Elapses string        `json:"elapsed"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// backendInitOptions 启动时Backend初始化：各协议Backend（Dubbo、Kafka等）的Init并行执行，
+// 按固定间隔输出初始化进度和未完成的Backend；每个组件的初始化耗时可通过 /debug/init-timings 查询。
+type backendInitOptions struct {
+	parallel         bool
+	progressInterval time.Duration
+	slowThreshold    time.Duration
+}
+
+func newBackendInitOptions(config *flux.Configuration) *backendInitOptions {
+	config.SetDefaults(map[string]interface{}{
+		BackendInitConfigKeyParallel:         true,
+		BackendInitConfigKeyProgressInterval: time.Second * 5,
+		BackendInitConfigKeySlowThreshold:    time.Second,
+	})
+	return &backendInitOptions{
+		parallel:         config.GetBool(BackendInitConfigKeyParallel),
+		progressInterval: config.GetDuration(BackendInitConfigKeyProgressInterval),
+		slowThreshold:    config.GetDuration(BackendInitConfigKeySlowThreshold),
+	}
+}
+
+// initTimings 记录组件初始化耗时；并发安全
+type initTimings struct {
+	mu    sync.Mutex
+	items []ComponentInitTiming
+}
+
+func (t *initTimings) record(timing ComponentInitTiming) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.items = append(t.items, timing)
+}
+
+// snapshot 返回按耗时降序排列的初始化耗时
+func (t *initTimings) snapshot() []ComponentInitTiming {
+	t.mu.Lock()
+	out := make([]ComponentInitTiming, len(t.items))
+	copy(out, t.items)
+	t.mu.Unlock()
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Elapsed > out[j].Elapsed
+	})
+	return out
+}
+
+// initialComponent 执行组件的Init并记录耗时；不注册组件的生命周期Hook
+func (r *Router) initialComponent(kind, name string, ref interface{}, config *flux.Configuration) error {
+	start := time.Now()
+	var err error
+	if init, ok := ref.(flux.Initializer); ok {
+		err = init.Init(config)
+	}
+	timing := ComponentInitTiming{
+		Kind: kind, Name: name, Type: reflect.TypeOf(ref).String(),
+		Elapsed: time.Since(start), Elapses: time.Since(start).String(),
+	}
+	if nil != err {
+		timing.Error = err.Error()
+	}
+	r.timings.record(timing)
+	if timing.Elapsed >= r.initOpts.slowThreshold {
+		logger.Warnw("Router component init slow", "kind", kind, "name", name, "type", timing.Type, "elapsed", timing.Elapses)
+	} else {
+		logger.Infow("Router component init done", "kind", kind, "name", name, "elapsed", timing.Elapses)
+	}
+	return err
+}
+
+type backendInitTask struct {
+	proto   string
+	backend flux.BackendTransport
+	config  *flux.Configuration
+}
+
+// initialBackends 初始化全部Backend：配置对象和Hook注册在当前协程中执行，Init按配置并行执行；
+// 返回按协议名排序的首个初始化错误。
+func (r *Router) initialBackends() error {
+	tasks := make([]backendInitTask, 0, 16)
+	for proto, backend := range r.extensions.LoadBackendTransports() {
+		ns := "BACKEND." + proto
+		logger.Infow("Load backend", "proto", proto, "type", reflect.TypeOf(backend), "config-ns", ns)
+		tasks = append(tasks, backendInitTask{proto: proto, backend: backend, config: r.config.Sub(ns)})
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].proto < tasks[j].proto
+	})
+	errs := make([]error, len(tasks))
+	if r.initOpts.parallel && len(tasks) > 1 {
+		r.initialBackendsParallel(tasks, errs)
+	} else {
+		for i, task := range tasks {
+			errs[i] = r.initialComponent(ComponentKindBackend, task.proto, task.backend, task.config)
+		}
+	}
+	for i, task := range tasks {
+		if nil != errs[i] {
+			return errs[i]
+		}
+		r.extensions.StoreHookFunc(task.backend)
+	}
+	return nil
+}
+
+func (r *Router) initialBackendsParallel(tasks []backendInitTask, errs []error) {
+	start := time.Now()
+	var mu sync.Mutex
+	pending := make(map[string]bool, len(tasks))
+	var wg sync.WaitGroup
+	for i := range tasks {
+		pending[tasks[i].proto] = true
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			task := tasks[i]
+			errs[i] = r.initialComponent(ComponentKindBackend, task.proto, task.backend, task.config)
+			mu.Lock()
+			delete(pending, task.proto)
+			mu.Unlock()
+		}(i)
+	}
+	done := make(chan struct{})
+	if r.initOpts.progressInterval > 0 {
+		ticker := time.NewTicker(r.initOpts.progressInterval)
+		defer ticker.Stop()
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					mu.Lock()
+					names := make([]string, 0, len(pending))
+					for name := range pending {
+						names = append(names, name)
+					}
+					mu.Unlock()
+					sort.Strings(names)
+					logger.Infow("Router backends initialing", "done", len(tasks)-len(names), "total", len(tasks),
+						"pending", names, "elapsed", time.Since(start))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	logger.Infow("Router backends initialed", "total", len(tasks), "elapsed", time.Since(start))
+}
+
+// InitTimings 返回各组件的初始化耗时，按耗时降序排列
+func (r *Router) InitTimings() []ComponentInitTiming {
+	return r.timings.snapshot()
+}
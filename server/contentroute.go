@@ -0,0 +1,83 @@
+package server
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/support"
+	"github.com/spf13/cast"
+)
+
+const (
+	// Endpoint扩展属性：按请求内容选择后端服务的规则列表，按顺序匹配，第一个匹配的规则生效；未匹配时使用Endpoint的默认服务。
+	// 例如：[{"when": "body:order.type == 'international'", "service": "order.international:create"}]
+	EndpointExtKeyContentRoutes = "content-routes"
+)
+
+// ContentRoute 内容路由规则：When 为条件表达式（参见 support.Predicate），Service 为后端服务的ServiceId
+type ContentRoute struct {
+	When    string `json:"when"`
+	Service string `json:"service"`
+}
+
+// contentRoutesOf 返回Endpoint声明的内容路由规则
+func contentRoutesOf(endpoint *flux.Endpoint) []ContentRoute {
+	items := cast.ToSlice(endpoint.Extensions[EndpointExtKeyContentRoutes])
+	routes := make([]ContentRoute, 0, len(items))
+	for _, item := range items {
+		m := cast.ToStringMap(item)
+		route := ContentRoute{When: cast.ToString(m["when"]), Service: cast.ToString(m["service"])}
+		if "" != route.When && "" != route.Service {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// predicateOf 返回编译后的条件表达式；表达式与Endpoint配置一起变更，按表达式文本缓存
+func (r *Router) predicateOf(expr string) (*support.Predicate, error) {
+	if v, ok := r.predicates.Load(expr); ok {
+		return v.(*support.Predicate), nil
+	}
+	predicate, err := support.CompilePredicate(expr)
+	if nil != err {
+		return nil, err
+	}
+	r.predicates.Store(expr, predicate)
+	return predicate, nil
+}
+
+// routeByContent 按内容路由规则选择后端服务，替换本次请求的Endpoint服务；
+// 表达式无效或计算失败的规则视为不匹配，匹配规则的服务不存在时返回错误
+func (r *Router) routeByContent(ctx *WrappedContext) *flux.ServeError {
+	if _, ok := ctx.endpoint.Extensions[EndpointExtKeyContentRoutes]; !ok {
+		return nil
+	}
+	for _, route := range contentRoutesOf(ctx.endpoint) {
+		predicate, err := r.predicateOf(route.When)
+		if nil != err {
+			logger.TraceContext(ctx).Warnw("Route, invalid content route predicate", "when", route.When, "error", err)
+			continue
+		}
+		matched, err := predicate.Eval(ctx)
+		if nil != err {
+			logger.TraceContext(ctx).Warnw("Route, eval content route predicate", "when", route.When, "error", err)
+			continue
+		}
+		if !matched {
+			continue
+		}
+		service, ok := r.extensions.LoadBackendService(route.Service)
+		if !ok {
+			logger.TraceContext(ctx).Warnw("Route, content route service not found", "when", route.When, "service-id", route.Service)
+			return &flux.ServeError{
+				StatusCode: flux.StatusServerError,
+				ErrorCode:  flux.ErrorCodeGatewayInternal,
+				Message:    flux.ErrorMessageContentRouteServiceNotFound,
+			}
+		}
+		logger.TraceContext(ctx).Debugw("Route, content route matched", "when", route.When, "service-id", route.Service)
+		ctx.rebindService(service)
+		return nil
+	}
+	return nil
+}
@@ -1,17 +1,32 @@
 package server
 
 import (
+	"bufio"
 	"context"
 	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
 	"github.com/spf13/cast"
+	"io"
+	"mime/multipart"
+	"net"
+	"strings"
 	"time"
 )
 
-var _ flux.Context = new(WrappedContext)
+var (
+	_ flux.Context           = new(WrappedContext)
+	_ flux.StreamingContext  = new(WrappedContext)
+	_ flux.UpgradeContext    = new(WrappedContext)
+	_ flux.MultipartContext  = new(WrappedContext)
+	_ flux.TracingContext    = new(WrappedContext)
+	_ flux.ClientIPContext   = new(WrappedContext)
+	_ flux.DetachableContext = new(WrappedContext)
+)
 
 // Context接口实现
 type WrappedContext struct {
 	requestId      string
+	clientIp       string
 	webc           flux.WebContext
 	endpoint       *flux.Endpoint
 	attributes     map[string]interface{}
@@ -21,6 +36,9 @@ type WrappedContext struct {
 	requestReader  *WrappedRequestReader
 	responseWriter *WrappedResponseWriter
 	ctxLogger      flux.Logger
+	streamed       bool
+	streaming      *streamOptions
+	extensions     *ext.Registry
 }
 
 func NewContextWrapper() interface{} {
@@ -55,6 +73,13 @@ func (c *WrappedContext) ServiceName() (interfaceName, methodName string) {
 	return c.endpoint.Service.Interface, c.endpoint.Service.Method
 }
 
+// rebindService 替换本次请求的后端服务；Endpoint在请求间共享，替换其副本
+func (c *WrappedContext) rebindService(service flux.BackendService) {
+	endpoint := *c.endpoint
+	endpoint.Service = service
+	c.endpoint = &endpoint
+}
+
 func (c *WrappedContext) Authorize() bool {
 	return c.endpoint.AttrAuthorize()
 }
@@ -71,6 +96,10 @@ func (c *WrappedContext) RequestId() string {
 	return c.requestId
 }
 
+func (c *WrappedContext) ClientIP() string {
+	return c.clientIp
+}
+
 func (c *WrappedContext) Attributes() map[string]interface{} {
 	copied := make(map[string]interface{}, len(c.attributes))
 	for k, v := range c.attributes {
@@ -120,8 +149,12 @@ func (c *WrappedContext) GetValueString(name string, defaultValue string) string
 	return cast.ToString(v)
 }
 
+// Context 返回请求的Context，绑定了网关引擎的扩展组件注册表
 func (c *WrappedContext) Context() context.Context {
-	return c.webc.Context()
+	if nil == c.extensions {
+		return c.webc.Context()
+	}
+	return ext.WithRegistry(c.webc.Context(), c.extensions)
 }
 
 func (c *WrappedContext) LoadMetrics() []flux.Metric {
@@ -152,8 +185,65 @@ func (c *WrappedContext) AddMetric(name string, elapsed time.Duration) {
 	})
 }
 
-func (c *WrappedContext) Reattach(requestId string, webc flux.WebContext, endpoint *flux.Endpoint) {
+// WriteStream 写入ResponseWriter中已设置的Header后，直接写入响应状态码和流数据
+func (c *WrappedContext) WriteStream(statusCode int, contentType string, reader io.Reader) error {
+	c.streamed = true
+	c.webc.SetResponseHeader(flux.HeaderXRequestId, c.RequestId())
+	c.webc.SetResponseHeader(flux.HeaderServer, "Flux/Gateway")
+	for k, v := range c.responseWriter.HeaderValues() {
+		for i, iv := range v {
+			if 0 == i {
+				c.webc.SetResponseHeader(k, iv)
+			} else {
+				c.webc.AddResponseHeader(k, iv)
+			}
+		}
+	}
+	if nil != c.streaming {
+		return c.streaming.write(c.webc, statusCode, contentType, reader)
+	}
+	return c.webc.WriteStream(statusCode, contentType, reader)
+}
+
+// Streamed 返回是否已通过WriteStream写入响应
+func (c *WrappedContext) Streamed() bool {
+	return c.streamed
+}
+
+func (c *WrappedContext) Upgrade() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := c.webc.Hijack()
+	if nil == err {
+		c.streamed = true
+	}
+	return conn, rw, err
+}
+
+func (c *WrappedContext) MultipartForm(maxMemory int64) (*multipart.Form, error) {
+	request, err := c.webc.HttpRequest()
+	if nil != err {
+		return nil, err
+	}
+	if err := request.ParseMultipartForm(maxMemory); nil != err {
+		return nil, err
+	}
+	return request.MultipartForm, nil
+}
+
+func (c *WrappedContext) TraceId() string {
+	if traceId, _, ok := parseTraceParent(c.webc.HeaderValue(flux.TraceParent)); ok {
+		return traceId
+	}
+	return c.requestId
+}
+
+func (c *WrappedContext) ParentSpanId() string {
+	_, spanId, _ := parseTraceParent(c.webc.HeaderValue(flux.TraceParent))
+	return spanId
+}
+
+func (c *WrappedContext) Reattach(requestId, clientIp string, webc flux.WebContext, endpoint *flux.Endpoint) {
 	c.requestId = requestId
+	c.clientIp = clientIp
 	c.webc = webc
 	c.endpoint = endpoint
 	c.attributes = make(map[string]interface{}, 8)
@@ -165,11 +255,22 @@ func (c *WrappedContext) Reattach(requestId string, webc flux.WebContext, endpoi
 	c.SetAttribute(flux.XRequestTime, c.beginTime.Unix())
 	c.SetAttribute(flux.XRequestId, c.requestId)
 	c.SetAttribute(flux.XRequestHost, webc.Host())
+	c.SetAttribute(flux.XClientIp, clientIp)
 	c.SetAttribute(flux.XRequestAgent, "flux/gateway")
 }
 
+// Detach 复制请求数据，返回不关联客户端连接的Context
+func (c *WrappedContext) Detach() (flux.Context, func(), error) {
+	detached, err := detachContext(c)
+	if nil != err {
+		return nil, nil, err
+	}
+	return detached, detached.Release, nil
+}
+
 func (c *WrappedContext) Release() {
 	c.requestId = ""
+	c.clientIp = ""
 	c.webc = nil
 	c.endpoint = nil
 	c.attributes = nil
@@ -178,4 +279,28 @@ func (c *WrappedContext) Release() {
 	c.requestReader.reset()
 	c.responseWriter.reset()
 	c.ctxLogger = nil
+	c.streamed = false
+	c.extensions = nil
+}
+
+// parseTraceParent 解析W3C traceparent：version-traceid-parentid-flags
+func parseTraceParent(value string) (traceId, spanId string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || "ff" == parts[0] || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", "", false
+	}
+	if !isLowerHex(parts[1]) || !isLowerHex(parts[2]) ||
+		strings.Repeat("0", 32) == parts[1] || strings.Repeat("0", 16) == parts[2] {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
 }
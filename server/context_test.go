@@ -0,0 +1,20 @@
+package server
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWrappedContext_Registry(t *testing.T) {
+	assert := assert2.New(t)
+	engine := NewHttpServeEngineOf(ext.NewRegistry(), DefaultServerResponseWriter, DefaultServerErrorsWriter)
+	webc := newAsyncTestWebContext("id", "")
+	ctx := engine.acquireContext("req-1", "127.0.0.1", webc, &flux.Endpoint{})
+	assert.True(engine.extensions == ext.RegistryOf(ctx.Context()))
+	detached, err := detachContext(ctx)
+	assert.NoError(err)
+	assert.True(engine.extensions == ext.RegistryOf(detached.Context()), "detached context keeps the engine registry")
+	engine.releaseContext(ctx)
+}
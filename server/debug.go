@@ -1,9 +1,14 @@
 package server
 
 import (
+	"crypto/subtle"
 	"github.com/bytepowered/flux"
 	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/webmidware"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
 )
 
@@ -17,6 +22,13 @@ const (
 	queryKeyServiceId    = "serviceid"
 	queryKeyServiceId0   = "service-id"
 	queryKeyServiceId1   = "serviceId"
+	queryKeyRequestPath  = "path"
+	queryKeyHttpMethod   = "method"
+)
+
+const (
+	// 管理接口的认证Token环境变量；未配置 feature-admin-token 时使用
+	EnvKeyFluxAdminToken = "FLUX_ADMIN_TOKEN"
 )
 
 type EndpointFilter func(ep *MultiEndpoint) bool
@@ -63,20 +75,30 @@ func init() {
 
 // NewDebugQueryEndpointHandler Endpoint查询
 func NewDebugQueryEndpointHandler() http.HandlerFunc {
+	return NewDebugQueryEndpointHandlerWith(defaultEndpoints)
+}
+
+// NewDebugQueryEndpointHandlerWith 查询指定映射表中的Endpoint
+func NewDebugQueryEndpointHandlerWith(endpoints *EndpointTable) http.HandlerFunc {
 	serializer := ext.LoadSerializer(ext.TypeNameSerializerJson)
 	return newSerializableHttpHandler(serializer, func(request *http.Request) interface{} {
-		return queryEndpoints(request)
+		return queryEndpoints(endpoints, request)
 	})
 }
 
 // NewDebugQueryServiceHandler Service查询
 func NewDebugQueryServiceHandler() http.HandlerFunc {
-	serializer := ext.LoadSerializer(ext.TypeNameSerializerJson)
+	return NewDebugQueryServiceHandlerWith(ext.Default())
+}
+
+// NewDebugQueryServiceHandlerWith 查询指定扩展组件注册表中的Service
+func NewDebugQueryServiceHandlerWith(extensions *ext.Registry) http.HandlerFunc {
+	serializer := extensions.LoadSerializer(ext.TypeNameSerializerJson)
 	return newSerializableHttpHandler(serializer, func(request *http.Request) interface{} {
 		query := request.URL.Query()
 		for _, key := range serviceQueryKeys {
 			if id := query.Get(key); "" != id {
-				service, ok := ext.LoadBackendService(id)
+				service, ok := extensions.LoadBackendService(id)
 				if ok {
 					return service
 				} else {
@@ -95,8 +117,59 @@ func NewDebugQueryServiceHandler() http.HandlerFunc {
 	})
 }
 
-func queryEndpoints(request *http.Request) interface{} {
-	data := LoadEndpoints()
+// NewDebugReadOnlyHandler 查询和切换网关只读模式：GET查询；POST/PUT参数 enabled=true|false, message=响应消息
+func NewDebugReadOnlyHandler(readOnly *webmidware.ReadOnlySwitch) http.HandlerFunc {
+	serializer := ext.LoadSerializer(ext.TypeNameSerializerJson)
+	return newSerializableHttpHandler(serializer, func(request *http.Request) interface{} {
+		if http.MethodPost == request.Method || http.MethodPut == request.Method {
+			enabled, err := strconv.ParseBool(request.FormValue("enabled"))
+			if nil != err {
+				return map[string]string{
+					"status":  "failed",
+					"message": "param is required: enabled=true|false",
+				}
+			}
+			readOnly.Set(enabled, request.FormValue("message"))
+			logger.Infow("HttpServeEngine read-only mode switched", "enabled", enabled)
+		}
+		enabled, message := readOnly.State()
+		return map[string]interface{}{
+			"read-only": enabled,
+			"message":   message,
+		}
+	})
+}
+
+// NewDebugInitTimingsHandler 查询各组件的初始化耗时，按耗时降序排列
+func NewDebugInitTimingsHandler(router *Router) http.HandlerFunc {
+	serializer := router.extensions.LoadSerializer(ext.TypeNameSerializerJson)
+	return newSerializableHttpHandler(serializer, func(request *http.Request) interface{} {
+		return router.InitTimings()
+	})
+}
+
+func queryEndpoints(endpoints *EndpointTable, request *http.Request) interface{} {
+	// 按请求路径查找Endpoint：path=/users/1001&method=GET
+	if path := request.URL.Query().Get(queryKeyRequestPath); "" != path {
+		method := strings.ToUpper(request.URL.Query().Get(queryKeyHttpMethod))
+		if "" == method {
+			method = http.MethodGet
+		}
+		if mve, match, ok := endpoints.Match(method, path); ok {
+			return map[string]interface{}{
+				"http-pattern": match.Pattern,
+				"path-values":  match.Params,
+				"endpoints":    mve.ToSerializable(),
+			}
+		}
+		return map[string]string{
+			"status":  "failed",
+			"message": "endpoint not found",
+			"path":    path,
+			"method":  method,
+		}
+	}
+	data := endpoints.Load()
 	filters := make([]EndpointFilter, 0)
 	query := request.URL.Query()
 	for _, key := range endpointQueryKeys {
@@ -149,3 +222,37 @@ func queryMatch(input, expected string) bool {
 	input, expected = strings.ToLower(input), strings.ToLower(expected)
 	return input == expected || strings.Contains(expected, input)
 }
+
+// NewDebugQueryOnlyHandler 只允许GET/HEAD请求的Debug接口；修改运行时状态的请求返回405，应使用需要认证的管理接口
+func NewDebugQueryOnlyHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if http.MethodGet != r.Method && http.MethodHead != r.Method {
+			w.Header().Set(flux.HeaderAllow, "GET, HEAD")
+			http.Error(w, "use admin handlers to change runtime state", http.StatusMethodNotAllowed)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// NewAdminAuthHandler 管理接口认证：请求须携带 Authorization: Bearer <token> Header，否则返回401
+func NewAdminAuthHandler(token string, next http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if 1 != subtle.ConstantTimeCompare([]byte(r.Header.Get(flux.HeaderAuthorization)), expected) {
+			logger.Warnw("Admin handler unauthorized", "path", r.URL.Path, "remote-addr", r.RemoteAddr)
+			w.Header().Set(flux.HeaderWWWAuthenticate, "Bearer")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminToken 返回管理接口的认证Token：优先使用配置，其次使用环境变量
+func (s *HttpServeEngine) adminToken() string {
+	if token := s.httpConfig.GetString(HttpWebServerConfigKeyFeatureAdminToken); "" != token {
+		return token
+	}
+	return os.Getenv(EnvKeyFluxAdminToken)
+}
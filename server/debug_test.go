@@ -0,0 +1,60 @@
+package server
+
+import (
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewDebugQueryOnlyHandler(t *testing.T) {
+	assert := assert2.New(t)
+	handler := NewDebugQueryOnlyHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	cases := []struct {
+		method string
+		expect int
+	}{
+		{method: http.MethodGet, expect: http.StatusOK},
+		{method: http.MethodHead, expect: http.StatusOK},
+		{method: http.MethodPost, expect: http.StatusMethodNotAllowed},
+		{method: http.MethodPut, expect: http.StatusMethodNotAllowed},
+		{method: http.MethodDelete, expect: http.StatusMethodNotAllowed},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(c.method, "/debug/snapshot", nil))
+		assert.Equal(c.expect, w.Code, c.method)
+	}
+}
+
+func TestNewAdminAuthHandler(t *testing.T) {
+	assert := assert2.New(t)
+	handler := NewAdminAuthHandler("s3cret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	cases := []struct {
+		name          string
+		authorization string
+		expect        int
+	}{
+		{name: "missing", authorization: "", expect: http.StatusUnauthorized},
+		{name: "wrong token", authorization: "Bearer other", expect: http.StatusUnauthorized},
+		{name: "wrong scheme", authorization: "Basic s3cret", expect: http.StatusUnauthorized},
+		{name: "token prefix", authorization: "Bearer s3cre", expect: http.StatusUnauthorized},
+		{name: "valid", authorization: "Bearer s3cret", expect: http.StatusOK},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/admin/readonly", nil)
+		if "" != c.authorization {
+			r.Header.Set("Authorization", c.authorization)
+		}
+		handler.ServeHTTP(w, r)
+		assert.Equal(c.expect, w.Code, c.name)
+		if http.StatusUnauthorized == c.expect {
+			assert.Equal("Bearer", w.Header().Get("WWW-Authenticate"), c.name)
+		}
+	}
+}
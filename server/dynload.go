@@ -5,7 +5,6 @@ import (
 	"github.com/bytepowered/flux"
 	"github.com/bytepowered/flux/ext"
 	"github.com/bytepowered/flux/logger"
-	"github.com/spf13/viper"
 )
 
 const (
@@ -21,10 +20,10 @@ type AwareConfig struct {
 }
 
 // 动态加载Filter
-func dynamicFilters() ([]AwareConfig, error) {
+func dynamicFilters(extensions *ext.Registry, root *flux.Configuration) ([]AwareConfig, error) {
 	out := make([]AwareConfig, 0)
-	for id := range viper.GetStringMap("FILTER") {
-		v := viper.Sub("FILTER." + id)
+	for id := range root.GetStringMap("FILTER") {
+		v := root.Reference().Sub("FILTER." + id)
 		if v == nil || !v.IsSet(dynConfigKeyTypeId) {
 			logger.Infow("Filter configuration is empty or without typeId", "typeId", id)
 			continue
@@ -35,7 +34,7 @@ func dynamicFilters() ([]AwareConfig, error) {
 			logger.Infow("Filter is DISABLED", "typeId", typeId, "id", id)
 			continue
 		}
-		factory, ok := ext.LoadTypedFactory(typeId)
+		factory, ok := extensions.LoadTypedFactory(typeId)
 		if !ok {
 			return nil, fmt.Errorf("FilterFactory not found, typeId: %s, name: %s", typeId, id)
 		}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	EdgeExportFormatNginx = "nginx"
+	EdgeExportFormatEnvoy = "envoy"
+)
+
+const (
+	// 边缘代理转发到网关的默认Upstream/Cluster名称
+	DefaultEdgeUpstream = "flux_gateway"
+)
+
+// EdgeRoute 网关对外暴露的路由；同一Pattern的多个Method合并为一个路由
+type EdgeRoute struct {
+	Pattern string
+	Methods []string
+}
+
+// EdgeRoutesOf 从Endpoint列表生成按Pattern排序的路由表
+func EdgeRoutesOf(endpoints []flux.Endpoint) []EdgeRoute {
+	methods := make(map[string]map[string]bool, len(endpoints))
+	for _, endpoint := range endpoints {
+		if "" == endpoint.HttpPattern || "" == endpoint.HttpMethod {
+			continue
+		}
+		if _, ok := methods[endpoint.HttpPattern]; !ok {
+			methods[endpoint.HttpPattern] = make(map[string]bool, 2)
+		}
+		methods[endpoint.HttpPattern][strings.ToUpper(endpoint.HttpMethod)] = true
+	}
+	out := make([]EdgeRoute, 0, len(methods))
+	for pattern, set := range methods {
+		route := EdgeRoute{Pattern: pattern}
+		for method := range set {
+			route.Methods = append(route.Methods, method)
+		}
+		sort.Strings(route.Methods)
+		out = append(out, route)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Pattern < out[j].Pattern
+	})
+	return out
+}
+
+// ExportEdgeRoutes 将路由表导出为边缘代理配置：Nginx location 配置块，或 Envoy RouteConfiguration（JSON）
+func ExportEdgeRoutes(format string, routes []EdgeRoute, upstream string) ([]byte, error) {
+	if "" == upstream {
+		upstream = DefaultEdgeUpstream
+	}
+	switch format {
+	case EdgeExportFormatNginx:
+		return exportNginxLocations(routes, upstream), nil
+	case EdgeExportFormatEnvoy:
+		return exportEnvoyRouteConfig(routes, upstream)
+	default:
+		return nil, fmt.Errorf("unsupported edge export format: %s", format)
+	}
+}
+
+func exportNginxLocations(routes []EdgeRoute, upstream string) []byte {
+	buf := new(bytes.Buffer)
+	for _, route := range routes {
+		switch kind, match := edgeMatchOf(route.Pattern); kind {
+		case edgeMatchExact:
+			_, _ = fmt.Fprintf(buf, "location = %s {\n", match)
+		case edgeMatchPrefix:
+			_, _ = fmt.Fprintf(buf, "location ^~ %s {\n", match)
+		default:
+			_, _ = fmt.Fprintf(buf, "location ~ %s {\n", match)
+		}
+		// limit_except GET 同时允许 HEAD
+		_, _ = fmt.Fprintf(buf, "    limit_except %s {\n        deny all;\n    }\n", strings.Join(route.Methods, " "))
+		_, _ = fmt.Fprintf(buf, "    proxy_pass http://%s;\n}\n", upstream)
+	}
+	return buf.Bytes()
+}
+
+func exportEnvoyRouteConfig(routes []EdgeRoute, upstream string) ([]byte, error) {
+	envoyRoutes := make([]map[string]interface{}, 0, len(routes))
+	for _, route := range routes {
+		match := make(map[string]interface{}, 2)
+		switch kind, value := edgeMatchOf(route.Pattern); kind {
+		case edgeMatchExact:
+			match["path"] = value
+		case edgeMatchPrefix:
+			match["prefix"] = value
+		default:
+			match["safe_regex"] = map[string]interface{}{"regex": value}
+		}
+		match["headers"] = []interface{}{
+			map[string]interface{}{
+				"name": ":method",
+				"string_match": map[string]interface{}{
+					"safe_regex": map[string]interface{}{"regex": "^(" + strings.Join(route.Methods, "|") + ")$"},
+				},
+			},
+		}
+		envoyRoutes = append(envoyRoutes, map[string]interface{}{
+			"match": match,
+			"route": map[string]interface{}{"cluster": upstream},
+		})
+	}
+	return json.MarshalIndent(map[string]interface{}{
+		"name": upstream,
+		"virtual_hosts": []interface{}{
+			map[string]interface{}{
+				"name":    upstream,
+				"domains": []string{"*"},
+				"routes":  envoyRoutes,
+			},
+		},
+	}, "", "  ")
+}
+
+const (
+	edgeMatchExact = iota
+	edgeMatchPrefix
+	edgeMatchRegex
+)
+
+// edgeMatchOf 将路由Pattern转换为边缘代理的匹配规则：
+// 无参数为精确匹配；仅末尾为 * 时为前缀匹配；包含 :param 参数时为正则匹配
+func edgeMatchOf(pattern string) (int, string) {
+	if !strings.ContainsAny(pattern, ":*") {
+		return edgeMatchExact, pattern
+	}
+	if strings.HasSuffix(pattern, "/*") && !strings.ContainsAny(pattern[:len(pattern)-1], ":*") {
+		return edgeMatchPrefix, pattern[:len(pattern)-1]
+	}
+	segments := strings.Split(pattern, "/")
+	for i, seg := range segments {
+		switch {
+		case "*" == seg && i == len(segments)-1:
+			segments[i] = ".*"
+		case strings.HasPrefix(seg, ":"):
+			segments[i] = "[^/]+"
+		default:
+			segments[i] = regexp.QuoteMeta(seg)
+		}
+	}
+	return edgeMatchRegex, "^" + strings.Join(segments, "/") + "$"
+}
+
+// NewDebugEdgeExportHandlerWith 导出指定映射表中已注册Endpoint的边缘代理配置；
+// 查询参数：format=nginx|envoy，upstream=转发到网关的Upstream/Cluster名称
+func NewDebugEdgeExportHandlerWith(endpoints *EndpointTable) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		format := request.URL.Query().Get("format")
+		if "" == format {
+			format = EdgeExportFormatNginx
+		}
+		routes := make([]flux.Endpoint, 0, 32)
+		for _, mve := range endpoints.Load() {
+			for _, endpoint := range mve.ToSerializable() {
+				routes = append(routes, *endpoint)
+			}
+		}
+		data, err := ExportEdgeRoutes(format, EdgeRoutesOf(routes), request.URL.Query().Get("upstream"))
+		if nil != err {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if EdgeExportFormatEnvoy == format {
+			writer.Header().Set("Content-Type", "application/json")
+		} else {
+			writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		}
+		_, _ = writer.Write(data)
+	}
+}
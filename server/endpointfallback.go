@@ -0,0 +1,131 @@
+package server
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/backend"
+	"github.com/bytepowered/flux/logger"
+	"github.com/spf13/cast"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const (
+	// Endpoint扩展属性：熔断打开或请求被限流时的降级响应模式：static 返回静态响应；backend 调用备用的后端服务；
+	// last-good 返回Backend响应缓存中该请求最近一次成功的响应，需开启响应缓存（response-cache-ttl），
+	// 过期响应的可用时长由 response-cache-max-stale 指定；只适用于GET/HEAD请求
+	EndpointExtKeyFallbackMode = "fallback-mode"
+	// Endpoint扩展属性：触发降级响应的条件列表：circuit-open、rate-limited；默认全部
+	EndpointExtKeyFallbackOn = "fallback-on"
+	// Endpoint扩展属性：static 模式的响应状态码；默认200
+	EndpointExtKeyFallbackStatus = "fallback-status"
+	// Endpoint扩展属性：static 模式的响应体；字符串按原文输出，其它对象按JSON序列化
+	EndpointExtKeyFallbackBody = "fallback-body"
+	// Endpoint扩展属性：static 模式的响应Content-Type；默认为JSON
+	EndpointExtKeyFallbackContentType = "fallback-content-type"
+	// Endpoint扩展属性：backend 模式的备用后端服务ID
+	EndpointExtKeyFallbackService = "fallback-service"
+)
+
+const (
+	FallbackModeStatic   = "static"
+	FallbackModeLastGood = "last-good"
+	FallbackModeBackend  = "backend"
+
+	FallbackOnCircuitOpen = "circuit-open"
+	FallbackOnRateLimited = "rate-limited"
+
+	// 降级响应的Header，值为降级模式
+	HeaderXFluxFallback = "X-Flux-Fallback"
+)
+
+// fallbackTriggerOf 返回触发降级的条件；非熔断打开或限流的错误返回false
+func fallbackTriggerOf(err *flux.ServeError) (string, bool) {
+	switch {
+	case flux.ErrorCodeGatewayCircuited == err.GetErrorCode():
+		return FallbackOnCircuitOpen, true
+	case http.StatusTooManyRequests == err.StatusCode:
+		return FallbackOnRateLimited, true
+	default:
+		return "", false
+	}
+}
+
+// fallbackModeOf 返回Endpoint对错误配置的降级模式；未配置或不匹配触发条件时返回false
+func fallbackModeOf(endpoint flux.Endpoint, err *flux.ServeError) (string, bool) {
+	mode := endpoint.ExtString(EndpointExtKeyFallbackMode)
+	if "" == mode {
+		return "", false
+	}
+	trigger, ok := fallbackTriggerOf(err)
+	if !ok {
+		return "", false
+	}
+	if v, ok := endpoint.Ext(EndpointExtKeyFallbackOn); ok {
+		matched := false
+		for _, on := range cast.ToStringSlice(v) {
+			matched = matched || strings.TrimSpace(on) == trigger
+		}
+		if !matched {
+			return "", false
+		}
+	}
+	return mode, true
+}
+
+// routeByFallback 熔断打开或请求被限流时，按Endpoint配置的降级模式写入降级响应；
+// 未配置降级或降级失败时返回false，由调用方返回原错误
+func (r *Router) routeByFallback(ctx *WrappedContext, err *flux.ServeError, exchange flux.FilterHandler) bool {
+	endpoint := ctx.Endpoint()
+	mode, ok := fallbackModeOf(endpoint, err)
+	if !ok {
+		return false
+	}
+	response := ctx.Response()
+	switch mode {
+	case FallbackModeStatic:
+		status := endpoint.ExtInt(EndpointExtKeyFallbackStatus)
+		if status <= 0 {
+			status = flux.StatusOK
+		}
+		contentType := endpoint.ExtString(EndpointExtKeyFallbackContentType)
+		if "" == contentType {
+			contentType = flux.MIMEApplicationJSONCharsetUTF8
+		}
+		body, _ := endpoint.Ext(EndpointExtKeyFallbackBody)
+		if text, ok := body.(string); ok {
+			body = ioutil.NopCloser(strings.NewReader(text))
+		}
+		response.SetStatusCode(status)
+		response.SetHeader(flux.HeaderContentType, contentType)
+		response.SetBody(body)
+	case FallbackModeLastGood:
+		// 使用Backend响应缓存中该请求最近一次成功的响应
+		if !backend.ServeStaleResponse(ctx) {
+			return false
+		}
+	case FallbackModeBackend:
+		id := endpoint.ExtString(EndpointExtKeyFallbackService)
+		service, ok := r.extensions.LoadBackendService(id)
+		if !ok {
+			logger.TraceContext(ctx).Warnw("Route fallback, service not found", "service-id", id)
+			return false
+		}
+		ctx.rebindService(service)
+		if serr := exchange(ctx); nil != serr {
+			logger.TraceContext(ctx).Warnw("Route fallback, backend failed", "service-id", id, "error", serr)
+			return false
+		}
+	default:
+		logger.TraceContext(ctx).Warnw("Route fallback, unknown mode", "mode", mode)
+		return false
+	}
+	for name, values := range err.Header {
+		for _, value := range values {
+			response.AddHeader(name, value)
+		}
+	}
+	response.SetHeader(HeaderXFluxFallback, mode)
+	logger.TraceContext(ctx).Infow("Route fallback, served", "mode", mode, "error", err)
+	return true
+}
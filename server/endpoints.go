@@ -2,30 +2,70 @@ package server
 
 import (
 	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/support"
 	"sync"
 )
 
 var (
-	endpoints = new(sync.Map)
+	defaultEndpoints = NewEndpointTable()
 )
 
 func SelectMultiEndpoint(key string) (*MultiEndpoint, bool) {
-	ep, ok := endpoints.Load(key)
+	return defaultEndpoints.Select(key)
+}
+
+func RegisterMultiEndpoint(key string, endpoint *flux.Endpoint) *MultiEndpoint {
+	return defaultEndpoints.Register(key, endpoint)
+}
+
+func LoadEndpoints() map[string]*MultiEndpoint {
+	return defaultEndpoints.Load()
+}
+
+// EndpointTable 路由Key（METHOD#pattern）与多版本Endpoint的映射表；并发安全。
+// 路由Pattern同时注册到前缀树，用于按请求路径查找Endpoint。
+type EndpointTable struct {
+	endpoints *sync.Map
+	routes    *support.RouteTrie
+}
+
+func NewEndpointTable() *EndpointTable {
+	return &EndpointTable{endpoints: new(sync.Map), routes: support.NewRouteTrie()}
+}
+
+func (t *EndpointTable) Select(key string) (*MultiEndpoint, bool) {
+	ep, ok := t.endpoints.Load(key)
 	if ok {
 		return ep.(*MultiEndpoint), true
 	}
 	return nil, false
 }
 
-func RegisterMultiEndpoint(key string, endpoint *flux.Endpoint) *MultiEndpoint {
+func (t *EndpointTable) Register(key string, endpoint *flux.Endpoint) *MultiEndpoint {
 	mve := newMultiEndpoint(endpoint)
-	endpoints.Store(key, mve)
+	t.endpoints.Store(key, mve)
+	if _, pattern, ok := parseRouteKey(key); ok {
+		if err := t.routes.Insert(pattern, pattern); nil != err {
+			logger.Warnw("Endpoint table, insert route pattern", "key", key, "error", err)
+		}
+	}
 	return mve
 }
 
-func LoadEndpoints() map[string]*MultiEndpoint {
+// Match 按请求方法和路径查找Endpoint，返回匹配的路由Pattern和路径变量
+func (t *EndpointTable) Match(method, path string) (*MultiEndpoint, support.RouteMatch, bool) {
+	match, ok := t.routes.Match(path)
+	if !ok {
+		return nil, support.RouteMatch{}, false
+	}
+	mve, ok := t.Select(method + "#" + match.Pattern)
+	return mve, match, ok
+}
+
+func (t *EndpointTable) Load() map[string]*MultiEndpoint {
 	out := make(map[string]*MultiEndpoint, 32)
-	endpoints.Range(func(key, value interface{}) bool {
+	t.endpoints.Range(func(key, value interface{}) bool {
 		out[key.(string)] = value.(*MultiEndpoint)
 		return true
 	})
@@ -72,6 +112,19 @@ func (m *MultiEndpoint) Delete(version string) {
 	m.Unlock()
 }
 
+// Latest 返回版本号最大的Endpoint
+func (m *MultiEndpoint) Latest() *flux.Endpoint {
+	m.RLock()
+	defer m.RUnlock()
+	var latest *flux.Endpoint
+	for _, v := range m.endpoint {
+		if nil == latest || compareVersion(v.Version, latest.Version) > 0 {
+			latest = v
+		}
+	}
+	return latest
+}
+
 func (m *MultiEndpoint) RandomVersion() *flux.Endpoint {
 	m.RLock()
 	rv := m.random()
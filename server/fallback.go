@@ -0,0 +1,104 @@
+package server
+
+import (
+	"fmt"
+	"github.com/bytepowered/flux"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+)
+
+// fallbackOptions 路由不存在（404）和方法不允许（405）时的处理配置
+type fallbackOptions struct {
+	hosts                map[string]string // Host -> Fallback Endpoint的路由Key（METHOD#pattern）
+	upstream             http.Handler      // 未知路径的默认上游，用于迁移场景
+	notFoundBody         []byte
+	methodNotAllowedBody []byte
+	contentType          string
+}
+
+func newFallbackOptions(config *flux.Configuration) (*fallbackOptions, error) {
+	opts := &fallbackOptions{
+		hosts:                make(map[string]string, 4),
+		notFoundBody:         []byte(config.GetString(HttpWebServerConfigKeyNotFoundBody)),
+		methodNotAllowedBody: []byte(config.GetString(HttpWebServerConfigKeyMethodNotAllowedBody)),
+		contentType:          config.GetString(HttpWebServerConfigKeyFallbackContentType),
+	}
+	for host, endpoint := range config.GetStringMapString(HttpWebServerConfigKeyFallbackHosts) {
+		method, pattern, ok := parseRouteKey(endpoint)
+		if !ok {
+			return nil, fmt.Errorf("fallback-hosts.%s is invalid, must be METHOD#pattern: %s", host, endpoint)
+		}
+		opts.hosts[strings.ToLower(host)] = fmt.Sprintf("%s#%s", method, pattern)
+	}
+	if upstream := config.GetString(HttpWebServerConfigKeyFallbackUpstream); "" != upstream {
+		target, err := url.Parse(upstream)
+		if nil != err || "" == target.Scheme || "" == target.Host {
+			return nil, fmt.Errorf("fallback-upstream is invalid: %s", upstream)
+		}
+		opts.upstream = httputil.NewSingleHostReverseProxy(target)
+	}
+	return opts, nil
+}
+
+// hostEndpoint 返回请求Host对应的Fallback Endpoint
+func (f *fallbackOptions) hostEndpoint(endpoints *EndpointTable, webc flux.WebContext) (*MultiEndpoint, bool) {
+	host := webc.Host()
+	if h, _, err := net.SplitHostPort(host); nil == err {
+		host = h
+	}
+	if routeKey, ok := f.hosts[strings.ToLower(host)]; ok {
+		return endpoints.Select(routeKey)
+	}
+	return nil, false
+}
+
+func parseRouteKey(key string) (method, pattern string, ok bool) {
+	parts := strings.SplitN(key, "#", 2)
+	if len(parts) != 2 || "" == parts[0] || !strings.HasPrefix(parts[1], "/") {
+		return "", "", false
+	}
+	return strings.ToUpper(parts[0]), parts[1], true
+}
+
+// defaultNotFoundErrorHandler 依次尝试：Host的Fallback Endpoint，迁移模式的旧系统，默认上游，自定义404响应体
+func (s *HttpServeEngine) defaultNotFoundErrorHandler(webc flux.WebContext) error {
+	if mve, ok := s.fallback.hostEndpoint(s.endpoints, webc); ok {
+		return s.HandleEndpointRequest(webc, mve, s.httpConfig.GetBool(HttpWebServerConfigKeyRequestLogEnable))
+	}
+	if nil != s.migration && s.migration.unmatched {
+		return flux.WrapHttpHandler(s.migration.legacy)(webc)
+	}
+	if nil != s.fallback.upstream {
+		return flux.WrapHttpHandler(s.fallback.upstream)(webc)
+	}
+	if len(s.fallback.notFoundBody) > 0 {
+		return s.writeFallbackBody(webc, flux.StatusNotFound, s.fallback.notFoundBody)
+	}
+	return &flux.ServeError{
+		StatusCode: flux.StatusNotFound,
+		ErrorCode:  flux.ErrorCodeRequestNotFound,
+		Message:    flux.ErrorMessageWebServerRequestNotFound,
+	}
+}
+
+// defaultMethodNotAllowedHandler 返回405；Allow Header由WebServer设置
+func (s *HttpServeEngine) defaultMethodNotAllowedHandler(webc flux.WebContext) error {
+	if len(s.fallback.methodNotAllowedBody) > 0 {
+		return s.writeFallbackBody(webc, http.StatusMethodNotAllowed, s.fallback.methodNotAllowedBody)
+	}
+	return &flux.ServeError{
+		StatusCode: http.StatusMethodNotAllowed,
+		ErrorCode:  flux.ErrorCodeRequestNotFound,
+		Message:    flux.ErrorMessageWebServerMethodNotAllowed,
+	}
+}
+
+func (s *HttpServeEngine) writeFallbackBody(webc flux.WebContext, status int, body []byte) error {
+	requestId, _ := webc.GetValue(flux.HeaderXRequestId).(string)
+	SetupResponseDefaults(webc, requestId, nil)
+	webc.SetResponseHeader(flux.HeaderContentType, s.fallback.contentType)
+	return webc.Write(status, s.fallback.contentType, body)
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/support"
+)
+
+const (
+	TypeIdFeatureFlagFilter = "FeatureFlagFilter"
+)
+
+var _ flux.Filter = new(featureFlagFilter)
+
+var featureFlag = new(featureFlagFilter)
+
+// featureFlagFilter 判断Endpoint的特性开关；由Router追加到Filter链的末尾，
+// 在认证Filter之后执行，特性开关可按认证主体（X-Jwt-Subject）灰度。未开启时按路由不存在返回404。
+type featureFlagFilter struct{}
+
+func (*featureFlagFilter) TypeId() string {
+	return TypeIdFeatureFlagFilter
+}
+
+func (*featureFlagFilter) DoFilter(next flux.FilterHandler) flux.FilterHandler {
+	return func(ctx flux.Context) *flux.ServeError {
+		flag := ctx.Endpoint().ExtString(flux.EndpointExtKeyFeatureFlag)
+		if "" == flag || support.IsFeatureEnabled(ctx, flag) {
+			return next(ctx)
+		}
+		logger.TraceContext(ctx).Infow("Route, endpoint feature flag disabled", "flag", flag)
+		return &flux.ServeError{
+			StatusCode: flux.StatusNotFound,
+			ErrorCode:  flux.ErrorCodeRequestNotFound,
+			Message:    flux.ErrorMessageWebServerRequestNotFound,
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestFeatureFlagFilter_AfterAuthentication(t *testing.T) {
+	assert := assert2.New(t)
+	engine := newAsyncTestEngine(t)
+	engine.extensions.StoreFeatureFlagProvider(flux.FeatureFlagProviderFunc(func(ctx flux.Context, flag string) (bool, error) {
+		return "alice" == ctx.GetAttributeString(flux.XJwtSubject, ""), nil
+	}))
+	endpoint := &flux.Endpoint{HttpMethod: http.MethodGet, HttpPattern: "/beta",
+		EmbeddedExtensions: flux.EmbeddedExtensions{Extensions: map[string]interface{}{flux.EndpointExtKeyFeatureFlag: "beta"}}}
+	cases := []struct {
+		subject string
+		expect  int
+	}{
+		{subject: "alice", expect: 0},
+		{subject: "bob", expect: flux.StatusNotFound},
+		// 未认证的请求先由认证Filter拒绝
+		{subject: "", expect: flux.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		ctxw := engine.acquireContext("req-"+c.subject, "127.0.0.1", newAsyncTestWebContext("", c.subject), endpoint)
+		filters := engine.router.selectFilters(ctxw)
+		assert.Equal(TypeIdFeatureFlagFilter, filters[len(filters)-1].TypeId())
+		serr := engine.router.authenticate(ctxw)
+		if 0 == c.expect {
+			assert.Nil(serr, c.subject)
+		} else if assert.NotNil(serr, c.subject) {
+			assert.Equal(c.expect, serr.StatusCode, c.subject)
+		}
+		engine.releaseContext(ctxw)
+	}
+}
+
+func TestRouter_SelectFilters_WithoutFeatureFlag(t *testing.T) {
+	engine := newAsyncTestEngine(t)
+	endpoint := &flux.Endpoint{HttpMethod: http.MethodGet, HttpPattern: "/stable"}
+	ctxw := engine.acquireContext("req", "127.0.0.1", newAsyncTestWebContext("", "alice"), endpoint)
+	defer engine.releaseContext(ctxw)
+	for _, f := range engine.router.selectFilters(ctxw) {
+		assert2.NotEqual(t, TypeIdFeatureFlagFilter, f.TypeId())
+	}
+}
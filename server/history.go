@@ -0,0 +1,381 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"github.com/spf13/cast"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ConfigHistoryConfigKeyEnable      = "enable"
+	ConfigHistoryConfigKeySize        = "size"
+	ConfigHistoryConfigKeyDir         = "dir"
+	ConfigHistoryConfigKeyQuietPeriod = "quiet-period"
+)
+
+const (
+	// 配置版本的来源：注册中心推送等运行时变更，或者回滚操作
+	ConfigSourceApplied  = "applied"
+	ConfigSourceRollback = "rollback"
+	configHistoryPrefix  = "config-"
+)
+
+var (
+	ErrConfigVersionNotFound = errors.New("config version not found")
+)
+
+// ConfigVersion 一次应用后的运行时配置版本
+type ConfigVersion struct {
+	Version   int64            `json:"version"`
+	AppliedAt time.Time        `json:"appliedAt"`
+	Source    string           `json:"source"`
+	Digest    string           `json:"digest"`
+	Endpoints int              `json:"endpoints"`
+	Services  int              `json:"services"`
+	Snapshot  *RuntimeSnapshot `json:"snapshot,omitempty"`
+}
+
+// ConfigDiff 两个配置版本的差异：Endpoint按 METHOD#pattern#version 标识，Service按ID标识
+type ConfigDiff struct {
+	From             int64    `json:"from"`
+	To               int64    `json:"to"`
+	AddedEndpoints   []string `json:"addedEndpoints"`
+	RemovedEndpoints []string `json:"removedEndpoints"`
+	ChangedEndpoints []string `json:"changedEndpoints"`
+	AddedServices    []string `json:"addedServices"`
+	RemovedServices  []string `json:"removedServices"`
+	ChangedServices  []string `json:"changedServices"`
+}
+
+// ConfigHistory 保存最近N个已应用的运行时配置版本（路由表、后端服务和运行时开关），用于对比和回滚；
+// 运行时变更在静默期内合并为一个版本，内容未变化时不产生新版本。配置了目录时同时保存到本地文件，重启后恢复历史
+type ConfigHistory struct {
+	export  func() *RuntimeSnapshot
+	size    int
+	dir     string
+	quiet   time.Duration
+	mu      sync.Mutex
+	timer   *time.Timer
+	next    int64
+	history []*ConfigVersion
+	// 回滚操作串行执行
+	rollback sync.Mutex
+}
+
+func NewConfigHistory(export func() *RuntimeSnapshot, size int, dir string, quiet time.Duration) *ConfigHistory {
+	if size <= 0 {
+		size = 10
+	}
+	h := &ConfigHistory{export: export, size: size, dir: dir, quiet: quiet, next: 1}
+	h.load()
+	return h
+}
+
+// Touch 通知运行时配置发生变更；静默期结束后记录新的配置版本
+func (h *ConfigHistory) Touch() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if nil != h.timer {
+		h.timer.Stop()
+	}
+	h.timer = time.AfterFunc(h.quiet, func() {
+		h.Record(ConfigSourceApplied)
+	})
+}
+
+// Close 停止未执行的记录任务
+func (h *ConfigHistory) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if nil != h.timer {
+		h.timer.Stop()
+	}
+}
+
+// Record 记录当前的运行时配置；与最新版本内容相同时不记录，返回最新版本
+func (h *ConfigHistory) Record(source string) *ConfigVersion {
+	snapshot := h.export()
+	digest := digestOfSnapshot(snapshot)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if n := len(h.history); n > 0 && h.history[n-1].Digest == digest {
+		return h.history[n-1]
+	}
+	version := &ConfigVersion{
+		Version:   h.next,
+		AppliedAt: snapshot.CreatedAt,
+		Source:    source,
+		Digest:    digest,
+		Endpoints: len(snapshot.Endpoints),
+		Services:  len(snapshot.Services),
+		Snapshot:  snapshot,
+	}
+	h.next++
+	h.history = append(h.history, version)
+	h.persist(version)
+	for len(h.history) > h.size {
+		h.evict(h.history[0])
+		h.history = h.history[1:]
+	}
+	logger.Infow("Config history recorded", "version", version.Version, "source", source, "digest", digest)
+	return version
+}
+
+// Versions 返回全部历史版本，不包含快照数据
+func (h *ConfigHistory) Versions() []ConfigVersion {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]ConfigVersion, len(h.history))
+	for i, v := range h.history {
+		out[i] = *v
+		out[i].Snapshot = nil
+	}
+	return out
+}
+
+// Get 返回指定的历史版本
+func (h *ConfigHistory) Get(version int64) (*ConfigVersion, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, v := range h.history {
+		if version == v.Version {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func (h *ConfigHistory) fileOf(version int64) string {
+	return filepath.Join(h.dir, fmt.Sprintf("%s%d.json", configHistoryPrefix, version))
+}
+
+func (h *ConfigHistory) persist(version *ConfigVersion) {
+	if "" == h.dir {
+		return
+	}
+	data, err := json.Marshal(version)
+	if nil == err {
+		err = NewFileSnapshotStore(h.dir).Save(fmt.Sprintf("%s%d", configHistoryPrefix, version.Version), data)
+	}
+	if nil != err {
+		logger.Warnw("Config history save failed", "version", version.Version, "error", err)
+	}
+}
+
+func (h *ConfigHistory) evict(version *ConfigVersion) {
+	if "" == h.dir {
+		return
+	}
+	if err := os.Remove(h.fileOf(version.Version)); nil != err && !os.IsNotExist(err) {
+		logger.Warnw("Config history remove failed", "version", version.Version, "error", err)
+	}
+}
+
+// load 从目录恢复历史版本；只保留最新的N个版本
+func (h *ConfigHistory) load() {
+	if "" == h.dir {
+		return
+	}
+	files, err := filepath.Glob(filepath.Join(h.dir, configHistoryPrefix+"*.json"))
+	if nil != err {
+		return
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if nil != err {
+			logger.Warnw("Config history load failed", "file", file, "error", err)
+			continue
+		}
+		version := new(ConfigVersion)
+		if err := json.Unmarshal(data, version); nil != err || nil == version.Snapshot {
+			logger.Warnw("Config history load failed", "file", file, "error", err)
+			continue
+		}
+		h.history = append(h.history, version)
+	}
+	sort.Slice(h.history, func(i, j int) bool {
+		return h.history[i].Version < h.history[j].Version
+	})
+	for len(h.history) > h.size {
+		h.evict(h.history[0])
+		h.history = h.history[1:]
+	}
+	if n := len(h.history); n > 0 {
+		h.next = h.history[n-1].Version + 1
+		logger.Infow("Config history loaded", "versions", n, "latest", h.history[n-1].Version)
+	}
+}
+
+// digestOfSnapshot 按路由表、后端服务和运行时开关计算配置摘要，不包含快照创建时间
+func digestOfSnapshot(snapshot *RuntimeSnapshot) string {
+	data, _ := json.Marshal([]interface{}{snapshot.Endpoints, snapshot.Services, snapshot.Toggles})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// DiffSnapshots 对比两个运行时快照的Endpoint和Service差异
+func DiffSnapshots(from, to *RuntimeSnapshot) ConfigDiff {
+	diff := ConfigDiff{}
+	endpoints := func(snapshot *RuntimeSnapshot) map[string]interface{} {
+		m := make(map[string]interface{}, len(snapshot.Endpoints))
+		for _, endpoint := range snapshot.Endpoints {
+			m[snapshotEndpointKey(endpoint)] = endpoint
+		}
+		return m
+	}
+	services := func(snapshot *RuntimeSnapshot) map[string]interface{} {
+		m := make(map[string]interface{}, len(snapshot.Services))
+		for id, service := range snapshot.Services {
+			m[id] = service
+		}
+		return m
+	}
+	diff.AddedEndpoints, diff.RemovedEndpoints, diff.ChangedEndpoints = diffKeyed(endpoints(from), endpoints(to))
+	diff.AddedServices, diff.RemovedServices, diff.ChangedServices = diffKeyed(services(from), services(to))
+	return diff
+}
+
+// diffKeyed 按Key对比；值按JSON编码比较，避免从文件恢复的快照因数值类型不同被判定为变更
+func diffKeyed(from, to map[string]interface{}) (added, removed, changed []string) {
+	added, removed, changed = []string{}, []string{}, []string{}
+	for key, value := range to {
+		if old, ok := from[key]; !ok {
+			added = append(added, key)
+		} else if !jsonEqual(old, value) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range from {
+		if _, ok := to[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
+
+// RollbackConfig 回滚到指定的历史配置版本：校验快照后以 replace 模式恢复，并记录为新的配置版本；回滚操作串行执行
+func (s *HttpServeEngine) RollbackConfig(version int64) (*ConfigVersion, SnapshotRestoreResult, error) {
+	h := s.configHistory
+	if nil == h {
+		return nil, SnapshotRestoreResult{}, ErrConfigVersionNotFound
+	}
+	h.rollback.Lock()
+	defer h.rollback.Unlock()
+	target, ok := h.Get(version)
+	if !ok {
+		return nil, SnapshotRestoreResult{}, ErrConfigVersionNotFound
+	}
+	for _, endpoint := range target.Snapshot.Endpoints {
+		if !isAllowedHttpMethod(strings.ToUpper(endpoint.HttpMethod)) || "" == endpoint.HttpPattern {
+			return nil, SnapshotRestoreResult{}, fmt.Errorf("config version %d has invalid endpoint: %s", version, snapshotEndpointKey(endpoint))
+		}
+	}
+	result, err := s.RestoreSnapshot(target.Snapshot, SnapshotRestoreReplace)
+	if nil != err {
+		return nil, result, err
+	}
+	logger.Infow("Config rolled back", "version", version)
+	return h.Record(fmt.Sprintf("%s:%d", ConfigSourceRollback, version)), result, nil
+}
+
+// NewDebugConfigHistoryHandler 运行时配置历史管理：GET返回历史版本列表；
+// GET参数 action=show&version=N，返回指定版本的快照；
+// GET参数 action=diff&from=N&to=M，返回两个版本的差异，未指定to时与当前运行时配置对比；
+// POST参数 action=rollback&version=N，回滚到指定版本
+func NewDebugConfigHistoryHandler(s *HttpServeEngine) http.HandlerFunc {
+	serializer := s.extensions.LoadSerializer(ext.TypeNameSerializerJson)
+	failed := func(message string, err error) interface{} {
+		return map[string]string{
+			"status":  "failed",
+			"message": message,
+			"error":   fmt.Sprintf("%v", err),
+		}
+	}
+	snapshotOf := func(param string) (*RuntimeSnapshot, int64, error) {
+		if "" == param {
+			return s.ExportSnapshot(), 0, nil
+		}
+		version := cast.ToInt64(param)
+		if v, ok := s.configHistory.Get(version); ok {
+			return v.Snapshot, version, nil
+		}
+		return nil, version, ErrConfigVersionNotFound
+	}
+	return newSerializableHttpHandler(serializer, func(request *http.Request) interface{} {
+		switch action := request.FormValue("action"); action {
+		case "":
+			return s.configHistory.Versions()
+		case "show":
+			v, ok := s.configHistory.Get(cast.ToInt64(request.FormValue("version")))
+			if !ok {
+				return failed("show config version", ErrConfigVersionNotFound)
+			}
+			return v
+		case "diff":
+			from, fromVersion, err := snapshotOf(request.FormValue("from"))
+			if nil != err || 0 == fromVersion {
+				return failed("param is required: from=<version>", err)
+			}
+			to, toVersion, err := snapshotOf(request.FormValue("to"))
+			if nil != err {
+				return failed("load config version", err)
+			}
+			diff := DiffSnapshots(from, to)
+			diff.From, diff.To = fromVersion, toVersion
+			return diff
+		case "rollback":
+			if http.MethodPost != request.Method && http.MethodPut != request.Method {
+				return failed("rollback config", errors.New("POST method is required"))
+			}
+			version, result, err := s.RollbackConfig(cast.ToInt64(request.FormValue("version")))
+			if nil != err {
+				return failed("rollback config", err)
+			}
+			return map[string]interface{}{"status": "success", "version": version, "result": result}
+		default:
+			return map[string]string{
+				"status":  "failed",
+				"message": "param is invalid: action=show|diff|rollback",
+			}
+		}
+	})
+}
+
+func (s *HttpServeEngine) initConfigHistory(config *flux.Configuration) {
+	config.SetDefaults(map[string]interface{}{
+		ConfigHistoryConfigKeyEnable:      true,
+		ConfigHistoryConfigKeySize:        10,
+		ConfigHistoryConfigKeyQuietPeriod: "2s",
+	})
+	if !config.GetBool(ConfigHistoryConfigKeyEnable) {
+		return
+	}
+	s.configHistory = NewConfigHistory(s.ExportSnapshot, config.GetInt(ConfigHistoryConfigKeySize),
+		config.GetString(ConfigHistoryConfigKeyDir), config.GetDuration(ConfigHistoryConfigKeyQuietPeriod))
+}
+
+func jsonEqual(a, b interface{}) bool {
+	x, err := json.Marshal(a)
+	if nil != err {
+		return false
+	}
+	y, err := json.Marshal(b)
+	return nil == err && string(x) == string(y)
+}
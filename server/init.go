@@ -22,6 +22,13 @@ func init() {
 	// Default: ZK
 	ext.StoreEndpointRegistryFactory(ext.EndpointRegistryIdDefault, registry.ZkEndpointRegistryFactory)
 	ext.StoreEndpointRegistryFactory(ext.EndpointRegistryIdZookeeper, registry.ZkEndpointRegistryFactory)
+	// Secret provider
+	// Default: configuration
+	ext.StoreSecretProvider(support.NewConfigSecretProvider(support.DefaultSecretConfigNamespace))
+	// Feature flag provider
+	// Default: configuration
+	ext.StoreFeatureFlagProvider(support.NewConfigFeatureFlagProvider(
+		support.DefaultFeatureFlagConfigNamespace, support.DefaultFeatureFlagTenantAttribute))
 	// Server
 	SetServerWriterSerializer(serializer)
 	SetServerResponseContentType(flux.MIMEApplicationJSONCharsetUTF8)
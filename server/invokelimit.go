@@ -0,0 +1,127 @@
+package server
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	InvokeLimitConfigKeyEnable        = "enable"
+	InvokeLimitConfigKeyMaxConcurrent = "max-concurrent"
+	InvokeLimitConfigKeyMaxWaiting    = "max-waiting"
+	InvokeLimitConfigKeyWaitTimeout   = "wait-timeout"
+	InvokeLimitConfigKeyOverflow      = "overflow"
+)
+
+const (
+	// 等待数量已满时立即拒绝请求
+	InvokeOverflowReject = "reject"
+	// 等待数量已满时不受并发限制，直接执行
+	InvokeOverflowCallerRuns = "caller-runs"
+)
+
+// invokeLimiter 后端调用的并发限制器：限制同时进行中的上游调用数量，后端调用在请求协程中执行。
+// 并发已满时请求等待空闲配额，等待超过 wait-timeout 或请求被取消时拒绝；等待数量已满时按 overflow 策略拒绝或直接执行。
+type invokeLimiter struct {
+	slots       chan struct{}
+	waiting     int32
+	maxWaiting  int32
+	waitTimeout time.Duration
+	overflow    string
+	metrics     *Metrics
+}
+
+func newInvokeLimiter(config *flux.Configuration, metrics *Metrics) *invokeLimiter {
+	config.SetDefaults(map[string]interface{}{
+		InvokeLimitConfigKeyEnable:        false,
+		InvokeLimitConfigKeyMaxConcurrent: 256,
+		InvokeLimitConfigKeyMaxWaiting:    1024,
+		InvokeLimitConfigKeyWaitTimeout:   time.Second,
+		InvokeLimitConfigKeyOverflow:      InvokeOverflowReject,
+	})
+	if !config.GetBool(InvokeLimitConfigKeyEnable) {
+		return nil
+	}
+	concurrent := config.GetInt(InvokeLimitConfigKeyMaxConcurrent)
+	if concurrent <= 0 {
+		concurrent = 256
+	}
+	limiter := &invokeLimiter{
+		slots:       make(chan struct{}, concurrent),
+		maxWaiting:  config.GetInt32(InvokeLimitConfigKeyMaxWaiting),
+		waitTimeout: config.GetDuration(InvokeLimitConfigKeyWaitTimeout),
+		overflow:    config.GetString(InvokeLimitConfigKeyOverflow),
+		metrics:     metrics,
+	}
+	logger.Infow("Invoke limit enabled", "max-concurrent", concurrent, "max-waiting", limiter.maxWaiting,
+		"wait-timeout", limiter.waitTimeout, "overflow", limiter.overflow)
+	return limiter
+}
+
+// execute 获取并发配额后在当前协程中执行后端调用
+func (l *invokeLimiter) execute(ctx flux.Context, run func()) *flux.ServeError {
+	acquired, serr := l.acquire(ctx)
+	if nil != serr {
+		return serr
+	}
+	l.metrics.InvokeActive.Inc()
+	defer func() {
+		l.metrics.InvokeActive.Dec()
+		if acquired {
+			<-l.slots
+		}
+	}()
+	run()
+	return nil
+}
+
+// acquire 获取并发配额；等待数量已满且 overflow 为 caller-runs 时，不占用配额并返回false
+func (l *invokeLimiter) acquire(ctx flux.Context) (bool, *flux.ServeError) {
+	select {
+	case l.slots <- struct{}{}:
+		return true, nil
+	default:
+	}
+	if atomic.AddInt32(&l.waiting, 1) > l.maxWaiting {
+		atomic.AddInt32(&l.waiting, -1)
+		if InvokeOverflowCallerRuns == l.overflow {
+			return false, nil
+		}
+		l.metrics.InvokeRejected.WithLabelValues("full").Inc()
+		return false, l.rejected()
+	}
+	l.metrics.InvokeWaiting.Inc()
+	defer func() {
+		atomic.AddInt32(&l.waiting, -1)
+		l.metrics.InvokeWaiting.Dec()
+	}()
+	var timeout <-chan time.Time
+	if l.waitTimeout > 0 {
+		timer := time.NewTimer(l.waitTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	start := time.Now()
+	select {
+	case l.slots <- struct{}{}:
+		l.metrics.InvokeWait.Observe(time.Since(start).Seconds())
+		return true, nil
+	case <-timeout:
+		l.metrics.InvokeRejected.WithLabelValues("timeout").Inc()
+		return false, l.rejected()
+	case <-ctx.Context().Done():
+		l.metrics.InvokeRejected.WithLabelValues("canceled").Inc()
+		return false, l.rejected()
+	}
+}
+
+func (l *invokeLimiter) rejected() *flux.ServeError {
+	return &flux.ServeError{
+		StatusCode: http.StatusServiceUnavailable,
+		ErrorCode:  flux.ErrorCodeGatewayOverload,
+		Message:    flux.ErrorMessageInvokeLimitOverflow,
+	}
+}
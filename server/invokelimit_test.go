@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/support"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/viper"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// invokeLimitTestContext 测试用Context：支持取消
+type invokeLimitTestContext struct {
+	*support.ValuesContext
+	ctx context.Context
+}
+
+func (c *invokeLimitTestContext) Context() context.Context {
+	return c.ctx
+}
+
+func newInvokeLimitTestContext(ctx context.Context) flux.Context {
+	return &invokeLimitTestContext{
+		ValuesContext: support.NewValuesContext(map[string]interface{}{}).(*support.ValuesContext),
+		ctx:           ctx,
+	}
+}
+
+func newTestInvokeLimiter(values map[string]interface{}) *invokeLimiter {
+	v := viper.New()
+	v.Set(InvokeLimitConfigKeyEnable, true)
+	for key, value := range values {
+		v.Set(key, value)
+	}
+	return newInvokeLimiter(flux.NewConfiguration(v), NewMetricsWith(prometheus.NewRegistry()))
+}
+
+// occupyInvokeLimit 占用全部并发配额，返回释放函数
+func occupyInvokeLimit(t *testing.T, l *invokeLimiter, n int) func() {
+	release, started := make(chan struct{}), make(chan struct{}, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			_ = l.execute(newInvokeLimitTestContext(context.Background()), func() {
+				started <- struct{}{}
+				<-release
+			})
+		}()
+	}
+	for i := 0; i < n; i++ {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("invoke not started")
+		}
+	}
+	return func() {
+		close(release)
+	}
+}
+
+func TestNewInvokeLimiter_Disabled(t *testing.T) {
+	assert2.Nil(t, newInvokeLimiter(flux.NewConfiguration(viper.New()), nil))
+}
+
+func TestInvokeLimiter_Execute(t *testing.T) {
+	assert := assert2.New(t)
+	l := newTestInvokeLimiter(map[string]interface{}{InvokeLimitConfigKeyMaxConcurrent: 2})
+	invoked := false
+	assert.Nil(l.execute(newInvokeLimitTestContext(context.Background()), func() {
+		invoked = true
+	}))
+	assert.True(invoked)
+	assert.Equal(0, len(l.slots), "slot must be released")
+}
+
+func TestInvokeLimiter_WaitForSlot(t *testing.T) {
+	assert := assert2.New(t)
+	l := newTestInvokeLimiter(map[string]interface{}{
+		InvokeLimitConfigKeyMaxConcurrent: 1,
+		InvokeLimitConfigKeyWaitTimeout:   time.Second,
+	})
+	release := occupyInvokeLimit(t, l, 1)
+	time.AfterFunc(20*time.Millisecond, release)
+	invoked := false
+	assert.Nil(l.execute(newInvokeLimitTestContext(context.Background()), func() {
+		invoked = true
+	}))
+	assert.True(invoked)
+}
+
+func TestInvokeLimiter_Rejected(t *testing.T) {
+	assert := assert2.New(t)
+	// 等待超时
+	l := newTestInvokeLimiter(map[string]interface{}{
+		InvokeLimitConfigKeyMaxConcurrent: 1,
+		InvokeLimitConfigKeyWaitTimeout:   10 * time.Millisecond,
+	})
+	release := occupyInvokeLimit(t, l, 1)
+	defer release()
+	serr := l.execute(newInvokeLimitTestContext(context.Background()), func() {
+		assert.Fail("must not invoke")
+	})
+	if assert.NotNil(serr) {
+		assert.Equal(http.StatusServiceUnavailable, serr.StatusCode)
+		assert.Equal(flux.ErrorCodeGatewayOverload, serr.ErrorCode)
+	}
+	// 请求已取消
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l.waitTimeout = 0
+	assert.NotNil(l.execute(newInvokeLimitTestContext(ctx), func() {
+		assert.Fail("must not invoke")
+	}))
+	// 等待数量已满
+	l.maxWaiting = 0
+	assert.NotNil(l.execute(newInvokeLimitTestContext(context.Background()), func() {
+		assert.Fail("must not invoke")
+	}))
+	assert.Equal(int32(0), l.waiting)
+}
+
+func TestInvokeLimiter_CallerRuns(t *testing.T) {
+	assert := assert2.New(t)
+	l := newTestInvokeLimiter(map[string]interface{}{
+		InvokeLimitConfigKeyMaxConcurrent: 1,
+		InvokeLimitConfigKeyMaxWaiting:    0,
+		InvokeLimitConfigKeyOverflow:      InvokeOverflowCallerRuns,
+	})
+	release := occupyInvokeLimit(t, l, 1)
+	defer release()
+	invoked := false
+	assert.Nil(l.execute(newInvokeLimitTestContext(context.Background()), func() {
+		invoked = true
+	}))
+	assert.True(invoked)
+	assert.Equal(1, len(l.slots), "caller-runs must not take a slot")
+}
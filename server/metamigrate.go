@@ -0,0 +1,264 @@
+package server
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/registry"
+	"github.com/bytepowered/flux/remoting/zk"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	EndpointStoreFile      = "file"
+	EndpointStoreZookeeper = "zookeeper"
+)
+
+// RunMetadataMigrate 执行Endpoint元数据迁移命令：
+// flux migrate -source zookeeper[:endpoint-path] -target file:endpoints.json [-dry-run]；返回进程退出码。
+// ZK连接使用全局配置 zookeeper.*；transforms 为迁移时的Endpoint转换函数。
+func RunMetadataMigrate(args []string, transforms ...registry.EndpointTransformFunc) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	source := fs.String("source", EndpointStoreZookeeper, "source store: zookeeper[:endpoint-path] or file:<path>")
+	target := fs.String("target", "", "target store: zookeeper[:endpoint-path] or file:<path>")
+	dryRun := fs.Bool("dry-run", false, "print diff without writing target store")
+	if err := fs.Parse(args); nil != err {
+		return 2
+	}
+	InitConfiguration(EnvKeyDeployEnv)
+	src, closeSrc, err := openEndpointStore(*source)
+	if nil != err {
+		_, _ = fmt.Fprintf(os.Stderr, "open source store: %s\n", err)
+		return 2
+	}
+	defer closeSrc()
+	dst, closeDst, err := openEndpointStore(*target)
+	if nil != err {
+		_, _ = fmt.Fprintf(os.Stderr, "open target store: %s\n", err)
+		return 2
+	}
+	defer closeDst()
+	migrator := &registry.EndpointMigrator{Source: src, Target: dst, Transforms: transforms, DryRun: *dryRun}
+	diff, err := migrator.Migrate()
+	if nil != diff {
+		writeMigrationDiff(os.Stdout, diff, *dryRun)
+	}
+	if nil != err {
+		_, _ = fmt.Fprintf(os.Stderr, "migrate: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// RunDubboGenerate 执行Dubbo草稿Endpoint生成命令：
+// flux generate-dubbo -target file:drafts.json [-interfaces a,b] [-prefix /api] [-dry-run]；返回进程退出码。
+// 生成的Endpoint带有 draft 扩展属性，审核后可通过 flux migrate 发布到注册中心。
+func RunDubboGenerate(args []string) int {
+	fs := flag.NewFlagSet("generate-dubbo", flag.ContinueOnError)
+	interfaces := fs.String("interfaces", "", "dubbo interfaces, separated by comma; empty for all")
+	root := fs.String("root", "", "dubbo registry root path, default /dubbo")
+	prefix := fs.String("prefix", "", "http pattern prefix")
+	target := fs.String("target", "", "target store: zookeeper[:endpoint-path] or file:<path>")
+	dryRun := fs.Bool("dry-run", false, "print generated endpoints without writing target store")
+	if err := fs.Parse(args); nil != err {
+		return 2
+	}
+	InitConfiguration(EnvKeyDeployEnv)
+	retriever, err := startZookeeperRetriever()
+	if nil != err {
+		_, _ = fmt.Fprintf(os.Stderr, "connect dubbo registry: %s\n", err)
+		return 2
+	}
+	defer retriever.Shutdown(context.Background())
+	names := make([]string, 0)
+	for _, name := range strings.Split(*interfaces, ",") {
+		if name = strings.TrimSpace(name); "" != name {
+			names = append(names, name)
+		}
+	}
+	providers, err := registry.NewDubboZookeeperProviderSource(retriever, *root).LoadProviders(names...)
+	if nil != err {
+		_, _ = fmt.Fprintf(os.Stderr, "load dubbo providers: %s\n", err)
+		return 1
+	}
+	endpoints := registry.DubboEndpointGenerator{PathPrefix: *prefix}.Generate(providers...)
+	for _, endpoint := range endpoints {
+		_, _ = fmt.Fprintf(os.Stdout, "+ %s -> %s\n", registry.EndpointKey(endpoint), endpoint.Service.ServiceID())
+	}
+	if *dryRun {
+		return 0
+	}
+	dst, closeDst, err := openEndpointStore(*target)
+	if nil != err {
+		_, _ = fmt.Fprintf(os.Stderr, "open target store: %s\n", err)
+		return 2
+	}
+	defer closeDst()
+	if err := dst.StoreEndpoints(endpoints); nil != err {
+		_, _ = fmt.Fprintf(os.Stderr, "store endpoints: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// RunRouteImport 执行Java网关路由导入命令：
+// flux import-routes -format scg|zuul -file routes.yml -target file:endpoints.json [-dry-run]；返回进程退出码。
+func RunRouteImport(args []string) int {
+	fs := flag.NewFlagSet("import-routes", flag.ContinueOnError)
+	format := fs.String("format", registry.RouteFormatSpringCloudGateway, "route format: scg or zuul")
+	file := fs.String("file", "", "route yaml file")
+	target := fs.String("target", "", "target store: zookeeper[:endpoint-path] or file:<path>")
+	dryRun := fs.Bool("dry-run", false, "print imported endpoints without writing target store")
+	if err := fs.Parse(args); nil != err {
+		return 2
+	}
+	data, err := ioutil.ReadFile(*file)
+	if nil != err {
+		_, _ = fmt.Fprintf(os.Stderr, "read route file: %s\n", err)
+		return 2
+	}
+	result, err := registry.ImportRoutes(*format, data)
+	if nil != err {
+		_, _ = fmt.Fprintf(os.Stderr, "import routes: %s\n", err)
+		return 1
+	}
+	for _, warning := range result.Warnings {
+		_, _ = fmt.Fprintf(os.Stderr, "WARN %s\n", warning)
+	}
+	for _, endpoint := range result.Endpoints {
+		_, _ = fmt.Fprintf(os.Stdout, "+ %s -> %s%s\n", registry.EndpointKey(endpoint), endpoint.Service.RemoteHost, endpoint.Service.Interface)
+	}
+	if *dryRun {
+		return 0
+	}
+	InitConfiguration(EnvKeyDeployEnv)
+	dst, closeDst, err := openEndpointStore(*target)
+	if nil != err {
+		_, _ = fmt.Fprintf(os.Stderr, "open target store: %s\n", err)
+		return 2
+	}
+	defer closeDst()
+	if err := dst.StoreEndpoints(result.Endpoints); nil != err {
+		_, _ = fmt.Fprintf(os.Stderr, "store endpoints: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// RunEdgeExport 执行边缘代理配置导出命令：
+// flux export-edge -format nginx|envoy -source file:endpoints.json [-upstream flux_gateway]；配置输出到标准输出，返回进程退出码。
+func RunEdgeExport(args []string) int {
+	fs := flag.NewFlagSet("export-edge", flag.ContinueOnError)
+	format := fs.String("format", EdgeExportFormatNginx, "export format: nginx or envoy")
+	source := fs.String("source", EndpointStoreZookeeper, "source store: zookeeper[:endpoint-path] or file:<path>")
+	upstream := fs.String("upstream", DefaultEdgeUpstream, "upstream or cluster name of the gateway")
+	if err := fs.Parse(args); nil != err {
+		return 2
+	}
+	InitConfiguration(EnvKeyDeployEnv)
+	src, closeSrc, err := openEndpointStore(*source)
+	if nil != err {
+		_, _ = fmt.Fprintf(os.Stderr, "open source store: %s\n", err)
+		return 2
+	}
+	defer closeSrc()
+	endpoints, err := src.LoadEndpoints()
+	if nil != err {
+		_, _ = fmt.Fprintf(os.Stderr, "load endpoints: %s\n", err)
+		return 1
+	}
+	data, err := ExportEdgeRoutes(*format, EdgeRoutesOf(endpoints), *upstream)
+	if nil != err {
+		_, _ = fmt.Fprintf(os.Stderr, "export: %s\n", err)
+		return 2
+	}
+	_, _ = os.Stdout.Write(data)
+	return 0
+}
+
+// RunSDKGenerate 从Endpoint存储生成客户端SDK源码，输出到标准输出
+func RunSDKGenerate(args []string) int {
+	fs := flag.NewFlagSet("generate-sdk", flag.ContinueOnError)
+	language := fs.String("lang", SDKLanguageGo, "sdk language: go or typescript")
+	source := fs.String("source", EndpointStoreZookeeper, "source store: zookeeper[:endpoint-path] or file:<path>")
+	pkg := fs.String("package", DefaultSDKPackage, "package name of the go sdk")
+	if err := fs.Parse(args); nil != err {
+		return 2
+	}
+	InitConfiguration(EnvKeyDeployEnv)
+	src, closeSrc, err := openEndpointStore(*source)
+	if nil != err {
+		_, _ = fmt.Fprintf(os.Stderr, "open source store: %s\n", err)
+		return 2
+	}
+	defer closeSrc()
+	endpoints, err := src.LoadEndpoints()
+	if nil != err {
+		_, _ = fmt.Fprintf(os.Stderr, "load endpoints: %s\n", err)
+		return 1
+	}
+	data, err := GenerateClientSDK(*language, SDKMethodsOf(endpoints), *pkg)
+	if nil != err {
+		_, _ = fmt.Fprintf(os.Stderr, "generate: %s\n", err)
+		return 2
+	}
+	_, _ = os.Stdout.Write(data)
+	return 0
+}
+
+func startZookeeperRetriever() (*zk.ZookeeperRetriever, error) {
+	config := flux.NewConfigurationOf("zookeeper")
+	config.SetDefault("timeout", time.Second*10)
+	retriever := zk.NewZookeeperRetriever()
+	if err := retriever.Init(config); nil != err {
+		return nil, err
+	}
+	return retriever, retriever.Startup()
+}
+
+// openEndpointStore 根据 kind[:arg] 格式的描述打开Endpoint存储，返回存储和关闭函数
+func openEndpointStore(spec string) (registry.EndpointStore, func(), error) {
+	kind, arg := spec, ""
+	if i := strings.Index(spec, ":"); i > 0 {
+		kind, arg = spec[:i], spec[i+1:]
+	}
+	switch kind {
+	case EndpointStoreFile:
+		if "" == arg {
+			return nil, nil, fmt.Errorf("file path is required: %s", spec)
+		}
+		return registry.NewFileEndpointStore(arg), func() {}, nil
+	case EndpointStoreZookeeper:
+		retriever, err := startZookeeperRetriever()
+		if nil != err {
+			return nil, nil, err
+		}
+		return registry.NewZookeeperEndpointStore(retriever, arg), func() {
+			_ = retriever.Shutdown(context.Background())
+		}, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported endpoint store: %s", spec)
+	}
+}
+
+func writeMigrationDiff(w io.Writer, diff *registry.MigrationDiff, dryRun bool) {
+	for _, item := range []struct {
+		mark string
+		keys []string
+	}{{"+", diff.Added}, {"~", diff.Updated}, {"-", diff.Skipped}} {
+		for _, key := range item.keys {
+			_, _ = fmt.Fprintf(w, "%s %s\n", item.mark, key)
+		}
+	}
+	mode := ""
+	if dryRun {
+		mode = " (dry-run)"
+	}
+	_, _ = fmt.Fprintf(w, "%d added, %d updated, %d unchanged, %d skipped%s\n",
+		len(diff.Added), len(diff.Updated), len(diff.Unchanged), len(diff.Skipped), mode)
+}
@@ -2,7 +2,6 @@ package server
 
 import (
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 var (
@@ -33,28 +32,79 @@ type Metrics struct {
 	EndpointAccess *prometheus.CounterVec
 	EndpointError  *prometheus.CounterVec
 	RouteDuration  *prometheus.HistogramVec
+	StreamActive   prometheus.Gauge
+	StreamStalled  *prometheus.CounterVec
+	InvokeWaiting  prometheus.Gauge
+	InvokeActive   prometheus.Gauge
+	InvokeRejected *prometheus.CounterVec
+	InvokeWait     prometheus.Histogram
 }
 
 func NewMetrics() *Metrics {
-	return &Metrics{
-		EndpointAccess: promauto.NewCounterVec(prometheus.CounterOpts{
+	return NewMetricsWith(prometheus.DefaultRegisterer)
+}
+
+// NewMetricsWith 创建Metrics，并注册到指定的Registerer
+func NewMetricsWith(registerer prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		EndpointAccess: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Namespace: defaultMetricNamespace,
 			Subsystem: defaultMetricSubsystem,
 			Name:      "endpoint_access_total",
 			Help:      "Number of endpoint access",
 		}, []string{"ProtoName", "Interface", "Method"}),
-		EndpointError: promauto.NewCounterVec(prometheus.CounterOpts{
+		EndpointError: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Namespace: defaultMetricNamespace,
 			Subsystem: defaultMetricSubsystem,
 			Name:      "endpoint_error_total",
 			Help:      "Number of endpoint access errors",
-		}, []string{"ProtoName", "Interface", "Method", "ErrorCode"}),
-		RouteDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+		}, []string{"ProtoName", "Interface", "Method", "ErrorCode", "ErrorCategory"}),
+		RouteDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
 			Namespace: defaultMetricNamespace,
 			Subsystem: defaultMetricSubsystem,
 			Name:      "endpoint_route_duration",
 			Help:      "Spend time by processing a endpoint",
 			Buckets:   defaultMetricBuckets,
 		}, []string{"ComponentType", "TypeId"}),
+		StreamActive: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: defaultMetricNamespace,
+			Subsystem: defaultMetricSubsystem,
+			Name:      "stream_active",
+			Help:      "Number of streaming responses in progress",
+		}),
+		StreamStalled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: defaultMetricNamespace,
+			Subsystem: defaultMetricSubsystem,
+			Name:      "stream_stalled_total",
+			Help:      "Number of streaming responses aborted by stalled client writer",
+		}, []string{"Action"}),
+		InvokeWaiting: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: defaultMetricNamespace,
+			Subsystem: defaultMetricSubsystem,
+			Name:      "invoke_limit_waiting",
+			Help:      "Number of backend invocations waiting for invoke limit slots",
+		}),
+		InvokeActive: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: defaultMetricNamespace,
+			Subsystem: defaultMetricSubsystem,
+			Name:      "invoke_limit_active",
+			Help:      "Number of backend invocations running under invoke limit",
+		}),
+		InvokeRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: defaultMetricNamespace,
+			Subsystem: defaultMetricSubsystem,
+			Name:      "invoke_limit_rejected_total",
+			Help:      "Number of backend invocations rejected by invoke limit",
+		}, []string{"Reason"}),
+		InvokeWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: defaultMetricNamespace,
+			Subsystem: defaultMetricSubsystem,
+			Name:      "invoke_limit_wait_duration",
+			Help:      "Spend time by backend invocations waiting for invoke limit slots",
+			Buckets:   defaultMetricBuckets,
+		}),
 	}
+	registerer.MustRegister(m.EndpointAccess, m.EndpointError, m.RouteDuration, m.StreamActive, m.StreamStalled,
+		m.InvokeWaiting, m.InvokeActive, m.InvokeRejected, m.InvokeWait)
+	return m
 }
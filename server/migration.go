@@ -0,0 +1,316 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/support"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+)
+
+const (
+	MigrationConfigKeyLegacyUpstream    = "legacy-upstream"
+	MigrationConfigKeyLegacyPaths       = "legacy-paths"
+	MigrationConfigKeyUnmatchedToLegacy = "unmatched-to-legacy"
+	MigrationConfigKeyPreserveHost      = "preserve-host"
+)
+
+const (
+	headerXForwardedHost  = "X-Forwarded-Host"
+	headerXForwardedProto = "X-Forwarded-Proto"
+)
+
+// migrationOptions Strangler迁移模式：指定路径和未匹配的请求透明代理到旧系统，已匹配的Endpoint路由到新服务
+type migrationOptions struct {
+	legacy        http.Handler
+	legacyPaths   []string
+	unmatched     bool
+	diffEndpoints map[string]struct{} // 开启响应差异对比的Endpoint路由Key（METHOD#pattern）
+}
+
+func newMigrationOptions(config *flux.Configuration) (*migrationOptions, error) {
+	config.SetDefaults(map[string]interface{}{
+		MigrationConfigKeyUnmatchedToLegacy: true,
+		MigrationConfigKeyPreserveHost:      true,
+	})
+	upstream := config.GetString(MigrationConfigKeyLegacyUpstream)
+	if "" == upstream {
+		return nil, nil
+	}
+	target, err := url.Parse(upstream)
+	if nil != err || "" == target.Scheme || "" == target.Host {
+		return nil, fmt.Errorf("migration.legacy-upstream is invalid: %s", upstream)
+	}
+	opts := &migrationOptions{
+		legacy:    newLegacyReverseProxy(target, config.GetBool(MigrationConfigKeyPreserveHost)),
+		unmatched: config.GetBool(MigrationConfigKeyUnmatchedToLegacy),
+	}
+	for _, path := range config.GetStringSlice(MigrationConfigKeyLegacyPaths) {
+		if !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("migration.legacy-paths is invalid: %s", path)
+		}
+		opts.legacyPaths = append(opts.legacyPaths, strings.TrimSuffix(path, "/"))
+	}
+	opts.diffEndpoints = make(map[string]struct{}, 4)
+	for _, key := range config.GetStringSlice(MigrationConfigKeyDiffEndpoints) {
+		method, pattern, ok := parseRouteKey(key)
+		if !ok {
+			return nil, fmt.Errorf("migration.diff-endpoints is invalid, must be METHOD#pattern: %s", key)
+		}
+		if !isDiffMethod(method) {
+			return nil, fmt.Errorf("migration.diff-endpoints only supports GET/HEAD: %s", key)
+		}
+		opts.diffEndpoints[method+"#"+pattern] = struct{}{}
+	}
+	logger.Infow("Migration mode enabled", "legacy-upstream", upstream, "legacy-paths", opts.legacyPaths,
+		"unmatched-to-legacy", opts.unmatched)
+	return opts, nil
+}
+
+// isLegacyPath 判断请求路径是否指定由旧系统处理；按路径段前缀匹配
+func (m *migrationOptions) isLegacyPath(path string) bool {
+	for _, prefix := range m.legacyPaths {
+		if "" == prefix || path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// newLegacyInterceptor 在路由前将指定路径的请求代理到旧系统
+func (m *migrationOptions) newLegacyInterceptor() flux.WebInterceptor {
+	legacy := flux.WrapHttpHandler(m.legacy)
+	return func(next flux.WebHandler) flux.WebHandler {
+		return func(webc flux.WebContext) error {
+			if u, _ := webc.RequestURL(); nil != u && m.isLegacyPath(u.Path) {
+				return legacy(webc)
+			}
+			return next(webc)
+		}
+	}
+}
+
+// newLegacyReverseProxy 创建旧系统反向代理；支持WebSocket协议升级。
+// 不保留Host时，改写旧系统返回的重定向地址和Cookie Domain，使其指向网关的Host。
+func newLegacyReverseProxy(target *url.URL, preserveHost bool) *httputil.ReverseProxy {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		host, proto := req.Host, "http"
+		if nil != req.TLS {
+			proto = "https"
+		}
+		director(req)
+		req.Header.Set(headerXForwardedHost, host)
+		req.Header.Set(headerXForwardedProto, proto)
+		if !preserveHost {
+			req.Host = target.Host
+		}
+	}
+	if preserveHost {
+		return proxy
+	}
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		host := resp.Request.Header.Get(headerXForwardedHost)
+		if "" == host {
+			return nil
+		}
+		if location, err := resp.Location(); nil == err && location.Host == target.Host {
+			location.Host = host
+			location.Scheme = resp.Request.Header.Get(headerXForwardedProto)
+			resp.Header.Set(flux.HeaderLocation, location.String())
+		}
+		cookies := resp.Cookies()
+		if len(cookies) == 0 {
+			return nil
+		}
+		resp.Header.Del(flux.HeaderSetCookie)
+		for _, cookie := range cookies {
+			if strings.EqualFold(strings.TrimPrefix(cookie.Domain, "."), target.Hostname()) {
+				cookie.Domain = ""
+			}
+			resp.Header.Add(flux.HeaderSetCookie, cookie.String())
+		}
+		return nil
+	}
+	return proxy
+}
+
+const (
+	// 同时进行的异步响应差异对比的上限
+	maxMigrationDiffInflight = 64
+)
+
+var (
+	migrationDiffInflight = make(chan struct{}, maxMigrationDiffInflight)
+)
+
+const (
+	MigrationConfigKeyDiffEndpoints = "diff-endpoints"
+	// Endpoint扩展属性：同时调用旧系统和新服务，输出旧系统响应并记录新服务响应的差异；只对GET/HEAD请求生效
+	MigrationExtKeyDiff = "migration-diff"
+)
+
+type (
+	// MigrationDiff 旧系统与新服务响应的差异记录
+	MigrationDiff struct {
+		RequestId    string
+		Method       string
+		Pattern      string
+		LegacyStatus int
+		ActualStatus int
+		Diffs        []support.JSONDiff
+	}
+	// MigrationDiffFunc 接收响应差异记录
+	MigrationDiffFunc func(ctx flux.Context, diff MigrationDiff)
+)
+
+// SetMigrationDiffFunc 设置迁移模式响应差异的记录函数；默认输出到日志
+func (s *HttpServeEngine) SetMigrationDiffFunc(f MigrationDiffFunc) {
+	s.migrationDiffFunc = f
+}
+
+// isDiffEndpoint 判断请求是否开启响应差异对比；请求会同时发送到两个系统，只对GET/HEAD请求开启
+func (m *migrationOptions) isDiffEndpoint(method string, endpoint *flux.Endpoint) bool {
+	if nil == m || !isDiffMethod(method) {
+		return false
+	}
+	if endpoint.ExtBool(MigrationExtKeyDiff) {
+		return true
+	}
+	_, ok := m.diffEndpoints[strings.ToUpper(endpoint.HttpMethod)+"#"+endpoint.HttpPattern]
+	return ok
+}
+
+func isDiffMethod(method string) bool {
+	method = strings.ToUpper(method)
+	return http.MethodGet == method || http.MethodHead == method
+}
+
+// routeWithLegacyDiff 向客户端输出旧系统的响应，然后使用复制的Context异步调用新服务，并记录新服务响应与其的差异。
+// 新服务的调用不影响客户端响应的延迟；异步对比已达上限时不对比。
+func (s *HttpServeEngine) routeWithLegacyDiff(webc flux.WebContext, ctxw *WrappedContext) (int, error) {
+	request, err := webc.HttpRequest()
+	if nil != err {
+		return flux.StatusServerError, err
+	}
+	// 旧系统：使用请求副本，避免消费原始请求Body
+	legacyReq := request.Clone(request.Context())
+	if body, err := webc.RequestBodyReader(); nil == err {
+		legacyReq.Body = body
+	}
+	// 不接受压缩响应，以便对比响应数据
+	legacyReq.Header.Del(flux.HeaderAcceptEncoding)
+	legacy := newResponseRecorder()
+	s.migration.legacy.ServeHTTP(legacy, legacyReq)
+	// 输出旧系统响应
+	header, _ := webc.ResponseHeader()
+	for name, values := range legacy.header {
+		header[name] = values
+	}
+	werr := webc.Write(legacy.status, legacy.header.Get(flux.HeaderContentType), legacy.body.Bytes())
+	// 新服务：异步调用并对比
+	select {
+	case migrationDiffInflight <- struct{}{}:
+	default:
+		logger.TraceContext(ctxw).Warnw("MIGRATION:DIFF, inflight overflow, skipped")
+		return legacy.status, werr
+	}
+	detached, err := detachContext(ctxw)
+	if nil != err {
+		<-migrationDiffInflight
+		logger.TraceContext(ctxw).Warnw("MIGRATION:DIFF, detach context", "error", err)
+		return legacy.status, werr
+	}
+	go func() {
+		defer func() {
+			if r := recover(); nil != r {
+				logger.TraceContext(detached).Errorw("MIGRATION:DIFF, route panic", "r", r)
+			}
+			detached.Release()
+			<-migrationDiffInflight
+		}()
+		s.diffLegacyResponse(detached, legacy)
+	}()
+	return legacy.status, werr
+}
+
+// diffLegacyResponse 执行新服务路由，记录新服务响应与旧系统响应的差异
+func (s *HttpServeEngine) diffLegacyResponse(ctxw *WrappedContext, legacy *responseRecorder) {
+	endpoint := ctxw.Endpoint()
+	diff := MigrationDiff{
+		RequestId:    ctxw.RequestId(),
+		Method:       endpoint.HttpMethod,
+		Pattern:      endpoint.HttpPattern,
+		LegacyStatus: legacy.status,
+	}
+	actual, status := s.routeToBytes(ctxw)
+	diff.ActualStatus = status
+	diff.Diffs = support.DiffJSONBytes(legacy.body.Bytes(), actual)
+	if diff.LegacyStatus == diff.ActualStatus && len(diff.Diffs) == 0 {
+		return
+	}
+	if nil != s.migrationDiffFunc {
+		s.migrationDiffFunc(ctxw, diff)
+	} else {
+		logger.TraceContext(ctxw).Infow("MIGRATION:DIFF", "method", diff.Method, "pattern", diff.Pattern,
+			"legacy-status", diff.LegacyStatus, "actual-status", diff.ActualStatus, "diffs", diff.Diffs)
+	}
+}
+
+// routeToBytes 执行新服务路由，返回序列化后的响应数据和状态码
+func (s *HttpServeEngine) routeToBytes(ctxw *WrappedContext) ([]byte, int) {
+	if serr := s.router.Route(ctxw); nil != serr {
+		resp := map[string]string{
+			"status":  "error",
+			"message": serr.Message,
+		}
+		if nil != serr.Internal {
+			resp["error"] = serr.Internal.Error()
+		}
+		data, _ := SerializeWith(serverWriterSerializer, resp)
+		return data, serr.StatusCode
+	}
+	response := ctxw.Response()
+	body := response.Body()
+	if r, ok := body.(io.Reader); ok {
+		if c, ok := r.(io.Closer); ok {
+			defer func() {
+				_ = c.Close()
+			}()
+		}
+		data, _ := ioutil.ReadAll(r)
+		return data, response.StatusCode()
+	}
+	data, _ := SerializeWith(serverWriterSerializer, body)
+	return data, response.StatusCode()
+}
+
+// responseRecorder 记录旧系统的响应
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{header: http.Header{}, status: http.StatusOK}
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	return r.body.Write(data)
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+}
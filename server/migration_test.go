@@ -0,0 +1,98 @@
+package server
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/spf13/viper"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// migrationTestTransport 测试用Backend：等待释放后返回固定响应
+type migrationTestTransport struct {
+	release chan struct{}
+}
+
+func (t *migrationTestTransport) Exchange(ctx flux.Context) *flux.ServeError {
+	<-t.release
+	ctx.Response().SetStatusCode(flux.StatusOK)
+	ctx.Response().SetBody(map[string]interface{}{"name": "new"})
+	return nil
+}
+
+func (t *migrationTestTransport) Invoke(flux.BackendService, flux.Context) (interface{}, *flux.ServeError) {
+	return nil, nil
+}
+
+func TestNewMigrationOptions_DiffMethod(t *testing.T) {
+	assert := assert2.New(t)
+	newConfig := func(diffs ...string) *flux.Configuration {
+		v := viper.New()
+		v.Set(MigrationConfigKeyLegacyUpstream, "http://legacy.local")
+		v.Set(MigrationConfigKeyDiffEndpoints, diffs)
+		return flux.NewConfiguration(v)
+	}
+	opts, err := newMigrationOptions(newConfig("GET#/users", "head#/users"))
+	assert.NoError(err)
+	assert.Len(opts.diffEndpoints, 2)
+	_, err = newMigrationOptions(newConfig("POST#/users"))
+	assert.Error(err)
+}
+
+func TestMigrationOptions_IsDiffEndpoint(t *testing.T) {
+	assert := assert2.New(t)
+	opts := &migrationOptions{diffEndpoints: map[string]struct{}{"GET#/users": {}}}
+	endpoint := &flux.Endpoint{HttpMethod: http.MethodGet, HttpPattern: "/users"}
+	assert.True(opts.isDiffEndpoint(http.MethodGet, endpoint))
+	assert.True(opts.isDiffEndpoint("head", endpoint))
+	assert.False(opts.isDiffEndpoint(http.MethodPost, endpoint))
+	// 扩展属性开启的非幂等请求不对比
+	writes := &flux.Endpoint{HttpMethod: http.MethodPost, HttpPattern: "/orders",
+		EmbeddedExtensions: flux.EmbeddedExtensions{Extensions: map[string]interface{}{MigrationExtKeyDiff: true}}}
+	assert.False(opts.isDiffEndpoint(http.MethodPost, writes))
+	var disabled *migrationOptions
+	assert.False(disabled.isDiffEndpoint(http.MethodGet, endpoint))
+}
+
+func TestRouteWithLegacyDiff_Async(t *testing.T) {
+	assert := assert2.New(t)
+	transport := &migrationTestTransport{release: make(chan struct{})}
+	extensions := ext.NewRegistry()
+	extensions.StoreBackendTransport(flux.ProtoEcho, transport)
+	engine := NewHttpServeEngineOf(extensions, DefaultServerResponseWriter, DefaultServerErrorsWriter)
+	engine.migration = &migrationOptions{
+		legacy: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(flux.HeaderContentType, flux.MIMEApplicationJSONCharsetUTF8)
+			_, _ = w.Write([]byte(`{"name":"legacy"}`))
+		}),
+	}
+	diffs := make(chan MigrationDiff, 1)
+	engine.SetMigrationDiffFunc(func(ctx flux.Context, diff MigrationDiff) {
+		diffs <- diff
+	})
+	endpoint := &flux.Endpoint{HttpMethod: http.MethodGet, HttpPattern: "/users",
+		Service: flux.BackendService{EmbeddedAttributes: flux.EmbeddedAttributes{
+			Attributes: []flux.Attribute{{Tag: flux.ServiceAttrTagRpcProto, Value: flux.ProtoEcho}}}}}
+	webc, err := newDetachedWebContext(newAsyncTestWebContext("", ""))
+	assert.NoError(err)
+	webc.request = httptest.NewRequest(http.MethodGet, "/users", nil)
+	ctxw := engine.acquireContext("req-1", "127.0.0.1", webc, endpoint)
+	// 新服务未返回时，已输出旧系统响应
+	status, err := engine.routeWithLegacyDiff(webc, ctxw)
+	engine.releaseContext(ctxw)
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, status)
+	assert.Equal(`{"name":"legacy"}`, webc.output.String())
+	close(transport.release)
+	select {
+	case diff := <-diffs:
+		assert.Equal("req-1", diff.RequestId)
+		assert.Equal(http.StatusOK, diff.ActualStatus)
+		assert.NotEmpty(diff.Diffs)
+	case <-time.After(time.Second):
+		assert.Fail("migration diff not recorded")
+	}
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"net/http"
+	"strings"
+)
+
+const (
+	// Postman Collection v2.1 格式；Insomnia 可直接导入该格式
+	PostmanSchemaV21 = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+	// 默认的集合名称
+	DefaultPostmanCollectionName = "Flux Gateway"
+	// 默认的网关地址变量值
+	DefaultPostmanBaseUrl = "http://localhost:8080"
+)
+
+const (
+	// 集合变量：网关地址
+	PostmanVariableBaseUrl = "baseUrl"
+	// 集合变量：需要授权的请求使用的Bearer Token
+	PostmanVariableToken = "token"
+)
+
+// PostmanCollection Postman集合
+type PostmanCollection struct {
+	Info     PostmanInfo       `json:"info"`
+	Item     []PostmanItem     `json:"item"`
+	Auth     *PostmanAuth      `json:"auth,omitempty"`
+	Variable []PostmanKeyValue `json:"variable,omitempty"`
+}
+
+type PostmanInfo struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema"`
+}
+
+type PostmanItem struct {
+	Name    string         `json:"name"`
+	Request PostmanRequest `json:"request"`
+}
+
+type PostmanRequest struct {
+	Method string            `json:"method"`
+	Auth   *PostmanAuth      `json:"auth,omitempty"`
+	Header []PostmanKeyValue `json:"header"`
+	Url    PostmanUrl        `json:"url"`
+	Body   *PostmanBody      `json:"body,omitempty"`
+}
+
+type PostmanUrl struct {
+	Raw      string            `json:"raw"`
+	Host     []string          `json:"host"`
+	Path     []string          `json:"path"`
+	Query    []PostmanKeyValue `json:"query,omitempty"`
+	Variable []PostmanKeyValue `json:"variable,omitempty"`
+}
+
+type PostmanBody struct {
+	Mode       string                 `json:"mode"`
+	Raw        string                 `json:"raw,omitempty"`
+	Urlencoded []PostmanKeyValue      `json:"urlencoded,omitempty"`
+	Options    map[string]interface{} `json:"options,omitempty"`
+}
+
+type PostmanAuth struct {
+	Type   string            `json:"type"`
+	Bearer []PostmanKeyValue `json:"bearer,omitempty"`
+}
+
+type PostmanKeyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Type  string `json:"type,omitempty"`
+}
+
+// NewPostmanCollection 从客户端方法生成Postman集合：参数值为按参数类型生成的示例值；
+// 需要授权的Endpoint继承集合的Bearer认证，其它Endpoint不使用认证
+func NewPostmanCollection(name string, methods []SDKMethod, baseUrl string) (PostmanCollection, error) {
+	if "" == name {
+		name = DefaultPostmanCollectionName
+	}
+	if "" == baseUrl {
+		baseUrl = DefaultPostmanBaseUrl
+	}
+	collection := PostmanCollection{
+		Info: PostmanInfo{Name: name, Schema: PostmanSchemaV21},
+		Item: make([]PostmanItem, 0, len(methods)),
+		Variable: []PostmanKeyValue{
+			{Key: PostmanVariableBaseUrl, Value: strings.TrimSuffix(baseUrl, "/"), Type: "string"},
+			{Key: PostmanVariableToken, Value: "", Type: "string"},
+		},
+	}
+	authorized := false
+	for _, m := range methods {
+		item, err := postmanItemOf(m)
+		if nil != err {
+			return collection, err
+		}
+		authorized = authorized || m.Authorize
+		collection.Item = append(collection.Item, item)
+	}
+	if authorized {
+		collection.Auth = &PostmanAuth{Type: "bearer", Bearer: []PostmanKeyValue{
+			{Key: "token", Value: "{{" + PostmanVariableToken + "}}", Type: "string"},
+		}}
+	}
+	return collection, nil
+}
+
+func postmanItemOf(m SDKMethod) (PostmanItem, error) {
+	request := PostmanRequest{
+		Method: m.HttpMethod,
+		Header: make([]PostmanKeyValue, 0, 2),
+		Url:    PostmanUrl{Host: []string{"{{" + PostmanVariableBaseUrl + "}}"}, Path: make([]string, 0, 4)},
+	}
+	if !m.Authorize {
+		request.Auth = &PostmanAuth{Type: "noauth"}
+	}
+	// Postman的路径变量格式与Endpoint相同（:name），通配符保持原样
+	for _, seg := range strings.Split(m.HttpPattern, "/") {
+		if "" != seg {
+			request.Url.Path = append(request.Url.Path, seg)
+		}
+	}
+	bodies := make(map[string]interface{}, 1)
+	var body interface{}
+	form := make([]PostmanKeyValue, 0, 4)
+	for _, p := range m.Params {
+		example := postmanExampleOf(p.Class, p.Generic, p.Fields)
+		switch p.Kind {
+		case sdkParamPath:
+			request.Url.Variable = append(request.Url.Variable, PostmanKeyValue{Key: p.HttpName, Value: postmanValueOf(example)})
+		case sdkParamQuery:
+			request.Url.Query = append(request.Url.Query, PostmanKeyValue{Key: p.HttpName, Value: postmanValueOf(example)})
+		case sdkParamHeader:
+			request.Header = append(request.Header, PostmanKeyValue{Key: p.HttpName, Value: postmanValueOf(example)})
+		case sdkParamForm:
+			form = append(form, PostmanKeyValue{Key: p.HttpName, Value: postmanValueOf(example), Type: "text"})
+		case sdkParamBody:
+			bodies[p.HttpName], body = example, example
+		}
+	}
+	// 多个Body参数按参数名称组合为JSON对象，与SDK的请求格式一致
+	if len(bodies) > 1 {
+		body = bodies
+	}
+	if len(bodies) > 0 {
+		raw, err := json.MarshalIndent(body, "", "  ")
+		if nil != err {
+			return PostmanItem{}, fmt.Errorf("postman body of %s %s: %w", m.HttpMethod, m.HttpPattern, err)
+		}
+		request.Header = append(request.Header, PostmanKeyValue{Key: flux.HeaderContentType, Value: flux.MIMEApplicationJSONCharsetUTF8})
+		request.Body = &PostmanBody{Mode: "raw", Raw: string(raw),
+			Options: map[string]interface{}{"raw": map[string]string{"language": "json"}}}
+	} else if len(form) > 0 {
+		request.Header = append(request.Header, PostmanKeyValue{Key: flux.HeaderContentType, Value: flux.MIMEApplicationForm})
+		request.Body = &PostmanBody{Mode: "urlencoded", Urlencoded: form}
+	}
+	request.Url.Raw = "{{" + PostmanVariableBaseUrl + "}}/" + strings.Join(request.Url.Path, "/")
+	for i, q := range request.Url.Query {
+		if 0 == i {
+			request.Url.Raw += "?"
+		} else {
+			request.Url.Raw += "&"
+		}
+		request.Url.Raw += q.Key + "=" + q.Value
+	}
+	return PostmanItem{Name: m.Name, Request: request}, nil
+}
+
+// postmanExampleOf 按Java类型生成示例值；复杂类型按字段生成对象
+func postmanExampleOf(class string, generic []string, fields []flux.Argument) interface{} {
+	switch class {
+	case flux.JavaLangStringClassName, "string", "char", "java.lang.Character":
+		return "string"
+	case flux.JavaLangIntegerClassName, flux.JavaLangLongClassName, "int", "long", "short", "byte",
+		"java.lang.Short", "java.lang.Byte", "java.math.BigInteger":
+		return 0
+	case flux.JavaLangFloatClassName, flux.JavaLangDoubleClassName, "float", "double", "java.math.BigDecimal":
+		return 0.0
+	case flux.JavaLangBooleanClassName, "boolean":
+		return false
+	case flux.JavaUtilMapClassName, "java.util.HashMap", "java.util.LinkedHashMap":
+		if 2 == len(generic) {
+			return map[string]interface{}{"key": postmanExampleOf(generic[1], nil, nil)}
+		}
+		return map[string]interface{}{}
+	case flux.JavaUtilListClassName, "java.util.ArrayList", "java.util.Set", "java.util.Collection":
+		if 1 == len(generic) {
+			return []interface{}{postmanExampleOf(generic[0], nil, nil)}
+		}
+		return []interface{}{}
+	}
+	if len(fields) > 0 {
+		object := make(map[string]interface{}, len(fields))
+		for _, f := range fields {
+			object[f.Name] = postmanExampleOf(f.Class, f.Generic, f.Fields)
+		}
+		return object
+	}
+	return nil
+}
+
+// postmanValueOf 返回Path、Query、Header和Form参数的文本示例值
+func postmanValueOf(example interface{}) string {
+	switch v := example.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}, map[string]interface{}:
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// NewDebugPostmanHandlerWith 导出指定映射表中已注册Endpoint的Postman集合，以附件形式下载；
+// 查询参数：name=集合名称，base-url=网关地址变量的初始值
+func NewDebugPostmanHandlerWith(endpoints *EndpointTable) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		routes := make([]flux.Endpoint, 0, 32)
+		for _, mve := range endpoints.Load() {
+			for _, endpoint := range mve.ToSerializable() {
+				routes = append(routes, *endpoint)
+			}
+		}
+		query := request.URL.Query()
+		collection, err := NewPostmanCollection(query.Get("name"), SDKMethodsOf(routes), query.Get("base-url"))
+		if nil != err {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data, err := json.MarshalIndent(collection, "", "  ")
+		if nil != err {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Header().Set("Content-Disposition", "attachment; filename=\"postman_collection.json\"")
+		_, _ = writer.Write(data)
+	}
+}
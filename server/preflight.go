@@ -0,0 +1,170 @@
+package server
+
+import (
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/webmidware"
+	"github.com/spf13/cast"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	PreflightConfigKeyEnable           = "enable"
+	PreflightConfigKeyAllowOrigins     = "allow-origins"
+	PreflightConfigKeyAllowHeaders     = "allow-headers"
+	PreflightConfigKeyAllowCredentials = "allow-credentials"
+	PreflightConfigKeyMaxAge           = "max-age"
+)
+
+const (
+	// Endpoint扩展属性：覆盖全局配置的CORS策略
+	EndpointExtKeyCorsAllowOrigins     = "cors-allow-origins"
+	EndpointExtKeyCorsAllowHeaders     = "cors-allow-headers"
+	EndpointExtKeyCorsAllowCredentials = "cors-allow-credentials"
+	EndpointExtKeyCorsMaxAge           = "cors-max-age"
+)
+
+// 参与计算 Access-Control-Allow-Methods 的方法，按输出顺序排列
+var preflightMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodTrace,
+}
+
+// corsPolicy 预检响应使用的CORS策略
+type corsPolicy struct {
+	allowOrigins     []string
+	allowHeaders     []string
+	allowCredentials bool
+	maxAge           int
+}
+
+// preflightOptions 在路由层生成OPTIONS/CORS预检响应：每个已注册的路由Pattern自动注册OPTIONS处理函数，
+// Access-Control-Allow-Methods 由同一Pattern下已注册的Endpoint方法计算；显式注册的OPTIONS Endpoint优先。
+type preflightOptions struct {
+	policy     corsPolicy
+	registered sync.Map // 已注册OPTIONS处理函数的Pattern
+}
+
+func newPreflightOptions(config *flux.Configuration) *preflightOptions {
+	config.SetDefaults(map[string]interface{}{
+		PreflightConfigKeyEnable:       false,
+		PreflightConfigKeyAllowOrigins: []string{"*"},
+	})
+	if !config.GetBool(PreflightConfigKeyEnable) {
+		return nil
+	}
+	opts := &preflightOptions{
+		policy: corsPolicy{
+			allowOrigins:     config.GetStringSlice(PreflightConfigKeyAllowOrigins),
+			allowHeaders:     config.GetStringSlice(PreflightConfigKeyAllowHeaders),
+			allowCredentials: config.GetBool(PreflightConfigKeyAllowCredentials),
+			maxAge:           config.GetInt(PreflightConfigKeyMaxAge),
+		},
+	}
+	logger.Infow("Preflight synthesis enabled", "allow-origins", opts.policy.allowOrigins)
+	return opts
+}
+
+// policyOf 返回Endpoint的CORS策略，未设置的属性使用全局配置
+func (p *preflightOptions) policyOf(endpoint *flux.Endpoint) corsPolicy {
+	policy := p.policy
+	if v, ok := endpoint.Ext(EndpointExtKeyCorsAllowOrigins); ok {
+		policy.allowOrigins = cast.ToStringSlice(v)
+	}
+	if v, ok := endpoint.Ext(EndpointExtKeyCorsAllowHeaders); ok {
+		policy.allowHeaders = cast.ToStringSlice(v)
+	}
+	if _, ok := endpoint.Ext(EndpointExtKeyCorsAllowCredentials); ok {
+		policy.allowCredentials = endpoint.ExtBool(EndpointExtKeyCorsAllowCredentials)
+	}
+	if _, ok := endpoint.Ext(EndpointExtKeyCorsMaxAge); ok {
+		policy.maxAge = endpoint.ExtInt(EndpointExtKeyCorsMaxAge)
+	}
+	return policy
+}
+
+// allowMethodsOf 返回Pattern下存在有效Endpoint的方法列表，包括OPTIONS
+func allowMethodsOf(endpoints *EndpointTable, pattern string) []string {
+	allows := make([]string, 0, 4)
+	for _, method := range preflightMethods {
+		if mve, ok := endpoints.Select(fmt.Sprintf("%s#%s", method, pattern)); ok && nil != mve.RandomVersion() {
+			allows = append(allows, method)
+		}
+	}
+	return append(allows, http.MethodOptions)
+}
+
+// registerPreflightHandler 为Pattern注册OPTIONS处理函数；每个Pattern只注册一次
+func (s *HttpServeEngine) registerPreflightHandler(method, pattern string) {
+	if nil == s.preflight || http.MethodOptions == method {
+		return
+	}
+	if _, ok := s.endpoints.Select(fmt.Sprintf("%s#%s", http.MethodOptions, pattern)); ok {
+		return
+	}
+	if _, loaded := s.preflight.registered.LoadOrStore(pattern, struct{}{}); loaded {
+		return
+	}
+	logger.Infow("Register preflight handler", "pattern", pattern)
+	s.httpWebServer.AddWebHandler(http.MethodOptions, pattern, s.newPreflightHandler(pattern))
+}
+
+func (s *HttpServeEngine) newPreflightHandler(pattern string) flux.WebHandler {
+	enabled := s.httpConfig.GetBool(HttpWebServerConfigKeyRequestLogEnable)
+	return func(webc flux.WebContext) error {
+		// 显式注册的OPTIONS Endpoint优先
+		if mve, ok := s.endpoints.Select(fmt.Sprintf("%s#%s", http.MethodOptions, pattern)); ok && nil != mve.RandomVersion() {
+			return s.HandleEndpointRequest(webc, mve, enabled)
+		}
+		allows := allowMethodsOf(s.endpoints, pattern)
+		webc.SetResponseHeader(flux.HeaderAllow, strings.Join(allows, ", "))
+		origin := webc.HeaderValue(flux.HeaderOrigin)
+		method := strings.ToUpper(webc.HeaderValue(flux.HeaderAccessControlRequestMethod))
+		if "" == origin || "" == method {
+			// 普通OPTIONS请求，只返回Allow
+			return writeNoContent(webc)
+		}
+		webc.AddResponseHeader(flux.HeaderVary, flux.HeaderOrigin)
+		webc.AddResponseHeader(flux.HeaderVary, flux.HeaderAccessControlRequestMethod)
+		webc.AddResponseHeader(flux.HeaderVary, flux.HeaderAccessControlRequestHeaders)
+		var endpoint *flux.Endpoint
+		if mve, ok := s.endpoints.Select(fmt.Sprintf("%s#%s", method, pattern)); ok {
+			endpoint, _ = s.versioning.selectEndpoint(webc, mve)
+		}
+		if nil == endpoint {
+			// 请求方法未注册：不返回CORS Header，预检失败
+			return writeNoContent(webc)
+		}
+		policy := s.preflight.policyOf(endpoint)
+		allowOrigin := webmidware.AllowOriginOf(origin, policy.allowOrigins, policy.allowCredentials)
+		if "" == allowOrigin {
+			return writeNoContent(webc)
+		}
+		webc.SetResponseHeader(flux.HeaderAccessControlAllowOrigin, allowOrigin)
+		webc.SetResponseHeader(flux.HeaderAccessControlAllowMethods, strings.Join(allows, ","))
+		if policy.allowCredentials {
+			webc.SetResponseHeader(flux.HeaderAccessControlAllowCredentials, "true")
+		}
+		if len(policy.allowHeaders) > 0 {
+			webc.SetResponseHeader(flux.HeaderAccessControlAllowHeaders, strings.Join(policy.allowHeaders, ","))
+		} else if h := webc.HeaderValue(flux.HeaderAccessControlRequestHeaders); "" != h {
+			webc.SetResponseHeader(flux.HeaderAccessControlAllowHeaders, h)
+		}
+		if policy.maxAge > 0 {
+			webc.SetResponseHeader(flux.HeaderAccessControlMaxAge, strconv.Itoa(policy.maxAge))
+		}
+		return writeNoContent(webc)
+	}
+}
+
+// writeNoContent 写入无Body的204响应
+func writeNoContent(webc flux.WebContext) error {
+	if w, err := webc.HttpResponseWriter(); nil == err {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	return webc.WriteStream(http.StatusNoContent, "", http.NoBody)
+}
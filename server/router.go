@@ -9,43 +9,66 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"reflect"
 	"sort"
+	"sync"
+	"time"
 )
 
 type Router struct {
-	metrics *Metrics
+	metrics       *Metrics
+	extensions    *ext.Registry
+	config        *flux.Configuration
+	invokeLimiter *invokeLimiter
+	asyncInvoker  *asyncInvoker
+	initOpts      *backendInitOptions
+	timings       *initTimings
+	predicates    sync.Map
+	shardRings    sync.Map
 }
 
 func NewRouter() *Router {
+	return NewRouterWith(ext.Default(), prometheus.DefaultRegisterer)
+}
+
+// NewRouterWith 使用指定的扩展组件注册表和Metrics注册器创建Router
+func NewRouterWith(extensions *ext.Registry, registerer prometheus.Registerer) *Router {
 	return &Router{
-		metrics: NewMetrics(),
+		metrics:    NewMetricsWith(registerer),
+		extensions: extensions,
+		config:     flux.NewGlobalConfiguration(),
+		initOpts:   newBackendInitOptions(flux.NewConfiguration(nil)),
+		timings:    new(initTimings),
 	}
 }
 
 func (r *Router) Initial() error {
 	logger.Infof("Router initialing")
 	// Backends
-	for proto, backend := range ext.LoadBackendTransports() {
-		ns := "BACKEND." + proto
-		logger.Infow("Load backend", "proto", proto, "type", reflect.TypeOf(backend), "config-ns", ns)
-		if err := r.InitialHook(backend, flux.NewConfigurationOf(ns)); nil != err {
+	if err := r.initialBackends(); nil != err {
+		return err
+	}
+	// Credential providers
+	for id, provider := range r.extensions.LoadCredentialProviders() {
+		ns := "CREDENTIAL." + id
+		logger.Infow("Load credential provider", "id", id, "type", reflect.TypeOf(provider), "config-ns", ns)
+		if err := r.initialHookOf(ComponentKindCredential, id, provider, r.config.Sub(ns)); nil != err {
 			return err
 		}
 	}
 	// 手动注册的单实例Filters
-	for _, filter := range append(ext.LoadGlobalFilters(), ext.LoadSelectiveFilters()...) {
+	for _, filter := range append(r.extensions.LoadGlobalFilters(), r.extensions.LoadSelectiveFilters()...) {
 		ns := filter.TypeId()
 		logger.Infow("Load static-filter", "type", reflect.TypeOf(filter), "config-ns", ns)
-		config := flux.NewConfigurationOf(ns)
+		config := r.config.Sub(ns)
 		if _isDisabled(config) {
 			logger.Infow("Set static-filter DISABLED", "filter-id", filter.TypeId())
 			continue
 		}
-		if err := r.InitialHook(filter, config); nil != err {
+		if err := r.initialHookOf(ComponentKindFilter, ns, filter, config); nil != err {
 			return err
 		}
 	}
 	// 加载和注册，动态多实例Filter
-	dynFilters, err := dynamicFilters()
+	dynFilters, err := dynamicFilters(r.extensions, r.config)
 	if nil != err {
 		return err
 	}
@@ -56,28 +79,31 @@ func (r *Router) Initial() error {
 			logger.Infow("Set dynamic-filter DISABLED", "filter-id", item.Id, "type-id", item.TypeId)
 			continue
 		}
-		if err := r.InitialHook(filter, item.Config); nil != err {
+		if err := r.initialHookOf(ComponentKindFilter, item.Id, filter, item.Config); nil != err {
 			return err
 		}
 		if filter, ok := filter.(flux.Filter); ok {
-			ext.StoreSelectiveFilter(filter)
+			r.extensions.StoreSelectiveFilter(filter)
 		}
 	}
 	return nil
 }
 
 func (r *Router) InitialHook(ref interface{}, config *flux.Configuration) error {
-	if init, ok := ref.(flux.Initializer); ok {
-		if err := init.Init(config); nil != err {
-			return err
-		}
+	return r.initialHookOf(ComponentKindHook, reflect.TypeOf(ref).String(), ref, config)
+}
+
+// initialHookOf 初始化组件，记录初始化耗时，并注册组件的生命周期Hook
+func (r *Router) initialHookOf(kind, name string, ref interface{}, config *flux.Configuration) error {
+	if err := r.initialComponent(kind, name, ref, config); nil != err {
+		return err
 	}
-	ext.StoreHookFunc(ref)
+	r.extensions.StoreHookFunc(ref)
 	return nil
 }
 
 func (r *Router) Startup() error {
-	for _, startup := range sortedStartup(ext.LoadStartupHooks()) {
+	for _, startup := range sortedStartup(r.extensions.LoadStartupHooks()) {
 		if err := startup.Startup(); nil != err {
 			return err
 		}
@@ -85,13 +111,36 @@ func (r *Router) Startup() error {
 	return nil
 }
 
+// Warmup 按顺序执行全部预热Hook；单个Hook失败不影响后续Hook执行，返回聚合的错误，字段名为Hook类型
+func (r *Router) Warmup(ctx context.Context) error {
+	ctx = ext.WithRegistry(ctx, r.extensions)
+	errs := new(flux.MultiError)
+	for _, warmer := range sortedWarmup(r.extensions.LoadWarmupHooks()) {
+		if err := ctx.Err(); nil != err {
+			errs.Append(err)
+			return errs.ErrorOrNil()
+		}
+		start := time.Now()
+		if err := warmer.Warmup(ctx); nil != err {
+			logger.Warnw("Router warmup hook failed", "type", reflect.TypeOf(warmer), "error", err)
+			errs.Append(flux.NewFieldError(reflect.TypeOf(warmer).String(), "", err))
+			continue
+		}
+		logger.Infow("Router warmup hook done", "type", reflect.TypeOf(warmer), "elapsed", time.Since(start))
+	}
+	return errs.ErrorOrNil()
+}
+
+// Shutdown 按顺序执行全部停止Hook；单个Hook失败不影响后续Hook执行，返回聚合的错误，字段名为Hook类型
 func (r *Router) Shutdown(ctx context.Context) error {
-	for _, shutdown := range sortedShutdown(ext.LoadShutdownHooks()) {
+	errs := new(flux.MultiError)
+	for _, shutdown := range sortedShutdown(r.extensions.LoadShutdownHooks()) {
 		if err := shutdown.Shutdown(ctx); nil != err {
-			return err
+			logger.Warnw("Router shutdown hook failed", "type", reflect.TypeOf(shutdown), "error", err)
+			errs.Append(flux.NewFieldError(reflect.TypeOf(shutdown).String(), "", err))
 		}
 	}
-	return nil
+	return errs.ErrorOrNil()
 }
 
 func (r *Router) Route(ctx *WrappedContext) *flux.ServeError {
@@ -101,8 +150,8 @@ func (r *Router) Route(ctx *WrappedContext) *flux.ServeError {
 		proto, _, uri, method := ctx.ServiceInterface()
 		r.metrics.EndpointAccess.WithLabelValues(proto, uri, method).Inc()
 		if nil != err {
-			// Error Counter: ProtoName, Interface, Method, ErrorCode
-			r.metrics.EndpointError.WithLabelValues(proto, uri, method, err.GetErrorCode()).Inc()
+			// Error Counter: ProtoName, Interface, Method, ErrorCode, ErrorCategory
+			r.metrics.EndpointError.WithLabelValues(proto, uri, method, err.GetErrorCode(), err.GetCategory()).Inc()
 		}
 		return err
 	}
@@ -110,27 +159,24 @@ func (r *Router) Route(ctx *WrappedContext) *flux.ServeError {
 	defer func() {
 		ctx.AddMetric("M-Route", ctx.ElapsedTime())
 	}()
-	// Select filters
-	globals := ext.LoadGlobalFilters()
-	selective := make([]flux.Filter, 0, 16)
-	for _, selector := range ext.FindSelectors(ctx.Request().Host()) {
-		for _, typeId := range selector.Select(ctx).FilterId {
-			if f, ok := ext.LoadSelectiveFilter(typeId); ok {
-				selective = append(selective, f)
-			} else {
-				logger.TraceContext(ctx).Warnw("Filter not found on selector", "type-id", typeId)
-			}
-		}
+	// Content routes
+	if err := r.routeByContent(ctx); nil != err {
+		return doMetricEndpointFunc(err)
+	}
+	// Sharding
+	if err := r.routeBySharding(ctx); nil != err {
+		return doMetricEndpointFunc(err)
 	}
+	// Select filters
+	filters := r.selectFilters(ctx)
 	ctx.AddMetric("M-Selector", ctx.ElapsedTime())
 	// Walk filters
-	filters := append(globals, selective...)
-	err := r.walk(func(ctx flux.Context) *flux.ServeError {
+	exchange := func(ctx flux.Context) *flux.ServeError {
 		protoName := ctx.ServiceProto()
 		defer func() {
 			ctx.AddMetric("M-Backend", ctx.ElapsedTime())
 		}()
-		if backend, ok := ext.LoadBackendTransport(protoName); !ok {
+		if backend, ok := r.extensions.LoadBackendTransport(protoName); !ok {
 			logger.TraceContext(ctx).Warnw("Route, unsupported protocol", "proto", protoName, "service", ctx.Endpoint().Service)
 			return &flux.ServeError{
 				StatusCode: flux.StatusNotFound,
@@ -139,14 +185,59 @@ func (r *Router) Route(ctx *WrappedContext) *flux.ServeError {
 		} else {
 			// Backend exchange
 			timer := prometheus.NewTimer(r.metrics.RouteDuration.WithLabelValues("BackendTransport", protoName))
-			ret := backend.Exchange(ctx)
-			timer.ObserveDuration()
+			defer timer.ObserveDuration()
+			if wc, ok := ctx.(*WrappedContext); ok && r.asyncInvoker.enabledFor(wc.Endpoint()) {
+				return r.asyncInvoker.submit(wc, backend)
+			}
+			if nil == r.invokeLimiter {
+				return backend.Exchange(ctx)
+			}
+			var ret *flux.ServeError
+			if serr := r.invokeLimiter.execute(ctx, func() {
+				ret = backend.Exchange(ctx)
+			}); nil != serr {
+				return serr
+			}
 			return ret
 		}
-	}, filters)(ctx)
+	}
+	err := r.walk(exchange, filters)(ctx)
+	if nil != err && r.routeByFallback(ctx, err, exchange) {
+		// 降级响应仍统计原错误
+		doMetricEndpointFunc(err)
+		return nil
+	}
 	return doMetricEndpointFunc(err)
 }
 
+// selectFilters 返回请求需要执行的全局Filter和Selector选择的Filter；
+// Endpoint配置了特性开关时，在末尾追加特性开关Filter，在认证Filter之后判断
+func (r *Router) selectFilters(ctx flux.Context) []flux.Filter {
+	globals := r.extensions.LoadGlobalFilters()
+	selective := make([]flux.Filter, 0, 16)
+	for _, selector := range r.extensions.FindSelectors(ctx.Request().Host()) {
+		for _, typeId := range selector.Select(ctx).FilterId {
+			if f, ok := r.extensions.LoadSelectiveFilter(typeId); ok {
+				selective = append(selective, f)
+			} else {
+				logger.TraceContext(ctx).Warnw("Filter not found on selector", "type-id", typeId)
+			}
+		}
+	}
+	filters := append(globals, selective...)
+	if "" != ctx.Endpoint().ExtString(flux.EndpointExtKeyFeatureFlag) {
+		filters = append(filters, featureFlag)
+	}
+	return filters
+}
+
+// authenticate 执行Endpoint的Filter链但不调用后端服务；用于不经过路由的内置接口复用Endpoint的认证Filter
+func (r *Router) authenticate(ctx *WrappedContext) *flux.ServeError {
+	return r.walk(func(flux.Context) *flux.ServeError {
+		return nil
+	}, r.selectFilters(ctx))(ctx)
+}
+
 func (r *Router) walk(next flux.FilterHandler, filters []flux.Filter) flux.FilterHandler {
 	for i := len(filters) - 1; i >= 0; i-- {
 		next = filters[i].DoFilter(next)
@@ -175,3 +266,12 @@ func sortedShutdown(items []flux.Shutdowner) []flux.Shutdowner {
 	sort.Sort(out)
 	return out
 }
+
+func sortedWarmup(items []flux.Warmer) []flux.Warmer {
+	out := make(WarmupArray, len(items))
+	for i, v := range items {
+		out[i] = v
+	}
+	sort.Sort(out)
+	return out
+}
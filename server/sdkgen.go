@@ -0,0 +1,612 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"go/format"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	SDKLanguageGo         = "go"
+	SDKLanguageTypeScript = "typescript"
+)
+
+const (
+	// Endpoint扩展属性：生成客户端SDK时使用的方法名称；未指定时使用后端服务的方法名，或由Http方法和路径生成
+	EndpointExtKeySDKMethod = "sdk-method"
+)
+
+const (
+	// 默认的Go客户端包名
+	DefaultSDKPackage = "fluxclient"
+)
+
+const (
+	sdkParamPath = iota
+	sdkParamQuery
+	sdkParamHeader
+	sdkParamForm
+	sdkParamBody
+)
+
+// SDKParam 客户端方法的请求参数
+type SDKParam struct {
+	Name     string          // 参数字段名称
+	HttpName string          // Http侧的参数名称
+	Kind     int             // 参数位置：Path、Query、Header、Form、Body
+	Class    string          // Java类型
+	Generic  []string        // Java泛型类型
+	Fields   []flux.Argument // 复杂类型的字段
+}
+
+// SDKMethod 客户端方法：每个Endpoint（Http方法和路径）生成一个方法
+type SDKMethod struct {
+	Name        string
+	HttpMethod  string
+	HttpPattern string
+	Authorize   bool // 是否需要授权
+	Params      []SDKParam
+}
+
+// SDKMethodsOf 从Endpoint列表生成按路径排序的客户端方法；多个版本的Endpoint只生成一个方法，方法名称重复时添加序号
+func SDKMethodsOf(endpoints []flux.Endpoint) []SDKMethod {
+	sorted := make([]flux.Endpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if "" != endpoint.HttpPattern && "" != endpoint.HttpMethod {
+			sorted = append(sorted, endpoint)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].HttpPattern != sorted[j].HttpPattern {
+			return sorted[i].HttpPattern < sorted[j].HttpPattern
+		}
+		return strings.ToUpper(sorted[i].HttpMethod) < strings.ToUpper(sorted[j].HttpMethod)
+	})
+	routes := make(map[string]bool, len(sorted))
+	names := make(map[string]int, len(sorted))
+	out := make([]SDKMethod, 0, len(sorted))
+	for _, endpoint := range sorted {
+		method := strings.ToUpper(endpoint.HttpMethod)
+		if key := method + "#" + endpoint.HttpPattern; routes[key] {
+			continue
+		} else {
+			routes[key] = true
+		}
+		name := sdkMethodNameOf(endpoint)
+		if n := names[name]; n > 0 {
+			names[name] = n + 1
+			name += strconv.Itoa(n + 1)
+		} else {
+			names[name] = 1
+		}
+		out = append(out, SDKMethod{
+			Name:        name,
+			HttpMethod:  method,
+			HttpPattern: endpoint.HttpPattern,
+			Authorize:   endpoint.AttrAuthorize(),
+			Params:      sdkParamsOf(endpoint),
+		})
+	}
+	return out
+}
+
+// GenerateClientSDK 生成指定语言的客户端SDK源码：go 或 typescript
+func GenerateClientSDK(language string, methods []SDKMethod, pkg string) ([]byte, error) {
+	switch strings.ToLower(language) {
+	case SDKLanguageGo:
+		if "" == pkg {
+			pkg = DefaultSDKPackage
+		}
+		return generateGoSDK(methods, pkg)
+	case SDKLanguageTypeScript, "ts":
+		return generateTypeScriptSDK(methods), nil
+	default:
+		return nil, fmt.Errorf("unsupported sdk language: %s", language)
+	}
+}
+
+func sdkMethodNameOf(endpoint flux.Endpoint) string {
+	if name := endpoint.ExtString(EndpointExtKeySDKMethod); "" != name {
+		return sdkPascalOf(name)
+	}
+	if "" != endpoint.Service.Method {
+		return sdkPascalOf(endpoint.Service.Method)
+	}
+	// GET /users/:id -> GetUsersById
+	parts := []string{strings.ToLower(endpoint.HttpMethod)}
+	for _, seg := range strings.Split(endpoint.HttpPattern, "/") {
+		switch {
+		case "" == seg || "*" == seg:
+		case strings.HasPrefix(seg, ":"):
+			parts = append(parts, "by", seg[1:])
+		default:
+			parts = append(parts, seg)
+		}
+	}
+	return sdkPascalOf(strings.Join(parts, "_"))
+}
+
+// sdkParamsOf 按参数的Http域确定参数位置；网关内部的域（Attribute、Value等）不生成参数。
+// Body域之外的复杂参数按字段展开；路径中未声明参数的变量生成字符串类型的路径参数。
+func sdkParamsOf(endpoint flux.Endpoint) []SDKParam {
+	params := make([]SDKParam, 0, len(endpoint.Service.Arguments))
+	seen := make(map[string]bool, len(endpoint.Service.Arguments))
+	var walk func(args []flux.Argument)
+	walk = func(args []flux.Argument) {
+		for _, arg := range args {
+			kind, ok := sdkParamKindOf(endpoint, arg)
+			if !ok {
+				continue
+			}
+			if flux.ArgumentTypeComplex == arg.Type && sdkParamBody != kind {
+				walk(arg.Fields)
+				continue
+			}
+			httpName := arg.HttpName
+			if "" == httpName {
+				httpName = arg.Name
+			}
+			name := sdkPascalOf(arg.Name)
+			if "" == name || seen[name] {
+				continue
+			}
+			seen[name] = true
+			params = append(params, SDKParam{Name: name, HttpName: httpName, Kind: kind,
+				Class: arg.Class, Generic: arg.Generic, Fields: arg.Fields})
+		}
+	}
+	walk(endpoint.Service.Arguments)
+	for _, seg := range strings.Split(endpoint.HttpPattern, "/") {
+		if !strings.HasPrefix(seg, ":") {
+			continue
+		}
+		declared := false
+		for _, p := range params {
+			declared = declared || (sdkParamPath == p.Kind && p.HttpName == seg[1:])
+		}
+		if name := sdkPascalOf(seg[1:]); !declared && !seen[name] {
+			seen[name] = true
+			params = append(params, SDKParam{Name: name, HttpName: seg[1:], Kind: sdkParamPath, Class: flux.JavaLangStringClassName})
+		}
+	}
+	return params
+}
+
+func sdkParamKindOf(endpoint flux.Endpoint, arg flux.Argument) (int, bool) {
+	switch strings.ToUpper(arg.HttpScope) {
+	case flux.ScopePath:
+		return sdkParamPath, true
+	case flux.ScopeHeader:
+		return sdkParamHeader, true
+	case flux.ScopeForm, flux.ScopeFormMulti:
+		return sdkParamForm, true
+	case flux.ScopeBody:
+		return sdkParamBody, true
+	case flux.ScopeQuery, flux.ScopeQueryMulti:
+		return sdkParamQuery, true
+	case flux.ScopeParam, flux.ScopeAuto, "":
+		// 路径变量优先；查询参数和表单参数中，GET请求使用查询参数
+		if strings.Contains(endpoint.HttpPattern, ":"+arg.HttpName) {
+			return sdkParamPath, true
+		}
+		if http.MethodGet == strings.ToUpper(endpoint.HttpMethod) || http.MethodDelete == strings.ToUpper(endpoint.HttpMethod) {
+			return sdkParamQuery, true
+		}
+		return sdkParamForm, true
+	default:
+		return 0, false
+	}
+}
+
+// sdkPascalOf 转换为大驼峰标识符，例如 user-id -> UserId
+func sdkPascalOf(name string) string {
+	var buf strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if 0 == buf.Len() && unicode.IsDigit(r) {
+			buf.WriteRune('N')
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+func sdkCamelOf(name string) string {
+	pascal := sdkPascalOf(name)
+	if "" == pascal {
+		return pascal
+	}
+	return strings.ToLower(pascal[:1]) + pascal[1:]
+}
+
+// sdkClassNameOf 返回Java类的简单名称，例如 com.example.UserDTO -> UserDTO
+func sdkClassNameOf(class string) string {
+	if i := strings.LastIndexAny(class, ".$"); i >= 0 {
+		class = class[i+1:]
+	}
+	return sdkPascalOf(class)
+}
+
+////
+
+// sdkGoTypeOf 将Java类型映射为Go类型；复杂类型生成结构体，记录到 structs
+func sdkGoTypeOf(class string, generic []string, fields []flux.Argument, structs map[string][]flux.Argument) string {
+	switch class {
+	case flux.JavaLangStringClassName, "string", "char", "java.lang.Character":
+		return "string"
+	case flux.JavaLangIntegerClassName, "int", "java.lang.Short", "short", "java.lang.Byte", "byte":
+		return "int32"
+	case flux.JavaLangLongClassName, "long", "java.math.BigInteger":
+		return "int64"
+	case flux.JavaLangFloatClassName, "float":
+		return "float32"
+	case flux.JavaLangDoubleClassName, "double", "java.math.BigDecimal":
+		return "float64"
+	case flux.JavaLangBooleanClassName, "boolean":
+		return "bool"
+	case flux.JavaUtilMapClassName, "java.util.HashMap", "java.util.LinkedHashMap":
+		if 2 == len(generic) {
+			return "map[string]" + sdkGoTypeOf(generic[1], nil, nil, structs)
+		}
+		return "map[string]interface{}"
+	case flux.JavaUtilListClassName, "java.util.ArrayList", "java.util.Set", "java.util.Collection":
+		if 1 == len(generic) {
+			return "[]" + sdkGoTypeOf(generic[0], nil, nil, structs)
+		}
+		return "[]interface{}"
+	}
+	if len(fields) > 0 {
+		name := sdkClassNameOf(class)
+		if "" != name {
+			structs[name] = fields
+			return "*" + name
+		}
+	}
+	return "interface{}"
+}
+
+func generateGoSDK(methods []SDKMethod, pkg string) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	structs := make(map[string][]flux.Argument, 8)
+	_, _ = fmt.Fprintf(buf, "// Code generated by flux generate-sdk. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	buf.WriteString(goSDKPrelude)
+	for _, m := range methods {
+		_, _ = fmt.Fprintf(buf, "\n// %sRequest %s %s\ntype %sRequest struct {\n", m.Name, m.HttpMethod, m.HttpPattern, m.Name)
+		for _, p := range m.Params {
+			_, _ = fmt.Fprintf(buf, "\t%s %s `json:\"%s,omitempty\"`\n", p.Name, sdkGoTypeOf(p.Class, p.Generic, p.Fields, structs), p.HttpName)
+		}
+		buf.WriteString("}\n")
+		_, _ = fmt.Fprintf(buf, "\n// %s %s %s\nfunc (c *Client) %s(ctx context.Context, req %sRequest) (*Response, error) {\n",
+			m.Name, m.HttpMethod, m.HttpPattern, m.Name, m.Name)
+		segments := make([]string, 0, 4)
+		for _, seg := range strings.Split(m.HttpPattern, "/") {
+			if strings.HasPrefix(seg, ":") {
+				for _, p := range m.Params {
+					if sdkParamPath == p.Kind && p.HttpName == seg[1:] {
+						seg = fmt.Sprintf("\" + url.PathEscape(fmt.Sprint(req.%s)) + \"", p.Name)
+					}
+				}
+			}
+			segments = append(segments, seg)
+		}
+		path := strings.TrimSuffix(fmt.Sprintf("\"%s\"", strings.Join(segments, "/")), " + \"\"")
+		_, _ = fmt.Fprintf(buf, "\tpath := %s\n", path)
+		buf.WriteString("\tquery, header, form := url.Values{}, http.Header{}, url.Values{}\n")
+		bodies := make([]SDKParam, 0, 1)
+		for _, p := range m.Params {
+			switch p.Kind {
+			case sdkParamQuery:
+				_, _ = fmt.Fprintf(buf, "\tsetValue(query, %q, req.%s)\n", p.HttpName, p.Name)
+			case sdkParamHeader:
+				_, _ = fmt.Fprintf(buf, "\tsetValue(header, %q, req.%s)\n", p.HttpName, p.Name)
+			case sdkParamForm:
+				_, _ = fmt.Fprintf(buf, "\tsetValue(form, %q, req.%s)\n", p.HttpName, p.Name)
+			case sdkParamBody:
+				bodies = append(bodies, p)
+			}
+		}
+		switch len(bodies) {
+		case 0:
+			buf.WriteString("\tvar body interface{}\n")
+		case 1:
+			_, _ = fmt.Fprintf(buf, "\tvar body interface{} = req.%s\n", bodies[0].Name)
+		default:
+			buf.WriteString("\tvar body interface{} = map[string]interface{}{\n")
+			for _, p := range bodies {
+				_, _ = fmt.Fprintf(buf, "\t\t%q: req.%s,\n", p.HttpName, p.Name)
+			}
+			buf.WriteString("\t}\n")
+		}
+		_, _ = fmt.Fprintf(buf, "\treturn c.do(ctx, %q, path, query, header, form, body)\n}\n", m.HttpMethod)
+	}
+	// 结构体字段中的复杂类型会继续加入 structs
+	for done := make(map[string]bool, len(structs)); len(done) < len(structs); {
+		names := make([]string, 0, len(structs))
+		for name := range structs {
+			if !done[name] {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			done[name] = true
+			_, _ = fmt.Fprintf(buf, "\ntype %s struct {\n", name)
+			for _, f := range structs[name] {
+				_, _ = fmt.Fprintf(buf, "\t%s %s `json:\"%s,omitempty\"`\n", sdkPascalOf(f.Name), sdkGoTypeOf(f.Class, f.Generic, f.Fields, structs), f.Name)
+			}
+			buf.WriteString("}\n")
+		}
+	}
+	return format.Source(buf.Bytes())
+}
+
+const goSDKPrelude = `import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// Client 网关客户端
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	Header     http.Header
+}
+
+// Response 网关响应
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       json.RawMessage
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: http.DefaultClient, Header: http.Header{}}
+}
+
+type valueAdder interface {
+	Add(key, value string)
+}
+
+func setValue(values valueAdder, key string, value interface{}) {
+	if nil == value || reflect.ValueOf(value).IsZero() {
+		return
+	}
+	if rv := reflect.ValueOf(value); reflect.Slice == rv.Kind() {
+		for i := 0; i < rv.Len(); i++ {
+			values.Add(key, fmt.Sprint(rv.Index(i).Interface()))
+		}
+		return
+	}
+	values.Add(key, fmt.Sprint(value))
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, header http.Header, form url.Values, body interface{}) (*Response, error) {
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var data []byte
+	contentType := ""
+	if nil != body && !reflect.ValueOf(body).IsZero() {
+		encoded, err := json.Marshal(body)
+		if nil != err {
+			return nil, err
+		}
+		data, contentType = encoded, "application/json"
+	} else if len(form) > 0 {
+		data, contentType = []byte(form.Encode()), "application/x-www-form-urlencoded"
+	}
+	request, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(data))
+	if nil != err {
+		return nil, err
+	}
+	for k, v := range c.Header {
+		request.Header[k] = v
+	}
+	for k, v := range header {
+		request.Header[k] = v
+	}
+	if "" != contentType {
+		request.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.HTTPClient.Do(request)
+	if nil != err {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	out, err := ioutil.ReadAll(resp.Body)
+	if nil != err {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("%s %s: status %d: %s", method, path, resp.StatusCode, out)
+	}
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: out}, nil
+}
+`
+
+////
+
+// sdkTypeScriptTypeOf 将Java类型映射为TypeScript类型；复杂类型生成接口，记录到 interfaces
+func sdkTypeScriptTypeOf(class string, generic []string, fields []flux.Argument, interfaces map[string][]flux.Argument) string {
+	switch class {
+	case flux.JavaLangStringClassName, "string", "char", "java.lang.Character":
+		return "string"
+	case flux.JavaLangIntegerClassName, flux.JavaLangLongClassName, flux.JavaLangFloatClassName, flux.JavaLangDoubleClassName,
+		"int", "long", "float", "double", "short", "byte", "java.lang.Short", "java.lang.Byte", "java.math.BigDecimal", "java.math.BigInteger":
+		return "number"
+	case flux.JavaLangBooleanClassName, "boolean":
+		return "boolean"
+	case flux.JavaUtilMapClassName, "java.util.HashMap", "java.util.LinkedHashMap":
+		if 2 == len(generic) {
+			return "Record<string, " + sdkTypeScriptTypeOf(generic[1], nil, nil, interfaces) + ">"
+		}
+		return "Record<string, unknown>"
+	case flux.JavaUtilListClassName, "java.util.ArrayList", "java.util.Set", "java.util.Collection":
+		if 1 == len(generic) {
+			return sdkTypeScriptTypeOf(generic[0], nil, nil, interfaces) + "[]"
+		}
+		return "unknown[]"
+	}
+	if len(fields) > 0 {
+		name := sdkClassNameOf(class)
+		if "" != name {
+			interfaces[name] = fields
+			return name
+		}
+	}
+	return "unknown"
+}
+
+func generateTypeScriptSDK(methods []SDKMethod) []byte {
+	buf := new(bytes.Buffer)
+	interfaces := make(map[string][]flux.Argument, 8)
+	buf.WriteString("// Code generated by flux generate-sdk. DO NOT EDIT.\n\n")
+	for _, m := range methods {
+		_, _ = fmt.Fprintf(buf, "/** %s %s */\nexport interface %sRequest {\n", m.HttpMethod, m.HttpPattern, m.Name)
+		for _, p := range m.Params {
+			_, _ = fmt.Fprintf(buf, "  %s?: %s;\n", sdkCamelOf(p.Name), sdkTypeScriptTypeOf(p.Class, p.Generic, p.Fields, interfaces))
+		}
+		buf.WriteString("}\n\n")
+	}
+	for done := make(map[string]bool, len(interfaces)); len(done) < len(interfaces); {
+		names := make([]string, 0, len(interfaces))
+		for name := range interfaces {
+			if !done[name] {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			done[name] = true
+			_, _ = fmt.Fprintf(buf, "export interface %s {\n", name)
+			for _, f := range interfaces[name] {
+				_, _ = fmt.Fprintf(buf, "  %q?: %s;\n", f.Name, sdkTypeScriptTypeOf(f.Class, f.Generic, f.Fields, interfaces))
+			}
+			buf.WriteString("}\n\n")
+		}
+	}
+	buf.WriteString(typeScriptSDKPrelude)
+	for _, m := range methods {
+		_, _ = fmt.Fprintf(buf, "\n  /** %s %s */\n  %s(req: %sRequest = {}, init?: RequestInit): Promise<Response> {\n",
+			m.HttpMethod, m.HttpPattern, sdkCamelOf(m.Name), m.Name)
+		segments := make([]string, 0, 4)
+		for _, seg := range strings.Split(m.HttpPattern, "/") {
+			if strings.HasPrefix(seg, ":") {
+				for _, p := range m.Params {
+					if sdkParamPath == p.Kind && p.HttpName == seg[1:] {
+						seg = fmt.Sprintf("${encodeURIComponent(String(req.%s ?? \"\"))}", sdkCamelOf(p.Name))
+					}
+				}
+			}
+			segments = append(segments, seg)
+		}
+		_, _ = fmt.Fprintf(buf, "    const path = `%s`;\n", strings.Join(segments, "/"))
+		buf.WriteString("    const query: Record<string, unknown> = {};\n    const headers: Record<string, unknown> = {};\n    const form: Record<string, unknown> = {};\n")
+		bodies := make([]SDKParam, 0, 1)
+		for _, p := range m.Params {
+			switch p.Kind {
+			case sdkParamQuery:
+				_, _ = fmt.Fprintf(buf, "    query[%q] = req.%s;\n", p.HttpName, sdkCamelOf(p.Name))
+			case sdkParamHeader:
+				_, _ = fmt.Fprintf(buf, "    headers[%q] = req.%s;\n", p.HttpName, sdkCamelOf(p.Name))
+			case sdkParamForm:
+				_, _ = fmt.Fprintf(buf, "    form[%q] = req.%s;\n", p.HttpName, sdkCamelOf(p.Name))
+			case sdkParamBody:
+				bodies = append(bodies, p)
+			}
+		}
+		switch len(bodies) {
+		case 0:
+			buf.WriteString("    const body: unknown = undefined;\n")
+		case 1:
+			_, _ = fmt.Fprintf(buf, "    const body: unknown = req.%s;\n", sdkCamelOf(bodies[0].Name))
+		default:
+			buf.WriteString("    const body: unknown = {\n")
+			for _, p := range bodies {
+				_, _ = fmt.Fprintf(buf, "      %q: req.%s,\n", p.HttpName, sdkCamelOf(p.Name))
+			}
+			buf.WriteString("    };\n")
+		}
+		_, _ = fmt.Fprintf(buf, "    return this.request(%q, path, query, headers, form, body, init);\n  }\n", m.HttpMethod)
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+const typeScriptSDKPrelude = `export class Client {
+  constructor(private readonly baseUrl: string, private readonly defaults: RequestInit = {}) {}
+
+  private async request(method: string, path: string, query: Record<string, unknown>,
+                        headers: Record<string, unknown>, form: Record<string, unknown>,
+                        body: unknown, init?: RequestInit): Promise<Response> {
+    const params = new URLSearchParams();
+    Object.entries(query).forEach(([k, v]) => v !== undefined && v !== null &&
+      (Array.isArray(v) ? v.forEach(item => params.append(k, String(item))) : params.set(k, String(v))));
+    const search = params.toString();
+    const requestHeaders = new Headers(init?.headers ?? this.defaults.headers);
+    Object.entries(headers).forEach(([k, v]) => v !== undefined && v !== null && requestHeaders.set(k, String(v)));
+    let payload: BodyInit | undefined;
+    if (body !== undefined && body !== null) {
+      payload = JSON.stringify(body);
+      requestHeaders.set("Content-Type", "application/json");
+    } else {
+      const fields = new URLSearchParams();
+      Object.entries(form).forEach(([k, v]) => v !== undefined && v !== null && fields.set(k, String(v)));
+      if (fields.toString() !== "") {
+        payload = fields;
+      }
+    }
+    const url = this.baseUrl.replace(/\/$/, "") + path + (search ? "?" + search : "");
+    const response = await fetch(url, {...this.defaults, ...init, method, headers: requestHeaders, body: payload});
+    if (!response.ok) {
+      throw new Error(method + " " + path + ": status " + response.status);
+    }
+    return response;
+  }
+`
+
+// NewDebugSDKHandlerWith 生成指定映射表中已注册Endpoint的客户端SDK；
+// 查询参数：lang=go|typescript，package=Go客户端包名
+func NewDebugSDKHandlerWith(endpoints *EndpointTable) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		language := request.URL.Query().Get("lang")
+		if "" == language {
+			language = SDKLanguageGo
+		}
+		routes := make([]flux.Endpoint, 0, 32)
+		for _, mve := range endpoints.Load() {
+			for _, endpoint := range mve.ToSerializable() {
+				routes = append(routes, *endpoint)
+			}
+		}
+		data, err := GenerateClientSDK(language, SDKMethodsOf(routes), request.URL.Query().Get("package"))
+		if nil != err {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = writer.Write(data)
+	}
+}
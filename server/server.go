@@ -2,15 +2,20 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/bytepowered/flux"
 	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/grpcserver"
 	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/support"
 	"github.com/bytepowered/flux/webmidware"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/cast"
+	"net"
 	"net/http"
-	_ "net/http/pprof"
+	"net/http/pprof"
 	"runtime/debug"
 	"strings"
 	"sync"
@@ -27,27 +32,84 @@ const (
 )
 
 const (
-	HttpWebServerConfigRootName              = "HttpWebServer"
-	HttpWebServerConfigKeyFeatureEchoEnable  = "feature-echo-enable"
-	HttpWebServerConfigKeyFeatureDebugEnable = "feature-debug-enable"
-	HttpWebServerConfigKeyFeatureDebugPort   = "feature-debug-port"
-	HttpWebServerConfigKeyFeatureCorsEnable  = "feature-cors-enable"
-	HttpWebServerConfigKeyVersionHeader      = "version-header"
-	HttpWebServerConfigKeyRequestIdHeaders   = "request-id-headers"
-	HttpWebServerConfigKeyRequestLogEnable   = "request-log-enable"
-	HttpWebServerConfigKeyAddress            = "address"
-	HttpWebServerConfigKeyPort               = "port"
-	HttpWebServerConfigKeyTlsCertFile        = "tls-cert-file"
-	HttpWebServerConfigKeyTlsKeyFile         = "tls-key-file"
+	HttpWebServerConfigRootName                = "HttpWebServer"
+	HttpWebServerConfigKeyFeatureEchoEnable    = "feature-echo-enable"
+	HttpWebServerConfigKeyFeatureDebugEnable   = "feature-debug-enable"
+	HttpWebServerConfigKeyFeatureDebugPort     = "feature-debug-port"
+	HttpWebServerConfigKeyFeatureDebugAddress  = "feature-debug-address"
+	HttpWebServerConfigKeyFeatureAdminToken    = "feature-admin-token"
+	HttpWebServerConfigKeyFeatureCorsEnable    = "feature-cors-enable"
+	HttpWebServerConfigKeyFeatureGrpcEnable    = "feature-grpc-enable"
+	HttpWebServerConfigKeyFeatureGrpcPort      = "feature-grpc-port"
+	HttpWebServerConfigKeyVersionHeader        = "version-header"
+	HttpWebServerConfigKeyRequestIdHeaders     = "request-id-headers"
+	HttpWebServerConfigKeyRequestLogEnable     = "request-log-enable"
+	HttpWebServerConfigKeyAddress              = "address"
+	HttpWebServerConfigKeyPort                 = "port"
+	HttpWebServerConfigKeyTlsCertFile          = "tls-cert-file"
+	HttpWebServerConfigKeyTlsKeyFile           = "tls-key-file"
+	HttpWebServerConfigKeyReadOnly             = "read-only"
+	HttpWebServerConfigKeyReadOnlyMessage      = "read-only-message"
+	HttpWebServerConfigKeyHeaderFirewall       = "header-firewall-enable"
+	HttpWebServerConfigKeyProtectedHeaders     = "protected-headers"
+	HttpWebServerConfigKeyTrustedProxies       = "trusted-proxies"
+	HttpWebServerConfigKeyNotFoundBody         = "not-found-body"
+	HttpWebServerConfigKeyMethodNotAllowedBody = "method-not-allowed-body"
+	HttpWebServerConfigKeyFallbackContentType  = "fallback-content-type"
+	HttpWebServerConfigKeyFallbackHosts        = "fallback-hosts"
+	HttpWebServerConfigKeyFallbackUpstream     = "fallback-upstream"
+	HttpWebServerConfigKeyMigration            = "migration"
+	HttpWebServerConfigKeyPreflight            = "preflight"
+	HttpWebServerConfigKeyVersioning           = "versioning"
+	HttpWebServerConfigKeyNormalize            = "normalize"
+	HttpWebServerConfigKeyRequestLimits        = "request-limits"
+	HttpWebServerConfigKeyStreaming            = "streaming"
+	HttpWebServerConfigKeyWarmup               = "warmup"
+	HttpWebServerConfigKeyInvokeLimit          = "invoke-limit"
+	HttpWebServerConfigKeyAsync                = "async"
+	HttpWebServerConfigKeyMemoryGuard          = "memory-guard"
+	HttpWebServerConfigKeyBackendInit          = "backend-init"
+	HttpWebServerConfigKeySnapshot             = "snapshot"
+	HttpWebServerConfigKeyTraffic              = "traffic"
+	HttpWebServerConfigKeyConfigHistory        = "config-history"
+	HttpWebServerConfigKeyTimeLayouts          = "time-layouts"
+)
+
+const (
+	NormalizeConfigKeyEnable          = "enable"
+	NormalizeConfigKeyTrimSpace       = "trim-space"
+	NormalizeConfigKeyCaseFoldParams  = "case-fold-params"
+	NormalizeConfigKeyDuplicatePolicy = "duplicate-policy"
+	NormalizeConfigKeyCleanPath       = "clean-path"
+)
+
+const (
+	MemoryGuardConfigKeyEnable          = "enable"
+	MemoryGuardConfigKeyBudget          = "budget-bytes"
+	MemoryGuardConfigKeyHighWatermark   = "high-watermark"
+	MemoryGuardConfigKeyLargeBodySize   = "large-body-size"
+	MemoryGuardConfigKeyGcInterval      = "gc-interval"
+	MemoryGuardConfigKeyTopConsumers    = "top-consumers"
+	MemoryGuardConfigKeyUnknownBodySize = "unknown-body-size"
+)
+
+const (
+	RequestLimitsConfigKeyMaxUriLength   = "max-uri-length"
+	RequestLimitsConfigKeyMaxHeaderCount = "max-header-count"
+	RequestLimitsConfigKeyMaxQueryParams = "max-query-params"
 )
 
 var (
 	HttpWebServerConfigDefaults = map[string]interface{}{
-		HttpWebServerConfigKeyVersionHeader:      DefaultHttpHeaderVersion,
-		HttpWebServerConfigKeyFeatureDebugEnable: false,
-		HttpWebServerConfigKeyFeatureDebugPort:   9527,
-		HttpWebServerConfigKeyAddress:            "0.0.0.0",
-		HttpWebServerConfigKeyPort:               8080,
+		HttpWebServerConfigKeyVersionHeader:       DefaultHttpHeaderVersion,
+		HttpWebServerConfigKeyFeatureDebugEnable:  false,
+		HttpWebServerConfigKeyFeatureDebugPort:    9527,
+		HttpWebServerConfigKeyFeatureDebugAddress: "127.0.0.1",
+		HttpWebServerConfigKeyFeatureGrpcPort:     9528,
+		HttpWebServerConfigKeyAddress:             "0.0.0.0",
+		HttpWebServerConfigKeyPort:                8080,
+		HttpWebServerConfigKeyHeaderFirewall:      true,
+		HttpWebServerConfigKeyFallbackContentType: flux.MIMEApplicationJSONCharsetUTF8,
 	}
 )
 
@@ -57,14 +119,36 @@ type HttpServeEngine struct {
 	serverResponseWriter flux.ServerResponseWriter
 	serverErrorsWriter   flux.ServerErrorsWriter
 	serverContextHooks   []flux.ServerContextHookFunc
+	readOnlySwitch       *webmidware.ReadOnlySwitch
+	trustedProxies       *support.TrustedProxies
+	fallback             *fallbackOptions
+	migration            *migrationOptions
+	preflight            *preflightOptions
+	versioning           *versioningOptions
+	streaming            *streamOptions
+	warmupOpts           *warmupOptions
+	migrationDiffFunc    MigrationDiffFunc
+	asyncStore           AsyncInvocationStore
+	snapshotStore        SnapshotStore
+	configHistory        *ConfigHistory
+	traffic              *trafficGenerator
 	debugServer          *http.Server
+	grpcServer           *grpcserver.GrpcFrontServer
+	grpcAddress          string
+	listenerServer       *http.Server
+	config               *flux.Configuration
 	httpConfig           *flux.Configuration
 	httpVersionHeader    string
 	router               *Router
+	extensions           *ext.Registry
+	endpoints            *EndpointTable
+	metricsGatherer      prometheus.Gatherer
+	debugServeMux        *http.ServeMux
 	endpointRegistry     flux.EndpointRegistry
 	contextWrappers      sync.Pool
 	stateStarted         chan struct{}
 	stateStopped         chan struct{}
+	stateReady           chan struct{}
 }
 
 func NewHttpServeEngine() *HttpServeEngine {
@@ -72,20 +156,48 @@ func NewHttpServeEngine() *HttpServeEngine {
 }
 
 func NewHttpServeEngineWith(responseWriter flux.ServerResponseWriter, errorWriter flux.ServerErrorsWriter) *HttpServeEngine {
+	return NewHttpServeEngineOf(ext.Default(), responseWriter, errorWriter)
+}
+
+// NewHttpServeEngineOf 使用指定的扩展组件注册表创建网关引擎。
+// 非默认注册表的引擎使用独立的Endpoint映射表、Metrics注册器和DebugServer路由，可在同一进程内运行多个相互隔离的引擎。
+func NewHttpServeEngineOf(extensions *ext.Registry, responseWriter flux.ServerResponseWriter, errorWriter flux.ServerErrorsWriter) *HttpServeEngine {
+	registerer, gatherer, mux, endpoints := prometheus.DefaultRegisterer, prometheus.DefaultGatherer, http.DefaultServeMux, defaultEndpoints
+	if extensions != ext.Default() {
+		registry := prometheus.NewRegistry()
+		registerer, gatherer, mux, endpoints = registry, registry, newDebugServeMux(), NewEndpointTable()
+	}
 	return &HttpServeEngine{
-		router:               NewRouter(),
+		router:               NewRouterWith(extensions, registerer),
+		extensions:           extensions,
+		config:               flux.NewGlobalConfiguration(),
+		endpoints:            endpoints,
+		metricsGatherer:      gatherer,
+		debugServeMux:        mux,
 		serverResponseWriter: responseWriter,
 		serverErrorsWriter:   errorWriter,
 		contextWrappers:      sync.Pool{New: NewContextWrapper},
 		serverContextHooks:   make([]flux.ServerContextHookFunc, 0, 4),
 		stateStarted:         make(chan struct{}),
 		stateStopped:         make(chan struct{}),
+		stateReady:           make(chan struct{}),
 	}
 }
 
+// SetConfiguration 设置引擎读取的根配置；默认为Viper全局配置。需要在 Prepare 之前调用
+func (s *HttpServeEngine) SetConfiguration(config *flux.Configuration) {
+	s.config = config
+	s.router.config = config
+}
+
+// Configuration 返回引擎读取的根配置
+func (s *HttpServeEngine) Configuration() *flux.Configuration {
+	return s.config
+}
+
 // Prepare Call before init and startup
 func (s *HttpServeEngine) Prepare(hooks ...flux.PrepareHookFunc) error {
-	for _, prepare := range append(ext.LoadPrepareHooks(), hooks...) {
+	for _, prepare := range append(s.extensions.LoadPrepareHooks(), hooks...) {
 		if err := prepare(); nil != err {
 			return err
 		}
@@ -96,32 +208,170 @@ func (s *HttpServeEngine) Prepare(hooks ...flux.PrepareHookFunc) error {
 // Initial
 func (s *HttpServeEngine) Initial() error {
 	// Http server
-	s.httpConfig = flux.NewConfigurationOf(HttpWebServerConfigRootName)
+	s.httpConfig = s.config.Sub(HttpWebServerConfigRootName)
 	s.httpConfig.SetDefaults(HttpWebServerConfigDefaults)
 	s.httpVersionHeader = s.httpConfig.GetString(HttpWebServerConfigKeyVersionHeader)
+	s.versioning = newVersioningOptions(s.httpVersionHeader, s.httpConfig.Sub(HttpWebServerConfigKeyVersioning))
+	// 可信代理：用于解析客户端IP
+	if proxies, err := support.NewTrustedProxies(s.httpConfig.GetStringSlice(HttpWebServerConfigKeyTrustedProxies)); nil != err {
+		return err
+	} else {
+		s.trustedProxies = proxies
+	}
+	// 时间类型参数值的解析格式：例如 ["2006-01-02 15:04:05", "unix-millis"]；未配置时使用默认格式
+	support.SetTimeValueLayouts(s.httpConfig.GetStringSlice(HttpWebServerConfigKeyTimeLayouts))
+	// 路由不存在和方法不允许时的Fallback处理
+	if fallback, err := newFallbackOptions(s.httpConfig); nil != err {
+		return err
+	} else {
+		s.fallback = fallback
+	}
+	// Strangler迁移模式：配置旧系统地址后开启
+	if migration, err := newMigrationOptions(s.httpConfig.Sub(HttpWebServerConfigKeyMigration)); nil != err {
+		return err
+	} else {
+		s.migration = migration
+	}
 	// 创建WebServer
-	s.httpWebServer = ext.LoadWebServerFactory()(s.httpConfig)
+	s.httpWebServer = s.extensions.LoadWebServerFactory()(s.httpConfig)
 	// 默认必备的WebServer功能
 	s.httpWebServer.SetWebErrorHandler(s.defaultServerErrorHandler)
 	s.httpWebServer.SetWebNotFoundHandler(s.defaultNotFoundErrorHandler)
+	s.httpWebServer.SetWebMethodNotAllowedHandler(s.defaultMethodNotAllowedHandler)
+
+	// - 流式响应：分块写入和客户端写超时
+	s.streaming = newStreamOptions(s.httpConfig.Sub(HttpWebServerConfigKeyStreaming), s.router.metrics)
+	// - 后端调用并发限制：默认关闭
+	s.router.invokeLimiter = newInvokeLimiter(s.httpConfig.Sub(HttpWebServerConfigKeyInvokeLimit), s.router.metrics)
+	// - 异步调用：默认关闭；开启后注册调用状态和结果查询接口
+	if s.router.asyncInvoker = newAsyncInvoker(s.httpConfig.Sub(HttpWebServerConfigKeyAsync), s.extensions); nil != s.router.asyncInvoker {
+		if nil != s.asyncStore {
+			s.router.asyncInvoker.store = s.asyncStore
+		}
+		s.httpWebServer.AddWebHandler(http.MethodGet, s.router.asyncInvoker.statusPath, s.newAsyncStatusHandler())
+		s.httpWebServer.AddWebHandler(http.MethodGet, s.router.asyncInvoker.resultPath, s.newAsyncResultHandler())
+	}
 
-	// - 请求CORS跨域支持：默认关闭，需要配置开启
+	// - 预检响应生成：默认关闭，需要配置开启
+	s.preflight = newPreflightOptions(s.httpConfig.Sub(HttpWebServerConfigKeyPreflight))
+
+	// - 请求CORS跨域支持：默认关闭，需要配置开启；开启预检响应生成时，预检请求由路由层处理
 	if s.httpConfig.GetBool(HttpWebServerConfigKeyFeatureCorsEnable) {
-		s.AddWebInterceptor(webmidware.NewCORSMiddleware())
+		config := webmidware.DefaultCorsConfig()
+		config.PreflightPassthrough = nil != s.preflight
+		s.AddWebInterceptor(webmidware.NewCORSMiddlewareWith(config))
 	}
 
 	// - RequestId是重要的参数，不可关闭；
 	headers := s.httpConfig.GetStringSlice(HttpWebServerConfigKeyRequestIdHeaders)
 	s.AddWebInterceptor(webmidware.NewRequestIdMiddlewareWithinHeader(headers...))
 
+	// - 请求限制：拒绝URI过长、Header或Query参数过多的请求；配置值小于等于0表示不限制
+	limits := s.httpConfig.Sub(HttpWebServerConfigKeyRequestLimits)
+	limits.SetDefaults(map[string]interface{}{
+		RequestLimitsConfigKeyMaxUriLength:   8192,
+		RequestLimitsConfigKeyMaxHeaderCount: 100,
+		RequestLimitsConfigKeyMaxQueryParams: 256,
+	})
+	s.AddWebInterceptor(webmidware.NewRequestLimitsMiddlewareWith(webmidware.RequestLimitsConfig{
+		MaxUriLength:   limits.GetInt(RequestLimitsConfigKeyMaxUriLength),
+		MaxHeaderCount: limits.GetInt(RequestLimitsConfigKeyMaxHeaderCount),
+		MaxQueryParams: limits.GetInt(RequestLimitsConfigKeyMaxQueryParams),
+	}))
+
+	// - 内存预算：进行中请求的请求体总量接近预算时拒绝大请求；默认关闭
+	if guard := s.httpConfig.Sub(HttpWebServerConfigKeyMemoryGuard); guard.GetBool(MemoryGuardConfigKeyEnable) {
+		guard.SetDefaults(map[string]interface{}{
+			MemoryGuardConfigKeyBudget:          256 * 1024 * 1024,
+			MemoryGuardConfigKeyHighWatermark:   0.9,
+			MemoryGuardConfigKeyLargeBodySize:   64 * 1024,
+			MemoryGuardConfigKeyGcInterval:      time.Second * 10,
+			MemoryGuardConfigKeyTopConsumers:    5,
+			MemoryGuardConfigKeyUnknownBodySize: 8 * 1024 * 1024,
+		})
+		s.AddWebInterceptor(webmidware.NewMemoryGuardMiddleware(webmidware.NewMemoryGuard(webmidware.MemoryGuardConfig{
+			Budget:          guard.GetInt64(MemoryGuardConfigKeyBudget),
+			HighWatermark:   guard.GetFloat64(MemoryGuardConfigKeyHighWatermark),
+			LargeBodySize:   guard.GetInt64(MemoryGuardConfigKeyLargeBodySize),
+			GcInterval:      guard.GetDuration(MemoryGuardConfigKeyGcInterval),
+			TopConsumers:    guard.GetInt(MemoryGuardConfigKeyTopConsumers),
+			UnknownBodySize: guard.GetInt64(MemoryGuardConfigKeyUnknownBodySize),
+			KeyFunc:         s.trustedProxies.ClientIP,
+		})))
+	}
+
+	// - Header防火墙：移除客户端伪造的内部Header；默认开启
+	if s.httpConfig.GetBool(HttpWebServerConfigKeyHeaderFirewall) {
+		protected := s.httpConfig.GetStringSlice(HttpWebServerConfigKeyProtectedHeaders)
+		if len(protected) == 0 {
+			protected = webmidware.DefaultProtectedHeaders
+		}
+		s.AddWebInterceptor(webmidware.NewHeaderFirewallMiddlewareWith(webmidware.HeaderFirewallConfig{
+			ProtectedHeaders: protected,
+		}))
+	}
+
+	// - 请求规范化：在路由前规范化路径和Query参数；默认关闭
+	if normalize := s.httpConfig.Sub(HttpWebServerConfigKeyNormalize); normalize.GetBool(NormalizeConfigKeyEnable) {
+		normalize.SetDefaults(map[string]interface{}{
+			NormalizeConfigKeyTrimSpace:       true,
+			NormalizeConfigKeyDuplicatePolicy: webmidware.DuplicateParamKeep,
+			NormalizeConfigKeyCleanPath:       true,
+		})
+		s.AddWebInterceptor(webmidware.NewNormalizeMiddlewareWith(webmidware.NormalizeConfig{
+			TrimSpace:       normalize.GetBool(NormalizeConfigKeyTrimSpace),
+			CaseFoldParams:  normalize.GetStringSlice(NormalizeConfigKeyCaseFoldParams),
+			DuplicatePolicy: normalize.GetString(NormalizeConfigKeyDuplicatePolicy),
+			CleanPath:       normalize.GetBool(NormalizeConfigKeyCleanPath),
+		}))
+	}
+
+	// - 只读模式：可通过配置或Debug管理接口切换
+	s.readOnlySwitch = webmidware.NewReadOnlySwitch(
+		s.httpConfig.GetBool(HttpWebServerConfigKeyReadOnly),
+		s.httpConfig.GetString(HttpWebServerConfigKeyReadOnlyMessage))
+	s.AddWebInterceptor(webmidware.NewReadOnlyMiddleware(s.readOnlySwitch))
+
+	// - 迁移模式：指定路径直接代理到旧系统
+	if nil != s.migration && len(s.migration.legacyPaths) > 0 {
+		s.AddWebInterceptor(s.migration.newLegacyInterceptor())
+	}
+
 	// Internal Web Server
+	// 默认只监听本机地址；容器探针等需要外部访问时，配置 feature-debug-address
 	port := s.httpConfig.GetInt(HttpWebServerConfigKeyFeatureDebugPort)
 	s.debugServer = &http.Server{
-		Handler: http.DefaultServeMux,
-		Addr:    fmt.Sprintf("0.0.0.0:%d", port),
+		Handler: s.debugServeMux,
+		Addr:    fmt.Sprintf("%s:%d", s.httpConfig.GetString(HttpWebServerConfigKeyFeatureDebugAddress), port),
 	}
+	// - 启动预热与就绪探针
+	s.warmupOpts = newWarmupOptions(s.httpConfig.Sub(HttpWebServerConfigKeyWarmup))
+	s.debugServeMux.Handle(ReadinessProbePath, s.newReadinessHandler())
+	// gRPC Server：面向内部调用方，复用Http处理链；默认关闭
+	if s.httpConfig.GetBool(HttpWebServerConfigKeyFeatureGrpcEnable) {
+		handler, ok := s.httpWebServer.RawWebServer().(http.Handler)
+		if !ok {
+			return errors.New("grpc server requires WebServer implements http.Handler")
+		}
+		s.grpcServer = grpcserver.NewGrpcFrontServer(handler, s.lookupGrpcEndpoint, s.httpVersionHeader)
+		s.grpcAddress = fmt.Sprintf("0.0.0.0:%d", s.httpConfig.GetInt(HttpWebServerConfigKeyFeatureGrpcPort))
+	}
+	// 静态配置的上游集群
+	if err := s.loadUpstreams(); nil != err {
+		return err
+	}
+	// 上游mTLS的SPIFFE身份
+	if err := s.initSpiffe(); nil != err {
+		return err
+	}
+	// xDS控制面下发的上游集群
+	if err := s.initXds(); nil != err {
+		return err
+	}
+	// 运行时配置历史
+	s.initConfigHistory(s.httpConfig.Sub(HttpWebServerConfigKeyConfigHistory))
 	// Endpoint registry
-	if registry, config, err := activeEndpointRegistry(); nil != err {
+	if registry, config, err := activeEndpointRegistry(s.extensions, s.config); nil != err {
 		return err
 	} else {
 		if err := s.router.InitialHook(registry, config); nil != err {
@@ -131,9 +381,41 @@ func (s *HttpServeEngine) Initial() error {
 	}
 	// - Debug特性支持：默认关闭，需要配置开启
 	if s.httpConfig.GetBool(HttpWebServerConfigKeyFeatureDebugEnable) {
-		http.DefaultServeMux.Handle("/debug/endpoints", NewDebugQueryEndpointHandler())
-		http.DefaultServeMux.Handle("/debug/services", NewDebugQueryServiceHandler())
-		http.DefaultServeMux.Handle("/debug/metrics", promhttp.Handler())
+		s.debugServeMux.Handle("/debug/endpoints", NewDebugQueryEndpointHandlerWith(s.endpoints))
+		s.debugServeMux.Handle("/debug/services", NewDebugQueryServiceHandlerWith(s.extensions))
+		s.debugServeMux.Handle("/debug/metrics", promhttp.HandlerFor(s.metricsGatherer, promhttp.HandlerOpts{}))
+		s.debugServeMux.Handle("/debug/readonly", NewDebugQueryOnlyHandler(NewDebugReadOnlyHandler(s.readOnlySwitch)))
+		s.debugServeMux.Handle("/debug/edge-routes", NewDebugEdgeExportHandlerWith(s.endpoints))
+		s.debugServeMux.Handle("/debug/sdk", NewDebugSDKHandlerWith(s.endpoints))
+		s.debugServeMux.Handle("/debug/postman", NewDebugPostmanHandlerWith(s.endpoints))
+		s.debugServeMux.Handle("/debug/init-timings", NewDebugInitTimingsHandler(s.router))
+		if nil == s.snapshotStore {
+			snapshot := s.httpConfig.Sub(HttpWebServerConfigKeySnapshot)
+			snapshot.SetDefault(SnapshotConfigKeyDir, "./snapshots")
+			s.snapshotStore = NewFileSnapshotStore(snapshot.GetString(SnapshotConfigKeyDir))
+		}
+		// Debug接口只提供查询；修改运行时状态的操作由需要认证的管理接口提供
+		admin := http.NewServeMux()
+		s.debugServeMux.Handle("/debug/snapshot", NewDebugQueryOnlyHandler(NewDebugSnapshotHandler(s)))
+		admin.Handle("/admin/snapshot", NewDebugSnapshotHandler(s))
+		admin.Handle("/admin/readonly", NewDebugReadOnlyHandler(s.readOnlySwitch))
+		if nil != s.configHistory {
+			s.debugServeMux.Handle("/debug/config-history", NewDebugQueryOnlyHandler(NewDebugConfigHistoryHandler(s)))
+			admin.Handle("/admin/config-history", NewDebugConfigHistoryHandler(s))
+		}
+		if handler, ok := s.httpWebServer.RawWebServer().(http.Handler); ok {
+			s.traffic = newTrafficGenerator(s.httpConfig.Sub(HttpWebServerConfigKeyTraffic), handler, s.httpVersionHeader)
+			s.debugServeMux.Handle("/debug/traffic", NewDebugQueryOnlyHandler(NewDebugTrafficHandler(s)))
+			admin.Handle("/admin/traffic", NewDebugTrafficHandler(s))
+		} else {
+			logger.Warnw("Traffic generator disabled", "error", ErrTrafficHandlerRequired)
+		}
+		if token := s.adminToken(); "" != token {
+			s.debugServeMux.Handle("/admin/", NewAdminAuthHandler(token, admin))
+		} else {
+			logger.Warnw("Admin handlers disabled, admin token not configured",
+				"config-key", HttpWebServerConfigKeyFeatureAdminToken, "env", EnvKeyFluxAdminToken)
+		}
 	}
 	// Echo feature
 	if s.httpConfig.GetBool(HttpWebServerConfigKeyFeatureEchoEnable) {
@@ -142,6 +424,7 @@ func (s *HttpServeEngine) Initial() error {
 			s.HandleHttpEndpointEvent(evt)
 		}
 	}
+	s.router.initOpts = newBackendInitOptions(s.httpConfig.Sub(HttpWebServerConfigKeyBackendInit))
 	return s.router.Initial()
 }
 
@@ -151,6 +434,32 @@ func (s *HttpServeEngine) Startup(version flux.BuildInfo) error {
 
 // StartServe server
 func (s *HttpServeEngine) StartServe(info flux.BuildInfo, config *flux.Configuration) error {
+	if err := s.start(info); nil != err {
+		return err
+	}
+	address := fmt.Sprintf("%s:%d", config.GetString("address"), config.GetInt("port"))
+	keyFile := config.GetString(HttpWebServerConfigKeyTlsKeyFile)
+	certFile := config.GetString(HttpWebServerConfigKeyTlsCertFile)
+	logger.Infow("HttpServeEngine starting", "address", address, "cert", certFile, "key", keyFile)
+	return s.httpWebServer.StartTLS(address, certFile, keyFile)
+}
+
+// ServeListener 在指定Listener上启动Http服务；要求WebServer实现http.Handler接口
+func (s *HttpServeEngine) ServeListener(info flux.BuildInfo, listener net.Listener) error {
+	handler, ok := s.ensure().httpWebServer.RawWebServer().(http.Handler)
+	if !ok {
+		return errors.New("serve listener requires WebServer implements http.Handler")
+	}
+	if err := s.start(info); nil != err {
+		return err
+	}
+	s.listenerServer = &http.Server{Handler: handler}
+	logger.Infow("HttpServeEngine starting", "address", listener.Addr().String())
+	return s.listenerServer.Serve(listener)
+}
+
+// start 启动Hook、注册中心事件监听和内部服务
+func (s *HttpServeEngine) start(info flux.BuildInfo) error {
 	if err := s.ensure().router.Startup(); nil != err {
 		return err
 	}
@@ -179,6 +488,10 @@ func (s *HttpServeEngine) StartServe(info flux.BuildInfo, config *flux.Configura
 		}()
 	}
 	close(s.stateStarted)
+	// 记录启动时的运行时配置版本
+	s.touchConfigHistory()
+	// 预热完成后就绪探针才返回就绪状态
+	go s.warmup()
 	logger.Info(Banner)
 	logger.Infof(VersionFormat, info.CommitId, info.Version, info.Date)
 	// Start Servers
@@ -188,16 +501,21 @@ func (s *HttpServeEngine) StartServe(info flux.BuildInfo, config *flux.Configura
 			_ = s.debugServer.ListenAndServe()
 		}()
 	}
-	address := fmt.Sprintf("%s:%d", config.GetString("address"), config.GetInt("port"))
-	keyFile := config.GetString(HttpWebServerConfigKeyTlsKeyFile)
-	certFile := config.GetString(HttpWebServerConfigKeyTlsCertFile)
-	logger.Infow("HttpServeEngine starting", "address", address, "cert", certFile, "key", keyFile)
-	return s.httpWebServer.StartTLS(address, certFile, keyFile)
+	if s.grpcServer != nil {
+		listener, err := net.Listen("tcp", s.grpcAddress)
+		if nil != err {
+			return fmt.Errorf("grpc server listen: %w", err)
+		}
+		go func() {
+			logger.Infow("GrpcServer starting", "address", s.grpcAddress)
+			_ = s.grpcServer.Serve(listener)
+		}()
+	}
+	return nil
 }
 
 func (s *HttpServeEngine) HandleEndpointRequest(webc flux.WebContext, endpoints *MultiEndpoint, tracing bool) error {
-	version := webc.HeaderValue(s.httpVersionHeader)
-	endpoint, found := endpoints.FindByVersion(version)
+	endpoint, found := s.versioning.selectEndpoint(webc, endpoints)
 	requestId := cast.ToString(webc.GetValue(flux.HeaderXRequestId))
 	defer func() {
 		if r := recover(); r != nil {
@@ -219,10 +537,11 @@ func (s *HttpServeEngine) HandleEndpointRequest(webc flux.WebContext, endpoints
 		}
 		return flux.ErrRouteNotFound
 	}
-	ctxw := s.acquireContext(requestId, webc, endpoint)
+	s.versioning.markDeprecated(webc, endpoint)
+	ctxw := s.acquireContext(requestId, s.trustedProxies.ClientIP(webc), webc, endpoint)
 	defer s.releaseContext(ctxw)
 	// Route call
-	logger.TraceContext(ctxw).Infow("HttpServeEngine route start")
+	logger.TraceContext(ctxw).Infow("HttpServeEngine route start", "client-ip", ctxw.ClientIP())
 	endcall := func(code int, start time.Time) {
 		logger.TraceContext(ctxw).Infow("HttpServeEngine route end",
 			"metric", ctxw.LoadMetrics(),
@@ -233,6 +552,12 @@ func (s *HttpServeEngine) HandleEndpointRequest(webc flux.WebContext, endpoints
 	for _, ctxhook := range s.serverContextHooks {
 		ctxhook(webc, ctxw)
 	}
+	// Migration: 输出旧系统响应，记录新服务响应差异
+	if s.migration.isDiffEndpoint(webc.Method(), endpoint) {
+		status, err := s.routeWithLegacyDiff(webc, ctxw)
+		defer endcall(status, start)
+		return err
+	}
 	// Route and response
 	response := ctxw.Response()
 	if err := s.router.Route(ctxw); nil != err {
@@ -240,6 +565,10 @@ func (s *HttpServeEngine) HandleEndpointRequest(webc flux.WebContext, endpoints
 		logger.TraceContext(ctxw).Errorw("HttpServeEngine route error", "error", err)
 		err.MergeHeader(response.HeaderValues())
 		return err
+	} else if ctxw.Streamed() {
+		// 已通过StreamingContext直接写入响应
+		defer endcall(response.StatusCode(), start)
+		return nil
 	} else {
 		defer endcall(response.StatusCode(), start)
 		return s.serverResponseWriter(webc, requestId, response.HeaderValues(), response.StatusCode(), response.Body())
@@ -252,25 +581,26 @@ func (s *HttpServeEngine) HandleBackendServiceEvent(event flux.BackendServiceEve
 	case flux.EventTypeAdded:
 		logger.Infow("New service",
 			"service-id", service.ServiceId, "alias-id", service.AliasId)
-		ext.StoreBackendService(service)
+		s.extensions.StoreBackendService(service)
 		if "" != service.AliasId {
-			ext.StoreBackendServiceById(service.AliasId, service)
+			s.extensions.StoreBackendServiceById(service.AliasId, service)
 		}
 	case flux.EventTypeUpdated:
 		logger.Infow("Update service",
 			"service-id", service.ServiceId, "alias-id", service.AliasId)
-		ext.StoreBackendService(service)
+		s.extensions.StoreBackendService(service)
 		if "" != service.AliasId {
-			ext.StoreBackendServiceById(service.AliasId, service)
+			s.extensions.StoreBackendServiceById(service.AliasId, service)
 		}
 	case flux.EventTypeRemoved:
 		logger.Infow("Delete service",
 			"service-id", service.ServiceId, "alias-id", service.AliasId)
-		ext.RemoveBackendService(service.ServiceId)
+		s.extensions.RemoveBackendService(service.ServiceId)
 		if "" != service.AliasId {
-			ext.RemoveBackendService(service.AliasId)
+			s.extensions.RemoveBackendService(service.AliasId)
 		}
 	}
+	s.touchConfigHistory()
 }
 
 func (s *HttpServeEngine) HandleHttpEndpointEvent(event flux.HttpEndpointEvent) {
@@ -292,6 +622,7 @@ func (s *HttpServeEngine) HandleHttpEndpointEvent(event flux.HttpEndpointEvent)
 		if isreg {
 			logger.Infow("Register http handler", "method", method, "pattern", pattern)
 			s.httpWebServer.AddWebHandler(method, pattern, s.newWrappedEndpointHandler(bind))
+			s.registerPreflightHandler(method, pattern)
 		}
 	case flux.EventTypeUpdated:
 		logger.Infow("Update endpoint", "version", endpoint.Version, "method", method, "pattern", pattern)
@@ -300,18 +631,40 @@ func (s *HttpServeEngine) HandleHttpEndpointEvent(event flux.HttpEndpointEvent)
 		logger.Infow("Delete endpoint", "method", method, "pattern", pattern)
 		bind.Delete(endpoint.Version)
 	}
+	s.touchConfigHistory()
+}
+
+// touchConfigHistory 通知运行时配置历史记录新的配置版本
+func (s *HttpServeEngine) touchConfigHistory() {
+	if nil != s.configHistory {
+		s.configHistory.Touch()
+	}
 }
 
 // Shutdown to cleanup resources
 func (s *HttpServeEngine) Shutdown(ctx context.Context) error {
 	logger.Info("HttpServeEngine shutdown...")
 	defer close(s.stateStopped)
+	if nil != s.configHistory {
+		s.configHistory.Close()
+	}
 	if s.debugServer != nil {
 		_ = s.debugServer.Close()
 	}
+	if s.grpcServer != nil {
+		_ = s.grpcServer.Shutdown(ctx)
+	}
+	if s.listenerServer != nil {
+		if err := s.listenerServer.Shutdown(ctx); nil != err {
+			return err
+		}
+	}
 	if err := s.httpWebServer.Shutdown(ctx); nil != err {
 		return err
 	}
+	if s.router.asyncInvoker != nil {
+		s.router.asyncInvoker.shutdown()
+	}
 	return s.router.Shutdown(ctx)
 }
 
@@ -320,6 +673,11 @@ func (s *HttpServeEngine) StateStarted() <-chan struct{} {
 	return s.stateStarted
 }
 
+// StateReady 返回一个Channel。当服务启动并完成预热、可接收流量时，此Channel将被关闭。
+func (s *HttpServeEngine) StateReady() <-chan struct{} {
+	return s.stateReady
+}
+
 // StateStopped 返回一个Channel。当服务停止后完成时，此Channel将被关闭。
 func (s *HttpServeEngine) StateStopped() <-chan struct{} {
 	return s.stateStopped
@@ -360,6 +718,16 @@ func (s *HttpServeEngine) DebugServer() (*http.Server, bool) {
 	return s.debugServer, nil != s.debugServer
 }
 
+// InitTimings 返回各组件的初始化耗时，按耗时降序排列；在Initial之后可用
+func (s *HttpServeEngine) InitTimings() []ComponentInitTiming {
+	return s.router.InitTimings()
+}
+
+// ReadOnlySwitch 返回网关只读模式开关；在Initial之后可用
+func (s *HttpServeEngine) ReadOnlySwitch() *webmidware.ReadOnlySwitch {
+	return s.readOnlySwitch
+}
+
 // AddServerContextExchangeHook 添加Http与Flux的Context桥接函数
 func (s *HttpServeEngine) AddServerContextExchangeHook(f flux.ServerContextHookFunc) {
 	s.serverContextHooks = append(s.serverContextHooks, f)
@@ -373,16 +741,18 @@ func (s *HttpServeEngine) newWrappedEndpointHandler(endpoint *MultiEndpoint) flu
 }
 
 func (s *HttpServeEngine) selectMultiEndpoint(routeKey string, endpoint *flux.Endpoint) (*MultiEndpoint, bool) {
-	if mve, ok := SelectMultiEndpoint(routeKey); ok {
+	if mve, ok := s.endpoints.Select(routeKey); ok {
 		return mve, false
 	} else {
-		return RegisterMultiEndpoint(routeKey, endpoint), true
+		return s.endpoints.Register(routeKey, endpoint), true
 	}
 }
 
-func (s *HttpServeEngine) acquireContext(id string, webc flux.WebContext, endpoint *flux.Endpoint) *WrappedContext {
+func (s *HttpServeEngine) acquireContext(id, clientIp string, webc flux.WebContext, endpoint *flux.Endpoint) *WrappedContext {
 	ctx := s.contextWrappers.Get().(*WrappedContext)
-	ctx.Reattach(id, webc, endpoint)
+	ctx.Reattach(id, clientIp, webc, endpoint)
+	ctx.streaming = s.streaming
+	ctx.extensions = s.extensions
 	return ctx
 }
 
@@ -398,14 +768,6 @@ func (s *HttpServeEngine) ensure() *HttpServeEngine {
 	return s
 }
 
-func (s *HttpServeEngine) defaultNotFoundErrorHandler(webc flux.WebContext) error {
-	return &flux.ServeError{
-		StatusCode: flux.StatusNotFound,
-		ErrorCode:  flux.ErrorCodeRequestNotFound,
-		Message:    flux.ErrorMessageWebServerRequestNotFound,
-	}
-}
-
 func (s *HttpServeEngine) defaultServerErrorHandler(err error, webc flux.WebContext) {
 	if err == nil {
 		return
@@ -427,12 +789,21 @@ func (s *HttpServeEngine) defaultServerErrorHandler(err error, webc flux.WebCont
 	}
 }
 
-func activeEndpointRegistry() (flux.EndpointRegistry, *flux.Configuration, error) {
-	config := flux.NewConfigurationOf(flux.KeyConfigRootEndpointRegistry)
+// lookupGrpcEndpoint 根据路由Key（METHOD#pattern）查找已注册的Endpoint
+func (s *HttpServeEngine) lookupGrpcEndpoint(endpointId string) (method, pattern string, ok bool) {
+	if method, pattern, ok = parseRouteKey(endpointId); !ok {
+		return "", "", false
+	}
+	_, ok = s.endpoints.Select(fmt.Sprintf("%s#%s", method, pattern))
+	return method, pattern, ok
+}
+
+func activeEndpointRegistry(extensions *ext.Registry, root *flux.Configuration) (flux.EndpointRegistry, *flux.Configuration, error) {
+	config := root.Sub(flux.KeyConfigRootEndpointRegistry)
 	config.SetDefault(flux.KeyConfigEndpointRegistryId, ext.EndpointRegistryIdDefault)
 	registryId := config.GetString(flux.KeyConfigEndpointRegistryId)
 	logger.Infow("Active endpoint registry", "registry-id", registryId)
-	if factory, ok := ext.LoadEndpointRegistryFactory(registryId); !ok {
+	if factory, ok := extensions.LoadEndpointRegistryFactory(registryId); !ok {
 		return nil, config, fmt.Errorf("EndpointRegistryFactory not found, id: %s", registryId)
 	} else {
 		return factory(), config, nil
@@ -440,14 +811,36 @@ func activeEndpointRegistry() (flux.EndpointRegistry, *flux.Configuration, error
 }
 
 func isAllowedHttpMethod(method string) bool {
+	if isSupportedHttpMethod(method) {
+		return true
+	}
+	// http.MethodConnect, and Others
+	logger.Errorw("Ignore unsupported http method:", "method", method)
+	return false
+}
+
+func isSupportedHttpMethod(method string) bool {
 	switch method {
 	case http.MethodGet, http.MethodPost, http.MethodDelete, http.MethodPut,
 		http.MethodHead, http.MethodOptions, http.MethodPatch, http.MethodTrace:
-		// Allowed
 		return true
 	default:
-		// http.MethodConnect, and Others
-		logger.Errorw("Ignore unsupported http method:", "method", method)
 		return false
 	}
 }
+
+// newDebugServeMux 创建独立的DebugServer路由，包含pprof接口
+func newDebugServeMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// Extensions 返回网关引擎使用的扩展组件注册表
+func (s *HttpServeEngine) Extensions() *ext.Registry {
+	return s.extensions
+}
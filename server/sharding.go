@@ -0,0 +1,89 @@
+package server
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/support"
+	"github.com/spf13/cast"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Endpoint扩展属性：分片Key的查找表达式，例如 path:userId、header:X-Tenant-Id、body:order.userId
+	EndpointExtKeyShardKey = "shard-key"
+	// Endpoint扩展属性：分片服务表，按一致性哈希选择分片；列表形式为等权重的ServiceId，Map形式为 ServiceId: 权重。
+	// 分片服务表随Endpoint配置热更新，增加或删除分片时只有少量Key迁移到其它分片。
+	EndpointExtKeyShardServices = "shard-services"
+)
+
+// shardWeightsOf 返回Endpoint声明的分片服务及其权重
+func shardWeightsOf(endpoint *flux.Endpoint) map[string]int {
+	v := endpoint.Extensions[EndpointExtKeyShardServices]
+	weights := make(map[string]int, 8)
+	if m, err := cast.ToStringMapE(v); nil == err {
+		for id, weight := range m {
+			weights[id] = cast.ToInt(weight)
+		}
+		return weights
+	}
+	for _, id := range cast.ToStringSlice(v) {
+		weights[id] = 1
+	}
+	return weights
+}
+
+// shardRingOf 返回分片服务表的一致性哈希环，按分片服务表缓存；分片服务表变更后创建新的哈希环
+func (r *Router) shardRingOf(weights map[string]int) *support.HashRing {
+	ids := make([]string, 0, len(weights))
+	for id, weight := range weights {
+		ids = append(ids, id+"="+strconv.Itoa(weight))
+	}
+	sort.Strings(ids)
+	key := strings.Join(ids, ",")
+	if v, ok := r.shardRings.Load(key); ok {
+		return v.(*support.HashRing)
+	}
+	ring := support.NewHashRing(weights, support.DefaultHashRingReplicas)
+	r.shardRings.Store(key, ring)
+	return ring
+}
+
+// routeBySharding 按分片Key的哈希选择分片服务，替换本次请求的Endpoint服务；
+// 分片Key为空或读取失败时使用Endpoint的默认服务，分片服务不存在时返回错误
+func (r *Router) routeBySharding(ctx *WrappedContext) *flux.ServeError {
+	expr := cast.ToString(ctx.endpoint.Extensions[EndpointExtKeyShardKey])
+	if "" == expr {
+		return nil
+	}
+	scope, key, ok := support.ParseLookupExpr(expr)
+	if !ok {
+		logger.TraceContext(ctx).Warnw("Route, invalid shard key", "shard-key", expr)
+		return nil
+	}
+	// key 可能包含冒号
+	key = expr[strings.Index(expr, ":")+1:]
+	value, err := support.NewContextPredicateLookup(ctx)(scope, key)
+	shardKey := cast.ToString(value)
+	if nil != err || "" == shardKey {
+		logger.TraceContext(ctx).Debugw("Route, shard key is empty, use default service", "shard-key", expr, "error", err)
+		return nil
+	}
+	id, ok := r.shardRingOf(shardWeightsOf(ctx.endpoint)).Get(shardKey)
+	if !ok {
+		return nil
+	}
+	service, ok := r.extensions.LoadBackendService(id)
+	if !ok {
+		logger.TraceContext(ctx).Warnw("Route, shard service not found", "shard-key", expr, "service-id", id)
+		return &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageShardServiceNotFound,
+		}
+	}
+	logger.TraceContext(ctx).Debugw("Route, shard selected", "shard-key", expr, "service-id", id)
+	ctx.rebindService(service)
+	return nil
+}
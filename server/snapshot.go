@@ -0,0 +1,248 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	SnapshotConfigKeyDir = "dir"
+)
+
+const (
+	// 快照格式版本
+	RuntimeSnapshotVersion = 1
+	// 合并恢复：添加和更新快照中的数据，保留快照中不存在的数据
+	SnapshotRestoreMerge = "merge"
+	// 替换恢复：删除快照中不存在的Endpoint和Service，使运行时状态与快照一致
+	SnapshotRestoreReplace = "replace"
+)
+
+var (
+	ErrSnapshotNameInvalid    = errors.New("snapshot name is invalid")
+	ErrSnapshotVersionInvalid = errors.New("snapshot version is unsupported")
+)
+
+// RuntimeSnapshot 网关运行时状态快照：路由表（Endpoint，包含Endpoint扩展属性中的各项策略）、
+// 后端服务注册表和运行时开关，用于在其它实例上恢复相同的运行时状态
+type RuntimeSnapshot struct {
+	Version   int                            `json:"version"`
+	CreatedAt time.Time                      `json:"createdAt"`
+	Endpoints []flux.Endpoint                `json:"endpoints"`
+	Services  map[string]flux.BackendService `json:"services"`
+	Toggles   RuntimeToggles                 `json:"toggles"`
+}
+
+// RuntimeToggles 运行时开关
+type RuntimeToggles struct {
+	ReadOnly        bool   `json:"readOnly"`
+	ReadOnlyMessage string `json:"readOnlyMessage,omitempty"`
+}
+
+// SnapshotRestoreResult 快照恢复结果
+type SnapshotRestoreResult struct {
+	Endpoints        int `json:"endpoints"`
+	Services         int `json:"services"`
+	RemovedEndpoints int `json:"removedEndpoints"`
+	RemovedServices  int `json:"removedServices"`
+}
+
+// SnapshotStore 快照存储；默认为本地目录，可替换为对象存储（Bucket）的实现
+type SnapshotStore interface {
+	Save(name string, data []byte) error
+	Load(name string) ([]byte, error)
+}
+
+// FileSnapshotStore 本地目录的快照存储：每个快照保存为 <name>.json 文件
+type FileSnapshotStore struct {
+	dir string
+}
+
+func NewFileSnapshotStore(dir string) *FileSnapshotStore {
+	return &FileSnapshotStore{dir: dir}
+}
+
+// Save 先写入临时文件再重命名，避免读取到写入一半的快照
+func (f *FileSnapshotStore) Save(name string, data []byte) error {
+	path, err := f.pathOf(name)
+	if nil != err {
+		return err
+	}
+	if err := os.MkdirAll(f.dir, 0755); nil != err {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); nil != err {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (f *FileSnapshotStore) Load(name string) ([]byte, error) {
+	path, err := f.pathOf(name)
+	if nil != err {
+		return nil, err
+	}
+	return ioutil.ReadFile(path)
+}
+
+func (f *FileSnapshotStore) pathOf(name string) (string, error) {
+	if "" == name || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return "", ErrSnapshotNameInvalid
+	}
+	return filepath.Join(f.dir, name+".json"), nil
+}
+
+// SetSnapshotStore 设置运行时快照存储；默认为配置项 snapshot.dir 指定的本地目录。需要在 Initial 之前调用
+func (s *HttpServeEngine) SetSnapshotStore(store SnapshotStore) {
+	s.snapshotStore = store
+}
+
+// ExportSnapshot 导出当前运行时状态快照；Endpoint按路由和版本排序
+func (s *HttpServeEngine) ExportSnapshot() *RuntimeSnapshot {
+	snapshot := &RuntimeSnapshot{
+		Version:   RuntimeSnapshotVersion,
+		CreatedAt: time.Now(),
+		Endpoints: make([]flux.Endpoint, 0, 64),
+		Services:  s.extensions.LoadBackendServices(),
+	}
+	for _, mve := range s.endpoints.Load() {
+		for _, endpoint := range mve.ToSerializable() {
+			snapshot.Endpoints = append(snapshot.Endpoints, *endpoint)
+		}
+	}
+	sort.Slice(snapshot.Endpoints, func(i, j int) bool {
+		a, b := snapshot.Endpoints[i], snapshot.Endpoints[j]
+		if a.HttpPattern != b.HttpPattern {
+			return a.HttpPattern < b.HttpPattern
+		}
+		if a.HttpMethod != b.HttpMethod {
+			return a.HttpMethod < b.HttpMethod
+		}
+		return a.Version < b.Version
+	})
+	if nil != s.readOnlySwitch {
+		snapshot.Toggles.ReadOnly, snapshot.Toggles.ReadOnlyMessage = s.readOnlySwitch.State()
+	}
+	return snapshot
+}
+
+// RestoreSnapshot 恢复运行时状态快照：按Endpoint和Service事件应用快照数据；
+// replace 模式下删除快照中不存在的Endpoint和Service
+func (s *HttpServeEngine) RestoreSnapshot(snapshot *RuntimeSnapshot, mode string) (SnapshotRestoreResult, error) {
+	result := SnapshotRestoreResult{}
+	if RuntimeSnapshotVersion != snapshot.Version {
+		return result, ErrSnapshotVersionInvalid
+	}
+	if SnapshotRestoreReplace == mode {
+		keep := make(map[string]bool, len(snapshot.Endpoints))
+		for _, endpoint := range snapshot.Endpoints {
+			keep[snapshotEndpointKey(endpoint)] = true
+		}
+		for _, mve := range s.endpoints.Load() {
+			for _, endpoint := range mve.ToSerializable() {
+				if !keep[snapshotEndpointKey(*endpoint)] {
+					s.HandleHttpEndpointEvent(flux.HttpEndpointEvent{EventType: flux.EventTypeRemoved, Endpoint: *endpoint})
+					result.RemovedEndpoints++
+				}
+			}
+		}
+		for id := range s.extensions.LoadBackendServices() {
+			if _, ok := snapshot.Services[id]; !ok {
+				s.extensions.RemoveBackendService(id)
+				result.RemovedServices++
+			}
+		}
+	}
+	for id, service := range snapshot.Services {
+		s.extensions.StoreBackendServiceById(id, service)
+		result.Services++
+	}
+	for _, endpoint := range snapshot.Endpoints {
+		s.HandleHttpEndpointEvent(flux.HttpEndpointEvent{EventType: flux.EventTypeAdded, Endpoint: endpoint})
+		result.Endpoints++
+	}
+	if nil != s.readOnlySwitch {
+		s.readOnlySwitch.Set(snapshot.Toggles.ReadOnly, snapshot.Toggles.ReadOnlyMessage)
+	}
+	logger.Infow("HttpServeEngine runtime snapshot restored", "mode", mode, "created-at", snapshot.CreatedAt,
+		"endpoints", result.Endpoints, "services", result.Services,
+		"removed-endpoints", result.RemovedEndpoints, "removed-services", result.RemovedServices)
+	return result, nil
+}
+
+func snapshotEndpointKey(endpoint flux.Endpoint) string {
+	return fmt.Sprintf("%s#%s#%s", strings.ToUpper(endpoint.HttpMethod), endpoint.HttpPattern, endpoint.Version)
+}
+
+// NewDebugSnapshotHandler 运行时状态快照管理：GET返回当前快照；
+// POST参数 action=export&name=快照名称，导出快照到存储；
+// POST参数 action=restore&name=快照名称&mode=merge|replace，从存储恢复快照；未指定name时从请求Body读取快照
+func NewDebugSnapshotHandler(s *HttpServeEngine) http.HandlerFunc {
+	serializer := s.extensions.LoadSerializer(ext.TypeNameSerializerJson)
+	failed := func(message string, err error) interface{} {
+		return map[string]string{
+			"status":  "failed",
+			"message": message,
+			"error":   fmt.Sprintf("%v", err),
+		}
+	}
+	return newSerializableHttpHandler(serializer, func(request *http.Request) interface{} {
+		if http.MethodPost != request.Method && http.MethodPut != request.Method {
+			return s.ExportSnapshot()
+		}
+		name := request.FormValue("name")
+		switch action := request.FormValue("action"); action {
+		case "export":
+			data, err := json.Marshal(s.ExportSnapshot())
+			if nil != err {
+				return failed("marshal snapshot", err)
+			}
+			if err := s.snapshotStore.Save(name, data); nil != err {
+				return failed("save snapshot", err)
+			}
+			logger.Infow("HttpServeEngine runtime snapshot exported", "name", name, "size", len(data))
+			return map[string]interface{}{"status": "success", "name": name, "size": len(data)}
+		case "restore":
+			var data []byte
+			var err error
+			if "" != name {
+				data, err = s.snapshotStore.Load(name)
+			} else {
+				data, err = ioutil.ReadAll(request.Body)
+			}
+			if nil != err {
+				return failed("load snapshot", err)
+			}
+			snapshot := new(RuntimeSnapshot)
+			if err := json.Unmarshal(data, snapshot); nil != err {
+				return failed("unmarshal snapshot", err)
+			}
+			mode := request.FormValue("mode")
+			if "" == mode {
+				mode = SnapshotRestoreMerge
+			}
+			result, err := s.RestoreSnapshot(snapshot, mode)
+			if nil != err {
+				return failed("restore snapshot", err)
+			}
+			return result
+		default:
+			return map[string]string{
+				"status":  "failed",
+				"message": "param is required: action=export|restore",
+			}
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/remoting/spiffe"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// SPIFFE身份配置的命名空间
+	SpiffeConfigRootName = "Spiffe"
+)
+
+const (
+	SpiffeConfigKeyEnable       = "enable"
+	SpiffeConfigKeySocket       = "socket"
+	SpiffeConfigKeyWaitTimeout  = "wait-timeout"
+	SpiffeConfigKeyAllowedIDs   = "allowed-ids"
+	SpiffeConfigKeyTrustDomains = "trust-domains"
+)
+
+// initSpiffe 开启SPIFFE身份时，从SPIRE Agent的Workload API获取并自动轮换网关的X.509 SVID，用于连接上游的mTLS。例如：
+// [Spiffe]
+// enable = true
+// socket = "unix:///run/spire/sockets/agent.sock"
+// trust-domains = ["example.org"]
+// 上游的SPIFFE ID验证策略：上游集群配置了 spiffe-ids 时只允许其中的ID；否则按 allowed-ids、trust-domains 的顺序使用全局策略，
+// 均未配置时只允许与网关相同信任域的ID。socket 未配置时读取环境变量 SPIFFE_ENDPOINT_SOCKET。
+func (s *HttpServeEngine) initSpiffe() error {
+	config := s.config.Sub(SpiffeConfigRootName)
+	config.SetDefaults(map[string]interface{}{
+		SpiffeConfigKeyEnable:      false,
+		SpiffeConfigKeySocket:      os.Getenv(spiffe.EnvKeyEndpointSocket),
+		SpiffeConfigKeyWaitTimeout: time.Second * 30,
+	})
+	if !config.GetBool(SpiffeConfigKeyEnable) {
+		return nil
+	}
+	socket := config.GetString(SpiffeConfigKeySocket)
+	if "" == socket {
+		return errors.New("spiffe workload api socket is required, config: " + SpiffeConfigRootName + "." + SpiffeConfigKeySocket)
+	}
+	client, err := spiffe.NewClient(socket)
+	if nil != err {
+		return err
+	}
+	source := spiffe.NewX509Source(client)
+	source.Start()
+	ctx, cancel := context.WithTimeout(context.Background(), config.GetDuration(SpiffeConfigKeyWaitTimeout))
+	defer cancel()
+	if err := source.WaitReady(ctx); nil != err {
+		_ = source.Shutdown(context.Background())
+		return err
+	}
+	s.extensions.StoreHookFunc(source)
+	authorizer := spiffe.AuthorizeMemberOf(config.GetStringSlice(SpiffeConfigKeyTrustDomains)...)
+	if ids := config.GetStringSlice(SpiffeConfigKeyAllowedIDs); len(ids) > 0 {
+		authorizer = spiffe.AuthorizeID(ids...)
+	} else if 0 == len(config.GetStringSlice(SpiffeConfigKeyTrustDomains)) {
+		x509ctx, _ := source.X509Context()
+		td, _ := spiffe.TrustDomainOf(x509ctx.SVIDs[0].ID)
+		authorizer = spiffe.AuthorizeMemberOf(td)
+	}
+	s.extensions.StoreUpstreamTLSConfigFunc(func(address string) (*tls.Config, error) {
+		if ids := s.upstreamSpiffeIDsOf(address); len(ids) > 0 {
+			return source.ClientTLSConfig(spiffe.AuthorizeID(ids...)), nil
+		}
+		return source.ClientTLSConfig(authorizer), nil
+	})
+	logger.Infow("SPIFFE upstream mTLS enabled", "socket", socket)
+	return nil
+}
+
+// upstreamSpiffeIDsOf 返回包含指定地址（host:port）的上游集群配置的SPIFFE ID
+func (s *HttpServeEngine) upstreamSpiffeIDsOf(address string) []string {
+	ids := make([]string, 0, 2)
+	for _, upstream := range s.extensions.LoadUpstreams() {
+		if 0 == len(upstream.SpiffeIDs) {
+			continue
+		}
+		for _, addr := range upstream.Addresses {
+			host := addr.Address
+			if i := strings.Index(host, "://"); i >= 0 {
+				host = host[i+3:]
+			}
+			if i := strings.IndexAny(host, "/?"); i >= 0 {
+				host = host[:i]
+			}
+			if host == address {
+				ids = append(ids, upstream.SpiffeIDs...)
+				break
+			}
+		}
+	}
+	return ids
+}
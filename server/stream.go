@@ -0,0 +1,130 @@
+package server
+
+import (
+	"errors"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"github.com/spf13/cast"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	StreamingConfigKeyWriteTimeout = "write-timeout"
+	StreamingConfigKeyBufferSize   = "buffer-size"
+	StreamingConfigKeyStallAction  = "stall-action"
+	StreamingConfigKeyDrainLimit   = "drain-limit"
+)
+
+const (
+	// 客户端写入超时后，立即关闭上游数据流
+	StreamStallActionAbort = "abort"
+	// 客户端写入超时后，读取并丢弃剩余的上游数据（不超过 drain-limit）再关闭，以便复用上游连接
+	StreamStallActionDrain = "drain"
+)
+
+var (
+	ErrStreamWriteStalled = errors.New("stream writer stalled: client write timeout")
+)
+
+// streamOptions 流式响应的写入控制：按固定大小分块读取上游数据，每块写入客户端并Flush后才读取下一块，
+// 上游数据的读取速度受客户端接收速度约束（背压），网关内存占用不超过一个分块；
+// 每块写入设置连接写超时，客户端接收过慢时中止响应，避免慢客户端长期占用上游连接。
+type streamOptions struct {
+	writeTimeout time.Duration
+	bufferSize   int
+	stallAction  string
+	drainLimit   int64
+	metrics      *Metrics
+}
+
+func newStreamOptions(config *flux.Configuration, metrics *Metrics) *streamOptions {
+	config.SetDefaults(map[string]interface{}{
+		StreamingConfigKeyWriteTimeout: time.Second * 30,
+		StreamingConfigKeyBufferSize:   32 * 1024,
+		StreamingConfigKeyStallAction:  StreamStallActionAbort,
+		StreamingConfigKeyDrainLimit:   256 * 1024,
+	})
+	opts := &streamOptions{
+		writeTimeout: config.GetDuration(StreamingConfigKeyWriteTimeout),
+		bufferSize:   config.GetInt(StreamingConfigKeyBufferSize),
+		stallAction:  config.GetString(StreamingConfigKeyStallAction),
+		drainLimit:   config.GetInt64(StreamingConfigKeyDrainLimit),
+		metrics:      metrics,
+	}
+	if opts.bufferSize <= 0 {
+		opts.bufferSize = 32 * 1024
+	}
+	logger.Infow("Streaming response options", "write-timeout", opts.writeTimeout,
+		"buffer-size", opts.bufferSize, "stall-action", opts.stallAction)
+	return opts
+}
+
+// write 分块写入流数据；客户端写入超时时按 stall-action 处理上游数据并关闭，返回 ErrStreamWriteStalled。
+// 连接级别的写超时只作用于HTTP/1.x连接；HTTP/2连接由多个请求共享，只使用分块写入的背压控制。
+func (o *streamOptions) write(webc flux.WebContext, statusCode int, contentType string, reader io.Reader) error {
+	w, err := webc.HttpResponseWriter()
+	if nil != err {
+		return webc.WriteStream(statusCode, contentType, reader)
+	}
+	var conn net.Conn
+	if request, err := webc.HttpRequest(); nil == err && 1 == request.ProtoMajor && o.writeTimeout > 0 {
+		conn, _ = flux.ConnOfContext(request.Context())
+	}
+	if nil != conn {
+		defer func() {
+			_ = conn.SetWriteDeadline(time.Time{})
+		}()
+	}
+	if nil != o.metrics {
+		o.metrics.StreamActive.Inc()
+		defer o.metrics.StreamActive.Dec()
+	}
+	if "" != contentType {
+		w.Header().Set(flux.HeaderContentType, contentType)
+	}
+	w.WriteHeader(statusCode)
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, o.bufferSize)
+	for {
+		n, rerr := reader.Read(buf)
+		if n > 0 {
+			if nil != conn {
+				_ = conn.SetWriteDeadline(time.Now().Add(o.writeTimeout))
+			}
+			if _, werr := w.Write(buf[:n]); nil != werr {
+				return o.stalled(webc, reader, werr)
+			}
+			if nil != flusher {
+				flusher.Flush()
+			}
+		}
+		if io.EOF == rerr {
+			return nil
+		}
+		if nil != rerr {
+			return rerr
+		}
+	}
+}
+
+// stalled 客户端写入失败：按 stall-action 处理上游数据并关闭
+func (o *streamOptions) stalled(webc flux.WebContext, reader io.Reader, cause error) error {
+	action := o.stallAction
+	if StreamStallActionDrain == action && o.drainLimit > 0 {
+		_, _ = io.CopyN(ioutil.Discard, reader, o.drainLimit)
+	} else {
+		action = StreamStallActionAbort
+	}
+	if c, ok := reader.(io.Closer); ok {
+		_ = c.Close()
+	}
+	if nil != o.metrics {
+		o.metrics.StreamStalled.WithLabelValues(action).Inc()
+	}
+	logger.Trace(cast.ToString(webc.GetValue(flux.HeaderXRequestId))).Warnw("Streaming response stalled", "action", action, "error", cause)
+	return ErrStreamWriteStalled
+}
@@ -0,0 +1,371 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"github.com/spf13/cast"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	TrafficConfigKeyMaxRate     = "max-rate"
+	TrafficConfigKeyMaxDuration = "max-duration"
+)
+
+const (
+	// Endpoint扩展属性：生成流量时使用的固定参数值，Key为参数的HttpName，优先于按参数类型生成的值
+	EndpointExtKeyTrafficValues = "traffic-values"
+	// 生成流量请求的标识Header
+	HeaderXFluxTraffic = "X-Flux-Traffic"
+)
+
+var (
+	ErrTrafficRunning         = errors.New("traffic generator is running")
+	ErrTrafficNoEndpoints     = errors.New("traffic generator: no endpoints selected")
+	ErrTrafficHandlerRequired = errors.New("traffic generator requires WebServer implements http.Handler")
+)
+
+// TrafficValueGenerator 按参数定义生成请求参数值
+type TrafficValueGenerator func(arg flux.Argument) interface{}
+
+var (
+	trafficValueGenerators = map[string]TrafficValueGenerator{
+		flux.JavaLangStringClassName: func(arg flux.Argument) interface{} {
+			return fmt.Sprintf("%s-%d", arg.HttpName, rand.Intn(10000))
+		},
+		flux.JavaLangIntegerClassName: func(flux.Argument) interface{} {
+			return rand.Intn(1000)
+		},
+		flux.JavaLangLongClassName: func(flux.Argument) interface{} {
+			return rand.Int63n(1000000)
+		},
+		flux.JavaLangFloatClassName: func(flux.Argument) interface{} {
+			return rand.Float32() * 100
+		},
+		flux.JavaLangDoubleClassName: func(flux.Argument) interface{} {
+			return rand.Float64() * 100
+		},
+		flux.JavaLangBooleanClassName: func(flux.Argument) interface{} {
+			return 0 == rand.Intn(2)
+		},
+	}
+	trafficValueGeneratorsLock sync.RWMutex
+)
+
+// SetTrafficValueGenerator 设置参数类型（Class）的值生成函数
+func SetTrafficValueGenerator(class string, generator TrafficValueGenerator) {
+	trafficValueGeneratorsLock.Lock()
+	defer trafficValueGeneratorsLock.Unlock()
+	trafficValueGenerators[class] = generator
+}
+
+// TrafficPlan 流量生成计划：按固定速率轮流向选中的Endpoint发送请求
+type TrafficPlan struct {
+	Pattern  string        `json:"pattern"`
+	Method   string        `json:"method"`
+	Rate     float64       `json:"rate"`
+	Duration time.Duration `json:"-"`
+	Count    int           `json:"count"`
+}
+
+// TrafficStatus 流量生成状态
+type TrafficStatus struct {
+	Running   bool           `json:"running"`
+	Plan      TrafficPlan    `json:"plan"`
+	Endpoints int            `json:"endpoints"`
+	StartedAt time.Time      `json:"startedAt"`
+	Sent      int            `json:"sent"`
+	Failed    int            `json:"failed"`
+	Statuses  map[string]int `json:"statuses"`
+}
+
+// trafficGenerator 进程内流量生成器：根据Endpoint的参数定义构建请求，直接交给WebServer处理，
+// 用于新路由的冒烟测试和缓存预热。同一时间只运行一个计划。
+type trafficGenerator struct {
+	handler       http.Handler
+	versionHeader string
+	maxRate       float64
+	maxDuration   time.Duration
+	mu            sync.Mutex
+	status        TrafficStatus
+	stop          chan struct{}
+}
+
+func newTrafficGenerator(config *flux.Configuration, handler http.Handler, versionHeader string) *trafficGenerator {
+	config.SetDefaults(map[string]interface{}{
+		TrafficConfigKeyMaxRate:     100,
+		TrafficConfigKeyMaxDuration: time.Minute * 10,
+	})
+	return &trafficGenerator{
+		handler:       handler,
+		versionHeader: versionHeader,
+		maxRate:       config.GetFloat64(TrafficConfigKeyMaxRate),
+		maxDuration:   config.GetDuration(TrafficConfigKeyMaxDuration),
+	}
+}
+
+// start 启动流量生成计划；速率和时长不超过配置的上限
+func (g *trafficGenerator) start(plan TrafficPlan, endpoints []flux.Endpoint) error {
+	if 0 == len(endpoints) {
+		return ErrTrafficNoEndpoints
+	}
+	if plan.Rate <= 0 {
+		plan.Rate = 1
+	}
+	if plan.Rate > g.maxRate {
+		plan.Rate = g.maxRate
+	}
+	if plan.Duration <= 0 || plan.Duration > g.maxDuration {
+		plan.Duration = g.maxDuration
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.status.Running {
+		return ErrTrafficRunning
+	}
+	g.status = TrafficStatus{
+		Running: true, Plan: plan, Endpoints: len(endpoints), StartedAt: time.Now(),
+		Statuses: make(map[string]int, 4),
+	}
+	g.stop = make(chan struct{})
+	logger.Infow("Traffic generator started", "pattern", plan.Pattern, "method", plan.Method,
+		"rate", plan.Rate, "duration", plan.Duration, "count", plan.Count, "endpoints", len(endpoints))
+	go g.run(plan, endpoints, g.stop)
+	return nil
+}
+
+func (g *trafficGenerator) run(plan TrafficPlan, endpoints []flux.Endpoint, stop chan struct{}) {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / plan.Rate))
+	defer ticker.Stop()
+	deadline := time.NewTimer(plan.Duration)
+	defer deadline.Stop()
+	defer g.finish()
+	for i := 0; 0 == plan.Count || i < plan.Count; i++ {
+		select {
+		case <-stop:
+			return
+		case <-deadline.C:
+			return
+		case <-ticker.C:
+			g.send(endpoints[i%len(endpoints)])
+		}
+	}
+}
+
+func (g *trafficGenerator) send(endpoint flux.Endpoint) {
+	status := 0
+	request, err := newTrafficRequest(endpoint, g.versionHeader)
+	if nil == err {
+		recorder := newResponseRecorder()
+		g.handler.ServeHTTP(recorder, request)
+		status = recorder.status
+	} else {
+		logger.Warnw("Traffic generator, build request", "pattern", endpoint.HttpPattern, "error", err)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.status.Sent++
+	if status < http.StatusOK || status >= http.StatusBadRequest {
+		g.status.Failed++
+	}
+	g.status.Statuses[strconv.Itoa(status)]++
+}
+
+func (g *trafficGenerator) finish() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.status.Running = false
+	logger.Infow("Traffic generator finished", "sent", g.status.Sent, "failed", g.status.Failed, "statuses", g.status.Statuses)
+}
+
+// cancel 停止运行中的计划
+func (g *trafficGenerator) cancel() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.status.Running && nil != g.stop {
+		close(g.stop)
+		g.stop = nil
+	}
+}
+
+func (g *trafficGenerator) snapshot() TrafficStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	out := g.status
+	out.Statuses = make(map[string]int, len(g.status.Statuses))
+	for k, v := range g.status.Statuses {
+		out.Statuses[k] = v
+	}
+	return out
+}
+
+// newTrafficRequest 根据Endpoint的参数定义构建请求：PATH/QUERY/HEADER/FORM参数按值域写入请求，
+// BODY参数和复杂类型参数写入JSON请求体；未知类型的参数不生成
+func newTrafficRequest(endpoint flux.Endpoint, versionHeader string) (*http.Request, error) {
+	fixed := cast.ToStringMap(endpoint.Extensions[EndpointExtKeyTrafficValues])
+	path := endpoint.HttpPattern
+	query, form := url.Values{}, url.Values{}
+	header := http.Header{}
+	body := make(map[string]interface{}, 4)
+	for _, arg := range endpoint.Service.Arguments {
+		name := arg.HttpName
+		if "" == name {
+			name = arg.Name
+		}
+		value, ok := fixed[name]
+		if !ok {
+			if value, ok = trafficValueOf(arg); !ok {
+				continue
+			}
+		}
+		switch strings.ToUpper(arg.HttpScope) {
+		case flux.ScopePath:
+			path = replacePathVariable(path, name, cast.ToString(value))
+		case flux.ScopeHeader:
+			header.Set(name, cast.ToString(value))
+		case flux.ScopeForm, flux.ScopeFormMulti:
+			form.Add(name, cast.ToString(value))
+		case flux.ScopeBody:
+			body[name] = value
+		default:
+			if flux.ArgumentTypeComplex == arg.Type {
+				body[name] = value
+			} else {
+				query.Add(name, cast.ToString(value))
+			}
+		}
+	}
+	target := path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var request *http.Request
+	var err error
+	switch {
+	case len(body) > 0:
+		data, merr := json.Marshal(body)
+		if nil != merr {
+			return nil, merr
+		}
+		request, err = http.NewRequest(endpoint.HttpMethod, target, bytes.NewReader(data))
+		header.Set(flux.HeaderContentType, flux.MIMEApplicationJSONCharsetUTF8)
+	case len(form) > 0:
+		request, err = http.NewRequest(endpoint.HttpMethod, target, strings.NewReader(form.Encode()))
+		header.Set(flux.HeaderContentType, flux.MIMEApplicationForm)
+	default:
+		request, err = http.NewRequest(endpoint.HttpMethod, target, nil)
+	}
+	if nil != err {
+		return nil, err
+	}
+	for k, v := range header {
+		request.Header[k] = v
+	}
+	request.Header.Set(HeaderXFluxTraffic, "generated")
+	if "" != endpoint.Version && "" != versionHeader {
+		request.Header.Set(versionHeader, endpoint.Version)
+	}
+	return request, nil
+}
+
+// replacePathVariable 替换路由Pattern中名称匹配的路径变量段（:name 或 {name}）
+func replacePathVariable(pattern, name, value string) string {
+	segments := strings.Split(pattern, "/")
+	for i, seg := range segments {
+		if ":"+name == seg || "{"+name+"}" == seg {
+			segments[i] = url.PathEscape(value)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// trafficValueOf 按参数类型生成值；复杂类型按字段生成JSON对象
+func trafficValueOf(arg flux.Argument) (interface{}, bool) {
+	if len(arg.Fields) > 0 {
+		fields := make(map[string]interface{}, len(arg.Fields))
+		for _, field := range arg.Fields {
+			if v, ok := trafficValueOf(field); ok {
+				fields[field.Name] = v
+			}
+		}
+		return fields, true
+	}
+	trafficValueGeneratorsLock.RLock()
+	generator, ok := trafficValueGenerators[arg.Class]
+	trafficValueGeneratorsLock.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return generator(arg), true
+}
+
+// selectTrafficEndpoints 按Pattern和Method选择Endpoint；每个路由只选择最新版本
+func (s *HttpServeEngine) selectTrafficEndpoints(pattern, method string) []flux.Endpoint {
+	out := make([]flux.Endpoint, 0, 16)
+	for _, mve := range s.endpoints.Load() {
+		endpoint := mve.Latest()
+		if nil == endpoint {
+			continue
+		}
+		if "" != pattern && !queryMatch(pattern, endpoint.HttpPattern) {
+			continue
+		}
+		if "" != method && !strings.EqualFold(method, endpoint.HttpMethod) {
+			continue
+		}
+		out = append(out, *endpoint)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].HttpPattern+out[i].HttpMethod < out[j].HttpPattern+out[j].HttpMethod
+	})
+	return out
+}
+
+// NewDebugTrafficHandler 流量生成器管理：GET返回运行状态；
+// POST参数 action=start&pattern=路由Pattern（包含匹配）&method=&rate=每秒请求数&duration=30s&count=最大请求数，启动生成；
+// POST参数 action=stop，停止生成
+func NewDebugTrafficHandler(s *HttpServeEngine) http.HandlerFunc {
+	serializer := s.extensions.LoadSerializer(ext.TypeNameSerializerJson)
+	return newSerializableHttpHandler(serializer, func(request *http.Request) interface{} {
+		if http.MethodPost != request.Method && http.MethodPut != request.Method {
+			return s.traffic.snapshot()
+		}
+		switch request.FormValue("action") {
+		case "start":
+			plan := TrafficPlan{
+				Pattern:  request.FormValue("pattern"),
+				Method:   request.FormValue("method"),
+				Rate:     cast.ToFloat64(request.FormValue("rate")),
+				Duration: cast.ToDuration(request.FormValue("duration")),
+				Count:    cast.ToInt(request.FormValue("count")),
+			}
+			if err := s.traffic.start(plan, s.selectTrafficEndpoints(plan.Pattern, plan.Method)); nil != err {
+				return map[string]string{
+					"status":  "failed",
+					"message": err.Error(),
+				}
+			}
+			return s.traffic.snapshot()
+		case "stop":
+			s.traffic.cancel()
+			return s.traffic.snapshot()
+		default:
+			return map[string]string{
+				"status":  "failed",
+				"message": "param is required: action=start|stop",
+			}
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"github.com/spf13/cast"
+	"strings"
+)
+
+const (
+	UpstreamConfigKeyBalancer    = "balancer"
+	UpstreamConfigKeyHashKey     = "hash-key"
+	UpstreamConfigKeyAddresses   = "addresses"
+	UpstreamConfigKeyHealthCheck = "health-check"
+	UpstreamConfigKeySpiffeIDs   = "spiffe-ids"
+)
+
+const (
+	HealthCheckConfigKeyType               = "type"
+	HealthCheckConfigKeyPath               = "path"
+	HealthCheckConfigKeyInterval           = "interval"
+	HealthCheckConfigKeyTimeout            = "timeout"
+	HealthCheckConfigKeyUnhealthyThreshold = "unhealthy-threshold"
+	HealthCheckConfigKeyHealthyThreshold   = "healthy-threshold"
+	HealthCheckConfigKeyEjectDuration      = "eject-duration"
+)
+
+// loadUpstreams 加载静态配置的上游集群。例如：
+// [Upstreams.order-cluster]
+// balancer = "weighted"
+// addresses = ["10.0.0.1:8080=3", "10.0.0.2:8080=1"]
+// spiffe-ids = ["spiffe://example.org/order"]
+// [Upstreams.order-cluster.health-check]
+// type = "http"
+// path = "/health"
+// 地址的权重以 "=" 分隔，未声明时为1；健康检查未配置的项使用默认值
+func (s *HttpServeEngine) loadUpstreams() error {
+	config := s.config.Sub(flux.KeyConfigRootUpstreams)
+	for name := range config.Reference().AllSettings() {
+		sub := config.Sub(name)
+		check := sub.Sub(UpstreamConfigKeyHealthCheck)
+		upstream := flux.Upstream{
+			Name:      name,
+			Balancer:  sub.GetString(UpstreamConfigKeyBalancer),
+			HashKey:   sub.GetString(UpstreamConfigKeyHashKey),
+			SpiffeIDs: sub.GetStringSlice(UpstreamConfigKeySpiffeIDs),
+			HealthCheck: flux.UpstreamHealthCheck{
+				Type:               check.GetString(HealthCheckConfigKeyType),
+				Path:               check.GetString(HealthCheckConfigKeyPath),
+				Interval:           check.GetDuration(HealthCheckConfigKeyInterval),
+				Timeout:            check.GetDuration(HealthCheckConfigKeyTimeout),
+				UnhealthyThreshold: check.GetInt(HealthCheckConfigKeyUnhealthyThreshold),
+				HealthyThreshold:   check.GetInt(HealthCheckConfigKeyHealthyThreshold),
+				EjectDuration:      check.GetDuration(HealthCheckConfigKeyEjectDuration),
+			},
+		}
+		for _, item := range sub.GetStringSlice(UpstreamConfigKeyAddresses) {
+			address, weight := strings.TrimSpace(item), 1
+			if i := strings.LastIndex(address, "="); i > 0 {
+				w, err := cast.ToIntE(strings.TrimSpace(address[i+1:]))
+				if nil != err {
+					return fmt.Errorf("upstream address weight is invalid, upstream: %s, address: %s", name, item)
+				}
+				address, weight = strings.TrimSpace(address[:i]), w
+			}
+			upstream.Addresses = append(upstream.Addresses, flux.UpstreamAddress{Address: address, Weight: weight})
+		}
+		if 0 == len(upstream.Addresses) {
+			return fmt.Errorf("upstream has no address, upstream: %s", name)
+		}
+		logger.Infow("Load upstream", "upstream", name, "balancer", upstream.Balancer, "addresses", upstream.Addresses)
+		s.extensions.StoreUpstream(upstream)
+	}
+	return nil
+}
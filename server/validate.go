@@ -0,0 +1,285 @@
+package server
+
+import (
+	"flag"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/registry"
+	"github.com/bytepowered/flux/support"
+	"github.com/spf13/viper"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	ValidationLevelError = "ERROR"
+	ValidationLevelWarn  = "WARN"
+)
+
+// 内置组件使用的配置根节点（小写，与Viper的Key一致）
+var knownConfigRoots = []string{
+	strings.ToLower(HttpWebServerConfigRootName),
+	strings.ToLower(flux.KeyConfigRootEndpointRegistry),
+	strings.ToLower(flux.KeyConfigRootUpstreams),
+	strings.ToLower(SpiffeConfigRootName),
+	strings.ToLower(XdsConfigRootName),
+	"backend", "credential", "filter", "zookeeper",
+	strings.ToLower(support.DefaultSecretConfigNamespace),
+	strings.ToLower(support.DefaultFeatureFlagConfigNamespace),
+}
+
+// ValidationIssue 配置校验发现的问题
+type ValidationIssue struct {
+	Level   string
+	Subject string // 问题所在的配置项或Endpoint
+	Message string
+}
+
+// ValidationReport 配置校验报告
+type ValidationReport struct {
+	Issues []ValidationIssue
+}
+
+func (r *ValidationReport) Errorf(subject string, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, ValidationIssue{Level: ValidationLevelError, Subject: subject, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *ValidationReport) Warnf(subject string, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, ValidationIssue{Level: ValidationLevelWarn, Subject: subject, Message: fmt.Sprintf(format, args...)})
+}
+
+// HasErrors 判断报告是否包含ERROR级别的问题
+func (r *ValidationReport) HasErrors() bool {
+	for _, issue := range r.Issues {
+		if ValidationLevelError == issue.Level {
+			return true
+		}
+	}
+	return false
+}
+
+// Write 逐行输出校验问题和汇总信息
+func (r *ValidationReport) Write(w io.Writer) {
+	errs := 0
+	for _, issue := range r.Issues {
+		if ValidationLevelError == issue.Level {
+			errs++
+		}
+		_, _ = fmt.Fprintf(w, "%-5s %s: %s\n", issue.Level, issue.Subject, issue.Message)
+	}
+	_, _ = fmt.Fprintf(w, "%d error(s), %d warning(s)\n", errs, len(r.Issues)-errs)
+}
+
+// Validate 对全局配置、扩展组件注册表和Endpoint快照进行交叉校验：
+// 检查引用的Filter、序列化器、协议和值解析器是否已注册，并报告未使用或冲突的配置。
+// knownRoots 为自定义组件使用的配置根节点，不会被报告为未使用的配置。
+func Validate(extensions *ext.Registry, endpoints []flux.Endpoint, knownRoots ...string) *ValidationReport {
+	report := new(ValidationReport)
+	validateSerializers(report, extensions)
+	validateEndpointRegistry(report, extensions)
+	filterIds := validateFilters(report, extensions)
+	validateSelectors(report, extensions, filterIds)
+	validateEndpoints(report, extensions, endpoints)
+	validateUnusedConfigs(report, extensions, filterIds, knownRoots)
+	return report
+}
+
+func validateSerializers(report *ValidationReport, extensions *ext.Registry) {
+	for _, name := range []string{ext.TypeNameSerializerDefault, ext.TypeNameSerializerJson} {
+		if nil == extensions.LoadSerializer(name) {
+			report.Errorf("serializer."+name, "serializer not registered")
+		}
+	}
+}
+
+func validateEndpointRegistry(report *ValidationReport, extensions *ext.Registry) {
+	config := flux.NewConfigurationOf(flux.KeyConfigRootEndpointRegistry)
+	config.SetDefault(flux.KeyConfigEndpointRegistryId, ext.EndpointRegistryIdDefault)
+	registryId := config.GetString(flux.KeyConfigEndpointRegistryId)
+	if _, ok := extensions.LoadEndpointRegistryFactory(registryId); !ok {
+		report.Errorf(flux.KeyConfigRootEndpointRegistry+"."+flux.KeyConfigEndpointRegistryId,
+			"EndpointRegistryFactory not found, id: %s", registryId)
+	}
+}
+
+// validateFilters 校验动态Filter配置，返回已知的全部FilterId（小写）
+func validateFilters(report *ValidationReport, extensions *ext.Registry) map[string]bool {
+	ids := make(map[string]bool, 16)
+	for _, filter := range append(extensions.LoadGlobalFilters(), extensions.LoadSelectiveFilters()...) {
+		lowerKeys(ids, filter.TypeId())
+	}
+	for id := range viper.GetStringMap("FILTER") {
+		subject := "FILTER." + id
+		v := viper.Sub(subject)
+		if nil == v || !v.IsSet(dynConfigKeyTypeId) {
+			report.Warnf(subject, "filter config without %s, ignored", dynConfigKeyTypeId)
+			continue
+		}
+		typeId := v.GetString(dynConfigKeyTypeId)
+		factory, ok := extensions.LoadTypedFactory(typeId)
+		if !ok {
+			report.Errorf(subject, "FilterFactory not found, typeId: %s", typeId)
+			continue
+		}
+		filter, ok := factory().(flux.Filter)
+		if !ok {
+			report.Errorf(subject, "factory of typeId %s does not create a Filter", typeId)
+			continue
+		}
+		if ids[strings.ToLower(filter.TypeId())] {
+			report.Errorf(subject, "filter id conflicts with another filter: %s", filter.TypeId())
+		}
+		lowerKeys(ids, filter.TypeId())
+	}
+	return ids
+}
+
+func validateSelectors(report *ValidationReport, extensions *ext.Registry, filterIds map[string]bool) {
+	for host, selectors := range extensions.LoadSelectors() {
+		for _, selector := range selectors {
+			static, ok := selector.(flux.StaticSelector)
+			if !ok {
+				continue
+			}
+			for _, id := range static.FilterIds() {
+				if !filterIds[strings.ToLower(id)] {
+					report.Errorf("selector@"+host, "selected filter not registered: %s", id)
+				}
+			}
+		}
+	}
+}
+
+func validateEndpoints(report *ValidationReport, extensions *ext.Registry, endpoints []flux.Endpoint) {
+	routes := make(map[string]string, len(endpoints))
+	for _, endpoint := range endpoints {
+		subject := endpoint.HttpMethod + "#" + endpoint.HttpPattern
+		if "" != endpoint.Version {
+			subject += "@" + endpoint.Version
+		}
+		if !endpoint.IsValid() {
+			report.Errorf(subject, "invalid endpoint, method, pattern and service are required")
+			continue
+		}
+		if !isSupportedHttpMethod(endpoint.HttpMethod) {
+			report.Errorf(subject, "unsupported http method: %s", endpoint.HttpMethod)
+		}
+		route := endpoint.HttpMethod + "#" + normalizeRoutePattern(endpoint.HttpPattern) + "@" + endpoint.Version
+		if pattern, ok := routes[route]; ok {
+			if pattern == endpoint.HttpPattern {
+				report.Errorf(subject, "duplicated endpoint")
+			} else {
+				report.Errorf(subject, "route conflicts with pattern: %s", pattern)
+			}
+		} else {
+			routes[route] = endpoint.HttpPattern
+		}
+		proto := endpoint.Service.AttrRpcProto()
+		if "" == proto {
+			report.Errorf(subject, "service rpc proto is empty")
+		} else if _, ok := extensions.LoadBackendTransport(proto); !ok {
+			report.Errorf(subject, "BackendTransport not registered, proto: %s", proto)
+		}
+		validateArguments(report, extensions, subject, endpoint.Service.Arguments)
+	}
+}
+
+func validateArguments(report *ValidationReport, extensions *ext.Registry, subject string, args []flux.Argument) {
+	for _, arg := range args {
+		if flux.ArgumentTypePrimitive == arg.Type {
+			if "" == arg.Class {
+				report.Errorf(subject, "argument class is empty: %s", arg.Name)
+			} else if nil == extensions.LoadMTValueResolver(arg.Class) {
+				report.Warnf(subject, "no value resolver for class %s, argument: %s; default resolver will be used", arg.Class, arg.Name)
+			}
+		}
+		validateArguments(report, extensions, subject, arg.Fields)
+	}
+}
+
+func validateUnusedConfigs(report *ValidationReport, extensions *ext.Registry, filterIds map[string]bool, knownRoots []string) {
+	transports := make(map[string]bool, 4)
+	for proto := range extensions.LoadBackendTransports() {
+		lowerKeys(transports, proto)
+	}
+	for proto := range viper.GetStringMap("BACKEND") {
+		if !transports[proto] {
+			report.Warnf("BACKEND."+proto, "config not used, BackendTransport not registered")
+		}
+	}
+	providers := make(map[string]bool, 4)
+	for id := range extensions.LoadCredentialProviders() {
+		lowerKeys(providers, id)
+	}
+	for id := range viper.GetStringMap("CREDENTIAL") {
+		if !providers[id] {
+			report.Warnf("CREDENTIAL."+id, "config not used, CredentialProvider not registered")
+		}
+	}
+	known := make(map[string]bool, 16)
+	for _, root := range append(knownConfigRoots, knownRoots...) {
+		lowerKeys(known, root)
+	}
+	keys := make([]string, 0)
+	for key := range viper.AllSettings() {
+		if !known[key] && !filterIds[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		report.Warnf(key, "config not used by any registered component")
+	}
+}
+
+// normalizeRoutePattern 将路径参数名统一替换，用于检查仅参数名不同的路由冲突
+func normalizeRoutePattern(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, ":") || (strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}")) {
+			segments[i] = ":"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func lowerKeys(keys map[string]bool, key string) {
+	keys[strings.ToLower(key)] = true
+}
+
+// RunValidate 执行配置校验命令：flux validate [-endpoints file]...；返回进程退出码
+func RunValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	files := fs.String("endpoints", "", "endpoint snapshot files (JSON array), separated by comma")
+	if err := fs.Parse(args); nil != err {
+		return 2
+	}
+	InitConfiguration(EnvKeyDeployEnv)
+	endpoints := make([]flux.Endpoint, 0)
+	for _, file := range strings.Split(*files, ",") {
+		if file = strings.TrimSpace(file); "" == file {
+			continue
+		}
+		data, err := ioutil.ReadFile(file)
+		if nil != err {
+			_, _ = fmt.Fprintf(os.Stderr, "read endpoint snapshot %s: %s\n", file, err)
+			return 2
+		}
+		loaded, err := registry.LoadEndpointSnapshot(data)
+		if nil != err {
+			_, _ = fmt.Fprintf(os.Stderr, "load endpoint snapshot %s: %s\n", file, err)
+			return 2
+		}
+		endpoints = append(endpoints, loaded...)
+	}
+	report := Validate(ext.Default(), endpoints)
+	report.Write(os.Stdout)
+	if report.HasErrors() {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+const (
+	VersioningConfigKeyMediaTypeVendor = "media-type-vendor"
+	VersioningConfigKeyDefaultVersion  = "default-version"
+	VersioningConfigKeyDeprecated      = "deprecated-versions"
+)
+
+const (
+	// Endpoint扩展属性：标记Endpoint版本已废弃
+	EndpointExtKeyDeprecated = "deprecated"
+	// Endpoint扩展属性：废弃版本的下线时间（HTTP-date），输出为 Sunset Header
+	EndpointExtKeySunset = "sunset"
+)
+
+const (
+	// 默认版本策略：未指定版本时选择最新版本
+	DefaultVersionLatest = "latest"
+)
+
+const (
+	HeaderDeprecation = "Deprecation"
+	HeaderSunset      = "Sunset"
+	HeaderWarning     = "Warning"
+)
+
+// versioningOptions 请求版本协商：依次从版本Header、Accept媒体类型（application/vnd.{vendor}.{version}+json）中读取版本，
+// 未指定版本时按默认版本策略选择；命中废弃版本时输出 Deprecation/Warning Header。
+type versioningOptions struct {
+	header         string
+	vendor         string
+	defaultVersion string
+	deprecated     map[string]struct{}
+}
+
+func newVersioningOptions(header string, config *flux.Configuration) *versioningOptions {
+	opts := &versioningOptions{
+		header:         header,
+		vendor:         config.GetString(VersioningConfigKeyMediaTypeVendor),
+		defaultVersion: config.GetString(VersioningConfigKeyDefaultVersion),
+		deprecated:     make(map[string]struct{}, 4),
+	}
+	for _, v := range config.GetStringSlice(VersioningConfigKeyDeprecated) {
+		opts.deprecated[v] = struct{}{}
+	}
+	if "" != opts.vendor || "" != opts.defaultVersion || len(opts.deprecated) > 0 {
+		logger.Infow("Versioning negotiation enabled", "media-type-vendor", opts.vendor,
+			"default-version", opts.defaultVersion, "deprecated-versions", config.GetStringSlice(VersioningConfigKeyDeprecated))
+	}
+	return opts
+}
+
+// requestVersion 返回请求指定的版本；未指定时返回空字符串
+func (v *versioningOptions) requestVersion(webc flux.WebContext) string {
+	if version := webc.HeaderValue(v.header); "" != version {
+		return version
+	}
+	if "" == v.vendor {
+		return ""
+	}
+	return versionOfAccept(webc.HeaderValue(flux.HeaderAccept), v.vendor)
+}
+
+// selectEndpoint 按请求版本选择Endpoint；未指定版本时使用默认版本策略
+func (v *versioningOptions) selectEndpoint(webc flux.WebContext, endpoints *MultiEndpoint) (*flux.Endpoint, bool) {
+	version := v.requestVersion(webc)
+	if "" == version {
+		switch v.defaultVersion {
+		case "":
+		case DefaultVersionLatest:
+			rv := endpoints.Latest()
+			return rv, nil != rv
+		default:
+			if rv, ok := endpoints.FindByVersion(v.defaultVersion); ok {
+				return rv, true
+			}
+		}
+	}
+	return endpoints.FindByVersion(version)
+}
+
+// markDeprecated 命中废弃版本时设置响应Header
+func (v *versioningOptions) markDeprecated(webc flux.WebContext, endpoint *flux.Endpoint) {
+	_, deprecated := v.deprecated[endpoint.Version]
+	if !deprecated && !endpoint.ExtBool(EndpointExtKeyDeprecated) {
+		return
+	}
+	webc.SetResponseHeader(HeaderDeprecation, "true")
+	webc.SetResponseHeader(HeaderWarning, fmt.Sprintf(`299 - "Deprecated API version %s"`, endpoint.Version))
+	if sunset := endpoint.ExtString(EndpointExtKeySunset); "" != sunset {
+		webc.SetResponseHeader(HeaderSunset, sunset)
+	}
+}
+
+// versionOfAccept 从Accept媒体类型中解析版本，例如：application/vnd.company.v2+json -> v2；
+// 多个媒体类型时返回第一个匹配厂商的版本
+func versionOfAccept(accept, vendor string) string {
+	prefix := "vnd." + vendor + "."
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if nil != err {
+			continue
+		}
+		subtype := mediaType[strings.IndexByte(mediaType, '/')+1:]
+		if !strings.HasPrefix(subtype, prefix) {
+			continue
+		}
+		version := subtype[len(prefix):]
+		if i := strings.IndexByte(version, '+'); i >= 0 {
+			version = version[:i]
+		}
+		if "" != version {
+			return version
+		}
+	}
+	return ""
+}
+
+// compareVersion 按数字段比较版本号，忽略前缀 v，缺少的段视为0，例如：v2 > 1.10 > 1.9，1.0 = 1；非数字段按字符串比较
+func compareVersion(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(strings.ToLower(a), "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(strings.ToLower(b), "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		x, y := "0", "0"
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		xi, xerr := strconv.Atoi(x)
+		yi, yerr := strconv.Atoi(y)
+		switch {
+		case nil == xerr && nil == yerr:
+			if xi != yi {
+				if xi < yi {
+					return -1
+				}
+				return 1
+			}
+		case x != y:
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
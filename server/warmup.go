@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"net/http"
+	"time"
+)
+
+const (
+	WarmupConfigKeyEnable      = "enable"
+	WarmupConfigKeyTimeout     = "timeout"
+	WarmupConfigKeyFailOnError = "fail-on-error"
+)
+
+const (
+	// 就绪探针路径，注册在DebugServer上
+	ReadinessProbePath = "/health/ready"
+)
+
+// warmupOptions 启动预热：服务启动后执行已注册的 flux.Warmer Hook（例如预加载后端引用、权限缓存等），
+// 预热完成后就绪探针才返回就绪状态，避免冷启动时的延迟尖峰。
+type warmupOptions struct {
+	enabled     bool
+	timeout     time.Duration
+	failOnError bool
+}
+
+func newWarmupOptions(config *flux.Configuration) *warmupOptions {
+	config.SetDefaults(map[string]interface{}{
+		WarmupConfigKeyEnable:      true,
+		WarmupConfigKeyTimeout:     time.Second * 30,
+		WarmupConfigKeyFailOnError: false,
+	})
+	return &warmupOptions{
+		enabled:     config.GetBool(WarmupConfigKeyEnable),
+		timeout:     config.GetDuration(WarmupConfigKeyTimeout),
+		failOnError: config.GetBool(WarmupConfigKeyFailOnError),
+	}
+}
+
+// warmup 执行预热Hook并在完成后设置就绪状态；fail-on-error 开启时，预热失败将保持未就绪状态
+func (s *HttpServeEngine) warmup() {
+	if nil == s.warmupOpts || !s.warmupOpts.enabled {
+		close(s.stateReady)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if s.warmupOpts.timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), s.warmupOpts.timeout)
+	}
+	defer cancel()
+	start := time.Now()
+	logger.Infow("HttpServeEngine warmup: starting", "timeout", s.warmupOpts.timeout)
+	if err := s.router.Warmup(ctx); nil != err {
+		if s.warmupOpts.failOnError {
+			logger.Errorw("HttpServeEngine warmup: failed, keep NOT-READY", "error", err)
+			return
+		}
+		logger.Warnw("HttpServeEngine warmup: failed, continue", "error", err)
+	}
+	logger.Infow("HttpServeEngine warmup: READY", "elapsed", time.Since(start))
+	close(s.stateReady)
+}
+
+// newReadinessHandler 就绪探针：预热完成后返回200；启动中、预热失败或已停止时返回503
+func (s *HttpServeEngine) newReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ready := isClosed(s.stateReady) && !isClosed(s.stateStopped)
+		w.Header().Set(flux.HeaderContentType, flux.MIMEApplicationJSONCharsetUTF8)
+		if !ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"status":"NOT-READY"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"status":"READY"}`))
+	})
+}
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
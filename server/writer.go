@@ -1,6 +1,7 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"github.com/bytepowered/flux"
 	"github.com/bytepowered/flux/logger"
@@ -36,12 +37,21 @@ func GetServerResponseContentType() string {
 
 func DefaultServerErrorsWriter(webc flux.WebContext, requestId string, header http.Header, serr *flux.ServeError) error {
 	SetupResponseDefaults(webc, requestId, header)
-	resp := map[string]string{
-		"status":  "error",
-		"message": serr.Message,
+	resp := map[string]interface{}{
+		"status":   "error",
+		"message":  serr.Message,
+		"category": serr.GetCategory(),
+	}
+	if serr.Retryable {
+		resp["retryable"] = true
 	}
 	if nil != serr.Internal {
 		resp["error"] = serr.Internal.Error()
+		// 聚合的错误：输出每个错误的字段名、错误码和消息
+		var multi *flux.MultiError
+		if errors.As(serr.Internal, &multi) {
+			resp["errors"] = multi.Items()
+		}
 	}
 	bytes, err := SerializeWith(serverWriterSerializer, resp)
 	if nil != err {
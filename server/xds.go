@@ -0,0 +1,141 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/backend"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/remoting/xds"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	// xDS控制面客户端配置的命名空间
+	XdsConfigRootName = "Xds"
+)
+
+const (
+	XdsConfigKeyEnable          = "enable"
+	XdsConfigKeyAddress         = "address"
+	XdsConfigKeyTLSEnable       = "tls-enable"
+	XdsConfigKeyNodeId          = "node-id"
+	XdsConfigKeyNodeCluster     = "node-cluster"
+	XdsConfigKeyUpstreamPrefix  = "upstream-prefix"
+	XdsConfigKeyClusterPrefixes = "cluster-prefixes"
+	XdsConfigKeyHashKey         = "hash-key"
+)
+
+// initXds 开启实验性的xDS客户端模式时，从Istio/Envoy控制面订阅CDS/EDS数据，并映射为命名的上游集群。例如：
+// [Xds]
+// enable = true
+// address = "istiod.istio-system.svc:15010"
+// cluster-prefixes = ["outbound|8080||"]
+// 上游集群名称为 upstream-prefix 加xDS集群名称，BackendService通过 upstream://<名称> 引用；
+// 负载均衡按地址权重轮询，RING_HASH/MAGLEV集群在配置了 hash-key 时使用一致性哈希。
+// tls-enable 开启时，连接控制面使用 UpstreamTLSConfigFunc 提供的TLS配置（例如SPIFFE身份）。
+func (s *HttpServeEngine) initXds() error {
+	config := s.config.Sub(XdsConfigRootName)
+	hostname, _ := os.Hostname()
+	config.SetDefaults(map[string]interface{}{
+		XdsConfigKeyEnable:      false,
+		XdsConfigKeyTLSEnable:   false,
+		XdsConfigKeyNodeId:      "flux~" + hostname,
+		XdsConfigKeyNodeCluster: "flux",
+	})
+	if !config.GetBool(XdsConfigKeyEnable) {
+		return nil
+	}
+	address := config.GetString(XdsConfigKeyAddress)
+	if "" == address {
+		return errors.New("xds control plane address is required, config: " + XdsConfigRootName + "." + XdsConfigKeyAddress)
+	}
+	options := xds.Options{
+		Address:     address,
+		NodeId:      config.GetString(XdsConfigKeyNodeId),
+		NodeCluster: config.GetString(XdsConfigKeyNodeCluster),
+	}
+	if config.GetBool(XdsConfigKeyTLSEnable) {
+		host, _, _ := net.SplitHostPort(address)
+		options.TLSConfig = &tls.Config{ServerName: host}
+		if f := s.extensions.LoadUpstreamTLSConfigFunc(); nil != f {
+			tc, err := f(address)
+			if nil != err {
+				return err
+			}
+			if nil != tc {
+				options.TLSConfig = tc.Clone()
+			}
+		}
+		options.TLSConfig.NextProtos = []string{"h2"}
+	}
+	syncer := &xdsUpstreamSyncer{
+		extensions: s.extensions,
+		prefix:     config.GetString(XdsConfigKeyUpstreamPrefix),
+		clusters:   config.GetStringSlice(XdsConfigKeyClusterPrefixes),
+		hashKey:    config.GetString(XdsConfigKeyHashKey),
+		managed:    make(map[string]bool, 16),
+	}
+	s.extensions.StoreHookFunc(xds.NewClient(options, syncer.sync))
+	logger.Infow("xDS client mode enabled", "address", address, "node-id", options.NodeId)
+	return nil
+}
+
+// xdsUpstreamSyncer 将xDS集群同步为上游集群；只管理由xDS创建的上游集群，不覆盖静态配置的同名上游集群
+type xdsUpstreamSyncer struct {
+	extensions *ext.Registry
+	prefix     string
+	clusters   []string
+	hashKey    string
+	mu         sync.Mutex
+	managed    map[string]bool
+}
+
+func (x *xdsUpstreamSyncer) sync(clusters []xds.Cluster) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	current := make(map[string]bool, len(clusters))
+	for _, cluster := range clusters {
+		if !x.accept(cluster.Name) {
+			continue
+		}
+		name := x.prefix + cluster.Name
+		if _, exists := x.extensions.LoadUpstream(name); exists && !x.managed[name] {
+			logger.Warnw("xDS cluster conflicts with static upstream, ignored", "upstream", name)
+			continue
+		}
+		upstream := flux.Upstream{Name: name, Balancer: backend.UpstreamBalancerWeighted}
+		if "" != x.hashKey && (xds.LbPolicyRingHash == cluster.LbPolicy || xds.LbPolicyMaglev == cluster.LbPolicy) {
+			upstream.Balancer, upstream.HashKey = backend.UpstreamBalancerConsistentHash, x.hashKey
+		}
+		for _, endpoint := range cluster.Endpoints {
+			upstream.Addresses = append(upstream.Addresses, flux.UpstreamAddress{Address: endpoint.Address, Weight: endpoint.Weight})
+		}
+		x.extensions.StoreUpstream(upstream)
+		current[name] = true
+	}
+	for name := range x.managed {
+		if !current[name] {
+			x.extensions.RemoveUpstream(name)
+			logger.Infow("xDS upstream removed", "upstream", name)
+		}
+	}
+	x.managed = current
+	logger.Infow("xDS upstreams synced", "upstreams", len(current))
+}
+
+func (x *xdsUpstreamSyncer) accept(cluster string) bool {
+	if 0 == len(x.clusters) {
+		return true
+	}
+	for _, prefix := range x.clusters {
+		if strings.HasPrefix(cluster, prefix) {
+			return true
+		}
+	}
+	return false
+}
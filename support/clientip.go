@@ -0,0 +1,103 @@
+package support
+
+import (
+	"fmt"
+	"github.com/bytepowered/flux"
+	"net"
+	"strings"
+)
+
+// ClientIPOf 返回Context的客户端IP；Context不支持 flux.ClientIPContext 时，使用 X-Client-Ip 属性
+func ClientIPOf(ctx flux.Context) string {
+	if cc, ok := ctx.(flux.ClientIPContext); ok {
+		return cc.ClientIP()
+	}
+	return ctx.GetAttributeString(flux.XClientIp, "")
+}
+
+// TrustedProxies 可信代理网段；用于从X-Forwarded-For中解析真实的客户端IP
+type TrustedProxies struct {
+	networks []*net.IPNet
+}
+
+// NewTrustedProxies 根据CIDR或IP列表创建可信代理网段；列表为空时不信任任何代理，客户端IP即为连接的远端地址
+func NewTrustedProxies(cidrs []string) (*TrustedProxies, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if cidr = strings.TrimSpace(cidr); "" == cidr {
+			continue
+		}
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); nil == ip {
+				return nil, fmt.Errorf("trusted-proxies: invalid ip: %s", cidr)
+			} else if nil != ip.To4() {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if nil != err {
+			return nil, fmt.Errorf("trusted-proxies: invalid cidr: %s, error: %w", cidr, err)
+		}
+		networks = append(networks, network)
+	}
+	return &TrustedProxies{networks: networks}, nil
+}
+
+// IsTrusted 判断IP是否属于可信代理
+func (t *TrustedProxies) IsTrusted(ip net.IP) bool {
+	for _, network := range t.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP 解析WebContext请求的客户端IP
+func (t *TrustedProxies) ClientIP(webc flux.WebContext) string {
+	remoteAddr := ""
+	if req, err := webc.HttpRequest(); nil == err {
+		remoteAddr = req.RemoteAddr
+	}
+	header, _ := webc.HeaderValues()
+	forwarded := header.Values(flux.HeaderXForwardedFor)
+	if len(forwarded) == 0 {
+		if realIp := webc.HeaderValue(flux.HeaderXRealIP); "" != realIp {
+			forwarded = []string{realIp}
+		}
+	}
+	return t.Resolve(remoteAddr, forwarded)
+}
+
+// Resolve 从连接的远端地址开始，自右向左遍历X-Forwarded-For，跳过可信代理，返回第一个不可信的地址；
+// 远端地址不可信时，忽略X-Forwarded-For直接返回远端地址；遇到无法解析的地址时，返回最后一个可信代理的地址。
+func (t *TrustedProxies) Resolve(remoteAddr string, forwardedFor []string) string {
+	ip := parseHostIP(remoteAddr)
+	if nil == ip {
+		return ""
+	}
+	hops := make([]string, 0, len(forwardedFor))
+	for _, value := range forwardedFor {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0 && t.IsTrusted(ip); i-- {
+		hop := parseHostIP(strings.TrimSpace(hops[i]))
+		if nil == hop {
+			break
+		}
+		ip = hop
+	}
+	return ip.String()
+}
+
+func parseHostIP(addr string) net.IP {
+	if ip := net.ParseIP(addr); nil != ip {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(addr); nil == err {
+		return net.ParseIP(host)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package support
+
+import (
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTrustedProxies_Resolve(t *testing.T) {
+	assert := assert2.New(t)
+	proxies, err := NewTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1", "::1"})
+	assert.NoError(err)
+	cases := []struct {
+		remote    string
+		forwarded []string
+		expected  string
+	}{
+		// 远端不可信，忽略XFF
+		{remote: "1.2.3.4:5678", forwarded: []string{"9.9.9.9"}, expected: "1.2.3.4"},
+		// 经由可信代理
+		{remote: "10.0.0.1:80", forwarded: []string{"9.9.9.9"}, expected: "9.9.9.9"},
+		// 客户端伪造的XFF前缀被忽略
+		{remote: "10.0.0.1:80", forwarded: []string{"6.6.6.6, 9.9.9.9, 192.168.1.1"}, expected: "9.9.9.9"},
+		{remote: "10.0.0.1:80", forwarded: []string{"6.6.6.6", "9.9.9.9, 10.1.1.1"}, expected: "9.9.9.9"},
+		// 全部为可信代理
+		{remote: "10.0.0.1:80", forwarded: []string{"10.0.0.2, 10.0.0.3"}, expected: "10.0.0.2"},
+		// 无效地址，返回最后一个可信代理
+		{remote: "10.0.0.1:80", forwarded: []string{"9.9.9.9, unknown"}, expected: "10.0.0.1"},
+		{remote: "[::1]:80", forwarded: []string{"2001:db8::1"}, expected: "2001:db8::1"},
+		{remote: "10.0.0.1:80", forwarded: nil, expected: "10.0.0.1"},
+		{remote: "", forwarded: []string{"9.9.9.9"}, expected: ""},
+	}
+	for _, c := range cases {
+		assert.Equal(c.expected, proxies.Resolve(c.remote, c.forwarded), c.remote)
+	}
+	untrusted, err := NewTrustedProxies(nil)
+	assert.NoError(err)
+	assert.Equal("10.0.0.1", untrusted.Resolve("10.0.0.1:80", []string{"9.9.9.9"}))
+	_, err = NewTrustedProxies([]string{"10.0.0.0/33"})
+	assert.Error(err)
+	_, err = NewTrustedProxies([]string{"bad-ip"})
+	assert.Error(err)
+}
+
+type fluxContext = flux.Context
+
+type attributesOnlyContext struct {
+	fluxContext
+}
+
+func TestClientIPOf(t *testing.T) {
+	assert := assert2.New(t)
+	assert.Equal("10.0.0.1", ClientIPOf(NewValuesContext(map[string]interface{}{"client-ip": "10.0.0.1"})))
+	// 不支持ClientIPContext时使用X-Client-Ip属性
+	ctx := NewValuesContext(map[string]interface{}{})
+	ctx.SetAttribute(flux.XClientIp, "10.0.0.2")
+	assert.Equal("10.0.0.2", ClientIPOf(attributesOnlyContext{fluxContext: ctx}))
+}
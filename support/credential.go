@@ -0,0 +1,176 @@
+package support
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"golang.org/x/crypto/bcrypt"
+	"io"
+	"os"
+	"strings"
+)
+
+var _ flux.CredentialProvider = new(HashedCredentialProvider)
+
+// HashedCredentialProvider 基于用户名和密码哈希的凭证验证实现；
+// 密码哈希格式与Apache htpasswd兼容：bcrypt($2y$/$2a$/$2b$)、{SHA}、$apr1$；不支持明文密码。
+type HashedCredentialProvider struct {
+	users map[string]string
+}
+
+// NewStaticCredentialProvider 使用 用户名->密码哈希 的静态配置创建凭证验证
+func NewStaticCredentialProvider(users map[string]string) (*HashedCredentialProvider, error) {
+	out := make(map[string]string, len(users))
+	for username, hash := range users {
+		if !IsSupportedPasswordHash(hash) {
+			return nil, fmt.Errorf("unsupported password hash, username: %s", username)
+		}
+		out[username] = hash
+	}
+	return &HashedCredentialProvider{users: out}, nil
+}
+
+// NewHtpasswdCredentialProvider 加载htpasswd文件创建凭证验证
+func NewHtpasswdCredentialProvider(path string) (*HashedCredentialProvider, error) {
+	file, err := os.Open(path)
+	if nil != err {
+		return nil, fmt.Errorf("open htpasswd file: %w", err)
+	}
+	defer file.Close()
+	users, err := ParseHtpasswd(file)
+	if nil != err {
+		return nil, fmt.Errorf("parse htpasswd file: %s, error: %w", path, err)
+	}
+	return NewStaticCredentialProvider(users)
+}
+
+// ParseHtpasswd 解析htpasswd格式的 username:hash 列表，忽略空行和#注释行
+func ParseHtpasswd(reader io.Reader) (map[string]string, error) {
+	users := make(map[string]string)
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if "" == text || strings.HasPrefix(text, "#") {
+			continue
+		}
+		idx := strings.IndexByte(text, ':')
+		if idx <= 0 || idx == len(text)-1 {
+			return nil, fmt.Errorf("invalid htpasswd line: %d", line)
+		}
+		users[text[:idx]] = text[idx+1:]
+	}
+	return users, scanner.Err()
+}
+
+func (p *HashedCredentialProvider) Authenticate(_ context.Context, username, password string) (bool, error) {
+	hash, ok := p.users[username]
+	if !ok || "" == password {
+		return false, nil
+	}
+	return VerifyPasswordHash(hash, password), nil
+}
+
+// IsSupportedPasswordHash 判断是否为支持的密码哈希格式
+func IsSupportedPasswordHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2y$") || strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") ||
+		strings.HasPrefix(hash, "{SHA}") || strings.HasPrefix(hash, "$apr1$")
+}
+
+// VerifyPasswordHash 验证密码与htpasswd格式的密码哈希是否匹配
+func VerifyPasswordHash(hash, password string) bool {
+	switch {
+	case strings.HasPrefix(hash, "$2y$"):
+		// Go的bcrypt实现不识别$2y$前缀，其算法与$2a$/$2b$一致
+		return nil == bcrypt.CompareHashAndPassword([]byte("$2a$"+hash[4:]), []byte(password))
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"):
+		return nil == bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	case strings.HasPrefix(hash, "{SHA}"):
+		sum := sha1.Sum([]byte(password))
+		expected := "{SHA}" + base64.StdEncoding.EncodeToString(sum[:])
+		return 1 == subtle.ConstantTimeCompare([]byte(hash), []byte(expected))
+	case strings.HasPrefix(hash, "$apr1$"):
+		parts := strings.SplitN(hash[6:], "$", 2)
+		if len(parts) != 2 {
+			return false
+		}
+		expected := apr1Crypt(password, parts[0])
+		return 1 == subtle.ConstantTimeCompare([]byte(hash), []byte(expected))
+	default:
+		return false
+	}
+}
+
+const apr1Alphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// apr1Crypt Apache的MD5-crypt变体
+func apr1Crypt(password, salt string) string {
+	if len(salt) > 8 {
+		salt = salt[:8]
+	}
+	pw, sb := []byte(password), []byte(salt)
+	alt := md5.New()
+	alt.Write(pw)
+	alt.Write(sb)
+	alt.Write(pw)
+	altSum := alt.Sum(nil)
+	ctx := md5.New()
+	ctx.Write(pw)
+	ctx.Write([]byte("$apr1$"))
+	ctx.Write(sb)
+	for i := len(pw); i > 0; i -= 16 {
+		if i > 16 {
+			ctx.Write(altSum)
+		} else {
+			ctx.Write(altSum[:i])
+		}
+	}
+	for i := len(pw); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			ctx.Write([]byte{0})
+		} else {
+			ctx.Write(pw[:1])
+		}
+	}
+	sum := ctx.Sum(nil)
+	for i := 0; i < 1000; i++ {
+		round := md5.New()
+		if i&1 != 0 {
+			round.Write(pw)
+		} else {
+			round.Write(sum)
+		}
+		if i%3 != 0 {
+			round.Write(sb)
+		}
+		if i%7 != 0 {
+			round.Write(pw)
+		}
+		if i&1 != 0 {
+			round.Write(sum)
+		} else {
+			round.Write(pw)
+		}
+		sum = round.Sum(nil)
+	}
+	var out strings.Builder
+	out.WriteString("$apr1$" + salt + "$")
+	encode := func(a, b, c byte, n int) {
+		v := uint(a)<<16 | uint(b)<<8 | uint(c)
+		for ; n > 0; n-- {
+			out.WriteByte(apr1Alphabet[v&0x3f])
+			v >>= 6
+		}
+	}
+	encode(sum[0], sum[6], sum[12], 4)
+	encode(sum[1], sum[7], sum[13], 4)
+	encode(sum[2], sum[8], sum[14], 4)
+	encode(sum[3], sum[9], sum[15], 4)
+	encode(sum[4], sum[10], sum[5], 4)
+	encode(0, 0, sum[11], 2)
+	return out.String()
+}
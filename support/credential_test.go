@@ -0,0 +1,47 @@
+package support
+
+import (
+	"context"
+	assert2 "github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"testing"
+)
+
+func TestVerifyPasswordHash(t *testing.T) {
+	bcryptHash, _ := bcrypt.GenerateFromPassword([]byte("myPassword"), bcrypt.MinCost)
+	cases := []struct {
+		hash     string
+		password string
+		expected bool
+	}{
+		{hash: "$apr1$r31.....$HqJZimcKQFAMYayBlzkrA/", password: "myPassword", expected: true},
+		{hash: "$apr1$r31.....$HqJZimcKQFAMYayBlzkrA/", password: "myPasswor", expected: false},
+		{hash: "{SHA}VBPuJHI7uixaa6LQGWx4s+5GKNE=", password: "myPassword", expected: true},
+		{hash: "{SHA}VBPuJHI7uixaa6LQGWx4s+5GKNE=", password: "other", expected: false},
+		{hash: string(bcryptHash), password: "myPassword", expected: true},
+		{hash: "$2y$" + string(bcryptHash[4:]), password: "myPassword", expected: true},
+		{hash: string(bcryptHash), password: "other", expected: false},
+		{hash: "myPassword", password: "myPassword", expected: false},
+	}
+	for _, c := range cases {
+		assert2.Equal(t, c.expected, VerifyPasswordHash(c.hash, c.password), c.hash)
+	}
+}
+
+func TestHashedCredentialProvider(t *testing.T) {
+	assert := assert2.New(t)
+	users, err := ParseHtpasswd(strings.NewReader("# users\n\nalice:$apr1$r31.....$HqJZimcKQFAMYayBlzkrA/\n"))
+	assert.NoError(err)
+	provider, err := NewStaticCredentialProvider(users)
+	assert.NoError(err)
+	ok, err := provider.Authenticate(context.TODO(), "alice", "myPassword")
+	assert.NoError(err)
+	assert.True(ok)
+	ok, _ = provider.Authenticate(context.TODO(), "bob", "myPassword")
+	assert.False(ok)
+	_, err = ParseHtpasswd(strings.NewReader("alice"))
+	assert.Error(err)
+	_, err = NewStaticCredentialProvider(map[string]string{"alice": "plain"})
+	assert.Error(err)
+}
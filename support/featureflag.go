@@ -0,0 +1,73 @@
+package support
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
+	"github.com/bytepowered/flux/pkg"
+	"github.com/spf13/viper"
+	"hash/fnv"
+)
+
+const (
+	// 默认特性开关配置的命名空间
+	DefaultFeatureFlagConfigNamespace = "FEATURE_FLAGS"
+	// 默认读取租户ID的Attribute
+	DefaultFeatureFlagTenantAttribute = "X-Tenant-Id"
+)
+
+var _ flux.FeatureFlagProvider = new(ConfigFeatureFlagProvider)
+
+// ConfigFeatureFlagProvider 基于配置文件的特性开关实现；按以下顺序判断：
+// 1. Subject（X-Jwt-Subject）在subjects列表，或租户在tenants列表中时开启；
+// 2. 按Subject的哈希值灰度：percentage为0-100；
+// 3. 使用enabled的默认值。例如：
+// [FEATURE_FLAGS.new-order-backend]
+// enabled = false
+// subjects = ["alice"]
+// tenants = ["t-001"]
+// percentage = 10
+type ConfigFeatureFlagProvider struct {
+	namespace       string
+	tenantAttribute string
+}
+
+func NewConfigFeatureFlagProvider(namespace string, tenantAttribute string) *ConfigFeatureFlagProvider {
+	return &ConfigFeatureFlagProvider{namespace: namespace, tenantAttribute: tenantAttribute}
+}
+
+func (p *ConfigFeatureFlagProvider) IsEnabled(ctx flux.Context, flag string) (bool, error) {
+	// 注意：延迟到使用时读取，确保配置文件已加载及支持动态更新
+	prefix := p.namespace + "." + flag + "."
+	subject := ctx.GetAttributeString(flux.XJwtSubject, "")
+	if "" != subject && pkg.StringSliceContains(viper.GetStringSlice(prefix+"subjects"), subject) {
+		return true, nil
+	}
+	if tenant := ctx.GetAttributeString(p.tenantAttribute, ""); "" != tenant &&
+		pkg.StringSliceContains(viper.GetStringSlice(prefix+"tenants"), tenant) {
+		return true, nil
+	}
+	if percentage := viper.GetInt(prefix + "percentage"); percentage > 0 && "" != subject {
+		hash := fnv.New32a()
+		_, _ = hash.Write([]byte(flag + ":" + subject))
+		if int(hash.Sum32()%100) < percentage {
+			return true, nil
+		}
+	}
+	return viper.GetBool(prefix + "enabled"), nil
+}
+
+// IsFeatureEnabled 使用特性开关接口判断特性是否开启；未设置特性开关接口或发生错误时，返回false。
+// 按认证主体灰度的特性，需在认证Filter之后调用，例如在Filter中切换特定行为
+func IsFeatureEnabled(ctx flux.Context, flag string) bool {
+	provider := ext.RegistryOf(ctx.Context()).LoadFeatureFlagProvider()
+	if nil == provider {
+		return false
+	}
+	enabled, err := provider.IsEnabled(ctx, flag)
+	if nil != err {
+		logger.TraceContext(ctx).Warnw("Feature flag evaluate failed", "flag", flag, "error", err)
+		return false
+	}
+	return enabled
+}
@@ -0,0 +1,40 @@
+package support
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/spf13/viper"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestConfigFeatureFlagProvider(t *testing.T) {
+	assert := assert2.New(t)
+	viper.Set("FLAGS_TEST.new-backend.enabled", false)
+	viper.Set("FLAGS_TEST.new-backend.subjects", []string{"alice"})
+	viper.Set("FLAGS_TEST.new-backend.tenants", []string{"t-001"})
+	viper.Set("FLAGS_TEST.all.enabled", true)
+	viper.Set("FLAGS_TEST.rollout.percentage", 100)
+	provider := NewConfigFeatureFlagProvider("FLAGS_TEST", DefaultFeatureFlagTenantAttribute)
+	cases := []struct {
+		flag     string
+		values   map[string]interface{}
+		expected bool
+	}{
+		{flag: "new-backend", values: map[string]interface{}{flux.XJwtSubject: "alice"}, expected: true},
+		{flag: "new-backend", values: map[string]interface{}{flux.XJwtSubject: "bob"}, expected: false},
+		{flag: "new-backend", values: map[string]interface{}{DefaultFeatureFlagTenantAttribute: "t-001"}, expected: true},
+		{flag: "all", values: map[string]interface{}{}, expected: true},
+		{flag: "rollout", values: map[string]interface{}{flux.XJwtSubject: "bob"}, expected: true},
+		{flag: "rollout", values: map[string]interface{}{}, expected: false},
+		{flag: "not-exists", values: map[string]interface{}{flux.XJwtSubject: "alice"}, expected: false},
+	}
+	for _, c := range cases {
+		ctx := NewValuesContext(c.values)
+		enabled, err := provider.IsEnabled(ctx, c.flag)
+		assert.NoError(err)
+		assert.Equal(c.expected, enabled, c.flag)
+	}
+	ext.StoreFeatureFlagProvider(provider)
+	assert.True(IsFeatureEnabled(NewValuesContext(map[string]interface{}{flux.XJwtSubject: "alice"}), "new-backend"))
+}
@@ -0,0 +1,29 @@
+//go:build gofuzz
+// +build gofuzz
+
+package support
+
+import (
+	"encoding/json"
+	"github.com/bytepowered/flux"
+)
+
+// go-fuzz 入口：go-fuzz-build -func FuzzQueryToJSON github.com/bytepowered/flux/support
+
+func FuzzQueryToJSON(data []byte) int {
+	out, err := JSONBytesFromQueryString(data)
+	if nil != err {
+		return 0
+	}
+	if !json.Valid(out) {
+		panic("invalid json: " + string(out))
+	}
+	return 1
+}
+
+func FuzzDecodeStringMap(data []byte) int {
+	if _, err := CastDecodeMTValueToStringMap(flux.MTValue{Value: data, MediaType: "application/x-www-form-urlencoded"}); nil != err {
+		return 0
+	}
+	return 1
+}
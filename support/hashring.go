@@ -0,0 +1,68 @@
+package support
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+const (
+	// 每个权重单位的虚拟节点数量
+	DefaultHashRingReplicas = 160
+)
+
+// HashRing 一致性哈希环：节点按权重映射为多个虚拟节点；增加或删除节点时，只有相邻区间的Key改变归属节点
+type HashRing struct {
+	hashes []uint32
+	nodes  map[uint32]string
+}
+
+// NewHashRing 创建一致性哈希环；weights 为节点及其权重，权重小于1的节点按1计算
+func NewHashRing(weights map[string]int, replicas int) *HashRing {
+	if replicas <= 0 {
+		replicas = DefaultHashRingReplicas
+	}
+	ring := &HashRing{
+		hashes: make([]uint32, 0, len(weights)*replicas),
+		nodes:  make(map[uint32]string, len(weights)*replicas),
+	}
+	// 按节点名称顺序构建，哈希冲突时结果稳定
+	names := make([]string, 0, len(weights))
+	for name := range weights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		weight := weights[name]
+		if weight < 1 {
+			weight = 1
+		}
+		for i := 0; i < weight*replicas; i++ {
+			hash := crc32.ChecksumIEEE([]byte(name + "#" + strconv.Itoa(i)))
+			if _, ok := ring.nodes[hash]; ok {
+				continue
+			}
+			ring.nodes[hash] = name
+			ring.hashes = append(ring.hashes, hash)
+		}
+	}
+	sort.Slice(ring.hashes, func(i, j int) bool {
+		return ring.hashes[i] < ring.hashes[j]
+	})
+	return ring
+}
+
+// Get 返回Key归属的节点；哈希环为空时返回false
+func (r *HashRing) Get(key string) (string, bool) {
+	if 0 == len(r.hashes) {
+		return "", false
+	}
+	hash := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.hashes), func(i int) bool {
+		return r.hashes[i] >= hash
+	})
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.nodes[r.hashes[i]], true
+}
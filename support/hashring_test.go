@@ -0,0 +1,45 @@
+package support
+
+import (
+	assert2 "github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func TestHashRing_Distribution(t *testing.T) {
+	assert := assert2.New(t)
+	ring := NewHashRing(map[string]int{"a": 1, "b": 1, "c": 2}, 0)
+	counts := make(map[string]int, 3)
+	for i := 0; i < 20000; i++ {
+		node, ok := ring.Get("user-" + strconv.Itoa(i))
+		assert.True(ok)
+		counts[node]++
+	}
+	// 按权重分布：a/b 约25%，c 约50%
+	assert.InDelta(5000, counts["a"], 1000)
+	assert.InDelta(5000, counts["b"], 1000)
+	assert.InDelta(10000, counts["c"], 1500)
+}
+
+func TestHashRing_Resharding(t *testing.T) {
+	assert := assert2.New(t)
+	before := NewHashRing(map[string]int{"a": 1, "b": 1, "c": 1}, 0)
+	after := NewHashRing(map[string]int{"a": 1, "b": 1, "c": 1, "d": 1}, 0)
+	moved := 0
+	for i := 0; i < 10000; i++ {
+		key := "order-" + strconv.Itoa(i)
+		from, _ := before.Get(key)
+		to, _ := after.Get(key)
+		if from != to {
+			// 只会迁移到新增的节点
+			assert.Equal("d", to)
+			moved++
+		}
+	}
+	assert.InDelta(2500, moved, 700)
+}
+
+func TestHashRing_Empty(t *testing.T) {
+	_, ok := NewHashRing(nil, 0).Get("key")
+	assert2.False(t, ok)
+}
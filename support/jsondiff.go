@@ -0,0 +1,90 @@
+package support
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// JSONDiff JSON结构的差异项；Path为JSONPath格式，Expected/Actual不存在时为nil
+type JSONDiff struct {
+	Path     string      `json:"path"`
+	Expected interface{} `json:"expected"`
+	Actual   interface{} `json:"actual"`
+}
+
+// DiffJSONBytes 比较两个JSON数据的结构差异；任一数据不是JSON时，按原始字符串比较
+func DiffJSONBytes(expected, actual []byte) []JSONDiff {
+	ev, eerr := decodeJSON(expected)
+	av, aerr := decodeJSON(actual)
+	if nil != eerr || nil != aerr {
+		if bytes.Equal(expected, actual) {
+			return nil
+		}
+		return []JSONDiff{{Path: "$", Expected: string(expected), Actual: string(actual)}}
+	}
+	return DiffJSON(ev, av)
+}
+
+// DiffJSON 比较两个JSON解码后的对象（map[string]interface{}, []interface{}及基础类型）的结构差异
+func DiffJSON(expected, actual interface{}) []JSONDiff {
+	return diffJSONValue("$", expected, actual, nil)
+}
+
+func diffJSONValue(path string, expected, actual interface{}, out []JSONDiff) []JSONDiff {
+	switch ev := expected.(type) {
+	case map[string]interface{}:
+		av, ok := actual.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(ev)+len(av))
+		for k := range ev {
+			keys = append(keys, k)
+		}
+		for k := range av {
+			if _, ok := ev[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			out = diffJSONValue(path+"."+k, ev[k], av[k], out)
+		}
+		return out
+	case []interface{}:
+		av, ok := actual.([]interface{})
+		if !ok {
+			break
+		}
+		size := len(ev)
+		if len(av) > size {
+			size = len(av)
+		}
+		for i := 0; i < size; i++ {
+			var e, a interface{}
+			if i < len(ev) {
+				e = ev[i]
+			}
+			if i < len(av) {
+				a = av[i]
+			}
+			out = diffJSONValue(path+"["+strconv.Itoa(i)+"]", e, a, out)
+		}
+		return out
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		out = append(out, JSONDiff{Path: path, Expected: expected, Actual: actual})
+	}
+	return out
+}
+
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v interface{}
+	err := decoder.Decode(&v)
+	return v, err
+}
@@ -0,0 +1,38 @@
+package support
+
+import (
+	"encoding/json"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDiffJSONBytes(t *testing.T) {
+	assert := assert2.New(t)
+	cases := []struct {
+		expected string
+		actual   string
+		diffs    []JSONDiff
+	}{
+		{expected: `{"a":1,"b":[1,2]}`, actual: `{"b":[1,2],"a":1}`, diffs: nil},
+		{expected: `{"a":1}`, actual: `{"a":2}`, diffs: []JSONDiff{
+			{Path: "$.a", Expected: json.Number("1"), Actual: json.Number("2")},
+		}},
+		{expected: `{"a":{"x":"1"},"b":true}`, actual: `{"a":{"x":"1","y":null},"c":false}`, diffs: []JSONDiff{
+			{Path: "$.b", Expected: true, Actual: nil},
+			{Path: "$.c", Expected: nil, Actual: false},
+		}},
+		{expected: `[1,2]`, actual: `[1]`, diffs: []JSONDiff{
+			{Path: "$[1]", Expected: json.Number("2"), Actual: nil},
+		}},
+		{expected: `{"a":[1]}`, actual: `{"a":"1"}`, diffs: []JSONDiff{
+			{Path: "$.a", Expected: []interface{}{json.Number("1")}, Actual: "1"},
+		}},
+		{expected: `ok`, actual: `ok`, diffs: nil},
+		{expected: `ok`, actual: `{}`, diffs: []JSONDiff{
+			{Path: "$", Expected: "ok", Actual: "{}"},
+		}},
+	}
+	for _, c := range cases {
+		assert.Equal(c.diffs, DiffJSONBytes([]byte(c.expected), []byte(c.actual)), c.expected)
+	}
+}
@@ -81,11 +81,12 @@ func DefaultArgumentValueLookupFunc(scope, key string, ctx flux.Context) (value
 
 // 默认实现：查找Argument的值解析函数
 func DefaultArgumentValueResolveFunc(mtValue flux.MTValue, arg flux.Argument, ctx flux.Context) (interface{}, error) {
-	valueResolver := ext.LoadMTValueResolver(arg.Class)
+	registry := ext.RegistryOf(ctx.Context())
+	valueResolver := registry.LoadMTValueResolver(arg.Class)
 	if nil == valueResolver {
 		logger.TraceContext(ctx).Warnw("Not supported argument type",
 			"http.key", arg.HttpName, "arg.name", arg.Name, "resolver-class", arg.Class, "generic", arg.Generic)
-		valueResolver = ext.LoadMTValueDefaultResolver()
+		valueResolver = registry.LoadMTValueDefaultResolver()
 	}
 	if value, err := valueResolver(mtValue, arg.Class, arg.Generic); nil != err {
 		logger.TraceContext(ctx).Warnw("Failed to resolve argument",
@@ -0,0 +1,497 @@
+package support
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/spf13/cast"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PredicateLookupFunc 查找表达式中 scope:key 引用的值
+type PredicateLookupFunc func(scope, key string) (interface{}, error)
+
+// Predicate 条件表达式：操作数为字面量（字符串、数字、true/false/null、列表）或 scope:key 形式的请求值引用，
+// 例如 header:X-Region、query:type；body:a.b.c 按路径读取JSON请求体的字段。支持的运算：
+// ==, !=, >, >=, <, <=, in, contains, matches（正则），以及 &&, ||, ! 和括号。
+// 例如：body:order.type == "international" && header:X-Region in ["eu", "us"]
+type Predicate struct {
+	expr string
+	root predicateNode
+}
+
+// CompilePredicate 解析条件表达式
+func CompilePredicate(expr string) (*Predicate, error) {
+	tokens, err := tokenizePredicate(expr)
+	if nil != err {
+		return nil, err
+	}
+	p := &predicateParser{expr: expr, tokens: tokens}
+	root, err := p.parseOr()
+	if nil != err {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("predicate: unexpected token %q, expr: %s", p.tokens[p.pos].text, expr)
+	}
+	return &Predicate{expr: expr, root: root}, nil
+}
+
+func (p *Predicate) String() string {
+	return p.expr
+}
+
+// Eval 使用请求Context计算表达式；请求体只读取和解析一次
+func (p *Predicate) Eval(ctx flux.Context) (bool, error) {
+	return p.EvalWith(NewContextPredicateLookup(ctx))
+}
+
+// EvalWith 使用指定的查找函数计算表达式
+func (p *Predicate) EvalWith(lookup PredicateLookupFunc) (bool, error) {
+	v, err := p.root.eval(lookup)
+	if nil != err {
+		return false, err
+	}
+	return predicateTruthy(v), nil
+}
+
+// NewContextPredicateLookup 返回基于请求Context的查找函数：BODY域按路径读取JSON请求体，其它域按 DefaultArgumentValueLookupFunc 查找
+func NewContextPredicateLookup(ctx flux.Context) PredicateLookupFunc {
+	var body interface{}
+	var bodyErr error
+	parsed := false
+	return func(scope, key string) (interface{}, error) {
+		if flux.ScopeBody != scope {
+			mtv, err := DefaultArgumentValueLookupFunc(scope, key, ctx)
+			if nil != err {
+				return nil, err
+			}
+			return mtv.Value, nil
+		}
+		if !parsed {
+			parsed = true
+			body, bodyErr = readJSONBody(ctx)
+		}
+		if nil != bodyErr {
+			return nil, bodyErr
+		}
+		return JSONPathValue(body, key), nil
+	}
+}
+
+func readJSONBody(ctx flux.Context) (interface{}, error) {
+	reader, err := ctx.Request().RequestBodyReader()
+	if nil != err {
+		return nil, err
+	}
+	defer reader.Close()
+	var body interface{}
+	if err := json.NewDecoder(reader).Decode(&body); nil != err {
+		return nil, fmt.Errorf("predicate: decode json body: %w", err)
+	}
+	return body, nil
+}
+
+// JSONPathValue 按点号分隔的路径读取JSON值，数组元素使用数字下标；路径不存在时返回nil
+func JSONPathValue(value interface{}, path string) interface{} {
+	if "" == path || "$" == path {
+		return value
+	}
+	for _, name := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[name]
+		case []interface{}:
+			i, err := strconv.Atoi(name)
+			if nil != err || i < 0 || i >= len(v) {
+				return nil
+			}
+			value = v[i]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+////
+
+type predicateNode interface {
+	eval(lookup PredicateLookupFunc) (interface{}, error)
+}
+
+type (
+	predicateLiteral struct {
+		value interface{}
+	}
+	predicateRef struct {
+		scope, key string
+	}
+	predicateList struct {
+		items []predicateNode
+	}
+	predicateNot struct {
+		node predicateNode
+	}
+	predicateLogic struct {
+		and         bool
+		left, right predicateNode
+	}
+	predicateCompare struct {
+		op          string
+		left, right predicateNode
+		pattern     *regexp.Regexp
+	}
+)
+
+func (n predicateLiteral) eval(PredicateLookupFunc) (interface{}, error) {
+	return n.value, nil
+}
+
+func (n predicateRef) eval(lookup PredicateLookupFunc) (interface{}, error) {
+	return lookup(n.scope, n.key)
+}
+
+func (n predicateList) eval(lookup PredicateLookupFunc) (interface{}, error) {
+	out := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		v, err := item.eval(lookup)
+		if nil != err {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func (n predicateNot) eval(lookup PredicateLookupFunc) (interface{}, error) {
+	v, err := n.node.eval(lookup)
+	if nil != err {
+		return nil, err
+	}
+	return !predicateTruthy(v), nil
+}
+
+// eval 短路计算
+func (n predicateLogic) eval(lookup PredicateLookupFunc) (interface{}, error) {
+	left, err := n.left.eval(lookup)
+	if nil != err {
+		return nil, err
+	}
+	if predicateTruthy(left) != n.and {
+		return !n.and, nil
+	}
+	right, err := n.right.eval(lookup)
+	if nil != err {
+		return nil, err
+	}
+	return predicateTruthy(right), nil
+}
+
+func (n *predicateCompare) eval(lookup PredicateLookupFunc) (interface{}, error) {
+	left, err := n.left.eval(lookup)
+	if nil != err {
+		return nil, err
+	}
+	right, err := n.right.eval(lookup)
+	if nil != err {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return predicateEquals(left, right), nil
+	case "!=":
+		return !predicateEquals(left, right), nil
+	case "in":
+		for _, item := range cast.ToSlice(right) {
+			if predicateEquals(left, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "contains":
+		if items, ok := left.([]interface{}); ok {
+			for _, item := range items {
+				if predicateEquals(item, right) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		return strings.Contains(cast.ToString(left), cast.ToString(right)), nil
+	case "matches":
+		pattern := n.pattern
+		if nil == pattern {
+			if pattern, err = regexp.Compile(cast.ToString(right)); nil != err {
+				return nil, err
+			}
+		}
+		return pattern.MatchString(cast.ToString(left)), nil
+	default:
+		lf, lerr := cast.ToFloat64E(left)
+		rf, rerr := cast.ToFloat64E(right)
+		if nil != lerr || nil != rerr {
+			return nil, fmt.Errorf("predicate: operator %s requires numbers: %v, %v", n.op, left, right)
+		}
+		switch n.op {
+		case ">":
+			return lf > rf, nil
+		case ">=":
+			return lf >= rf, nil
+		case "<":
+			return lf < rf, nil
+		default:
+			return lf <= rf, nil
+		}
+	}
+}
+
+// predicateEquals 两侧均可转换为数字时按数值比较，否则按字符串比较；nil只与nil相等
+func predicateEquals(a, b interface{}) bool {
+	if nil == a || nil == b {
+		return nil == a && nil == b
+	}
+	if _, ok := a.(bool); ok {
+		return cast.ToString(a) == cast.ToString(b)
+	}
+	af, aerr := cast.ToFloat64E(a)
+	bf, berr := cast.ToFloat64E(b)
+	if nil == aerr && nil == berr {
+		return af == bf
+	}
+	return cast.ToString(a) == cast.ToString(b)
+}
+
+func predicateTruthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		return "" != t && "false" != t
+	case []interface{}:
+		return len(t) > 0
+	case []string:
+		return len(t) > 0
+	default:
+		f, err := cast.ToFloat64E(v)
+		return nil != err || 0 != f
+	}
+}
+
+////
+
+const (
+	tokenOp = iota
+	tokenString
+	tokenNumber
+	tokenWord
+)
+
+type predicateToken struct {
+	kind int
+	text string
+}
+
+func tokenizePredicate(expr string) ([]predicateToken, error) {
+	tokens := make([]predicateToken, 0, 16)
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case ' ' == c || '\t' == c || '\n' == c || '\r' == c:
+			i++
+		case strings.HasPrefix(expr[i:], "&&") || strings.HasPrefix(expr[i:], "||") ||
+			strings.HasPrefix(expr[i:], "==") || strings.HasPrefix(expr[i:], "!=") ||
+			strings.HasPrefix(expr[i:], ">=") || strings.HasPrefix(expr[i:], "<="):
+			tokens = append(tokens, predicateToken{kind: tokenOp, text: expr[i : i+2]})
+			i += 2
+		case strings.IndexByte("()[],!<>", c) >= 0:
+			tokens = append(tokens, predicateToken{kind: tokenOp, text: expr[i : i+1]})
+			i++
+		case '"' == c || '\'' == c:
+			j := i + 1
+			var sb strings.Builder
+			for ; j < len(expr) && expr[j] != c; j++ {
+				if '\\' == expr[j] && j+1 < len(expr) {
+					j++
+				}
+				sb.WriteByte(expr[j])
+			}
+			if j >= len(expr) {
+				return nil, fmt.Errorf("predicate: unterminated string, expr: %s", expr)
+			}
+			tokens = append(tokens, predicateToken{kind: tokenString, text: sb.String()})
+			i = j + 1
+		case '-' == c || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(expr) && (expr[j] >= '0' && expr[j] <= '9' || '.' == expr[j]) {
+				j++
+			}
+			tokens = append(tokens, predicateToken{kind: tokenNumber, text: expr[i:j]})
+			i = j
+		case isPredicateWordChar(c):
+			j := i
+			for j < len(expr) && isPredicateWordChar(expr[j]) {
+				j++
+			}
+			tokens = append(tokens, predicateToken{kind: tokenWord, text: expr[i:j]})
+			i = j
+		default:
+			return nil, fmt.Errorf("predicate: unexpected char %q at %d, expr: %s", c, i, expr)
+		}
+	}
+	return tokens, nil
+}
+
+func isPredicateWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		strings.IndexByte("_-.:$", c) >= 0
+}
+
+type predicateParser struct {
+	expr   string
+	tokens []predicateToken
+	pos    int
+}
+
+var errPredicateEnd = errors.New("predicate: unexpected end of expr")
+
+func (p *predicateParser) peek() (predicateToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return predicateToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *predicateParser) accept(kind int, text string) bool {
+	if t, ok := p.peek(); ok && t.kind == kind && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *predicateParser) parseOr() (predicateNode, error) {
+	left, err := p.parseAnd()
+	for nil == err && p.accept(tokenOp, "||") {
+		var right predicateNode
+		if right, err = p.parseAnd(); nil == err {
+			left = predicateLogic{and: false, left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *predicateParser) parseAnd() (predicateNode, error) {
+	left, err := p.parseUnary()
+	for nil == err && p.accept(tokenOp, "&&") {
+		var right predicateNode
+		if right, err = p.parseUnary(); nil == err {
+			left = predicateLogic{and: true, left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *predicateParser) parseUnary() (predicateNode, error) {
+	if p.accept(tokenOp, "!") {
+		node, err := p.parseUnary()
+		return predicateNot{node: node}, err
+	}
+	if p.accept(tokenOp, "(") {
+		node, err := p.parseOr()
+		if nil != err {
+			return nil, err
+		}
+		if !p.accept(tokenOp, ")") {
+			return nil, fmt.Errorf("predicate: missing ')', expr: %s", p.expr)
+		}
+		return node, nil
+	}
+	left, err := p.parseOperand()
+	if nil != err {
+		return nil, err
+	}
+	t, ok := p.peek()
+	if !ok {
+		return left, nil
+	}
+	op := t.text
+	switch {
+	case tokenOp == t.kind && strings.Contains(" == != > >= < <= ", " "+op+" "):
+	case tokenWord == t.kind && ("in" == op || "contains" == op || "matches" == op):
+	default:
+		return left, nil
+	}
+	p.pos++
+	right, err := p.parseOperand()
+	if nil != err {
+		return nil, err
+	}
+	node := &predicateCompare{op: op, left: left, right: right}
+	if lit, ok := right.(predicateLiteral); ok && "matches" == op {
+		if node.pattern, err = regexp.Compile(cast.ToString(lit.value)); nil != err {
+			return nil, fmt.Errorf("predicate: invalid pattern: %w", err)
+		}
+	}
+	return node, nil
+}
+
+func (p *predicateParser) parseOperand() (predicateNode, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, errPredicateEnd
+	}
+	p.pos++
+	switch t.kind {
+	case tokenString:
+		return predicateLiteral{value: t.text}, nil
+	case tokenNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if nil != err {
+			return nil, fmt.Errorf("predicate: invalid number %q, expr: %s", t.text, p.expr)
+		}
+		return predicateLiteral{value: f}, nil
+	case tokenWord:
+		switch t.text {
+		case "true":
+			return predicateLiteral{value: true}, nil
+		case "false":
+			return predicateLiteral{value: false}, nil
+		case "null":
+			return predicateLiteral{value: nil}, nil
+		}
+		scope, key, ok := ParseLookupExpr(t.text)
+		if !ok {
+			return nil, fmt.Errorf("predicate: invalid reference %q, requires scope:key, expr: %s", t.text, p.expr)
+		}
+		// key 可能包含冒号
+		key = t.text[strings.Index(t.text, ":")+1:]
+		return predicateRef{scope: scope, key: key}, nil
+	default:
+		if "[" != t.text {
+			return nil, fmt.Errorf("predicate: unexpected token %q, expr: %s", t.text, p.expr)
+		}
+		list := predicateList{items: make([]predicateNode, 0, 4)}
+		if p.accept(tokenOp, "]") {
+			return list, nil
+		}
+		for {
+			item, err := p.parseOperand()
+			if nil != err {
+				return nil, err
+			}
+			list.items = append(list.items, item)
+			if p.accept(tokenOp, "]") {
+				return list, nil
+			}
+			if !p.accept(tokenOp, ",") {
+				return nil, fmt.Errorf("predicate: missing ']' in list, expr: %s", p.expr)
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package support
+
+import (
+	"encoding/json"
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPredicate_EvalWith(t *testing.T) {
+	var body interface{}
+	_ = json.Unmarshal([]byte(`{"order":{"type":"international","amount":120,"tags":["vip","new"]}}`), &body)
+	values := map[string]interface{}{
+		"HEADER:X-Region": "eu",
+		"QUERY:debug":     "",
+	}
+	lookup := func(scope, key string) (interface{}, error) {
+		if flux.ScopeBody == scope {
+			return JSONPathValue(body, key), nil
+		}
+		return values[scope+":"+key], nil
+	}
+	cases := []struct {
+		expr     string
+		expected bool
+	}{
+		{expr: `body:order.type == "international"`, expected: true},
+		{expr: `body:order.type != 'international'`, expected: false},
+		{expr: `body:order.amount > 100 && body:order.amount <= 120`, expected: true},
+		{expr: `body:order.amount == "120"`, expected: true},
+		{expr: `header:X-Region in ["us", "eu"]`, expected: true},
+		{expr: `!(header:X-Region in ["us", "cn"])`, expected: true},
+		{expr: `body:order.tags contains "vip"`, expected: true},
+		{expr: `body:order.tags.1 == "new"`, expected: true},
+		{expr: `body:order.type matches "^inter"`, expected: true},
+		{expr: `query:debug || body:order.missing == null`, expected: true},
+		{expr: `query:debug`, expected: false},
+		{expr: `body:order.type == "domestic" || header:X-Region == "us"`, expected: false},
+	}
+	for _, tc := range cases {
+		assert := assert2.New(t)
+		p, err := CompilePredicate(tc.expr)
+		assert.NoError(err, "expr: %s", tc.expr)
+		ok, err := p.EvalWith(lookup)
+		assert.NoError(err, "expr: %s", tc.expr)
+		assert.Equal(tc.expected, ok, "expr: %s", tc.expr)
+	}
+}
+
+func TestCompilePredicate_Invalid(t *testing.T) {
+	cases := []string{
+		``,
+		`body:type ==`,
+		`type == "a"`,
+		`(header:a == "b"`,
+		`header:a in ["b"`,
+		`header:a == "b`,
+		`header:a matches "("`,
+		`header:a == "b" "c"`,
+	}
+	for _, expr := range cases {
+		_, err := CompilePredicate(expr)
+		assert2.Error(t, err, "expr: %s", expr)
+	}
+}
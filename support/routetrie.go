@@ -0,0 +1,277 @@
+package support
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	routeNodeStatic = iota
+	routeNodeParam
+	routeNodeAny
+)
+
+// RouteTrie 压缩前缀树（Radix Tree）实现的路由匹配器，查找耗时与路由数量无关，只与请求路径长度相关；
+// 支持静态路径、路径变量（:name 或 {name}，匹配一个路径段）和通配符（*，匹配剩余路径）。
+// 匹配优先级：静态路径 > 路径变量 > 通配符。并发安全。
+type RouteTrie struct {
+	mu   sync.RWMutex
+	root *routeNode
+	size int
+}
+
+type routeNode struct {
+	kind    int
+	prefix  string
+	statics []*routeNode
+	param   *routeNode
+	any     *routeNode
+	route   *routeValue
+}
+
+type routeValue struct {
+	pattern string
+	names   []string
+	value   interface{}
+}
+
+// RouteMatch 路由匹配结果
+type RouteMatch struct {
+	Pattern string
+	Value   interface{}
+	Params  map[string]string
+}
+
+func NewRouteTrie() *RouteTrie {
+	return &RouteTrie{root: &routeNode{kind: routeNodeStatic}}
+}
+
+// Insert 添加路由Pattern和关联数据；Pattern已存在时替换关联数据
+func (t *RouteTrie) Insert(pattern string, value interface{}) error {
+	segments, names, err := parseRoutePattern(pattern)
+	if nil != err {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	node := t.root
+	for _, seg := range segments {
+		switch seg.kind {
+		case routeNodeParam:
+			if nil == node.param {
+				node.param = &routeNode{kind: routeNodeParam}
+			}
+			node = node.param
+		case routeNodeAny:
+			if nil == node.any {
+				node.any = &routeNode{kind: routeNodeAny}
+			}
+			node = node.any
+		default:
+			node = node.insertStatic(seg.text)
+		}
+	}
+	if nil == node.route {
+		t.size++
+	}
+	node.route = &routeValue{pattern: pattern, names: names, value: value}
+	return nil
+}
+
+// Remove 删除路由Pattern，返回是否存在
+func (t *RouteTrie) Remove(pattern string) bool {
+	segments, _, err := parseRoutePattern(pattern)
+	if nil != err {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	node := t.root
+	for _, seg := range segments {
+		switch seg.kind {
+		case routeNodeParam:
+			node = node.param
+		case routeNodeAny:
+			node = node.any
+		default:
+			node = node.findStatic(seg.text)
+		}
+		if nil == node {
+			return false
+		}
+	}
+	if nil == node.route {
+		return false
+	}
+	node.route = nil
+	t.size--
+	return true
+}
+
+// Match 查找匹配请求路径的路由，返回路由Pattern、关联数据和路径变量
+func (t *RouteTrie) Match(path string) (RouteMatch, bool) {
+	values := make([]string, 0, 4)
+	t.mu.RLock()
+	route := t.root.match(path, &values)
+	t.mu.RUnlock()
+	if nil == route {
+		return RouteMatch{}, false
+	}
+	match := RouteMatch{Pattern: route.pattern, Value: route.value}
+	if len(route.names) > 0 {
+		match.Params = make(map[string]string, len(route.names))
+		for i, name := range route.names {
+			match.Params[name] = values[i]
+		}
+	}
+	return match, true
+}
+
+// Len 返回路由数量
+func (t *RouteTrie) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.size
+}
+
+// insertStatic 插入静态路径片段，公共前缀不同时分裂节点；返回片段末尾的节点
+func (n *routeNode) insertStatic(text string) *routeNode {
+	for "" != text {
+		var next *routeNode
+		for i, child := range n.statics {
+			l := commonPrefixLen(child.prefix, text)
+			if 0 == l {
+				continue
+			}
+			if l < len(child.prefix) {
+				split := &routeNode{kind: routeNodeStatic, prefix: child.prefix[:l]}
+				child.prefix = child.prefix[l:]
+				split.statics = []*routeNode{child}
+				n.statics[i] = split
+				child = split
+			}
+			next, text = child, text[l:]
+			break
+		}
+		if nil == next {
+			next = &routeNode{kind: routeNodeStatic, prefix: text}
+			n.statics = append(n.statics, next)
+			text = ""
+		}
+		n = next
+	}
+	return n
+}
+
+func (n *routeNode) findStatic(text string) *routeNode {
+	for "" != text {
+		var next *routeNode
+		for _, child := range n.statics {
+			if strings.HasPrefix(text, child.prefix) {
+				next, text = child, text[len(child.prefix):]
+				break
+			}
+		}
+		if nil == next {
+			return nil
+		}
+		n = next
+	}
+	return n
+}
+
+// match 按优先级回溯匹配：静态子节点，路径变量，通配符
+func (n *routeNode) match(path string, values *[]string) *routeValue {
+	if "" == path && nil != n.route {
+		return n.route
+	}
+	for _, child := range n.statics {
+		if len(path) >= len(child.prefix) && path[0] == child.prefix[0] && path[:len(child.prefix)] == child.prefix {
+			if route := child.match(path[len(child.prefix):], values); nil != route {
+				return route
+			}
+			// 静态子节点的首字节互不相同，最多只有一个子节点匹配
+			break
+		}
+	}
+	if nil != n.param && "" != path {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			*values = append(*values, path[:end])
+			if route := n.param.match(path[end:], values); nil != route {
+				return route
+			}
+			*values = (*values)[:len(*values)-1]
+		}
+	}
+	if nil != n.any && nil != n.any.route {
+		*values = append(*values, path)
+		return n.any.route
+	}
+	return nil
+}
+
+type routeSegment struct {
+	kind int
+	text string
+}
+
+// parseRoutePattern 解析路由Pattern为静态片段、路径变量和通配符；相邻的静态片段合并
+func parseRoutePattern(pattern string) ([]routeSegment, []string, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return nil, nil, fmt.Errorf("route pattern must starts with '/': %s", pattern)
+	}
+	segments := make([]routeSegment, 0, 4)
+	names := make([]string, 0, 2)
+	static := strings.Builder{}
+	parts := strings.Split(pattern[1:], "/")
+	for i, part := range parts {
+		static.WriteByte('/')
+		var name string
+		kind := routeNodeStatic
+		switch {
+		case strings.HasPrefix(part, ":"):
+			kind, name = routeNodeParam, part[1:]
+		case strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}"):
+			kind, name = routeNodeParam, part[1:len(part)-1]
+		case strings.HasPrefix(part, "*"):
+			if i != len(parts)-1 {
+				return nil, nil, fmt.Errorf("route pattern wildcard must be the last segment: %s", pattern)
+			}
+			kind, name = routeNodeAny, part[1:]
+			if "" == name {
+				name = "*"
+			}
+		default:
+			static.WriteString(part)
+			continue
+		}
+		if "" == name {
+			return nil, nil, fmt.Errorf("route pattern has empty variable name: %s", pattern)
+		}
+		segments = append(segments, routeSegment{kind: routeNodeStatic, text: static.String()})
+		static.Reset()
+		segments = append(segments, routeSegment{kind: kind})
+		names = append(names, name)
+	}
+	if static.Len() > 0 {
+		segments = append(segments, routeSegment{kind: routeNodeStatic, text: static.String()})
+	}
+	return segments, names, nil
+}
+
+func commonPrefixLen(a, b string) int {
+	max := len(a)
+	if len(b) < max {
+		max = len(b)
+	}
+	i := 0
+	for i < max && a[i] == b[i] {
+		i++
+	}
+	return i
+}
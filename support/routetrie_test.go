@@ -0,0 +1,146 @@
+package support
+
+import (
+	"fmt"
+	assert2 "github.com/stretchr/testify/assert"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRouteTrie_Match(t *testing.T) {
+	assert := assert2.New(t)
+	trie := NewRouteTrie()
+	for _, pattern := range []string{
+		"/", "/users", "/users/me", "/users/:id", "/users/{id}/orders/:orderId",
+		"/user-groups/:group", "/files/*path", "/static/*",
+	} {
+		assert.NoError(trie.Insert(pattern, pattern))
+	}
+	cases := []struct {
+		path    string
+		pattern string
+		params  map[string]string
+	}{
+		{path: "/", pattern: "/"},
+		{path: "/users", pattern: "/users"},
+		{path: "/users/me", pattern: "/users/me"},
+		{path: "/users/1001", pattern: "/users/:id", params: map[string]string{"id": "1001"}},
+		{path: "/users/mee", pattern: "/users/:id", params: map[string]string{"id": "mee"}},
+		{path: "/users/1001/orders/9", pattern: "/users/{id}/orders/:orderId", params: map[string]string{"id": "1001", "orderId": "9"}},
+		{path: "/user-groups/admin", pattern: "/user-groups/:group", params: map[string]string{"group": "admin"}},
+		{path: "/files/a/b.txt", pattern: "/files/*path", params: map[string]string{"path": "a/b.txt"}},
+		{path: "/static/", pattern: "/static/*", params: map[string]string{"*": ""}},
+		{path: "/users/1001/orders", pattern: ""},
+		{path: "/users/", pattern: ""},
+		{path: "/unknown", pattern: ""},
+	}
+	for _, tc := range cases {
+		match, ok := trie.Match(tc.path)
+		assert.Equal("" != tc.pattern, ok, tc.path)
+		assert.Equal(tc.pattern, match.Pattern, tc.path)
+		assert.Equal(tc.params, match.Params, tc.path)
+	}
+	assert.Equal(8, trie.Len())
+	assert.True(trie.Remove("/users/me"))
+	assert.False(trie.Remove("/users/me"))
+	match, ok := trie.Match("/users/me")
+	assert.True(ok)
+	assert.Equal("/users/:id", match.Pattern)
+	assert.Error(trie.Insert("users", nil))
+	assert.Error(trie.Insert("/files/*/more", nil))
+}
+
+// 基准测试：5万条路由下，前缀树与逐条匹配Pattern的查找耗时和内存占用
+
+const benchRouteCount = 50000
+
+func benchRoutePatterns() []string {
+	patterns := make([]string, 0, benchRouteCount)
+	for i := 0; len(patterns) < benchRouteCount; i++ {
+		patterns = append(patterns,
+			fmt.Sprintf("/api/v1/service-%d/items", i),
+			fmt.Sprintf("/api/v1/service-%d/items/:id", i),
+			fmt.Sprintf("/api/v1/service-%d/items/:id/detail", i),
+			fmt.Sprintf("/api/v1/service-%d/files/*path", i),
+		)
+	}
+	return patterns[:benchRouteCount]
+}
+
+func benchRoutePaths() []string {
+	last := benchRouteCount/4 - 1
+	return []string{
+		"/api/v1/service-1/items",
+		fmt.Sprintf("/api/v1/service-%d/items/1001", last/2),
+		fmt.Sprintf("/api/v1/service-%d/items/1001/detail", last),
+		fmt.Sprintf("/api/v1/service-%d/files/a/b/c.txt", last),
+		"/api/v1/not-found",
+	}
+}
+
+// linearRoutes 逐条比较路径段的匹配方式，作为对比基准
+type linearRoutes [][]string
+
+func (l linearRoutes) match(path string) (int, bool) {
+	segments := strings.Split(path[1:], "/")
+RouteLoop:
+	for i, route := range l {
+		for j, seg := range route {
+			if strings.HasPrefix(seg, "*") {
+				return i, true
+			}
+			if j >= len(segments) {
+				continue RouteLoop
+			}
+			if !strings.HasPrefix(seg, ":") && seg != segments[j] {
+				continue RouteLoop
+			}
+		}
+		if len(route) == len(segments) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+func BenchmarkRouteTrie_Match(b *testing.B) {
+	before := heapInuse()
+	trie := NewRouteTrie()
+	for _, pattern := range benchRoutePatterns() {
+		_ = trie.Insert(pattern, pattern)
+	}
+	size := int64(heapInuse()) - int64(before)
+	paths := benchRoutePaths()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		trie.Match(paths[i%len(paths)])
+	}
+	b.ReportMetric(float64(size)/benchRouteCount, "heap-bytes/route")
+	runtime.KeepAlive(trie)
+}
+
+func BenchmarkRouteLinear_Match(b *testing.B) {
+	before := heapInuse()
+	routes := make(linearRoutes, 0, benchRouteCount)
+	for _, pattern := range benchRoutePatterns() {
+		routes = append(routes, strings.Split(pattern[1:], "/"))
+	}
+	size := int64(heapInuse()) - int64(before)
+	paths := benchRoutePaths()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		routes.match(paths[i%len(paths)])
+	}
+	b.ReportMetric(float64(size)/benchRouteCount, "heap-bytes/route")
+	runtime.KeepAlive(routes)
+}
+
+func heapInuse() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse
+}
@@ -0,0 +1,43 @@
+package support
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/bytepowered/flux"
+	"github.com/spf13/viper"
+)
+
+const (
+	// 默认密钥配置的命名空间
+	DefaultSecretConfigNamespace = "SECRETS"
+)
+
+var _ flux.SecretProvider = new(ConfigSecretProvider)
+
+// ConfigSecretProvider 基于配置文件的密钥提供实现；
+// 密钥以Base64编码，按KeyId配置在指定命名空间下。例如：
+// [SECRETS]
+// pii-key-v1 = "base64-encoded-key"
+type ConfigSecretProvider struct {
+	namespace string
+}
+
+func NewConfigSecretProvider(namespace string) *ConfigSecretProvider {
+	return &ConfigSecretProvider{namespace: namespace}
+}
+
+func (p *ConfigSecretProvider) LoadSecret(keyId string) ([]byte, error) {
+	if "" == keyId {
+		return nil, fmt.Errorf("secret key-id is empty")
+	}
+	// 注意：延迟到使用时读取，确保配置文件已加载
+	text := viper.GetString(p.namespace + "." + keyId)
+	if "" == text {
+		return nil, fmt.Errorf("secret not found, key-id: %s", keyId)
+	}
+	if secret, err := base64.StdEncoding.DecodeString(text); nil != err {
+		return nil, fmt.Errorf("secret decode base64, key-id: %s, error: %w", keyId, err)
+	} else {
+		return secret, nil
+	}
+}
@@ -0,0 +1,34 @@
+package support
+
+import (
+	"encoding/base64"
+	"github.com/spf13/viper"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestConfigSecretProvider_LoadSecret(t *testing.T) {
+	viper.Set("TEST-SECRETS.k1", base64.StdEncoding.EncodeToString([]byte("0123456789abcdef")))
+	viper.Set("TEST-SECRETS.bad", "not-base64!")
+	cases := []struct {
+		keyId    string
+		expected []byte
+		isError  bool
+	}{
+		{keyId: "k1", expected: []byte("0123456789abcdef"), isError: false},
+		{keyId: "", isError: true},
+		{keyId: "missing", isError: true},
+		{keyId: "bad", isError: true},
+	}
+	assert := assert2.New(t)
+	provider := NewConfigSecretProvider("TEST-SECRETS")
+	for _, c := range cases {
+		secret, err := provider.LoadSecret(c.keyId)
+		if c.isError {
+			assert.Error(err, "key-id: "+c.keyId)
+		} else {
+			assert.NoError(err)
+			assert.Equal(c.expected, secret)
+		}
+	}
+}
@@ -114,6 +114,11 @@ func (r *ValuesRequestReader) FormValue(name string) string {
 }
 
 func (r *ValuesRequestReader) CookieValue(name string) (cookie *http.Cookie, ok bool) {
+	for _, c := range r.CookieValues() {
+		if name == c.Name {
+			return c, true
+		}
+	}
 	return nil, false
 }
 
@@ -166,6 +171,10 @@ func (v *ValuesContext) RequestId() string {
 	return cast.ToString(v.request.values["request-id"])
 }
 
+func (v *ValuesContext) ClientIP() string {
+	return cast.ToString(v.request.values["client-ip"])
+}
+
 func (v *ValuesContext) Request() flux.RequestReader {
 	return v.request
 }
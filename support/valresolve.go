@@ -2,6 +2,8 @@ package support
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/bytepowered/flux"
@@ -9,15 +11,46 @@ import (
 	"github.com/spf13/cast"
 	"io"
 	"io/ioutil"
+	"math"
+	"math/big"
 	"net/url"
 	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// 时间格式：Unix时间戳，单位为秒
+	TimeLayoutUnix = "unix"
+	// 时间格式：Unix时间戳，单位为毫秒
+	TimeLayoutUnixMillis = "unix-millis"
 )
 
 var (
 	errCastToByteTypeNotSupported = errors.New("cannot convert value to []byte")
 )
 
+const (
+	// POJO类型递归解析的最大深度，避免自引用的类型定义无限递归
+	maxPOJOResolveDepth = 32
+)
+
+var (
+	// 十进制数值文本：可选负号、整数部分、小数部分和指数部分
+	decimalTextPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+)
+
+var (
+	// 时间类型参数值的解析格式，按顺序尝试；不含时区的格式按本地时区解析
+	timeValueLayouts = []string{
+		time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02", TimeLayoutUnixMillis,
+	}
+)
+
 var (
 	stringResolver = flux.MTValueResolver(func(mtValue flux.MTValue, _ string, genericTypes []string) (interface{}, error) {
 		return CastDecodeMTValueToString(mtValue)
@@ -37,18 +70,52 @@ var (
 	booleanResolver = flux.WrapMTValueResolver(func(value interface{}) (interface{}, error) {
 		return cast.ToBool(value), nil
 	}).ResolveMT
-	mapResolver = flux.MTValueResolver(func(value flux.MTValue, _ string, genericTypes []string) (interface{}, error) {
-		return CastDecodeMTValueToStringMap(value)
+	mapResolver = ext.MTValueResolverFactory(func(r *ext.Registry) flux.MTValueResolver {
+		return func(value flux.MTValue, _ string, genericTypes []string) (interface{}, error) {
+			// 声明值类型时，按值类型解析每个值，例如 Map<String,Long>
+			if len(genericTypes) > 1 {
+				return castDecodeMTValueToTypedMap(r, genericTypes, value)
+			}
+			return CastDecodeMTValueToStringMap(value)
+		}
+	})
+	listResolver = ext.MTValueResolverFactory(func(r *ext.Registry) flux.MTValueResolver {
+		return func(value flux.MTValue, class string, genericTypes []string) (interface{}, error) {
+			// 元素为POJO类型时，按字段定义解析每个元素
+			if len(genericTypes) > 0 {
+				if _, ok := r.LoadPOJOType(genericTypes[0]); ok {
+					return resolveTypedValue(r, class, genericTypes, value, 0)
+				}
+			}
+			return castDecodeMTValueToSliceList(r, genericTypes, value)
+		}
+	})
+	timeResolver = flux.MTValueResolver(func(mtValue flux.MTValue, _ string, _ []string) (interface{}, error) {
+		return CastDecodeMTValueToTime(mtValue, timeValueLayouts)
 	})
-	listResolver = flux.MTValueResolver(func(value flux.MTValue, _ string, genericTypes []string) (interface{}, error) {
-		return CastDecodeMTValueToSliceList(genericTypes, value)
+	bigDecimalResolver = flux.MTValueResolver(func(mtValue flux.MTValue, _ string, _ []string) (interface{}, error) {
+		return CastDecodeMTValueToBigDecimal(mtValue)
 	})
-	complexObjectResolver = flux.MTValueResolver(func(mtValue flux.MTValue, typeClass string, typeGeneric []string) (interface{}, error) {
-		return map[string]interface{}{
-			"class":   typeClass,
-			"generic": typeGeneric,
-			"value":   mtValue.Value,
-		}, nil
+	bigIntegerResolver = flux.MTValueResolver(func(mtValue flux.MTValue, _ string, _ []string) (interface{}, error) {
+		return CastDecodeMTValueToBigInt(mtValue)
+	})
+	byteArrayResolver = flux.MTValueResolver(func(mtValue flux.MTValue, _ string, _ []string) (interface{}, error) {
+		return CastDecodeMTValueToBytes(mtValue)
+	})
+	enumResolver = flux.MTValueResolver(func(mtValue flux.MTValue, _ string, genericTypes []string) (interface{}, error) {
+		return CastDecodeMTValueToEnum(mtValue, genericTypes)
+	})
+	complexObjectResolver = ext.MTValueResolverFactory(func(r *ext.Registry) flux.MTValueResolver {
+		return func(mtValue flux.MTValue, typeClass string, typeGeneric []string) (interface{}, error) {
+			if _, ok := r.LoadPOJOType(typeClass); ok {
+				return castDecodeMTValueToPOJO(r, mtValue, typeClass)
+			}
+			return map[string]interface{}{
+				"class":   typeClass,
+				"generic": typeGeneric,
+				"value":   mtValue.Value,
+			}, nil
+		}
 	})
 )
 
@@ -75,14 +142,33 @@ func init() {
 	ext.RegisterMTValueResolver("boolean", booleanResolver)
 	ext.RegisterMTValueResolver(flux.JavaLangBooleanClassName, booleanResolver)
 
-	ext.RegisterMTValueResolver("map", mapResolver)
-	ext.RegisterMTValueResolver(flux.JavaUtilMapClassName, mapResolver)
+	ext.RegisterMTValueResolverFactory("map", mapResolver)
+	ext.RegisterMTValueResolverFactory(flux.JavaUtilMapClassName, mapResolver)
 
-	ext.RegisterMTValueResolver("slice", listResolver)
-	ext.RegisterMTValueResolver("list", listResolver)
-	ext.RegisterMTValueResolver(flux.JavaUtilListClassName, listResolver)
+	ext.RegisterMTValueResolverFactory("slice", listResolver)
+	ext.RegisterMTValueResolverFactory("list", listResolver)
+	ext.RegisterMTValueResolverFactory(flux.JavaUtilListClassName, listResolver)
 
-	ext.RegisterMTValueResolver(ext.DefaultMTValueResolverName, complexObjectResolver)
+	ext.RegisterMTValueResolver("time", timeResolver)
+	ext.RegisterMTValueResolver("time.Time", timeResolver)
+	ext.RegisterMTValueResolver(flux.JavaUtilDateClassName, timeResolver)
+	ext.RegisterMTValueResolver(flux.JavaTimeLocalDateTimeClassName, timeResolver)
+
+	ext.RegisterMTValueResolver("decimal", bigDecimalResolver)
+	ext.RegisterMTValueResolver(flux.JavaMathBigDecimalClassName, bigDecimalResolver)
+
+	ext.RegisterMTValueResolver("bigint", bigIntegerResolver)
+	ext.RegisterMTValueResolver(flux.JavaMathBigIntegerClassName, bigIntegerResolver)
+
+	ext.RegisterMTValueResolver("bytes", byteArrayResolver)
+	ext.RegisterMTValueResolver("[]byte", byteArrayResolver)
+	ext.RegisterMTValueResolver("byte[]", byteArrayResolver)
+	ext.RegisterMTValueResolver(flux.JavaByteArrayClassName, byteArrayResolver)
+
+	ext.RegisterMTValueResolver("enum", enumResolver)
+	ext.RegisterMTValueResolver(flux.JavaLangEnumClassName, enumResolver)
+
+	ext.RegisterMTValueResolverFactory(ext.DefaultMTValueResolverName, complexObjectResolver)
 }
 
 // CastDecodeToString 最大努力地将值转换成String类型。
@@ -113,8 +199,12 @@ func CastDecodeMTValueToStringMap(mtValue flux.MTValue) (map[string]interface{},
 	case flux.ValueMediaTypeGoStringMap:
 		return cast.ToStringMap(mtValue.Value), nil
 	case flux.ValueMediaTypeGoString:
+		text, ok := mtValue.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot decode non-string to hashmap, value: %+v, value.type:%T", mtValue.Value, mtValue.Value)
+		}
 		var hashmap = map[string]interface{}{}
-		if err := ext.JSONUnmarshal([]byte(mtValue.Value.(string)), &hashmap); nil != err {
+		if err := ext.JSONUnmarshal([]byte(text), &hashmap); nil != err {
 			return nil, fmt.Errorf("cannot decode text to hashmap, text: %s, error:%w", mtValue.Value, err)
 		} else {
 			return hashmap, nil
@@ -155,25 +245,411 @@ func CastDecodeMTValueToStringMap(mtValue flux.MTValue) (map[string]interface{},
 	}
 }
 
-// CastDecodeMTValueToSliceList 最大努力地将值转换成[]any类型。
+// CastDecodeMTValueToSliceList 最大努力地将值转换成[]any类型：切片和重复Key的多值（[]string、url.Values）按元素转换，
+// 多值中的字符串元素和单个字符串按逗号分隔；JSON数组文本按数组解码。指定泛型类型时，每个元素使用泛型类型的解析函数解析；
+// 未指定泛型类型时，切片原样返回。元素使用默认注册表的解析函数解析。
 // 如果类型无法安全地转换成[]any或者解析异常，返回错误。
 func CastDecodeMTValueToSliceList(genericTypes []string, mtValue flux.MTValue) (interface{}, error) {
-	vType := reflect.TypeOf(mtValue.Value)
-	if vType.Kind() == reflect.Slice {
-		return mtValue.Value, nil
-	}
-	// SingleValue to arraylist
-	if len(genericTypes) > 0 {
-		typeClass := genericTypes[0]
-		resolver := ext.LoadMTValueResolver(typeClass)
-		if v, err := resolver(mtValue, typeClass, []string{}); nil != err {
-			return nil, err
+	return castDecodeMTValueToSliceList(ext.Default(), genericTypes, mtValue)
+}
+
+func castDecodeMTValueToSliceList(r *ext.Registry, genericTypes []string, mtValue flux.MTValue) (interface{}, error) {
+	var items []interface{}
+	switch value := mtValue.Value.(type) {
+	case string:
+		text := strings.TrimSpace(value)
+		if strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]") {
+			decoded, err := castDecodeMTValueToItems(mtValue)
+			if nil != err {
+				return nil, err
+			}
+			items = decoded
+		} else if strings.HasPrefix(text, "{") {
+			// JSON对象文本作为单个元素
+			items = []interface{}{value}
 		} else {
-			return []interface{}{v}, nil
+			items = splitListText(value, items)
 		}
-	} else {
-		return []interface{}{mtValue.Value}, nil
+	case []string:
+		if 0 == len(genericTypes) {
+			return value, nil
+		}
+		for _, v := range value {
+			items = splitListText(v, items)
+		}
+	case url.Values:
+		items = flattenListValues(value)
+	case map[string][]string:
+		items = flattenListValues(value)
+	default:
+		if rv := reflect.ValueOf(value); reflect.Slice == rv.Kind() {
+			if 0 == len(genericTypes) || reflect.Uint8 == rv.Type().Elem().Kind() {
+				return value, nil
+			}
+			items, _ = castDecodeMTValueToItems(mtValue)
+			break
+		}
+		// SingleValue to arraylist
+		if 0 == len(genericTypes) {
+			return []interface{}{value}, nil
+		}
+		v, err := resolveListItem(r, genericTypes, mtValue)
+		if nil != err {
+			return nil, err
+		}
+		return []interface{}{v}, nil
+	}
+	if 0 == len(genericTypes) {
+		return items, nil
+	}
+	out := make([]interface{}, len(items))
+	for i, item := range items {
+		v, err := resolveListItem(r, genericTypes, typedMTValueOf(item))
+		if nil != err {
+			return nil, fmt.Errorf("index: %d, %w", i, err)
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// resolveListItem 使用泛型类型的解析函数解析列表元素
+func resolveListItem(r *ext.Registry, genericTypes []string, mtValue flux.MTValue) (interface{}, error) {
+	typeClass := genericTypes[0]
+	resolver := r.LoadMTValueResolver(typeClass)
+	if nil == resolver {
+		return nil, fmt.Errorf("unsupported generic type to arraylist, type: %s", typeClass)
+	}
+	return resolver(mtValue, typeClass, genericTypes[1:])
+}
+
+// splitListText 按逗号分隔文本，去除元素的首尾空格后追加到列表
+func splitListText(text string, items []interface{}) []interface{} {
+	for _, v := range strings.Split(text, ",") {
+		items = append(items, strings.TrimSpace(v))
+	}
+	return items
+}
+
+// flattenListValues 按Key排序展开多值Map的全部值
+func flattenListValues(values map[string][]string) []interface{} {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	items := make([]interface{}, 0, len(values))
+	for _, key := range keys {
+		for _, v := range values[key] {
+			items = splitListText(v, items)
+		}
+	}
+	return items
+}
+
+// SetTimeValueLayouts 设置时间类型参数值的解析格式，按顺序尝试；支持Go时间格式和 unix、unix-millis 时间戳。
+// 应在网关启动前设置；格式列表为空时不修改
+func SetTimeValueLayouts(layouts []string) {
+	if len(layouts) > 0 {
+		timeValueLayouts = layouts
+	}
+}
+
+// CastDecodeMTValueToTime 按格式列表将值转换成time.Time类型：数值按第一个时间戳格式解析，字符串按顺序尝试各个格式。
+// 如果所有格式均无法解析，返回错误。
+func CastDecodeMTValueToTime(mtValue flux.MTValue, layouts []string) (time.Time, error) {
+	switch v := mtValue.Value.(type) {
+	case time.Time:
+		return v, nil
+	case *time.Time:
+		if nil != v {
+			return *v, nil
+		}
+		return time.Time{}, errors.New("cannot decode nil to time")
+	case int, int32, int64, uint, uint32, uint64, float32, float64:
+		for _, layout := range layouts {
+			if TimeLayoutUnix == layout || TimeLayoutUnixMillis == layout {
+				return timeOfUnix(layout, cast.ToInt64(v)), nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cannot decode number to time, no unix layout, value: %v", v)
+	}
+	text, err := CastDecodeMTValueToString(mtValue)
+	if nil != err {
+		return time.Time{}, err
 	}
+	text = strings.TrimSpace(text)
+	for _, layout := range layouts {
+		if TimeLayoutUnix == layout || TimeLayoutUnixMillis == layout {
+			if n, err := strconv.ParseInt(text, 10, 64); nil == err {
+				return timeOfUnix(layout, n), nil
+			}
+		} else if t, err := time.ParseInLocation(layout, text, time.Local); nil == err {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot decode text to time, text: %s, layouts: %v", text, layouts)
+}
+
+// CastDecodeMTValueToBigDecimal 将值转换成十进制数值文本（json.Number），保留原始精度和小数位数；
+// 浮点数值按最短表示转换。JSON编码时按数值输出。如果值不是有效的十进制数值，返回错误。
+func CastDecodeMTValueToBigDecimal(mtValue flux.MTValue) (json.Number, error) {
+	var text string
+	switch v := mtValue.Value.(type) {
+	case float32:
+		text = strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	case *big.Int:
+		if nil == v {
+			return "", errors.New("cannot decode nil to decimal")
+		}
+		text = v.String()
+	default:
+		str, err := CastDecodeMTValueToString(mtValue)
+		if nil != err {
+			return "", err
+		}
+		text = strings.TrimPrefix(strings.TrimSpace(str), "+")
+	}
+	if !decimalTextPattern.MatchString(text) {
+		return "", fmt.Errorf("cannot decode text to decimal, text: %s", text)
+	}
+	return json.Number(text), nil
+}
+
+// CastDecodeMTValueToBigInt 将值转换成*big.Int类型；字符串按十进制解析，不经过float64转换。
+// 如果值不是整数，返回错误。
+func CastDecodeMTValueToBigInt(mtValue flux.MTValue) (*big.Int, error) {
+	switch v := mtValue.Value.(type) {
+	case *big.Int:
+		return v, nil
+	case float32, float64:
+		f := cast.ToFloat64(v)
+		if math.IsNaN(f) || math.IsInf(f, 0) || f != math.Trunc(f) {
+			return nil, fmt.Errorf("cannot decode non-integer number to bigint, value: %v", v)
+		}
+		i, _ := big.NewFloat(f).Int(nil)
+		return i, nil
+	}
+	text, err := CastDecodeMTValueToString(mtValue)
+	if nil != err {
+		return nil, err
+	}
+	i, ok := new(big.Int).SetString(strings.TrimPrefix(strings.TrimSpace(text), "+"), 10)
+	if !ok {
+		return nil, fmt.Errorf("cannot decode text to bigint, text: %s", text)
+	}
+	return i, nil
+}
+
+// CastDecodeMTValueToBytes 将值转换成[]byte类型：字节数组和请求体等流式数据按原始字节返回，字符串按Base64解码，
+// 支持标准和URL编码，以及无填充的格式。如果字符串不是有效的Base64编码，返回错误。
+func CastDecodeMTValueToBytes(mtValue flux.MTValue) ([]byte, error) {
+	switch v := mtValue.Value.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case string:
+		text := strings.TrimSpace(v)
+		for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+			if data, err := encoding.DecodeString(text); nil == err {
+				return data, nil
+			}
+		}
+		return nil, fmt.Errorf("cannot decode base64 text to bytes, text: %s", text)
+	case io.Reader:
+		return toByteArray(v)
+	default:
+		return nil, fmt.Errorf("unsupported type to bytes, value.type: %T", v)
+	}
+}
+
+// CastDecodeMTValueToEnum 将值转换为枚举常量：值须与允许的常量之一完全相同（区分大小写），空值返回nil。
+// 如果值不在允许的常量中，返回状态码为400的 *flux.ServeError，错误消息包含无效的值和允许的常量；未声明允许的常量时返回错误。
+func CastDecodeMTValueToEnum(mtValue flux.MTValue, allowed []string) (interface{}, error) {
+	if 0 == len(allowed) {
+		return nil, errors.New("enum allowed values not declared")
+	}
+	text, err := CastDecodeMTValueToString(mtValue)
+	if nil != err {
+		return nil, err
+	}
+	text = strings.TrimSpace(text)
+	if "" == text {
+		return nil, nil
+	}
+	for _, v := range allowed {
+		if v == text {
+			return text, nil
+		}
+	}
+	return nil, &flux.ServeError{
+		StatusCode: flux.StatusBadRequest,
+		ErrorCode:  flux.ErrorCodeRequestInvalid,
+		Message:    fmt.Sprintf("%s: %s, allowed: [%s]", flux.ErrorMessageArgumentEnumInvalid, text, strings.Join(allowed, ", ")),
+	}
+}
+
+// CastDecodeMTValueToTypedMap 将值转换为Map，并使用值类型（genericTypes[1]）的解析函数解析每个值，例如 Map<String,Long>；
+// Key按字符串处理。多值Map（url.Values）的值类型不是列表时，使用每个Key的第一个值。值使用默认注册表的解析函数解析。
+// 如果值无法转换为Map或者值解析异常，返回错误。
+func CastDecodeMTValueToTypedMap(genericTypes []string, mtValue flux.MTValue) (map[string]interface{}, error) {
+	return castDecodeMTValueToTypedMap(ext.Default(), genericTypes, mtValue)
+}
+
+func castDecodeMTValueToTypedMap(r *ext.Registry, genericTypes []string, mtValue flux.MTValue) (map[string]interface{}, error) {
+	v, err := resolveTypedValue(r, flux.JavaUtilMapClassName, genericTypes, mtValue, 0)
+	if nil != err {
+		return nil, err
+	}
+	m, _ := v.(map[string]interface{})
+	return m, nil
+}
+
+// CastDecodeMTValueToPOJO 按已注册的POJO字段定义，将值（Map或JSON文本）解析为字段名到字段类型值的Map；
+// 字段按其声明的类型递归解析，未声明的字段被忽略，缺失的字段不写入结果。POJO类型从默认注册表加载。
+// 如果POJO类型未注册或者解析异常，返回错误。
+func CastDecodeMTValueToPOJO(mtValue flux.MTValue, class string) (map[string]interface{}, error) {
+	return castDecodeMTValueToPOJO(ext.Default(), mtValue, class)
+}
+
+func castDecodeMTValueToPOJO(r *ext.Registry, mtValue flux.MTValue, class string) (map[string]interface{}, error) {
+	if _, ok := r.LoadPOJOType(class); !ok {
+		return nil, fmt.Errorf("pojo type not registered, class: %s", class)
+	}
+	v, err := resolveTypedValue(r, class, nil, mtValue, 0)
+	if nil != err {
+		return nil, err
+	}
+	return v.(map[string]interface{}), nil
+}
+
+// resolveTypedValue 按类型解析值：POJO类型按字段定义解析；List/Map类型按泛型类型解析元素；
+// 其它类型使用已注册的解析函数，未注册解析函数的类型返回原值
+func resolveTypedValue(r *ext.Registry, class string, generic []string, mtValue flux.MTValue, depth int) (interface{}, error) {
+	if depth > maxPOJOResolveDepth {
+		return nil, fmt.Errorf("pojo resolve depth exceeds %d, class: %s", maxPOJOResolveDepth, class)
+	}
+	if nil == mtValue.Value {
+		return nil, nil
+	}
+	if fields, ok := r.LoadPOJOType(class); ok {
+		values, err := CastDecodeMTValueToStringMap(mtValue)
+		if nil != err {
+			return nil, fmt.Errorf("pojo: %s, %w", class, err)
+		}
+		out := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			raw, ok := values[field.Name]
+			if !ok {
+				continue
+			}
+			value, err := resolveTypedValue(r, field.Class, field.Generic, typedMTValueOf(raw), depth+1)
+			if nil != err {
+				return nil, fmt.Errorf("pojo: %s, field: %s, %w", class, field.Name, err)
+			}
+			out[field.Name] = value
+		}
+		return out, nil
+	}
+	switch strings.ToLower(class) {
+	case "slice", "list", strings.ToLower(flux.JavaUtilListClassName):
+		if len(generic) > 0 {
+			items, err := castDecodeMTValueToItems(mtValue)
+			if nil != err {
+				return nil, err
+			}
+			out := make([]interface{}, len(items))
+			for i, item := range items {
+				if out[i], err = resolveTypedValue(r, generic[0], generic[1:], typedMTValueOf(item), depth+1); nil != err {
+					return nil, fmt.Errorf("index: %d, %w", i, err)
+				}
+			}
+			return out, nil
+		}
+	case "map", strings.ToLower(flux.JavaUtilMapClassName):
+		if len(generic) > 1 {
+			values, err := castDecodeMTValueToMapItems(mtValue, generic[1])
+			if nil != err {
+				return nil, err
+			}
+			out := make(map[string]interface{}, len(values))
+			for key, item := range values {
+				if out[key], err = resolveTypedValue(r, generic[1], generic[2:], typedMTValueOf(item), depth+1); nil != err {
+					return nil, fmt.Errorf("key: %s, %w", key, err)
+				}
+			}
+			return out, nil
+		}
+	}
+	if resolver := r.LoadMTValueResolver(class); nil != resolver {
+		return resolver(mtValue, class, generic)
+	}
+	return mtValue.Value, nil
+}
+
+// castDecodeMTValueToMapItems 将值转换为Map；多值Map（url.Values）的值类型不是列表时，使用每个Key的第一个值
+func castDecodeMTValueToMapItems(mtValue flux.MTValue, valueClass string) (map[string]interface{}, error) {
+	var multi map[string][]string
+	switch v := mtValue.Value.(type) {
+	case url.Values:
+		multi = v
+	case map[string][]string:
+		multi = v
+	default:
+		return CastDecodeMTValueToStringMap(mtValue)
+	}
+	list := false
+	switch strings.ToLower(valueClass) {
+	case "slice", "list", strings.ToLower(flux.JavaUtilListClassName):
+		list = true
+	}
+	out := make(map[string]interface{}, len(multi))
+	for key, values := range multi {
+		if list {
+			out[key] = values
+		} else if len(values) > 0 {
+			out[key] = values[0]
+		}
+	}
+	return out, nil
+}
+
+// castDecodeMTValueToItems 将切片或JSON数组文本转换为[]any类型
+func castDecodeMTValueToItems(mtValue flux.MTValue) ([]interface{}, error) {
+	if rv := reflect.ValueOf(mtValue.Value); reflect.Slice == rv.Kind() && reflect.Uint8 != rv.Type().Elem().Kind() {
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = rv.Index(i).Interface()
+		}
+		return items, nil
+	}
+	data, err := toByteArray(mtValue.Value)
+	if nil != err {
+		return nil, err
+	}
+	var items []interface{}
+	if err := ext.JSONUnmarshal(data, &items); nil != err {
+		return nil, fmt.Errorf("cannot decode text to list, text: %s, error: %w", data, err)
+	}
+	return items, nil
+}
+
+// typedMTValueOf 包装已解码的字段值：字符串按文本值，其它按对象值
+func typedMTValueOf(value interface{}) flux.MTValue {
+	if text, ok := value.(string); ok {
+		return flux.WrapStringMTValue(text)
+	}
+	return flux.WrapObjectMTValue(value)
+}
+
+func timeOfUnix(layout string, n int64) time.Time {
+	if TimeLayoutUnixMillis == layout {
+		return time.Unix(0, n*int64(time.Millisecond))
+	}
+	return time.Unix(n, 0)
 }
 
 func toByteArray(v interface{}) ([]byte, error) {
@@ -219,9 +695,9 @@ func JSONBytesFromQueryString(queryStr []byte) ([]byte, error) {
 			for i, val := range values {
 				copied[i] = "\"" + string(JSONStringValueEncode(&val)) + "\""
 			}
-			fields = append(fields, "\""+key+"\":["+strings.Join(copied, ",")+"]")
+			fields = append(fields, "\""+string(JSONStringValueEncode(&key))+"\":["+strings.Join(copied, ",")+"]")
 		} else {
-			fields = append(fields, "\""+key+"\":\""+string(JSONStringValueEncode(&values[0]))+"\"")
+			fields = append(fields, "\""+string(JSONStringValueEncode(&key))+"\":\""+string(JSONStringValueEncode(&values[0]))+"\"")
 		}
 	}
 	bf := new(bytes.Buffer)
@@ -231,6 +707,33 @@ func JSONBytesFromQueryString(queryStr []byte) ([]byte, error) {
 	return bf.Bytes(), nil
 }
 
+// JSONStringValueEncode 按JSON字符串规则转义（不包含首尾引号）：转义引号、反斜杠和控制字符；
+// 非法的UTF-8字节替换为U+FFFD
 func JSONStringValueEncode(str *string) []byte {
-	return []byte(strings.Replace(*str, `"`, `\"`, -1))
+	out := make([]byte, 0, len(*str)+8)
+	buf := make([]byte, utf8.UTFMax)
+	for _, r := range *str {
+		switch r {
+		case '"', '\\':
+			out = append(out, '\\', byte(r))
+		case '\n':
+			out = append(out, '\\', 'n')
+		case '\r':
+			out = append(out, '\\', 'r')
+		case '\t':
+			out = append(out, '\\', 't')
+		default:
+			if r < 0x20 {
+				out = append(out, `\u00`...)
+				if r < 0x10 {
+					out = append(out, '0')
+				}
+				out = strconv.AppendInt(out, int64(r), 16)
+			} else {
+				n := utf8.EncodeRune(buf, r)
+				out = append(out, buf[:n]...)
+			}
+		}
+	}
+	return out
 }
@@ -0,0 +1,38 @@
+//go:build go1.18
+// +build go1.18
+
+package support
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bytepowered/flux"
+)
+
+func FuzzJSONBytesFromQueryString(f *testing.F) {
+	for _, seed := range []string{`foo=bar&abc=001&foo=value&data="abc"`, `a=%5C&b=%0A`, `k%22=v`, `a=%ff%fe`} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, query []byte) {
+		data, err := JSONBytesFromQueryString(query)
+		if nil != err {
+			return
+		}
+		if !json.Valid(data) {
+			t.Fatalf("invalid json: %q, query: %q", data, query)
+		}
+	})
+}
+
+func FuzzCastDecodeMTValueToStringMap(f *testing.F) {
+	for _, seed := range []string{`{"k":1}`, `k=1&e=a`, `a=%5C%22`, `{`} {
+		f.Add(seed, "application/x-www-form-urlencoded")
+		f.Add(seed, "application/json")
+		f.Add(seed, flux.ValueMediaTypeGoString)
+	}
+	f.Fuzz(func(t *testing.T, value string, mediaType string) {
+		_, _ = CastDecodeMTValueToStringMap(flux.MTValue{Value: value, MediaType: mediaType})
+		_, _ = CastDecodeMTValueToStringMap(flux.MTValue{Value: []byte(value), MediaType: mediaType})
+	})
+}
@@ -1,11 +1,14 @@
 package support
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bytepowered/flux"
 	"github.com/bytepowered/flux/ext"
@@ -28,6 +31,28 @@ func Test_QueryToJsonBytes(t *testing.T) {
 	fmt.Println(hmap)
 }
 
+func Test_QueryToJsonBytesEscape(t *testing.T) {
+	cases := []struct {
+		query    string
+		key      string
+		expected string
+	}{
+		{query: `a=%5C`, key: "a", expected: "\\"},
+		{query: `a=%5C%22`, key: "a", expected: "\\\""},
+		{query: `a=x%0Ay%09z%01`, key: "a", expected: "x\ny\tz\x01"},
+		{query: `k%22%5C=v`, key: "k\"\\", expected: "v"},
+		{query: `a=%ff`, key: "a", expected: "\ufffd"},
+	}
+	assert := assert2.New(t)
+	for _, tc := range cases {
+		jb, err := JSONBytesFromQueryString([]byte(tc.query))
+		assert.NoError(err)
+		hmap := make(map[string]interface{})
+		assert.NoError(json.Unmarshal(jb, &hmap), "query: %s, json: %s", tc.query, jb)
+		assert.Equal(tc.expected, hmap[tc.key], "query: %s", tc.query)
+	}
+}
+
 func Benchmark_QueryToJsonBytes(b *testing.B) {
 	query := `foo=bar&abc=001&foo=value&data="abc"`
 	for i := 0; i < b.N; i++ {
@@ -53,6 +78,260 @@ func TestValueToArrayList_String(t *testing.T) {
 	assert.Equal([]interface{}{"123"}, a1)
 }
 
+func TestValueToArrayList_MultiValues(t *testing.T) {
+	assert := assert2.New(t)
+	ext.StoreSerializer(ext.TypeNameSerializerJson, flux.NewJsonSerializer())
+	cases := []struct {
+		value  flux.MTValue
+		expect interface{}
+	}{
+		{value: flux.WrapStringMTValue("1, 2,3"), expect: []interface{}{1, 2, 3}},
+		{value: flux.WrapStringMTValue("[4,5]"), expect: []interface{}{4, 5}},
+		{value: flux.WrapStrListMTValue([]string{"1", "2,3"}), expect: []interface{}{1, 2, 3}},
+		{value: flux.WrapStrValuesMapMTValue(url.Values{"ids": []string{"7", "8"}}), expect: []interface{}{7, 8}},
+		{value: flux.WrapObjectMTValue([]interface{}{"9", 10.0}), expect: []interface{}{9, 10}},
+	}
+	for _, c := range cases {
+		v, err := CastDecodeMTValueToSliceList([]string{"int"}, c.value)
+		assert.NoError(err, "value: %v", c.value.Value)
+		assert.Equal(c.expect, v, "value: %v", c.value.Value)
+	}
+	v, err := CastDecodeMTValueToSliceList(nil, flux.WrapStringMTValue("a,b"))
+	assert.NoError(err)
+	assert.Equal([]interface{}{"a", "b"}, v)
+	v, err = CastDecodeMTValueToSliceList(nil, flux.WrapStrListMTValue([]string{"a,b"}))
+	assert.NoError(err)
+	assert.Equal([]string{"a,b"}, v)
+	_, err = CastDecodeMTValueToSliceList([]string{"time"}, flux.WrapStringMTValue("2021-03-04,03/04/2021"))
+	assert.Error(err)
+}
+
+func TestCastDecodeMTValueToTypedMap(t *testing.T) {
+	assert := assert2.New(t)
+	ext.StoreSerializer(ext.TypeNameSerializerJson, flux.NewJsonSerializer())
+	resolver := ext.LoadMTValueResolver(flux.JavaUtilMapClassName)
+	v, err := resolver(flux.WrapStringMTValue(`{"a":"1","b":2}`), flux.JavaUtilMapClassName, []string{flux.JavaLangStringClassName, flux.JavaLangLongClassName})
+	assert.NoError(err)
+	assert.Equal(map[string]interface{}{"a": int64(1), "b": int64(2)}, v)
+	v, err = resolver(flux.WrapStrValuesMapMTValue(url.Values{"on": {"true", "false"}, "off": {"0"}}), "map", []string{"string", "boolean"})
+	assert.NoError(err)
+	assert.Equal(map[string]interface{}{"on": true, "off": false}, v)
+	v, err = resolver(flux.WrapStrValuesMapMTValue(url.Values{"ids": {"1", "2"}}), "map", []string{"string", "list", "int"})
+	assert.NoError(err)
+	assert.Equal(map[string]interface{}{"ids": []interface{}{1, 2}}, v)
+	// 未声明值类型时保持原值
+	v, err = resolver(flux.WrapStringMTValue(`{"a":"1"}`), "map", nil)
+	assert.NoError(err)
+	assert.Equal(map[string]interface{}{"a": "1"}, v)
+	_, err = CastDecodeMTValueToTypedMap([]string{"string", "time"}, flux.WrapStrMapMTValue(map[string]interface{}{"at": "03/04/2021"}))
+	assert.Error(err)
+}
+
+func TestCastDecodeMTValueToBytes(t *testing.T) {
+	assert := assert2.New(t)
+	cases := []flux.MTValue{
+		flux.WrapStringMTValue("aGVsbG8="),
+		flux.WrapStringMTValue("aGVsbG8"),
+		flux.WrapObjectMTValue([]byte("hello")),
+		{Value: ioutil.NopCloser(strings.NewReader("hello")), MediaType: "application/octet-stream"},
+	}
+	for _, c := range cases {
+		v, err := CastDecodeMTValueToBytes(c)
+		assert.NoError(err, "value: %v", c.Value)
+		assert.Equal([]byte("hello"), v, "value: %v", c.Value)
+	}
+	v, err := ext.LoadMTValueResolver(flux.JavaByteArrayClassName)(flux.WrapStringMTValue("-_8"), flux.JavaByteArrayClassName, nil)
+	assert.NoError(err)
+	assert.Equal([]byte{0xfb, 0xff}, v)
+	_, err = CastDecodeMTValueToBytes(flux.WrapStringMTValue("not base64!"))
+	assert.Error(err)
+}
+
+func TestCastDecodeMTValueToEnum(t *testing.T) {
+	assert := assert2.New(t)
+	allowed := []string{"RED", "GREEN"}
+	v, err := CastDecodeMTValueToEnum(flux.WrapStringMTValue(" GREEN "), allowed)
+	assert.NoError(err)
+	assert.Equal("GREEN", v)
+	v, err = CastDecodeMTValueToEnum(flux.WrapStringMTValue(""), allowed)
+	assert.NoError(err)
+	assert.Nil(v)
+	_, err = CastDecodeMTValueToEnum(flux.WrapStringMTValue("red"), allowed)
+	if serr, ok := err.(*flux.ServeError); assert.True(ok, "must be serve error") {
+		assert.Equal(flux.StatusBadRequest, serr.StatusCode)
+		assert.Equal("ARGUMENT:ENUM:INVALID: red, allowed: [RED, GREEN]", serr.Message)
+	}
+	_, err = CastDecodeMTValueToEnum(flux.WrapStringMTValue("RED"), nil)
+	assert.Error(err)
+}
+
+//// Time
+
+func TestCastDecodeMTValueToTime(t *testing.T) {
+	assert := assert2.New(t)
+	expected := time.Date(2021, 3, 4, 5, 6, 7, 0, time.Local)
+	cases := []flux.MTValue{
+		{Value: expected.Format(time.RFC3339), MediaType: flux.ValueMediaTypeGoString},
+		{Value: "2021-03-04 05:06:07", MediaType: flux.ValueMediaTypeGoString},
+		{Value: "2021-03-04T05:06:07", MediaType: "text"},
+		{Value: fmt.Sprintf("%d", expected.UnixNano()/int64(time.Millisecond)), MediaType: flux.ValueMediaTypeGoString},
+		{Value: float64(expected.UnixNano() / int64(time.Millisecond)), MediaType: flux.ValueMediaTypeGoObject},
+		{Value: expected, MediaType: flux.ValueMediaTypeGoObject},
+	}
+	for _, c := range cases {
+		v, err := CastDecodeMTValueToTime(c, timeValueLayouts)
+		assert.NoError(err, "value: %v", c.Value)
+		assert.True(expected.Equal(v), "value: %v, time: %s", c.Value, v)
+	}
+	v, err := CastDecodeMTValueToTime(flux.MTValue{Value: int64(1614834367)}, []string{TimeLayoutUnix})
+	assert.NoError(err)
+	assert.Equal(int64(1614834367), v.Unix())
+	_, err = CastDecodeMTValueToTime(flux.MTValue{Value: "03/04/2021"}, timeValueLayouts)
+	assert.Error(err)
+	_, err = CastDecodeMTValueToTime(flux.MTValue{Value: 123}, []string{time.RFC3339})
+	assert.Error(err)
+	// 注册的Java类型
+	resolver := ext.LoadMTValueResolver(flux.JavaUtilDateClassName)
+	assert.NotNil(resolver)
+	date, err := resolver(flux.MTValue{Value: "2021-03-04", MediaType: "text"}, flux.JavaUtilDateClassName, nil)
+	assert.NoError(err)
+	assert.True(time.Date(2021, 3, 4, 0, 0, 0, 0, time.Local).Equal(date.(time.Time)))
+}
+
+//// BigDecimal / BigInteger
+
+func TestCastDecodeMTValueToBigDecimal(t *testing.T) {
+	assert := assert2.New(t)
+	cases := map[interface{}]json.Number{
+		"12345678901234567890.123456789": "12345678901234567890.123456789",
+		" +1.10 ":                        "1.10",
+		"-1.5E+10":                       "-1.5E+10",
+		0.1:                              "0.1",
+		int64(42):                        "42",
+	}
+	for in, expected := range cases {
+		v, err := CastDecodeMTValueToBigDecimal(flux.MTValue{Value: in, MediaType: flux.ValueMediaTypeGoObject})
+		assert.NoError(err, "value: %v", in)
+		assert.Equal(expected, v, "value: %v", in)
+	}
+	for _, in := range []interface{}{"abc", "1.", ".5", "1/2", "NaN"} {
+		_, err := CastDecodeMTValueToBigDecimal(flux.MTValue{Value: in})
+		assert.Error(err, "value: %v", in)
+	}
+	data, err := json.Marshal(map[string]interface{}{"amount": json.Number("12345678901234567890.10")})
+	assert.NoError(err)
+	assert.Equal(`{"amount":12345678901234567890.10}`, string(data))
+}
+
+func TestCastDecodeMTValueToBigInt(t *testing.T) {
+	assert := assert2.New(t)
+	v, err := CastDecodeMTValueToBigInt(flux.MTValue{Value: "123456789012345678901234567890"})
+	assert.NoError(err)
+	assert.Equal("123456789012345678901234567890", v.String())
+	v, err = CastDecodeMTValueToBigInt(flux.MTValue{Value: float64(1e18)})
+	assert.NoError(err)
+	assert.Equal("1000000000000000000", v.String())
+	v, err = CastDecodeMTValueToBigInt(flux.MTValue{Value: -42})
+	assert.NoError(err)
+	assert.Equal("-42", v.String())
+	for _, in := range []interface{}{"1.5", 1.5, "abc"} {
+		_, err := CastDecodeMTValueToBigInt(flux.MTValue{Value: in})
+		assert.Error(err, "value: %v", in)
+	}
+	resolver := ext.LoadMTValueResolver(flux.JavaMathBigIntegerClassName)
+	assert.NotNil(resolver)
+	i, err := resolver(flux.MTValue{Value: "99999999999999999999", MediaType: "text"}, flux.JavaMathBigIntegerClassName, nil)
+	assert.NoError(err)
+	assert.Equal("99999999999999999999", fmt.Sprint(i))
+}
+
+//// POJO
+
+func TestCastDecodeMTValueToPOJO(t *testing.T) {
+	assert := assert2.New(t)
+	ext.StoreSerializer(ext.TypeNameSerializerJson, flux.NewJsonSerializer())
+	ext.RegisterPOJOType("com.foo.Item", []flux.POJOField{
+		{Name: "sku", Class: flux.JavaLangStringClassName},
+		{Name: "count", Class: flux.JavaLangIntegerClassName},
+	})
+	ext.RegisterPOJOType("com.foo.Order", []flux.POJOField{
+		{Name: "id", Class: flux.JavaLangLongClassName},
+		{Name: "amount", Class: flux.JavaMathBigDecimalClassName},
+		{Name: "paid", Class: flux.JavaLangBooleanClassName},
+		{Name: "buyer", Class: "com.foo.Buyer"},
+		{Name: "items", Class: flux.JavaUtilListClassName, Generic: []string{"com.foo.Item"}},
+		{Name: "tags", Class: flux.JavaUtilMapClassName, Generic: []string{flux.JavaLangStringClassName, flux.JavaLangIntegerClassName}},
+	})
+	ext.RegisterPOJOType("com.foo.Buyer", []flux.POJOField{
+		{Name: "name", Class: flux.JavaLangStringClassName},
+	})
+	body := `{"id": "1001", "amount": "12.30", "paid": "true", "ignored": 1,
+		"buyer": {"name": "yongjia", "age": 18},
+		"items": [{"sku": "A-1", "count": "2"}, {"sku": "B-2", "count": 3}],
+		"tags": {"vip": "1"}}`
+	resolver := ext.LoadMTValueDefaultResolver()
+	v, err := resolver(flux.MTValue{Value: body, MediaType: "application/json"}, "com.foo.Order", nil)
+	assert.NoError(err)
+	assert.Equal(map[string]interface{}{
+		"id":     int64(1001),
+		"amount": json.Number("12.30"),
+		"paid":   true,
+		"buyer":  map[string]interface{}{"name": "yongjia"},
+		"items": []interface{}{
+			map[string]interface{}{"sku": "A-1", "count": 2},
+			map[string]interface{}{"sku": "B-2", "count": 3},
+		},
+		"tags": map[string]interface{}{"vip": 1},
+	}, v)
+	// List参数的元素为POJO类型
+	list, err := ext.LoadMTValueResolver(flux.JavaUtilListClassName)(
+		flux.MTValue{Value: `[{"sku": "C-3", "count": "4"}]`, MediaType: flux.ValueMediaTypeGoString}, flux.JavaUtilListClassName, []string{"com.foo.Item"})
+	assert.NoError(err)
+	assert.Equal([]interface{}{map[string]interface{}{"sku": "C-3", "count": 4}}, list)
+	// 字段类型解析失败
+	_, err = resolver(flux.MTValue{Value: `{"amount": "abc"}`, MediaType: "application/json"}, "com.foo.Order", nil)
+	assert.Error(err)
+	// 未注册的类型
+	_, err = CastDecodeMTValueToPOJO(flux.MTValue{Value: "{}", MediaType: "application/json"}, "com.foo.Unknown")
+	assert.Error(err)
+}
+
+func TestCastDecodeMTValueToPOJO_SelfReference(t *testing.T) {
+	ext.RegisterPOJOType("com.foo.Node", []flux.POJOField{{Name: "next", Class: "com.foo.Node"}})
+	node := map[string]interface{}{}
+	node["next"] = node
+	_, err := CastDecodeMTValueToPOJO(flux.WrapObjectMTValue(node), "com.foo.Node")
+	assert2.Error(t, err)
+}
+
+func TestCastDecodeMTValueToPOJO_ClonedRegistry(t *testing.T) {
+	assert := assert2.New(t)
+	ext.StoreSerializer(ext.TypeNameSerializerJson, flux.NewJsonSerializer())
+	registry := ext.Default().Clone()
+	registry.RegisterPOJOType("com.foo.Scoped", []flux.POJOField{{Name: "n", Class: flux.JavaLangIntegerClassName}})
+	_, ok := ext.LoadPOJOType("com.foo.Scoped")
+	assert.False(ok, "pojo type must be scoped to the cloned registry")
+	v, err := registry.LoadMTValueDefaultResolver()(flux.MTValue{Value: `{"n": "7"}`, MediaType: "application/json"}, "com.foo.Scoped", nil)
+	assert.NoError(err)
+	assert.Equal(map[string]interface{}{"n": 7}, v)
+	// 请求Context绑定的注册表
+	ctx := &registryValuesContext{ValuesContext: NewValuesContext(nil).(*ValuesContext), registry: registry}
+	list, err := DefaultArgumentValueResolveFunc(flux.MTValue{Value: `[{"n": 8}]`, MediaType: flux.ValueMediaTypeGoString},
+		flux.Argument{Name: "list", Class: flux.JavaUtilListClassName, Generic: []string{"com.foo.Scoped"}}, ctx)
+	assert.NoError(err)
+	assert.Equal([]interface{}{map[string]interface{}{"n": 8}}, list)
+}
+
+// registryValuesContext 绑定扩展组件注册表的测试Context
+type registryValuesContext struct {
+	*ValuesContext
+	registry *ext.Registry
+}
+
+func (c *registryValuesContext) Context() context.Context {
+	return ext.WithRegistry(context.Background(), c.registry)
+}
+
 //// StringMap
 
 func TestCastToStringMapUnsupportedError(t *testing.T) {
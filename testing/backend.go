@@ -0,0 +1,85 @@
+package fluxtest
+
+import (
+	"github.com/bytepowered/flux"
+	"net/http"
+	"sync"
+)
+
+var _ flux.BackendTransport = new(FakeBackend)
+
+// FakeResponseFunc 自定义一次后端调用的响应
+type FakeResponseFunc func(service flux.BackendService, ctx flux.Context) (interface{}, *flux.ServeError)
+
+// FakeBackend 按编排顺序返回响应的Backend；编排的响应用完后，重复最后一个响应。
+// 未编排响应时，返回200和空响应体。并发安全。
+type FakeBackend struct {
+	mu        sync.Mutex
+	responses []FakeResponseFunc
+	calls     []flux.BackendService
+}
+
+func NewFakeBackend() *FakeBackend {
+	return &FakeBackend{
+		responses: make([]FakeResponseFunc, 0, 4),
+	}
+}
+
+// Respond 编排一个成功响应
+func (b *FakeBackend) Respond(body interface{}) *FakeBackend {
+	return b.RespondFunc(func(flux.BackendService, flux.Context) (interface{}, *flux.ServeError) {
+		return body, nil
+	})
+}
+
+// Fail 编排一个错误响应
+func (b *FakeBackend) Fail(err *flux.ServeError) *FakeBackend {
+	return b.RespondFunc(func(flux.BackendService, flux.Context) (interface{}, *flux.ServeError) {
+		return nil, err
+	})
+}
+
+// RespondFunc 编排一个自定义响应
+func (b *FakeBackend) RespondFunc(f FakeResponseFunc) *FakeBackend {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.responses = append(b.responses, f)
+	return b
+}
+
+// Calls 返回已调用的后端服务列表
+func (b *FakeBackend) Calls() []flux.BackendService {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]flux.BackendService, len(b.calls))
+	copy(out, b.calls)
+	return out
+}
+
+func (b *FakeBackend) Exchange(ctx flux.Context) *flux.ServeError {
+	body, err := b.Invoke(ctx.Endpoint().Service, ctx)
+	if nil != err {
+		return err
+	}
+	ctx.Response().SetStatusCode(http.StatusOK)
+	ctx.Response().SetBody(body)
+	return nil
+}
+
+func (b *FakeBackend) Invoke(service flux.BackendService, ctx flux.Context) (interface{}, *flux.ServeError) {
+	b.mu.Lock()
+	index := len(b.calls)
+	b.calls = append(b.calls, service)
+	var f FakeResponseFunc
+	if n := len(b.responses); n > 0 {
+		if index >= n {
+			index = n - 1
+		}
+		f = b.responses[index]
+	}
+	b.mu.Unlock()
+	if nil == f {
+		return nil, nil
+	}
+	return f(service, ctx)
+}
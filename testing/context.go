@@ -0,0 +1,29 @@
+package fluxtest
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/server"
+	"github.com/bytepowered/flux/support"
+)
+
+const (
+	// 测试Context的默认请求ID
+	DefaultRequestId = "fluxtest-request-id"
+)
+
+// NewContext 基于WebContext和Endpoint创建与网关运行时一致的Context；客户端IP为请求的远端地址。
+// 通过 SetAttribute/SetValue 控制Filter读取的属性和值。
+func NewContext(webc flux.WebContext, endpoint flux.Endpoint) *server.WrappedContext {
+	ctx := server.NewContextWrapper().(*server.WrappedContext)
+	ctx.Reattach(DefaultRequestId, new(support.TrustedProxies).ClientIP(webc), webc, &endpoint)
+	return ctx
+}
+
+// NewContextWithAttributes 创建Context，并设置Attributes
+func NewContextWithAttributes(webc flux.WebContext, endpoint flux.Endpoint, attributes map[string]interface{}) *server.WrappedContext {
+	ctx := NewContext(webc, endpoint)
+	for name, value := range attributes {
+		ctx.SetAttribute(name, value)
+	}
+	return ctx
+}
@@ -0,0 +1,31 @@
+package fluxtest
+
+import (
+	"github.com/bytepowered/flux"
+)
+
+// InitFilter 使用指定的配置项初始化Filter；Filter未实现Initializer接口时直接返回
+func InitFilter(filter flux.Filter, configs map[string]interface{}) error {
+	init, ok := filter.(flux.Initializer)
+	if !ok {
+		return nil
+	}
+	config := flux.NewConfiguration(nil)
+	for key, value := range configs {
+		config.Set(key, value)
+	}
+	return init.Init(config)
+}
+
+// RunFilters 按顺序执行Filter链，最后调用Backend；与网关路由执行Filter链的方式一致
+func RunFilters(ctx flux.Context, backend flux.BackendTransport, filters ...flux.Filter) *flux.ServeError {
+	return RunFiltersWith(ctx, backend.Exchange, filters...)
+}
+
+// RunFiltersWith 按顺序执行Filter链，最后调用next
+func RunFiltersWith(ctx flux.Context, next flux.FilterHandler, filters ...flux.Filter) *flux.ServeError {
+	for i := len(filters) - 1; i >= 0; i-- {
+		next = filters[i].DoFilter(next)
+	}
+	return next(ctx)
+}
@@ -0,0 +1,88 @@
+package fluxtest
+
+import (
+	"github.com/bytepowered/flux"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+type headerCheckFilter struct {
+	header string
+}
+
+func (f *headerCheckFilter) Init(config *flux.Configuration) error {
+	f.header = config.GetString("header")
+	return nil
+}
+
+func (*headerCheckFilter) TypeId() string {
+	return "HeaderCheckFilter"
+}
+
+func (f *headerCheckFilter) DoFilter(next flux.FilterHandler) flux.FilterHandler {
+	return func(ctx flux.Context) *flux.ServeError {
+		if "" == ctx.Request().HeaderValue(f.header) {
+			return &flux.ServeError{StatusCode: http.StatusUnauthorized}
+		}
+		ctx.SetAttribute("checked", true)
+		return next(ctx)
+	}
+}
+
+func TestRunFilters(t *testing.T) {
+	assert := assert2.New(t)
+	filter := new(headerCheckFilter)
+	assert.NoError(InitFilter(filter, map[string]interface{}{"header": "X-Token"}))
+	backend := NewFakeBackend().
+		Fail(&flux.ServeError{StatusCode: http.StatusBadGateway}).
+		Respond("ok")
+	cases := []struct {
+		token      string
+		statusCode int
+		body       interface{}
+		calls      int
+	}{
+		{token: "", statusCode: http.StatusUnauthorized, calls: 0},
+		{token: "t", statusCode: http.StatusBadGateway, calls: 1},
+		{token: "t", statusCode: http.StatusOK, body: "ok", calls: 2},
+		{token: "t", statusCode: http.StatusOK, body: "ok", calls: 3},
+	}
+	for _, tc := range cases {
+		webc := NewWebContext(http.MethodGet, "/users?id=1").
+			WithHeader("X-Token", tc.token).
+			WithRemoteAddr("10.0.0.1:1234").
+			Build()
+		ctx := NewContext(webc, flux.Endpoint{HttpMethod: http.MethodGet, HttpPattern: "/users"})
+		assert.Equal("10.0.0.1", ctx.ClientIP())
+		assert.Equal("1", ctx.Request().QueryValue("id"))
+		err := RunFilters(ctx, backend, filter)
+		if nil != err {
+			assert.Equal(tc.statusCode, err.StatusCode)
+		} else {
+			assert.Equal(tc.statusCode, ctx.Response().StatusCode())
+			assert.Equal(tc.body, ctx.Response().Body())
+			checked, _ := ctx.GetAttribute("checked")
+			assert.Equal(true, checked)
+		}
+		assert.Equal(tc.calls, len(backend.Calls()))
+	}
+}
+
+func TestWebContext_Body(t *testing.T) {
+	assert := assert2.New(t)
+	webc := NewWebContext(http.MethodPost, "/form").
+		WithFormValue("name", "flux").
+		WithPathValue("id", "7").
+		Build()
+	assert.Equal("flux", webc.FormValue("name"))
+	assert.Equal("7", webc.PathValue("id"))
+	reader, err := webc.RequestBodyReader()
+	assert.NoError(err)
+	buf := make([]byte, 64)
+	n, _ := reader.Read(buf)
+	assert.Equal("name=flux", string(buf[:n]))
+	assert.NoError(webc.Write(http.StatusCreated, flux.MIMEApplicationJSONCharsetUTF8, []byte(`{}`)))
+	assert.Equal(http.StatusCreated, webc.Recorder().Code)
+	assert.Equal(`{}`, webc.Recorder().Body.String())
+}
@@ -0,0 +1,267 @@
+package fluxtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/registry"
+	"github.com/bytepowered/flux/server"
+	_ "github.com/bytepowered/flux/webecho"
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	// 设置为true时，使用实际响应重新生成黄金文件
+	EnvGoldenUpdate = "FLUX_GOLDEN_UPDATE"
+	// 黄金测试使用的EndpointRegistry ID
+	EndpointRegistryIdGolden = "fluxtest-golden"
+)
+
+const (
+	goldenFileConfig    = "config.json"
+	goldenFileEndpoints = "endpoints.json"
+	goldenFileBackends  = "backends.json"
+	goldenDirCases      = "cases"
+	goldenExtCase       = ".json"
+	goldenExtGolden     = ".golden"
+)
+
+// GoldenRequest 黄金测试用例的请求定义
+type GoldenRequest struct {
+	Method string            `json:"method"`
+	Path   string            `json:"path"`
+	Header map[string]string `json:"header"`
+	Body   string            `json:"body"`
+	// Compare 需要对比的响应Header；Content-Type总是被对比
+	Compare []string `json:"compare"`
+}
+
+// GoldenResponse 黄金文件记录的响应
+type GoldenResponse struct {
+	StatusCode int               `json:"status"`
+	Header     map[string]string `json:"header"`
+	Body       json.RawMessage   `json:"body"`
+}
+
+// RecordedResponse 后端服务的录制响应；StatusCode >= 400 时返回Error作为错误消息
+type RecordedResponse struct {
+	StatusCode int             `json:"status"`
+	Body       json.RawMessage `json:"body"`
+	Error      string          `json:"error"`
+}
+
+// RecordedBackend 按 interface:method 返回录制响应的Backend
+type RecordedBackend struct {
+	responses map[string]RecordedResponse
+}
+
+func NewRecordedBackend(responses map[string]RecordedResponse) *RecordedBackend {
+	return &RecordedBackend{responses: responses}
+}
+
+func (b *RecordedBackend) Exchange(ctx flux.Context) *flux.ServeError {
+	body, err := b.Invoke(ctx.Endpoint().Service, ctx)
+	if nil != err {
+		return err
+	}
+	ctx.Response().SetStatusCode(flux.StatusOK)
+	ctx.Response().SetBody(body)
+	return nil
+}
+
+func (b *RecordedBackend) Invoke(service flux.BackendService, _ flux.Context) (interface{}, *flux.ServeError) {
+	key := service.Interface + ":" + service.Method
+	recorded, ok := b.responses[key]
+	if !ok {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusBadGateway,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    "fluxtest: no recorded response: " + key,
+		}
+	}
+	if recorded.StatusCode >= http.StatusBadRequest {
+		return nil, &flux.ServeError{
+			StatusCode:     recorded.StatusCode,
+			ErrorCode:      flux.ErrorCodeGatewayBackend,
+			Message:        recorded.Error,
+			UpstreamStatus: recorded.StatusCode,
+		}
+	}
+	return recorded.Body, nil
+}
+
+// RunGolden 运行黄金文件集成测试。目录结构：
+//
+//	<dir>/config.json             可选，网关配置，例如 {"HttpWebServer": {...}}
+//	<dir>/endpoints.json          Endpoint定义列表，格式与注册中心数据一致
+//	<dir>/backends.json           后端录制响应：{"<interface>:<method>": RecordedResponse}
+//	<dir>/cases/<name>.json       请求定义：GoldenRequest
+//	<dir>/cases/<name>.golden     期望响应：GoldenResponse
+//
+// 请求经由完整的网关处理链（WebServer、拦截器、Filter、路由）；后端调用返回录制响应。
+// 设置环境变量 FLUX_GOLDEN_UPDATE=true 时，使用实际响应重新生成黄金文件。
+func RunGolden(t *testing.T, dir string, filters ...flux.Filter) {
+	t.Helper()
+	endpoints, err := loadGoldenEndpoints(filepath.Join(dir, goldenFileEndpoints))
+	if nil != err {
+		t.Fatalf("load endpoints: %s", err)
+	}
+	responses := make(map[string]RecordedResponse, 8)
+	if err := readGoldenJSON(filepath.Join(dir, goldenFileBackends), &responses); nil != err && !os.IsNotExist(err) {
+		t.Fatalf("load backends: %s", err)
+	}
+	settings := make(map[string]interface{}, 4)
+	if err := readGoldenJSON(filepath.Join(dir, goldenFileConfig), &settings); nil != err && !os.IsNotExist(err) {
+		t.Fatalf("load config: %s", err)
+	}
+	address, shutdown, err := startGoldenEngine(settings, endpoints, NewRecordedBackend(responses), filters)
+	if nil != err {
+		t.Fatalf("start engine: %s", err)
+	}
+	defer shutdown()
+	cases, _ := filepath.Glob(filepath.Join(dir, goldenDirCases, "*"+goldenExtCase))
+	sort.Strings(cases)
+	update, _ := strconv.ParseBool(os.Getenv(EnvGoldenUpdate))
+	for _, file := range cases {
+		name := strings.TrimSuffix(filepath.Base(file), goldenExtCase)
+		t.Run(name, func(t *testing.T) {
+			runGoldenCase(t, address, file, update)
+		})
+	}
+}
+
+func runGoldenCase(t *testing.T, address, file string, update bool) {
+	var request GoldenRequest
+	if err := readGoldenJSON(file, &request); nil != err {
+		t.Fatalf("load case: %s", err)
+	}
+	actual, err := doGoldenRequest(address, request)
+	if nil != err {
+		t.Fatalf("request: %s", err)
+	}
+	goldenFile := strings.TrimSuffix(file, goldenExtCase) + goldenExtGolden
+	if update {
+		if err := ioutil.WriteFile(goldenFile, append(actual, '\n'), 0644); nil != err {
+			t.Fatalf("update golden: %s", err)
+		}
+		return
+	}
+	expected, err := ioutil.ReadFile(goldenFile)
+	if nil != err {
+		t.Fatalf("load golden: %s", err)
+	}
+	if !bytes.Equal(bytes.TrimSpace(expected), bytes.TrimSpace(actual)) {
+		t.Errorf("response mismatch golden file %s\n--- expected\n%s\n--- actual\n%s", goldenFile, expected, actual)
+	}
+}
+
+func doGoldenRequest(address string, request GoldenRequest) ([]byte, error) {
+	method := request.Method
+	if "" == method {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequest(method, "http://"+address+request.Path, strings.NewReader(request.Body))
+	if nil != err {
+		return nil, err
+	}
+	for name, value := range request.Header {
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+		} else {
+			req.Header.Set(name, value)
+		}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if nil != err {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if nil != err {
+		return nil, err
+	}
+	out := GoldenResponse{
+		StatusCode: resp.StatusCode,
+		Header:     make(map[string]string, 1+len(request.Compare)),
+	}
+	for _, name := range append([]string{"Content-Type"}, request.Compare...) {
+		out.Header[http.CanonicalHeaderKey(name)] = resp.Header.Get(name)
+	}
+	if json.Valid(data) {
+		out.Body = data
+	} else {
+		out.Body, _ = json.Marshal(string(data))
+	}
+	return json.MarshalIndent(out, "", "  ")
+}
+
+func startGoldenEngine(settings map[string]interface{}, endpoints []flux.Endpoint, backend flux.BackendTransport, filters []flux.Filter) (string, func(), error) {
+	extensions := ext.Default().Clone()
+	for _, endpoint := range endpoints {
+		extensions.StoreBackendTransport(endpoint.Service.AttrRpcProto(), backend)
+	}
+	for _, filter := range filters {
+		extensions.StoreGlobalFilter(filter)
+	}
+	extensions.StoreEndpointRegistryFactory(EndpointRegistryIdGolden, func() flux.EndpointRegistry {
+		return registry.NewMemoryEndpointRegistry()
+	})
+	config := viper.New()
+	if err := config.MergeConfigMap(settings); nil != err {
+		return "", nil, err
+	}
+	config.Set(flux.KeyConfigRootEndpointRegistry+"."+flux.KeyConfigEndpointRegistryId, EndpointRegistryIdGolden)
+	engine := server.NewHttpServeEngineOf(extensions, server.DefaultServerResponseWriter, server.DefaultServerErrorsWriter)
+	engine.SetConfiguration(flux.NewConfiguration(config))
+	if err := engine.Prepare(); nil != err {
+		return "", nil, err
+	}
+	if err := engine.Initial(); nil != err {
+		return "", nil, err
+	}
+	// 同步注册Endpoint，保证请求前路由已就绪
+	for _, endpoint := range endpoints {
+		engine.HandleHttpEndpointEvent(flux.HttpEndpointEvent{EventType: flux.EventTypeAdded, Endpoint: endpoint})
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		return "", nil, err
+	}
+	go func() {
+		_ = engine.ServeListener(flux.BuildInfo{Version: "golden"}, listener)
+	}()
+	<-engine.StateStarted()
+	return listener.Addr().String(), func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = engine.Shutdown(ctx)
+	}, nil
+}
+
+func loadGoldenEndpoints(file string) ([]flux.Endpoint, error) {
+	data, err := ioutil.ReadFile(file)
+	if nil != err {
+		return nil, err
+	}
+	return registry.LoadEndpointSnapshot(data)
+}
+
+func readGoldenJSON(file string, out interface{}) error {
+	data, err := ioutil.ReadFile(file)
+	if nil != err {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
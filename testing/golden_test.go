@@ -0,0 +1,13 @@
+package fluxtest
+
+import (
+	"testing"
+)
+
+func TestRunGolden(t *testing.T) {
+	RunGolden(t, "testdata/golden")
+}
+
+func TestRunGolden_Server(t *testing.T) {
+	RunGolden(t, "testdata/server")
+}
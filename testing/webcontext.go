@@ -0,0 +1,282 @@
+// Package fluxtest 提供用于单元测试自定义Filter的工具：内存实现的WebContext、可编排响应的Backend、
+// 可控制属性的Context，以及独立运行Filter链的辅助函数；无需启动Web服务和注册中心。
+package fluxtest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"github.com/bytepowered/flux"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+)
+
+var _ flux.WebContext = new(WebContext)
+
+// WebContextBuilder 构建内存WebContext
+type WebContextBuilder struct {
+	request    *http.Request
+	body       []byte
+	pathValues url.Values
+	formValues url.Values
+	values     map[string]interface{}
+}
+
+// NewWebContext 创建指定请求方法和请求地址（可包含Query参数）的WebContext构建器
+func NewWebContext(method, target string) *WebContextBuilder {
+	return &WebContextBuilder{
+		request:    httptest.NewRequest(method, target, nil),
+		pathValues: make(url.Values, 2),
+		formValues: make(url.Values, 2),
+		values:     make(map[string]interface{}, 2),
+	}
+}
+
+// WithHeader 添加请求Header
+func (b *WebContextBuilder) WithHeader(name, value string) *WebContextBuilder {
+	b.request.Header.Add(name, value)
+	return b
+}
+
+// WithRemoteAddr 设置请求的远端地址，格式为 ip:port
+func (b *WebContextBuilder) WithRemoteAddr(addr string) *WebContextBuilder {
+	b.request.RemoteAddr = addr
+	return b
+}
+
+// WithPathValue 设置动态路径参数
+func (b *WebContextBuilder) WithPathValue(name, value string) *WebContextBuilder {
+	b.pathValues.Set(name, value)
+	return b
+}
+
+// WithFormValue 添加表单参数
+func (b *WebContextBuilder) WithFormValue(name, value string) *WebContextBuilder {
+	b.formValues.Add(name, value)
+	return b
+}
+
+// WithCookie 添加请求Cookie
+func (b *WebContextBuilder) WithCookie(cookie *http.Cookie) *WebContextBuilder {
+	b.request.AddCookie(cookie)
+	return b
+}
+
+// WithBody 设置请求体
+func (b *WebContextBuilder) WithBody(contentType string, body []byte) *WebContextBuilder {
+	b.request.Header.Set("Content-Type", contentType)
+	b.body = body
+	return b
+}
+
+// WithValue 设置WebContext域键值
+func (b *WebContextBuilder) WithValue(name string, value interface{}) *WebContextBuilder {
+	b.values[name] = value
+	return b
+}
+
+// Build 返回WebContext；响应数据写入内存，通过 Recorder 读取
+func (b *WebContextBuilder) Build() *WebContext {
+	request := b.request
+	data := b.body
+	if len(b.formValues) > 0 && nil == data {
+		data = []byte(b.formValues.Encode())
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	request.Body = ioutil.NopCloser(bytes.NewReader(data))
+	request.ContentLength = int64(len(data))
+	request.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	return &WebContext{
+		request:    request,
+		recorder:   httptest.NewRecorder(),
+		pathValues: b.pathValues,
+		formValues: b.formValues,
+		values:     b.values,
+	}
+}
+
+// WebContext 基于http.Request和httptest.ResponseRecorder的内存WebContext实现
+type WebContext struct {
+	request    *http.Request
+	recorder   *httptest.ResponseRecorder
+	writer     http.ResponseWriter
+	pathValues url.Values
+	formValues url.Values
+	values     map[string]interface{}
+}
+
+// Recorder 返回记录响应数据的ResponseRecorder
+func (c *WebContext) Recorder() *httptest.ResponseRecorder {
+	return c.recorder
+}
+
+func (c *WebContext) Method() string {
+	return c.request.Method
+}
+
+func (c *WebContext) Host() string {
+	return c.request.Host
+}
+
+func (c *WebContext) UserAgent() string {
+	return c.request.UserAgent()
+}
+
+func (c *WebContext) RequestURI() string {
+	return c.request.RequestURI
+}
+
+func (c *WebContext) RequestURL() (*url.URL, bool) {
+	return c.request.URL, true
+}
+
+func (c *WebContext) RequestBodyReader() (io.ReadCloser, error) {
+	return c.request.GetBody()
+}
+
+func (c *WebContext) RequestRewrite(method string, path string) {
+	c.request.Method = method
+	c.request.URL.Path = path
+}
+
+func (c *WebContext) SetRequestHeader(name, value string) {
+	c.request.Header.Set(name, value)
+}
+
+func (c *WebContext) AddRequestHeader(name, value string) {
+	c.request.Header.Add(name, value)
+}
+
+func (c *WebContext) RemoveRequestHeader(name string) {
+	c.request.Header.Del(name)
+}
+
+func (c *WebContext) HeaderValues() (http.Header, bool) {
+	return c.request.Header, true
+}
+
+func (c *WebContext) QueryValues() url.Values {
+	return c.request.URL.Query()
+}
+
+func (c *WebContext) PathValues() url.Values {
+	return c.pathValues
+}
+
+func (c *WebContext) FormValues() url.Values {
+	return c.formValues
+}
+
+func (c *WebContext) CookieValues() []*http.Cookie {
+	return c.request.Cookies()
+}
+
+func (c *WebContext) HeaderValue(name string) string {
+	return c.request.Header.Get(name)
+}
+
+func (c *WebContext) QueryValue(name string) string {
+	return c.request.URL.Query().Get(name)
+}
+
+func (c *WebContext) PathValue(name string) string {
+	return c.pathValues.Get(name)
+}
+
+func (c *WebContext) FormValue(name string) string {
+	return c.formValues.Get(name)
+}
+
+func (c *WebContext) CookieValue(name string) (*http.Cookie, bool) {
+	cookie, err := c.request.Cookie(name)
+	return cookie, nil == err
+}
+
+func (c *WebContext) Write(statusCode int, contentType string, bytes []byte) error {
+	w := c.responseWriter()
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+	_, err := w.Write(bytes)
+	return err
+}
+
+func (c *WebContext) WriteStream(statusCode int, contentType string, reader io.Reader) error {
+	w := c.responseWriter()
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+	_, err := io.Copy(w, reader)
+	return err
+}
+
+func (c *WebContext) ResponseHeader() (http.Header, bool) {
+	return c.responseWriter().Header(), true
+}
+
+func (c *WebContext) GetResponseHeader(name string) string {
+	return c.responseWriter().Header().Get(name)
+}
+
+func (c *WebContext) SetResponseHeader(name, value string) {
+	c.responseWriter().Header().Set(name, value)
+}
+
+func (c *WebContext) AddResponseHeader(name, value string) {
+	c.responseWriter().Header().Add(name, value)
+}
+
+func (c *WebContext) SetResponseWriter(w http.ResponseWriter) error {
+	c.writer = w
+	return nil
+}
+
+func (c *WebContext) SetValue(name string, value interface{}) {
+	c.values[name] = value
+}
+
+func (c *WebContext) GetValue(name string) interface{} {
+	return c.values[name]
+}
+
+func (c *WebContext) HttpRequest() (*http.Request, error) {
+	return c.request, nil
+}
+
+func (c *WebContext) Context() context.Context {
+	return c.request.Context()
+}
+
+func (c *WebContext) HttpResponseWriter() (http.ResponseWriter, error) {
+	return c.responseWriter(), nil
+}
+
+func (c *WebContext) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := c.responseWriter().(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, flux.ErrHttpHijackNotSupported
+}
+
+func (c *WebContext) RawWebContext() interface{} {
+	return c
+}
+
+func (c *WebContext) RawWebRequest() interface{} {
+	return c.request
+}
+
+func (c *WebContext) RawWebResponse() interface{} {
+	return c.responseWriter()
+}
+
+func (c *WebContext) responseWriter() http.ResponseWriter {
+	if nil != c.writer {
+		return c.writer
+	}
+	return c.recorder
+}
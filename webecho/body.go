@@ -2,11 +2,13 @@ package webecho
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"github.com/bytepowered/flux"
 	"github.com/labstack/echo/v4"
 	"io"
 	"io/ioutil"
+	"net/http"
 )
 
 // Body缓存，允许通过 GetBody 多次读取Body
@@ -15,7 +17,14 @@ func RepeatableBodyReader(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(echo echo.Context) error {
 		request := echo.Request()
 		data, err := ioutil.ReadAll(request.Body)
-		if nil != err {
+		if errors.Is(err, flux.ErrHttpRequestBodyTooLarge) {
+			return &flux.ServeError{
+				StatusCode: http.StatusRequestEntityTooLarge,
+				ErrorCode:  flux.ErrorCodeRequestInvalid,
+				Message:    flux.ErrorMessageRequestBodyTooLarge,
+				Internal:   fmt.Errorf("read req-body, method: %s, uri:%s, err: %w", request.Method, request.RequestURI, err),
+			}
+		} else if nil != err {
 			return &flux.ServeError{
 				StatusCode: flux.StatusBadRequest,
 				ErrorCode:  flux.ErrorCodeGatewayInternal,
@@ -1,10 +1,12 @@
 package webecho
 
 import (
+	"bufio"
 	"context"
 	"github.com/bytepowered/flux"
 	"github.com/labstack/echo/v4"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 )
@@ -190,6 +192,13 @@ func (c *AdaptWebContext) HttpResponseWriter() (http.ResponseWriter, error) {
 	return c.echoc.Response().Writer, nil
 }
 
+func (c *AdaptWebContext) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if _, ok := c.echoc.Response().Writer.(http.Hijacker); !ok {
+		return nil, nil, flux.ErrHttpHijackNotSupported
+	}
+	return c.echoc.Response().Hijack()
+}
+
 func toAdaptWebContext(echo echo.Context) flux.WebContext {
 	webc, ok := echo.Get(keyWebContext).(*AdaptWebContext)
 	if !ok {
@@ -0,0 +1,23 @@
+//go:build gofuzz
+// +build gofuzz
+
+package webecho
+
+import (
+	"bytes"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"net/http/httptest"
+)
+
+// go-fuzz 入口：go-fuzz-build -func FuzzMultipartBodyDecoder github.com/bytepowered/flux/webecho
+
+func FuzzMultipartBodyDecoder(data []byte) int {
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+	req.Header.Set(echo.HeaderContentType, "multipart/form-data; boundary=fuzz")
+	echoc := echo.New().NewContext(req, httptest.NewRecorder())
+	if len(DefaultRequestBodyDecoder(NewAdaptWebContext(echoc, DefaultRequestBodyDecoder))) == 0 {
+		return 0
+	}
+	return 1
+}
@@ -2,13 +2,16 @@ package webecho
 
 import (
 	"context"
-	"fmt"
 	"github.com/bytepowered/flux"
 	"github.com/bytepowered/flux/ext"
+	"github.com/bytepowered/flux/logger"
 	"github.com/labstack/echo/v4"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 var _ flux.WebServer = new(AdaptWebServer)
@@ -21,10 +24,16 @@ func NewAdaptWebServer(config *flux.Configuration) flux.WebServer {
 	server := echo.New()
 	server.HideBanner = true
 	server.HidePort = true
+	// 注入客户端连接，用于流式响应设置写超时
+	server.Server.ConnContext = flux.ContextWithConn
+	server.TLSServer.ConnContext = flux.ContextWithConn
 	aws := &AdaptWebServer{
 		server:      server,
 		bodyDecoder: DefaultRequestBodyDecoder,
 	}
+	aws.interceptors.Store(make([]echo.MiddlewareFunc, 0))
+	// 路由失败由当前WebServer的处理函数响应
+	server.Use(aws.routeFallback)
 	// 注入EchoContext
 	server.Pre(func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
@@ -32,8 +41,15 @@ func NewAdaptWebServer(config *flux.Configuration) flux.WebServer {
 			return next(c)
 		}
 	})
-	// 注入对Body的可重读逻辑
-	server.Pre(RepeatableBodyReader)
+	// 注入对Body的可重读逻辑：在全部WebInterceptor之后执行，拦截器可以在缓存请求体之前拒绝请求
+	server.Pre(func(next echo.HandlerFunc) echo.HandlerFunc {
+		next = RepeatableBodyReader(next)
+		interceptors := aws.interceptors.Load().([]echo.MiddlewareFunc)
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			next = interceptors[i](next)
+		}
+		return next
+	})
 	return aws
 }
 
@@ -42,6 +58,13 @@ func NewAdaptWebServer(config *flux.Configuration) flux.WebServer {
 type AdaptWebServer struct {
 	server      *echo.Echo
 	bodyDecoder flux.WebRequestBodyDecoder
+	methods     sync.Map // 路由Path -> 已注册方法列表，避免405响应时遍历全部路由
+	// 路由不存在（404）和方法不允许（405）的处理函数；不修改echo的全局处理函数，多个WebServer互不影响
+	notFoundHandler         echo.HandlerFunc
+	methodNotAllowedHandler echo.HandlerFunc
+	// WebInterceptor列表，写时复制；每个请求按注册顺序执行
+	interceptors atomic.Value
+	mu           sync.Mutex
 }
 
 func (w *AdaptWebServer) SetWebRequestBodyDecoder(decoder flux.WebRequestBodyDecoder) {
@@ -49,11 +72,38 @@ func (w *AdaptWebServer) SetWebRequestBodyDecoder(decoder flux.WebRequestBodyDec
 }
 
 func (w *AdaptWebServer) SetWebNotFoundHandler(fun flux.WebHandler) {
-	echo.NotFoundHandler = AdaptWebRouteHandler(fun).AdaptFunc
+	w.notFoundHandler = AdaptWebRouteHandler(fun).AdaptFunc
+}
+
+func (w *AdaptWebServer) SetWebMethodNotAllowedHandler(fun flux.WebHandler) {
+	w.methodNotAllowedHandler = func(c echo.Context) error {
+		if allows := w.allowMethodsOf(c.Path()); len(allows) > 0 {
+			c.Response().Header().Set(flux.HeaderAllow, strings.Join(allows, ", "))
+		}
+		return AdaptWebRouteHandler(fun).AdaptFunc(c)
+	}
 }
 
 func (w *AdaptWebServer) HandleWebNotFound(webc flux.WebContext) error {
-	return echo.NotFoundHandler(webc.RawWebContext().(echo.Context))
+	if nil == w.notFoundHandler {
+		return echo.ErrNotFound
+	}
+	return w.notFoundHandler(webc.RawWebContext().(echo.Context))
+}
+
+// routeFallback 路由失败时，echo路由返回默认处理函数的错误；转换为当前WebServer设置的处理函数
+func (w *AdaptWebServer) routeFallback(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		err := next(c)
+		switch {
+		case echo.ErrNotFound == err && nil != w.notFoundHandler:
+			return w.notFoundHandler(c)
+		case echo.ErrMethodNotAllowed == err && nil != w.methodNotAllowedHandler:
+			return w.methodNotAllowedHandler(c)
+		default:
+			return err
+		}
+	}
 }
 
 func (w *AdaptWebServer) SetWebErrorHandler(fun flux.WebErrorHandler) {
@@ -63,7 +113,10 @@ func (w *AdaptWebServer) SetWebErrorHandler(fun flux.WebErrorHandler) {
 }
 
 func (w *AdaptWebServer) AddWebInterceptor(m flux.WebInterceptor) {
-	w.server.Pre(AdaptWebInterceptor(m).AdaptFunc)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	interceptors := w.interceptors.Load().([]echo.MiddlewareFunc)
+	w.interceptors.Store(append(interceptors[:len(interceptors):len(interceptors)], AdaptWebInterceptor(m).AdaptFunc))
 }
 
 func (w *AdaptWebServer) AddWebMiddleware(m flux.WebInterceptor) {
@@ -76,6 +129,7 @@ func (w *AdaptWebServer) AddWebHandler(method, pattern string, h flux.WebHandler
 		wms[i] = AdaptWebInterceptor(mi).AdaptFunc
 	}
 	w.server.Add(method, toRoutePattern(pattern), AdaptWebRouteHandler(h).AdaptFunc, wms...)
+	w.addRouteMethod(method, toRoutePattern(pattern))
 }
 
 func (w *AdaptWebServer) AddWebHttpHandler(method, pattern string, h http.Handler, m ...func(http.Handler) http.Handler) {
@@ -84,6 +138,7 @@ func (w *AdaptWebServer) AddWebHttpHandler(method, pattern string, h http.Handle
 		wms[i] = echo.WrapMiddleware(mf)
 	}
 	w.server.Add(method, toRoutePattern(pattern), echo.WrapHandler(h), wms...)
+	w.addRouteMethod(method, toRoutePattern(pattern))
 }
 
 func (w *AdaptWebServer) RawWebRouter() interface{} {
@@ -106,6 +161,40 @@ func (w *AdaptWebServer) Shutdown(ctx context.Context) error {
 	return w.server.Shutdown(ctx)
 }
 
+// allowMethodsOf 返回路由Path已注册的全部方法
+func (w *AdaptWebServer) allowMethodsOf(path string) []string {
+	if v, ok := w.methods.Load(path); ok {
+		return v.([]string)
+	}
+	// 直接通过 RawWebRouter 注册的路由
+	allows := make([]string, 0, 4)
+	for _, route := range w.server.Routes() {
+		if route.Path == path {
+			allows = append(allows, route.Method)
+		}
+	}
+	sort.Strings(allows)
+	return allows
+}
+
+// addRouteMethod 更新路由Path的已注册方法列表；写时复制，读取无需加锁
+func (w *AdaptWebServer) addRouteMethod(method, path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var allows []string
+	if v, ok := w.methods.Load(path); ok {
+		allows = v.([]string)
+	}
+	for _, m := range allows {
+		if m == method {
+			return
+		}
+	}
+	updated := append(append(make([]string, 0, len(allows)+1), allows...), method)
+	sort.Strings(updated)
+	w.methods.Store(path, updated)
+}
+
 func toRoutePattern(uri string) string {
 	// /api/{userId} -> /api/:userId
 	replaced := strings.Replace(uri, "}", "", -1)
@@ -116,11 +205,12 @@ func toRoutePattern(uri string) string {
 	}
 }
 
-// 默认对RequestBody的表单数据进行解析
+// 默认对RequestBody的表单数据进行解析；请求体格式错误（例如损坏的multipart数据）时返回空表单
 func DefaultRequestBodyDecoder(webc flux.WebContext) url.Values {
 	form, err := webc.(*AdaptWebContext).echoc.FormParams()
 	if nil != err {
-		panic(fmt.Errorf("parse form params failed, err: %w", err))
+		logger.Warnw("Parse form params failed", "request-uri", webc.RequestURI(), "error", err)
+		return url.Values{}
 	}
 	return form
 }
@@ -0,0 +1,136 @@
+package webecho
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/webmidware"
+	assert2 "github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// countingBody 记录被读取字节数的请求体
+type countingBody struct {
+	reader io.Reader
+	read   int
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.reader.Read(p)
+	b.read += n
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	return nil
+}
+
+func newMemoryGuardTestServer(budget int64) http.Handler {
+	server := NewAdaptWebServer(flux.NewConfiguration(nil))
+	server.SetWebErrorHandler(func(err error, webc flux.WebContext) {
+		status := http.StatusInternalServerError
+		if serr, ok := err.(*flux.ServeError); ok {
+			status = serr.StatusCode
+		}
+		_ = webc.Write(status, "text/plain", []byte(err.Error()))
+	})
+	server.AddWebInterceptor(webmidware.NewMemoryGuardMiddleware(webmidware.NewMemoryGuard(webmidware.MemoryGuardConfig{
+		Budget:          budget,
+		LargeBodySize:   16,
+		UnknownBodySize: 32,
+	})))
+	server.AddWebHandler(http.MethodPost, "/upload", func(webc flux.WebContext) error {
+		reader, err := webc.RequestBodyReader()
+		if nil != err {
+			return err
+		}
+		data, err := ioutil.ReadAll(reader)
+		if nil != err {
+			return err
+		}
+		return webc.Write(http.StatusOK, "text/plain", data)
+	})
+	return server.RawWebServer().(http.Handler)
+}
+
+func TestMemoryGuard_RejectBeforeBuffering(t *testing.T) {
+	assert := assert2.New(t)
+	handler := newMemoryGuardTestServer(64)
+	body := &countingBody{reader: strings.NewReader(strings.Repeat("x", 128))}
+	request := httptest.NewRequest(http.MethodPost, "/upload", body)
+	request.ContentLength = 128
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, request)
+	assert.Equal(http.StatusServiceUnavailable, w.Code)
+	assert.Equal(0, body.read, "request body must not be buffered")
+}
+
+func TestMemoryGuard_DeclaredBody(t *testing.T) {
+	assert := assert2.New(t)
+	handler := newMemoryGuardTestServer(1024)
+	request := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("hello"))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, request)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("hello", w.Body.String())
+}
+
+func TestMemoryGuard_UnknownBodySize(t *testing.T) {
+	assert := assert2.New(t)
+	handler := newMemoryGuardTestServer(1024)
+	cases := []struct {
+		name   string
+		size   int
+		expect int
+	}{
+		{name: "within limit", size: 32, expect: http.StatusOK},
+		{name: "exceeds limit", size: 33, expect: http.StatusRequestEntityTooLarge},
+	}
+	for _, c := range cases {
+		request := httptest.NewRequest(http.MethodPost, "/upload", &countingBody{reader: strings.NewReader(strings.Repeat("x", c.size))})
+		request.ContentLength = -1
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, request)
+		assert.Equal(c.expect, w.Code, c.name)
+	}
+}
+
+func newFallbackTestServer(name string) http.Handler {
+	server := NewAdaptWebServer(flux.NewConfiguration(nil))
+	server.SetWebNotFoundHandler(func(webc flux.WebContext) error {
+		return webc.Write(http.StatusNotFound, "text/plain", []byte(name+":not-found"))
+	})
+	server.SetWebMethodNotAllowedHandler(func(webc flux.WebContext) error {
+		return webc.Write(http.StatusMethodNotAllowed, "text/plain", []byte(name+":method-not-allowed"))
+	})
+	server.AddWebHandler(http.MethodGet, "/users/:id", func(webc flux.WebContext) error {
+		return webc.Write(http.StatusOK, "text/plain", []byte(name))
+	})
+	server.AddWebHandler(http.MethodPut, "/users/:id", func(webc flux.WebContext) error {
+		return webc.Write(http.StatusOK, "text/plain", []byte(name))
+	})
+	return server.RawWebServer().(http.Handler)
+}
+
+func TestAdaptWebServer_FallbackHandlers(t *testing.T) {
+	assert := assert2.New(t)
+	// 每个WebServer使用各自的404/405处理函数
+	servers := map[string]http.Handler{"a": newFallbackTestServer("a"), "b": newFallbackTestServer("b")}
+	for name, server := range servers {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/orders", nil))
+		assert.Equal(http.StatusNotFound, recorder.Code)
+		assert.Equal(name+":not-found", recorder.Body.String())
+		recorder = httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+		assert.Equal(http.StatusMethodNotAllowed, recorder.Code)
+		assert.Equal(name+":method-not-allowed", recorder.Body.String())
+		assert.Equal("GET, PUT", recorder.Header().Get(flux.HeaderAllow))
+		recorder = httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+		assert.Equal(name, recorder.Body.String())
+	}
+}
@@ -38,13 +38,20 @@ type CorsConfig struct {
 	ExposeHeaders    []string
 	AllowCredentials bool
 	MaxAge           int
+	// PreflightPassthrough 不处理预检请求，由路由层根据Endpoint生成预检响应
+	PreflightPassthrough bool
 }
 
 func NewCORSMiddleware() flux.WebInterceptor {
-	return NewCORSMiddlewareWith(CorsConfig{
+	return NewCORSMiddlewareWith(DefaultCorsConfig())
+}
+
+// DefaultCorsConfig 返回默认CORS配置：允许全部Origin和常用方法
+func DefaultCorsConfig() CorsConfig {
+	return CorsConfig{
 		AllowOrigins: []string{"*"},
 		AllowMethods: []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPatch, http.MethodPost, http.MethodDelete},
-	})
+	}
 }
 
 func NewCORSMiddlewareWith(config CorsConfig) flux.WebInterceptor {
@@ -59,26 +66,10 @@ func NewCORSMiddlewareWith(config CorsConfig) flux.WebInterceptor {
 			}
 
 			origin := webc.HeaderValue(flux.HeaderOrigin)
-			allowOrigin := ""
-
-			// Check allowed origins
-			for _, o := range config.AllowOrigins {
-				if o == "*" && config.AllowCredentials {
-					allowOrigin = origin
-					break
-				}
-				if o == "*" || o == origin {
-					allowOrigin = o
-					break
-				}
-				if matchSubdomain(origin, o) {
-					allowOrigin = origin
-					break
-				}
-			}
+			allowOrigin := AllowOriginOf(origin, config.AllowOrigins, config.AllowCredentials)
 
 			// Simple request
-			if webc.Method() != http.MethodOptions {
+			if webc.Method() != http.MethodOptions || config.PreflightPassthrough {
 				webc.AddResponseHeader(flux.HeaderVary, flux.HeaderOrigin)
 				webc.SetResponseHeader(flux.HeaderAccessControlAllowOrigin, allowOrigin)
 				if config.AllowCredentials {
@@ -118,6 +109,22 @@ func NewCORSMiddlewareWith(config CorsConfig) flux.WebInterceptor {
 	}
 }
 
+// AllowOriginOf 返回请求Origin匹配的Access-Control-Allow-Origin值；不允许时返回空字符串
+func AllowOriginOf(origin string, allowOrigins []string, allowCredentials bool) string {
+	for _, o := range allowOrigins {
+		if o == "*" && allowCredentials {
+			return origin
+		}
+		if o == "*" || o == origin {
+			return o
+		}
+		if matchSubdomain(origin, o) {
+			return origin
+		}
+	}
+	return ""
+}
+
 func matchScheme(domain, pattern string) bool {
 	didx := strings.Index(domain, ":")
 	pidx := strings.Index(pattern, ":")
@@ -0,0 +1,82 @@
+package webmidware
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"net/http"
+	"strings"
+)
+
+var (
+	// DefaultProtectedHeaders 默认受保护的内部Header；以 * 结尾表示前缀匹配
+	DefaultProtectedHeaders = []string{
+		"X-Internal-*",
+		flux.XJwtSubject,
+		flux.XJwtIssuer,
+		flux.XJwtToken,
+	}
+)
+
+type HeaderFirewallConfig struct {
+	Skipper flux.WebSkipper
+	// ProtectedHeaders 受保护的Header列表；不区分大小写，以 * 结尾表示前缀匹配
+	ProtectedHeaders []string
+}
+
+func NewHeaderFirewallMiddleware() flux.WebInterceptor {
+	return NewHeaderFirewallMiddlewareWith(HeaderFirewallConfig{
+		ProtectedHeaders: DefaultProtectedHeaders,
+	})
+}
+
+// NewHeaderFirewallMiddlewareWith 生成入站Header防火墙中间件：在Filter执行前，移除客户端传入的内部Header，
+// 防止客户端伪造网关注入的身份Header实现越权。
+func NewHeaderFirewallMiddlewareWith(config HeaderFirewallConfig) flux.WebInterceptor {
+	exact := make(map[string]struct{}, len(config.ProtectedHeaders))
+	prefixes := make([]string, 0)
+	for _, name := range config.ProtectedHeaders {
+		if name = strings.TrimSpace(name); "" == name {
+			continue
+		}
+		if strings.HasSuffix(name, "*") {
+			prefixes = append(prefixes, http.CanonicalHeaderKey(strings.TrimSuffix(name, "*")))
+		} else {
+			exact[http.CanonicalHeaderKey(name)] = struct{}{}
+		}
+	}
+	return func(next flux.WebHandler) flux.WebHandler {
+		return func(webc flux.WebContext) error {
+			if config.Skipper != nil && config.Skipper(webc) {
+				return next(webc)
+			}
+			header, _ := webc.HeaderValues()
+			stripped := make([]string, 0)
+			for name := range header {
+				if isProtectedHeader(http.CanonicalHeaderKey(name), exact, prefixes) {
+					stripped = append(stripped, name)
+				}
+			}
+			for _, name := range stripped {
+				webc.RemoveRequestHeader(name)
+			}
+			if len(stripped) > 0 {
+				logger.Infow("HeaderFirewall, strip client-supplied protected headers",
+					"request-id", webc.GetValue(flux.HeaderXRequestId), "headers", stripped)
+			}
+			return next(webc)
+		}
+	}
+}
+
+// isProtectedHeader 判断Header名称是否受保护；name, exact, prefixes 均为规范化的Header名称
+func isProtectedHeader(name string, exact map[string]struct{}, prefixes []string) bool {
+	if _, ok := exact[name]; ok {
+		return true
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
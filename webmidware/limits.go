@@ -0,0 +1,92 @@
+package webmidware
+
+import (
+	"github.com/bytepowered/flux"
+	"net/http"
+	"strings"
+)
+
+type RequestLimitsConfig struct {
+	Skipper flux.WebSkipper
+	// MaxUriLength 请求URI（路径和Query参数）的最大长度；小于等于0表示不限制
+	MaxUriLength int
+	// MaxHeaderCount 请求Header的最大数量（多值Header按值的数量计算）；小于等于0表示不限制
+	MaxHeaderCount int
+	// MaxQueryParams Query参数的最大数量（重复参数按出现次数计算）；小于等于0表示不限制
+	MaxQueryParams int
+}
+
+func NewRequestLimitsMiddleware() flux.WebInterceptor {
+	return NewRequestLimitsMiddlewareWith(RequestLimitsConfig{
+		MaxUriLength:   8192,
+		MaxHeaderCount: 100,
+		MaxQueryParams: 256,
+	})
+}
+
+// NewRequestLimitsMiddlewareWith 生成请求限制中间件：在路由和参数解析前检查URI长度、Header数量和Query参数数量，
+// 超出限制时返回 414(URI Too Long) 或 431(Request Header Fields Too Large)。
+func NewRequestLimitsMiddlewareWith(config RequestLimitsConfig) flux.WebInterceptor {
+	return func(next flux.WebHandler) flux.WebHandler {
+		return func(webc flux.WebContext) error {
+			if config.Skipper != nil && config.Skipper(webc) {
+				return next(webc)
+			}
+			if config.MaxUriLength > 0 && len(webc.RequestURI()) > config.MaxUriLength {
+				return &flux.ServeError{
+					StatusCode: http.StatusRequestURITooLong,
+					ErrorCode:  flux.ErrorCodeRequestInvalid,
+					Message:    flux.ErrorMessageRequestUriTooLong,
+				}
+			}
+			if config.MaxHeaderCount > 0 {
+				header, _ := webc.HeaderValues()
+				if countHeaderValues(header, config.MaxHeaderCount) > config.MaxHeaderCount {
+					return &flux.ServeError{
+						StatusCode: http.StatusRequestHeaderFieldsTooLarge,
+						ErrorCode:  flux.ErrorCodeRequestInvalid,
+						Message:    flux.ErrorMessageRequestTooManyHeaders,
+					}
+				}
+			}
+			if config.MaxQueryParams > 0 {
+				if target, _ := webc.RequestURL(); nil != target && countQueryParams(target.RawQuery) > config.MaxQueryParams {
+					return &flux.ServeError{
+						StatusCode: http.StatusRequestURITooLong,
+						ErrorCode:  flux.ErrorCodeRequestInvalid,
+						Message:    flux.ErrorMessageRequestTooManyQueryArgs,
+					}
+				}
+			}
+			return next(webc)
+		}
+	}
+}
+
+// countHeaderValues 计算Header值的数量；超过limit后停止计算
+func countHeaderValues(header http.Header, limit int) int {
+	count := 0
+	for _, values := range header {
+		if count += len(values); count > limit {
+			break
+		}
+	}
+	return count
+}
+
+// countQueryParams 计算Query参数的数量；不解析参数，忽略空的参数段
+func countQueryParams(rawQuery string) int {
+	count := 0
+	for "" != rawQuery {
+		var param string
+		if i := strings.IndexAny(rawQuery, "&;"); i >= 0 {
+			param, rawQuery = rawQuery[:i], rawQuery[i+1:]
+		} else {
+			param, rawQuery = rawQuery, ""
+		}
+		if "" != param {
+			count++
+		}
+	}
+	return count
+}
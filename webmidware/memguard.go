@@ -0,0 +1,206 @@
+package webmidware
+
+import (
+	"github.com/bytepowered/flux"
+	"github.com/bytepowered/flux/logger"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultOverloadLogInterval = time.Second * 10
+	defaultUnknownBodySize     = 8 * 1024 * 1024
+)
+
+type MemoryGuardConfig struct {
+	Skipper flux.WebSkipper
+	// Budget 全部进行中请求的缓存数据总预算（字节）
+	Budget int64
+	// HighWatermark 拒绝大请求的水位，为 Budget 的比例；默认为0.9
+	HighWatermark float64
+	// LargeBodySize 大请求的请求体大小（字节）；小于该值的请求只计数，不拒绝
+	LargeBodySize int64
+	// UnknownBodySize 未声明 Content-Length 的请求按该大小占用预算，请求体超过该大小时读取失败；默认为8MB
+	UnknownBodySize int64
+	// GcInterval 紧急GC的最小间隔；小于等于0表示不执行紧急GC
+	GcInterval time.Duration
+	// TopConsumers 拒绝请求时日志输出的占用最多的调用方数量；日志按 GcInterval 限制频率，未开启紧急GC时为10秒
+	TopConsumers int
+	// KeyFunc 返回请求的调用方标识；默认为请求Host
+	KeyFunc func(webc flux.WebContext) string
+}
+
+// MemoryConsumer 调用方的缓存数据占用
+type MemoryConsumer struct {
+	Key      string
+	Bytes    int64
+	Requests int
+}
+
+// MemoryGuard 统计进行中请求缓存的请求体大小，与全局预算比较；接近预算时拒绝新的大请求，
+// 避免异常流量下网关进程被OOM终止。并发安全。
+type MemoryGuard struct {
+	config    MemoryGuardConfig
+	mu        sync.Mutex
+	used      int64
+	consumers map[string]*MemoryConsumer
+	firedAt   time.Time
+}
+
+func NewMemoryGuard(config MemoryGuardConfig) *MemoryGuard {
+	if config.HighWatermark <= 0 || config.HighWatermark > 1 {
+		config.HighWatermark = 0.9
+	}
+	if config.UnknownBodySize <= 0 {
+		config.UnknownBodySize = defaultUnknownBodySize
+	}
+	if nil == config.KeyFunc {
+		config.KeyFunc = func(webc flux.WebContext) string {
+			return webc.Host()
+		}
+	}
+	return &MemoryGuard{
+		config:    config,
+		consumers: make(map[string]*MemoryConsumer, 64),
+	}
+}
+
+// Acquire 占用指定大小的预算，返回是否允许；大请求在超过水位时被拒绝，小请求总是允许
+func (g *MemoryGuard) Acquire(key string, size int64) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if size >= g.config.LargeBodySize && float64(g.used+size) > float64(g.config.Budget)*g.config.HighWatermark {
+		return false
+	}
+	g.used += size
+	c, ok := g.consumers[key]
+	if !ok {
+		c = &MemoryConsumer{Key: key}
+		g.consumers[key] = c
+	}
+	c.Bytes += size
+	c.Requests++
+	return true
+}
+
+// Release 释放 Acquire 占用的预算
+func (g *MemoryGuard) Release(key string, size int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.used -= size
+	if c, ok := g.consumers[key]; ok {
+		c.Bytes -= size
+		if c.Requests--; c.Requests <= 0 {
+			delete(g.consumers, key)
+		}
+	}
+}
+
+// Used 返回已占用的预算
+func (g *MemoryGuard) Used() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.used
+}
+
+// TopConsumers 返回占用最多的 n 个调用方
+func (g *MemoryGuard) TopConsumers(n int) []MemoryConsumer {
+	g.mu.Lock()
+	out := make([]MemoryConsumer, 0, len(g.consumers))
+	for _, c := range g.consumers {
+		out = append(out, *c)
+	}
+	g.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Bytes > out[j].Bytes
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// overloaded 拒绝请求时输出占用最多的调用方并执行紧急GC；日志和GC均按 GcInterval 限制频率
+func (g *MemoryGuard) overloaded(key string, size int64) {
+	interval := g.config.GcInterval
+	if interval <= 0 {
+		interval = defaultOverloadLogInterval
+	}
+	now := time.Now()
+	g.mu.Lock()
+	fire := now.Sub(g.firedAt) >= interval
+	if fire {
+		g.firedAt = now
+	}
+	g.mu.Unlock()
+	if !fire {
+		return
+	}
+	logger.Warnw("Memory guard, budget exceeded, reject large request",
+		"used", g.Used(), "budget", g.config.Budget, "rejected-key", key, "rejected-size", size,
+		"top-consumers", g.TopConsumers(g.config.TopConsumers))
+	if g.config.GcInterval > 0 {
+		// 释放已回收的内存给操作系统；在独立协程中执行，不阻塞请求
+		go debug.FreeOSMemory()
+	}
+}
+
+// NewMemoryGuardMiddleware 返回内存预算中间件：在缓存请求体之前，按声明的请求体大小占用预算，请求处理完成后释放；
+// 未声明 Content-Length 的请求按 UnknownBodySize 占用预算并限制请求体大小。接近预算时拒绝大请求并返回503。
+func NewMemoryGuardMiddleware(guard *MemoryGuard) flux.WebInterceptor {
+	return func(next flux.WebHandler) flux.WebHandler {
+		return func(webc flux.WebContext) error {
+			if guard.config.Skipper != nil && guard.config.Skipper(webc) {
+				return next(webc)
+			}
+			request, err := webc.HttpRequest()
+			if nil != err || nil == request.Body || http.NoBody == request.Body {
+				return next(webc)
+			}
+			size := request.ContentLength
+			if size < 0 {
+				size = guard.config.UnknownBodySize
+				request.Body = &limitedBody{ReadCloser: request.Body, remaining: size}
+			}
+			if size <= 0 {
+				return next(webc)
+			}
+			key := guard.config.KeyFunc(webc)
+			if !guard.Acquire(key, size) {
+				guard.overloaded(key, size)
+				return &flux.ServeError{
+					StatusCode: http.StatusServiceUnavailable,
+					ErrorCode:  flux.ErrorCodeGatewayOverload,
+					Message:    flux.ErrorMessageMemoryBudgetExceeded,
+				}
+			}
+			defer guard.Release(key, size)
+			return next(webc)
+		}
+	}
+}
+
+// limitedBody 限制读取大小的请求体；超过限制时返回 flux.ErrHttpRequestBodyTooLarge
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, flux.ErrHttpRequestBodyTooLarge
+	}
+	// 多读取1字节，用于判断是否超过限制
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if b.remaining -= int64(n); b.remaining < 0 {
+		return n + int(b.remaining), flux.ErrHttpRequestBodyTooLarge
+	}
+	return n, err
+}
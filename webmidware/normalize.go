@@ -0,0 +1,176 @@
+package webmidware
+
+import (
+	"github.com/bytepowered/flux"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// 重复参数的处理策略
+const (
+	DuplicateParamKeep  = "keep"
+	DuplicateParamFirst = "first"
+	DuplicateParamLast  = "last"
+	DuplicateParamJoin  = "join"
+)
+
+type NormalizeConfig struct {
+	Skipper flux.WebSkipper
+	// TrimSpace 去除Query参数值首尾的空白字符
+	TrimSpace bool
+	// CaseFoldParams 不区分大小写的Query参数：参数名按配置的名称匹配，参数值转换为小写
+	CaseFoldParams []string
+	// DuplicatePolicy 重复Query参数的处理策略：keep（默认，保留全部）、first、last、join（逗号连接）
+	DuplicatePolicy string
+	// CleanPath 规范化请求路径：合并重复的斜杠、解析 . 和 .. 路径段、统一百分号编码
+	CleanPath bool
+}
+
+func NewNormalizeMiddleware() flux.WebInterceptor {
+	return NewNormalizeMiddlewareWith(NormalizeConfig{
+		TrimSpace:       true,
+		DuplicatePolicy: DuplicateParamKeep,
+		CleanPath:       true,
+	})
+}
+
+// NewNormalizeMiddlewareWith 生成入站请求规范化中间件：在路由前规范化请求路径和Query参数，
+// Query参数按名称排序重新编码，使等价请求具有相同的路由结果和缓存Key。
+func NewNormalizeMiddlewareWith(config NormalizeConfig) flux.WebInterceptor {
+	folds := make(map[string]string, len(config.CaseFoldParams))
+	for _, name := range config.CaseFoldParams {
+		if name = strings.TrimSpace(name); "" != name {
+			folds[strings.ToLower(name)] = name
+		}
+	}
+	return func(next flux.WebHandler) flux.WebHandler {
+		return func(webc flux.WebContext) error {
+			if config.Skipper != nil && config.Skipper(webc) {
+				return next(webc)
+			}
+			target, writable := webc.RequestURL()
+			if nil == target || !writable {
+				return next(webc)
+			}
+			if config.CleanPath {
+				normalizePath(target)
+			}
+			if "" != target.RawQuery {
+				target.RawQuery = normalizeQuery(target.RawQuery, config, folds)
+			}
+			return next(webc)
+		}
+	}
+}
+
+// normalizePath 规范化URL路径；包含编码斜杠（%2F）等保留字符时保留RawPath
+func normalizePath(target *url.URL) {
+	if "" != target.RawPath {
+		raw := cleanPath(normalizeEscapes(target.RawPath))
+		if unescaped, err := url.PathUnescape(raw); nil == err {
+			target.Path = unescaped
+			if raw != target.EscapedPath() {
+				target.RawPath = raw
+			} else {
+				target.RawPath = ""
+			}
+			return
+		}
+	}
+	target.Path = cleanPath(target.Path)
+	target.RawPath = ""
+}
+
+// cleanPath 合并重复的斜杠并解析 . 和 .. 路径段；保留结尾的斜杠
+func cleanPath(p string) string {
+	if "" == p {
+		return "/"
+	}
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && "/" != cleaned {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// normalizeEscapes 解码非保留字符的百分号编码，其它编码统一为大写，例如：%7euser%2f -> ~user%2F
+func normalizeEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if '%' == s[i] && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			c := unhex(s[i+1])<<4 | unhex(s[i+2])
+			if isUnreserved(c) {
+				sb.WriteByte(c)
+			} else {
+				sb.WriteByte('%')
+				sb.WriteString(strings.ToUpper(s[i+1 : i+3]))
+			}
+			i += 2
+			continue
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+func normalizeQuery(rawQuery string, config NormalizeConfig, folds map[string]string) string {
+	values, err := url.ParseQuery(rawQuery)
+	if nil != err {
+		// 无法解析的Query参数保持原样，由后续处理返回错误
+		return rawQuery
+	}
+	out := make(url.Values, len(values))
+	for name, vs := range values {
+		fold, isfold := folds[strings.ToLower(name)]
+		if isfold {
+			name = fold
+		}
+		for _, v := range vs {
+			if config.TrimSpace {
+				v = strings.TrimSpace(v)
+			}
+			if isfold {
+				v = strings.ToLower(v)
+			}
+			out[name] = append(out[name], v)
+		}
+	}
+	for name, vs := range out {
+		if len(vs) < 2 {
+			continue
+		}
+		switch config.DuplicatePolicy {
+		case DuplicateParamFirst:
+			out[name] = vs[:1]
+		case DuplicateParamLast:
+			out[name] = vs[len(vs)-1:]
+		case DuplicateParamJoin:
+			out[name] = []string{strings.Join(vs, ",")}
+		}
+	}
+	return out.Encode()
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		'-' == c || '.' == c || '_' == c || '~' == c
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
@@ -0,0 +1,66 @@
+package webmidware
+
+import (
+	"github.com/bytepowered/flux"
+	"net/http"
+	"sync"
+)
+
+// ReadOnlySwitch 网关只读模式开关；只读模式下仅允许GET/HEAD/OPTIONS请求。并发安全。
+type ReadOnlySwitch struct {
+	mu      sync.RWMutex
+	enabled bool
+	message string
+}
+
+func NewReadOnlySwitch(enabled bool, message string) *ReadOnlySwitch {
+	s := &ReadOnlySwitch{}
+	s.Set(enabled, message)
+	return s
+}
+
+// Set 设置只读模式状态和拒绝请求时的响应消息；消息为空时使用默认消息
+func (s *ReadOnlySwitch) Set(enabled bool, message string) {
+	if "" == message {
+		message = flux.ErrorMessageGatewayReadOnly
+	}
+	s.mu.Lock()
+	s.enabled, s.message = enabled, message
+	s.mu.Unlock()
+}
+
+// State 返回只读模式状态和响应消息
+func (s *ReadOnlySwitch) State() (enabled bool, message string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.enabled, s.message
+}
+
+// IsReadOnlyMethod 判断请求方法是否为只读方法
+func IsReadOnlyMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+// NewReadOnlyMiddleware 返回只读模式中间件；开启只读模式时，拒绝修改类请求并返回503
+func NewReadOnlyMiddleware(s *ReadOnlySwitch) flux.WebInterceptor {
+	return func(next flux.WebHandler) flux.WebHandler {
+		return func(webc flux.WebContext) error {
+			if IsReadOnlyMethod(webc.Method()) {
+				return next(webc)
+			}
+			if enabled, message := s.State(); enabled {
+				return &flux.ServeError{
+					StatusCode: http.StatusServiceUnavailable,
+					ErrorCode:  flux.ErrorCodeGatewayReadOnly,
+					Message:    message,
+				}
+			}
+			return next(webc)
+		}
+	}
+}
@@ -1,16 +1,21 @@
 package flux
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"strings"
 )
 
 var (
 	ErrHttpRequestNotSupported  = errors.New("webserver: http.request not supported")
 	ErrHttpResponseNotSupported = errors.New("webserver: http.responsewriter not supported")
+	ErrHttpHijackNotSupported   = errors.New("webserver: http.hijacker not supported")
+	ErrHttpRequestBodyTooLarge  = errors.New("webserver: http.request body too large")
 )
 
 const (
@@ -29,18 +34,22 @@ const (
 	HeaderAccept              = "Accept"
 	HeaderAcceptEncoding      = "Accept-Encoding"
 	HeaderAllow               = "Allow"
+	HeaderAge                 = "Age"
 	HeaderAuthorization       = "Authorization"
 	HeaderContentDisposition  = "Content-Disposition"
 	HeaderContentEncoding     = "Content-Encoding"
 	HeaderContentLength       = "Content-Length"
 	HeaderContentType         = "Content-Type"
+	HeaderConnection          = "Connection"
 	HeaderCookie              = "Cookie"
 	HeaderSetCookie           = "Set-Cookie"
 	HeaderIfModifiedSince     = "If-Modified-Since"
 	HeaderLastModified        = "Last-Modified"
 	HeaderLocation            = "Location"
+	HeaderRetryAfter          = "Retry-After"
 	HeaderUpgrade             = "Upgrade"
 	HeaderVary                = "Vary"
+	HeaderWarning             = "Warning"
 	HeaderWWWAuthenticate     = "WWW-Authenticate"
 	HeaderXForwardedFor       = "X-Forwarded-For"
 	HeaderXForwardedProto     = "X-Forwarded-Protocol"
@@ -80,13 +89,14 @@ const (
 
 // Common used status code
 const (
-	StatusOK           = http.StatusOK
-	StatusBadRequest   = http.StatusBadRequest
-	StatusNotFound     = http.StatusNotFound
-	StatusUnauthorized = http.StatusUnauthorized
-	StatusAccessDenied = http.StatusForbidden
-	StatusServerError  = http.StatusInternalServerError
-	StatusBadGateway   = http.StatusBadGateway
+	StatusOK             = http.StatusOK
+	StatusBadRequest     = http.StatusBadRequest
+	StatusNotFound       = http.StatusNotFound
+	StatusUnauthorized   = http.StatusUnauthorized
+	StatusAccessDenied   = http.StatusForbidden
+	StatusServerError    = http.StatusInternalServerError
+	StatusBadGateway     = http.StatusBadGateway
+	StatusGatewayTimeout = http.StatusGatewayTimeout
 )
 
 // Web interfaces defines
@@ -212,6 +222,10 @@ type WebContext interface {
 	// 如果Web框架不支持标准ResponseWriter（如fasthttp），返回 ErrHttpResponseNotSupported
 	HttpResponseWriter() (http.ResponseWriter, error)
 
+	// Hijack 接管客户端连接，用于WebSocket等协议升级；接管后由调用方负责写入响应和关闭连接。
+	// 如果Web框架或ResponseWriter不支持接管连接，返回 ErrHttpHijackNotSupported
+	Hijack() (net.Conn, *bufio.ReadWriter, error)
+
 	// Context 返回具体Web框架实现的WebContext对象
 	RawWebContext() interface{}
 
@@ -231,6 +245,9 @@ type WebServer interface {
 	// SetWebNotFoundHandler 设置Web路由不存在处理函数
 	SetWebNotFoundHandler(h WebHandler)
 
+	// SetWebMethodNotAllowedHandler 设置Web路由方法不允许处理函数；调用前需设置响应的Allow Header
+	SetWebMethodNotAllowedHandler(h WebHandler)
+
 	// SetWebRequestBodyDecoder 设置Body体解析接口
 	SetWebRequestBodyDecoder(decoder WebRequestBodyDecoder)
 
@@ -259,6 +276,35 @@ type WebServer interface {
 	Shutdown(ctx context.Context) error
 }
 
+type connContextKey struct{}
+
+// ContextWithConn 保存客户端连接到Context；由WebServer通过 http.Server.ConnContext 注入，用于设置连接级别的读写超时
+func ContextWithConn(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, connContextKey{}, conn)
+}
+
+// ConnOfContext 返回Context中保存的客户端连接
+func ConnOfContext(ctx context.Context) (net.Conn, bool) {
+	conn, ok := ctx.Value(connContextKey{}).(net.Conn)
+	return conn, ok
+}
+
+// IsWebSocketUpgrade 判断请求是否为WebSocket协议升级请求
+func IsWebSocketUpgrade(request RequestReader) bool {
+	return request.Method() == http.MethodGet &&
+		headerContainsToken(request.HeaderValue(HeaderConnection), "upgrade") &&
+		strings.EqualFold(request.HeaderValue(HeaderUpgrade), "websocket")
+}
+
+func headerContainsToken(value, token string) bool {
+	for _, v := range strings.Split(value, ",") {
+		if strings.EqualFold(strings.TrimSpace(v), token) {
+			return true
+		}
+	}
+	return false
+}
+
 /// Wrapper functions
 
 func WrapHttpHandler(h http.Handler) WebHandler {